@@ -19,6 +19,7 @@ package client
 
 import (
 	"math/rand"
+	"time"
 
 	"github.com/cockroachdb/cockroach/proto"
 	gogoproto "github.com/gogo/protobuf/proto"
@@ -58,6 +59,17 @@ func GetCall(key proto.Key) Call {
 	}
 }
 
+// StaleGetCall is like GetCall, but the value may be read by the
+// nearest replica, without consulting the range leader, as of a
+// timestamp up to maxStaleness in the past. The timestamp read at is
+// returned in the reply header. Stale calls may not be run within a
+// transaction.
+func StaleGetCall(key proto.Key, maxStaleness time.Duration) Call {
+	c := GetCall(key)
+	c.Args.Header().MaxStaleness = maxStaleness.Nanoseconds()
+	return c
+}
+
 // IncrementCall returns a Call object initialized to increment the
 // value at key by increment.
 func IncrementCall(key proto.Key, increment int64) Call {
@@ -72,6 +84,26 @@ func IncrementCall(key proto.Key, increment int64) Call {
 	}
 }
 
+// BoundedIncrementCall returns a Call object initialized to increment
+// the value at key by increment. If no value exists for key, initial
+// is incremented. The increment is not applied and the call fails with
+// an IncrementBoundsError if the new value would fall outside of
+// [min, max].
+func BoundedIncrementCall(key proto.Key, increment, initial, min, max int64) Call {
+	return Call{
+		Args: &proto.IncrementRequest{
+			RequestHeader: proto.RequestHeader{
+				Key: key,
+			},
+			Increment:    increment,
+			InitialValue: initial,
+			MinValue:     gogoproto.Int64(min),
+			MaxValue:     gogoproto.Int64(max),
+		},
+		Reply: &proto.IncrementResponse{},
+	}
+}
+
 // PutCall returns a Call object initialized to put value
 // as a byte slice at key.
 func PutCall(key proto.Key, valueBytes []byte) Call {
@@ -149,3 +181,33 @@ func ScanCall(key, endKey proto.Key, maxResults int64) Call {
 		Reply: &proto.ScanResponse{},
 	}
 }
+
+// CompressedScanCall is like ScanCall, but asks the server to elide
+// the prefix each returned key shares with the key preceding it. This
+// reduces response sizes considerably for long, structured keys. The
+// keys are restored transparently when the call is run.
+func CompressedScanCall(key, endKey proto.Key, maxResults int64) Call {
+	c := ScanCall(key, endKey, maxResults)
+	c.Args.(*proto.ScanRequest).CompressKeys = true
+	return c
+}
+
+// IntentPolicyScanCall is like ScanCall, but treats the intents of
+// other transactions according to policy. With SKIP_INTENTS, the keys
+// holding them are omitted from the reply's rows and listed in its
+// SkippedIntents; with FAIL_ON_INTENTS, the call fails with a
+// WriteIntentError rather than waiting for their transactions.
+func IntentPolicyScanCall(key, endKey proto.Key, maxResults int64, policy proto.ScanIntentPolicy) Call {
+	c := ScanCall(key, endKey, maxResults)
+	c.Args.(*proto.ScanRequest).IntentPolicy = policy
+	return c
+}
+
+// StaleScanCall is like ScanCall, but with the staleness of the rows
+// bounded by maxStaleness as for StaleGetCall. A scan spanning ranges
+// reads all of them at the timestamp chosen for the first.
+func StaleScanCall(key, endKey proto.Key, maxResults int64, maxStaleness time.Duration) Call {
+	c := ScanCall(key, endKey, maxResults)
+	c.Args.Header().MaxStaleness = maxStaleness.Nanoseconds()
+	return c
+}
@@ -20,6 +20,7 @@ package client
 import (
 	"time"
 
+	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/util"
 )
 
@@ -51,6 +52,7 @@ var (
 type Context struct {
 	User            string
 	UserPriority    int32
+	PriorityClass   proto.PriorityClass
 	TxnRetryOptions util.RetryOptions
 	Clock           Clock
 }
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
 
@@ -64,7 +65,8 @@ var HTTPRetryOptions = util.RetryOptions{
 
 // NewHTTPClient initializes a new http client. If certsDir is not empty,
 // it initializes the TLS config from the certificates in the specified
-// directory.
+// directory. The client connects to unix sockets named by the hosts of
+// URLs created with util.HTTPHost.
 func NewHTTPClient(certsDir string) (*http.Client, error) {
 	var tlsConfig *tls.Config
 	if certsDir == "" {
@@ -78,7 +80,15 @@ func NewHTTPClient(certsDir string) (*http.Client, error) {
 		}
 		tlsConfig = cfg.Config()
 	}
-	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, Dial: dialHTTP}}, nil
+}
+
+// dialHTTP connects to addr, or to the unix socket it names.
+func dialHTTP(network, addr string) (net.Conn, error) {
+	if path, ok := util.UnixSocketFromHTTPHost(addr); ok {
+		return net.Dial("unix", path)
+	}
+	return net.Dial(network, addr)
 }
 
 // HTTPSender is an implementation of KVSender which exposes the
@@ -86,18 +96,19 @@ func NewHTTPClient(certsDir string) (*http.Client, error) {
 // via HTTP to a Cockroach node. Overly-busy nodes will redirect
 // this client to other nodes.
 type HTTPSender struct {
-	server string       // The host:port address of the Cockroach gateway node
+	server string       // The host of the Cockroach gateway node in URLs
 	client *http.Client // The HTTP client
 }
 
-// NewHTTPSender returns a new instance of HTTPSender.
+// NewHTTPSender returns a new instance of HTTPSender which connects
+// to server, a host:port pair or a unix socket address.
 func NewHTTPSender(server string, certsDir string) (*HTTPSender, error) {
 	client, err := NewHTTPClient(certsDir)
 	if err != nil {
 		return nil, err
 	}
 	return &HTTPSender{
-		server: server,
+		server: util.HTTPHost(server),
 		client: client,
 	}, nil
 }
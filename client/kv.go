@@ -18,6 +18,8 @@
 package client
 
 import (
+	"time"
+
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -29,6 +31,15 @@ type TransactionOptions struct {
 	Name         string // Concise desc of txn for debugging
 	Isolation    proto.IsolationType
 	UserPriority int32
+	// PriorityClass, if set, overrides the priority class of the KV
+	// for the transaction's requests.
+	PriorityClass proto.PriorityClass
+	// HeartbeatInterval is how often a running transaction which has
+	// written is heartbeat. Zero uses DefaultTxnHeartbeatInterval; a
+	// negative interval disables heartbeating, leaving the transaction
+	// to be considered abandoned if it's idle for longer than its
+	// coordinator's client timeout.
+	HeartbeatInterval time.Duration
 }
 
 // KVSender is an interface for sending a request to a Key-Value
@@ -63,7 +74,11 @@ type KV struct {
 	// UserPriority is the default user priority to set on API calls. If
 	// UserPriority is set non-zero in call arguments, this value is
 	// ignored.
-	UserPriority    int32
+	UserPriority int32
+	// PriorityClass is the default priority class to set on API calls.
+	// If PriorityClass is set to other than NORMAL_PRIORITY in call
+	// arguments, this value is ignored.
+	PriorityClass   proto.PriorityClass
 	TxnRetryOptions util.RetryOptions
 	Sender          KVSender
 	clock           Clock
@@ -83,13 +98,16 @@ func NewKV(ctx *Context, sender KVSender) *KV {
 		Sender:          sender,
 		User:            ctx.User,
 		UserPriority:    ctx.UserPriority,
+		PriorityClass:   ctx.PriorityClass,
 		TxnRetryOptions: ctx.TxnRetryOptions,
 		clock:           ctx.Clock,
 	}
 }
 
 // Run runs the specified calls synchronously in a single batch and
-// returns any errors.
+// returns any errors. A batch rejected by the coordinator as too large
+// is split into batches within its limits, which are run in order; the
+// first error encountered is returned.
 func (kv *KV) Run(calls ...Call) (err error) {
 	if len(calls) == 0 {
 		return nil
@@ -112,9 +130,15 @@ func (kv *KV) Run(calls ...Call) (err error) {
 		if c.Args.Header().UserPriority == nil && kv.UserPriority != 0 {
 			c.Args.Header().UserPriority = gogoproto.Int32(kv.UserPriority)
 		}
+		if c.Args.Header().PriorityClass == proto.NORMAL_PRIORITY {
+			c.Args.Header().PriorityClass = kv.PriorityClass
+		}
 		c.resetClientCmdID(kv.clock)
 		kv.Sender.Send(c)
 		err = c.Reply.Header().GoError()
+		if err == nil {
+			err = decompressScanKeys(c.Reply)
+		}
 		if err != nil {
 			log.Infof("failed %s: %s", c.Method(), err)
 		}
@@ -128,6 +152,22 @@ func (kv *KV) Run(calls ...Call) (err error) {
 		replies = append(replies, call.Reply)
 	}
 	err = kv.Run(Call{Args: bArgs, Reply: bReply})
+	if tooLarge, ok := err.(*proto.BatchTooLargeError); ok {
+		sizes := make([]int64, len(bArgs.Requests))
+		for i := range bArgs.Requests {
+			sizes[i] = int64(bArgs.Requests[i].Size())
+		}
+		chunks := chunkCalls(calls, sizes, int(tooLarge.MaxRequests), tooLarge.MaxBytes)
+		if len(chunks) == 1 {
+			return err
+		}
+		for _, chunk := range chunks {
+			if err := kv.Run(chunk...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
 	// Recover from protobuf merge panics.
 	defer func() {
@@ -146,10 +186,45 @@ func (kv *KV) Run(calls ...Call) (err error) {
 	for i, reply := range bReply.Responses {
 		replies[i].Reset()
 		gogoproto.Merge(replies[i], reply.GetValue().(gogoproto.Message))
+		if dErr := decompressScanKeys(replies[i]); dErr != nil && err == nil {
+			err = dErr
+		}
 	}
 	return
 }
 
+// chunkCalls splits calls, whose encoded sizes are given by sizes, into
+// consecutive chunks of at most maxRequests calls and maxBytes bytes,
+// preserving their order. A call larger than maxBytes forms a chunk of
+// its own. Zero limits are unlimited.
+func chunkCalls(calls []Call, sizes []int64, maxRequests int, maxBytes int64) [][]Call {
+	var chunks [][]Call
+	var start int
+	var bytes int64
+	for i := range calls {
+		full := maxRequests > 0 && i-start >= maxRequests
+		if maxBytes > 0 && bytes+sizes[i] > maxBytes {
+			full = true
+		}
+		if full && i > start {
+			chunks = append(chunks, calls[start:i])
+			start, bytes = i, 0
+		}
+		bytes += sizes[i]
+	}
+	return append(chunks, calls[start:])
+}
+
+// decompressScanKeys restores the full keys of a scan response whose
+// keys were compressed at the server's request, so that callers never
+// observe compressed keys.
+func decompressScanKeys(reply proto.Response) error {
+	if sr, ok := reply.(*proto.ScanResponse); ok {
+		return sr.DecompressKeys()
+	}
+	return nil
+}
+
 // RunTransaction executes retryable in the context of a distributed
 // transaction. The transaction is automatically aborted if retryable
 // returns any error aside from recoverable internal errors, and is
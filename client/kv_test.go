@@ -126,6 +126,35 @@ func TestKVTransactionConfig(t *testing.T) {
 	}
 }
 
+// TestKVPriorityClass verifies that the client's priority class is
+// set on calls which don't specify one and that a transaction's
+// options override it.
+func TestKVPriorityClass(t *testing.T) {
+	var classes []proto.PriorityClass
+	client := NewKV(nil, newTestSender(func(call Call) {
+		classes = append(classes, call.Args.Header().PriorityClass)
+	}))
+	client.PriorityClass = proto.LOW_PRIORITY
+	high := GetCall(proto.Key("a"))
+	high.Args.Header().PriorityClass = proto.HIGH_PRIORITY
+	if err := client.Run(GetCall(proto.Key("a"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Run(high); err != nil {
+		t.Fatal(err)
+	}
+	opts := &TransactionOptions{PriorityClass: proto.SYSTEM_PRIORITY}
+	if err := client.RunTransaction(opts, func(txn *Txn) error {
+		return txn.Run(GetCall(proto.Key("a")))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expClasses := []proto.PriorityClass{proto.LOW_PRIORITY, proto.HIGH_PRIORITY, proto.SYSTEM_PRIORITY}
+	if !reflect.DeepEqual(classes, expClasses) {
+		t.Errorf("expected priority classes %s; got %s", expClasses, classes)
+	}
+}
+
 // TestKVCommitReadOnlyTransaction verifies that transaction is
 // committed but EndTransaction is not sent if only read-only
 // operations were performed.
@@ -282,3 +311,69 @@ func TestKVRunTransactionRetryOnErrors(t *testing.T) {
 		}
 	}
 }
+
+// TestKVRunSplitsOversizedBatch verifies that a batch rejected as too
+// large is split into batches within the limit, which are run in
+// order.
+func TestKVRunSplitsOversizedBatch(t *testing.T) {
+	var sizes []int
+	var keys []string
+	client := NewKV(nil, newTestSender(func(call Call) {
+		switch args := call.Args.(type) {
+		case *proto.BatchRequest:
+			sizes = append(sizes, len(args.Requests))
+			if len(args.Requests) > 2 {
+				call.Reply.Header().SetGoError(&proto.BatchTooLargeError{
+					Requests: int32(len(args.Requests)), MaxRequests: 2})
+				return
+			}
+			for _, union := range args.Requests {
+				keys = append(keys, string(union.GetValue().(proto.Request).Header().Key))
+			}
+		default:
+			sizes = append(sizes, 1)
+			keys = append(keys, string(args.Header().Key))
+		}
+	}))
+
+	var calls []Call
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		calls = append(calls, PutCall(proto.Key(key), []byte("value")))
+	}
+	if err := client.Run(calls...); err != nil {
+		t.Fatal(err)
+	}
+	if expSizes := []int{5, 2, 2, 1}; !reflect.DeepEqual(expSizes, sizes) {
+		t.Errorf("expected batches of %v; got %v", expSizes, sizes)
+	}
+	if expKeys := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(expKeys, keys) {
+		t.Errorf("expected keys %v in order; got %v", expKeys, keys)
+	}
+}
+
+// TestChunkCalls verifies the splitting of calls by request count and
+// size.
+func TestChunkCalls(t *testing.T) {
+	calls := make([]Call, 5)
+	testCases := []struct {
+		sizes       []int64
+		maxRequests int
+		maxBytes    int64
+		expLens     []int
+	}{
+		{[]int64{1, 1, 1, 1, 1}, 0, 0, []int{5}},
+		{[]int64{1, 1, 1, 1, 1}, 2, 0, []int{2, 2, 1}},
+		{[]int64{1, 1, 1, 1, 1}, 0, 3, []int{3, 2}},
+		{[]int64{2, 5, 1, 1, 2}, 0, 3, []int{1, 1, 2, 1}},
+		{[]int64{1, 1, 1, 1, 1}, 4, 2, []int{2, 2, 1}},
+	}
+	for i, test := range testCases {
+		var lens []int
+		for _, chunk := range chunkCalls(calls, test.sizes, test.maxRequests, test.maxBytes) {
+			lens = append(lens, len(chunk))
+		}
+		if !reflect.DeepEqual(test.expLens, lens) {
+			t.Errorf("%d: expected chunks of %v; got %v", i, test.expLens, lens)
+		}
+	}
+}
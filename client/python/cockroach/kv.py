@@ -0,0 +1,184 @@
+# Copyright 2015 The Cockroach Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+# implied. See the License for the specific language governing
+# permissions and limitations under the License. See the AUTHORS file
+# for names of contributors.
+
+"""Client for the key-value API served at /kv/db/.
+
+Requests and responses are JSON objects whose field names are those of
+the messages in proto/api.proto. Keys and byte values are base64
+encoded; see README.md for the complete mapping.
+"""
+
+import base64
+import json
+import numbers
+
+try:
+    from urllib.request import Request, urlopen
+    from urllib.error import HTTPError
+except ImportError:  # Python 2
+    from urllib2 import Request, urlopen, HTTPError
+
+# KV_DB_ENDPOINT matches client.KVDBEndpoint.
+KV_DB_ENDPOINT = "/kv/db/"
+
+
+class KVError(Exception):
+    """An error returned in the header of a response."""
+
+    def __init__(self, error):
+        Exception.__init__(self, error.get("message", ""))
+        self.error = error
+        self.retryable = error.get("retryable", False)
+        self.detail = error.get("detail") or {}
+
+
+class ConditionFailedError(KVError):
+    """The expected value of a conditional put did not match."""
+
+    def __init__(self, error):
+        KVError.__init__(self, error)
+        self.actual_value = _decode_value(
+            self.detail["condition_failed"].get("actual_value"))
+
+
+def _encode_bytes(b):
+    if not isinstance(b, bytes):
+        b = b.encode("utf-8")
+    return base64.b64encode(b).decode("ascii")
+
+
+def _decode_bytes(s):
+    if s is None:
+        return b""
+    return base64.b64decode(s)
+
+
+def _encode_value(value):
+    if isinstance(value, numbers.Integral) and not isinstance(value, bool):
+        return {"integer": value}
+    return {"bytes": _encode_bytes(value)}
+
+
+def _decode_value(value):
+    """Returns the bytes or integer held by a Value, or None."""
+    if value is None:
+        return None
+    if "integer" in value:
+        return value["integer"]
+    return _decode_bytes(value.get("bytes"))
+
+
+class KV(object):
+    """A client for the key-value API of a single node.
+
+    addr is the node's host:port. ssl_context, if given, is used to
+    verify the node's certificate; nodes always serve over HTTPS.
+    """
+
+    def __init__(self, addr, user="root", ssl_context=None):
+        self.addr = addr
+        self.user = user
+        self.ssl_context = ssl_context
+
+    def call(self, method, args):
+        """Invokes method with the request args, a dict, and returns
+        the response dict. Raises KVError if the response holds an
+        error."""
+        header = args.setdefault("header", {})
+        header.setdefault("user", self.user)
+        req = Request("https://%s%s%s" % (self.addr, KV_DB_ENDPOINT, method),
+                      data=json.dumps(args).encode("utf-8"),
+                      headers={"Content-Type": "application/json",
+                               "Accept": "application/json"})
+        try:
+            if self.ssl_context is not None:
+                resp = urlopen(req, context=self.ssl_context)
+            else:
+                resp = urlopen(req)
+            reply = json.loads(resp.read().decode("utf-8"))
+        except HTTPError as e:
+            raise KVError({"message": "%s: %s" % (e.code, e.read())})
+        error = reply.get("header", {}).get("error")
+        if error:
+            if "condition_failed" in (error.get("detail") or {}):
+                raise ConditionFailedError(error)
+            raise KVError(error)
+        return reply
+
+    def _header(self, key, end_key=None):
+        header = {"key": _encode_bytes(key)}
+        if end_key is not None:
+            header["end_key"] = _encode_bytes(end_key)
+        return header
+
+    def contains(self, key):
+        reply = self.call("Contains", {"header": self._header(key)})
+        return reply.get("exists", False)
+
+    def get(self, key):
+        """Returns the bytes or integer stored at key, or None."""
+        reply = self.call("Get", {"header": self._header(key)})
+        return _decode_value(reply.get("value"))
+
+    def put(self, key, value):
+        """Stores value, which is bytes, a string or an integer."""
+        self.call("Put", {"header": self._header(key),
+                          "value": _encode_value(value)})
+
+    def conditional_put(self, key, value, exp_value):
+        """Stores value if the existing value equals exp_value, which
+        is None if the key must not exist. Raises ConditionFailedError
+        otherwise."""
+        args = {"header": self._header(key), "value": _encode_value(value)}
+        if exp_value is not None:
+            args["exp_value"] = _encode_value(exp_value)
+        self.call("ConditionalPut", args)
+
+    def increment(self, key, increment):
+        """Increments the integer at key and returns the new value."""
+        reply = self.call("Increment", {"header": self._header(key),
+                                        "increment": increment})
+        return reply.get("new_value", 0)
+
+    def delete(self, key):
+        self.call("Delete", {"header": self._header(key)})
+
+    def delete_range(self, key, end_key, max_entries=0):
+        """Deletes keys in [key, end_key) and returns the count."""
+        reply = self.call("DeleteRange", {
+            "header": self._header(key, end_key),
+            "max_entries_to_delete": max_entries})
+        return reply.get("num_deleted", 0)
+
+    def scan(self, key, end_key, max_results=0, compress_keys=False):
+        """Returns a list of (key, value) tuples in [key, end_key).
+
+        If compress_keys is set, the server elides the prefix each key
+        shares with its predecessor, and the keys are restored here.
+        """
+        reply = self.call("Scan", {"header": self._header(key, end_key),
+                                   "max_results": max_results,
+                                   "compress_keys": compress_keys})
+        rows = reply.get("rows") or []
+        prefix_lens = reply.get("key_prefix_lengths")
+        result = []
+        prev = b""
+        for i, row in enumerate(rows):
+            k = _decode_bytes(row.get("key"))
+            if prefix_lens is not None:
+                k = prev[:prefix_lens[i]] + k
+            prev = k
+            result.append((k, _decode_value(row.get("value"))))
+        return result
@@ -0,0 +1,108 @@
+# Copyright 2015 The Cockroach Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+# implied. See the License for the specific language governing
+# permissions and limitations under the License. See the AUTHORS file
+# for names of contributors.
+
+"""Conformance tests for the key-value HTTP API.
+
+Runs against the node at $COCKROACH_ADDR (default localhost:8080).
+The node's certificate is verified against $COCKROACH_CA_CERT if set
+and not verified otherwise. Each run writes below a fresh key prefix.
+
+    python conformance_test.py
+"""
+
+import os
+import ssl
+import time
+import unittest
+
+from cockroach import KV, KVError, ConditionFailedError
+
+
+def make_client():
+    addr = os.environ.get("COCKROACH_ADDR", "localhost:8080")
+    ca_cert = os.environ.get("COCKROACH_CA_CERT")
+    if ca_cert:
+        ctx = ssl.create_default_context(cafile=ca_cert)
+    else:
+        ctx = ssl._create_unverified_context()
+    return KV(addr, ssl_context=ctx)
+
+
+class ConformanceTest(unittest.TestCase):
+
+    def setUp(self):
+        self.kv = make_client()
+        self.prefix = ("conformance/%d/%s/" % (
+            int(time.time() * 1e6), self._testMethodName)).encode("ascii")
+
+    def key(self, suffix):
+        return self.prefix + suffix
+
+    def test_put_get(self):
+        k = self.key(b"\x00binary key\xff")
+        self.assertIsNone(self.kv.get(k))
+        self.assertFalse(self.kv.contains(k))
+        self.kv.put(k, b"\x00\x01value")
+        self.assertEqual(self.kv.get(k), b"\x00\x01value")
+        self.assertTrue(self.kv.contains(k))
+
+    def test_delete(self):
+        k = self.key(b"a")
+        self.kv.put(k, b"value")
+        self.kv.delete(k)
+        self.assertIsNone(self.kv.get(k))
+
+    def test_increment(self):
+        k = self.key(b"counter")
+        self.assertEqual(self.kv.increment(k, 5), 5)
+        self.assertEqual(self.kv.increment(k, -7), -2)
+        self.assertEqual(self.kv.get(k), -2)
+        big = 1 << 62
+        self.assertEqual(self.kv.increment(k, big), big - 2)
+
+    def test_conditional_put(self):
+        k = self.key(b"cput")
+        self.kv.conditional_put(k, b"v1", None)
+        self.kv.conditional_put(k, b"v2", b"v1")
+        with self.assertRaises(ConditionFailedError) as cm:
+            self.kv.conditional_put(k, b"v3", b"v1")
+        self.assertEqual(cm.exception.actual_value, b"v2")
+        self.assertEqual(self.kv.get(k), b"v2")
+
+    def test_scan(self):
+        keys = [self.key(s) for s in (b"a", b"ab", b"abc", b"b")]
+        for i, k in enumerate(keys):
+            self.kv.put(k, k + b"-value")
+        for compress in (False, True):
+            rows = self.kv.scan(self.key(b""), self.key(b"\xff"),
+                                compress_keys=compress)
+            self.assertEqual(rows, [(k, k + b"-value") for k in keys])
+        rows = self.kv.scan(self.key(b""), self.key(b"\xff"), max_results=2)
+        self.assertEqual([k for k, _ in rows], keys[:2])
+
+    def test_delete_range(self):
+        for s in (b"a", b"b", b"c"):
+            self.kv.put(self.key(s), b"value")
+        self.assertEqual(self.kv.delete_range(self.key(b"a"), self.key(b"c")), 2)
+        rows = self.kv.scan(self.key(b""), self.key(b"\xff"))
+        self.assertEqual([k for k, _ in rows], [self.key(b"c")])
+
+    def test_unknown_method(self):
+        with self.assertRaises(KVError):
+            self.kv.call("NoSuchMethod", {})
+
+
+if __name__ == "__main__":
+    unittest.main()
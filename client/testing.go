@@ -21,20 +21,24 @@ import (
 	"net/http"
 
 	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/util"
 )
 
-// CreateTestHTTPClient initialises a new http client with insecure TLS config.
-// TODO(marc): load test certs when enforced.
+// CreateTestHTTPClient initialises a new http client with insecure TLS config,
+// authenticating with the embedded test node certificate.
 func CreateTestHTTPClient() *http.Client {
-	tlsConfig := security.LoadInsecureClientTLSConfig().Config()
-	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	cfg, err := security.LoadTestClientTLSConfig("test_certs")
+	if err != nil {
+		panic(err)
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg.Config(), Dial: dialHTTP}}
 }
 
 // CreateTestHTTPSender initializes a new HTTPSender for 'addr'.
 // It uses an insecure TLS config.
 func CreateTestHTTPSender(addr string) *HTTPSender {
 	return &HTTPSender{
-		server: addr,
+		server: util.HTTPHost(addr),
 		client: CreateTestHTTPClient(),
 	}
 }
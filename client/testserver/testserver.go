@@ -0,0 +1,50 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+// Package testserver runs an in-process single-node cluster with an
+// in-memory store, so that programs using the client package may be
+// tested against the semantics of a real cluster:
+//
+//   db, stop, err := testserver.Start()
+//   if err != nil {
+//     t.Fatal(err)
+//   }
+//   defer stop()
+//   err = db.Run(client.PutCall(proto.Key("a"), []byte("value")))
+package testserver
+
+import (
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/storage/engine"
+)
+
+// storeSize is the maximum size of the in-memory store.
+const storeSize = 100 << 20
+
+// Start bootstraps and starts a single-node cluster on a new in-memory
+// store. It returns a client of the cluster, sending commands as the
+// root user, and a function which stops the cluster and discards its
+// data.
+func Start() (*client.KV, func(), error) {
+	ctx := server.NewTestContext()
+	ctx.Engines = []engine.Engine{engine.NewInMem(proto.Attributes{}, storeSize)}
+	e, err := server.NewEmbedded(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e.KV(), e.Stop, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package testserver_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/client/testserver"
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// TestStart verifies that the test server accepts transactional
+// writes and serves them to later reads.
+func TestStart(t *testing.T) {
+	db, stop, err := testserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	opts := &client.TransactionOptions{Name: "test"}
+	if err := db.RunTransaction(opts, func(txn *client.Txn) error {
+		txn.Prepare(client.PutCall(proto.Key("a"), []byte("1")))
+		txn.Prepare(client.PutCall(proto.Key("b"), []byte("2")))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	call := client.ScanCall(proto.Key("a"), proto.Key("c"), 0)
+	if err := db.Run(call); err != nil {
+		t.Fatal(err)
+	}
+	if rows := call.Reply.(*proto.ScanResponse).Rows; len(rows) != 2 {
+		t.Errorf("expected 2 rows; got %d", len(rows))
+	}
+}
@@ -18,11 +18,19 @@
 package client
 
 import (
+	"sync"
+	"time"
+
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
 )
 
+// DefaultTxnHeartbeatInterval is the default interval at which a
+// running transaction which has written is heartbeat.
+const DefaultTxnHeartbeatInterval = 5 * time.Second
+
 type txnSender struct {
 	*Txn
 }
@@ -41,6 +49,15 @@ func (ts *txnSender) Send(call Call) {
 			Priority:  err.Txn.Priority, // acts as a minimum priority on restart
 		}
 	}
+
+	// Publish the transaction to the heartbeat loop once it has written.
+	ts.mu.Lock()
+	if ts.needsEndTxn && len(ts.txn.ID) > 0 {
+		ts.heartbeatTxn = gogoproto.Clone(&ts.txn).(*proto.Transaction)
+	} else {
+		ts.heartbeatTxn = nil
+	}
+	ts.mu.Unlock()
 }
 
 // Txn provides serial access to a KV store via Run and parallel
@@ -49,14 +66,22 @@ func (ts *txnSender) Send(call Call) {
 // error passed to caller. On receipt of TransactionAbortedError, the
 // transaction is re-created and the error passed to caller.
 //
+// While a transaction which has written runs, it's heartbeat in the
+// background, so that its coordinator doesn't consider it abandoned
+// while the application is busy between calls.
+//
 // A Txn instance is not thread safe.
 type Txn struct {
-	kv          KV
-	wrapped     KVSender
-	txn         proto.Transaction
-	txnSender   txnSender
-	prepared    []Call
-	needsEndTxn bool // True if EndTransaction needs to be sent
+	kv                KV
+	wrapped           KVSender
+	txn               proto.Transaction
+	txnSender         txnSender
+	prepared          []Call
+	needsEndTxn       bool // True if EndTransaction needs to be sent
+	heartbeatInterval time.Duration
+
+	mu           sync.Mutex         // Protects heartbeatTxn
+	heartbeatTxn *proto.Transaction // Copy of txn to heartbeat; nil until it writes
 }
 
 var defaultTxnOpts = TransactionOptions{}
@@ -73,16 +98,32 @@ func newTxn(kv *KV, opts *TransactionOptions) *Txn {
 			Name:      opts.Name,
 			Isolation: opts.Isolation,
 		},
+		heartbeatInterval: opts.HeartbeatInterval,
+	}
+	if t.heartbeatInterval == 0 {
+		t.heartbeatInterval = DefaultTxnHeartbeatInterval
 	}
 	t.txnSender.Txn = t
 	t.kv.Sender = &t.txnSender
 	if opts != &defaultTxnOpts {
 		t.kv.UserPriority = opts.UserPriority
 	}
+	if opts.PriorityClass != proto.NORMAL_PRIORITY {
+		t.kv.PriorityClass = opts.PriorityClass
+	}
 	return t
 }
 
 func (t *Txn) exec(retryable func(txn *Txn) error) error {
+	if t.heartbeatInterval > 0 {
+		stop, done := make(chan struct{}), make(chan struct{})
+		go t.heartbeat(stop, done)
+		defer func() {
+			close(stop)
+			<-done
+		}()
+	}
+
 	// Run retryable in a retry loop until we encounter a success or
 	// error condition this loop isn't capable of handling.
 	retryOpts := t.kv.TxnRetryOptions
@@ -126,6 +167,63 @@ func (t *Txn) exec(retryable func(txn *Txn) error) error {
 	return err
 }
 
+// HeartbeatLoop calls beat every interval until stop is closed or
+// beat returns false. It runs the heartbeats of transactions, both
+// those sent by a running Txn and those sent by the transaction's
+// coordinator.
+func HeartbeatLoop(interval time.Duration, stop <-chan struct{}, beat func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !beat() {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// TxnHeartbeatCall returns a call which heartbeats txn as user.
+func TxnHeartbeatCall(txn *proto.Transaction, user string) Call {
+	return Call{
+		Args: &proto.InternalHeartbeatTxnRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:  txn.Key,
+				User: user,
+				Txn:  txn,
+			},
+		},
+		Reply: &proto.InternalHeartbeatTxnResponse{},
+	}
+}
+
+// heartbeat sends an InternalHeartbeatTxn for the transaction every
+// heartbeat interval, once it has written, until stop is closed, and
+// then closes done. Besides advancing the heartbeat of the transaction
+// record, each heartbeat tells the transaction's coordinator that the
+// client is still live.
+func (t *Txn) heartbeat(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	HeartbeatLoop(t.heartbeatInterval, stop, func() bool {
+		t.mu.Lock()
+		txn := t.heartbeatTxn
+		t.mu.Unlock()
+		if txn == nil {
+			return true
+		}
+		call := TxnHeartbeatCall(txn, t.kv.User)
+		call.resetClientCmdID(t.kv.clock)
+		t.wrapped.Send(call)
+		if err := call.Reply.Header().GoError(); err != nil {
+			log.Warningf("heartbeat of txn %s failed: %s", txn, err)
+		}
+		return true
+	})
+}
+
 // Run runs the specified calls synchronously in a single batch and
 // returns any errors.
 func (t *Txn) Run(calls ...Call) error {
@@ -167,9 +265,11 @@ func (t *Txn) Prepare(calls ...Call) {
 
 // Flush sends all previously prepared calls, buffered by invocations
 // of Prepare(). The calls are organized into a single batch command
-// and sent together. Flush returns nil if all prepared calls are
-// executed successfully. Otherwise, Flush returns the first error,
-// where calls are executed in the order in which they were prepared.
+// and sent together, and calls whose keys don't overlap those of
+// preceding calls may be executed in parallel. Flush returns
+// nil if all prepared calls are executed successfully. Otherwise,
+// Flush returns the first error, in the order in which the calls were
+// prepared.
 // After Flush returns, all prepared reply structs will be valid.
 func (t *Txn) Flush() error {
 	calls := t.prepared
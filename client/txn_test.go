@@ -18,7 +18,9 @@
 package client
 
 import (
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"code.google.com/p/go-uuid/uuid"
 	"github.com/cockroachdb/cockroach/proto"
@@ -104,3 +106,50 @@ func TestTxnResetTxnOnAbort(t *testing.T) {
 		t.Errorf("expected txn to be cleared")
 	}
 }
+
+// TestTxnHeartbeat verifies that a running transaction is heartbeat
+// once it has written, and not after it has finished.
+func TestTxnHeartbeat(t *testing.T) {
+	var heartbeats int32
+	kv := NewKV(nil, newTestSender(func(call Call) {
+		if _, ok := call.Args.(*proto.InternalHeartbeatTxnRequest); ok {
+			if !call.Args.Header().Key.Equal(txnKey) {
+				t.Errorf("expected heartbeat of key %q; got %q", txnKey, call.Args.Header().Key)
+			}
+			atomic.AddInt32(&heartbeats, 1)
+		}
+	}))
+	opts := &TransactionOptions{HeartbeatInterval: time.Millisecond}
+
+	// A read-only transaction isn't heartbeat.
+	if err := kv.RunTransaction(opts, func(txn *Txn) error {
+		if err := txn.Run(GetCall(testKey)); err != nil {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&heartbeats); n != 0 {
+		t.Fatalf("expected no heartbeats of read-only txn; got %d", n)
+	}
+
+	if err := kv.RunTransaction(opts, func(txn *Txn) error {
+		if err := txn.Run(Call{Args: testPutReq, Reply: &proto.PutResponse{}}); err != nil {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	n := atomic.LoadInt32(&heartbeats)
+	if n == 0 {
+		t.Fatal("expected heartbeats of txn")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if after := atomic.LoadInt32(&heartbeats); after != n {
+		t.Errorf("expected no heartbeats after txn finished; got %d", after-n)
+	}
+}
@@ -98,13 +98,22 @@ func (c *client) close() {
 // gossip loops, sending deltas of the infostore and receiving deltas
 // in turn. If an alternate is proposed on response, the client addr
 // is modified and method returns for forwarding by caller.
+//
+// The first request carries no delta; the peer's response holds its
+// high water stamps, which are used to omit infos the peer already
+// has from subsequent deltas.
 func (c *client) gossip(g *Gossip, stopper *util.Stopper) error {
 	localMaxSeq := int64(0)
 	remoteMaxSeq := int64(-1)
+	var remoteStamps []proto.HighWaterStamp
 	for {
 		// Compute the delta of local node's infostore to send with request.
 		g.mu.Lock()
-		delta := g.is.delta(c.peerID, localMaxSeq)
+		var delta *infoStore
+		if remoteMaxSeq != -1 {
+			delta = g.is.delta(c.peerID, localMaxSeq, remoteStamps)
+		}
+		stamps := g.is.highWaterStamps()
 		nodeID := g.is.NodeID // needs to be accessed with the lock held
 		g.mu.Unlock()
 		var deltaBytes []byte
@@ -119,11 +128,12 @@ func (c *client) gossip(g *Gossip, stopper *util.Stopper) error {
 
 		// Send gossip with timeout.
 		args := &proto.GossipRequest{
-			NodeID: nodeID,
-			Addr:   *proto.FromNetAddr(g.is.NodeAddr),
-			LAddr:  *proto.FromNetAddr(c.rpcClient.LocalAddr()),
-			MaxSeq: remoteMaxSeq,
-			Delta:  deltaBytes,
+			NodeID:          nodeID,
+			Addr:            *proto.FromNetAddr(g.is.NodeAddr),
+			LAddr:           *proto.FromNetAddr(c.rpcClient.LocalAddr()),
+			MaxSeq:          remoteMaxSeq,
+			Delta:           deltaBytes,
+			HighWaterStamps: stamps,
 		}
 		reply := &proto.GossipResponse{}
 		gossipCall := c.rpcClient.Go("Gossip.Gossip", args, reply, nil)
@@ -138,8 +148,8 @@ func (c *client) gossip(g *Gossip, stopper *util.Stopper) error {
 			return nil
 		case <-stopper.ShouldStop():
 			return nil
-		case <-time.After(g.interval * 10):
-			return util.Errorf("timeout after: %s", g.interval*10)
+		case <-time.After(g.gossipInterval() * 10):
+			return util.Errorf("timeout after: %s", g.gossipInterval()*10)
 		}
 
 		// Handle remote forwarding.
@@ -152,6 +162,7 @@ func (c *client) gossip(g *Gossip, stopper *util.Stopper) error {
 		}
 
 		// Combine remote node's infostore delta with ours.
+		remoteStamps = reply.HighWaterStamps
 		now := time.Now().UnixNano()
 		if reply.Delta != nil {
 			delta := &infoStore{}
@@ -0,0 +1,351 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package gossip
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+)
+
+const (
+	// defaultCloudPort is the port joined with the addresses of the
+	// instances found by cloud resolvers whose address doesn't name
+	// one; it's that of a node's default -addr.
+	defaultCloudPort = "8080"
+	// cloudAPITimeout bounds each request made to a metadata server or
+	// cloud provider API.
+	cloudAPITimeout = 10 * time.Second
+	// ec2APIVersion is the version of the EC2 query API used to
+	// describe instances.
+	ec2APIVersion = "2016-11-15"
+)
+
+// cloudAddrPrefixes maps the types of cloud resolvers to the prefixes
+// of their addresses given without a type, as in
+// "aws-tag://Name=cockroach".
+var cloudAddrPrefixes = map[string]string{
+	"aws-tag":   "aws-tag://",
+	"gce-label": "gce-label://",
+}
+
+// The endpoints queried by cloud resolvers. They're variables so that
+// tests may point them at local servers.
+var (
+	awsMetadataURL = "http://169.254.169.254/latest/meta-data/"
+	awsEC2URL      = func(region string) string { return "https://ec2." + region + ".amazonaws.com/" }
+	gceMetadataURL = "http://metadata.google.internal/computeMetadata/v1/"
+	gceComputeURL  = "https://www.googleapis.com/compute/v1/"
+)
+
+var cloudHTTPClient = &http.Client{Timeout: cloudAPITimeout}
+
+// newCloudResolver returns a resolver of the given cloud type which
+// yields the private addresses of the running instances with the tag
+// or label of its address, which is of the form key=value[:port]. The
+// port is only looked for after the "=", as tag keys such as
+// "aws:cloudformation:stack-name" may hold colons.
+func newCloudResolver(typ, addr string) (Resolver, error) {
+	parts := strings.SplitN(addr, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return nil, util.Errorf("%s resolver address %q must be of the form key=value[:port]", typ, addr)
+	}
+	key, value, port := parts[0], parts[1], defaultCloudPort
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		if _, err := strconv.ParseUint(value[i+1:], 10, 16); err != nil {
+			return nil, util.Errorf("invalid port in %s resolver address %q", typ, addr)
+		}
+		value, port = value[:i], value[i+1:]
+	}
+	if len(value) == 0 {
+		return nil, util.Errorf("%s resolver address %q must be of the form key=value[:port]", typ, addr)
+	}
+	var lookup func() ([]string, error)
+	switch typ {
+	case "aws-tag":
+		lookup = func() ([]string, error) { return lookupAWSTag(key, value) }
+	case "gce-label":
+		lookup = func() ([]string, error) { return lookupGCELabel(key, value) }
+	default:
+		return nil, util.Errorf("unknown cloud resolver type %q", typ)
+	}
+	return newHostsResolver(typ, addr, port, lookup), nil
+}
+
+// cloudGet sends a GET request for the URL with the given headers and
+// returns the response body, or an error for any status but 200.
+func cloudGet(url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := cloudHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, util.Errorf("GET %s: %s: %s", req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// awsCredentials are the credentials with which EC2 requests are
+// signed. The field names are those of the instance metadata's
+// security credentials.
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	Token           string
+}
+
+// ec2DescribeInstancesResponse holds the fields used of the response
+// to an EC2 DescribeInstances request.
+type ec2DescribeInstancesResponse struct {
+	Reservations []struct {
+		Instances []struct {
+			PrivateIPAddress string `xml:"privateIpAddress"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// lookupAWSTag returns the private addresses of the running EC2
+// instances tagged key=value in the region of this instance, or that
+// of $AWS_REGION. Requests are signed with the credentials in
+// $AWS_ACCESS_KEY_ID, $AWS_SECRET_ACCESS_KEY and $AWS_SESSION_TOKEN if
+// set, or else those of the instance's IAM role.
+func lookupAWSTag(key, value string) ([]string, error) {
+	creds, err := getAWSCredentials()
+	if err != nil {
+		return nil, util.Errorf("unable to get AWS credentials: %s", err)
+	}
+	region := os.Getenv("AWS_REGION")
+	if len(region) == 0 {
+		zone, err := cloudGet(awsMetadataURL+"placement/availability-zone", nil)
+		if err != nil || len(zone) < 2 {
+			return nil, util.Errorf("unable to determine AWS region; set $AWS_REGION: %v", err)
+		}
+		region = string(zone[:len(zone)-1])
+	}
+	endpoint, err := url.Parse(awsEC2URL(region))
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+	var nextToken string
+	for {
+		params := url.Values{
+			"Action":           {"DescribeInstances"},
+			"Version":          {ec2APIVersion},
+			"Filter.1.Name":    {"tag:" + key},
+			"Filter.1.Value.1": {value},
+			"Filter.2.Name":    {"instance-state-name"},
+			"Filter.2.Value.1": {"running"},
+		}
+		if len(nextToken) > 0 {
+			params.Set("NextToken", nextToken)
+		}
+		u := *endpoint
+		// SigV4 requires RFC 3986 escaping, which differs from that of
+		// url.Values only in spaces.
+		u.RawQuery = strings.Replace(params.Encode(), "+", "%20", -1)
+		header := signAWSRequest(creds, region, "ec2", u.Host, u.RawQuery, time.Now())
+		body, err := cloudGet(u.String(), header)
+		if err != nil {
+			return nil, err
+		}
+		var resp ec2DescribeInstancesResponse
+		if err := xml.Unmarshal(body, &resp); err != nil {
+			return nil, util.Errorf("unable to parse DescribeInstances response: %s", err)
+		}
+		for _, r := range resp.Reservations {
+			for _, i := range r.Instances {
+				if len(i.PrivateIPAddress) > 0 {
+					hosts = append(hosts, i.PrivateIPAddress)
+				}
+			}
+		}
+		if nextToken = resp.NextToken; len(nextToken) == 0 {
+			return hosts, nil
+		}
+	}
+}
+
+// getAWSCredentials returns the credentials in the environment, or
+// else those of the role of the instance, from its metadata.
+func getAWSCredentials() (awsCredentials, error) {
+	creds := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		Token:           os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if len(creds.AccessKeyID) > 0 && len(creds.SecretAccessKey) > 0 {
+		return creds, nil
+	}
+	roles, err := cloudGet(awsMetadataURL+"iam/security-credentials/", nil)
+	if err != nil {
+		return creds, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if len(role) == 0 {
+		return creds, util.Errorf("instance has no IAM role")
+	}
+	body, err := cloudGet(awsMetadataURL+"iam/security-credentials/"+role, nil)
+	if err != nil {
+		return creds, err
+	}
+	if err := json.Unmarshal(body, &creds); err != nil {
+		return creds, err
+	}
+	return creds, nil
+}
+
+// signAWSRequest returns the headers which sign a GET request to the
+// root path of host with the given escaped query, as described by
+// AWS Signature Version 4.
+func signAWSRequest(creds awsCredentials, region, service, host, query string, now time.Time) http.Header {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	header := http.Header{}
+	header.Set("X-Amz-Date", amzDate)
+	canonicalHeaders := "host:" + host + "\nx-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-date"
+	if len(creds.Token) > 0 {
+		header.Set("X-Amz-Security-Token", creds.Token)
+		canonicalHeaders += "x-amz-security-token:" + creds.Token + "\n"
+		signedHeaders += ";x-amz-security-token"
+	}
+	canonicalRequest := strings.Join([]string{
+		"GET", "/", query, canonicalHeaders, signedHeaders, hexSHA256(""),
+	}, "\n")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256(canonicalRequest),
+	}, "\n")
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+	return header
+}
+
+func hexSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// gceInstancesResponse holds the fields used of the response to a
+// GCE aggregated instances list request.
+type gceInstancesResponse struct {
+	Items map[string]struct {
+		Instances []struct {
+			Status            string `json:"status"`
+			NetworkInterfaces []struct {
+				NetworkIP string `json:"networkIP"`
+			} `json:"networkInterfaces"`
+		} `json:"instances"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// lookupGCELabel returns the internal addresses of the running GCE
+// instances labeled key=value in any zone of this instance's project,
+// authorized by the token of its default service account.
+func lookupGCELabel(key, value string) ([]string, error) {
+	metadataHeader := http.Header{"Metadata-Flavor": {"Google"}}
+	project, err := cloudGet(gceMetadataURL+"project/project-id", metadataHeader)
+	if err != nil {
+		return nil, util.Errorf("unable to determine GCE project: %s", err)
+	}
+	body, err := cloudGet(gceMetadataURL+"instance/service-accounts/default/token", metadataHeader)
+	if err != nil {
+		return nil, util.Errorf("unable to get GCE service account token: %s", err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, util.Errorf("unable to parse GCE service account token: %s", err)
+	}
+	header := http.Header{"Authorization": {"Bearer " + token.AccessToken}}
+
+	var hosts []string
+	var pageToken string
+	for {
+		// Filter literals are regular expressions which must match the
+		// whole label value.
+		params := url.Values{"filter": {fmt.Sprintf("labels.%s eq %s", key, regexp.QuoteMeta(value))}}
+		if len(pageToken) > 0 {
+			params.Set("pageToken", pageToken)
+		}
+		u := gceComputeURL + "projects/" + url.QueryEscape(strings.TrimSpace(string(project))) +
+			"/aggregated/instances?" + params.Encode()
+		body, err := cloudGet(u, header)
+		if err != nil {
+			return nil, err
+		}
+		var resp gceInstancesResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, util.Errorf("unable to parse GCE instances response: %s", err)
+		}
+		for _, zone := range resp.Items {
+			for _, i := range zone.Instances {
+				if i.Status != "RUNNING" {
+					continue
+				}
+				for _, ni := range i.NetworkInterfaces {
+					if len(ni.NetworkIP) > 0 {
+						hosts = append(hosts, ni.NetworkIP)
+						break
+					}
+				}
+			}
+		}
+		if pageToken = resp.NextPageToken; len(pageToken) == 0 {
+			return hosts, nil
+		}
+	}
+}
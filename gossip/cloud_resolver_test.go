@@ -0,0 +1,209 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package gossip
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startCloudServer starts a server for the metadata and API requests
+// of cloud resolvers, served by handler, and points the resolvers at
+// it until the returned function is called.
+func startCloudServer(handler http.HandlerFunc) func() {
+	ts := httptest.NewServer(handler)
+	oldAWSMetadata, oldAWSEC2, oldGCEMetadata, oldGCECompute := awsMetadataURL, awsEC2URL, gceMetadataURL, gceComputeURL
+	awsMetadataURL = ts.URL + "/aws/meta-data/"
+	awsEC2URL = func(region string) string { return ts.URL + "/ec2/" + region + "/" }
+	gceMetadataURL = ts.URL + "/gce/metadata/"
+	gceComputeURL = ts.URL + "/gce/compute/"
+	return func() {
+		ts.Close()
+		awsMetadataURL, awsEC2URL, gceMetadataURL, gceComputeURL = oldAWSMetadata, oldAWSEC2, oldGCEMetadata, oldGCECompute
+	}
+}
+
+// TestAWSTagResolver verifies that an aws-tag resolver describes the
+// running instances with its tag, in the region of the instance and
+// with the credentials of its role, and yields their private
+// addresses across pages of results.
+func TestAWSTagResolver(t *testing.T) {
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_REGION"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+	const page = `<DescribeInstancesResponse><reservationSet><item><instancesSet>
+<item><privateIpAddress>%s</privateIpAddress></item>
+</instancesSet></item></reservationSet><nextToken>%s</nextToken></DescribeInstancesResponse>`
+	defer startCloudServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/aws/meta-data/placement/availability-zone":
+			fmt.Fprint(w, "us-east-1b")
+		case "/aws/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "cockroach-role\n")
+		case "/aws/meta-data/iam/security-credentials/cockroach-role":
+			fmt.Fprint(w, `{"AccessKeyId": "AKID", "SecretAccessKey": "secret", "Token": "token"}`)
+		case "/ec2/us-east-1/":
+			q := r.URL.Query()
+			if q.Get("Filter.1.Name") != "tag:Name" || q.Get("Filter.1.Value.1") != "cockroach" ||
+				q.Get("Filter.2.Value.1") != "running" {
+				http.Error(w, "unexpected filters: "+r.URL.RawQuery, http.StatusBadRequest)
+				return
+			}
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+				!strings.Contains(auth, "/us-east-1/ec2/aws4_request, SignedHeaders=host;x-amz-date;x-amz-security-token, ") ||
+				r.Header.Get("X-Amz-Security-Token") != "token" {
+				http.Error(w, "unexpected authorization: "+auth, http.StatusForbidden)
+				return
+			}
+			if q.Get("NextToken") == "" {
+				fmt.Fprintf(w, page, "10.0.0.2", "page2")
+			} else {
+				fmt.Fprintf(w, page, "10.0.0.1", "")
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})()
+
+	resolver, err := NewResolver("aws-tag://Name=cockroach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs []string
+	for !resolver.IsExhausted() {
+		addr, err := resolver.GetAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, addr.String())
+	}
+	if expected := []string{"10.0.0.1:8080", "10.0.0.2:8080"}; !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("expected addresses %v; got %v", expected, addrs)
+	}
+}
+
+// TestSignAWSRequest verifies signatures against the
+// get-vanilla-query-order-key-case case of the AWS Signature Version 4
+// test suite.
+func TestSignAWSRequest(t *testing.T) {
+	now, err := time.Parse("20060102T150405Z", "20150830T123600Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	header := signAWSRequest(creds, "us-east-1", "service", "example.amazonaws.com", "Param1=value1&Param2=value2", now)
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"
+	if auth := header.Get("Authorization"); auth != expected {
+		t.Errorf("expected authorization %q; got %q", expected, auth)
+	}
+	if date := header.Get("X-Amz-Date"); date != "20150830T123600Z" {
+		t.Errorf("expected date 20150830T123600Z; got %q", date)
+	}
+}
+
+// TestGCELabelResolver verifies that a gce-label resolver lists the
+// instances with its label in the instance's project, authorized by
+// its service account, and yields the addresses of those running.
+func TestGCELabelResolver(t *testing.T) {
+	defer startCloudServer(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/gce/metadata/") && r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor header", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/gce/metadata/project/project-id":
+			fmt.Fprint(w, "cockroach-project")
+		case "/gce/metadata/instance/service-accounts/default/token":
+			fmt.Fprint(w, `{"access_token": "token", "token_type": "Bearer"}`)
+		case "/gce/compute/projects/cockroach-project/aggregated/instances":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "unexpected authorization", http.StatusUnauthorized)
+				return
+			}
+			if filter := r.URL.Query().Get("filter"); filter != `labels.role eq db\.1` {
+				http.Error(w, "unexpected filter: "+filter, http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"items": {
+"zones/us-central1-a": {"instances": [
+  {"status": "RUNNING", "networkInterfaces": [{"networkIP": "10.0.0.2"}]},
+  {"status": "TERMINATED", "networkInterfaces": [{"networkIP": "10.0.0.3"}]}]},
+"zones/us-central1-b": {"instances": [
+  {"status": "RUNNING", "networkInterfaces": [{"networkIP": "10.0.0.1"}]}]},
+"zones/us-central1-c": {}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})()
+
+	resolver, err := NewResolver("gce-label=role=db.1:26257")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs []string
+	for !resolver.IsExhausted() {
+		addr, err := resolver.GetAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, addr.String())
+	}
+	if expected := []string{"10.0.0.1:26257", "10.0.0.2:26257"}; !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("expected addresses %v; got %v", expected, addrs)
+	}
+}
+
+// TestCloudResolverAddr verifies that the port of a cloud resolver's
+// address is only parsed from the tag or label value, so that keys may
+// hold colons.
+func TestCloudResolverAddr(t *testing.T) {
+	testCases := []struct {
+		addr    string
+		port    string
+		success bool
+	}{
+		{"Name=cockroach", defaultCloudPort, true},
+		{"Name=cockroach:26257", "26257", true},
+		{"aws:cloudformation:stack-name=db", defaultCloudPort, true},
+		{"aws:cloudformation:stack-name=db:26257", "26257", true},
+		{"Name=cockroach:port", "", false},
+		{"Name=cockroach:99999", "", false},
+		{"Name=:26257", "", false},
+		{"=cockroach", "", false},
+		{"cockroach:26257", "", false},
+	}
+	for i, tc := range testCases {
+		resolver, err := newCloudResolver("aws-tag", tc.addr)
+		if (err == nil) != tc.success {
+			t.Errorf("%d: %q: expected success=%t; got %v", i, tc.addr, tc.success, err)
+			continue
+		}
+		if err == nil {
+			if port := resolver.(*hostsResolver).port; port != tc.port {
+				t.Errorf("%d: %q: expected port %s; got %s", i, tc.addr, tc.port, port)
+			}
+		}
+	}
+}
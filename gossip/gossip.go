@@ -70,7 +70,8 @@ import (
 )
 
 const (
-	// MaxPeers is the maximum number of connected gossip peers.
+	// MaxPeers is the default maximum number of outgoing and of
+	// incoming gossip connections. See Gossip.SetMaxPeers.
 	MaxPeers = 10
 	// defaultNodeCount is the default number of nodes in the gossip
 	// network. The actual count of nodes in the cluster is gossiped
@@ -100,9 +101,10 @@ var (
 
 // NodeDescriptor holds details on node physical/network topology.
 type NodeDescriptor struct {
-	NodeID  proto.NodeID
-	Address net.Addr
-	Attrs   proto.Attributes // node specific attributes (e.g. datacenter, machine info)
+	NodeID      proto.NodeID
+	Address     net.Addr
+	HTTPAddress net.Addr         // nil unless HTTP is served apart from Address
+	Attrs       proto.Attributes // node specific attributes (e.g. datacenter, machine info)
 }
 
 func init() {
@@ -125,6 +127,11 @@ type Gossip struct {
 	disconnected  chan *client        // Channel of disconnected clients
 	stalled       chan struct{}       // Channel to wakeup stalled bootstrap
 
+	// Instrumentation of the tightening of the network graph.
+	distantCount    int   // Count of distant nodes found by the last check
+	tightenConnects int64 // Clients started to distant nodes
+	tightenCloses   int64 // Least useful clients closed to make room
+
 	// resolvers is a list of resolvers used to determine
 	// bootstrap hosts for connecting to the gossip network.
 	resolverIdx int
@@ -156,6 +163,29 @@ func New(rpcContext *rpc.Context, gossipInterval time.Duration, resolvers []Reso
 	return g
 }
 
+// SetMaxPeers sets the maximum number of outgoing gossip connections,
+// which is the target fan-out of the gossip network, and the maximum
+// number of incoming connections. A larger fan-out propagates infos
+// over fewer hops at the cost of more connections per node. It must
+// be called before Start.
+func (g *Gossip) SetMaxPeers(outgoing, incoming int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.outgoing.maxSize = outgoing
+	g.incoming.maxSize = incoming
+	g.disconnected = make(chan *client, outgoing)
+}
+
+// SetAdvertiseAddr sets the address at which other nodes reach this
+// one, for nodes whose RPC server is bound to an address peers can't
+// use, e.g. 0.0.0.0 behind NAT. By default the RPC server's address is
+// used. It must be called before Start.
+func (g *Gossip) SetAdvertiseAddr(addr net.Addr) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.is.NodeAddr = addr
+}
+
 // GetNodeID returns the instance's saved NodeID.
 func (g *Gossip) GetNodeID() proto.NodeID {
 	g.mu.Lock()
@@ -322,15 +352,59 @@ func (g *Gossip) Start(rpcServer *rpc.Server, stopper *util.Stopper) {
 
 // maxToleratedHops computes the maximum number of hops which the
 // gossip network should allow when optimally configured. It's based
-// on the level of fanout (MaxPeers) and the count of nodes in the
-// cluster.
+// on the level of fanout (the maximum number of outgoing connections)
+// and the count of nodes in the cluster.
 func (g *Gossip) maxToleratedHops() uint32 {
 	// Get info directly as we have mutex held here.
 	var nodeCount = int64(defaultNodeCount)
 	if info := g.is.getInfo(KeyNodeCount); info != nil {
 		nodeCount = info.Val.(int64)
 	}
-	return uint32(math.Ceil(math.Log(float64(nodeCount))/math.Log(float64(MaxPeers))))*2 + 1
+	fanout := math.Max(float64(g.outgoing.maxSize), 2)
+	return uint32(math.Ceil(math.Log(float64(nodeCount))/math.Log(fanout)))*2 + 1
+}
+
+// Metrics describes the connections of a gossip instance and the
+// tightening of the network graph, by which connections are opened
+// to the originators of infos more than the tolerated number of hops
+// away.
+type Metrics struct {
+	// Incoming and Outgoing are the numbers of connections, and
+	// MaxIncoming and MaxOutgoing their limits.
+	Incoming, MaxIncoming int
+	Outgoing, MaxOutgoing int
+	// RefusedIncoming is the number of incoming clients redirected to an
+	// alternate peer because the maximum was reached.
+	RefusedIncoming int64
+	// MaxHops is the number of hops to the furthest info, and
+	// MaxToleratedHops the number beyond which the graph is tightened.
+	MaxHops, MaxToleratedHops uint32
+	// DistantNodes is the number of unconnected nodes whose infos were
+	// more than the tolerated number of hops away at the last check.
+	DistantNodes int
+	// TightenConnects is the number of clients started to distant
+	// nodes, and TightenCloses the number of least useful clients
+	// closed to make room for them.
+	TightenConnects int64
+	TightenCloses   int64
+}
+
+// Metrics returns the gossip instance's current metrics.
+func (g *Gossip) Metrics() Metrics {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Metrics{
+		Incoming:         g.incoming.len(),
+		MaxIncoming:      g.incoming.maxSize,
+		Outgoing:         g.outgoing.len(),
+		MaxOutgoing:      g.outgoing.maxSize,
+		RefusedIncoming:  g.refused,
+		MaxHops:          g.is.maxHops(),
+		MaxToleratedHops: g.maxToleratedHops(),
+		DistantNodes:     g.distantCount,
+		TightenConnects:  g.tightenConnects,
+		TightenCloses:    g.tightenCloses,
+	}
 }
 
 // hasIncoming returns whether the server has an incoming gossip
@@ -355,31 +429,47 @@ func (g *Gossip) filterExtant(nodes *nodeSet) *nodeSet {
 // getNextBootstrapAddress returns the next available bootstrap
 // address by consulting the first non-exhausted resolver from the
 // slice supplied to the constructor or set using setBootstrap().
+//
+// Resolvers may look their hosts up over the network, as the dns and
+// cloud resolvers do, so they're consulted without holding the mutex,
+// which must not be held by the caller. Only the bootstrap worker
+// calls it, so the resolvers needn't be safe for concurrent use.
 func (g *Gossip) getNextBootstrapAddress() net.Addr {
-	if len(g.resolvers) == 0 {
+	g.mu.Lock()
+	resolvers := g.resolvers
+	g.mu.Unlock()
+	if len(resolvers) == 0 {
 		log.Fatalf("no resolvers specified for gossip network")
 	}
 
 	// Run through resolvers round robin starting at last resolved index.
-	for i := 0; i < len(g.resolvers); i++ {
-		g.resolverIdx = (g.resolverIdx + 1) % len(g.resolvers)
-		if g.resolverIdx == len(g.resolvers)-1 {
+	for i := 0; i < len(resolvers); i++ {
+		g.mu.Lock()
+		g.resolverIdx = (g.resolverIdx + 1) % len(resolvers)
+		if g.resolverIdx == len(resolvers)-1 {
 			g.triedAll = true
 		}
-		resolver := g.resolvers[g.resolverIdx]
+		resolver := resolvers[g.resolverIdx]
+		g.mu.Unlock()
+
 		addr, err := resolver.GetAddress()
 		if err != nil {
 			log.Errorf("invalid bootstrap address: %+v, %v", resolver, err)
 			continue
-		} else if g.is.NodeAddr != nil && addr.String() == g.is.NodeAddr.String() {
+		}
+		g.mu.Lock()
+		if g.is.NodeAddr != nil && addr.String() == g.is.NodeAddr.String() {
 			// Skip our own node address.
+			g.mu.Unlock()
 			continue
 		}
 		_, addrActive := g.bootstrapping[addr.String()]
 		if !resolver.IsExhausted() || !addrActive {
 			g.bootstrapping[addr.String()] = struct{}{}
+			g.mu.Unlock()
 			return addr
 		}
+		g.mu.Unlock()
 	}
 
 	return nil
@@ -402,13 +492,17 @@ func (g *Gossip) bootstrap(stopper *util.Stopper) {
 			// Check whether or not we need bootstrap.
 			haveClients := g.outgoing.len() > 0
 			haveSentinel := g.is.getInfo(KeySentinel) != nil
+			g.mu.Unlock()
 			if !haveClients || !haveSentinel {
 				// Try to get another bootstrap address from the resolvers.
 				if addr := g.getNextBootstrapAddress(); addr != nil {
-					g.startClient(addr, g.bsRPCContext, stopper)
+					g.mu.Lock()
+					if !g.closed {
+						g.startClient(addr, g.bsRPCContext, stopper)
+					}
+					g.mu.Unlock()
 				}
 			}
-			g.mu.Unlock()
 
 			// Block until we need bootstrapping again.
 			select {
@@ -423,8 +517,8 @@ func (g *Gossip) bootstrap(stopper *util.Stopper) {
 
 // manage manages outgoing clients. Periodically, the infostore is
 // scanned for infos with hop count exceeding maxToleratedHops()
-// threshold. If the number of outgoing clients doesn't exceed
-// MaxPeers, a new gossip client is connected to a randomly selected
+// threshold. If the number of outgoing clients doesn't exceed the
+// maximum, a new gossip client is connected to a randomly selected
 // peer beyond maxToleratedHops threshold. Otherwise, the least useful
 // peer node is cut off to make room for a replacement. Disconnected
 // clients are processed via the disconnected channel and taken out of
@@ -433,7 +527,7 @@ func (g *Gossip) bootstrap(stopper *util.Stopper) {
 // is notified via the stalled conditional variable.
 func (g *Gossip) manage(stopper *util.Stopper) {
 	stopper.RunWorker(func() {
-		checkTimeout := time.Tick(g.jitteredGossipInterval())
+		checkTimeout := time.After(g.jitteredGossipInterval())
 		// Loop until closed and there are no remaining outgoing connections.
 		for {
 			select {
@@ -450,10 +544,12 @@ func (g *Gossip) manage(stopper *util.Stopper) {
 				}
 
 			case <-checkTimeout:
+				checkTimeout = time.After(g.jitteredGossipInterval())
 				g.mu.Lock()
 				// Check whether the graph needs to be tightened to
 				// accommodate distant infos.
 				distant := g.filterExtant(g.is.distant(g.maxToleratedHops()))
+				g.distantCount = distant.len()
 				if distant.len() > 0 {
 					// If we have space, start a client immediately.
 					if g.outgoing.hasSpace() {
@@ -461,7 +557,9 @@ func (g *Gossip) manage(stopper *util.Stopper) {
 						if nodeAddr, err := g.getNodeIDAddressLocked(nodeID); err != nil {
 							log.Errorf("node %d: %s", nodeID, err)
 						} else {
+							log.V(1).Infof("starting client to distant node %d to tighten network graph", nodeID)
 							g.startClient(nodeAddr, g.RPCContext, stopper)
+							g.tightenConnects++
 						}
 					} else {
 						// Otherwise, find least useful peer and close it. Make sure
@@ -471,9 +569,13 @@ func (g *Gossip) manage(stopper *util.Stopper) {
 						if nodeID != 0 {
 							log.Infof("closing least useful client %d to tighten network graph", nodeID)
 							g.closeClient(nodeID)
+							g.tightenCloses++
 						}
 					}
 				}
+				if err := g.gossipConnectionsLocked(); err != nil {
+					log.Warningf("unable to gossip connections: %s", err)
+				}
 
 			case <-stopper.ShouldStop():
 				return
@@ -54,6 +54,28 @@ func TestGossipInfoStore(t *testing.T) {
 	}
 }
 
+// TestGossipSetMaxPeers verifies that the connection limits of a
+// gossip instance are reported in its metrics and that the tolerated
+// hop count shrinks as the fan-out grows.
+func TestGossipSetMaxPeers(t *testing.T) {
+	rpcContext := rpc.NewContext(hlc.NewClock(hlc.UnixNano), security.LoadInsecureTLSConfig(), nil)
+	g := New(rpcContext, TestInterval, TestBootstrap)
+	m := g.Metrics()
+	if m.MaxOutgoing != MaxPeers || m.MaxIncoming != MaxPeers {
+		t.Errorf("expected default limits of %d; got %d outgoing, %d incoming", MaxPeers, m.MaxOutgoing, m.MaxIncoming)
+	}
+	defaultHops := m.MaxToleratedHops
+
+	g.SetMaxPeers(100, 20)
+	m = g.Metrics()
+	if m.MaxOutgoing != 100 || m.MaxIncoming != 20 {
+		t.Errorf("expected limits of 100 outgoing, 20 incoming; got %d, %d", m.MaxOutgoing, m.MaxIncoming)
+	}
+	if m.MaxToleratedHops >= defaultHops {
+		t.Errorf("expected fewer tolerated hops than %d with a larger fan-out; got %d", defaultHops, m.MaxToleratedHops)
+	}
+}
+
 // TestGossipGroupsInfoStore verifies gossiping of groups via the
 // gossip instance infostore.
 func TestGossipGroupsInfoStore(t *testing.T) {
@@ -0,0 +1,202 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package gossip
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// ttlConnectionsGossip is the time-to-live of the gossiped connections
+// of a node, after which a node which stopped gossiping drops out of
+// the graphs of the others.
+const ttlConnectionsGossip = time.Minute
+
+// Connections are the gossip connections of a node, gossiped by it so
+// that the graph of the network may be drawn from any node.
+type Connections struct {
+	// Incoming are the node IDs of the clients connected to the node
+	// and Outgoing those of the peers it's connected to.
+	Incoming []proto.NodeID
+	Outgoing []proto.NodeID
+}
+
+func init() {
+	gob.Register(&Connections{})
+}
+
+// A GraphNode is a node of the gossip network known to the graphing
+// node through the infos originated by it.
+type GraphNode struct {
+	NodeID  proto.NodeID `json:"node_id"`
+	Address string       `json:"address,omitempty"`
+	// Incoming and Outgoing are the node's connections, as last gossiped
+	// by it; they're the current ones for the graphing node.
+	Incoming []proto.NodeID `json:"incoming"`
+	Outgoing []proto.NodeID `json:"outgoing"`
+	// AgeNanos is the age of the newest info originated by the node,
+	// which grows while the node is partitioned from the graphing one.
+	AgeNanos int64 `json:"age_nanos"`
+	// Hops is the fewest hops over which an info from the node reached
+	// the graphing node.
+	Hops uint32 `json:"hops"`
+}
+
+// A GraphInfo describes an info held by the graphing node.
+type GraphInfo struct {
+	Key string `json:"key"`
+	// NodeID is the node which originated the info.
+	NodeID   proto.NodeID `json:"node_id"`
+	Hops     uint32       `json:"hops"`
+	AgeNanos int64        `json:"age_nanos"`
+}
+
+// A Graph is the gossip network as seen by one of its nodes.
+type Graph struct {
+	// NodeID is the graphing node.
+	NodeID proto.NodeID `json:"node_id"`
+	// Nodes are sorted by node ID and Infos by key.
+	Nodes []GraphNode `json:"nodes"`
+	Infos []GraphInfo `json:"infos"`
+}
+
+// Graph returns the gossip network as seen by this node: the nodes
+// which originated the infos it holds, with the connections they
+// gossiped, and the hops and age of each info.
+func (g *Gossip) Graph() Graph {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now().UnixNano()
+	graph := Graph{NodeID: g.is.NodeID, Nodes: []GraphNode{}, Infos: []GraphInfo{}}
+	nodes := map[proto.NodeID]*GraphNode{}
+	_ = g.is.visitInfos(nil, func(i *info) error {
+		graph.Infos = append(graph.Infos, GraphInfo{Key: i.Key, NodeID: i.NodeID, Hops: i.Hops, AgeNanos: now - i.Timestamp})
+		// Infos added before a node's ID is known have no node.
+		if i.NodeID == 0 {
+			return nil
+		}
+		n, ok := nodes[i.NodeID]
+		if !ok {
+			n = &GraphNode{NodeID: i.NodeID, Incoming: []proto.NodeID{}, Outgoing: []proto.NodeID{}, AgeNanos: now - i.Timestamp, Hops: i.Hops}
+			nodes[i.NodeID] = n
+		}
+		if age := now - i.Timestamp; age < n.AgeNanos {
+			n.AgeNanos = age
+		}
+		if i.Hops < n.Hops {
+			n.Hops = i.Hops
+		}
+		switch v := i.Val.(type) {
+		case *NodeDescriptor:
+			if v.Address != nil {
+				n.Address = v.Address.String()
+			}
+		case *Connections:
+			n.Incoming, n.Outgoing = v.Incoming, v.Outgoing
+		}
+		return nil
+	})
+	if n, ok := nodes[g.is.NodeID]; ok {
+		n.Incoming, n.Outgoing = g.connectionsLocked()
+	}
+	for _, n := range nodes {
+		graph.Nodes = append(graph.Nodes, *n)
+	}
+	sort.Sort(graphNodesByID(graph.Nodes))
+	sort.Sort(graphInfosByKey(graph.Infos))
+	return graph
+}
+
+// DOT returns the graph in the DOT language of Graphviz, with an edge
+// from each node to each of the peers it's connected to. Nodes are
+// labeled with their age and the hops from the graphing node, which
+// is drawn doubled; a node whose outgoing connection isn't matched by
+// an incoming one of its peer, as happens while a partition forms or
+// heals, has the edge drawn dashed.
+func (gr Graph) DOT() string {
+	incoming := map[proto.NodeID]map[proto.NodeID]bool{}
+	for _, n := range gr.Nodes {
+		incoming[n.NodeID] = map[proto.NodeID]bool{}
+		for _, id := range n.Incoming {
+			incoming[n.NodeID][id] = true
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("digraph gossip {\n")
+	for _, n := range gr.Nodes {
+		shape := "ellipse"
+		if n.NodeID == gr.NodeID {
+			shape = "doublecircle"
+		}
+		fmt.Fprintf(&buf, "  %d [shape=%s, label=\"node %d\\n%s\\n%s\\n%d hops\"];\n", n.NodeID, shape, n.NodeID,
+			n.Address, time.Duration(n.AgeNanos).String(), n.Hops)
+	}
+	for _, n := range gr.Nodes {
+		for _, id := range n.Outgoing {
+			style := "solid"
+			if peer, ok := incoming[id]; !ok || !peer[n.NodeID] {
+				style = "dashed"
+			}
+			fmt.Fprintf(&buf, "  %d -> %d [style=%s];\n", n.NodeID, id, style)
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// connectionsLocked returns the node's incoming and outgoing gossip
+// connections, sorted by node ID. The mutex is assumed held by the
+// caller.
+func (g *Gossip) connectionsLocked() (incoming, outgoing []proto.NodeID) {
+	incoming, outgoing = g.incoming.asSlice(), g.outgoing.asSlice()
+	sort.Sort(nodeIDsByValue(incoming))
+	sort.Sort(nodeIDsByValue(outgoing))
+	return incoming, outgoing
+}
+
+// gossipConnectionsLocked gossips the node's connections, once its
+// node ID is known. The mutex is assumed held by the caller.
+func (g *Gossip) gossipConnectionsLocked() error {
+	if g.is.NodeID == 0 {
+		return nil
+	}
+	incoming, outgoing := g.connectionsLocked()
+	val := &Connections{Incoming: incoming, Outgoing: outgoing}
+	return g.is.addInfo(g.is.newInfo(MakeConnectionsKey(g.is.NodeID), val, ttlConnectionsGossip))
+}
+
+type graphNodesByID []GraphNode
+
+func (g graphNodesByID) Len() int           { return len(g) }
+func (g graphNodesByID) Less(i, j int) bool { return g[i].NodeID < g[j].NodeID }
+func (g graphNodesByID) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+
+type graphInfosByKey []GraphInfo
+
+func (g graphInfosByKey) Len() int           { return len(g) }
+func (g graphInfosByKey) Less(i, j int) bool { return g[i].Key < g[j].Key }
+func (g graphInfosByKey) Swap(i, j int)      { g[i], g[j] = g[j], g[i] }
+
+type nodeIDsByValue []proto.NodeID
+
+func (n nodeIDsByValue) Len() int           { return len(n) }
+func (n nodeIDsByValue) Less(i, j int) bool { return n[i] < n[j] }
+func (n nodeIDsByValue) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
@@ -0,0 +1,84 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package gossip
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/rpc"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+)
+
+// TestGossipGraph verifies that the graph of the gossip network holds
+// the gossiped connections and hops of the nodes originating infos,
+// and that unreturned connections are drawn dashed.
+func TestGossipGraph(t *testing.T) {
+	rpcContext := rpc.NewContext(hlc.NewClock(hlc.UnixNano), security.LoadInsecureTLSConfig(), nil)
+	g := New(rpcContext, TestInterval, TestBootstrap)
+	addr := util.MakeRawAddr("tcp", "localhost:1")
+	if err := g.SetNodeDescriptor(&NodeDescriptor{NodeID: 1, Address: &addr}); err != nil {
+		t.Fatal(err)
+	}
+
+	g.mu.Lock()
+	g.incoming.addNode(2)
+	g.outgoing.addNode(3)
+	if err := g.gossipConnectionsLocked(); err != nil {
+		t.Fatal(err)
+	}
+	remote := g.is.newInfo(MakeConnectionsKey(2), &Connections{Outgoing: []proto.NodeID{1}}, time.Hour)
+	remote.NodeID, remote.Hops = 2, 2
+	if err := g.is.addInfo(remote); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Unlock()
+
+	graph := g.Graph()
+	if graph.NodeID != 1 || len(graph.Nodes) != 2 {
+		t.Fatalf("expected graph of nodes 1 and 2 by node 1; got %+v", graph)
+	}
+	expNodes := []struct {
+		address            string
+		incoming, outgoing []proto.NodeID
+		hops               uint32
+	}{
+		{"localhost:1", []proto.NodeID{2}, []proto.NodeID{3}, 0},
+		{"", []proto.NodeID{}, []proto.NodeID{1}, 2},
+	}
+	for i, exp := range expNodes {
+		n := graph.Nodes[i]
+		if n.Address != exp.address || !reflect.DeepEqual(n.Incoming, exp.incoming) ||
+			!reflect.DeepEqual(n.Outgoing, exp.outgoing) || n.Hops != exp.hops {
+			t.Errorf("%d: expected %+v; got %+v", i, exp, n)
+		}
+	}
+	if len(graph.Infos) != 3 || graph.Infos[1].Key != MakeConnectionsKey(2) || graph.Infos[1].Hops != 2 {
+		t.Errorf("expected connection and node infos; got %+v", graph.Infos)
+	}
+
+	dot := graph.DOT()
+	for _, exp := range []string{"1 [shape=doublecircle", "2 -> 1 [style=solid]", "1 -> 3 [style=dashed]"} {
+		if !strings.Contains(dot, exp) {
+			t.Errorf("expected %q in graph:\n%s", exp, dot)
+		}
+	}
+}
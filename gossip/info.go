@@ -100,3 +100,12 @@ type infoSlice []*info
 func (a infoSlice) Len() int           { return len(a) }
 func (a infoSlice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a infoSlice) Less(i, j int) bool { return a[i].less(a[j]) }
+
+// infosBySeq is a slice of info object pointers sorted by sequence
+// number.
+type infosBySeq []*info
+
+// Implement sort.Interface for infosBySeq.
+func (a infosBySeq) Len() int           { return len(a) }
+func (a infosBySeq) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a infosBySeq) Less(i, j int) bool { return a[i].seq < a[j].seq }
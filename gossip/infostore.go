@@ -24,6 +24,7 @@ import (
 	"net"
 	"reflect"
 	"regexp"
+	"sort"
 	"sync"
 	"time"
 
@@ -32,6 +33,10 @@ import (
 	"github.com/cockroachdb/cockroach/util/log"
 )
 
+// maxInfosPerDelta is the maximum number of infos sent in a single
+// gossip exchange. Larger deltas are sent over successive exchanges.
+const maxInfosPerDelta = 500
+
 // callback holds regexp pattern match and GossipCallback method.
 type callback struct {
 	pattern *regexp.Regexp
@@ -368,32 +373,69 @@ func (is *infoStore) combine(delta *infoStore) int {
 	return freshCount
 }
 
+// highWaterStamps returns the timestamp of the newest info originated
+// by each node.
+func (is *infoStore) highWaterStamps() []proto.HighWaterStamp {
+	stamps := map[proto.NodeID]int64{}
+	is.visitInfos(nil, func(i *info) error {
+		if i.Timestamp > stamps[i.NodeID] {
+			stamps[i.NodeID] = i.Timestamp
+		}
+		return nil
+	})
+	result := make([]proto.HighWaterStamp, 0, len(stamps))
+	for nodeID, stamp := range stamps {
+		result = append(result, proto.HighWaterStamp{NodeID: nodeID, Stamp: stamp})
+	}
+	return result
+}
+
 // delta returns an incremental delta of infos added to the info store
 // since (not including) the specified sequence number. These deltas
 // are intended for efficiently updating peer nodes. Any infos passed
-// from node requesting delta are ignored.
+// from node requesting delta are ignored, as are infos no newer than
+// the requesting node's high water stamp for their originating node,
+// which the requesting node is assumed to have received already.
+//
+// A delta holds at most maxInfosPerDelta infos, those with the lowest
+// sequence numbers. The MaxSeq of a truncated delta is that of its
+// last info, so the remaining infos are included in the next delta.
 //
 // Returns nil if there are no deltas.
-func (is *infoStore) delta(nodeID proto.NodeID, seq int64) *infoStore {
+func (is *infoStore) delta(nodeID proto.NodeID, seq int64, highWaterStamps []proto.HighWaterStamp) *infoStore {
 	if seq >= is.MaxSeq {
 		return nil
 	}
+	stamps := make(map[proto.NodeID]int64, len(highWaterStamps))
+	for _, hws := range highWaterStamps {
+		stamps[hws.NodeID] = hws.Stamp
+	}
 
 	delta := newInfoStore(is.NodeID, is.NodeAddr)
 
 	// Compute delta of groups and infos.
+	var fresh infosBySeq
 	is.visitInfos(func(g *group) error {
 		gDelta := newGroup(g.Prefix, g.Limit, g.TypeOf)
 		delta.registerGroup(gDelta)
 		return nil
 	}, func(i *info) error {
-		if i.isFresh(nodeID, seq) {
-			delta.addInfo(i)
+		if i.isFresh(nodeID, seq) && i.Timestamp > stamps[i.NodeID] {
+			fresh = append(fresh, i)
 		}
 		return nil
 	})
 
-	delta.MaxSeq = is.MaxSeq
+	maxSeq := is.MaxSeq
+	if len(fresh) > maxInfosPerDelta {
+		sort.Sort(fresh)
+		fresh = fresh[:maxInfosPerDelta]
+		maxSeq = fresh[len(fresh)-1].seq
+	}
+	for _, i := range fresh {
+		delta.addInfo(i)
+	}
+	delta.MaxSeq = maxSeq
 	return delta
 }
 
@@ -382,7 +382,7 @@ func TestInfoStoreDelta(t *testing.T) {
 
 	// Verify deltas with successive sequence numbers.
 	for i := 0; i < 10; i++ {
-		delta := is.delta(2, int64(i*3))
+		delta := is.delta(2, int64(i*3), nil)
 		infosA := delta.getGroupInfos("a")
 		infosB := delta.getGroupInfos("b")
 		if len(infosA) != 10-i || len(infosB) != 10-i {
@@ -409,11 +409,83 @@ func TestInfoStoreDelta(t *testing.T) {
 		}
 	}
 
-	if delta := is.delta(2, int64(30)); delta != nil {
+	if delta := is.delta(2, int64(30), nil); delta != nil {
 		t.Error("fetching delta of infostore at maximum sequence number should return nil")
 	}
 }
 
+// TestInfoStoreHighWaterStamps verifies that the high water stamps of
+// an infostore hold the timestamp of the newest info from each
+// originating node, and that deltas omit infos no newer.
+func TestInfoStoreHighWaterStamps(t *testing.T) {
+	is := newInfoStore(1, emptyAddr)
+	for i := 0; i < 4; i++ {
+		inf := is.newInfo(fmt.Sprintf("a.%d", i), float64(i), time.Second)
+		inf.NodeID = proto.NodeID(i%2 + 1)
+		if err := is.addInfo(inf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stamps := is.highWaterStamps()
+	if len(stamps) != 2 {
+		t.Fatalf("expected 2 high water stamps; got %+v", stamps)
+	}
+	for _, hws := range stamps {
+		// Node 1 originated a.0 and a.2; node 2 originated a.1 and a.3.
+		exp := is.getInfo(fmt.Sprintf("a.%d", hws.NodeID+1)).Timestamp
+		if hws.Stamp != exp {
+			t.Errorf("node %d: expected high water stamp %d; got %d", hws.NodeID, exp, hws.Stamp)
+		}
+	}
+
+	// A peer which has received a.1 from node 2 is sent a.3 only,
+	// along with all of node 1's infos.
+	peerStamps := []proto.HighWaterStamp{{NodeID: 2, Stamp: is.getInfo("a.1").Timestamp}}
+	delta := is.delta(3, 0, peerStamps)
+	if delta.getInfo("a.1") != nil {
+		t.Error("expected delta to omit info a.1")
+	}
+	for _, key := range []string{"a.0", "a.2", "a.3"} {
+		if delta.getInfo(key) == nil {
+			t.Errorf("expected delta to include info %s", key)
+		}
+	}
+	if delta.MaxSeq != is.MaxSeq {
+		t.Errorf("expected delta max seq %d; got %d", is.MaxSeq, delta.MaxSeq)
+	}
+}
+
+// TestInfoStoreDeltaTruncated verifies that deltas hold at most
+// maxInfosPerDelta infos and that successive deltas hold the rest.
+func TestInfoStoreDeltaTruncated(t *testing.T) {
+	is := newInfoStore(1, emptyAddr)
+	count := maxInfosPerDelta*2 + 1
+	for i := 0; i < count; i++ {
+		if err := is.addInfo(is.newInfo(fmt.Sprintf("a.%d", i), float64(i), time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var seq int64
+	var total int
+	for _, exp := range []int{maxInfosPerDelta, maxInfosPerDelta, 1} {
+		delta := is.delta(2, seq, nil)
+		if delta == nil {
+			t.Fatalf("expected delta after seq %d", seq)
+		}
+		if n := int(delta.infoCount()); n != exp {
+			t.Errorf("expected %d infos in delta; got %d", exp, n)
+		}
+		total += int(delta.infoCount())
+		seq = delta.MaxSeq
+	}
+	if total != count {
+		t.Errorf("expected %d infos in all; got %d", count, total)
+	}
+	if delta := is.delta(2, seq, nil); delta != nil {
+		t.Errorf("expected no delta after final seq %d; got %s", seq, delta)
+	}
+}
+
 // TestInfoStoreDistant verifies selection of infos from store with
 // Hops > maxHops.
 func TestInfoStoreDistant(t *testing.T) {
@@ -39,6 +39,10 @@ const (
 	// KeyConfigAccounting is the accounting configuration map.
 	KeyConfigAccounting = "accounting"
 
+	// KeyConfigBalance is the cluster's balance thresholds. The value
+	// is a *proto.BalanceConfig.
+	KeyConfigBalance = "balance"
+
 	// KeyConfigPermission is the permission configuration map.
 	KeyConfigPermission = "permissions"
 
@@ -50,6 +54,11 @@ const (
 	// The value is a storage.StoreDescriptor struct.
 	KeyMaxAvailCapacityPrefix = "max-avail-capacity"
 
+	// KeyConnectionsPrefix is the key prefix for gossiping the gossip
+	// connections of each node. The suffix is the node ID; the value is
+	// a Connections struct.
+	KeyConnectionsPrefix = "gossip-connections"
+
 	// KeyNodeCount is the count of gossip nodes in the network. The
 	// value is an int64 containing the count of nodes in the cluster.
 	// TODO(spencer): should remove this and instead just count the
@@ -93,6 +102,12 @@ func MakeNodeIDKey(nodeID proto.NodeID) string {
 	return MakeKey(KeyNodeIDPrefix, nodeID.String())
 }
 
+// MakeConnectionsKey returns the gossip key for the given node's
+// gossip connections.
+func MakeConnectionsKey(nodeID proto.NodeID) string {
+	return MakeKey(KeyConnectionsPrefix, nodeID.String())
+}
+
 // MakeMaxAvailCapacityKey returns the gossip key for the given store's capacity.
 func MakeMaxAvailCapacityKey(nodeID proto.NodeID, storeID proto.StoreID) string {
 	return MakeKey(KeyMaxAvailCapacityPrefix, nodeID.String(), storeID.String())
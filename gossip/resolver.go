@@ -18,13 +18,25 @@
 package gossip
 
 import (
+	"math/rand"
 	"net"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 )
 
+const (
+	// dnsAddrPrefix prefixes the addresses of "dns" resolvers given
+	// without a type, as in "dns://cockroach.example.com:8080".
+	dnsAddrPrefix = "dns://"
+	// resolveInterval is how long the addresses found by a "dns" or
+	// cloud resolver are used before they're looked up again.
+	resolveInterval = 30 * time.Second
+)
+
 // Resolver is an interface which provides an abstract factory for
 // net.Addr addresses.
 type Resolver interface {
@@ -38,9 +50,10 @@ type Resolver interface {
 // address resolvers. Based on the Type, it may return the same
 // address multiple times (eg: "lb") or not (eg: "tcp").
 type socketResolver struct {
-	typ       string
-	addr      string
-	exhausted bool // Can we try this resolver again?
+	typ        string
+	addr       string
+	exhausted  bool                                // Can we try this resolver again?
+	lookupHost func(host string) ([]string, error) // Resolves hosts if not nil
 }
 
 // Type returns the resolver type.
@@ -60,27 +73,135 @@ func (sr *socketResolver) GetAddress() (net.Addr, error) {
 		sr.exhausted = true
 		return addr, nil
 	case "tcp", "lb":
-		_, err := net.ResolveTCPAddr("tcp", sr.addr)
-		if err != nil {
+		addr := sr.addr
+		if sr.lookupHost != nil {
+			var err error
+			if addr, err = sr.resolveHost(); err != nil {
+				return nil, err
+			}
+		} else if _, err := net.ResolveTCPAddr("tcp", sr.addr); err != nil {
 			return nil, err
 		}
 		if sr.typ == "tcp" {
 			// "tcp" resolvers point to a single host. "lb" have an unknown of number of backends.
 			sr.exhausted = true
 		}
-		return util.MakeRawAddr("tcp", sr.addr), nil
+		return util.MakeRawAddr("tcp", addr), nil
 	}
 	return nil, util.Errorf("unknown address type: %q", sr.typ)
 }
 
+// resolveHost resolves the host of the resolver's address with its
+// lookupHost function, returning the first address found, or a random
+// one for "lb" resolvers, joined with the port.
+func (sr *socketResolver) resolveHost() (string, error) {
+	host, port, err := net.SplitHostPort(sr.addr)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := sr.lookupHost(host)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", util.Errorf("no addresses found for host %q", host)
+	}
+	addr := addrs[0]
+	if sr.typ == "lb" {
+		addr = addrs[rand.Intn(len(addrs))]
+	}
+	return net.JoinHostPort(addr, port), nil
+}
+
 // IsExhausted returns whether the resolver can yield further
 // addresses.
 func (sr *socketResolver) IsExhausted() bool { return sr.exhausted }
 
+// hostsResolver yields each of the addresses found by its lookup
+// function in turn, joined with its port: those of the A records of a
+// host name, as served for a headless service or by round-robin DNS,
+// for "dns" resolvers, and those of the instances with a tag or label
+// for the cloud resolvers. The lookup is repeated once every address
+// has been yielded or resolveInterval has passed, so that nodes which
+// join or leave the set are picked up.
+type hostsResolver struct {
+	typ      string
+	addr     string
+	port     string
+	lookup   func() ([]string, error)
+	interval time.Duration
+	now      func() time.Time
+
+	addrs      []string  // Addresses of the last lookup, sorted
+	next       int       // Index into addrs of the next address to yield
+	resolvedAt time.Time // Time of the last lookup
+}
+
+// newHostsResolver returns a hostsResolver of the given type and
+// address which yields the hosts found by lookup joined with port.
+func newHostsResolver(typ, addr, port string, lookup func() ([]string, error)) *hostsResolver {
+	return &hostsResolver{
+		typ:      typ,
+		addr:     addr,
+		port:     port,
+		lookup:   lookup,
+		interval: resolveInterval,
+		now:      time.Now,
+	}
+}
+
+// Type returns the resolver type.
+func (hr *hostsResolver) Type() string { return hr.typ }
+
+// Addr returns the resolver address.
+func (hr *hostsResolver) Addr() string { return hr.addr }
+
+// GetAddress returns the next address of the last lookup, looking the
+// hosts up first if all have been returned or the lookup is stale.
+func (hr *hostsResolver) GetAddress() (net.Addr, error) {
+	if hr.next >= len(hr.addrs) || hr.now().Sub(hr.resolvedAt) >= hr.interval {
+		if err := hr.resolve(); err != nil {
+			return nil, err
+		}
+	}
+	addr := hr.addrs[hr.next]
+	hr.next++
+	return util.MakeRawAddr("tcp", addr), nil
+}
+
+// resolve looks up the resolver's hosts.
+func (hr *hostsResolver) resolve() error {
+	hosts, err := hr.lookup()
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		return util.Errorf("no addresses found for %s resolver %q", hr.typ, hr.addr)
+	}
+	sort.Strings(hosts)
+	hr.addrs = hr.addrs[:0]
+	for _, h := range hosts {
+		hr.addrs = append(hr.addrs, net.JoinHostPort(h, hr.port))
+	}
+	hr.next = 0
+	hr.resolvedAt = hr.now()
+	return nil
+}
+
+// IsExhausted returns whether every address of the last lookup has
+// been returned. Any address may be returned again, after the hosts
+// are looked up again.
+func (hr *hostsResolver) IsExhausted() bool {
+	return !hr.resolvedAt.IsZero() && hr.next >= len(hr.addrs)
+}
+
 var validTypes = map[string]struct{}{
-	"tcp":  struct{}{},
-	"lb":   struct{}{},
-	"unix": struct{}{},
+	"tcp":       struct{}{},
+	"lb":        struct{}{},
+	"unix":      struct{}{},
+	"dns":       struct{}{},
+	"aws-tag":   struct{}{},
+	"gce-label": struct{}{},
 }
 
 // NewResolver takes a resolver specification and returns a new resolver.
@@ -89,14 +210,50 @@ var validTypes = map[string]struct{}{
 // - tcp: plain hostname of ip address
 // - lb: load balancer host name or ip: points to an unknown number of backends
 // - unix: unix sockets
-// If "network type" is not specified, "tcp" is assumed.
+// - dns: host name resolving to the addresses of many nodes, which are
+//   each used in turn and resolved again periodically
+// - aws-tag: key=value[:port] tag of the EC2 instances whose private
+//   addresses are each used in turn and looked up again periodically
+// - gce-label: key=value[:port] label of the GCE instances whose
+//   internal addresses are used as for aws-tag
+// If "network type" is not specified, "tcp" is assumed, unless the
+// address is a unix socket path prefixed by util.UnixAddrPrefix, a
+// host name prefixed by "dns://" or a tag or label prefixed by
+// "aws-tag://" or "gce-label://".
 func NewResolver(spec string) (Resolver, error) {
+	return NewResolverWithLookup(spec, nil)
+}
+
+// NewResolverWithLookup is like NewResolver, but the hosts of "tcp",
+// "lb" and "dns" resolvers are resolved by lookupHost, which has the signature
+// of net.LookupHost, if it's not nil. This allows tests and
+// deployments with split-horizon DNS to control how addresses resolve.
+func NewResolverWithLookup(spec string, lookupHost func(host string) ([]string, error)) (Resolver, error) {
+	spec = strings.TrimSpace(spec)
+	for typ, prefix := range cloudAddrPrefixes {
+		if strings.HasPrefix(spec, prefix) {
+			spec = typ + "=" + strings.TrimPrefix(spec, prefix)
+		}
+	}
 	parts := strings.Split(spec, "=")
+	if _, ok := cloudAddrPrefixes[strings.TrimSpace(parts[0])]; ok && len(parts) == 3 {
+		// The addresses of cloud resolvers are themselves of the form
+		// key=value.
+		parts = []string{parts[0], parts[1] + "=" + parts[2]}
+	}
 	var typ, addr string
 	if len(parts) == 1 {
-		// No type specified: assume "tcp".
+		// No type specified: assume "tcp" unless the address names a
+		// unix socket.
 		typ = "tcp"
 		addr = strings.TrimSpace(parts[0])
+		if strings.HasPrefix(addr, util.UnixAddrPrefix) {
+			typ = "unix"
+			addr = strings.TrimPrefix(addr, util.UnixAddrPrefix)
+		} else if strings.HasPrefix(addr, dnsAddrPrefix) {
+			typ = "dns"
+			addr = strings.TrimPrefix(addr, dnsAddrPrefix)
+		}
 	} else if len(parts) == 2 {
 		typ = strings.TrimSpace(parts[0])
 		addr = strings.TrimSpace(parts[1])
@@ -120,7 +277,23 @@ func NewResolver(spec string) (Resolver, error) {
 		addr = util.EnsureHost(addr)
 	}
 
-	return &socketResolver{typ: typ, addr: addr}, nil
+	if typ == "dns" {
+		// The port can't be filled in from the records, so it's checked
+		// now rather than on every resolution.
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || len(host) == 0 {
+			return nil, util.Errorf("dns resolver spec %q must name a host and port", spec)
+		}
+		if lookupHost == nil {
+			lookupHost = net.LookupHost
+		}
+		return newHostsResolver(typ, addr, port, func() ([]string, error) { return lookupHost(host) }), nil
+	}
+	if _, ok := cloudAddrPrefixes[typ]; ok {
+		return newCloudResolver(typ, addr)
+	}
+
+	return &socketResolver{typ: typ, addr: addr, lookupHost: lookupHost}, nil
 }
 
 // NewResolverFromAddress takes a net.Addr and contructs a resolver.
@@ -18,7 +18,9 @@
 package gossip
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/util"
 )
@@ -37,6 +39,17 @@ func TestParseResolverSpec(t *testing.T) {
 		{"tcp=127.0.0.1", true, "tcp", "127.0.0.1"},
 		{"lb=127.0.0.1", true, "lb", "127.0.0.1"},
 		{"unix=/tmp/unix-socket12345", true, "unix", "/tmp/unix-socket12345"},
+		{"unix:///tmp/unix-socket12345", true, "unix", "/tmp/unix-socket12345"},
+		{"dns=nodes.example.com:8080", true, "dns", "nodes.example.com:8080"},
+		{"dns://nodes.example.com:8080", true, "dns", "nodes.example.com:8080"},
+		{"dns=nodes.example.com", false, "", ""},
+		{"dns=:8080", false, "", ""},
+		{"aws-tag://Name=cockroach", true, "aws-tag", "Name=cockroach"},
+		{"aws-tag=Name=cockroach:26257", true, "aws-tag", "Name=cockroach:26257"},
+		{"gce-label://role=db", true, "gce-label", "role=db"},
+		{"gce-label=role", false, "", ""},
+		{"gce-label=role=db:port", false, "", ""},
+		{"tcp=a=b", false, "", ""},
 		{"", false, "", ""},
 		{"foo=127.0.0.1", false, "", ""},
 		{"lb=", false, "", ""},
@@ -100,3 +113,114 @@ func TestGetAddress(t *testing.T) {
 		}
 	}
 }
+
+// TestGetAddressWithLookup verifies that hosts are resolved with a
+// supplied lookup function.
+func TestGetAddressWithLookup(t *testing.T) {
+	lookupHost := func(host string) ([]string, error) {
+		switch host {
+		case "node1":
+			return []string{"10.0.0.1"}, nil
+		case "backends":
+			return []string{"10.0.1.1", "10.0.1.2"}, nil
+		}
+		return nil, nil
+	}
+	testCases := []struct {
+		resolverSpec string
+		success      bool
+		addresses    []string
+	}{
+		{"tcp=node1:8080", true, []string{"10.0.0.1:8080"}},
+		{"lb=backends:80", true, []string{"10.0.1.1:80", "10.0.1.2:80"}},
+		{"tcp=unknown:8080", false, nil},
+		{"unix=/tmp/foo", true, []string{"/tmp/foo"}},
+	}
+
+	for tcNum, tc := range testCases {
+		resolver, err := NewResolverWithLookup(tc.resolverSpec, lookupHost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		address, err := resolver.GetAddress()
+		if (err == nil) != tc.success {
+			t.Errorf("#%d: expected success=%t, got err=%v", tcNum, tc.success, err)
+		}
+		if err != nil {
+			continue
+		}
+		var found bool
+		for _, a := range tc.addresses {
+			found = found || address.String() == a
+		}
+		if !found {
+			t.Errorf("#%d: expected address in %v, got %+v", tcNum, tc.addresses, address)
+		}
+	}
+}
+
+// TestDNSResolver verifies that a dns resolver yields each address its
+// host resolves to and resolves it again once they've all been yielded
+// or the resolution is stale.
+func TestDNSResolver(t *testing.T) {
+	hosts := []string{"10.0.0.2", "10.0.0.1"}
+	lookups := 0
+	lookupHost := func(host string) ([]string, error) {
+		if host != "nodes" {
+			return nil, util.Errorf("unknown host %q", host)
+		}
+		lookups++
+		return append([]string(nil), hosts...), nil
+	}
+	resolver, err := NewResolverWithLookup("dns://nodes:8080", lookupHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	resolver.(*hostsResolver).now = func() time.Time { return now }
+
+	getAddresses := func(n int) []string {
+		var addrs []string
+		for i := 0; i < n; i++ {
+			addr, err := resolver.GetAddress()
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrs = append(addrs, addr.String())
+		}
+		return addrs
+	}
+	expected := []string{"10.0.0.1:8080", "10.0.0.2:8080"}
+	if addrs := getAddresses(2); !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("expected addresses %v; got %v", expected, addrs)
+	}
+	if !resolver.IsExhausted() || lookups != 1 {
+		t.Errorf("expected resolver exhausted after 1 lookup; got %t after %d", resolver.IsExhausted(), lookups)
+	}
+
+	// Once exhausted, the host is resolved again, picking up new nodes.
+	hosts = append(hosts, "10.0.0.3")
+	expected = append(expected, "10.0.0.3:8080")
+	if addrs := getAddresses(3); !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("expected addresses %v; got %v", expected, addrs)
+	}
+
+	// A stale resolution is replaced even if addresses remain.
+	if addrs := getAddresses(1); addrs[0] != expected[0] {
+		t.Errorf("expected address %s; got %s", expected[0], addrs[0])
+	}
+	hosts = []string{"10.0.0.4"}
+	now = now.Add(resolveInterval)
+	if addrs := getAddresses(1); addrs[0] != "10.0.0.4:8080" {
+		t.Errorf("expected address of new resolution; got %s", addrs[0])
+	}
+	if lookups != 4 {
+		t.Errorf("expected 4 lookups; got %d", lookups)
+	}
+
+	hosts = nil
+	now = now.Add(resolveInterval)
+	if _, err := resolver.GetAddress(); err == nil {
+		t.Error("expected error resolving host without addresses")
+	}
+}
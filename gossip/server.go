@@ -23,6 +23,7 @@ import (
 	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/proto"
@@ -39,22 +40,25 @@ type clientInfo struct {
 // server maintains an array of connected peers to which it gossips
 // newly arrived information on a periodic basis.
 type server struct {
-	interval time.Duration         // Interval at which to gossip fresh info
-	mu       sync.Mutex            // Mutex protects is (infostore) & incoming
-	ready    *sync.Cond            // Broadcasts wakeup to waiting gossip requests
-	is       *infoStore            // The backing infostore
-	closed   bool                  // True if server was closed
-	incoming *nodeSet              // Incoming client node IDs
-	lAddrMap map[string]clientInfo // Incoming client's local address -> client's node info
+	interval  int64                 // Interval at which to gossip fresh info, in nanoseconds; accessed atomically
+	mu        sync.Mutex            // Mutex protects is (infostore) & incoming
+	ready     *sync.Cond            // Broadcasts wakeup to waiting gossip requests
+	is        *infoStore            // The backing infostore
+	closed    bool                  // True if server was closed
+	incoming  *nodeSet              // Incoming client node IDs
+	lAddrMap  map[string]clientInfo // Incoming client's local address -> client's node info
+	truncated map[proto.NodeID]bool // Incoming client node IDs last sent a truncated delta
+	refused   int64                 // Count of incoming clients refused for lack of capacity
 }
 
 // newServer creates and returns a server struct.
 func newServer(interval time.Duration) *server {
 	s := &server{
-		is:       newInfoStore(0, nil),
-		interval: interval,
-		incoming: newNodeSet(MaxPeers),
-		lAddrMap: map[string]clientInfo{},
+		is:        newInfoStore(0, nil),
+		interval:  int64(interval),
+		incoming:  newNodeSet(MaxPeers),
+		lAddrMap:  map[string]clientInfo{},
+		truncated: map[proto.NodeID]bool{},
 	}
 	s.ready = sync.NewCond(&s.mu)
 	return s
@@ -80,6 +84,7 @@ func (s *server) Gossip(args *proto.GossipRequest, reply *proto.GossipResponse)
 	// a random already-being-serviced incoming client as an alternate.
 	if !s.incoming.hasNode(args.NodeID) {
 		if !s.incoming.hasSpace() {
+			s.refused++
 			idx := rand.Intn(len(s.lAddrMap))
 			count := 0
 			for _, cInfo := range s.lAddrMap {
@@ -106,8 +111,10 @@ func (s *server) Gossip(args *proto.GossipRequest, reply *proto.GossipResponse)
 		log.V(1).Infof("received delta infostore from client %s: %s", addr, delta)
 		s.is.combine(delta)
 	}
-	// If requested max sequence is not -1, wait for gossip interval to expire.
-	if args.MaxSeq != -1 {
+	// If requested max sequence is not -1, wait for gossip interval to
+	// expire, unless the client has yet to receive the remainder of a
+	// truncated delta.
+	if args.MaxSeq != -1 && !s.truncated[args.NodeID] {
 		s.ready.Wait()
 	}
 	// The exit condition for waiting clients.
@@ -115,7 +122,13 @@ func (s *server) Gossip(args *proto.GossipRequest, reply *proto.GossipResponse)
 		return util.Errorf("gossip server shutdown")
 	}
 	// Return reciprocal delta.
-	delta := s.is.delta(args.NodeID, args.MaxSeq)
+	reply.HighWaterStamps = s.is.highWaterStamps()
+	delta := s.is.delta(args.NodeID, args.MaxSeq, args.HighWaterStamps)
+	if delta != nil && delta.MaxSeq < s.is.MaxSeq {
+		s.truncated[args.NodeID] = true
+	} else {
+		delete(s.truncated, args.NodeID)
+	}
 	if delta != nil {
 		var buf bytes.Buffer
 		if err := gob.NewEncoder(&buf).Encode(delta); err != nil {
@@ -129,18 +142,34 @@ func (s *server) Gossip(args *proto.GossipRequest, reply *proto.GossipResponse)
 	return nil
 }
 
+// SetInterval changes the interval at which fresh info is gossiped.
+// It takes effect from the next round of gossip.
+func (s *server) SetInterval(interval time.Duration) {
+	atomic.StoreInt64(&s.interval, int64(interval))
+}
+
+// gossipInterval returns the interval at which fresh info is gossiped.
+func (s *server) gossipInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.interval))
+}
+
 // jitteredGossipInterval returns a randomly jittered duration from
 // interval [0.75 * gossipInterval, 1.25 * gossipInterval).
 func (s *server) jitteredGossipInterval() time.Duration {
-	return time.Duration(float64(s.interval) * (0.75 + 0.5*rand.Float64()))
+	return time.Duration(float64(s.gossipInterval()) * (0.75 + 0.5*rand.Float64()))
 }
 
-// start initializes the infostore with the rpc server address and
-// then begins processing connecting clients in an infinite select
-// loop via goroutine. Periodically, clients connected and awaiting
-// the next round of gossip are awoken via the conditional variable.
+// start initializes the infostore with the rpc server address, unless
+// an advertised address has been set, and then begins processing
+// connecting clients in an infinite select loop via goroutine.
+// Periodically, clients connected and awaiting the next round of
+// gossip are awoken via the conditional variable.
 func (s *server) start(rpcServer *rpc.Server, stopper *util.Stopper) {
-	s.is.NodeAddr = rpcServer.Addr()
+	s.mu.Lock()
+	if s.is.NodeAddr == nil {
+		s.is.NodeAddr = rpcServer.Addr()
+	}
+	s.mu.Unlock()
 	if err := rpcServer.RegisterName("Gossip", s); err != nil {
 		log.Fatalf("unable to register gossip service with RPC server: %s", err)
 	}
@@ -148,12 +177,13 @@ func (s *server) start(rpcServer *rpc.Server, stopper *util.Stopper) {
 
 	stopper.RunWorker(func() {
 		// Periodically wakeup blocked client gossip requests.
-		gossipTimeout := time.Tick(s.jitteredGossipInterval())
+		gossipTimeout := time.After(s.jitteredGossipInterval())
 		for {
 			select {
 			case <-gossipTimeout:
 				// Wakeup all blocked gossip requests.
 				s.ready.Broadcast()
+				gossipTimeout = time.After(s.jitteredGossipInterval())
 			case <-stopper.ShouldStop():
 				s.stop()
 				return
@@ -178,5 +208,6 @@ func (s *server) onClose(conn net.Conn) {
 	defer s.mu.Unlock()
 	if cInfo, ok := s.lAddrMap[conn.RemoteAddr().String()]; ok {
 		s.incoming.removeNode(cInfo.id)
+		delete(s.truncated, cInfo.id)
 	}
 }
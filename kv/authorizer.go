@@ -0,0 +1,181 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// An Authorizer decides whether a user may invoke a request. It's
+// consulted by the DistSender for every request it sends, allowing
+// embedders to plug in their own policies in place of the permission
+// configs. That includes the requests of the root user, as which the
+// node runs its internal requests, so an Authorizer which refuses
+// them leaves the node unable to operate.
+type Authorizer interface {
+	// Authorize returns an error unless the user named in the
+	// request's header may invoke its method on the span from its key
+	// to its end key, or on its key alone if the end key is empty.
+	// Whether the method reads or writes is reported by proto.IsRead
+	// and proto.IsWrite, and whether it's an admin command by
+	// proto.IsAdmin.
+	Authorize(args proto.Request) error
+}
+
+// The AuthorizerFunc type is an adapter to allow the use of ordinary
+// functions as Authorizers.
+type AuthorizerFunc func(args proto.Request) error
+
+// Authorize implements the Authorizer interface by calling f(args).
+func (f AuthorizerFunc) Authorize(args proto.Request) error {
+	return f(args)
+}
+
+// permConfigAuthorizer is the default Authorizer, which authorizes
+// requests according to the permission configs available via gossip.
+// The configs are cached, and refreshed by a gossip callback whenever
+// they change.
+type permConfigAuthorizer struct {
+	gossip  *gossip.Gossip
+	mu      sync.Mutex
+	permMap storage.PrefixConfigMap // Cached perm configs; nil until gossiped
+}
+
+// NewPermConfigAuthorizer returns an Authorizer backed by the
+// permission configs gossiped to g. Root may invoke any request, admin
+// commands may only be invoked by root, and the replication user may
+// read and write any key.
+func NewPermConfigAuthorizer(g *gossip.Gossip) Authorizer {
+	a := &permConfigAuthorizer{gossip: g}
+	g.RegisterCallback(gossip.KeyConfigPermission, a.permGossipUpdate)
+	return a
+}
+
+// permGossipUpdate is a gossip callback which replaces the cached perm
+// configs with the newly gossiped ones.
+func (a *permConfigAuthorizer) permGossipUpdate(key string, contentsChanged bool) {
+	if !contentsChanged {
+		return
+	}
+	info, err := a.gossip.GetInfo(key)
+	if err != nil {
+		log.Errorf("unable to fetch perm configs from gossip: %s", err)
+		return
+	}
+	permMap, ok := info.(storage.PrefixConfigMap)
+	if !ok {
+		log.Errorf("gossiped info is not a prefix configuration map: %+v", info)
+		return
+	}
+	a.mu.Lock()
+	a.permMap = permMap
+	a.mu.Unlock()
+}
+
+// permConfigs returns the cached perm configs. Until the gossip
+// callback has run, they're fetched from gossip directly, and cached
+// unless the callback has since cached newer ones.
+func (a *permConfigAuthorizer) permConfigs() (storage.PrefixConfigMap, error) {
+	a.mu.Lock()
+	permMap := a.permMap
+	a.mu.Unlock()
+	if permMap != nil {
+		return permMap, nil
+	}
+	configMap, err := a.gossip.GetInfo(gossip.KeyConfigPermission)
+	if err != nil || configMap == nil {
+		return nil, err
+	}
+	permMap = configMap.(storage.PrefixConfigMap)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.permMap == nil {
+		a.permMap = permMap
+	}
+	return a.permMap, nil
+}
+
+// Authorize implements the Authorizer interface. The user must have
+// permission to read or write, as the method requires. In the event
+// that multiple permission configs apply to the key range implicated
+// by the command, the lowest common denominator for permission. For
+// example, if a scan crosses two permission configs, both configs
+// must allow read permissions or the entire scan will fail.
+func (a *permConfigAuthorizer) Authorize(args proto.Request) error {
+	header := args.Header()
+	if header.User == storage.UserRoot {
+		return nil
+	}
+	// Check for admin methods.
+	if proto.IsAdmin(args) {
+		if header.User != storage.UserRoot {
+			return util.Errorf("user %q cannot invoke admin command %s", header.User, args.Method())
+		}
+		return nil
+	}
+	// The replication user may read and write any key.
+	if header.User == storage.UserReplication {
+		return nil
+	}
+	permMap, err := a.permConfigs()
+	if err != nil {
+		return util.Errorf("permissions not available via gossip")
+	}
+	if permMap == nil {
+		return util.Errorf("perm configs not available; cannot execute %s", args.Method())
+	}
+	headerEnd := header.EndKey
+	if headerEnd == nil {
+		headerEnd = header.Key
+	}
+	// Visit PermConfig(s) which apply to the method's key range.
+	//   - For each perm config which the range covers, verify read or writes
+	//     are allowed as method requires.
+	//   - Verify the permissions hierarchically; that is, if permissions aren't
+	//     granted at the longest prefix, try next longest, then next, etc., up
+	//     to and including the default prefix.
+	//
+	// TODO(spencer): it might make sense to visit prefixes from the
+	//   shortest to longest instead for performance. Keep an eye on profiling
+	//   for this code path as permission sets grow large.
+	return permMap.VisitPrefixes(header.Key, headerEnd,
+		func(start, end proto.Key, config interface{}) (bool, error) {
+			hasPerm := false
+			permMap.VisitPrefixesHierarchically(start, func(start, end proto.Key, config interface{}) (bool, error) {
+				perm := config.(*proto.PermConfig)
+				if proto.IsRead(args) && !perm.CanRead(header.User) {
+					return false, nil
+				}
+				if proto.IsWrite(args) && !perm.CanWrite(header.User) {
+					return false, nil
+				}
+				// Return done = true, as permissions have been granted by this config.
+				hasPerm = true
+				return true, nil
+			})
+			if !hasPerm {
+				return false, util.Errorf("user %q cannot invoke %s at %q-%q",
+					header.User, args.Method(), start, end)
+			}
+			return false, nil
+		})
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+const (
+	// BatchPrefix is the endpoint which executes an array of key-value
+	// operations as a single batch.
+	BatchPrefix = "/kv/batch"
+	// batchParamTxn is the query parameter which, if true, runs the
+	// batch within a transaction.
+	batchParamTxn = "txn"
+)
+
+// A BatchServer provides an HTTP endpoint which accepts a BatchRequest,
+// JSON or protobuf-encoded, and executes its requests as a single
+// batch, sparing clients which can't speak the RPC protocol a round
+// trip per operation. If the txn query parameter is true, the batch is
+// run within a transaction which is committed if every request
+// succeeds and aborted otherwise. Otherwise, every request is executed
+// and the failed ones may be retried on their own, unless the batch is
+// all-or-nothing.
+type BatchServer struct {
+	db *client.KV
+}
+
+// NewBatchServer allocates and returns a new BatchServer.
+func NewBatchServer(db *client.KV) *BatchServer {
+	return &BatchServer{db: db}
+}
+
+// ServeHTTP implements http.Handler. The response is a BatchResponse
+// holding one response per request, in order, each with its own
+// error. The first error encountered is also returned in the response
+// header.
+func (s *BatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != BatchPrefix {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var useTxn bool
+	if param := r.URL.Query().Get(batchParamTxn); len(param) > 0 {
+		var err error
+		if useTxn, err = strconv.ParseBool(param); err != nil {
+			http.Error(w, "error parsing txn: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	reqBody, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	batch := &proto.BatchRequest{}
+	if err := util.UnmarshalRequest(r, reqBody, batch, allowedEncodings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	args, err := createBatchArgs(batch, useTxn)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reply := &proto.BatchResponse{}
+	call := client.Call{Args: args, Reply: reply}
+	if useTxn {
+		opts := &client.TransactionOptions{Name: "http batch"}
+		err = s.db.RunTransaction(opts, func(txn *client.Txn) error {
+			reply.Reset()
+			return txn.Run(call)
+		})
+		if err != nil && reply.Error == nil {
+			reply.SetGoError(err)
+		}
+	} else {
+		s.db.Run(call)
+	}
+
+	body, contentType, err := util.MarshalResponse(r, reply, allowedEncodings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// createBatchArgs returns a copy of the batch suitable for execution
+// by a KV client. Only the requests, the ordered, all-or-nothing and
+// priority class fields are retained; the header is derived from the
+// requests. Transactional batches may not contain EndTransaction
+// requests, as the transaction is managed by the server.
+func createBatchArgs(batch *proto.BatchRequest, useTxn bool) (*proto.BatchRequest, error) {
+	if len(batch.Requests) == 0 {
+		return nil, util.Errorf("batch contains no requests")
+	}
+	args := &proto.BatchRequest{Ordered: batch.Ordered, AllOrNothing: batch.AllOrNothing}
+	args.PriorityClass = batch.PriorityClass
+	for i, union := range batch.Requests {
+		req, ok := union.GetValue().(proto.Request)
+		if !ok {
+			return nil, util.Errorf("request %d is empty", i)
+		}
+		if _, ok := req.(*proto.EndTransactionRequest); ok && useTxn {
+			return nil, util.Errorf("request %d: EndTransaction not permitted in a transactional batch", i)
+		}
+		if err := verifyRequest(req); err != nil {
+			return nil, err
+		}
+		args.Add(req)
+	}
+	return args, nil
+}
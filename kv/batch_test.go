@@ -0,0 +1,202 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/kv"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// postBatch sends the requests as a JSON-encoded batch to the batch
+// endpoint and returns the HTTP status code and the decoded response.
+func postBatch(t *testing.T, addr, query string, reqs ...proto.Request) (int, *proto.BatchResponse) {
+	batch := &proto.BatchRequest{}
+	for _, req := range reqs {
+		batch.Add(req)
+	}
+	return postBatchRequest(t, addr, query, batch)
+}
+
+// postBatchRequest sends the JSON-encoded batch to the batch endpoint
+// and returns the HTTP status code and the decoded response.
+func postBatchRequest(t *testing.T, addr, query string, batch *proto.BatchRequest) (int, *proto.BatchResponse) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, err := http.NewRequest("POST", "https://"+addr+kv.BatchPrefix+query, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Add(util.ContentTypeHeader, util.JSONContentType)
+	resp, err := httpDoReq(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	reply := &proto.BatchResponse{}
+	if err := json.Unmarshal(respBody, reply); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, reply
+}
+
+func putArgs(key, value string) *proto.PutRequest {
+	return &proto.PutRequest{
+		RequestHeader: proto.RequestHeader{Key: proto.Key(key)},
+		Value:         proto.Value{Bytes: []byte(value)},
+	}
+}
+
+// TestKVBatch verifies that a batch's requests are executed in order
+// and that a response is returned for each.
+func TestKVBatch(t *testing.T) {
+	addr, _, stopper := startServer(t)
+	defer stopper.Stop()
+
+	get := &proto.GetRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("a")}}
+	scan := &proto.ScanRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("a"), EndKey: proto.Key("c")}}
+	batch := []proto.Request{putArgs("a", "1"), putArgs("b", "2"), get, scan}
+	status, reply := postBatch(t, addr, "?txn=false", batch...)
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200; got %d", status)
+	}
+	if err := reply.GoError(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Responses) != len(batch) {
+		t.Fatalf("expected %d responses; got %d", len(batch), len(reply.Responses))
+	}
+	if gr := reply.Responses[2].Get; gr == nil || gr.Value == nil || string(gr.Value.Bytes) != "1" {
+		t.Errorf("expected get of value 1; got %+v", reply.Responses[2])
+	}
+	if sr := reply.Responses[3].Scan; sr == nil || len(sr.Rows) != 2 {
+		t.Errorf("expected scan of 2 rows; got %+v", reply.Responses[3])
+	}
+}
+
+// TestKVBatchTransaction verifies that a transactional batch is
+// aborted if any of its requests fail.
+func TestKVBatchTransaction(t *testing.T) {
+	addr, db, stopper := startServer(t)
+	defer stopper.Stop()
+
+	cPut := &proto.ConditionalPutRequest{
+		RequestHeader: proto.RequestHeader{Key: proto.Key("b")},
+		Value:         proto.Value{Bytes: []byte("2")},
+		ExpValue:      &proto.Value{Bytes: []byte("missing")},
+	}
+	status, reply := postBatch(t, addr, "?txn=true", putArgs("a", "1"), cPut)
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200; got %d", status)
+	}
+	if _, ok := reply.GoError().(*proto.ConditionFailedError); !ok {
+		t.Fatalf("expected condition failed error; got %v", reply.GoError())
+	}
+	call := client.GetCall(proto.Key("a"))
+	if err := db.Run(call); err != nil {
+		t.Fatal(err)
+	}
+	if gr := call.Reply.(*proto.GetResponse); gr.Value != nil {
+		t.Errorf("expected aborted put to be invisible; got %q", gr.Value.Bytes)
+	}
+
+	// Transactions are managed by the server; they may not be ended by
+	// the client.
+	et := &proto.EndTransactionRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("a")}}
+	if status, _ := postBatch(t, addr, "?txn=true", putArgs("a", "1"), et); status != http.StatusBadRequest {
+		t.Errorf("expected status 400; got %d", status)
+	}
+	if status, _ := postBatch(t, addr, "?txn=maybe", putArgs("a", "1")); status != http.StatusBadRequest {
+		t.Errorf("expected status 400; got %d", status)
+	}
+	if status, _ := postBatch(t, addr, ""); status != http.StatusBadRequest {
+		t.Errorf("expected status 400; got %d", status)
+	}
+}
+
+// TestKVBatchPartial verifies that every request of a non-transactional
+// batch is executed, with its own error, unless the batch is
+// all-or-nothing.
+func TestKVBatchPartial(t *testing.T) {
+	addr, db, stopper := startServer(t)
+	defer stopper.Stop()
+
+	cPut := func(key string) *proto.ConditionalPutRequest {
+		return &proto.ConditionalPutRequest{
+			RequestHeader: proto.RequestHeader{Key: proto.Key(key)},
+			Value:         proto.Value{Bytes: []byte("2")},
+			ExpValue:      &proto.Value{Bytes: []byte("missing")},
+		}
+	}
+	get := func(key string) *proto.Value {
+		call := client.GetCall(proto.Key(key))
+		if err := db.Run(call); err != nil {
+			t.Fatal(err)
+		}
+		return call.Reply.(*proto.GetResponse).Value
+	}
+
+	for _, allOrNothing := range []bool{false, true} {
+		batch := &proto.BatchRequest{Ordered: true, AllOrNothing: allOrNothing}
+		prefix := fmt.Sprintf("%t-", allOrNothing)
+		batch.Add(putArgs(prefix+"a", "1"))
+		batch.Add(cPut(prefix + "b"))
+		batch.Add(putArgs(prefix+"c", "3"))
+		status, reply := postBatchRequest(t, addr, "?txn=false", batch)
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200; got %d", status)
+		}
+		if _, ok := reply.GoError().(*proto.ConditionFailedError); !ok {
+			t.Errorf("%t: expected condition failed error; got %v", allOrNothing, reply.GoError())
+		}
+		if allOrNothing {
+			if v := get(prefix + "a"); v != nil {
+				t.Errorf("expected put of all-or-nothing batch to be invisible; got %q", v.Bytes)
+			}
+			continue
+		}
+		if len(reply.Responses) != 3 {
+			t.Fatalf("expected 3 responses; got %d", len(reply.Responses))
+		}
+		for i, expErr := range []bool{false, true, false} {
+			if err := reply.Responses[i].GetValue().(proto.Response).Header().GoError(); (err != nil) != expErr {
+				t.Errorf("%d: expected error %t; got %v", i, expErr, err)
+			}
+		}
+		for _, key := range []string{"a", "c"} {
+			if v := get(prefix + key); v == nil {
+				t.Errorf("expected put of %q to succeed", key)
+			}
+		}
+	}
+}
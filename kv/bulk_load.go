@@ -0,0 +1,174 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+const (
+	// BulkLoadPrefix is the endpoint which loads rows into empty spans
+	// of keys.
+	BulkLoadPrefix = "/kv/bulk"
+	// bulkLoadChunkBytes bounds the size of the keys and values written
+	// by a single InternalIngest command.
+	bulkLoadChunkBytes = 4 << 20
+)
+
+// A BulkLoadServer provides an HTTP endpoint for initial loads of
+// data. It accepts an InternalIngestRequest, JSON or protobuf-encoded,
+// whose rows are sorted and written, in chunks which each fall within
+// a range, by InternalIngest commands. These link the rows into the
+// stores of the ranges' replicas as sstables, which is orders of
+// magnitude faster than putting them, but only writes to spans which
+// hold no keys. The key span of the request is ignored; its header
+// names the user the rows are written as.
+type BulkLoadServer struct {
+	db *client.KV
+}
+
+// NewBulkLoadServer allocates and returns a new BulkLoadServer.
+func NewBulkLoadServer(db *client.KV) *BulkLoadServer {
+	return &BulkLoadServer{db: db}
+}
+
+// ServeHTTP implements http.Handler. The response is an
+// InternalIngestResponse whose header holds the error, if any, which
+// stopped the load. The chunks ingested before it remain.
+func (s *BulkLoadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != BulkLoadPrefix {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	reqBody, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	args := &proto.InternalIngestRequest{}
+	if err := util.UnmarshalRequest(r, reqBody, args, allowedEncodings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sortBulkLoadRows(args.Rows); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reply := &proto.InternalIngestResponse{}
+	if err := s.load(args.User, args.Rows); err != nil {
+		reply.SetGoError(err)
+	}
+	body, contentType, err := util.MarshalResponse(r, reply, allowedEncodings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// rowsByKey sorts rows by key.
+type rowsByKey []proto.KeyValue
+
+func (r rowsByKey) Len() int           { return len(r) }
+func (r rowsByKey) Less(i, j int) bool { return r[i].Key.Less(r[j].Key) }
+func (r rowsByKey) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// sortBulkLoadRows sorts the rows of a bulk load by key, returning an
+// error if any keys are repeated or are system keys.
+func sortBulkLoadRows(rows []proto.KeyValue) error {
+	if len(rows) == 0 {
+		return util.Errorf("bulk load contains no rows")
+	}
+	sort.Sort(rowsByKey(rows))
+	if rows[0].Key.Less(engine.KeySystemMax) {
+		return util.Errorf("bulk loads may not write system key %q", rows[0].Key)
+	}
+	for i := range rows {
+		if i > 0 && rows[i-1].Key.Equal(rows[i].Key) {
+			return util.Errorf("key %q is repeated", rows[i].Key)
+		}
+		rows[i].Value.Timestamp = nil
+	}
+	return nil
+}
+
+// load ingests the sorted rows as user, a chunk at a time. A chunk
+// which straddles a split of its range since its end was read is split
+// as well and retried.
+func (s *BulkLoadServer) load(user string, rows []proto.KeyValue) error {
+	for len(rows) > 0 {
+		end, err := s.rangeEnd(rows[0].Key)
+		if err != nil {
+			return err
+		}
+		n, size := 0, 0
+		for n < len(rows) && rows[n].Key.Less(end) && (n == 0 || size < bulkLoadChunkBytes) {
+			size += len(rows[n].Key) + len(rows[n].Value.Bytes)
+			n++
+		}
+		args := &proto.InternalIngestRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:    rows[0].Key,
+				EndKey: rows[n-1].Key.Next(),
+				User:   user,
+			},
+			Rows: rows[:n],
+		}
+		if err := s.db.Run(client.Call{Args: args, Reply: args.CreateReply()}); err != nil {
+			if newEnd, endErr := s.rangeEnd(rows[0].Key); endErr == nil && newEnd.Less(end) {
+				continue
+			}
+			return err
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+// rangeEnd returns the end key of the range holding key, read from its
+// addressing record.
+func (s *BulkLoadServer) rangeEnd(key proto.Key) (proto.Key, error) {
+	call := client.ScanCall(engine.RangeMetaKey(key).Next(), engine.KeyMeta2Prefix.PrefixEnd(), 1)
+	call.Args.Header().User = storage.UserRoot
+	if err := s.db.Run(call); err != nil {
+		return nil, err
+	}
+	rows := call.Reply.(*proto.ScanResponse).Rows
+	if len(rows) == 0 {
+		return nil, util.Errorf("no addressing record found for key %q", key)
+	}
+	desc := &proto.RangeDescriptor{}
+	if err := gogoproto.Unmarshal(rows[0].Value.Bytes, desc); err != nil {
+		return nil, util.Errorf("unable to unmarshal range descriptor for key %q: %s", key, err)
+	}
+	return desc.EndKey, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/kv"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// postBulkLoad sends the rows as a JSON-encoded InternalIngestRequest
+// to the bulk-load endpoint and returns the HTTP status code and the
+// decoded response.
+func postBulkLoad(t *testing.T, addr string, rows ...proto.KeyValue) (int, *proto.InternalIngestResponse) {
+	body, err := json.Marshal(&proto.InternalIngestRequest{Rows: rows})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, err := http.NewRequest("POST", "https://"+addr+kv.BulkLoadPrefix, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Add(util.ContentTypeHeader, util.JSONContentType)
+	resp, err := httpDoReq(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	reply := &proto.InternalIngestResponse{}
+	if err := json.Unmarshal(respBody, reply); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, reply
+}
+
+func bulkRow(key, value string) proto.KeyValue {
+	return proto.KeyValue{Key: proto.Key(key), Value: proto.Value{Bytes: []byte(value)}}
+}
+
+// TestKVBulkLoad verifies that unsorted rows are loaded into an empty
+// span, that spans which hold keys are refused and that malformed
+// loads are rejected.
+func TestKVBulkLoad(t *testing.T) {
+	addr, db, stopper := startServer(t)
+	defer stopper.Stop()
+
+	status, reply := postBulkLoad(t, addr, bulkRow("c", "3"), bulkRow("a", "1"), bulkRow("b", "2"))
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200; got %d", status)
+	}
+	if err := reply.GoError(); err != nil {
+		t.Fatal(err)
+	}
+	call := client.ScanCall(proto.Key("a"), proto.Key("d"), 0)
+	if err := db.Run(call); err != nil {
+		t.Fatal(err)
+	}
+	rows := call.Reply.(*proto.ScanResponse).Rows
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows; got %d", len(rows))
+	}
+	for i, expValue := range []string{"1", "2", "3"} {
+		if v := string(rows[i].Value.Bytes); v != expValue {
+			t.Errorf("%d: expected value %q; got %q", i, expValue, v)
+		}
+	}
+
+	// The span now holds keys, so a second load into it fails.
+	status, reply = postBulkLoad(t, addr, bulkRow("a", "4"), bulkRow("bb", "5"))
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200; got %d", status)
+	}
+	if reply.GoError() == nil {
+		t.Error("expected error loading into a span which holds keys")
+	}
+
+	for i, rows := range [][]proto.KeyValue{
+		{},
+		{bulkRow("x", "1"), bulkRow("x", "2")},
+		{bulkRow("\x00system", "1")},
+	} {
+		if status, _ := postBulkLoad(t, addr, rows...); status != http.StatusBadRequest {
+			t.Errorf("%d: expected status 400; got %d", i, status)
+		}
+	}
+}
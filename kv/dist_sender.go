@@ -50,6 +50,11 @@ const (
 	defaultLeaderCacheSize = 1 << 16
 	// The default size of the range descriptor cache.
 	defaultRangeDescriptorCacheSize = 1 << 20
+	// The default maximum burst of retries permitted by a retry budget.
+	defaultRetryBudgetMaxRetries = 10
+	// The default maximum number of calls from a single batch which
+	// are in flight at a time.
+	defaultBatchConcurrency = 16
 )
 
 var defaultRPCRetryOptions = util.RetryOptions{
@@ -112,6 +117,14 @@ type DistSender struct {
 	// outside of tests.
 	rpcSend         rpcSendFn
 	rpcRetryOptions util.RetryOptions
+	// hedgeReadTimeout is the duration after which read-only requests
+	// are additionally sent to the next replica.
+	hedgeReadTimeout time.Duration
+	// retryBudget, if not nil, limits retries of failed RPCs.
+	retryBudget *retryBudget
+	// authorizer decides whether requests may be invoked by the users
+	// named in their headers.
+	authorizer Authorizer
 }
 
 // rpcSendFn is the function type used to dispatch RPC calls.
@@ -129,6 +142,19 @@ type DistSenderContext struct {
 	RangeLookupMaxRanges int32
 	LeaderCacheSize      int32
 	RPCRetryOptions      *util.RetryOptions
+	// HedgeReadTimeout, if non-zero, is the duration after which a
+	// read-only request which has not yet received a reply is
+	// additionally sent to the next replica. Lowering it below the
+	// default reduces the tail latency caused by a single slow node.
+	HedgeReadTimeout time.Duration
+	// RetryBudgetRatio, if positive, enables a retry budget which
+	// limits retries of failed RPCs to the given ratio of requests
+	// sent, with bursts of up to RetryBudgetMaxRetries retries.
+	RetryBudgetRatio      float64
+	RetryBudgetMaxRetries int
+	// Authorizer, if not nil, is consulted in place of the permission
+	// configs to authorize requests.
+	Authorizer Authorizer
 	// nodeDescriptor, if provided, is used to describe which node the DistSender
 	// lives on, for instance when deciding where to send RPCs.
 	// Usually it is filled in from the Gossip network on demand.
@@ -181,73 +207,28 @@ func NewDistSender(ctx *DistSenderContext, gossip *gossip.Gossip) *DistSender {
 	if ctx.RPCRetryOptions != nil {
 		ds.rpcRetryOptions = *ctx.RPCRetryOptions
 	}
+	ds.hedgeReadTimeout = defaultSendNextTimeout
+	if ctx.HedgeReadTimeout > 0 {
+		ds.hedgeReadTimeout = ctx.HedgeReadTimeout
+	}
+	if ctx.RetryBudgetRatio > 0 {
+		maxRetries := ctx.RetryBudgetMaxRetries
+		if maxRetries <= 0 {
+			maxRetries = defaultRetryBudgetMaxRetries
+		}
+		ds.retryBudget = newRetryBudget(ctx.RetryBudgetRatio, maxRetries)
+	}
+	ds.authorizer = ctx.Authorizer
+	if ds.authorizer == nil {
+		ds.authorizer = NewPermConfigAuthorizer(gossip)
+	}
 	return ds
 }
 
 // verifyPermissions verifies that the requesting user (header.User)
-// has permission to read/write (capabilities depend on method
-// name). In the event that multiple permission configs apply to the
-// key range implicated by the command, the lowest common denominator
-// for permission. For example, if a scan crosses two permission
-// configs, both configs must allow read permissions or the entire
-// scan will fail.
+// is authorized to invoke the request by the DistSender's Authorizer.
 func (ds *DistSender) verifyPermissions(args proto.Request) error {
-	// The root user can always proceed.
-	header := args.Header()
-	if header.User == storage.UserRoot {
-		return nil
-	}
-	// Check for admin methods.
-	if proto.IsAdmin(args) {
-		if header.User != storage.UserRoot {
-			return util.Errorf("user %q cannot invoke admin command %s", header.User, args.Method())
-		}
-		return nil
-	}
-	// Get permissions map from gossip.
-	configMap, err := ds.gossip.GetInfo(gossip.KeyConfigPermission)
-	if err != nil {
-		return util.Errorf("permissions not available via gossip")
-	}
-	if configMap == nil {
-		return util.Errorf("perm configs not available; cannot execute %s", args.Method())
-	}
-	permMap := configMap.(storage.PrefixConfigMap)
-	headerEnd := header.EndKey
-	if headerEnd == nil {
-		headerEnd = header.Key
-	}
-	// Visit PermConfig(s) which apply to the method's key range.
-	//   - For each perm config which the range covers, verify read or writes
-	//     are allowed as method requires.
-	//   - Verify the permissions hierarchically; that is, if permissions aren't
-	//     granted at the longest prefix, try next longest, then next, etc., up
-	//     to and including the default prefix.
-	//
-	// TODO(spencer): it might make sense to visit prefixes from the
-	//   shortest to longest instead for performance. Keep an eye on profiling
-	//   for this code path as permission sets grow large.
-	return permMap.VisitPrefixes(header.Key, headerEnd,
-		func(start, end proto.Key, config interface{}) (bool, error) {
-			hasPerm := false
-			permMap.VisitPrefixesHierarchically(start, func(start, end proto.Key, config interface{}) (bool, error) {
-				perm := config.(*proto.PermConfig)
-				if proto.IsRead(args) && !perm.CanRead(header.User) {
-					return false, nil
-				}
-				if proto.IsWrite(args) && !perm.CanWrite(header.User) {
-					return false, nil
-				}
-				// Return done = true, as permissions have been granted by this config.
-				hasPerm = true
-				return true, nil
-			})
-			if !hasPerm {
-				return false, util.Errorf("user %q cannot invoke %s at %q-%q",
-					header.User, args.Method(), start, end)
-			}
-			return false, nil
-		})
+	return ds.authorizer.Authorize(args)
 }
 
 // internalRangeLookup dispatches an InternalRangeLookup request for the given
@@ -264,6 +245,7 @@ func (ds *DistSender) internalRangeLookup(key proto.Key, info *proto.RangeDescri
 			Key:             key,
 			User:            storage.UserRoot,
 			ReadConsistency: proto.INCONSISTENT,
+			PriorityClass:   proto.SYSTEM_PRIORITY,
 		},
 		MaxRanges: ds.rangeLookupMaxRanges,
 	}
@@ -395,7 +377,8 @@ func (ds *DistSender) sendRPC(desc *proto.RangeDescriptor,
 
 	// If this request needs to go to a leader and we know who that is, move
 	// it to the front and send requests in order.
-	if args.Header().ReadConsistency != proto.INCONSISTENT || proto.IsWrite(args) {
+	if (args.Header().ReadConsistency != proto.INCONSISTENT && args.Header().MaxStaleness <= 0) ||
+		proto.IsWrite(args) {
 		if leader := ds.leaderCache.Lookup(proto.RaftID(desc.RaftID)); leader != nil {
 			i, _ := replicas.FindReplica(leader.StoreID)
 			if i >= 0 {
@@ -433,6 +416,13 @@ func (ds *DistSender) sendRPC(desc *proto.RangeDescriptor,
 		SendNextTimeout: defaultSendNextTimeout,
 		Timeout:         defaultRPCTimeout,
 	}
+	// Read-only requests are safe to hedge: if the first replica is
+	// slow to respond, send to the next one as well and use whichever
+	// reply arrives first. Low priority requests aren't hedged, so as
+	// not to add to the load of replicas on behalf of bulk work.
+	if proto.IsReadOnly(args) && args.Header().PriorityClass != proto.LOW_PRIORITY {
+		rpcOpts.SendNextTimeout = ds.hedgeReadTimeout
+	}
 	// getArgs clones the arguments on demand for all but the first replica.
 	firstArgs := true
 	getArgs := func(addr net.Addr) interface{} {
@@ -490,11 +480,17 @@ func (ds *DistSender) Send(call client.Call) {
 	args := call.Args
 
 	// In the event that timestamp isn't set and read consistency isn't
-	// required, set the timestamp using the local clock.
-	if args.Header().ReadConsistency == proto.INCONSISTENT && args.Header().Timestamp.Equal(proto.ZeroTimestamp) {
+	// required or staleness is bounded relative to it, set the
+	// timestamp using the local clock.
+	if (args.Header().ReadConsistency == proto.INCONSISTENT || args.Header().MaxStaleness > 0) &&
+		args.Header().Timestamp.Equal(proto.ZeroTimestamp) {
 		args.Header().Timestamp = ds.clock.Now()
 	}
 
+	if ds.retryBudget != nil {
+		ds.retryBudget.deposit()
+	}
+
 	for {
 		reply := call.Reply
 		err := util.RetryWithBackoff(retryOpts, func() (util.RetryStatus, error) {
@@ -513,7 +509,8 @@ func (ds *DistSender) Send(call client.Call) {
 					// case where we don't need to re-run is if the read
 					// consistency is not required.
 					if call.Args.Header().Txn == nil &&
-						args.Header().ReadConsistency != proto.INCONSISTENT {
+						args.Header().ReadConsistency != proto.INCONSISTENT &&
+						args.Header().MaxStaleness <= 0 {
 						return util.RetryBreak, &proto.OpRequiresTxnError{}
 					}
 					// This next lookup is likely for free since we've read the
@@ -557,9 +554,15 @@ func (ds *DistSender) Send(call client.Call) {
 				case *proto.NotLeaderError:
 					ds.updateLeaderCache(proto.RaftID(desc.RaftID),
 						err.(*proto.NotLeaderError).GetLeader())
+					// A bounded-staleness read which the replica couldn't
+					// serve is retried as a consistent read by the leader.
+					args.Header().MaxStaleness = 0
 					return util.RetryReset, nil
 				default:
 					if retryErr, ok := err.(util.Retryable); ok && retryErr.CanRetry() {
+						if ds.retryBudget != nil && !ds.retryBudget.withdraw() {
+							return util.RetryBreak, retryBudgetExhaustedError{err}
+						}
 						return util.RetryContinue, nil
 					}
 				}
@@ -567,6 +570,13 @@ func (ds *DistSender) Send(call client.Call) {
 				// If this request spans ranges, collect the replies.
 				responses = append(responses, reply)
 
+				// A bounded-staleness read reads the remaining ranges at
+				// the timestamp chosen for the first, consistently.
+				if args.Header().MaxStaleness > 0 {
+					args.Header().Timestamp = reply.Header().Timestamp
+					args.Header().MaxStaleness = 0
+				}
+
 				// If this request has a bound, such as MaxResults in ScanRequest,
 				// check whether enough rows are got in this round.
 				if args, ok := args.(proto.Bounded); ok && args.GetBound() > 0 {
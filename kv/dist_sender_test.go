@@ -125,7 +125,7 @@ func TestSendRPCOrder(t *testing.T) {
 		leader int32 // 0 for not caching a leader.
 		// Naming is somewhat off, as eventually consistent reads usually
 		// do not have to go to the leader when a node has a read lease.
-		// Would really want CONSENSUS here, but that is not implemented.
+		// Would really want CONSENSUS here.
 		// Likely a test setup here will never have a read lease, but good
 		// to keep in mind.
 		consistent bool
@@ -514,3 +514,150 @@ func TestVerifyPermissions(t *testing.T) {
 	}
 	n.Stop()
 }
+
+// TestPermConfigAuthorizerRefresh verifies that the permission config
+// authorizer picks up changes to the gossiped perm configs.
+func TestPermConfigAuthorizerRefresh(t *testing.T) {
+	n := simulation.NewNetwork(1, "unix", gossip.TestInterval)
+	defer n.Stop()
+	g := n.Nodes[0].Gossip
+	a := NewPermConfigAuthorizer(g)
+	args := &proto.PutRequest{}
+	args.User = "writer"
+	if err := a.Authorize(args); err == nil {
+		t.Fatal("expected authorization to fail without perm configs")
+	}
+
+	addPermConfig := func(write []string) {
+		configMap, err := storage.NewPrefixConfigMap([]*storage.PrefixConfig{
+			{engine.KeyMin, nil, &proto.PermConfig{Write: write}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.AddInfo(gossip.KeyConfigPermission, configMap, time.Hour)
+	}
+	addPermConfig([]string{"writer"})
+	if err := a.Authorize(args); err != nil {
+		t.Fatal(err)
+	}
+	addPermConfig(nil)
+	util.SucceedsWithin(t, time.Second, func() error {
+		if err := a.Authorize(args); err == nil {
+			return util.Errorf("expected write permission to be revoked")
+		}
+		return nil
+	})
+}
+
+// TestAuthorizer verifies that requests are authorized by the
+// Authorizer of the DistSender's context in place of the permission
+// configs, including those of the root user.
+func TestAuthorizer(t *testing.T) {
+	g := makeTestGossip(t)
+	var sent int
+	var testFn rpcSendFn = func(_ rpc.Options, _ string, _ []net.Addr, _ func(addr net.Addr) interface{}, getReply func() interface{}, _ *rpc.Context) ([]interface{}, error) {
+		sent++
+		return []interface{}{getReply()}, nil
+	}
+	var authorized []proto.Method
+	ctx := &DistSenderContext{
+		rpcSend: testFn,
+		Authorizer: AuthorizerFunc(func(args proto.Request) error {
+			authorized = append(authorized, args.Method())
+			if proto.IsWrite(args) && args.Header().User != storage.UserRoot {
+				return util.Errorf("user %q may not write", args.Header().User)
+			}
+			return nil
+		}),
+		rangeDescriptorDB: mockRangeDescriptorDB(func(_ proto.Key) ([]proto.RangeDescriptor, error) {
+			return []proto.RangeDescriptor{testRangeDescriptor}, nil
+		}),
+	}
+	ds := NewDistSender(ctx, g)
+
+	for i, test := range []struct {
+		call   client.Call
+		user   string
+		expErr bool
+	}{
+		{client.GetCall(proto.Key("a")), "reader", false},
+		{client.PutCall(proto.Key("a"), []byte("value")), "reader", true},
+		{client.PutCall(proto.Key("a"), []byte("value")), storage.UserRoot, false},
+	} {
+		test.call.Args.Header().User = test.user
+		ds.Send(test.call)
+		if err := test.call.Reply.Header().GoError(); (err != nil) != test.expErr {
+			t.Errorf("%d: expected error %t; got %v", i, test.expErr, err)
+		}
+	}
+	if exp := []proto.Method{proto.Get, proto.Put, proto.Put}; !reflect.DeepEqual(authorized, exp) {
+		t.Errorf("expected authorization of %v; got %v", exp, authorized)
+	}
+	if sent != 2 {
+		t.Errorf("expected 2 requests to be sent; got %d", sent)
+	}
+}
+
+// TestHedgeReadTimeout verifies that read-only requests are sent to
+// additional replicas after the hedge read timeout, while writes use
+// the default send next timeout.
+func TestHedgeReadTimeout(t *testing.T) {
+	g := makeTestGossip(t)
+	hedgeTimeout := 10 * time.Millisecond
+	var sendNextTimeout time.Duration
+	var testFn rpcSendFn = func(opts rpc.Options, _ string, _ []net.Addr, _ func(addr net.Addr) interface{}, _ func() interface{}, _ *rpc.Context) ([]interface{}, error) {
+		sendNextTimeout = opts.SendNextTimeout
+		return nil, nil
+	}
+	ctx := &DistSenderContext{
+		rpcSend:          testFn,
+		HedgeReadTimeout: hedgeTimeout,
+		rangeDescriptorDB: mockRangeDescriptorDB(func(_ proto.Key) ([]proto.RangeDescriptor, error) {
+			return []proto.RangeDescriptor{testRangeDescriptor}, nil
+		}),
+	}
+	ds := NewDistSender(ctx, g)
+
+	ds.Send(client.GetCall(proto.Key("a")))
+	if sendNextTimeout != hedgeTimeout {
+		t.Errorf("expected read to use hedge timeout %s; got %s", hedgeTimeout, sendNextTimeout)
+	}
+	ds.Send(client.PutCall(proto.Key("a"), []byte("value")))
+	if sendNextTimeout != defaultSendNextTimeout {
+		t.Errorf("expected write to use default timeout %s; got %s", defaultSendNextTimeout, sendNextTimeout)
+	}
+}
+
+// TestRetryBudgetExhausted verifies that the DistSender stops
+// retrying retryable errors once its retry budget is exhausted.
+func TestRetryBudgetExhausted(t *testing.T) {
+	g := makeTestGossip(t)
+	attempts := 0
+	var testFn rpcSendFn = func(_ rpc.Options, _ string, _ []net.Addr, _ func(addr net.Addr) interface{}, _ func() interface{}, _ *rpc.Context) ([]interface{}, error) {
+		attempts++
+		return nil, noNodeAddrsAvailError{}
+	}
+	retryOpts := defaultRPCRetryOptions
+	retryOpts.Backoff = time.Millisecond
+	retryOpts.MaxAttempts = 10
+	ctx := &DistSenderContext{
+		rpcSend:               testFn,
+		RPCRetryOptions:       &retryOpts,
+		RetryBudgetRatio:      0.1,
+		RetryBudgetMaxRetries: 2,
+		rangeDescriptorDB: mockRangeDescriptorDB(func(_ proto.Key) ([]proto.RangeDescriptor, error) {
+			return []proto.RangeDescriptor{testRangeDescriptor}, nil
+		}),
+	}
+	ds := NewDistSender(ctx, g)
+	call := client.GetCall(proto.Key("a"))
+	ds.Send(call)
+	if call.Reply.Header().GoError() == nil {
+		t.Fatal("expected an error once the retry budget is exhausted")
+	}
+	// The initial attempt plus the two retries permitted by the budget.
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts; got %d", attempts)
+	}
+}
@@ -52,13 +52,15 @@ func startServer(t *testing.T) (string, *client.KV, *util.Stopper) {
 	// Initialize engine, store, and localDB.
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
 	stopper := util.NewStopper()
-	db, err := server.BootstrapCluster("test-cluster", e, stopper)
+	db, err := server.BootstrapCluster("test-cluster", e, nil, stopper)
 	if err != nil {
 		t.Fatalf("could not bootstrap test cluster: %s", err)
 	}
 	mux := http.NewServeMux()
 	mux.Handle(RESTPrefix, NewRESTServer(db))
 	mux.Handle(DBPrefix, NewDBServer(db.Sender))
+	mux.Handle(BatchPrefix, NewBatchServer(db))
+	mux.Handle(BulkLoadPrefix, NewBulkLoadServer(db))
 	server := httptest.NewTLSServer(mux)
 	stopper.AddCloser(server)
 	addr := server.Listener.Addr().String()
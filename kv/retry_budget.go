@@ -0,0 +1,79 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A retryBudgetExhaustedError indicates that a request was not retried
+// because the DistSender's retry budget was exhausted.
+type retryBudgetExhaustedError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (r retryBudgetExhaustedError) Error() string {
+	return fmt.Sprintf("retry budget exhausted: %s", r.err)
+}
+
+// A retryBudget limits the number of retries performed by a
+// DistSender relative to the number of requests it sends. This
+// prevents a slow or failing node from multiplying the load on a
+// cluster through retries. Each request deposits ratio tokens into
+// the budget and each retry withdraws one token. The budget holds at
+// most maxTokens and starts out full, which allows short bursts of
+// retries.
+type retryBudget struct {
+	sync.Mutex
+	ratio     float64
+	tokens    float64
+	maxTokens float64
+}
+
+// newRetryBudget returns a retryBudget which allows retries for the
+// given ratio of requests, with bursts of up to maxRetries.
+func newRetryBudget(ratio float64, maxRetries int) *retryBudget {
+	return &retryBudget{
+		ratio:     ratio,
+		tokens:    float64(maxRetries),
+		maxTokens: float64(maxRetries),
+	}
+}
+
+// deposit is invoked for every request sent and adds ratio tokens
+// to the budget.
+func (rb *retryBudget) deposit() {
+	rb.Lock()
+	defer rb.Unlock()
+	rb.tokens += rb.ratio
+	if rb.tokens > rb.maxTokens {
+		rb.tokens = rb.maxTokens
+	}
+}
+
+// withdraw is invoked before a retry and returns whether the retry
+// may proceed.
+func (rb *retryBudget) withdraw() bool {
+	rb.Lock()
+	defer rb.Unlock()
+	if rb.tokens < 1 {
+		return false
+	}
+	rb.tokens--
+	return true
+}
@@ -0,0 +1,54 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import "testing"
+
+// TestRetryBudget verifies that the retry budget allows an initial
+// burst of retries and is replenished by requests at the configured
+// ratio.
+func TestRetryBudget(t *testing.T) {
+	rb := newRetryBudget(0.5, 2)
+	for i := 0; i < 2; i++ {
+		if !rb.withdraw() {
+			t.Fatalf("%d: expected retry to be allowed", i)
+		}
+	}
+	if rb.withdraw() {
+		t.Fatal("expected retry budget to be exhausted")
+	}
+	// A single request only deposits half a token.
+	rb.deposit()
+	if rb.withdraw() {
+		t.Fatal("expected retry budget to be exhausted after one request")
+	}
+	rb.deposit()
+	if !rb.withdraw() {
+		t.Fatal("expected retry to be allowed after two requests")
+	}
+	// Deposits are capped at the maximum number of retries.
+	for i := 0; i < 10; i++ {
+		rb.deposit()
+	}
+	for i := 0; i < 2; i++ {
+		if !rb.withdraw() {
+			t.Fatalf("%d: expected retry to be allowed", i)
+		}
+	}
+	if rb.withdraw() {
+		t.Fatal("expected retry budget to be capped")
+	}
+}
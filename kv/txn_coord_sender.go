@@ -32,6 +32,15 @@ import (
 	gogoproto "github.com/gogo/protobuf/proto"
 )
 
+const (
+	// DefaultMaxBatchRequests is the default maximum number of requests
+	// in a batch accepted by a TxnCoordSender.
+	DefaultMaxBatchRequests = 10000
+	// DefaultMaxBatchBytes is the default maximum size of the requests
+	// in a batch accepted by a TxnCoordSender.
+	DefaultMaxBatchBytes = 64 << 20 // 64 MB
+)
+
 // txnMetadata holds information about an ongoing transaction, as
 // seen from the perspective of this coordinator. It records all
 // keys (and key ranges) mutated as part of the transaction for
@@ -167,6 +176,11 @@ type TxnCoordSender struct {
 	sync.Mutex                                // Protects the txns map.
 	txns              map[string]*txnMetadata // txn key to metadata
 	linearizable      bool                    // Enables linearizable behaviour.
+	batchConcurrency  int                     // Max parallel calls per batch.
+	pipelineWrites    bool                    // Enables write pipelining.
+	pipelines         map[string]*txnPipeline // txn ID to pipelined writes
+	maxBatchRequests  int                     // Max requests per batch; 0 is unlimited.
+	maxBatchBytes     int64                   // Max bytes per batch; 0 is unlimited.
 	stopper           *util.Stopper
 }
 
@@ -182,26 +196,79 @@ func NewTxnCoordSender(wrapped client.KVSender, clock *hlc.Clock, linearizable b
 		clientTimeout:     defaultClientTimeout,
 		txns:              map[string]*txnMetadata{},
 		linearizable:      linearizable,
+		batchConcurrency:  defaultBatchConcurrency,
+		pipelines:         map[string]*txnPipeline{},
+		maxBatchRequests:  DefaultMaxBatchRequests,
+		maxBatchBytes:     DefaultMaxBatchBytes,
 		stopper:           stopper,
 	}
 	return tc
 }
 
+// SetPipelineWrites enables or disables the pipelining of
+// transactional writes. A pipelined Put or Delete is sent without
+// waiting for it to achieve consensus and is acknowledged to the client
+// immediately. Later requests of the transaction which overlap it wait
+// for it, and EndTransaction waits for all pipelined writes. If any of
+// them failed, the first failure is returned in place of the reply to
+// the next request, leaving the client to retry or abort the
+// transaction.
+func (tc *TxnCoordSender) SetPipelineWrites(enabled bool) {
+	tc.pipelineWrites = enabled
+}
+
+// SetBatchLimits sets the maximum number of requests in a batch and
+// the maximum total size in bytes of those requests. A batch exceeding
+// either limit is rejected with a BatchTooLargeError before any of its
+// requests are executed; zero values are unlimited.
+func (tc *TxnCoordSender) SetBatchLimits(maxRequests int, maxBytes int64) {
+	tc.maxBatchRequests = maxRequests
+	tc.maxBatchBytes = maxBytes
+}
+
 // Send implements the client.KVSender interface. If the call is part
 // of a transaction, the coordinator will initialize the transaction
 // if it's not nil but has an empty ID.
 func (tc *TxnCoordSender) Send(call client.Call) {
+	breq, isBatch := call.Args.(*proto.BatchRequest)
+	if isBatch {
+		if err := tc.checkBatchLimits(breq); err != nil {
+			call.Reply.Header().SetGoError(err)
+			return
+		}
+	}
+
 	header := call.Args.Header()
 	tc.maybeBeginTxn(header)
 
 	// Process batch specially; otherwise, send via wrapped sender.
-	if breq, ok := call.Args.(*proto.BatchRequest); ok {
+	if isBatch {
 		tc.sendBatch(breq, call.Reply.(*proto.BatchResponse))
 	} else {
 		tc.sendOne(call)
 	}
 }
 
+// checkBatchLimits returns a BatchTooLargeError if the batch holds
+// more requests or bytes than the coordinator accepts.
+func (tc *TxnCoordSender) checkBatchLimits(batchArgs *proto.BatchRequest) error {
+	var size int64
+	for i := range batchArgs.Requests {
+		size += int64(batchArgs.Requests[i].Size())
+	}
+	count := len(batchArgs.Requests)
+	if (tc.maxBatchRequests > 0 && count > tc.maxBatchRequests) ||
+		(tc.maxBatchBytes > 0 && size > tc.maxBatchBytes) {
+		return &proto.BatchTooLargeError{
+			Requests:    int32(count),
+			Bytes:       size,
+			MaxRequests: int32(tc.maxBatchRequests),
+			MaxBytes:    tc.maxBatchBytes,
+		}
+	}
+	return nil
+}
+
 // maybeBeginTxn begins a new transaction if a txn has been specified
 // in the request but has a nil ID. The new transaction is initialized
 // using the name and isolation in the otherwise uninitialized txn.
@@ -221,7 +288,75 @@ func (tc *TxnCoordSender) maybeBeginTxn(header *proto.RequestHeader) {
 	}
 }
 
-// sendOne sends a single call via the wrapped sender. If the call is
+// sendOne sends a single call, pipelining it if it's a transactional
+// write and pipelining is enabled. Otherwise, the call waits for any
+// overlapping pipelined writes of its transaction, or for all of them
+// if it's an EndTransaction, and is sent via sendOneSync.
+func (tc *TxnCoordSender) sendOne(call client.Call) {
+	header := call.Args.Header()
+	if !tc.pipelineWrites || header.Txn == nil {
+		tc.sendOneSync(call)
+		return
+	}
+	txnID := string(header.Txn.ID)
+	tc.Lock()
+	p, ok := tc.pipelines[txnID]
+	if !ok {
+		p = newTxnPipeline()
+		tc.pipelines[txnID] = p
+	}
+	tc.Unlock()
+
+	_, endTxn := call.Args.(*proto.EndTransactionRequest)
+	p.wait(header.Key, header.EndKey, endTxn)
+	if failed := p.update(header.Txn); failed != nil {
+		p.wait(nil, nil, true)
+		tc.removePipeline(txnID)
+		*call.Reply.Header() = *failed
+		call.Reply.Header().Txn = gogoproto.Clone(failed.Txn).(*proto.Transaction)
+		return
+	}
+	if isPipelinable(call.Args) && tc.stopper.StartTask() {
+		tc.sendPipelined(p, call)
+		return
+	}
+	tc.sendOneSync(call)
+	if endTxn || call.Reply.Header().Error != nil {
+		// Don't let writes from this epoch of the transaction complete
+		// after the client has seen the error and restarted.
+		p.wait(nil, nil, true)
+		tc.removePipeline(txnID)
+	}
+}
+
+// sendPipelined sends a copy of the call asynchronously and replies to
+// the call immediately with the request's transaction. The caller must
+// have started a task on the stopper.
+func (tc *TxnCoordSender) sendPipelined(p *txnPipeline, call client.Call) {
+	header := call.Args.Header()
+	args := gogoproto.Clone(call.Args).(proto.Request)
+	w := p.add(header.Key, header.EndKey)
+	go func() {
+		defer tc.stopper.FinishTask()
+		reply := args.CreateReply()
+		tc.sendOneSync(client.Call{Args: args, Reply: reply})
+		if err := reply.Header().GoError(); err != nil {
+			log.V(1).Infof("pipelined %s to %q failed: %s", args.Method(), args.Header().Key, err)
+		}
+		p.complete(w, reply.Header())
+	}()
+	call.Reply.Header().Timestamp = header.Txn.Timestamp
+	call.Reply.Header().Txn = gogoproto.Clone(header.Txn).(*proto.Transaction)
+}
+
+// removePipeline forgets the pipelined writes of the transaction.
+func (tc *TxnCoordSender) removePipeline(txnID string) {
+	tc.Lock()
+	delete(tc.pipelines, txnID)
+	tc.Unlock()
+}
+
+// sendOneSync sends a single call via the wrapped sender. If the call is
 // part of a transaction, the TxnCoordSender adds the transaction to a
 // map of active transactions and begins heartbeating it. Every
 // subsequent call for the same transaction updates the lastUpdateTS
@@ -234,7 +369,7 @@ func (tc *TxnCoordSender) maybeBeginTxn(header *proto.RequestHeader) {
 // key range is recorded as live intents for eventual cleanup upon
 // transaction commit. Upon successful txn commit, initiates cleanup
 // of intents.
-func (tc *TxnCoordSender) sendOne(call client.Call) {
+func (tc *TxnCoordSender) sendOneSync(call client.Call) {
 	var startNS int64
 	header := call.Args.Header()
 	// If this call is part of a transaction...
@@ -287,6 +422,14 @@ func (tc *TxnCoordSender) sendOne(call client.Call) {
 		txnMeta.lastUpdateTS = tc.clock.Now()
 		txnMeta.addKeyRange(header.Key, header.EndKey)
 		tc.Unlock()
+	} else if header.Txn != nil {
+		// Any other call, including the client's own heartbeats, shows
+		// the client to be live.
+		tc.Lock()
+		if txnMeta, ok := tc.txns[string(header.Txn.ID)]; ok {
+			txnMeta.lastUpdateTS = tc.clock.Now()
+		}
+		tc.Unlock()
 	}
 
 	// Cleanup intents and transaction map if end of transaction.
@@ -305,6 +448,7 @@ func (tc *TxnCoordSender) sendOne(call client.Call) {
 		tmpKV := client.NewKV(nil, tc)
 		tmpKV.User = call.Args.Header().User
 		tmpKV.UserPriority = call.Args.Header().GetUserPriority()
+		tmpKV.PriorityClass = call.Args.Header().PriorityClass
 		call.Reply.Reset()
 		tmpKV.RunTransaction(txnOpts, func(txn *client.Txn) error {
 			return txn.Run(call)
@@ -342,42 +486,184 @@ func (tc *TxnCoordSender) sendOne(call client.Call) {
 }
 
 // sendBatch unrolls a batched command and sends each constituent
-// command in parallel.
+// command. Unless the batch requires ordered execution, constituent
+// commands are sent in parallel, with at most batchConcurrency calls
+// in flight at a time. EndTransaction requests, and commands whose
+// keys overlap those of a preceding command, act as a barrier: all
+// preceding commands must complete before one is sent, so that it
+// sees their writes and the transaction as they left it.
+//
+// A transactional batch stops at the first error. The commands of a
+// non-transactional batch are all sent, each reply holding its own
+// error, unless the batch is all-or-nothing, in which case it's run
+// in a transaction of its own.
 func (tc *TxnCoordSender) sendBatch(batchArgs *proto.BatchRequest, batchReply *proto.BatchResponse) {
+	if batchArgs.AllOrNothing && batchArgs.Txn == nil {
+		tc.sendBatchInTxn(batchArgs, batchReply)
+		return
+	}
+	partial := batchArgs.Txn == nil
+
 	// Prepare the calls by unrolling the batch. If the batchReply is
 	// pre-initialized with replies, use those; otherwise create replies
 	// as needed.
-	// TODO(spencer): send calls in parallel.
 	batchReply.Txn = batchArgs.Txn
+	calls := make([]client.Call, len(batchArgs.Requests))
 	for i := range batchArgs.Requests {
 		// Initialize args header values where appropriate.
 		args := batchArgs.Requests[i].GetValue().(proto.Request)
-		call := client.Call{Args: args}
 		if args.Header().User == "" {
 			args.Header().User = batchArgs.User
 		}
 		if args.Header().UserPriority == nil {
 			args.Header().UserPriority = batchArgs.UserPriority
 		}
+		if args.Header().PriorityClass == proto.NORMAL_PRIORITY {
+			args.Header().PriorityClass = batchArgs.PriorityClass
+		}
 		args.Header().Txn = batchArgs.Txn
+		calls[i].Args = args
 
 		// Create a reply from the method type and add to batch response.
 		if i >= len(batchReply.Responses) {
-			call.Reply = args.CreateReply()
-			batchReply.Add(call.Reply)
+			calls[i].Reply = args.CreateReply()
+			batchReply.Add(calls[i].Reply)
 		} else {
-			call.Reply = batchReply.Responses[i].GetValue().(proto.Response)
+			calls[i].Reply = batchReply.Responses[i].GetValue().(proto.Response)
 		}
-		tc.sendOne(call)
-		// Amalgamate transaction updates and propagate first error, if applicable.
-		if batchReply.Txn != nil {
-			batchReply.Txn.Update(call.Reply.Header().Txn)
+	}
+
+	if batchArgs.Ordered {
+		for _, call := range calls {
+			tc.sendOne(call)
+			if !tc.mergeBatchReply(call, batchReply) && !partial {
+				return
+			}
 		}
-		if call.Reply.Header().Error != nil {
+		return
+	}
+
+	for len(calls) > 0 {
+		// Send all calls up to the next EndTransaction or call which
+		// overlaps a preceding one in parallel.
+		n := 0
+		for n < len(calls) {
+			if _, ok := calls[n].Args.(*proto.EndTransactionRequest); ok {
+				break
+			}
+			if overlapsCalls(calls[n], calls[:n]) {
+				break
+			}
+			n++
+		}
+		if batchReply.Txn != nil && n > 1 {
+			// Calls sent in parallel must not share the same txn.
+			for _, call := range calls[:n] {
+				call.Args.Header().Txn = gogoproto.Clone(batchReply.Txn).(*proto.Transaction)
+			}
+		}
+		tc.sendParallel(calls[:n])
+		// Amalgamate transaction updates in order of the requests and
+		// propagate the first error, if applicable.
+		for _, call := range calls[:n] {
+			if !tc.mergeBatchReply(call, batchReply) && !partial {
+				return
+			}
+		}
+		if n < len(calls) {
+			calls[n].Args.Header().Txn = batchReply.Txn
+			tc.sendOne(calls[n])
+			if !tc.mergeBatchReply(calls[n], batchReply) && !partial {
+				return
+			}
+			n++
+		}
+		calls = calls[n:]
+	}
+}
+
+// overlapsCalls returns whether the keys of the call overlap those of
+// any of the other calls.
+func overlapsCalls(call client.Call, others []client.Call) bool {
+	key, endKey := callSpan(call)
+	for _, other := range others {
+		oKey, oEndKey := callSpan(other)
+		if key.Less(oEndKey) && oKey.Less(endKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// callSpan returns the key range [key, endKey) addressed by the call.
+func callSpan(call client.Call) (proto.Key, proto.Key) {
+	header := call.Args.Header()
+	if len(header.EndKey) == 0 {
+		return header.Key, header.Key.Next()
+	}
+	return header.Key, header.EndKey
+}
+
+// sendBatchInTxn runs a non-transactional batch in a transaction of
+// its own, retrying as necessary. If any command fails, none of the
+// batch's commands take effect.
+func (tc *TxnCoordSender) sendBatchInTxn(batchArgs *proto.BatchRequest, batchReply *proto.BatchResponse) {
+	// Must not call Close() on this KV - that would call tc.Close().
+	tmpKV := client.NewKV(nil, tc)
+	tmpKV.User = batchArgs.User
+	tmpKV.UserPriority = batchArgs.GetUserPriority()
+	tmpKV.PriorityClass = batchArgs.PriorityClass
+	txnOpts := &client.TransactionOptions{
+		Name: "all-or-nothing batch",
+	}
+	err := tmpKV.RunTransaction(txnOpts, func(txn *client.Txn) error {
+		batchReply.Reset()
+		return txn.Run(client.Call{Args: batchArgs, Reply: batchReply})
+	})
+	if err != nil && batchReply.Error == nil {
+		batchReply.SetGoError(err)
+	}
+}
+
+// sendParallel sends the supplied calls via sendOne, with at most
+// batchConcurrency calls in flight at a time, and waits for all of
+// them to complete.
+func (tc *TxnCoordSender) sendParallel(calls []client.Call) {
+	if len(calls) == 1 {
+		tc.sendOne(calls[0])
+		return
+	}
+	sem := make(chan struct{}, tc.batchConcurrency)
+	var wg sync.WaitGroup
+	for _, call := range calls {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(call client.Call) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			tc.sendOne(call)
+		}(call)
+	}
+	wg.Wait()
+}
+
+// mergeBatchReply amalgamates the transaction of the call's reply
+// into the batch reply. If the call's reply contains an error, the
+// error is propagated to the batch reply, unless it already holds an
+// earlier error, and false is returned.
+func (tc *TxnCoordSender) mergeBatchReply(call client.Call, batchReply *proto.BatchResponse) bool {
+	if batchReply.Txn != nil {
+		batchReply.Txn.Update(call.Reply.Header().Txn)
+	}
+	if call.Reply.Header().Error != nil {
+		if batchReply.Error == nil {
 			batchReply.Error = call.Reply.Header().Error
-			return
 		}
+		return false
 	}
+	return true
 }
 
 // updateResponseTxn updates the response txn based on the response
@@ -463,6 +749,7 @@ func (tc *TxnCoordSender) hasClientAbandonedCoord(txnID []byte) bool {
 	timeout.WallTime -= txnMeta.timeoutDuration.Nanoseconds()
 	if txnMeta.lastUpdateTS.Less(timeout) {
 		delete(tc.txns, string(txnID))
+		delete(tc.pipelines, string(txnID))
 		return true
 	}
 	return false
@@ -473,51 +760,31 @@ func (tc *TxnCoordSender) hasClientAbandonedCoord(txnID []byte) bool {
 // aborted or committed or if the TxnCoordSender is closed.
 func (tc *TxnCoordSender) heartbeat(txn *proto.Transaction) {
 	tc.stopper.RunWorker(func() {
-		ticker := time.NewTicker(tc.heartbeatInterval)
-		request := &proto.InternalHeartbeatTxnRequest{
-			RequestHeader: proto.RequestHeader{
-				Key:  txn.Key,
-				User: storage.UserRoot,
-				Txn:  txn,
-			},
-		}
-
-		// Loop with ticker for periodic heartbeats.
-		for {
-			select {
-			case <-ticker.C:
-				if !tc.stopper.StartTask() {
-					continue
-				}
-				// Before we send a heartbeat, determine whether this transaction
-				// should be considered abandoned. If so, exit heartbeat.
-				if tc.hasClientAbandonedCoord(txn.ID) {
-					log.V(1).Infof("transaction %q:%q abandoned; stopping heartbeat", txn.Key, txn.ID)
-					tc.stopper.FinishTask()
-					return
-				}
-				request.Header().Timestamp = tc.clock.Now()
-				reply := &proto.InternalHeartbeatTxnResponse{}
-				call := client.Call{
-					Args:  request,
-					Reply: reply,
-				}
-				tc.wrapped.Send(call)
-				// If the transaction is not in pending state, then we can stop
-				// the heartbeat. It's either aborted or committed, and we resolve
-				// write intents accordingly.
-				if reply.GoError() != nil {
-					log.Warningf("heartbeat to %q:%q failed: %s", txn.Key, txn.ID, reply.GoError())
-				} else if reply.Txn != nil && reply.Txn.Status != proto.PENDING {
-					tc.cleanupTxn(reply.Txn, nil)
-					tc.stopper.FinishTask()
-					return
-				}
-				tc.stopper.FinishTask()
-
-			case <-tc.stopper.ShouldStop():
-				return
+		client.HeartbeatLoop(tc.heartbeatInterval, tc.stopper.ShouldStop(), func() bool {
+			if !tc.stopper.StartTask() {
+				return true
 			}
-		}
+			defer tc.stopper.FinishTask()
+			// Before we send a heartbeat, determine whether this transaction
+			// should be considered abandoned. If so, exit heartbeat.
+			if tc.hasClientAbandonedCoord(txn.ID) {
+				log.V(1).Infof("transaction %q:%q abandoned; stopping heartbeat", txn.Key, txn.ID)
+				return false
+			}
+			call := client.TxnHeartbeatCall(txn, storage.UserRoot)
+			call.Args.Header().Timestamp = tc.clock.Now()
+			tc.wrapped.Send(call)
+			// If the transaction is not in pending state, then we can stop
+			// the heartbeat. It's either aborted or committed, and we resolve
+			// write intents accordingly.
+			reply := call.Reply.(*proto.InternalHeartbeatTxnResponse)
+			if reply.GoError() != nil {
+				log.Warningf("heartbeat to %q:%q failed: %s", txn.Key, txn.ID, reply.GoError())
+			} else if reply.Txn != nil && reply.Txn.Status != proto.PENDING {
+				tc.cleanupTxn(reply.Txn, nil)
+				return false
+			}
+			return true
+		})
 	})
 }
@@ -19,7 +19,10 @@ package kv
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -545,3 +548,370 @@ func TestTxnCoordSenderTxnUpdatedOnError(t *testing.T) {
 		}
 	}
 }
+
+// TestTxnCoordSenderBatchParallel verifies that the constituent
+// requests of a batch are sent in parallel, up to the batch
+// concurrency limit, unless the batch requires ordered execution.
+func TestTxnCoordSenderBatchParallel(t *testing.T) {
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+
+	for _, ordered := range []bool{false, true} {
+		stopper := util.NewStopper()
+		var mu sync.Mutex
+		var inFlight, maxInFlight int
+		var keys []string
+		ts := NewTxnCoordSender(newTestSender(func(call client.Call) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			keys = append(keys, string(call.Args.Header().Key))
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}), clock, false, stopper)
+		ts.batchConcurrency = 4
+
+		bArgs := &proto.BatchRequest{Ordered: ordered}
+		for i := 0; i < 10; i++ {
+			bArgs.Add(&proto.PutRequest{
+				RequestHeader: proto.RequestHeader{Key: proto.Key(fmt.Sprintf("%02d", i))},
+			})
+		}
+		bReply := &proto.BatchResponse{}
+		ts.Send(client.Call{Args: bArgs, Reply: bReply})
+		stopper.Stop()
+
+		if err := bReply.GoError(); err != nil {
+			t.Fatal(err)
+		}
+		if len(bReply.Responses) != 10 || len(keys) != 10 {
+			t.Fatalf("expected 10 requests and responses; got %d, %d", len(keys), len(bReply.Responses))
+		}
+		if ordered {
+			if maxInFlight != 1 {
+				t.Errorf("expected ordered batch to be sent sequentially; got %d in flight", maxInFlight)
+			}
+			if !sort.StringsAreSorted(keys) {
+				t.Errorf("expected ordered batch to be sent in order; got %s", keys)
+			}
+		} else if maxInFlight <= 1 || maxInFlight > ts.batchConcurrency {
+			t.Errorf("expected between 2 and %d requests in flight; got %d", ts.batchConcurrency, maxInFlight)
+		}
+	}
+}
+
+// TestTxnCoordSenderBatchEndTxnLast verifies that an EndTransaction
+// request in an unordered batch is only sent after all preceding
+// requests have completed.
+func TestTxnCoordSenderBatchEndTxnLast(t *testing.T) {
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+
+	var mu sync.Mutex
+	var puts int
+	ts := NewTxnCoordSender(newTestSender(func(call client.Call) {
+		if _, ok := call.Args.(*proto.EndTransactionRequest); ok {
+			mu.Lock()
+			defer mu.Unlock()
+			if puts != 5 {
+				call.Reply.Header().SetGoError(util.Errorf("end transaction sent after %d of 5 puts", puts))
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		puts++
+		mu.Unlock()
+	}), clock, false, stopper)
+
+	bArgs := &proto.BatchRequest{
+		RequestHeader: proto.RequestHeader{
+			User: storage.UserRoot,
+			Txn:  &proto.Transaction{Name: "test txn"},
+		},
+	}
+	for i := 0; i < 5; i++ {
+		bArgs.Add(&proto.PutRequest{
+			RequestHeader: proto.RequestHeader{Key: proto.Key(fmt.Sprintf("%02d", i))},
+		})
+	}
+	bArgs.Add(&proto.EndTransactionRequest{Commit: true})
+	bReply := &proto.BatchResponse{}
+	ts.Send(client.Call{Args: bArgs, Reply: bReply})
+	if err := bReply.GoError(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTxnCoordSenderBatchOverlapping verifies that a request of an
+// unordered transactional batch whose key overlaps that of a preceding
+// request is only sent once the preceding request has completed, and
+// with the transaction as updated by it.
+func TestTxnCoordSenderBatchOverlapping(t *testing.T) {
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+
+	var mu sync.Mutex
+	var events []string
+	var putTimestamps []proto.Timestamp
+	ts := NewTxnCoordSender(newTestSender(func(call client.Call) {
+		header := call.Args.Header()
+		if string(header.Key) != "a" {
+			return
+		}
+		method := call.Args.Method().String()
+		mu.Lock()
+		events = append(events, "start "+method)
+		if _, ok := call.Args.(*proto.PutRequest); ok {
+			putTimestamps = append(putTimestamps, header.Txn.Timestamp)
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		// The first write pushes the transaction's timestamp.
+		txn := gogoproto.Clone(header.Txn).(*proto.Transaction)
+		txn.Timestamp = proto.Timestamp{WallTime: 10}
+		call.Reply.Header().Txn = txn
+		mu.Lock()
+		events = append(events, "end "+method)
+		mu.Unlock()
+	}), clock, false, stopper)
+
+	bArgs := &proto.BatchRequest{
+		RequestHeader: proto.RequestHeader{
+			User: storage.UserRoot,
+			Txn:  &proto.Transaction{Name: "test txn"},
+		},
+	}
+	put := func(key string) *proto.PutRequest {
+		return &proto.PutRequest{RequestHeader: proto.RequestHeader{Key: proto.Key(key)}}
+	}
+	bArgs.Add(put("a"))
+	bArgs.Add(&proto.GetRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("a")}})
+	bArgs.Add(put("a"))
+	bArgs.Add(put("b"))
+	bReply := &proto.BatchResponse{}
+	ts.Send(client.Call{Args: bArgs, Reply: bReply})
+	if err := bReply.GoError(); err != nil {
+		t.Fatal(err)
+	}
+
+	expEvents := []string{"start Put", "end Put", "start Get", "end Get", "start Put", "end Put"}
+	if !reflect.DeepEqual(events, expEvents) {
+		t.Errorf("expected requests to %q to be sent in order %v; got %v", "a", expEvents, events)
+	}
+	if len(putTimestamps) != 2 || putTimestamps[1].WallTime != 10 {
+		t.Errorf("expected second put to see the pushed timestamp; got %v", putTimestamps)
+	}
+}
+
+// TestTxnCoordSenderBatchPartialOverlapping verifies that a failing
+// request of a non-transactional batch whose key overlaps that of a
+// preceding request doesn't prevent the requests following it from
+// being sent.
+func TestTxnCoordSenderBatchPartialOverlapping(t *testing.T) {
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+
+	var mu sync.Mutex
+	var keys []string
+	ts := NewTxnCoordSender(newTestSender(func(call client.Call) {
+		mu.Lock()
+		keys = append(keys, string(call.Args.Header().Key))
+		mu.Unlock()
+		if _, ok := call.Args.(*proto.ConditionalPutRequest); ok {
+			call.Reply.Header().SetGoError(util.Errorf("unexpected value"))
+		}
+	}), clock, false, stopper)
+
+	bArgs := &proto.BatchRequest{
+		RequestHeader: proto.RequestHeader{User: storage.UserRoot},
+	}
+	bArgs.Add(&proto.PutRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("a")}})
+	bArgs.Add(&proto.ConditionalPutRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("a")}})
+	bArgs.Add(&proto.PutRequest{RequestHeader: proto.RequestHeader{Key: proto.Key("c")}})
+	bReply := &proto.BatchResponse{}
+	ts.Send(client.Call{Args: bArgs, Reply: bReply})
+
+	sort.Strings(keys)
+	if expKeys := []string{"a", "a", "c"}; !reflect.DeepEqual(keys, expKeys) {
+		t.Fatalf("expected requests to %v to be sent; got %v", expKeys, keys)
+	}
+	if len(bReply.Responses) != 3 {
+		t.Fatalf("expected 3 responses; got %d", len(bReply.Responses))
+	}
+	for i, expErr := range []bool{false, true, false} {
+		err := bReply.Responses[i].GetValue().(proto.Response).Header().GoError()
+		if (err != nil) != expErr {
+			t.Errorf("%d: expected error %t; got %v", i, expErr, err)
+		}
+	}
+}
+
+// TestTxnCoordSenderPipelineWrites verifies that pipelined writes are
+// acknowledged before they complete, that overlapping requests and
+// the commit wait for them, and that a failed pipelined write is
+// returned in place of the commit.
+func TestTxnCoordSenderPipelineWrites(t *testing.T) {
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+
+	for _, fail := range []bool{false, true} {
+		stopper := util.NewStopper()
+		release := map[string]chan struct{}{"a": make(chan struct{}), "b": make(chan struct{})}
+		var mu sync.Mutex
+		var puts int
+		var endTxnSent bool
+		ts := NewTxnCoordSender(newTestSender(func(call client.Call) {
+			switch call.Args.(type) {
+			case *proto.PutRequest:
+				<-release[string(call.Args.Header().Key)]
+				mu.Lock()
+				puts++
+				mu.Unlock()
+				if fail && string(call.Args.Header().Key) == "b" {
+					call.Reply.Header().SetGoError(&proto.TransactionRetryError{})
+				}
+			case *proto.GetRequest:
+				mu.Lock()
+				defer mu.Unlock()
+				if puts != 1 {
+					call.Reply.Header().SetGoError(util.Errorf("get sent after %d puts", puts))
+				}
+			case *proto.EndTransactionRequest:
+				mu.Lock()
+				defer mu.Unlock()
+				endTxnSent = true
+				if puts != 2 {
+					call.Reply.Header().SetGoError(util.Errorf("end transaction sent after %d of 2 puts", puts))
+				}
+			}
+		}), clock, false, stopper)
+		ts.SetPipelineWrites(true)
+
+		txn := &proto.Transaction{Name: "test txn"}
+		for _, key := range []string{"a", "b"} {
+			reply := &proto.PutResponse{}
+			ts.Send(client.Call{
+				Args: &proto.PutRequest{RequestHeader: proto.RequestHeader{
+					Key: proto.Key(key), User: storage.UserRoot, Txn: txn}},
+				Reply: reply,
+			})
+			if err := reply.GoError(); err != nil {
+				t.Fatal(err)
+			}
+			txn = reply.Txn
+		}
+		// Both puts were acknowledged while blocked; release the first,
+		// which the get of its key waits for.
+		close(release["a"])
+		getReply := &proto.GetResponse{}
+		ts.Send(client.Call{
+			Args: &proto.GetRequest{RequestHeader: proto.RequestHeader{
+				Key: proto.Key("a"), User: storage.UserRoot, Txn: txn}},
+			Reply: getReply,
+		})
+		if err := getReply.GoError(); err != nil {
+			t.Fatal(err)
+		}
+		close(release["b"])
+
+		etReply := &proto.EndTransactionResponse{}
+		ts.Send(client.Call{
+			Args: &proto.EndTransactionRequest{RequestHeader: proto.RequestHeader{
+				User: storage.UserRoot, Txn: txn}, Commit: true},
+			Reply: etReply,
+		})
+		if fail {
+			if _, ok := etReply.GoError().(*proto.TransactionRetryError); !ok {
+				t.Errorf("expected retry error from failed put; got %v", etReply.GoError())
+			}
+			if endTxnSent {
+				t.Errorf("expected end transaction not to be sent after failed put")
+			}
+		} else if err := etReply.GoError(); err != nil {
+			t.Error(err)
+		}
+		ts.Lock()
+		if len(ts.pipelines) != 0 {
+			t.Errorf("expected pipeline to be removed; got %d", len(ts.pipelines))
+		}
+		ts.Unlock()
+		stopper.Stop()
+	}
+}
+
+// TestTxnCoordSenderBatchLimits verifies that a batch exceeding the
+// request count or size limits is rejected with a retryable
+// BatchTooLargeError before any of its requests are sent.
+func TestTxnCoordSenderBatchLimits(t *testing.T) {
+	manual := hlc.NewManualClock(0)
+	clock := hlc.NewClock(manual.UnixNano)
+
+	value := proto.Value{Bytes: make([]byte, 100)}
+	testCases := []struct {
+		maxRequests int
+		maxBytes    int64
+		expErr      bool
+	}{
+		{0, 0, false},
+		{10, 0, false},
+		{9, 0, true},
+		{0, 10 << 10, false},
+		{0, 500, true},
+	}
+	for i, test := range testCases {
+		stopper := util.NewStopper()
+		var sent int
+		ts := NewTxnCoordSender(newTestSender(func(call client.Call) {
+			sent++
+		}), clock, false, stopper)
+		ts.SetBatchLimits(test.maxRequests, test.maxBytes)
+		ts.batchConcurrency = 1
+
+		bArgs := &proto.BatchRequest{}
+		for j := 0; j < 10; j++ {
+			bArgs.Add(&proto.PutRequest{
+				RequestHeader: proto.RequestHeader{Key: proto.Key(fmt.Sprintf("%02d", j))},
+				Value:         value,
+			})
+		}
+		bReply := &proto.BatchResponse{}
+		ts.Send(client.Call{Args: bArgs, Reply: bReply})
+		stopper.Stop()
+
+		if !test.expErr {
+			if err := bReply.GoError(); err != nil {
+				t.Errorf("%d: unexpected error: %s", i, err)
+			}
+			if sent != 10 {
+				t.Errorf("%d: expected 10 requests to be sent; got %d", i, sent)
+			}
+			continue
+		}
+		tooLarge, ok := bReply.GoError().(*proto.BatchTooLargeError)
+		if !ok {
+			t.Errorf("%d: expected batch too large error; got %v", i, bReply.GoError())
+			continue
+		}
+		if !bReply.Error.Retryable {
+			t.Errorf("%d: expected error to be retryable", i)
+		}
+		if tooLarge.Requests != 10 || tooLarge.Bytes < 1000 {
+			t.Errorf("%d: expected 10 requests of more than 1000 bytes; got %+v", i, tooLarge)
+		}
+		if sent != 0 {
+			t.Errorf("%d: expected no requests to be sent; got %d", i, sent)
+		}
+	}
+}
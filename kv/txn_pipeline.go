@@ -0,0 +1,133 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package kv
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// A pipelinedWrite is a transactional write which has been sent by the
+// coordinator without waiting for its reply. done is closed once the
+// write has been acknowledged.
+type pipelinedWrite struct {
+	key, endKey proto.Key
+	done        chan struct{}
+}
+
+// overlaps returns whether the write affects any key in [key, endKey),
+// or key itself if endKey is empty.
+func (w *pipelinedWrite) overlaps(key, endKey proto.Key) bool {
+	wStart, wEnd := keySpan(w.key, w.endKey)
+	start, end := keySpan(key, endKey)
+	return wStart.Less(end) && start.Less(wEnd)
+}
+
+// keySpan returns the half-open span of keys addressed by key and
+// endKey.
+func keySpan(key, endKey proto.Key) (proto.Key, proto.Key) {
+	if len(endKey) == 0 {
+		return key, key.Next()
+	}
+	return key, endKey
+}
+
+// A txnPipeline tracks the pipelined writes of a single transaction.
+// Proposing a write asynchronously lets a transaction continue while
+// the write achieves consensus, which over WAN replication saves a
+// round trip between replicas for each intermediate write. Requests
+// which overlap a pipelined write wait for it so that a transaction
+// observes its own writes, and the commit waits for all of them.
+type txnPipeline struct {
+	sync.Mutex
+	inFlight map[*pipelinedWrite]struct{}
+	// txn accumulates the transaction updates returned with the
+	// replies of completed writes.
+	txn proto.Transaction
+	// failed is the header of the first reply holding an error.
+	failed *proto.ResponseHeader
+}
+
+func newTxnPipeline() *txnPipeline {
+	return &txnPipeline{inFlight: map[*pipelinedWrite]struct{}{}}
+}
+
+// add records a write to the specified key or key range as in flight.
+func (p *txnPipeline) add(key, endKey proto.Key) *pipelinedWrite {
+	w := &pipelinedWrite{key: key, endKey: endKey, done: make(chan struct{})}
+	p.Lock()
+	p.inFlight[w] = struct{}{}
+	p.Unlock()
+	return w
+}
+
+// complete records the reply to a pipelined write and wakes any
+// requests waiting for it.
+func (p *txnPipeline) complete(w *pipelinedWrite, reply *proto.ResponseHeader) {
+	p.Lock()
+	delete(p.inFlight, w)
+	p.txn.Update(reply.Txn)
+	if reply.Error != nil && p.failed == nil {
+		p.failed = reply
+	}
+	p.Unlock()
+	close(w.done)
+}
+
+// wait blocks until no pipelined write overlapping the specified key
+// or key range is in flight. If all is true, it waits for every
+// pipelined write instead.
+func (p *txnPipeline) wait(key, endKey proto.Key, all bool) {
+	for {
+		var done chan struct{}
+		p.Lock()
+		for w := range p.inFlight {
+			if all || w.overlaps(key, endKey) {
+				done = w.done
+				break
+			}
+		}
+		p.Unlock()
+		if done == nil {
+			return
+		}
+		<-done
+	}
+}
+
+// update moves txn forward with the updates returned by completed
+// writes and returns the header of the first failed write's reply, or
+// nil if none have failed.
+func (p *txnPipeline) update(txn *proto.Transaction) *proto.ResponseHeader {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.txn.ID) > 0 {
+		txn.Update(&p.txn)
+	}
+	return p.failed
+}
+
+// isPipelinable returns whether the request may be pipelined: only
+// blind writes, whose replies carry nothing but the response header,
+// qualify.
+func isPipelinable(args proto.Request) bool {
+	switch args.(type) {
+	case *proto.PutRequest, *proto.DeleteRequest:
+		return true
+	}
+	return false
+}
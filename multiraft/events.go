@@ -18,6 +18,7 @@
 package multiraft
 
 import (
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/coreos/etcd/raft/raftpb"
 )
@@ -85,3 +86,29 @@ func decodeCommand(data []byte) (commandID string, command []byte) {
 	}
 	return string(data[1 : 1+commandIDLen]), data[1+commandIDLen:]
 }
+
+// DecodeEntry returns the command ID and the application-supplied
+// command held by a committed log entry, as delivered by an
+// EventCommandCommitted or EventMembershipChangeCommitted. Both are
+// empty for entries which don't hold a command, such as the empty
+// entries appended by new leaders. This allows logs to be read
+// offline; unlike the log processing of a running node, malformed
+// entries are reported with an error.
+func DecodeEntry(entry raftpb.Entry) (commandID string, command []byte, err error) {
+	data := entry.Data
+	if entry.Type == raftpb.EntryConfChange {
+		cc := raftpb.ConfChange{}
+		if err := cc.Unmarshal(entry.Data); err != nil {
+			return "", nil, util.Errorf("invalid ConfChange data at index %d: %s", entry.Index, err)
+		}
+		data = cc.Context
+	}
+	if len(data) == 0 {
+		return "", nil, nil
+	}
+	if data[0] != commandEncodingVersion || len(data) < 1+commandIDLen {
+		return "", nil, util.Errorf("unknown command encoding at index %d", entry.Index)
+	}
+	commandID, command = decodeCommand(data)
+	return commandID, command, nil
+}
@@ -61,6 +61,12 @@ type Config struct {
 	HeartbeatIntervalTicks int
 	TickInterval           time.Duration
 
+	// If SnapshotRate is positive, incoming snapshots are admitted at
+	// no more than that many bytes of snapshot data per second, so that
+	// applying them, as when ranges are rebalanced, doesn't starve
+	// writes to the other groups sharing the storage.
+	SnapshotRate int64
+
 	// If Strict is true, some warnings become fatal panics and additional (possibly expensive)
 	// sanity checks will be done.
 	Strict bool
@@ -99,6 +105,8 @@ type MultiRaft struct {
 	proposalChan    chan *proposal
 	// callbackChan is a generic hook to run a callback in the raft thread.
 	callbackChan chan func()
+	// snapshotThrottle is nil if incoming snapshots aren't throttled.
+	snapshotThrottle *snapshotThrottle
 }
 
 // multiraftServer is a type alias to separate RPC methods
@@ -143,6 +151,9 @@ func NewMultiRaft(nodeID NodeID, config *Config) (*MultiRaft, error) {
 		proposalChan:    make(chan *proposal, 100),
 		callbackChan:    make(chan func(), 100),
 	}
+	if config.SnapshotRate > 0 {
+		m.snapshotThrottle = newSnapshotThrottle(config.SnapshotRate)
+	}
 
 	err = m.Transport.Listen(nodeID, (*multiraftServer)(m))
 	if err != nil {
@@ -160,9 +171,19 @@ func (m *MultiRaft) Start(stopper *util.Stopper) error {
 }
 
 // RaftMessage implements ServerInterface; this method is called by net/rpc
-// when we receive a message.
+// when we receive a message. Snapshots are held back here, rather than
+// where they're written, when the snapshot budget is exhausted; only
+// the RPC carrying the snapshot waits, not the writes of other groups.
 func (ms *multiraftServer) RaftMessage(req *RaftMessageRequest,
 	resp *RaftMessageResponse) error {
+	if req.Message.Type == raftpb.MsgSnap && ms.snapshotThrottle != nil {
+		size := len(req.Message.Snapshot.Data)
+		if wait := ms.snapshotThrottle.reserve(time.Now(), size); wait > 0 {
+			log.V(1).Infof("node %v: delaying %d byte snapshot of group %d by %s",
+				ms.nodeID, size, req.GroupID, wait)
+			time.Sleep(wait)
+		}
+	}
 	ms.reqChan <- req
 	return nil
 }
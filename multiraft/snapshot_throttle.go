@@ -0,0 +1,57 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package multiraft
+
+import (
+	"sync"
+	"time"
+)
+
+// A snapshotThrottle paces the admission of incoming snapshots so that
+// their data is written to storage at no more than a fixed number of
+// bytes per second on average. A snapshot is admitted as soon as the
+// budget spent by its predecessors has been replenished, so a single
+// snapshot is never delayed but a burst of them is spread out.
+//
+// Admitted snapshots are written by their groups' storage; stores
+// ingest them as sstables, bypassing the memtable and write-ahead log
+// which foreground writes go through. A snapshot arrives whole in a
+// single Raft message rather than streamed in chunks, so its budget is
+// spent at once on receipt.
+type snapshotThrottle struct {
+	sync.Mutex
+	rate int64     // Bytes per second
+	next time.Time // Time at which the budget is next available
+}
+
+// newSnapshotThrottle returns a snapshotThrottle admitting rate bytes
+// of snapshot data per second.
+func newSnapshotThrottle(rate int64) *snapshotThrottle {
+	return &snapshotThrottle{rate: rate}
+}
+
+// reserve spends the budget for a snapshot of size bytes and returns
+// how long the caller must wait, as of now, before admitting it.
+func (st *snapshotThrottle) reserve(now time.Time, size int) time.Duration {
+	st.Lock()
+	defer st.Unlock()
+	if st.next.Before(now) {
+		st.next = now
+	}
+	wait := st.next.Sub(now)
+	st.next = st.next.Add(time.Duration(int64(size) * int64(time.Second) / st.rate))
+	return wait
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package multiraft
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestSnapshotThrottle verifies that snapshots are admitted once the
+// budget spent by their predecessors has been replenished.
+func TestSnapshotThrottle(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	st := newSnapshotThrottle(1000)
+	now := time.Unix(0, 0)
+	testCases := []struct {
+		elapsed time.Duration
+		size    int
+		wait    time.Duration
+	}{
+		// The first snapshot is admitted immediately.
+		{0, 500, 0},
+		// The next waits for the 500 bytes of budget spent on the first.
+		{0, 1000, 500 * time.Millisecond},
+		// Part of the budget has been replenished.
+		{1 * time.Second, 100, 500 * time.Millisecond},
+		// Unused budget doesn't accumulate.
+		{10 * time.Second, 100, 0},
+		{0, 100, 100 * time.Millisecond},
+	}
+	for i, test := range testCases {
+		now = now.Add(test.elapsed)
+		if wait := st.reserve(now, test.size); wait != test.wait {
+			t.Errorf("%d: expected wait of %s; got %s", i, test.wait, wait)
+		}
+	}
+}
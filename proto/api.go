@@ -19,6 +19,7 @@ package proto
 
 import (
 	"log"
+	"sync"
 
 	"github.com/cockroachdb/cockroach/util"
 	gogoproto "github.com/gogo/protobuf/proto"
@@ -32,6 +33,25 @@ func (ccid ClientCmdID) IsEmpty() bool {
 	return ccid.WallTime == 0 && ccid.Random == 0
 }
 
+// Outranks returns whether requests of class p are to be served ahead
+// of those of class o. The enum values are ordered for compatibility,
+// with the default NORMAL_PRIORITY being zero, rather than by rank.
+func (p PriorityClass) Outranks(o PriorityClass) bool {
+	return p.rank() > o.rank()
+}
+
+func (p PriorityClass) rank() int {
+	switch p {
+	case LOW_PRIORITY:
+		return -1
+	case HIGH_PRIORITY:
+		return 1
+	case SYSTEM_PRIORITY:
+		return 2
+	}
+	return 0
+}
+
 const (
 	isAdmin = 1 << iota
 	isRead
@@ -115,6 +135,8 @@ func (sr *ScanResponse) Combine(c Response) {
 	otherSR := c.(*ScanResponse)
 	if sr != nil {
 		sr.Rows = append(sr.Rows, otherSR.GetRows()...)
+		sr.KeyPrefixLengths = append(sr.KeyPrefixLengths, otherSR.GetKeyPrefixLengths()...)
+		sr.SkippedIntents = append(sr.SkippedIntents, otherSR.SkippedIntents...)
 		sr.Header().Combine(otherSR.Header())
 	}
 }
@@ -221,11 +243,83 @@ func (gr *GetResponse) Verify(req Request) error {
 
 // Verify verifies the integrity of every value returned in the scan.
 func (sr *ScanResponse) Verify(req Request) error {
-	for _, kv := range sr.Rows {
-		if err := kv.Value.Verify(kv.Key); err != nil {
+	return sr.visitKeys(func(i int, key Key) error {
+		return sr.Rows[i].Value.Verify(key)
+	})
+}
+
+// visitKeys invokes fn with the index and full key of each row,
+// reconstructing the keys if they are compressed.
+func (sr *ScanResponse) visitKeys(fn func(i int, key Key) error) error {
+	if sr.KeyPrefixLengths == nil {
+		for i := range sr.Rows {
+			if err := fn(i, sr.Rows[i].Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(sr.KeyPrefixLengths) != len(sr.Rows) {
+		return util.Errorf("%d key prefix lengths for %d rows", len(sr.KeyPrefixLengths), len(sr.Rows))
+	}
+	var prev Key
+	for i := range sr.Rows {
+		n := int(sr.KeyPrefixLengths[i])
+		if n < 0 || n > len(prev) {
+			return util.Errorf("invalid prefix length %d for row %d following key of length %d", n, i, len(prev))
+		}
+		suffix := sr.Rows[i].Key
+		key := make(Key, n+len(suffix))
+		copy(key, prev[:n])
+		copy(key[n:], suffix)
+		if err := fn(i, key); err != nil {
 			return err
 		}
+		prev = key
+	}
+	return nil
+}
+
+// CompressKeys replaces the key of each row with the suffix left after
+// eliding the prefix it shares with the key of the preceding row. The
+// elided lengths are recorded in KeyPrefixLengths. Keys which are
+// already compressed are left untouched.
+func (sr *ScanResponse) CompressKeys() {
+	if sr.KeyPrefixLengths != nil {
+		return
+	}
+	sr.KeyPrefixLengths = make([]int32, len(sr.Rows))
+	var prev Key
+	for i := range sr.Rows {
+		key := sr.Rows[i].Key
+		n := 0
+		for n < len(prev) && n < len(key) && prev[n] == key[n] {
+			n++
+		}
+		sr.KeyPrefixLengths[i] = int32(n)
+		sr.Rows[i].Key = key[n:]
+		prev = key
+	}
+}
+
+// DecompressKeys restores the full key of each row of a response whose
+// keys were compressed by CompressKeys. It is a no-op if the keys are
+// not compressed.
+func (sr *ScanResponse) DecompressKeys() error {
+	if sr.KeyPrefixLengths == nil {
+		return nil
+	}
+	keys := make([]Key, len(sr.Rows))
+	if err := sr.visitKeys(func(i int, key Key) error {
+		keys[i] = key
+		return nil
+	}); err != nil {
+		return err
 	}
+	for i := range sr.Rows {
+		sr.Rows[i].Key = keys[i]
+	}
+	sr.KeyPrefixLengths = nil
 	return nil
 }
 
@@ -279,6 +373,46 @@ func (sr *ScanResponse) Count() int64 {
 	return int64(len(sr.Rows))
 }
 
+// maxPooledScanRows is the capacity beyond which the rows of a released
+// ScanResponse aren't pooled, so that one large scan doesn't pin its
+// memory.
+const maxPooledScanRows = 1 << 12
+
+// scanRowsPool holds the row slices of released ScanResponses.
+var scanRowsPool = sync.Pool{
+	New: func() interface{} {
+		return &[]KeyValue{}
+	},
+}
+
+// NewScanRows returns an empty slice of rows, reusing those of a
+// released ScanResponse if possible, for a scan to append its results
+// to.
+func NewScanRows() []KeyValue {
+	rows := scanRowsPool.Get().(*[]KeyValue)
+	return (*rows)[:0]
+}
+
+// Release returns the rows of the response for reuse by later scans
+// and resets it. It's called by servers once the response has been
+// encoded, and neither the response nor its rows may be used
+// afterwards.
+func (sr *ScanResponse) Release() {
+	rows := sr.Rows
+	sr.Reset()
+	if cap(rows) == 0 || cap(rows) > maxPooledScanRows {
+		return
+	}
+	// The keys and values of the rows are cleared so that the pool
+	// doesn't keep them alive.
+	rows = rows[:cap(rows)]
+	for i := range rows {
+		rows[i] = KeyValue{}
+	}
+	rows = rows[:0]
+	scanRowsPool.Put(&rows)
+}
+
 // Method implements the Request interface.
 func (*ContainsRequest) Method() Method { return Contains }
 
@@ -339,6 +473,9 @@ func (*InternalLeaderLeaseRequest) Method() Method { return InternalLeaderLease
 // Method implements the Request interface.
 func (*InternalTruncateLogRequest) Method() Method { return InternalTruncateLog }
 
+// Method implements the Request interface.
+func (*InternalIngestRequest) Method() Method { return InternalIngest }
+
 // CreateReply implements the Request interface.
 func (*ContainsRequest) CreateReply() Response { return &ContainsResponse{} }
 
@@ -399,6 +536,9 @@ func (*InternalTruncateLogRequest) CreateReply() Response { return &InternalTrun
 // CreateReply implements the Request interface.
 func (*InternalLeaderLeaseRequest) CreateReply() Response { return &InternalLeaderLeaseResponse{} }
 
+// CreateReply implements the Request interface.
+func (*InternalIngestRequest) CreateReply() Response { return &InternalIngestResponse{} }
+
 func (*ContainsRequest) flags() int              { return isRead }
 func (*GetRequest) flags() int                   { return isRead }
 func (*PutRequest) flags() int                   { return isWrite | isTxnWrite }
@@ -419,3 +559,4 @@ func (*InternalResolveIntentRequest) flags() int { return isWrite }
 func (*InternalMergeRequest) flags() int         { return isWrite }
 func (*InternalTruncateLogRequest) flags() int   { return isWrite }
 func (*InternalLeaderLeaseRequest) flags() int   { return isWrite }
+func (*InternalIngestRequest) flags() int        { return isWrite }
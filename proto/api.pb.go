@@ -70,9 +70,9 @@ const (
 	// mechanism relies on clocks to determine lease expirations.
 	CONSISTENT ReadConsistencyType = 0
 	// CONSENSUS requires that reads must achieve consensus. This is a
-	// stronger guarantee of consistency than CONSISTENT.
-	//
-	// TODO(spencer): current unimplemented.
+	// stronger guarantee of consistency than CONSISTENT: the read is
+	// proposed to raft and executed once applied, rather than served from
+	// the leader's state. It's much slower and meant for debugging.
 	CONSENSUS ReadConsistencyType = 1
 	// INCONSISTENT reads return the latest available, committed values.
 	// They are more efficient, but may read stale values as pending
@@ -108,6 +108,103 @@ func (x *ReadConsistencyType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// PriorityClass orders requests competing for a range's resources.
+// Requests of a higher class are admitted ahead of those of lower
+// classes by rate limits and the command queue. Unlike the user
+// priority, it doesn't affect the outcome of transaction conflicts.
+type PriorityClass int32
+
+const (
+	// NORMAL_PRIORITY is the default class, for interactive traffic.
+	NORMAL_PRIORITY PriorityClass = 0
+	// LOW_PRIORITY is for bulk work, such as backups and batch jobs,
+	// which should yield to interactive traffic.
+	LOW_PRIORITY PriorityClass = 1
+	// HIGH_PRIORITY is for latency-sensitive traffic which should be
+	// served ahead of normal traffic.
+	HIGH_PRIORITY PriorityClass = 2
+	// SYSTEM_PRIORITY is for the cluster's own operations, such as
+	// range lookups, which other traffic depends on.
+	SYSTEM_PRIORITY PriorityClass = 3
+)
+
+var PriorityClass_name = map[int32]string{
+	0: "NORMAL_PRIORITY",
+	1: "LOW_PRIORITY",
+	2: "HIGH_PRIORITY",
+	3: "SYSTEM_PRIORITY",
+}
+var PriorityClass_value = map[string]int32{
+	"NORMAL_PRIORITY": 0,
+	"LOW_PRIORITY":    1,
+	"HIGH_PRIORITY":   2,
+	"SYSTEM_PRIORITY": 3,
+}
+
+func (x PriorityClass) Enum() *PriorityClass {
+	p := new(PriorityClass)
+	*p = x
+	return p
+}
+func (x PriorityClass) String() string {
+	return proto1.EnumName(PriorityClass_name, int32(x))
+}
+func (x *PriorityClass) UnmarshalJSON(data []byte) error {
+	value, err := proto1.UnmarshalJSONEnum(PriorityClass_value, data, "PriorityClass")
+	if err != nil {
+		return err
+	}
+	*x = PriorityClass(value)
+	return nil
+}
+
+// ScanIntentPolicy specifies how a scan treats the intents of other
+// transactions it encounters. Inconsistent scans ignore intents
+// whatever the policy.
+type ScanIntentPolicy int32
+
+const (
+	// BLOCK_ON_INTENTS scans push the transactions whose intents they
+	// encounter and wait for them to be resolved. It's the default.
+	BLOCK_ON_INTENTS ScanIntentPolicy = 0
+	// SKIP_INTENTS scans omit the keys holding intents of other
+	// transactions from their rows and report them in the response's
+	// skipped_intents, so that analytical scans aren't stalled behind
+	// long-running writers.
+	SKIP_INTENTS ScanIntentPolicy = 1
+	// FAIL_ON_INTENTS scans return a WriteIntentError for the first
+	// intent of another transaction they encounter, without pushing it.
+	FAIL_ON_INTENTS ScanIntentPolicy = 2
+)
+
+var ScanIntentPolicy_name = map[int32]string{
+	0: "BLOCK_ON_INTENTS",
+	1: "SKIP_INTENTS",
+	2: "FAIL_ON_INTENTS",
+}
+var ScanIntentPolicy_value = map[string]int32{
+	"BLOCK_ON_INTENTS": 0,
+	"SKIP_INTENTS":     1,
+	"FAIL_ON_INTENTS":  2,
+}
+
+func (x ScanIntentPolicy) Enum() *ScanIntentPolicy {
+	p := new(ScanIntentPolicy)
+	*p = x
+	return p
+}
+func (x ScanIntentPolicy) String() string {
+	return proto1.EnumName(ScanIntentPolicy_name, int32(x))
+}
+func (x *ScanIntentPolicy) UnmarshalJSON(data []byte) error {
+	value, err := proto1.UnmarshalJSONEnum(ScanIntentPolicy_value, data, "ScanIntentPolicy")
+	if err != nil {
+		return err
+	}
+	*x = ScanIntentPolicy(value)
+	return nil
+}
+
 // ClientCmdID provides a unique ID for client commands. Clients which
 // provide ClientCmdID gain operation idempotence. In other words,
 // clients can submit the same command multiple times and always
@@ -191,8 +288,18 @@ type RequestHeader struct {
 	// ReadConsistency specifies the consistency for read
 	// operations. The default is CONSISTENT. This value is ignored for
 	// write operations.
-	ReadConsistency  ReadConsistencyType `protobuf:"varint,10,opt,name=read_consistency,enum=cockroach.proto.ReadConsistencyType" json:"read_consistency"`
-	XXX_unrecognized []byte              `json:"-"`
+	ReadConsistency ReadConsistencyType `protobuf:"varint,10,opt,name=read_consistency,enum=cockroach.proto.ReadConsistencyType" json:"read_consistency"`
+	// MaxStaleness, if positive, permits a consistent, non-transactional
+	// read to be served by a replica other than the leader, at the newest
+	// timestamp no more than max_staleness nanoseconds before the request
+	// timestamp which the replica has closed. The timestamp the read was
+	// served at is returned in the response header.
+	MaxStaleness int64 `protobuf:"varint,11,opt,name=max_staleness" json:"max_staleness"`
+	// PriorityClass is the class of the request; see PriorityClass.
+	// Requests in a batch which don't specify a class inherit that of the
+	// batch.
+	PriorityClass    PriorityClass `protobuf:"varint,12,opt,name=priority_class,enum=cockroach.proto.PriorityClass" json:"priority_class"`
+	XXX_unrecognized []byte        `json:"-"`
 }
 
 func (m *RequestHeader) Reset()         { *m = RequestHeader{} }
@@ -257,6 +364,20 @@ func (m *RequestHeader) GetReadConsistency() ReadConsistencyType {
 	return CONSISTENT
 }
 
+func (m *RequestHeader) GetMaxStaleness() int64 {
+	if m != nil {
+		return m.MaxStaleness
+	}
+	return 0
+}
+
+func (m *RequestHeader) GetPriorityClass() PriorityClass {
+	if m != nil {
+		return m.PriorityClass
+	}
+	return 0
+}
+
 // ResponseHeader is returned with every storage node response.
 type ResponseHeader struct {
 	// Error is non-nil if an error occurred.
@@ -436,13 +557,24 @@ func (*ConditionalPutResponse) ProtoMessage()    {}
 
 // An IncrementRequest is arguments to the Increment() method. It
 // increments the value for key, and returns the new value. If no
-// value exists for a key, incrementing by 0 is not a noop, but will
-// create a zero value. IncrementRequest cannot be called on a key set
-// by Put() or ConditionalPut(). Similarly, Put() and ConditionalPut()
-// cannot be invoked on an incremented key.
+// value exists for a key, the increment is applied to initial_value;
+// incrementing by 0 is not a noop, but will create the initial
+// value. IncrementRequest cannot be called on a key set by Put() or
+// ConditionalPut(). Similarly, Put() and ConditionalPut() cannot be
+// invoked on an incremented key.
+//
+// If min_value or max_value are specified, an increment which would
+// result in a value outside of [min_value, max_value] is not applied
+// and an IncrementBoundsError is returned instead.
 type IncrementRequest struct {
-	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
-	Increment        int64  `protobuf:"varint,2,opt,name=increment" json:"increment"`
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	Increment     int64 `protobuf:"varint,2,opt,name=increment" json:"increment"`
+	// The value of a missing key before the increment is applied.
+	InitialValue int64 `protobuf:"varint,3,opt,name=initial_value" json:"initial_value"`
+	// Optional inclusive lower bound for the new value.
+	MinValue *int64 `protobuf:"varint,4,opt,name=min_value" json:"min_value,omitempty"`
+	// Optional inclusive upper bound for the new value.
+	MaxValue         *int64 `protobuf:"varint,5,opt,name=max_value" json:"max_value,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -457,6 +589,27 @@ func (m *IncrementRequest) GetIncrement() int64 {
 	return 0
 }
 
+func (m *IncrementRequest) GetInitialValue() int64 {
+	if m != nil {
+		return m.InitialValue
+	}
+	return 0
+}
+
+func (m *IncrementRequest) GetMinValue() int64 {
+	if m != nil && m.MinValue != nil {
+		return *m.MinValue
+	}
+	return 0
+}
+
+func (m *IncrementRequest) GetMaxValue() int64 {
+	if m != nil && m.MaxValue != nil {
+		return *m.MaxValue
+	}
+	return 0
+}
+
 // An IncrementResponse is the return value from the Increment
 // method. The new value after increment is specified in NewValue. If
 // the value could not be decoded as specified, Error will be set.
@@ -543,8 +696,13 @@ func (m *DeleteRangeResponse) GetNumDeleted() int64 {
 type ScanRequest struct {
 	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
 	// Must be > 0.
-	MaxResults       int64  `protobuf:"varint,2,opt,name=max_results" json:"max_results"`
-	XXX_unrecognized []byte `json:"-"`
+	MaxResults int64 `protobuf:"varint,2,opt,name=max_results" json:"max_results"`
+	// If set, the response elides the prefix each returned key shares with
+	// the key preceding it. See ScanResponse.KeyPrefixLengths.
+	CompressKeys bool `protobuf:"varint,3,opt,name=compress_keys" json:"compress_keys"`
+	// How intents of other transactions are treated.
+	IntentPolicy     ScanIntentPolicy `protobuf:"varint,4,opt,name=intent_policy,enum=cockroach.proto.ScanIntentPolicy" json:"intent_policy"`
+	XXX_unrecognized []byte           `json:"-"`
 }
 
 func (m *ScanRequest) Reset()         { *m = ScanRequest{} }
@@ -558,12 +716,36 @@ func (m *ScanRequest) GetMaxResults() int64 {
 	return 0
 }
 
+func (m *ScanRequest) GetCompressKeys() bool {
+	if m != nil {
+		return m.CompressKeys
+	}
+	return false
+}
+
+func (m *ScanRequest) GetIntentPolicy() ScanIntentPolicy {
+	if m != nil {
+		return m.IntentPolicy
+	}
+	return 0
+}
+
 // A ScanResponse is the return value from the Scan() method.
 type ScanResponse struct {
 	ResponseHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
 	// Empty if no rows were scanned.
-	Rows             []KeyValue `protobuf:"bytes,2,rep,name=rows" json:"rows"`
-	XXX_unrecognized []byte     `json:"-"`
+	Rows []KeyValue `protobuf:"bytes,2,rep,name=rows" json:"rows"`
+	// Set only if the request specified compress_keys, in which case it
+	// holds one entry per row: the number of leading bytes the row's key
+	// shares with the key of the preceding row. Only the remaining suffix
+	// is stored in the row itself. The first row of every range's response
+	// has a shared prefix length of zero.
+	KeyPrefixLengths []int32 `protobuf:"varint,3,rep,name=key_prefix_lengths" json:"key_prefix_lengths,omitempty"`
+	// Set only if the request's intent policy is SKIP_INTENTS, in which
+	// case it holds the keys omitted from rows as they hold intents of
+	// other transactions, in order.
+	SkippedIntents   []Key  `protobuf:"bytes,4,rep,name=skipped_intents,customtype=Key" json:"skipped_intents,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *ScanResponse) Reset()         { *m = ScanResponse{} }
@@ -577,6 +759,13 @@ func (m *ScanResponse) GetRows() []KeyValue {
 	return nil
 }
 
+func (m *ScanResponse) GetKeyPrefixLengths() []int32 {
+	if m != nil {
+		return m.KeyPrefixLengths
+	}
+	return nil
+}
+
 // An EndTransactionRequest is arguments to the EndTransaction() method.
 // It specifies whether to commit or roll back an extant transaction.
 type EndTransactionRequest struct {
@@ -804,9 +993,20 @@ func (m *ResponseUnion) GetEndTransaction() *EndTransactionResponse {
 // parallel, or if applicable (based on write-only commands and
 // range-locality), as a single update.
 type BatchRequest struct {
-	RequestHeader    `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
-	Requests         []RequestUnion `protobuf:"bytes,2,rep,name=requests" json:"requests"`
-	XXX_unrecognized []byte         `json:"-"`
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	Requests      []RequestUnion `protobuf:"bytes,2,rep,name=requests" json:"requests"`
+	// Ordered requires that the requests be executed sequentially in the
+	// order specified. If false, requests may be dispatched to their
+	// ranges in parallel, except that each is sent after any preceding
+	// requests whose keys it overlaps.
+	Ordered bool `protobuf:"varint,3,opt,name=ordered" json:"ordered"`
+	// AllOrNothing, for a batch outside of a transaction, runs the batch
+	// in a transaction of its own, so that either all of its requests take
+	// effect or none do. Otherwise, each request of a non-transactional
+	// batch is executed regardless of the failure of others, and its error
+	// is returned in its own response.
+	AllOrNothing     bool   `protobuf:"varint,4,opt,name=all_or_nothing" json:"all_or_nothing"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *BatchRequest) Reset()         { *m = BatchRequest{} }
@@ -820,6 +1020,20 @@ func (m *BatchRequest) GetRequests() []RequestUnion {
 	return nil
 }
 
+func (m *BatchRequest) GetOrdered() bool {
+	if m != nil {
+		return m.Ordered
+	}
+	return false
+}
+
+func (m *BatchRequest) GetAllOrNothing() bool {
+	if m != nil {
+		return m.AllOrNothing
+	}
+	return false
+}
+
 // A BatchResponse contains one or more responses, one per request
 // corresponding to the requests in the matching BatchRequest. The
 // error in the response header is set to the first error from the
@@ -913,6 +1127,8 @@ func (*AdminMergeResponse) ProtoMessage()    {}
 
 func init() {
 	proto1.RegisterEnum("cockroach.proto.ReadConsistencyType", ReadConsistencyType_name, ReadConsistencyType_value)
+	proto1.RegisterEnum("cockroach.proto.PriorityClass", PriorityClass_name, PriorityClass_value)
+	proto1.RegisterEnum("cockroach.proto.ScanIntentPolicy", ScanIntentPolicy_name, ScanIntentPolicy_value)
 }
 func (m *ClientCmdID) Unmarshal(data []byte) error {
 	l := len(data)
@@ -1221,6 +1437,36 @@ func (m *RequestHeader) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxStaleness", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxStaleness |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PriorityClass", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.PriorityClass |= (PriorityClass(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -2069,6 +2315,55 @@ func (m *IncrementRequest) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InitialValue", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.InitialValue |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinValue", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.MinValue = &v
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxValue", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.MaxValue = &v
 		default:
 			var sizeOfWire int
 			for {
@@ -2525,6 +2820,38 @@ func (m *ScanRequest) Unmarshal(data []byte) error {
 					break
 				}
 			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CompressKeys", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.CompressKeys = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntentPolicy", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.IntentPolicy |= (ScanIntentPolicy(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			var sizeOfWire int
 			for {
@@ -2614,6 +2941,46 @@ func (m *ScanResponse) Unmarshal(data []byte) error {
 			m.Rows = append(m.Rows, KeyValue{})
 			m.Rows[len(m.Rows)-1].Unmarshal(data[index:postIndex])
 			index = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field KeyPrefixLengths", wireType)
+			}
+			var v int32
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.KeyPrefixLengths = append(m.KeyPrefixLengths, v)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SkippedIntents", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SkippedIntents = append(m.SkippedIntents, Key{})
+			m.SkippedIntents[len(m.SkippedIntents)-1].Unmarshal(data[index:postIndex])
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -3487,6 +3854,40 @@ func (m *BatchRequest) Unmarshal(data []byte) error {
 			m.Requests = append(m.Requests, RequestUnion{})
 			m.Requests[len(m.Requests)-1].Unmarshal(data[index:postIndex])
 			index = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ordered", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ordered = bool(v != 0)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AllOrNothing", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.AllOrNothing = bool(v != 0)
 		default:
 			var sizeOfWire int
 			for {
@@ -4034,6 +4435,8 @@ func (m *RequestHeader) Size() (n int) {
 		n += 1 + l + sovApi(uint64(l))
 	}
 	n += 1 + sovApi(uint64(m.ReadConsistency))
+	n += 1 + sovApi(uint64(m.MaxStaleness))
+	n += 1 + sovApi(uint64(m.PriorityClass))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4166,6 +4569,13 @@ func (m *IncrementRequest) Size() (n int) {
 	l = m.RequestHeader.Size()
 	n += 1 + l + sovApi(uint64(l))
 	n += 1 + sovApi(uint64(m.Increment))
+	n += 1 + sovApi(uint64(m.InitialValue))
+	if m.MinValue != nil {
+		n += 1 + sovApi(uint64(*m.MinValue))
+	}
+	if m.MaxValue != nil {
+		n += 1 + sovApi(uint64(*m.MaxValue))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4236,6 +4646,8 @@ func (m *ScanRequest) Size() (n int) {
 	l = m.RequestHeader.Size()
 	n += 1 + l + sovApi(uint64(l))
 	n += 1 + sovApi(uint64(m.MaxResults))
+	n += 2
+	n += 1 + sovApi(uint64(m.IntentPolicy))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4253,6 +4665,17 @@ func (m *ScanResponse) Size() (n int) {
 			n += 1 + l + sovApi(uint64(l))
 		}
 	}
+	if len(m.KeyPrefixLengths) > 0 {
+		for _, e := range m.KeyPrefixLengths {
+			n += 1 + sovApi(uint64(e))
+		}
+	}
+	if len(m.SkippedIntents) > 0 {
+		for _, e := range m.SkippedIntents {
+			l = e.Size()
+			n += 1 + l + sovApi(uint64(l))
+		}
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4394,6 +4817,8 @@ func (m *BatchRequest) Size() (n int) {
 			n += 1 + l + sovApi(uint64(l))
 		}
 	}
+	n += 2
+	n += 2
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4583,6 +5008,12 @@ func (m *RequestHeader) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0x50
 	i++
 	i = encodeVarintApi(data, i, uint64(m.ReadConsistency))
+	data[i] = 0x58
+	i++
+	i = encodeVarintApi(data, i, uint64(m.MaxStaleness))
+	data[i] = 0x60
+	i++
+	i = encodeVarintApi(data, i, uint64(m.PriorityClass))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -4940,6 +5371,19 @@ func (m *IncrementRequest) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0x10
 	i++
 	i = encodeVarintApi(data, i, uint64(m.Increment))
+	data[i] = 0x18
+	i++
+	i = encodeVarintApi(data, i, uint64(m.InitialValue))
+	if m.MinValue != nil {
+		data[i] = 0x20
+		i++
+		i = encodeVarintApi(data, i, uint64(*m.MinValue))
+	}
+	if m.MaxValue != nil {
+		data[i] = 0x28
+		i++
+		i = encodeVarintApi(data, i, uint64(*m.MaxValue))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5126,6 +5570,17 @@ func (m *ScanRequest) MarshalTo(data []byte) (n int, err error) {
 	data[i] = 0x10
 	i++
 	i = encodeVarintApi(data, i, uint64(m.MaxResults))
+	data[i] = 0x18
+	i++
+	if m.CompressKeys {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
+	data[i] = 0x20
+	i++
+	i = encodeVarintApi(data, i, uint64(m.IntentPolicy))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5167,6 +5622,25 @@ func (m *ScanResponse) MarshalTo(data []byte) (n int, err error) {
 			i += n
 		}
 	}
+	if len(m.KeyPrefixLengths) > 0 {
+		for _, num := range m.KeyPrefixLengths {
+			data[i] = 0x18
+			i++
+			i = encodeVarintApi(data, i, uint64(num))
+		}
+	}
+	if len(m.SkippedIntents) > 0 {
+		for _, msg := range m.SkippedIntents {
+			data[i] = 0x22
+			i++
+			i = encodeVarintApi(data, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(data[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5521,6 +5995,22 @@ func (m *BatchRequest) MarshalTo(data []byte) (n int, err error) {
 			i += n
 		}
 	}
+	data[i] = 0x18
+	i++
+	if m.Ordered {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
+	data[i] = 0x20
+	i++
+	if m.AllOrNothing {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
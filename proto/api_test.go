@@ -18,9 +18,12 @@
 package proto
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
+
+	gogoproto "github.com/gogo/protobuf/proto"
 )
 
 func TestClientCmdIDIsEmpty(t *testing.T) {
@@ -64,6 +67,68 @@ func TestResponseHeaderNilError(t *testing.T) {
 	}
 }
 
+// TestIncrementBoundsErrorRoundTrip verifies that an
+// IncrementBoundsError survives serialization of the response.
+func TestIncrementBoundsErrorRoundTrip(t *testing.T) {
+	reply := &IncrementResponse{}
+	reply.SetGoError(&IncrementBoundsError{CurrentValue: 7})
+	data, err := reply.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &IncrementResponse{}
+	if err := decoded.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	bErr, ok := decoded.GoError().(*IncrementBoundsError)
+	if !ok {
+		t.Fatalf("expected IncrementBoundsError; got %T", decoded.GoError())
+	}
+	if bErr.CurrentValue != 7 {
+		t.Errorf("expected current value 7; got %d", bErr.CurrentValue)
+	}
+}
+
+// TestJSONMapping verifies the JSON encoding of requests and responses
+// accepted and returned by the HTTP KV API. Clients in other languages
+// depend on this mapping, so it must not change.
+func TestJSONMapping(t *testing.T) {
+	req := &ScanRequest{
+		RequestHeader: RequestHeader{
+			Timestamp:       Timestamp{WallTime: 1, Logical: 2},
+			Key:             Key("a"),
+			EndKey:          Key("b"),
+			User:            "root",
+			ReadConsistency: INCONSISTENT,
+		},
+		MaxResults: 10,
+	}
+	resp := &ScanResponse{
+		Rows: []KeyValue{{Key: Key("a"), Value: Value{Bytes: []byte("v"), Checksum: gogoproto.Uint32(7)}}},
+	}
+	resp.Error = &Error{Message: "boom", Retryable: true}
+	testCases := []struct {
+		msg      interface{}
+		expected string
+	}{
+		{req, `{"header":{"timestamp":{"wall_time":1,"logical":2},"cmd_id":{"wall_time":0,"random":0},` +
+			`"key":"YQ==","end_key":"Yg==","user":"root","replica":{"node_id":0,"store_id":0,"attrs":{"attrs":null}},` +
+			`"raft_id":0,"read_consistency":2,"max_staleness":0,"priority_class":0},"max_results":10,"compress_keys":false,"intent_policy":0}`},
+		{resp, `{"header":{"error":{"message":"boom","retryable":true,"transaction_restart":0},` +
+			`"timestamp":{"wall_time":0,"logical":0}},` +
+			`"rows":[{"key":"YQ==","value":{"bytes":"dg==","checksum":7}}]}`},
+	}
+	for i, test := range testCases {
+		b, err := json.Marshal(test.msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.expected {
+			t.Errorf("%d: expected %s; got %s", i, test.expected, b)
+		}
+	}
+}
+
 type XX interface {
 	Run()
 }
@@ -140,6 +205,62 @@ func TestCombinable(t *testing.T) {
 	}
 }
 
+// TestScanResponseCompressKeys verifies that compressed keys survive
+// serialization, verification, combination and decompression.
+func TestScanResponseCompressKeys(t *testing.T) {
+	makeRows := func(keys ...string) []KeyValue {
+		var rows []KeyValue
+		for _, k := range keys {
+			kv := KeyValue{Key: Key(k), Value: Value{Bytes: []byte("v" + k)}}
+			kv.Value.InitChecksum(kv.Key)
+			rows = append(rows, kv)
+		}
+		return rows
+	}
+	sr1 := &ScanResponse{Rows: makeRows("/table/1/a", "/table/1/ab", "/table/2/b")}
+	sr2 := &ScanResponse{Rows: makeRows("/table/3/c", "/table/3/cd")}
+	wanted := append(makeRows(), sr1.Rows...)
+	wanted = append(wanted, sr2.Rows...)
+
+	sr1.CompressKeys()
+	sr2.CompressKeys()
+	if !reflect.DeepEqual(sr1.KeyPrefixLengths, []int32{0, 10, 7}) {
+		t.Errorf("unexpected prefix lengths %v", sr1.KeyPrefixLengths)
+	}
+	if k := sr1.Rows[1].Key; !k.Equal(Key("b")) {
+		t.Errorf("expected compressed key %q; got %q", "b", k)
+	}
+
+	data, err := sr1.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &ScanResponse{}
+	if err := decoded.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.Verify(&ScanRequest{}); err != nil {
+		t.Errorf("expected compressed rows to verify: %s", err)
+	}
+
+	decoded.Combine(sr2)
+	if err := decoded.DecompressKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.KeyPrefixLengths != nil {
+		t.Errorf("expected prefix lengths to be cleared; got %v", decoded.KeyPrefixLengths)
+	}
+	if !reflect.DeepEqual(decoded.Rows, wanted) {
+		t.Errorf("wanted %v, got %v", wanted, decoded.Rows)
+	}
+
+	// A prefix length exceeding the preceding key is rejected.
+	bad := &ScanResponse{Rows: makeRows("a"), KeyPrefixLengths: []int32{1}}
+	if err := bad.DecompressKeys(); err == nil {
+		t.Error("expected error decompressing invalid prefix length")
+	}
+}
+
 func TestSetGoErrorCopy(t *testing.T) {
 	rh := ResponseHeader{}
 	err := &Error{Message: "test123"}
@@ -149,3 +270,39 @@ func TestSetGoErrorCopy(t *testing.T) {
 		t.Fatalf("SetGoError did not create a new error")
 	}
 }
+
+// TestPriorityClassOutranks verifies the ranking of priority classes.
+func TestPriorityClassOutranks(t *testing.T) {
+	classes := []PriorityClass{LOW_PRIORITY, NORMAL_PRIORITY, HIGH_PRIORITY, SYSTEM_PRIORITY}
+	for i, p := range classes {
+		for j, o := range classes {
+			if p.Outranks(o) != (i > j) {
+				t.Errorf("expected %s outranks %s to be %t", p, o, i > j)
+			}
+		}
+	}
+}
+
+// TestScanResponseRelease verifies that releasing a ScanResponse
+// resets it and clears its rows, and that the rows of new scans are
+// empty.
+func TestScanResponseRelease(t *testing.T) {
+	rows := NewScanRows()
+	if rows == nil || len(rows) != 0 {
+		t.Fatalf("expected empty rows; got %v", rows)
+	}
+	rows = append(rows, KeyValue{Key: Key("a")}, KeyValue{Key: Key("b")})
+	sr := &ScanResponse{Rows: rows, KeyPrefixLengths: []int32{0, 0}}
+	sr.Release()
+	if sr.Rows != nil || sr.KeyPrefixLengths != nil {
+		t.Errorf("expected response to be reset; got %+v", sr)
+	}
+	for i, kv := range rows {
+		if kv.Key != nil {
+			t.Errorf("%d: expected released row to be cleared; got %q", i, kv.Key)
+		}
+	}
+	if rows := NewScanRows(); len(rows) != 0 {
+		t.Errorf("expected empty rows; got %v", rows)
+	}
+}
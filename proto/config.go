@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/gogo/protobuf/proto"
@@ -149,6 +150,57 @@ func (p *PermConfig) CanWrite(user string) bool {
 	return false
 }
 
+// ActivePin returns the stores to which the zone's replicas are pinned
+// at the supplied time, or nil if the zone has no unexpired pin.
+func (z *ZoneConfig) ActivePin(now time.Time) []StoreID {
+	if now.Unix() >= z.PinExpiration {
+		return nil
+	}
+	return z.PinnedStores
+}
+
+// InheritFrom sets each field of the zone which isn't set to its value
+// in parent, the zone of a shorter prefix, and returns the YAML names
+// of the fields which were set. The pin is inherited only if neither
+// of its fields is set. Inherit itself isn't changed.
+func (z *ZoneConfig) InheritFrom(parent *ZoneConfig) []string {
+	var fields []string
+	if len(z.ReplicaAttrs) == 0 && len(parent.ReplicaAttrs) > 0 {
+		z.ReplicaAttrs = parent.ReplicaAttrs
+		fields = append(fields, "replicas")
+	}
+	if z.RangeMinBytes == 0 && parent.RangeMinBytes != 0 {
+		z.RangeMinBytes = parent.RangeMinBytes
+		fields = append(fields, "range_min_bytes")
+	}
+	if z.RangeMaxBytes == 0 && parent.RangeMaxBytes != 0 {
+		z.RangeMaxBytes = parent.RangeMaxBytes
+		fields = append(fields, "range_max_bytes")
+	}
+	if z.GC == nil && parent.GC != nil {
+		z.GC = parent.GC
+		fields = append(fields, "gc")
+	}
+	if z.ReadsPerSecond == 0 && parent.ReadsPerSecond != 0 {
+		z.ReadsPerSecond = parent.ReadsPerSecond
+		fields = append(fields, "reads_per_second")
+	}
+	if z.WritesPerSecond == 0 && parent.WritesPerSecond != 0 {
+		z.WritesPerSecond = parent.WritesPerSecond
+		fields = append(fields, "writes_per_second")
+	}
+	if len(z.PinnedStores) == 0 && z.PinExpiration == 0 && parent.PinExpiration != 0 {
+		z.PinnedStores = parent.PinnedStores
+		z.PinExpiration = parent.PinExpiration
+		fields = append(fields, "pinned_stores", "pin_expiration")
+	}
+	if z.CommitCoalescingWindowMicros == 0 && parent.CommitCoalescingWindowMicros != 0 {
+		z.CommitCoalescingWindowMicros = parent.CommitCoalescingWindowMicros
+		fields = append(fields, "commit_coalescing_window_micros")
+	}
+	return fields
+}
+
 // A ReplicaSlice is a slice of Replicas.
 type ReplicaSlice []Replica
 
@@ -172,8 +172,31 @@ type ZoneConfig struct {
 	RangeMaxBytes int64        `protobuf:"varint,3,opt,name=range_max_bytes" json:"range_max_bytes" yaml:"range_max_bytes,omitempty"`
 	// If GC policy is not set, uses the next highest, non-null policy
 	// in the zone config hierarchy, up to the default policy if necessary.
-	GC               *GCPolicy `protobuf:"bytes,4,opt,name=gc" json:"gc,omitempty" yaml:"gc,omitempty"`
-	XXX_unrecognized []byte    `json:"-"`
+	GC *GCPolicy `protobuf:"bytes,4,opt,name=gc" json:"gc,omitempty" yaml:"gc,omitempty"`
+	// ReadsPerSecond and WritesPerSecond limit the rate at which the
+	// leader of each range in the zone admits read and write commands,
+	// with bursts of up to one second's worth. Zero means unlimited.
+	ReadsPerSecond  int64 `protobuf:"varint,5,opt,name=reads_per_second" json:"reads_per_second" yaml:"reads_per_second,omitempty"`
+	WritesPerSecond int64 `protobuf:"varint,6,opt,name=writes_per_second" json:"writes_per_second" yaml:"writes_per_second,omitempty"`
+	// PinnedStores, until PinExpiration, names the stores on which the
+	// replicas of the zone's ranges are placed, overriding the allocator
+	// and ReplicaAttrs. Replicas are added to the pinned stores which
+	// lack them and then removed from the other stores.
+	PinnedStores []StoreID `protobuf:"varint,7,rep,name=pinned_stores,customtype=StoreID" json:"pinned_stores,omitempty" yaml:"pinned_stores,omitempty"`
+	// PinExpiration is the Unix time, in seconds, at which the pin of
+	// PinnedStores expires and placement reverts to the allocator.
+	PinExpiration int64 `protobuf:"varint,8,opt,name=pin_expiration" json:"pin_expiration" yaml:"pin_expiration,omitempty"`
+	// Inherit, if true, fills in each field of the zone which isn't set
+	// from the zone of the next shorter matching prefix, which may itself
+	// inherit, up to the default zone. The pin is inherited as a whole.
+	Inherit bool `protobuf:"varint,9,opt,name=inherit" json:"inherit" yaml:"inherit,omitempty"`
+	// CommitCoalescingWindowMicros is the number of microseconds for
+	// which the leader of each range in the zone holds its Raft proposals so
+	// that those made within the window are appended to the log, and
+	// synced, together. It trades latency for throughput on prefixes taking
+	// bulk writes. Zero proposes each command immediately.
+	CommitCoalescingWindowMicros int64  `protobuf:"varint,10,opt,name=commit_coalescing_window_micros" json:"commit_coalescing_window_micros" yaml:"commit_coalescing_window_micros,omitempty"`
+	XXX_unrecognized             []byte `json:"-"`
 }
 
 func (m *ZoneConfig) Reset()         { *m = ZoneConfig{} }
@@ -208,6 +231,76 @@ func (m *ZoneConfig) GetGC() *GCPolicy {
 	return nil
 }
 
+func (m *ZoneConfig) GetReadsPerSecond() int64 {
+	if m != nil {
+		return m.ReadsPerSecond
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetWritesPerSecond() int64 {
+	if m != nil {
+		return m.WritesPerSecond
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetPinnedStores() []StoreID {
+	if m != nil {
+		return m.PinnedStores
+	}
+	return nil
+}
+
+func (m *ZoneConfig) GetPinExpiration() int64 {
+	if m != nil {
+		return m.PinExpiration
+	}
+	return 0
+}
+
+func (m *ZoneConfig) GetInherit() bool {
+	if m != nil {
+		return m.Inherit
+	}
+	return false
+}
+
+func (m *ZoneConfig) GetCommitCoalescingWindowMicros() int64 {
+	if m != nil {
+		return m.CommitCoalescingWindowMicros
+	}
+	return 0
+}
+
+// BalanceConfig holds the cluster-wide thresholds which the allocator
+// applies to the balance of the stores: the fractions by which the
+// range count and the bytes used of a store may exceed the means of the
+// stores eligible for a replica before it's considered overfull.
+type BalanceConfig struct {
+	RangeCountThreshold float64 `protobuf:"fixed64,1,opt,name=range_count_threshold" json:"range_count_threshold" yaml:"range_count_threshold"`
+	BytesThreshold      float64 `protobuf:"fixed64,2,opt,name=bytes_threshold" json:"bytes_threshold" yaml:"bytes_threshold"`
+	XXX_unrecognized    []byte  `json:"-"`
+}
+
+func (m *BalanceConfig) Reset()         { *m = BalanceConfig{} }
+func (m *BalanceConfig) String() string { return proto1.CompactTextString(m) }
+func (*BalanceConfig) ProtoMessage()    {}
+
+func (m *BalanceConfig) GetRangeCountThreshold() float64 {
+	if m != nil {
+		return m.RangeCountThreshold
+	}
+	return 0
+}
+
+func (m *BalanceConfig) GetBytesThreshold() float64 {
+	if m != nil {
+		return m.BytesThreshold
+	}
+	return 0
+}
+
 // RangeTree holds the root node and size of the range tree.
 type RangeTree struct {
 	RootKey          Key    `protobuf:"bytes,1,opt,name=root_key,customtype=Key" json:"root_key"`
@@ -840,6 +933,180 @@ func (m *ZoneConfig) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadsPerSecond", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ReadsPerSecond |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WritesPerSecond", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.WritesPerSecond |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PinnedStores", wireType)
+			}
+			var v StoreID
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (StoreID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.PinnedStores = append(m.PinnedStores, v)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PinExpiration", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.PinExpiration |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Inherit", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Inherit = bool(v != 0)
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CommitCoalescingWindowMicros", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.CommitCoalescingWindowMicros |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *BalanceConfig) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RangeCountThreshold", wireType)
+			}
+			var v uint64
+			i := index + 8
+			if i > l {
+				return io.ErrUnexpectedEOF
+			}
+			index += 8
+			v = uint64(data[i-8])
+			v |= uint64(data[i-7]) << 8
+			v |= uint64(data[i-6]) << 16
+			v |= uint64(data[i-5]) << 24
+			v |= uint64(data[i-4]) << 32
+			v |= uint64(data[i-3]) << 40
+			v |= uint64(data[i-2]) << 48
+			v |= uint64(data[i-1]) << 56
+			m.RangeCountThreshold = math.Float64frombits(v)
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BytesThreshold", wireType)
+			}
+			var v uint64
+			i := index + 8
+			if i > l {
+				return io.ErrUnexpectedEOF
+			}
+			index += 8
+			v = uint64(data[i-8])
+			v |= uint64(data[i-7]) << 8
+			v |= uint64(data[i-6]) << 16
+			v |= uint64(data[i-5]) << 24
+			v |= uint64(data[i-4]) << 32
+			v |= uint64(data[i-3]) << 40
+			v |= uint64(data[i-2]) << 48
+			v |= uint64(data[i-1]) << 56
+			m.BytesThreshold = math.Float64frombits(v)
 		default:
 			var sizeOfWire int
 			for {
@@ -1191,6 +1458,27 @@ func (m *ZoneConfig) Size() (n int) {
 		l = m.GC.Size()
 		n += 1 + l + sovConfig(uint64(l))
 	}
+	n += 1 + sovConfig(uint64(m.ReadsPerSecond))
+	n += 1 + sovConfig(uint64(m.WritesPerSecond))
+	if len(m.PinnedStores) > 0 {
+		for _, e := range m.PinnedStores {
+			n += 1 + sovConfig(uint64(e))
+		}
+	}
+	n += 1 + sovConfig(uint64(m.PinExpiration))
+	n += 2
+	n += 1 + sovConfig(uint64(m.CommitCoalescingWindowMicros))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *BalanceConfig) Size() (n int) {
+	var l int
+	_ = l
+	n += 9
+	n += 9
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1509,6 +1797,60 @@ func (m *ZoneConfig) MarshalTo(data []byte) (n int, err error) {
 		}
 		i += n4
 	}
+	data[i] = 0x28
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.ReadsPerSecond))
+	data[i] = 0x30
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.WritesPerSecond))
+	if len(m.PinnedStores) > 0 {
+		for _, num := range m.PinnedStores {
+			data[i] = 0x38
+			i++
+			i = encodeVarintConfig(data, i, uint64(num))
+		}
+	}
+	data[i] = 0x40
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.PinExpiration))
+	data[i] = 0x48
+	i++
+	if m.Inherit {
+		data[i] = 1
+	} else {
+		data[i] = 0
+	}
+	i++
+	data[i] = 0x50
+	i++
+	i = encodeVarintConfig(data, i, uint64(m.CommitCoalescingWindowMicros))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *BalanceConfig) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *BalanceConfig) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x9
+	i++
+	i = encodeFixed64Config(data, i, uint64(math.Float64bits(m.RangeCountThreshold)))
+	data[i] = 0x11
+	i++
+	i = encodeFixed64Config(data, i, uint64(math.Float64bits(m.BytesThreshold)))
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
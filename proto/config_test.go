@@ -21,6 +21,9 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
 )
 
 func TestAttributesIsSubset(t *testing.T) {
@@ -152,6 +155,71 @@ func TestPermConfig(t *testing.T) {
 	}
 }
 
+// TestZoneConfigActivePin verifies that a zone's pinned stores are
+// only returned until the pin expires, and survive encoding.
+func TestZoneConfigActivePin(t *testing.T) {
+	now := time.Unix(1000, 0)
+	z := &ZoneConfig{PinnedStores: []StoreID{1, 3}, PinExpiration: 1001}
+	data, err := proto.Marshal(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &ZoneConfig{}
+	if err := proto.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if pinned := decoded.ActivePin(now); !reflect.DeepEqual(pinned, z.PinnedStores) {
+		t.Errorf("expected pinned stores %v; got %v", z.PinnedStores, pinned)
+	}
+	if pinned := decoded.ActivePin(now.Add(time.Second)); pinned != nil {
+		t.Errorf("expected expired pin; got %v", pinned)
+	}
+	if pinned := (&ZoneConfig{}).ActivePin(now); pinned != nil {
+		t.Errorf("expected no pin; got %v", pinned)
+	}
+}
+
+// TestZoneConfigInheritFrom verifies that only unset fields are
+// inherited and that the pin is inherited as a whole.
+func TestZoneConfigInheritFrom(t *testing.T) {
+	parent := &ZoneConfig{
+		ReplicaAttrs:   []Attributes{{Attrs: []string{"ssd"}}},
+		RangeMinBytes:  1 << 20,
+		RangeMaxBytes:  64 << 20,
+		GC:             &GCPolicy{TTLSeconds: 3600},
+		ReadsPerSecond: 100,
+		PinnedStores:   []StoreID{1},
+		PinExpiration:  1000,
+
+		CommitCoalescingWindowMicros: 2000,
+	}
+	z := &ZoneConfig{RangeMaxBytes: 32 << 20, PinExpiration: 2000, Inherit: true}
+	fields := z.InheritFrom(parent)
+	expFields := []string{"replicas", "range_min_bytes", "gc", "reads_per_second", "commit_coalescing_window_micros"}
+	if !reflect.DeepEqual(fields, expFields) {
+		t.Errorf("expected inherited fields %v; got %v", expFields, fields)
+	}
+	expected := &ZoneConfig{
+		ReplicaAttrs:   parent.ReplicaAttrs,
+		RangeMinBytes:  1 << 20,
+		RangeMaxBytes:  32 << 20,
+		GC:             parent.GC,
+		ReadsPerSecond: 100,
+		PinExpiration:  2000,
+		Inherit:        true,
+
+		CommitCoalescingWindowMicros: 2000,
+	}
+	if !reflect.DeepEqual(z, expected) {
+		t.Errorf("expected zone %+v; got %+v", expected, z)
+	}
+
+	z = &ZoneConfig{}
+	if fields := z.InheritFrom(parent); len(fields) != 8 || !reflect.DeepEqual(z.PinnedStores, parent.PinnedStores) {
+		t.Errorf("expected all fields to be inherited; got %v: %+v", fields, z)
+	}
+}
+
 func verifyOrdering(attrs []string, rs ReplicaSlice, prefixLen int) bool {
 	prevMatchIndex := len(attrs)
 	for i := range rs {
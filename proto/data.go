@@ -20,6 +20,7 @@ package proto
 import (
 	"bytes"
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -205,17 +206,30 @@ func (k *EncodedKey) Unmarshal(bytes []byte) error {
 }
 
 // The following methods implement custom unmarshalling necessary
-// for key objects to be converted from JSON.
+// for key objects to be converted from JSON. Keys are encoded in JSON
+// as base64 strings, the same as all other bytes fields, so that they
+// round trip unchanged.
 
 // UnmarshalJSON implements the json Unmarshaler interface.
 func (k *Key) UnmarshalJSON(bytes []byte) error {
-	*k = Key(append([]byte(nil), bytes...))
+	var b []byte
+	if err := json.Unmarshal(bytes, &b); err != nil {
+		return err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	*k = Key(b)
 	return nil
 }
 
 // UnmarshalJSON implements the json Unmarshaler interface.
 func (k *EncodedKey) UnmarshalJSON(bytes []byte) error {
-	*k = EncodedKey(append([]byte(nil), bytes...))
+	var b []byte
+	if err := json.Unmarshal(bytes, &b); err != nil {
+		return err
+	}
+	*k = EncodedKey(b)
 	return nil
 }
 
@@ -137,6 +137,44 @@ func (x *TransactionStatus) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// RangeHistoryEventType is the type of a RangeHistoryEvent.
+type RangeHistoryEventType int32
+
+const (
+	// SPLIT records the creation of the child range from the upper
+	// half of the parent range.
+	SPLIT RangeHistoryEventType = 0
+	// MERGE records the subsumption of the child range by the parent
+	// range preceding it.
+	MERGE RangeHistoryEventType = 1
+)
+
+var RangeHistoryEventType_name = map[int32]string{
+	0: "SPLIT",
+	1: "MERGE",
+}
+var RangeHistoryEventType_value = map[string]int32{
+	"SPLIT": 0,
+	"MERGE": 1,
+}
+
+func (x RangeHistoryEventType) Enum() *RangeHistoryEventType {
+	p := new(RangeHistoryEventType)
+	*p = x
+	return p
+}
+func (x RangeHistoryEventType) String() string {
+	return proto1.EnumName(RangeHistoryEventType_name, int32(x))
+}
+func (x *RangeHistoryEventType) UnmarshalJSON(data []byte) error {
+	value, err := proto1.UnmarshalJSONEnum(RangeHistoryEventType_value, data, "RangeHistoryEventType")
+	if err != nil {
+		return err
+	}
+	*x = RangeHistoryEventType(value)
+	return nil
+}
+
 // Timestamp represents a state of the hybrid logical clock.
 type Timestamp struct {
 	// Holds a wall time, typically a unix epoch time
@@ -747,6 +785,75 @@ func (m *GCMetadata) GetOldestIntentNanos() int64 {
 	return 0
 }
 
+// RangeHistoryEvent records a split or merge in which a range took
+// part.
+type RangeHistoryEvent struct {
+	Type RangeHistoryEventType `protobuf:"varint,1,opt,name=type,enum=cockroach.proto.RangeHistoryEventType" json:"type"`
+	// Timestamp is the commit timestamp of the split or merge
+	// transaction.
+	Timestamp Timestamp `protobuf:"bytes,2,opt,name=timestamp" json:"timestamp"`
+	// ParentRaftID is the range which was split or which subsumed the
+	// child range.
+	ParentRaftID int64 `protobuf:"varint,3,opt,name=parent_raft_id" json:"parent_raft_id"`
+	// ChildRaftID is the range which was created by the split or
+	// subsumed by the merge.
+	ChildRaftID int64 `protobuf:"varint,4,opt,name=child_raft_id" json:"child_raft_id"`
+	// Key is the boundary between the parent and child ranges.
+	Key              Key    `protobuf:"bytes,5,opt,name=key,customtype=Key" json:"key"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *RangeHistoryEvent) Reset()         { *m = RangeHistoryEvent{} }
+func (m *RangeHistoryEvent) String() string { return proto1.CompactTextString(m) }
+func (*RangeHistoryEvent) ProtoMessage()    {}
+
+func (m *RangeHistoryEvent) GetType() RangeHistoryEventType {
+	if m != nil {
+		return m.Type
+	}
+	return 0
+}
+
+func (m *RangeHistoryEvent) GetTimestamp() Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return Timestamp{}
+}
+
+func (m *RangeHistoryEvent) GetParentRaftID() int64 {
+	if m != nil {
+		return m.ParentRaftID
+	}
+	return 0
+}
+
+func (m *RangeHistoryEvent) GetChildRaftID() int64 {
+	if m != nil {
+		return m.ChildRaftID
+	}
+	return 0
+}
+
+// RangeHistory holds the most recent splits and merges in which a range
+// took part, oldest first. It is stored in a range-local key and copied
+// to the new range on a split.
+type RangeHistory struct {
+	Events           []RangeHistoryEvent `protobuf:"bytes,1,rep,name=events" json:"events"`
+	XXX_unrecognized []byte              `json:"-"`
+}
+
+func (m *RangeHistory) Reset()         { *m = RangeHistory{} }
+func (m *RangeHistory) String() string { return proto1.CompactTextString(m) }
+func (*RangeHistory) ProtoMessage()    {}
+
+func (m *RangeHistory) GetEvents() []RangeHistoryEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
 // TimeSeriesDatapoint is a single point of time series data; a value associated
 // with a timestamp.
 type TimeSeriesDatapoint struct {
@@ -937,6 +1044,7 @@ func init() {
 	proto1.RegisterEnum("cockroach.proto.ReplicaChangeType", ReplicaChangeType_name, ReplicaChangeType_value)
 	proto1.RegisterEnum("cockroach.proto.IsolationType", IsolationType_name, IsolationType_value)
 	proto1.RegisterEnum("cockroach.proto.TransactionStatus", TransactionStatus_name, TransactionStatus_value)
+	proto1.RegisterEnum("cockroach.proto.RangeHistoryEventType", RangeHistoryEventType_name, RangeHistoryEventType_value)
 }
 func (m *Timestamp) Unmarshal(data []byte) error {
 	l := len(data)
@@ -2669,6 +2777,206 @@ func (m *GCMetadata) Unmarshal(data []byte) error {
 	}
 	return nil
 }
+func (m *RangeHistoryEvent) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Type |= (RangeHistoryEventType(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Timestamp.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ParentRaftID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ParentRaftID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChildRaftID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ChildRaftID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Key.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *RangeHistory) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Events", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Events = append(m.Events, RangeHistoryEvent{})
+			m.Events[len(m.Events)-1].Unmarshal(data[index:postIndex])
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
 func (m *TimeSeriesDatapoint) Unmarshal(data []byte) error {
 	l := len(data)
 	index := 0
@@ -3335,6 +3643,37 @@ func (m *GCMetadata) Size() (n int) {
 	return n
 }
 
+func (m *RangeHistoryEvent) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovData(uint64(m.Type))
+	l = m.Timestamp.Size()
+	n += 1 + l + sovData(uint64(l))
+	n += 1 + sovData(uint64(m.ParentRaftID))
+	n += 1 + sovData(uint64(m.ChildRaftID))
+	l = m.Key.Size()
+	n += 1 + l + sovData(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RangeHistory) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Events) > 0 {
+		for _, e := range m.Events {
+			l = e.Size()
+			n += 1 + l + sovData(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
 func (m *TimeSeriesDatapoint) Size() (n int) {
 	var l int
 	_ = l
@@ -4056,6 +4395,85 @@ func (m *GCMetadata) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
+func (m *RangeHistoryEvent) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RangeHistoryEvent) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintData(data, i, uint64(m.Type))
+	data[i] = 0x12
+	i++
+	i = encodeVarintData(data, i, uint64(m.Timestamp.Size()))
+	n23, err := m.Timestamp.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n23
+	data[i] = 0x18
+	i++
+	i = encodeVarintData(data, i, uint64(m.ParentRaftID))
+	data[i] = 0x20
+	i++
+	i = encodeVarintData(data, i, uint64(m.ChildRaftID))
+	data[i] = 0x2a
+	i++
+	i = encodeVarintData(data, i, uint64(m.Key.Size()))
+	n24, err := m.Key.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n24
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *RangeHistory) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *RangeHistory) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Events) > 0 {
+		for _, msg := range m.Events {
+			data[i] = 0xa
+			i++
+			i = encodeVarintData(data, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(data[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
 func (m *TimeSeriesDatapoint) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
@@ -19,6 +19,7 @@ package proto
 
 import (
 	"bytes"
+	"encoding/json"
 	"math"
 	"math/rand"
 	"reflect"
@@ -167,6 +168,31 @@ func TestKeyString(t *testing.T) {
 	}
 }
 
+// TestKeyJSON verifies that keys are encoded in JSON as base64 strings
+// and decode to the original key.
+func TestKeyJSON(t *testing.T) {
+	for i, k := range []Key{{}, Key("a"), Key("\x00\xff/key with spaces")} {
+		b, err := json.Marshal(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Key
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if !decoded.Equal(k) || decoded == nil {
+			t.Errorf("%d: expected %q; got %q", i, k, decoded)
+		}
+	}
+	var k Key
+	if err := json.Unmarshal([]byte(`"YQ=="`), &k); err != nil || !k.Equal(Key("a")) {
+		t.Errorf("expected base64 key to decode to %q; got %q (%v)", "a", k, err)
+	}
+	if err := json.Unmarshal([]byte(`"not base64!"`), &k); err == nil {
+		t.Error("expected error decoding key which is not base64")
+	}
+}
+
 func makeTS(walltime int64, logical int32) Timestamp {
 	return Timestamp{
 		WallTime: walltime,
@@ -177,3 +177,20 @@ func (e *OpRequiresTxnError) Error() string {
 func (e *ConditionFailedError) Error() string {
 	return fmt.Sprintf("unexpected value: %s", e.ActualValue)
 }
+
+// Error formats error.
+func (e *IncrementBoundsError) Error() string {
+	return fmt.Sprintf("increment out of bounds; current value: %d", e.CurrentValue)
+}
+
+// Error formats error.
+func (e *BatchTooLargeError) Error() string {
+	return fmt.Sprintf("batch of %d requests, %d bytes exceeds the limit of %d requests, %d bytes; split it into smaller batches",
+		e.Requests, e.Bytes, e.MaxRequests, e.MaxBytes)
+}
+
+// CanRetry indicates whether or not this BatchTooLargeError can be
+// retried. It can, once the batch has been split.
+func (e *BatchTooLargeError) CanRetry() bool {
+	return true
+}
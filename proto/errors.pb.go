@@ -332,6 +332,74 @@ func (m *ConditionFailedError) GetActualValue() *Value {
 	return nil
 }
 
+// An IncrementBoundsError indicates that an increment would have
+// moved the value of a key outside of the bounds specified in the
+// IncrementRequest. The increment is not applied. The error will
+// contain the current value of the key.
+type IncrementBoundsError struct {
+	CurrentValue     int64  `protobuf:"varint,1,opt,name=current_value" json:"current_value"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *IncrementBoundsError) Reset()         { *m = IncrementBoundsError{} }
+func (m *IncrementBoundsError) String() string { return proto1.CompactTextString(m) }
+func (*IncrementBoundsError) ProtoMessage()    {}
+
+func (m *IncrementBoundsError) GetCurrentValue() int64 {
+	if m != nil {
+		return m.CurrentValue
+	}
+	return 0
+}
+
+// A BatchTooLargeError indicates that a batch exceeded the maximum
+// number of requests or bytes accepted by the coordinator. None of its
+// requests were executed. The batch may be retried once split into
+// batches within the limits, which the client does automatically.
+type BatchTooLargeError struct {
+	// Requests and Bytes are the number of requests in the batch and
+	// their size.
+	Requests int32 `protobuf:"varint,1,opt,name=requests" json:"requests"`
+	Bytes    int64 `protobuf:"varint,2,opt,name=bytes" json:"bytes"`
+	// MaxRequests and MaxBytes are the limits; zero values are
+	// unlimited.
+	MaxRequests      int32  `protobuf:"varint,3,opt,name=max_requests" json:"max_requests"`
+	MaxBytes         int64  `protobuf:"varint,4,opt,name=max_bytes" json:"max_bytes"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *BatchTooLargeError) Reset()         { *m = BatchTooLargeError{} }
+func (m *BatchTooLargeError) String() string { return proto1.CompactTextString(m) }
+func (*BatchTooLargeError) ProtoMessage()    {}
+
+func (m *BatchTooLargeError) GetRequests() int32 {
+	if m != nil {
+		return m.Requests
+	}
+	return 0
+}
+
+func (m *BatchTooLargeError) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *BatchTooLargeError) GetMaxRequests() int32 {
+	if m != nil {
+		return m.MaxRequests
+	}
+	return 0
+}
+
+func (m *BatchTooLargeError) GetMaxBytes() int64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
 // ErrorDetail is a union type containing all available errors.
 type ErrorDetail struct {
 	NotLeader                     *NotLeaderError                     `protobuf:"bytes,1,opt,name=not_leader" json:"not_leader,omitempty"`
@@ -346,6 +414,8 @@ type ErrorDetail struct {
 	WriteTooOld                   *WriteTooOldError                   `protobuf:"bytes,10,opt,name=write_too_old" json:"write_too_old,omitempty"`
 	OpRequiresTxn                 *OpRequiresTxnError                 `protobuf:"bytes,11,opt,name=op_requires_txn" json:"op_requires_txn,omitempty"`
 	ConditionFailed               *ConditionFailedError               `protobuf:"bytes,12,opt,name=condition_failed" json:"condition_failed,omitempty"`
+	IncrementBounds               *IncrementBoundsError               `protobuf:"bytes,13,opt,name=increment_bounds" json:"increment_bounds,omitempty"`
+	BatchTooLarge                 *BatchTooLargeError                 `protobuf:"bytes,14,opt,name=batch_too_large" json:"batch_too_large,omitempty"`
 	XXX_unrecognized              []byte                              `json:"-"`
 }
 
@@ -437,6 +507,20 @@ func (m *ErrorDetail) GetConditionFailed() *ConditionFailedError {
 	return nil
 }
 
+func (m *ErrorDetail) GetIncrementBounds() *IncrementBoundsError {
+	if m != nil {
+		return m.IncrementBounds
+	}
+	return nil
+}
+
+func (m *ErrorDetail) GetBatchTooLarge() *BatchTooLargeError {
+	if m != nil {
+		return m.BatchTooLarge
+	}
+	return nil
+}
+
 // Error is a generic represesentation including a string message
 // and information about retryability.
 type Error struct {
@@ -1439,6 +1523,165 @@ func (m *ConditionFailedError) Unmarshal(data []byte) error {
 	}
 	return nil
 }
+func (m *IncrementBoundsError) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CurrentValue", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.CurrentValue |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *BatchTooLargeError) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Requests", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Requests |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Bytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxRequests", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxRequests |= (int32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBytes", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.MaxBytes |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
 func (m *ErrorDetail) Unmarshal(data []byte) error {
 	l := len(data)
 	index := 0
@@ -1782,6 +2025,60 @@ func (m *ErrorDetail) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IncrementBounds", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.IncrementBounds == nil {
+				m.IncrementBounds = &IncrementBoundsError{}
+			}
+			if err := m.IncrementBounds.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BatchTooLarge", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.BatchTooLarge == nil {
+				m.BatchTooLarge = &BatchTooLargeError{}
+			}
+			if err := m.BatchTooLarge.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -1965,6 +2262,12 @@ func (this *ErrorDetail) GetValue() interface{} {
 	if this.ConditionFailed != nil {
 		return this.ConditionFailed
 	}
+	if this.IncrementBounds != nil {
+		return this.IncrementBounds
+	}
+	if this.BatchTooLarge != nil {
+		return this.BatchTooLarge
+	}
 	return nil
 }
 
@@ -1994,6 +2297,10 @@ func (this *ErrorDetail) SetValue(value interface{}) bool {
 		this.OpRequiresTxn = vt
 	case *ConditionFailedError:
 		this.ConditionFailed = vt
+	case *IncrementBoundsError:
+		this.IncrementBounds = vt
+	case *BatchTooLargeError:
+		this.BatchTooLarge = vt
 	default:
 		return false
 	}
@@ -2149,6 +2456,29 @@ func (m *ConditionFailedError) Size() (n int) {
 	return n
 }
 
+func (m *IncrementBoundsError) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovErrors(uint64(m.CurrentValue))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *BatchTooLargeError) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovErrors(uint64(m.Requests))
+	n += 1 + sovErrors(uint64(m.Bytes))
+	n += 1 + sovErrors(uint64(m.MaxRequests))
+	n += 1 + sovErrors(uint64(m.MaxBytes))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
 func (m *ErrorDetail) Size() (n int) {
 	var l int
 	_ = l
@@ -2200,6 +2530,14 @@ func (m *ErrorDetail) Size() (n int) {
 		l = m.ConditionFailed.Size()
 		n += 1 + l + sovErrors(uint64(l))
 	}
+	if m.IncrementBounds != nil {
+		l = m.IncrementBounds.Size()
+		n += 1 + l + sovErrors(uint64(l))
+	}
+	if m.BatchTooLarge != nil {
+		l = m.BatchTooLarge.Size()
+		n += 1 + l + sovErrors(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2637,6 +2975,63 @@ func (m *ConditionFailedError) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
+func (m *IncrementBoundsError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *IncrementBoundsError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.CurrentValue))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *BatchTooLargeError) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *BatchTooLargeError) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Requests))
+	data[i] = 0x10
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.Bytes))
+	data[i] = 0x18
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.MaxRequests))
+	data[i] = 0x20
+	i++
+	i = encodeVarintErrors(data, i, uint64(m.MaxBytes))
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
 func (m *ErrorDetail) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
@@ -2772,6 +3167,26 @@ func (m *ErrorDetail) MarshalTo(data []byte) (n int, err error) {
 		}
 		i += n28
 	}
+	if m.IncrementBounds != nil {
+		data[i] = 0x6a
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.IncrementBounds.Size()))
+		n30, err := m.IncrementBounds.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n30
+	}
+	if m.BatchTooLarge != nil {
+		data[i] = 0x72
+		i++
+		i = encodeVarintErrors(data, i, uint64(m.BatchTooLarge.Size()))
+		n31, err := m.BatchTooLarge.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n31
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
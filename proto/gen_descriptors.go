@@ -0,0 +1,60 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+// +build ignore
+
+// gen_descriptors reads a serialized FileDescriptorSet, as written by
+// protoc's --descriptor_set_out flag, from stdin and writes a Go
+// source file embedding it in gzipped form to stdout.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const header = `// Code generated by gen_descriptors.go. DO NOT EDIT!
+
+package proto
+
+// fileDescriptorSetGzipped holds the gzipped and serialized
+// google.protobuf.FileDescriptorSet for the protocol buffer
+// definitions in this package and their dependencies.
+var fileDescriptorSetGzipped = []byte("`
+
+func main() {
+	set, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read descriptor set: %s\n", err)
+		os.Exit(1)
+	}
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to compress descriptor set: %s\n", err)
+		os.Exit(1)
+	}
+	gz.Write(set)
+	gz.Close()
+
+	fmt.Print(header)
+	for _, b := range buf.Bytes() {
+		fmt.Printf("\\x%02x", b)
+	}
+	fmt.Print("\")\n")
+}
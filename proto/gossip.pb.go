@@ -37,6 +37,25 @@ func (m *Addr) GetAddress() string {
 	return ""
 }
 
+// HighWaterStamp is the timestamp of the newest info originated by a
+// node which a gossip peer holds.
+type HighWaterStamp struct {
+	NodeID           NodeID `protobuf:"varint,1,opt,name=node_id,customtype=NodeID" json:"node_id"`
+	Stamp            int64  `protobuf:"varint,2,opt,name=stamp" json:"stamp"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *HighWaterStamp) Reset()         { *m = HighWaterStamp{} }
+func (m *HighWaterStamp) String() string { return proto1.CompactTextString(m) }
+func (*HighWaterStamp) ProtoMessage()    {}
+
+func (m *HighWaterStamp) GetStamp() int64 {
+	if m != nil {
+		return m.Stamp
+	}
+	return 0
+}
+
 // GossipRequest is the request struct passed with the Gossip RPC.
 type GossipRequest struct {
 	// Requesting node's ID.
@@ -49,8 +68,11 @@ type GossipRequest struct {
 	// Maximum sequence number of gossip from this peer.
 	MaxSeq int64 `protobuf:"varint,4,opt,name=max_seq" json:"max_seq"`
 	// Reciprocal delta of new info since last gossip.
-	Delta            []byte `protobuf:"bytes,5,opt,name=delta" json:"delta"`
-	XXX_unrecognized []byte `json:"-"`
+	Delta []byte `protobuf:"bytes,5,opt,name=delta" json:"delta"`
+	// High water timestamps of the requesting node's infos, by
+	// originating node. Infos no newer are omitted from the response.
+	HighWaterStamps  []HighWaterStamp `protobuf:"bytes,6,rep,name=high_water_stamps" json:"high_water_stamps"`
+	XXX_unrecognized []byte           `json:"-"`
 }
 
 func (m *GossipRequest) Reset()         { *m = GossipRequest{} }
@@ -85,14 +107,24 @@ func (m *GossipRequest) GetDelta() []byte {
 	return nil
 }
 
+func (m *GossipRequest) GetHighWaterStamps() []HighWaterStamp {
+	if m != nil {
+		return m.HighWaterStamps
+	}
+	return nil
+}
+
 // GossipResponse is returned from the Gossip.Gossip RPC.
 // Delta will be nil in the event that Alternate is set.
 type GossipResponse struct {
 	// Requested delta of server's infostore.
 	Delta []byte `protobuf:"bytes,1,opt,name=delta" json:"delta"`
 	// Non-nil means client should retry with this address.
-	Alternate        *Addr  `protobuf:"bytes,2,opt,name=alternate" json:"alternate,omitempty"`
-	XXX_unrecognized []byte `json:"-"`
+	Alternate *Addr `protobuf:"bytes,2,opt,name=alternate" json:"alternate,omitempty"`
+	// High water timestamps of the server's infos, by originating node.
+	// Infos no newer may be omitted from the next request.
+	HighWaterStamps  []HighWaterStamp `protobuf:"bytes,3,rep,name=high_water_stamps" json:"high_water_stamps"`
+	XXX_unrecognized []byte           `json:"-"`
 }
 
 func (m *GossipResponse) Reset()         { *m = GossipResponse{} }
@@ -113,5 +145,12 @@ func (m *GossipResponse) GetAlternate() *Addr {
 	return nil
 }
 
+func (m *GossipResponse) GetHighWaterStamps() []HighWaterStamp {
+	if m != nil {
+		return m.HighWaterStamps
+	}
+	return nil
+}
+
 func init() {
 }
@@ -54,6 +54,96 @@ func (x *InternalValueType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// JobType identifies the kind of long-running operation tracked by a
+// JobRecord.
+type JobType int32
+
+const (
+	// BACKUP writes the keys with a prefix to a backup file.
+	JobType_BACKUP JobType = 1
+	// RESTORE writes the keys held by a backup file.
+	JobType_RESTORE JobType = 2
+	// DROP_NAMESPACE deletes the keys of dropped namespaces.
+	JobType_DROP_NAMESPACE JobType = 6
+)
+
+var JobType_name = map[int32]string{
+	1: "BACKUP",
+	2: "RESTORE",
+	6: "DROP_NAMESPACE",
+}
+var JobType_value = map[string]int32{
+	"BACKUP":         1,
+	"RESTORE":        2,
+	"DROP_NAMESPACE": 6,
+}
+
+func (x JobType) Enum() *JobType {
+	p := new(JobType)
+	*p = x
+	return p
+}
+func (x JobType) String() string {
+	return proto1.EnumName(JobType_name, int32(x))
+}
+func (x *JobType) UnmarshalJSON(data []byte) error {
+	value, err := proto1.UnmarshalJSONEnum(JobType_value, data, "JobType")
+	if err != nil {
+		return err
+	}
+	*x = JobType(value)
+	return nil
+}
+
+// JobStatus specifies the possible states of a job.
+type JobStatus int32
+
+const (
+	// QUEUED jobs have been created but not yet started.
+	JobStatus_QUEUED JobStatus = 0
+	// RUNNING jobs are being executed by a node.
+	JobStatus_RUNNING JobStatus = 1
+	// PAUSED jobs have been stopped on request and may be resumed.
+	JobStatus_PAUSED JobStatus = 2
+	// SUCCEEDED jobs have completed all of their work.
+	JobStatus_SUCCEEDED JobStatus = 3
+	// FAILED jobs have stopped with an error, recorded in the job's
+	// error field.
+	JobStatus_FAILED JobStatus = 4
+)
+
+var JobStatus_name = map[int32]string{
+	0: "QUEUED",
+	1: "RUNNING",
+	2: "PAUSED",
+	3: "SUCCEEDED",
+	4: "FAILED",
+}
+var JobStatus_value = map[string]int32{
+	"QUEUED":    0,
+	"RUNNING":   1,
+	"PAUSED":    2,
+	"SUCCEEDED": 3,
+	"FAILED":    4,
+}
+
+func (x JobStatus) Enum() *JobStatus {
+	p := new(JobStatus)
+	*p = x
+	return p
+}
+func (x JobStatus) String() string {
+	return proto1.EnumName(JobStatus_name, int32(x))
+}
+func (x *JobStatus) UnmarshalJSON(data []byte) error {
+	value, err := proto1.UnmarshalJSONEnum(JobStatus_value, data, "JobStatus")
+	if err != nil {
+		return err
+	}
+	*x = JobStatus(value)
+	return nil
+}
+
 // An InternalRangeLookupRequest is arguments to the
 // InternalRangeLookup() method. It specifies the key for which the
 // containing range is being requested, and the maximum number of
@@ -368,6 +458,41 @@ func (m *InternalLeaderLeaseResponse) Reset()         { *m = InternalLeaderLease
 func (m *InternalLeaderLeaseResponse) String() string { return proto1.CompactTextString(m) }
 func (*InternalLeaderLeaseResponse) ProtoMessage()    {}
 
+// An InternalIngestRequest is arguments to the InternalIngest() method. It
+// writes rows, sorted by key and with distinct keys, as values at the request
+// timestamp to the key span of the request, which must otherwise be empty.
+// The rows are linked into the stores of the range's replicas as sstables,
+// bypassing their memtables, which makes for far faster initial loads of
+// data than puts.
+type InternalIngestRequest struct {
+	RequestHeader `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	// Rows are the keys and values to write, in increasing key order.
+	Rows             []KeyValue `protobuf:"bytes,2,rep,name=rows" json:"rows"`
+	XXX_unrecognized []byte     `json:"-"`
+}
+
+func (m *InternalIngestRequest) Reset()         { *m = InternalIngestRequest{} }
+func (m *InternalIngestRequest) String() string { return proto1.CompactTextString(m) }
+func (*InternalIngestRequest) ProtoMessage()    {}
+
+func (m *InternalIngestRequest) GetRows() []KeyValue {
+	if m != nil {
+		return m.Rows
+	}
+	return nil
+}
+
+// An InternalIngestResponse is the response to an InternalIngest()
+// operation.
+type InternalIngestResponse struct {
+	ResponseHeader   `protobuf:"bytes,1,opt,name=header,embedded=header" json:"header"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *InternalIngestResponse) Reset()         { *m = InternalIngestResponse{} }
+func (m *InternalIngestResponse) String() string { return proto1.CompactTextString(m) }
+func (*InternalIngestResponse) ProtoMessage()    {}
+
 // A ReadWriteCmdResponse is a union type containing instances of all
 // mutating commands. Note that any entry added here must be handled
 // in storage/engine/db.cc in GetResponseHeader().
@@ -384,6 +509,7 @@ type ReadWriteCmdResponse struct {
 	InternalMerge         *InternalMergeResponse         `protobuf:"bytes,13,opt,name=internal_merge" json:"internal_merge,omitempty"`
 	InternalTruncateLog   *InternalTruncateLogResponse   `protobuf:"bytes,14,opt,name=internal_truncate_log" json:"internal_truncate_log,omitempty"`
 	InternalGc            *InternalGCResponse            `protobuf:"bytes,15,opt,name=internal_gc" json:"internal_gc,omitempty"`
+	InternalIngest        *InternalIngestResponse        `protobuf:"bytes,16,opt,name=internal_ingest" json:"internal_ingest,omitempty"`
 	XXX_unrecognized      []byte                         `json:"-"`
 }
 
@@ -475,6 +601,13 @@ func (m *ReadWriteCmdResponse) GetInternalGc() *InternalGCResponse {
 	return nil
 }
 
+func (m *ReadWriteCmdResponse) GetInternalIngest() *InternalIngestResponse {
+	if m != nil {
+		return m.InternalIngest
+	}
+	return nil
+}
+
 // An InternalRaftCommandUnion is the union of all commands which can be
 // sent via raft.
 type InternalRaftCommandUnion struct {
@@ -499,6 +632,7 @@ type InternalRaftCommandUnion struct {
 	InternalTruncateLog   *InternalTruncateLogRequest   `protobuf:"bytes,36,opt,name=internal_truncate_log" json:"internal_truncate_log,omitempty"`
 	InternalGC            *InternalGCRequest            `protobuf:"bytes,37,opt,name=internal_gc" json:"internal_gc,omitempty"`
 	InternalLease         *InternalLeaderLeaseRequest   `protobuf:"bytes,38,opt,name=internal_lease" json:"internal_lease,omitempty"`
+	InternalIngest        *InternalIngestRequest        `protobuf:"bytes,39,opt,name=internal_ingest" json:"internal_ingest,omitempty"`
 	XXX_unrecognized      []byte                        `json:"-"`
 }
 
@@ -632,12 +766,28 @@ func (m *InternalRaftCommandUnion) GetInternalLease() *InternalLeaderLeaseReques
 	return nil
 }
 
+func (m *InternalRaftCommandUnion) GetInternalIngest() *InternalIngestRequest {
+	if m != nil {
+		return m.InternalIngest
+	}
+	return nil
+}
+
 // An InternalRaftCommand is a command which can be serialized and
 // sent via raft.
 type InternalRaftCommand struct {
-	RaftID           int64                    `protobuf:"varint,2,opt,name=raft_id" json:"raft_id"`
-	Cmd              InternalRaftCommandUnion `protobuf:"bytes,3,opt,name=cmd" json:"cmd"`
-	XXX_unrecognized []byte                   `json:"-"`
+	RaftID int64                    `protobuf:"varint,2,opt,name=raft_id" json:"raft_id"`
+	Cmd    InternalRaftCommandUnion `protobuf:"bytes,3,opt,name=cmd" json:"cmd"`
+	// ClosedTimestamp is the leader's closed timestamp when the command
+	// was proposed: no command proposed after it writes at or below this
+	// timestamp.
+	ClosedTimestamp Timestamp `protobuf:"bytes,4,opt,name=closed_timestamp" json:"closed_timestamp"`
+	// Coalesced, if set, are the commands held by the leader for its
+	// commit coalescing window and proposed together, in the order they
+	// were proposed. They're applied in a single engine batch; the
+	// command itself then holds no request.
+	Coalesced        []CoalescedRaftCommand `protobuf:"bytes,5,rep,name=coalesced" json:"coalesced"`
+	XXX_unrecognized []byte                 `json:"-"`
 }
 
 func (m *InternalRaftCommand) Reset()         { *m = InternalRaftCommand{} }
@@ -658,6 +808,48 @@ func (m *InternalRaftCommand) GetCmd() InternalRaftCommandUnion {
 	return InternalRaftCommandUnion{}
 }
 
+func (m *InternalRaftCommand) GetClosedTimestamp() Timestamp {
+	if m != nil {
+		return m.ClosedTimestamp
+	}
+	return Timestamp{}
+}
+
+func (m *InternalRaftCommand) GetCoalesced() []CoalescedRaftCommand {
+	if m != nil {
+		return m.Coalesced
+	}
+	return nil
+}
+
+// A CoalescedRaftCommand is one of the commands of a coalesced
+// InternalRaftCommand.
+type CoalescedRaftCommand struct {
+	// CmdIDKey identifies the command to its proposer, as the command ID
+	// of the raft log entry does for a command proposed alone.
+	CmdIDKey         string              `protobuf:"bytes,1,opt,name=cmd_id_key" json:"cmd_id_key"`
+	Cmd              InternalRaftCommand `protobuf:"bytes,2,opt,name=cmd" json:"cmd"`
+	XXX_unrecognized []byte              `json:"-"`
+}
+
+func (m *CoalescedRaftCommand) Reset()         { *m = CoalescedRaftCommand{} }
+func (m *CoalescedRaftCommand) String() string { return proto1.CompactTextString(m) }
+func (*CoalescedRaftCommand) ProtoMessage()    {}
+
+func (m *CoalescedRaftCommand) GetCmdIDKey() string {
+	if m != nil {
+		return m.CmdIDKey
+	}
+	return ""
+}
+
+func (m *CoalescedRaftCommand) GetCmd() InternalRaftCommand {
+	if m != nil {
+		return m.Cmd
+	}
+	return InternalRaftCommand{}
+}
+
 // RaftMessageRequest is the request used to send raft messages using our
 // protobuf-based RPC codec. Unlike most of the requests defined in this file
 // and api.proto, this one is implemented in a separate service defined in
@@ -944,8 +1136,121 @@ func (m *RaftSnapshotData_KeyValue) GetValue() []byte {
 	return nil
 }
 
+// JobRecord is the persistent state of a long-running operation,
+// stored under the system job key prefix.
+type JobRecord struct {
+	ID   int64   `protobuf:"varint,1,opt,name=id" json:"id"`
+	Type JobType `protobuf:"varint,2,opt,name=type,enum=cockroach.proto.JobType" json:"type"`
+	// A human-readable description of the work performed by the job.
+	Description string    `protobuf:"bytes,3,opt,name=description" json:"description"`
+	Status      JobStatus `protobuf:"varint,4,opt,name=status,enum=cockroach.proto.JobStatus" json:"status"`
+	// The fraction of the job's work completed, between 0 and 1.
+	Progress float64 `protobuf:"fixed64,5,opt,name=progress" json:"progress"`
+	// The wall times at which the job was created and last modified,
+	// expressed as unix epoch times in nanoseconds.
+	CreatedNanos  int64 `protobuf:"varint,6,opt,name=created_nanos" json:"created_nanos"`
+	ModifiedNanos int64 `protobuf:"varint,7,opt,name=modified_nanos" json:"modified_nanos"`
+	// Set if the job failed, describing the error which caused it.
+	Error            string `protobuf:"bytes,8,opt,name=error" json:"error"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *JobRecord) Reset()         { *m = JobRecord{} }
+func (m *JobRecord) String() string { return proto1.CompactTextString(m) }
+func (*JobRecord) ProtoMessage()    {}
+
+func (m *JobRecord) GetID() int64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *JobRecord) GetType() JobType {
+	if m != nil {
+		return m.Type
+	}
+	return 0
+}
+
+func (m *JobRecord) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *JobRecord) GetStatus() JobStatus {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *JobRecord) GetProgress() float64 {
+	if m != nil {
+		return m.Progress
+	}
+	return 0
+}
+
+func (m *JobRecord) GetCreatedNanos() int64 {
+	if m != nil {
+		return m.CreatedNanos
+	}
+	return 0
+}
+
+func (m *JobRecord) GetModifiedNanos() int64 {
+	if m != nil {
+		return m.ModifiedNanos
+	}
+	return 0
+}
+
+func (m *JobRecord) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// NamespaceDescriptor records the allocation of a key prefix to a named
+// namespace. Descriptors of live namespaces are stored under the system
+// namespace key prefix by name; those of dropped namespaces whose keys
+// have yet to be deleted are stored under the dropped namespace key
+// prefix by ID.
+type NamespaceDescriptor struct {
+	ID   int64  `protobuf:"varint,1,opt,name=id" json:"id"`
+	Name string `protobuf:"bytes,2,opt,name=name" json:"name"`
+	// Prefix is the key prefix allocated to the namespace. It is derived
+	// from the ID and so is kept when the namespace is renamed.
+	Prefix           Key    `protobuf:"bytes,3,opt,name=prefix,customtype=Key" json:"prefix"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *NamespaceDescriptor) Reset()         { *m = NamespaceDescriptor{} }
+func (m *NamespaceDescriptor) String() string { return proto1.CompactTextString(m) }
+func (*NamespaceDescriptor) ProtoMessage()    {}
+
+func (m *NamespaceDescriptor) GetID() int64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *NamespaceDescriptor) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
 func init() {
 	proto1.RegisterEnum("cockroach.proto.InternalValueType", InternalValueType_name, InternalValueType_value)
+	proto1.RegisterEnum("cockroach.proto.JobType", JobType_name, JobType_value)
+	proto1.RegisterEnum("cockroach.proto.JobStatus", JobStatus_name, JobStatus_value)
 }
 func (m *InternalRangeLookupRequest) Unmarshal(data []byte) error {
 	l := len(data)
@@ -2309,7 +2614,7 @@ func (m *InternalLeaderLeaseResponse) Unmarshal(data []byte) error {
 	}
 	return nil
 }
-func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
+func (m *InternalIngestRequest) Unmarshal(data []byte) error {
 	l := len(data)
 	index := 0
 	for index < l {
@@ -2330,7 +2635,7 @@ func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Put", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestHeader", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2348,16 +2653,13 @@ func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Put == nil {
-				m.Put = &PutResponse{}
-			}
-			if err := m.Put.Unmarshal(data[index:postIndex]); err != nil {
+			if err := m.RequestHeader.Unmarshal(data[index:postIndex]); err != nil {
 				return err
 			}
 			index = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ConditionalPut", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Rows", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2375,16 +2677,54 @@ func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.ConditionalPut == nil {
-				m.ConditionalPut = &ConditionalPutResponse{}
+			m.Rows = append(m.Rows, KeyValue{})
+			m.Rows[len(m.Rows)-1].Unmarshal(data[index:postIndex])
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
 			}
-			if err := m.ConditionalPut.Unmarshal(data[index:postIndex]); err != nil {
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
 				return err
 			}
-			index = postIndex
-		case 3:
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *InternalIngestResponse) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Increment", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseHeader", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2402,16 +2742,55 @@ func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Increment == nil {
-				m.Increment = &IncrementResponse{}
-			}
-			if err := m.Increment.Unmarshal(data[index:postIndex]); err != nil {
+			if err := m.ResponseHeader.Unmarshal(data[index:postIndex]); err != nil {
 				return err
 			}
 			index = postIndex
-		case 4:
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Delete", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Put", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2429,16 +2808,16 @@ func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Delete == nil {
-				m.Delete = &DeleteResponse{}
+			if m.Put == nil {
+				m.Put = &PutResponse{}
 			}
-			if err := m.Delete.Unmarshal(data[index:postIndex]); err != nil {
+			if err := m.Put.Unmarshal(data[index:postIndex]); err != nil {
 				return err
 			}
 			index = postIndex
-		case 5:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DeleteRange", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ConditionalPut", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2456,10 +2835,91 @@ func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.DeleteRange == nil {
-				m.DeleteRange = &DeleteRangeResponse{}
+			if m.ConditionalPut == nil {
+				m.ConditionalPut = &ConditionalPutResponse{}
 			}
-			if err := m.DeleteRange.Unmarshal(data[index:postIndex]); err != nil {
+			if err := m.ConditionalPut.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Increment", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Increment == nil {
+				m.Increment = &IncrementResponse{}
+			}
+			if err := m.Increment.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Delete", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Delete == nil {
+				m.Delete = &DeleteResponse{}
+			}
+			if err := m.Delete.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DeleteRange", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.DeleteRange == nil {
+				m.DeleteRange = &DeleteRangeResponse{}
+			}
+			if err := m.DeleteRange.Unmarshal(data[index:postIndex]); err != nil {
 				return err
 			}
 			index = postIndex
@@ -2652,6 +3112,33 @@ func (m *ReadWriteCmdResponse) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 16:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InternalIngest", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.InternalIngest == nil {
+				m.InternalIngest = &InternalIngestResponse{}
+			}
+			if err := m.InternalIngest.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -3180,6 +3667,33 @@ func (m *InternalRaftCommandUnion) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 39:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InternalIngest", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.InternalIngest == nil {
+				m.InternalIngest = &InternalIngestRequest{}
+			}
+			if err := m.InternalIngest.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -3261,6 +3775,143 @@ func (m *InternalRaftCommand) Unmarshal(data []byte) error {
 				return err
 			}
 			index = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClosedTimestamp", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.ClosedTimestamp.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Coalesced", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Coalesced = append(m.Coalesced, CoalescedRaftCommand{})
+			if err := m.Coalesced[len(m.Coalesced)-1].Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *CoalescedRaftCommand) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CmdIDKey", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.CmdIDKey = string(data[index:postIndex])
+			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cmd", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Cmd.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
+			index = postIndex
 		default:
 			var sizeOfWire int
 			for {
@@ -3843,29 +4494,310 @@ func (m *RaftSnapshotData_KeyValue) Unmarshal(data []byte) error {
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append([]byte{}, data[index:postIndex]...)
+			index = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append([]byte{}, data[index:postIndex]...)
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *JobRecord) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Type |= (JobType(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Description", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Description = string(data[index:postIndex])
+			index = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.Status |= (JobStatus(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Progress", wireType)
+			}
+			var v uint64
+			i := index + 8
+			if i > l {
+				return io.ErrUnexpectedEOF
+			}
+			index += 8
+			v = uint64(data[i-8])
+			v |= uint64(data[i-7]) << 8
+			v |= uint64(data[i-6]) << 16
+			v |= uint64(data[i-5]) << 24
+			v |= uint64(data[i-4]) << 32
+			v |= uint64(data[i-3]) << 40
+			v |= uint64(data[i-2]) << 48
+			v |= uint64(data[i-1]) << 56
+			m.Progress = math.Float64frombits(v)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreatedNanos", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.CreatedNanos |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModifiedNanos", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ModifiedNanos |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Error", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			postIndex := index + int(stringLen)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Error = string(data[index:postIndex])
+			index = postIndex
+		default:
+			var sizeOfWire int
+			for {
+				sizeOfWire++
+				wire >>= 7
+				if wire == 0 {
+					break
+				}
+			}
+			index -= sizeOfWire
+			skippy, err := github_com_gogo_protobuf_proto.Skip(data[index:])
+			if err != nil {
+				return err
+			}
+			if (index + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, data[index:index+skippy]...)
+			index += skippy
+		}
+	}
+	return nil
+}
+func (m *NamespaceDescriptor) Unmarshal(data []byte) error {
+	l := len(data)
+	index := 0
+	for index < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if index >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := data[index]
+			index++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+			}
+			for shift := uint(0); ; shift += 7 {
+				if index >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[index]
+				index++
+				m.ID |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if index >= l {
 					return io.ErrUnexpectedEOF
 				}
 				b := data[index]
 				index++
-				byteLen |= (int(b) & 0x7F) << shift
+				stringLen |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			postIndex := index + byteLen
+			postIndex := index + int(stringLen)
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Key = append([]byte{}, data[index:postIndex]...)
+			m.Name = string(data[index:postIndex])
 			index = postIndex
-		case 2:
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Prefix", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -3883,7 +4815,9 @@ func (m *RaftSnapshotData_KeyValue) Unmarshal(data []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Value = append([]byte{}, data[index:postIndex]...)
+			if err := m.Prefix.Unmarshal(data[index:postIndex]); err != nil {
+				return err
+			}
 			index = postIndex
 		default:
 			var sizeOfWire int
@@ -3945,6 +4879,9 @@ func (this *ReadWriteCmdResponse) GetValue() interface{} {
 	if this.InternalGc != nil {
 		return this.InternalGc
 	}
+	if this.InternalIngest != nil {
+		return this.InternalIngest
+	}
 	return nil
 }
 
@@ -3974,6 +4911,8 @@ func (this *ReadWriteCmdResponse) SetValue(value interface{}) bool {
 		this.InternalTruncateLog = vt
 	case *InternalGCResponse:
 		this.InternalGc = vt
+	case *InternalIngestResponse:
+		this.InternalIngest = vt
 	default:
 		return false
 	}
@@ -4034,6 +4973,9 @@ func (this *InternalRaftCommandUnion) GetValue() interface{} {
 	if this.InternalLease != nil {
 		return this.InternalLease
 	}
+	if this.InternalIngest != nil {
+		return this.InternalIngest
+	}
 	return nil
 }
 
@@ -4075,6 +5017,8 @@ func (this *InternalRaftCommandUnion) SetValue(value interface{}) bool {
 		this.InternalGC = vt
 	case *InternalLeaderLeaseRequest:
 		this.InternalLease = vt
+	case *InternalIngestRequest:
+		this.InternalIngest = vt
 	default:
 		return false
 	}
@@ -4296,6 +5240,34 @@ func (m *InternalLeaderLeaseResponse) Size() (n int) {
 	return n
 }
 
+func (m *InternalIngestRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = m.RequestHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if len(m.Rows) > 0 {
+		for _, e := range m.Rows {
+			l = e.Size()
+			n += 1 + l + sovInternal(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *InternalIngestResponse) Size() (n int) {
+	var l int
+	_ = l
+	l = m.ResponseHeader.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
 func (m *ReadWriteCmdResponse) Size() (n int) {
 	var l int
 	_ = l
@@ -4347,6 +5319,10 @@ func (m *ReadWriteCmdResponse) Size() (n int) {
 		l = m.InternalGc.Size()
 		n += 1 + l + sovInternal(uint64(l))
 	}
+	if m.InternalIngest != nil {
+		l = m.InternalIngest.Size()
+		n += 2 + l + sovInternal(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4428,6 +5404,10 @@ func (m *InternalRaftCommandUnion) Size() (n int) {
 		l = m.InternalLease.Size()
 		n += 2 + l + sovInternal(uint64(l))
 	}
+	if m.InternalIngest != nil {
+		l = m.InternalIngest.Size()
+		n += 2 + l + sovInternal(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4440,6 +5420,27 @@ func (m *InternalRaftCommand) Size() (n int) {
 	n += 1 + sovInternal(uint64(m.RaftID))
 	l = m.Cmd.Size()
 	n += 1 + l + sovInternal(uint64(l))
+	l = m.ClosedTimestamp.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if len(m.Coalesced) > 0 {
+		for _, e := range m.Coalesced {
+			l = e.Size()
+			n += 1 + l + sovInternal(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *CoalescedRaftCommand) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.CmdIDKey)
+	n += 1 + l + sovInternal(uint64(l))
+	l = m.Cmd.Size()
+	n += 1 + l + sovInternal(uint64(l))
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -4559,6 +5560,39 @@ func (m *RaftSnapshotData_KeyValue) Size() (n int) {
 	return n
 }
 
+func (m *JobRecord) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovInternal(uint64(m.ID))
+	n += 1 + sovInternal(uint64(m.Type))
+	l = len(m.Description)
+	n += 1 + l + sovInternal(uint64(l))
+	n += 1 + sovInternal(uint64(m.Status))
+	n += 9
+	n += 1 + sovInternal(uint64(m.CreatedNanos))
+	n += 1 + sovInternal(uint64(m.ModifiedNanos))
+	l = len(m.Error)
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *NamespaceDescriptor) Size() (n int) {
+	var l int
+	_ = l
+	n += 1 + sovInternal(uint64(m.ID))
+	l = len(m.Name)
+	n += 1 + l + sovInternal(uint64(l))
+	l = m.Prefix.Size()
+	n += 1 + l + sovInternal(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
 func sovInternal(x uint64) (n int) {
 	for {
 		n++
@@ -5153,6 +6187,76 @@ func (m *InternalLeaderLeaseResponse) MarshalTo(data []byte) (n int, err error)
 	return i, nil
 }
 
+func (m *InternalIngestRequest) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalIngestRequest) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.RequestHeader.Size()))
+	n56, err := m.RequestHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n56
+	if len(m.Rows) > 0 {
+		for _, msg := range m.Rows {
+			data[i] = 0x12
+			i++
+			i = encodeVarintInternal(data, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(data[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *InternalIngestResponse) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *InternalIngestResponse) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ResponseHeader.Size()))
+	n57, err := m.ResponseHeader.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n57
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
 func (m *ReadWriteCmdResponse) Marshal() (data []byte, err error) {
 	size := m.Size()
 	data = make([]byte, size)
@@ -5288,6 +6392,18 @@ func (m *ReadWriteCmdResponse) MarshalTo(data []byte) (n int, err error) {
 		}
 		i += n35
 	}
+	if m.InternalIngest != nil {
+		data[i] = 0x82
+		i++
+		data[i] = 0x1
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalIngest.Size()))
+		n58, err := m.InternalIngest.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n58
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5507,6 +6623,18 @@ func (m *InternalRaftCommandUnion) MarshalTo(data []byte) (n int, err error) {
 		}
 		i += n53
 	}
+	if m.InternalIngest != nil {
+		data[i] = 0xba
+		i++
+		data[i] = 0x2
+		i++
+		i = encodeVarintInternal(data, i, uint64(m.InternalIngest.Size()))
+		n59, err := m.InternalIngest.MarshalTo(data[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n59
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5539,6 +6667,59 @@ func (m *InternalRaftCommand) MarshalTo(data []byte) (n int, err error) {
 		return 0, err
 	}
 	i += n54
+	data[i] = 0x22
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ClosedTimestamp.Size()))
+	n55, err := m.ClosedTimestamp.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n55
+	if len(m.Coalesced) > 0 {
+		for _, msg := range m.Coalesced {
+			data[i] = 0x2a
+			i++
+			i = encodeVarintInternal(data, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(data[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *CoalescedRaftCommand) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *CoalescedRaftCommand) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0xa
+	i++
+	i = encodeVarintInternal(data, i, uint64(len(m.CmdIDKey)))
+	i += copy(data[i:], m.CmdIDKey)
+	data[i] = 0x12
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Cmd.Size()))
+	n61, err := m.Cmd.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n61
 	if m.XXX_unrecognized != nil {
 		i += copy(data[i:], m.XXX_unrecognized)
 	}
@@ -5788,6 +6969,89 @@ func (m *RaftSnapshotData_KeyValue) MarshalTo(data []byte) (n int, err error) {
 	return i, nil
 }
 
+func (m *JobRecord) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *JobRecord) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ID))
+	data[i] = 0x10
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Type))
+	data[i] = 0x1a
+	i++
+	i = encodeVarintInternal(data, i, uint64(len(m.Description)))
+	i += copy(data[i:], m.Description)
+	data[i] = 0x20
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Status))
+	data[i] = 0x29
+	i++
+	i = encodeFixed64Internal(data, i, uint64(math.Float64bits(m.Progress)))
+	data[i] = 0x30
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.CreatedNanos))
+	data[i] = 0x38
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ModifiedNanos))
+	data[i] = 0x42
+	i++
+	i = encodeVarintInternal(data, i, uint64(len(m.Error)))
+	i += copy(data[i:], m.Error)
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
+func (m *NamespaceDescriptor) Marshal() (data []byte, err error) {
+	size := m.Size()
+	data = make([]byte, size)
+	n, err := m.MarshalTo(data)
+	if err != nil {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+func (m *NamespaceDescriptor) MarshalTo(data []byte) (n int, err error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	data[i] = 0x8
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.ID))
+	data[i] = 0x12
+	i++
+	i = encodeVarintInternal(data, i, uint64(len(m.Name)))
+	i += copy(data[i:], m.Name)
+	data[i] = 0x1a
+	i++
+	i = encodeVarintInternal(data, i, uint64(m.Prefix.Size()))
+	n60, err := m.Prefix.MarshalTo(data[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n60
+	if m.XXX_unrecognized != nil {
+		i += copy(data[i:], m.XXX_unrecognized)
+	}
+	return i, nil
+}
+
 func encodeFixed64Internal(data []byte, offset int, v uint64) int {
 	data[offset] = uint8(v)
 	data[offset+1] = uint8(v >> 8)
@@ -105,6 +105,9 @@ const (
 	InternalTruncateLog
 	// InternalLeaderLease requests a leader lease for a replica.
 	InternalLeaderLease
+	// InternalIngest writes rows to an empty span of keys, linking them
+	// into the stores of the range's replicas as sstables.
+	InternalIngest
 )
 
 // AllMethods is a map from string to method enum.
@@ -129,4 +132,5 @@ var AllMethods = map[string]Method{
 	InternalMerge.String():         InternalMerge,
 	InternalTruncateLog.String():   InternalTruncateLog,
 	InternalLeaderLease.String():   InternalLeaderLease,
+	InternalIngest.String():        InternalIngest,
 }
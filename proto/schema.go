@@ -0,0 +1,46 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package proto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"sync"
+)
+
+var fileDescriptorSet struct {
+	sync.Once
+	b   []byte
+	err error
+}
+
+// FileDescriptorSet returns the serialized google.protobuf.FileDescriptorSet
+// describing the protocol buffer definitions in this package and their
+// dependencies. Clients written in other languages can use it to decode
+// stored values and RPC messages without a copy of the .proto files.
+// The descriptor set is regenerated along with the Go code by "make".
+func FileDescriptorSet() ([]byte, error) {
+	fileDescriptorSet.Do(func() {
+		var gz *gzip.Reader
+		if gz, fileDescriptorSet.err = gzip.NewReader(bytes.NewReader(fileDescriptorSetGzipped)); fileDescriptorSet.err != nil {
+			return
+		}
+		defer gz.Close()
+		fileDescriptorSet.b, fileDescriptorSet.err = ioutil.ReadAll(gz)
+	})
+	return fileDescriptorSet.b, fileDescriptorSet.err
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package proto
+
+import (
+	"testing"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+	descriptor "github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+)
+
+// TestFileDescriptorSet verifies that the embedded descriptor set
+// decodes and describes the messages of this package.
+func TestFileDescriptorSet(t *testing.T) {
+	b, err := FileDescriptorSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := &descriptor.FileDescriptorSet{}
+	if err := gogoproto.Unmarshal(b, set); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*descriptor.FileDescriptorProto{}
+	for _, f := range set.File {
+		files[f.GetName()] = f
+	}
+	for _, name := range []string{"cockroach/proto/api.proto", "gogoproto/gogo.proto", "google/protobuf/descriptor.proto"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected descriptor for %s", name)
+		}
+	}
+	api, ok := files["cockroach/proto/api.proto"]
+	if !ok {
+		return
+	}
+	for _, m := range api.MessageType {
+		if m.GetName() == "ScanRequest" {
+			return
+		}
+	}
+	t.Error("expected descriptor for message ScanRequest")
+}
@@ -127,7 +127,7 @@ func (c *Client) connect(opts *util.RetryOptions, context *Context) {
 	retryOpts.Stopper = context.stopper
 
 	err := util.RetryWithBackoff(retryOpts, func() (util.RetryStatus, error) {
-		conn, err := tlsDialHTTP(c.addr.Network(), c.addr.String(), context.tlsConfig)
+		conn, err := tlsDialHTTP(c.addr.Network(), c.addr.String(), context.tlsConfig, context.Dial)
 		if err != nil {
 			log.Info(err)
 			return util.RetryContinue, nil
@@ -18,7 +18,9 @@
 package rpc
 
 import (
+	"net"
 	"net/rpc"
+	"sync"
 	"testing"
 	"time"
 
@@ -69,6 +71,35 @@ func TestClientNoCache(t *testing.T) {
 	s.Close()
 }
 
+// TestClientDial verifies that clients connect using the context's
+// dial function if one is supplied.
+func TestClientDial(t *testing.T) {
+	rpcContext := NewTestContext(t)
+	rpcContext.DisableCache = true
+	var dialed []string
+	var mu sync.Mutex
+	rpcContext.Dial = func(network, address string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, address)
+		mu.Unlock()
+		return net.Dial(network, address)
+	}
+	addr := util.CreateTestAddr("tcp")
+
+	s := NewServer(addr, rpcContext)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := NewClient(s.Addr(), nil, rpcContext)
+	<-c.Ready
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dialed) != 1 || dialed[0] != s.Addr().String() {
+		t.Errorf("expected a single dial of %s; got %v", s.Addr(), dialed)
+	}
+}
+
 // TestClientHeartbeatBadServer verifies that the client is not marked
 // as "ready" until a heartbeat request succeeds.
 func TestClientHeartbeatBadServer(t *testing.T) {
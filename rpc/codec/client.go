@@ -145,7 +145,7 @@ func (c *clientCodec) writeRequest(r *rpc.Request, request proto.Message) error
 
 	// send body (end)
 	if enableSnappy {
-		return snappyEncode(pbRequest, c.sendFrame)
+		return snappyEncode(pbRequest, &c.snappyBuf, c.sendFrame)
 	}
 	return c.sendFrame(pbRequest)
 }
@@ -41,10 +41,11 @@ var decompressors = [...]decompressFunc{
 }
 
 type baseConn struct {
-	w        *bufio.Writer
-	r        *bufio.Reader
-	c        io.Closer
-	frameBuf [binary.MaxVarintLen64]byte
+	w         *bufio.Writer
+	r         *bufio.Reader
+	c         io.Closer
+	frameBuf  [binary.MaxVarintLen64]byte
+	snappyBuf []byte // Reused by snappyEncode
 }
 
 // Close closes the underlying connection.
@@ -113,12 +113,23 @@ func (c *serverCodec) WriteResponse(r *rpc.Response, x interface{}) error {
 		}
 	}
 
-	if err := c.writeResponse(r, response); err != nil {
+	err := c.writeResponse(r, response)
+	// Once a response is encoded, the buffers it holds may be reused.
+	if rel, ok := response.(releaser); ok {
+		rel.Release()
+	}
+	if err != nil {
 		return err
 	}
 	return c.w.Flush()
 }
 
+// A releaser is a response whose buffers may be reused once it has
+// been encoded, such as a proto.ScanResponse.
+type releaser interface {
+	Release()
+}
+
 func (c *serverCodec) writeResponse(r *rpc.Response, response proto.Message) error {
 	// clear response if error
 	if r.Error != "" {
@@ -159,7 +170,7 @@ func (c *serverCodec) writeResponse(r *rpc.Response, response proto.Message) err
 
 	// send body (end)
 	if enableSnappy {
-		return snappyEncode(pbResponse, c.sendFrame)
+		return snappyEncode(pbResponse, &c.snappyBuf, c.sendFrame)
 	}
 	return c.sendFrame(pbResponse)
 }
@@ -34,19 +34,6 @@ import (
 // #include <stdlib.h>
 // #include <snappy-c.h>
 //
-// snappy_status snappy_encode(const char* input,
-//                             size_t input_length,
-//                             void** compressed,
-//                             size_t* compressed_length) {
-//   *compressed_length = snappy_max_compressed_length(input_length);
-//   *compressed = malloc(*compressed_length);
-//   snappy_status status = snappy_compress(input, input_length, *compressed, compressed_length);
-//   if (status != SNAPPY_OK) {
-//     free(*compressed);
-//   }
-//   return status;
-// }
-//
 // snappy_status snappy_decode(const char* compressed,
 //                             size_t compressed_length,
 //                             void** uncompressed,
@@ -87,24 +74,27 @@ var (
 )
 
 // snappyEncode compresses the byte array src and sends the compressed
-// data to w.
-func snappyEncode(src []byte, w func([]byte) error) error {
+// data to w. The data is compressed into buf, which is grown if
+// necessary and reused by later calls, sparing a connection an
+// allocation per message.
+func snappyEncode(src []byte, buf *[]byte, w func([]byte) error) error {
 	if len(src) == 0 {
 		return w([]byte(nil))
 	}
 
-	var dLen C.size_t
-	var dst unsafe.Pointer
+	maxLen := int(C.snappy_max_compressed_length(C.size_t(len(src))))
+	if cap(*buf) < maxLen {
+		*buf = make([]byte, maxLen)
+	}
+	dst := (*buf)[:maxLen]
+	dLen := C.size_t(maxLen)
 
-	cerr := C.snappy_encode((*C.char)(unsafe.Pointer(&src[0])), C.size_t(len(src)),
-		&dst, &dLen)
+	cerr := C.snappy_compress((*C.char)(unsafe.Pointer(&src[0])), C.size_t(len(src)),
+		(*C.char)(unsafe.Pointer(&dst[0])), &dLen)
 	if cerr != C.SNAPPY_OK {
 		return snappyError(cerr)
 	}
-
-	err := w(unsafeSlice(dst, dLen))
-	C.free(dst)
-	return err
+	return w(dst[:dLen])
 }
 
 // snappyDecode uncompresses the byte array src and unmarshals the
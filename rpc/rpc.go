@@ -1,6 +1,8 @@
 package rpc
 
 import (
+	"net"
+
 	"github.com/cockroachdb/cockroach/security"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
@@ -13,6 +15,11 @@ type Context struct {
 	stopper      *util.Stopper
 	RemoteClocks *RemoteClockMonitor
 	DisableCache bool // Disable client cache when calling NewClient()
+	// Dial, if not nil, is used in place of net.Dial to connect to
+	// servers, allowing tests and deployments with split-horizon DNS to
+	// control how addresses are reached. TLS is negotiated over the
+	// returned connection.
+	Dial func(network, address string) (net.Conn, error)
 }
 
 // NewContext creates an rpc Context with the supplied values.
@@ -34,5 +41,6 @@ func (c *Context) Copy() *Context {
 		stopper:      c.stopper,
 		RemoteClocks: newRemoteClockMonitor(c.localClock),
 		DisableCache: c.DisableCache,
+		Dial:         c.Dial,
 	}
 }
@@ -47,21 +47,49 @@ func tlsListen(network, address string, config *security.TLSConfig) (net.Listene
 }
 
 // tlsDial wraps either net.Dial or crypto/tls.Dial, depending on the contents of
-// the passed TLSConfig.
-func tlsDial(network, address string, config *security.TLSConfig) (net.Conn, error) {
+// the passed TLSConfig. If dial is not nil, it's used to connect in place of
+// net.Dial, and TLS is negotiated over the connection it returns.
+func tlsDial(network, address string, config *security.TLSConfig,
+	dial func(network, address string) (net.Conn, error)) (net.Conn, error) {
 	cfg := config.Config()
 	if cfg == nil {
 		if network != "unix" {
 			log.Warningf("connecting via %s to %s without TLS", network, address)
 		}
+		if dial != nil {
+			return dial(network, address)
+		}
 		return net.Dial(network, address)
 	}
-	return tls.Dial(network, address, cfg)
+	if dial == nil {
+		return tls.Dial(network, address, cfg)
+	}
+	conn, err := dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	// As tls.Dial, verify the server's certificate against the host
+	// being dialed unless a server name is configured. The config is a
+	// copy, so it may be modified.
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		cfg.ServerName = host
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
 }
 
 // tlsDialHTTP connects to an HTTP RPC server at the specified address.
-func tlsDialHTTP(network, address string, config *security.TLSConfig) (net.Conn, error) {
-	conn, err := tlsDial(network, address, config)
+func tlsDialHTTP(network, address string, config *security.TLSConfig,
+	dial func(network, address string) (net.Conn, error)) (net.Conn, error) {
+	conn, err := tlsDial(network, address, config, dial)
 	if err != nil {
 		return conn, err
 	}
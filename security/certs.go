@@ -115,28 +115,61 @@ func RunCreateNodeCert(certsDir string, hosts []string) error {
 		return util.Errorf("no hosts specified. Need at least one")
 	}
 
-	// Load the CA certificate.
+	caCert, caKey, err := loadCACertAndKey(certsDir)
+	if err != nil {
+		return err
+	}
+
+	// Generate certificate.
+	certificate, key, err := GenerateNodeCert(caCert, caKey, hosts)
+	if err != nil {
+		return util.Errorf("error creating node certificate and key: %s", err)
+	}
+
+	err = writeCertificateAndKey(certsDir, "node", certificate, key)
+	return err
+}
+
+// RunCreateClientCert is the entry-point from the command-line
+// interface to generate a client cert and key authenticating user.
+func RunCreateClientCert(certsDir string, user string) error {
+	if certsDir == "" {
+		return util.Errorf("no certs directory specified, use -certs")
+	}
+	if user == "" {
+		return util.Errorf("no user specified")
+	}
+
+	caCert, caKey, err := loadCACertAndKey(certsDir)
+	if err != nil {
+		return err
+	}
+
+	// Generate certificate.
+	certificate, key, err := GenerateClientCert(caCert, caKey, user)
+	if err != nil {
+		return util.Errorf("error creating client certificate and key: %s", err)
+	}
+
+	err = writeCertificateAndKey(certsDir, "client", certificate, key)
+	return err
+}
+
+// loadCACertAndKey loads the CA certificate and key from certsDir.
+func loadCACertAndKey(certsDir string) (*x509.Certificate, crypto.PrivateKey, error) {
 	caCertPath := path.Join(certsDir, "ca.crt")
 	caKeyPath := path.Join(certsDir, "ca.key")
 	// LoadX509KeyPair does a bunch of validation, including len(Certificates) != 0.
 	caCert, err := tls.LoadX509KeyPair(caCertPath, caKeyPath)
 	if err != nil {
-		return util.Errorf("error loading CA certificate %s and key %s: %s",
+		return nil, nil, util.Errorf("error loading CA certificate %s and key %s: %s",
 			caCertPath, caKeyPath, err)
 	}
 
 	// Extract x509 certificate from tls cert.
 	x509Cert, err := x509.ParseCertificate(caCert.Certificate[0])
 	if err != nil {
-		return util.Errorf("error parsing CA certificate %s: %s", caCertPath, err)
+		return nil, nil, util.Errorf("error parsing CA certificate %s: %s", caCertPath, err)
 	}
-
-	// Generate certificate.
-	certificate, key, err := GenerateNodeCert(x509Cert, caCert.PrivateKey, hosts)
-	if err != nil {
-		return util.Errorf("error creating node certificate and key: %s", err)
-	}
-
-	err = writeCertificateAndKey(certsDir, "node", certificate, key)
-	return err
+	return x509Cert, caCert.PrivateKey, nil
 }
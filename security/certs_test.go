@@ -58,6 +58,16 @@ func TestGenerateCerts(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Expected success, got %v", err)
 	}
+
+	// Client certs need a user.
+	err = security.RunCreateClientCert(certsDir, "")
+	if err == nil {
+		t.Fatalf("Expected error, but got none")
+	}
+	err = security.RunCreateClientCert(certsDir, "alice")
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
 }
 
 // This is a fairly high-level test of CA and node certificates.
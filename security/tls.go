@@ -145,7 +145,9 @@ func LoadInsecureTLSConfig() *TLSConfig {
 }
 
 // LoadClientTLSConfigFromDir creates a client TLSConfig by loading the root CA certs from the
-// specified directory. The directory must contain ca.crt.
+// specified directory. The directory must contain ca.crt. The client
+// authenticates with the certificate in client.crt and client.key if
+// present, or else with the node certificate in node.crt and node.key.
 func LoadClientTLSConfigFromDir(certDir string) (*TLSConfig, error) {
 	if strings.HasPrefix(certDir, EmbeddedPrefix) {
 		return LoadTestClientTLSConfig(certDir[len(EmbeddedPrefix):])
@@ -154,17 +156,65 @@ func LoadClientTLSConfigFromDir(certDir string) (*TLSConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	return LoadClientTLSConfig(caPEM)
+	cfg, err := LoadClientTLSConfig(caPEM)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range []string{"client", "node"} {
+		certPEM, err := ioutil.ReadFile(path.Join(certDir, prefix+".crt"))
+		if err != nil {
+			continue
+		}
+		keyPEM, err := ioutil.ReadFile(path.Join(certDir, prefix+".key"))
+		if err != nil {
+			continue
+		}
+		if err := cfg.setCertificate(certPEM, keyPEM); err != nil {
+			return nil, err
+		}
+		break
+	}
+	return cfg, nil
 }
 
-// LoadTestClientTLSConfig loads the embedded certs. This is only called from
+// LoadTestClientTLSConfig loads the embedded certs, authenticating with
+// the embedded node certificate. This is only called from
 // LoadClientTLSConfigFromDir when the certdir path starts with "embedded=".
 func LoadTestClientTLSConfig(certDir string) (*TLSConfig, error) {
 	caPEM, err := securitytest.Asset(path.Join(certDir, "ca.crt"))
 	if err != nil {
 		return nil, err
 	}
-	return LoadClientTLSConfig(caPEM)
+	certPEM, err := securitytest.Asset(path.Join(certDir, "node.crt"))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := securitytest.Asset(path.Join(certDir, "node.key"))
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadClientTLSConfig(caPEM)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.setCertificate(certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// setCertificate sets the certificate with which a client
+// authenticates from the supplied byte strings containing the
+// certificate and its private key.
+func (c *TLSConfig) setCertificate(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.config.Certificates = []tls.Certificate{cert}
+	return nil
 }
 
 // LoadClientTLSConfig creates a client TLSConfig from the supplied byte strings containing
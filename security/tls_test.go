@@ -57,6 +57,22 @@ func TestLoadTLSConfig(t *testing.T) {
 	}
 }
 
+// TestLoadClientTLSConfig verifies that clients authenticate with the
+// embedded node certificate.
+func TestLoadClientTLSConfig(t *testing.T) {
+	wrapperConfig, err := LoadClientTLSConfigFromDir(EmbeddedPrefix + "test_certs")
+	if err != nil {
+		t.Fatalf("Failed to load client TLS config: %v", err)
+	}
+	config := wrapperConfig.Config()
+	if len(config.Certificates) != 1 {
+		t.Fatalf("config.Certificates should have 1 cert; found %d", len(config.Certificates))
+	}
+	if _, err := x509.ParseCertificate(config.Certificates[0].Certificate[0]); err != nil {
+		t.Fatalf("Couldn't parse test cert: %v", err)
+	}
+}
+
 func verifyX509Cert(cert *x509.Certificate, dnsName string, roots *x509.CertPool) error {
 	verifyOptions := x509.VerifyOptions{
 		DNSName: dnsName,
@@ -162,3 +162,32 @@ func GenerateNodeCert(caCert *x509.Certificate, caKey crypto.PrivateKey, hosts [
 
 	return certBytes, privateKey, nil
 }
+
+// GenerateClientCert generates a client certificate authenticating
+// user, its common name, and returns the cert bytes as well as the
+// private key used to generate the certificate.
+// The CA cert and private key should be passed in.
+func GenerateClientCert(caCert *x509.Certificate, caKey crypto.PrivateKey, user string) (
+	[]byte, crypto.PrivateKey, error) {
+	privateKey, publicKey, err := generateKeyPair()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Set client-specific fields.
+	// Clients need SSL for client authentication only.
+	template.Subject.CommonName = user
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, caCert, publicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certBytes, privateKey, nil
+}
@@ -29,10 +29,14 @@ import (
 	// endpoints with the http.DefaultServeMux.
 	_ "net/http/pprof"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+	descriptor "github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
 )
 
 const (
@@ -50,12 +54,57 @@ const (
 	healthPath = adminEndpoint + "health"
 	// quitPath is the quit endpoint.
 	quitPath = adminEndpoint + "quit"
+	// drainPath is the endpoint for starting the node's drain and
+	// querying its progress.
+	drainPath = adminEndpoint + "drain"
+	// standbyPath is the endpoint for querying the read-only mode of a
+	// standby node and promoting its cluster.
+	standbyPath = adminEndpoint + "standby"
+	// readOnlyPath is the endpoint for querying and setting the
+	// node's read-only mode.
+	readOnlyPath = adminEndpoint + "readonly"
+	// balancePath is the endpoint for querying and setting the
+	// cluster's balance thresholds.
+	balancePath = adminEndpoint + "balance"
 	// acctPathPrefix is the prefix for accounting configuration changes.
 	acctPathPrefix = adminEndpoint + "acct"
 	// permPathPrefix is the prefix for permission configuration changes.
 	permPathPrefix = adminEndpoint + "perms"
 	// zonePathPrefix is the prefix for zone configuration changes.
 	zonePathPrefix = adminEndpoint + "zones"
+	// zonePlanPathPrefix is the prefix for planning zone configuration
+	// changes without making them.
+	zonePlanPathPrefix = adminEndpoint + "zoneplan"
+	// jobPathPrefix is the prefix for querying and controlling jobs.
+	jobPathPrefix = adminEndpoint + "jobs"
+	// namespacePathPrefix is the prefix for creating, renaming and
+	// dropping namespaces.
+	namespacePathPrefix = adminEndpoint + "namespaces"
+	// reloadPath is the endpoint for querying and reloading the node's
+	// reloadable settings.
+	reloadPath = adminEndpoint + "reload"
+	// schemaPath serves the protocol buffer descriptors.
+	schemaPath = adminEndpoint + "schema"
+	// configPath serves the node's effective configuration.
+	configPath = adminEndpoint + "config"
+	// compactPath is the endpoint for compacting the engines of the
+	// node's stores.
+	compactPath = adminEndpoint + "compact"
+	// compactParamStore, compactParamStart and compactParamEnd are the
+	// query parameters selecting the store, by ID, and the first and
+	// last keys to compact. All stores and keys are compacted if
+	// they're not given.
+	compactParamStore = "store"
+	compactParamStart = "start"
+	compactParamEnd   = "end"
+	// checkpointPath is the endpoint for checkpointing the engine of one
+	// of the node's stores into a directory or streaming it.
+	checkpointPath = adminEndpoint + "checkpoint"
+	// checkpointParamStore and checkpointParamDir are the query
+	// parameters selecting the store, by ID, and the directory of the
+	// node into which it's checkpointed.
+	checkpointParamStore = "store"
+	checkpointParamDir   = "dir"
 )
 
 // An actionHandler is an interface which provides Get, Put & Delete
@@ -71,51 +120,229 @@ type actionHandler interface {
 type adminServer struct {
 	db      *client.KV    // Key-value database client
 	stopper *util.Stopper // Used to shutdown the server
+	node    *Node         // The node whose read-only mode is controlled
+	drainer *drainer      // Drains the node before it's shut down
+	standby *standbyGate  // Gates the node's endpoints if it's a standby; may be nil
 	acct    *acctHandler
 	perm    *permHandler
 	zone    *zoneHandler
+	job     *jobHandler
+	ns      *namespaceHandler
+
+	// ctx is the context the node was started with.
+	ctx *Context
+	// reloadable holds the node's reloadable settings.
+	reloadable *ReloadableContext
+
+	// insecure is set if the node serves without TLS, in which case
+	// requests can't be authenticated and are made as the root user.
+	insecure bool
 }
 
 // newAdminServer allocates and returns a new REST server for
 // administrative APIs.
-func newAdminServer(db *client.KV, stopper *util.Stopper) *adminServer {
+func newAdminServer(db *client.KV, stopper *util.Stopper, jobs *JobCoordinator, namespaces *NamespaceManager, node *Node,
+	drainer *drainer, standby *standbyGate, ctx *Context, reloadable *ReloadableContext, insecure bool) *adminServer {
 	return &adminServer{
-		db:      db,
-		stopper: stopper,
-		acct:    &acctHandler{db: db},
-		perm:    &permHandler{db: db},
-		zone:    &zoneHandler{db: db},
+		db:         db,
+		stopper:    stopper,
+		node:       node,
+		drainer:    drainer,
+		standby:    standby,
+		ctx:        ctx,
+		reloadable: reloadable,
+		insecure:   insecure,
+		acct:       &acctHandler{db: db},
+		perm:       &permHandler{db: db},
+		zone:       &zoneHandler{db: db},
+		job:        &jobHandler{coord: jobs},
+		ns:         &namespaceHandler{nm: namespaces},
 	}
 }
 
 // registerHandlers registers admin handlers with the supplied
-// serve mux.
+// serve mux. All but the health endpoint require an authenticated
+// user; see authenticated.
 func (s *adminServer) registerHandlers(mux *http.ServeMux) {
 	// Pass through requests to /debug to the default serve mux so we
 	// get exported variables and pprof tools.
-	mux.HandleFunc(acctPathPrefix, s.handleAcctAction)
-	mux.HandleFunc(acctPathPrefix+"/", s.handleAcctAction)
-	mux.HandleFunc(debugEndpoint, s.handleDebug)
+	mux.HandleFunc(acctPathPrefix, s.authenticated(accessByMethod, s.handleAcctAction))
+	mux.HandleFunc(acctPathPrefix+"/", s.authenticated(accessByMethod, s.handleAcctAction))
+	mux.HandleFunc(balancePath, s.authenticated(accessByMethod, s.handleBalance))
+	mux.HandleFunc(checkpointPath, s.authenticated(accessWrite, s.handleCheckpoint))
+	mux.HandleFunc(compactPath, s.authenticated(accessByMethod, s.handleCompact))
+	mux.HandleFunc(configPath, s.authenticated(accessByMethod, s.handleConfig))
+	mux.HandleFunc(debugEndpoint, s.authenticated(accessByMethod, s.handleDebug))
+	mux.HandleFunc(drainPath, s.authenticated(accessByMethod, s.handleDrain))
 	mux.HandleFunc(healthPath, s.handleHealth)
-	mux.HandleFunc(quitPath, s.handleQuit)
-	mux.HandleFunc(permPathPrefix, s.handlePermAction)
-	mux.HandleFunc(permPathPrefix+"/", s.handlePermAction)
-	mux.HandleFunc(zonePathPrefix, s.handleZoneAction)
-	mux.HandleFunc(zonePathPrefix+"/", s.handleZoneAction)
+	mux.HandleFunc(jobPathPrefix, s.authenticated(accessByMethod, s.handleJobAction))
+	mux.HandleFunc(jobPathPrefix+"/", s.authenticated(accessByMethod, s.handleJobAction))
+	mux.HandleFunc(namespacePathPrefix, s.authenticated(accessByMethod, s.handleNamespaceAction))
+	mux.HandleFunc(namespacePathPrefix+"/", s.authenticated(accessByMethod, s.handleNamespaceAction))
+	mux.HandleFunc(quitPath, s.authenticated(accessWrite, s.handleQuit))
+	mux.HandleFunc(permPathPrefix, s.authenticated(accessByMethod, s.handlePermAction))
+	mux.HandleFunc(readOnlyPath, s.authenticated(accessByMethod, s.handleReadOnly))
+	mux.HandleFunc(reloadPath, s.authenticated(accessByMethod, s.handleReload))
+	mux.HandleFunc(schemaPath, s.authenticated(accessByMethod, s.handleSchema))
+	mux.HandleFunc(standbyPath, s.authenticated(accessByMethod, s.handleStandby))
+	mux.HandleFunc(permPathPrefix+"/", s.authenticated(accessByMethod, s.handlePermAction))
+	mux.HandleFunc(usagePath, s.authenticated(accessByMethod, s.handleUsage))
+	mux.HandleFunc(zonePathPrefix, s.authenticated(accessByMethod, s.handleZoneAction))
+	mux.HandleFunc(zonePathPrefix+"/", s.authenticated(accessByMethod, s.handleZoneAction))
+	mux.HandleFunc(zonePlanPathPrefix+"/", s.authenticated(accessByMethod, s.handleZonePlan))
 }
 
 // handleHealth responds to health requests from monitoring services.
+// Until the node has started, it responds with status 503 and the
+// progress of its stores in recovering their state. Once the node is
+// draining, it responds with status 503 and the drain's phase.
 func (s *adminServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
+	if s.drainer != nil && s.drainer.draining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "draining: %s\n", s.drainer.getStatus().Phase)
+		return
+	}
+	if s.node != nil {
+		if p := s.node.StartupProgress(); !p.Started {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, p)
+			return
+		}
+	}
 	fmt.Fprintln(w, "ok")
 }
 
-// handleQuit is the shutdown hook. The server is first placed into a
-// draining mode, followed by exit.
+// handleQuit is the shutdown hook. The server is first drained,
+// followed by exit.
 func (s *adminServer) handleQuit(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	fmt.Fprintln(w, "ok")
-	go s.stopper.Stop()
+	go func() {
+		if s.drainer != nil {
+			<-s.drainer.start()
+		}
+		s.stopper.Stop()
+	}()
+}
+
+// handleDrain responds to GET requests with the progress of the node's
+// drain, as a DrainStatus. POST requests start the drain, if it hasn't
+// been started yet, and respond with its progress; the node keeps
+// running once drained, so that orchestration tools can poll until
+// the phase is "done" before stopping it.
+func (s *adminServer) handleDrain(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "POST":
+		s.drainer.start()
+	default:
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	body, contentType, err := util.MarshalResponse(r, s.drainer.getStatus(), []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// handleStandby responds to GET requests with the read-only mode of a
+// standby node, as a StandbyStatus. POST requests promote its cluster,
+// which then takes over from its primary; see standbyGate.Promote.
+func (s *adminServer) handleStandby(w http.ResponseWriter, r *http.Request) {
+	if s.standby == nil {
+		http.Error(w, "node was not started with -standby", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case "GET":
+	case "POST":
+		if err := s.standby.Promote(); err != nil {
+			http.Error(w, "unable to promote standby: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	body, contentType, err := util.MarshalResponse(r, s.standby.getStatus(), []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// handleReadOnly responds to GET requests with whether the node is in
+// read-only mode, as "true" or "false". PUT and POST requests set the
+// mode to the boolean held in the body. While read-only, the node's
+// stores reject writes and don't acquire leader leases but continue
+// to serve reads and to replicate; see storage.Store.SetReadOnly.
+func (s *adminServer) handleReadOnly(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "PUT", "POST":
+		b, err := ioutil.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		readOnly, err := strconv.ParseBool(strings.TrimSpace(string(b)))
+		if err != nil {
+			http.Error(w, "error parsing read-only mode: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.node.SetReadOnly(readOnly)
+	default:
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, s.node.ReadOnly())
+}
+
+// handleReload responds to GET requests with the node's reloadable
+// settings, one "key: value" line per setting. POST requests first
+// reload the settings from the node's config file, as a SIGHUP does;
+// see ReloadableContext.
+func (s *adminServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "POST":
+		if err := s.reloadable.Reload(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, s.reloadable.Settings())
+}
+
+// handleConfig responds with the node's effective configuration as
+// JSON: the fields of the context it was started with, defaults
+// included and secrets redacted, its current reloadable settings, and
+// the engines and gossip bootstrap resolvers parsed from the context.
+func (s *adminServer) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	body, contentType, err := util.MarshalResponse(r, effectiveConfig(s.ctx, s.reloadable.Settings()),
+		[]util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
 }
 
 // handleDebug passes requests with the debugPathPrefix onto the default
@@ -126,6 +353,32 @@ func (s *adminServer) handleDebug(w http.ResponseWriter, r *http.Request) {
 	handler.ServeHTTP(w, r)
 }
 
+// handleSchema responds with the descriptors of all protocol buffer
+// messages used by the cluster, as a google.protobuf.FileDescriptorSet.
+// Clients in languages other than Go can use them to decode stored
+// values and RPC messages dynamically. The set is encoded according to
+// the request's Accept header; request "application/x-protobuf" for
+// the form expected by protobuf libraries.
+func (s *adminServer) handleSchema(w http.ResponseWriter, r *http.Request) {
+	b, err := proto.FileDescriptorSet()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	set := &descriptor.FileDescriptorSet{}
+	if err := gogoproto.Unmarshal(b, set); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, contentType, err := util.MarshalResponse(r, set, util.AllEncodings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
 // handleAcctAction handles actions for accounting configuration by method.
 func (s *adminServer) handleAcctAction(w http.ResponseWriter, r *http.Request) {
 	s.handleRESTAction(s.acct, w, r, acctPathPrefix)
@@ -141,6 +394,16 @@ func (s *adminServer) handleZoneAction(w http.ResponseWriter, r *http.Request) {
 	s.handleRESTAction(s.zone, w, r, zonePathPrefix)
 }
 
+// handleJobAction handles actions for jobs by method.
+func (s *adminServer) handleJobAction(w http.ResponseWriter, r *http.Request) {
+	s.handleRESTAction(s.job, w, r, jobPathPrefix)
+}
+
+// handleNamespaceAction handles actions for namespaces by method.
+func (s *adminServer) handleNamespaceAction(w http.ResponseWriter, r *http.Request) {
+	s.handleRESTAction(s.ns, w, r, namespacePathPrefix)
+}
+
 // handleRESTAction handles RESTful admin actions.
 func (s *adminServer) handleRESTAction(handler actionHandler, w http.ResponseWriter, r *http.Request, prefix string) {
 	switch r.Method {
@@ -0,0 +1,141 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// An adminAccess is the permission an admin request requires of its
+// user.
+type adminAccess int
+
+const (
+	// accessByMethod requires read permission of GET and HEAD requests
+	// and write permission of all others.
+	accessByMethod adminAccess = iota
+	// accessWrite requires write permission of all requests.
+	accessWrite
+)
+
+// requiresWrite returns whether the request requires write permission.
+func (a adminAccess) requiresWrite(r *http.Request) bool {
+	return a == accessWrite || (r.Method != "GET" && r.Method != "HEAD")
+}
+
+// authenticated wraps an admin handler, which is only invoked for
+// requests authenticated by a client certificate whose user has the
+// permission required by access. Users are granted admin permissions
+// by the default permission config: users which may read it may use
+// the admin endpoints to read and users which may write it may use
+// them to make changes. The root user may always proceed, as may
+// every request to an insecure node. Every request, allowed or not,
+// is recorded in the audit log.
+func (s *adminServer) authenticated(access adminAccess, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := storage.UserRoot
+		if !s.insecure {
+			var err error
+			if user, err = authenticatedUser(r); err != nil {
+				auditAdminRequest(r, "", http.StatusUnauthorized)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		if err := s.authorize(user, access.requiresWrite(r)); err != nil {
+			auditAdminRequest(r, user, http.StatusForbidden)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(sw, r)
+		auditAdminRequest(r, user, sw.status)
+	}
+}
+
+// authenticatedUser returns the user authenticated by the verified
+// client certificate of the request: the certificate's common name, or
+// the root user for node certificates, which have none.
+func authenticatedUser(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", util.Errorf("admin requests require a client certificate signed by the cluster CA; " +
+			"create one with \"cockroach create-client-cert\"")
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName, nil
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageServerAuth {
+			return storage.UserRoot, nil
+		}
+	}
+	return "", util.Errorf("client certificate doesn't name a user")
+}
+
+// authorize returns an error unless the default permission config
+// allows user to read or write, as specified.
+func (s *adminServer) authorize(user string, write bool) error {
+	if user == storage.UserRoot {
+		return nil
+	}
+	call := client.GetCall(engine.MakeKey(engine.KeyConfigPermissionPrefix, engine.KeyMin))
+	call.Args.Header().User = storage.UserRoot
+	if err := s.db.Run(call); err != nil {
+		return util.Errorf("unable to fetch default permission config: %s", err)
+	}
+	perm := &proto.PermConfig{}
+	if reply := call.Reply.(*proto.GetResponse); reply.Value != nil {
+		if err := gogoproto.Unmarshal(reply.Value.Bytes, perm); err != nil {
+			return util.Errorf("unable to decode default permission config: %s", err)
+		}
+	}
+	if write && !perm.CanWrite(user) {
+		return util.Errorf("user %q may not make admin changes", user)
+	}
+	if !write && !perm.CanRead(user) {
+		return util.Errorf("user %q may not read admin endpoints", user)
+	}
+	return nil
+}
+
+// auditAdminRequest records an admin request in the audit log with
+// its authenticated user, if any, and response status.
+func auditAdminRequest(r *http.Request, user string, status int) {
+	log.Infof("admin audit: user=%q remote=%s method=%s path=%q status=%d",
+		user, r.RemoteAddr, r.Method, r.URL.Path, status)
+}
+
+// A statusResponseWriter records the status code of a response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *statusResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/security/securitytest"
+)
+
+// createTestUserHTTPClient returns an http client authenticating user
+// with a client certificate signed by the test CA.
+func createTestUserHTTPClient(t *testing.T, user string) *http.Client {
+	caPEM, err := securitytest.Asset("test_certs/ca.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKeyPEM, err := securitytest.Asset("test_certs/ca.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(caPEM)
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode(caKeyPEM)
+	caKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := security.GenerateClientCert(caCert, caKey, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig := security.LoadInsecureClientTLSConfig().Config()
+	tlsConfig.Certificates = []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
+
+// TestAdminAuthentication verifies that admin requests other than
+// health checks require a client certificate whose user has the
+// permission the request requires.
+func TestAdminAuthentication(t *testing.T) {
+	url, stopper := startAdminServer()
+	defer stopper.Stop()
+
+	rootClient := client.CreateTestHTTPClient()
+	anonClient := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: security.LoadInsecureClientTLSConfig().Config(),
+	}}
+	userClient := createTestUserHTTPClient(t, "alice")
+
+	send := func(c *http.Client, method, path, body string) int {
+		req, err := http.NewRequest(method, url+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "text/yaml")
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	testCases := []struct {
+		client    *http.Client
+		method    string
+		path      string
+		body      string
+		expStatus int
+	}{
+		{anonClient, "GET", healthPath, "", http.StatusOK},
+		{anonClient, "GET", zonePathPrefix, "", http.StatusUnauthorized},
+		{anonClient, "GET", debugEndpoint + "vars", "", http.StatusUnauthorized},
+		{rootClient, "GET", zonePathPrefix, "", http.StatusOK},
+		{userClient, "GET", zonePathPrefix, "", http.StatusForbidden},
+		// Grant alice read permission in the default permission config.
+		{rootClient, "POST", permPathPrefix + "/", "read: [root, alice]\nwrite: [root]\n", http.StatusOK},
+		{userClient, "GET", zonePathPrefix, "", http.StatusOK},
+		{userClient, "POST", readOnlyPath, "true", http.StatusForbidden},
+		{userClient, "GET", quitPath, "", http.StatusForbidden},
+		{userClient, "DELETE", zonePathPrefix + "/foo", "", http.StatusForbidden},
+	}
+	for i, test := range testCases {
+		if status := send(test.client, test.method, test.path, test.body); status != test.expStatus {
+			t.Errorf("%d: %s %s: expected status %d; got %d", i, test.method, test.path, test.expStatus, status)
+		}
+	}
+}
@@ -18,10 +18,19 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/util"
 )
 
@@ -49,7 +58,7 @@ func sendAdminRequest(ctx *Context, req *http.Request) ([]byte, error) {
 
 // SendQuit requests the admin quit path to drain and shutdown the server.
 func SendQuit(ctx *Context) error {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.Addr, quitPath), nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), quitPath), nil)
 	if err != nil {
 		return util.Errorf("unable to create request to admin REST endpoint: %s", err)
 	}
@@ -62,3 +71,235 @@ func SendQuit(ctx *Context) error {
 
 	return nil
 }
+
+// drainPollInterval is how often SendDrain polls the progress of the
+// node's drain.
+const drainPollInterval = time.Second
+
+// SendDrain requests the admin drain path to start draining the node
+// and polls it, printing each phase, until the drain is done.
+func SendDrain(ctx *Context) error {
+	url := fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), drainPath)
+	method := "POST"
+	var phase DrainPhase
+	for {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return util.Errorf("unable to create request to admin REST endpoint: %s", err)
+		}
+		req.Header.Set(util.AcceptHeader, util.JSONContentType)
+		b, err := sendAdminRequest(ctx, req)
+		if err != nil {
+			return util.Errorf("admin REST request failed: %s", err)
+		}
+		var status DrainStatus
+		if err := json.Unmarshal(b, &status); err != nil {
+			return util.Errorf("unable to decode drain status: %s", err)
+		}
+		if status.Phase != phase {
+			phase = status.Phase
+			fmt.Printf("node drain: %s\n", phase)
+		}
+		if phase == DrainDone {
+			if status.Error != "" {
+				return util.Errorf("node drained with errors: %s", status.Error)
+			}
+			return nil
+		}
+		method = "GET"
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// SendReadOnly requests the admin read-only path to put the node into
+// or take it out of read-only mode.
+func SendReadOnly(ctx *Context, readOnly bool) error {
+	url := fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), readOnlyPath)
+	req, err := http.NewRequest("POST", url, strings.NewReader(strconv.FormatBool(readOnly)))
+	if err != nil {
+		return util.Errorf("unable to create request to admin REST endpoint: %s", err)
+	}
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		return util.Errorf("admin REST request failed: %s", err)
+	}
+
+	fmt.Printf("node read-only: %s", string(b))
+
+	return nil
+}
+
+// SendPromote requests the admin standby path to promote the standby
+// cluster of the node, and prints the timestamp up to which the
+// primary's writes were shipped.
+func SendPromote(ctx *Context) error {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), standbyPath), nil)
+	if err != nil {
+		return util.Errorf("unable to create request to admin REST endpoint: %s", err)
+	}
+	req.Header.Set(util.AcceptHeader, util.JSONContentType)
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		return util.Errorf("admin REST request failed: %s", err)
+	}
+	var status StandbyStatus
+	if err := json.Unmarshal(b, &status); err != nil {
+		return util.Errorf("unable to decode standby status: %s", err)
+	}
+	fmt.Printf("promoted; writes shipped up to %s\n", status.ReplicatedTimestamp)
+	return nil
+}
+
+// SendBalanceConfig requests the admin balance path to set the
+// cluster's balance thresholds, or only to fetch them if config is nil,
+// and prints them.
+func SendBalanceConfig(ctx *Context, config *proto.BalanceConfig) error {
+	method := "GET"
+	var body io.Reader
+	if config != nil {
+		b, err := json.Marshal(config)
+		if err != nil {
+			return util.Errorf("unable to encode balance config: %s", err)
+		}
+		method, body = "POST", bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), balancePath), body)
+	if err != nil {
+		return util.Errorf("unable to create request to admin REST endpoint: %s", err)
+	}
+	req.Header.Set(util.ContentTypeHeader, util.JSONContentType)
+	req.Header.Set(util.AcceptHeader, util.YAMLContentType)
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		return util.Errorf("admin REST request failed: %s", err)
+	}
+	fmt.Printf("balance thresholds:\n%s", string(b))
+	return nil
+}
+
+// GetConfig requests the node's effective configuration, as JSON, from
+// the admin config path.
+func GetConfig(ctx *Context) ([]byte, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), configPath), nil)
+	if err != nil {
+		return nil, util.Errorf("unable to create request to admin REST endpoint: %s", err)
+	}
+	req.Header.Set(util.AcceptHeader, util.JSONContentType)
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		return nil, util.Errorf("admin REST request failed: %s", err)
+	}
+	return b, nil
+}
+
+// SendCompact requests the admin compact path to compact the keys from
+// start through end of the node's store with storeID, or of all its
+// stores if storeID is zero, and prints the stores' compaction stats
+// once they're compacted. Empty keys leave the range unbounded.
+func SendCompact(ctx *Context, storeID proto.StoreID, start, end proto.Key) error {
+	query := url.Values{}
+	if storeID != 0 {
+		query.Set(compactParamStore, strconv.Itoa(int(storeID)))
+	}
+	if len(start) > 0 {
+		query.Set(compactParamStart, string(start))
+	}
+	if len(end) > 0 {
+		query.Set(compactParamEnd, string(end))
+	}
+	u := fmt.Sprintf("%s://%s%s?%s", adminScheme, ctx.HTTPHost(), compactPath, query.Encode())
+	req, err := http.NewRequest("POST", u, nil)
+	if err != nil {
+		return util.Errorf("unable to create request to admin REST endpoint: %s", err)
+	}
+	req.Header.Set(util.AcceptHeader, util.JSONContentType)
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		return util.Errorf("admin REST request failed: %s", err)
+	}
+	var stats struct {
+		Stores []StoreCompactionStats `json:"stores"`
+	}
+	if err := json.Unmarshal(b, &stats); err != nil {
+		return util.Errorf("unable to decode compaction stats: %s", err)
+	}
+	for _, s := range stats.Stores {
+		fmt.Printf("store %d: %d bytes pending compaction\n", s.StoreID, s.Stats.PendingBytes)
+		for _, l := range s.Stats.Levels {
+			if l.Files > 0 {
+				fmt.Printf("  level %d: %d files, %d bytes\n", l.Level, l.Files, l.Bytes)
+			}
+		}
+	}
+	return nil
+}
+
+// GetReplicationReport requests the cluster's replication report from
+// the status replication path of the node.
+func GetReplicationReport(ctx *Context) (*storage.ReplicationReport, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), statusReplicationKey), nil)
+	if err != nil {
+		return nil, util.Errorf("unable to create request to status REST endpoint: %s", err)
+	}
+	req.Header.Set(util.AcceptHeader, util.JSONContentType)
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		return nil, util.Errorf("status REST request failed: %s", err)
+	}
+	report := &storage.ReplicationReport{}
+	if err := json.Unmarshal(b, report); err != nil {
+		return nil, util.Errorf("unable to decode replication report: %s", err)
+	}
+	return report, nil
+}
+
+// SendCheckpoint requests the admin checkpoint path to checkpoint the
+// engine of the node's store with storeID. If dir isn't empty, the
+// node writes the checkpoint to dir on its filesystem; otherwise the
+// checkpoint is streamed, as a tar archive, to out.
+func SendCheckpoint(ctx *Context, storeID proto.StoreID, dir string, out io.Writer) error {
+	query := url.Values{}
+	query.Set(checkpointParamStore, strconv.Itoa(int(storeID)))
+	method := "GET"
+	if dir != "" {
+		query.Set(checkpointParamDir, dir)
+		method = "POST"
+	}
+	u := fmt.Sprintf("%s://%s%s?%s", adminScheme, ctx.HTTPHost(), checkpointPath, query.Encode())
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return util.Errorf("unable to create request to admin REST endpoint: %s", err)
+	}
+	if dir != "" {
+		req.Header.Set(util.AcceptHeader, util.JSONContentType)
+		b, err := sendAdminRequest(ctx, req)
+		if err != nil {
+			return util.Errorf("admin REST request failed: %s", err)
+		}
+		var status CheckpointStatus
+		if err := json.Unmarshal(b, &status); err != nil {
+			return util.Errorf("unable to decode checkpoint status: %s", err)
+		}
+		fmt.Printf("store %d checkpointed into %s\n", status.StoreID, status.Dir)
+		return nil
+	}
+	// The archive is streamed rather than read by sendAdminRequest, as
+	// it's as large as the store.
+	client, err := ctx.GetHTTPClient()
+	if err != nil {
+		return util.Errorf("failed to initialized http client: %s", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return util.Errorf("admin REST request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return util.Errorf("%s: %s", resp.Status, string(b))
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return util.Errorf("unable to read checkpoint of store %d: %s", storeID, err)
+	}
+	return nil
+}
@@ -28,9 +28,13 @@ import (
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/security"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+	descriptor "github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
 )
 
 // startAdminServer launches a new admin server using minimal engine
@@ -39,14 +43,22 @@ import (
 // Cockroach KV client address is set to the address of the test server.
 func startAdminServer() (string, *util.Stopper) {
 	stopper := util.NewStopper()
-	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), stopper)
+	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), nil, stopper)
 	if err != nil {
 		log.Fatal(err)
 	}
-	admin := newAdminServer(db, stopper)
+	jobs := NewJobCoordinator(db, hlc.NewClock(hlc.UnixNano), stopper)
+	admin := newAdminServer(db, stopper, jobs, NewNamespaceManager(db, jobs), nil, nil, nil, nil, nil, false)
 	mux := http.NewServeMux()
 	admin.registerHandlers(mux)
-	httpServer := httptest.NewTLSServer(mux)
+	// Serve with the test certs so that client certificates are verified.
+	tlsConfig, err := security.LoadTLSConfigFromDir(testContext.Certs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	httpServer := httptest.NewUnstartedServer(mux)
+	httpServer.TLS = tlsConfig.Config()
+	httpServer.StartTLS()
 	stopper.AddCloser(httpServer)
 
 	if strings.HasPrefix(httpServer.URL, "http://") {
@@ -103,6 +115,41 @@ func TestAdminDebugExpVar(t *testing.T) {
 	}
 }
 
+// TestAdminSchema verifies that the protocol buffer descriptors are
+// available via the schema endpoint.
+func TestAdminSchema(t *testing.T) {
+	url, stopper := startAdminServer()
+	defer stopper.Stop()
+
+	req, err := http.NewRequest("GET", url+schemaPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(util.AcceptHeader, util.ProtoContentType)
+	resp, err := client.CreateTestHTTPClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := &descriptor.FileDescriptorSet{}
+	if err := gogoproto.Unmarshal(body, set); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range set.File {
+		if f.GetName() == "cockroach/proto/data.proto" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected descriptor for data.proto in %d files", len(set.File))
+	}
+}
+
 // TestAdminDebugPprof verifies that pprof tools are available.
 // via the /debug/pprof/* links.
 func TestAdminDebugPprof(t *testing.T) {
@@ -117,3 +164,33 @@ func TestAdminDebugPprof(t *testing.T) {
 		t.Errorf("expected match: %t; err nil: %v", matches, err)
 	}
 }
+
+// TestAdminConfig verifies that the config endpoint serves the node's
+// context, settings and engines.
+func TestAdminConfig(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+
+	jI, err := getJSON("https://" + s.ServingAddr() + configPath)
+	if err != nil {
+		t.Fatalf("failed to fetch JSON: %v", err)
+	}
+	j := jI.(map[string]interface{})
+	ctx, ok := j["context"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("context not found in JSON response: %v", j)
+	}
+	if ctx["Certs"] != s.Ctx.Certs || ctx["ScanInterval"] != s.Ctx.ScanInterval.String() {
+		t.Errorf("unexpected context %v", ctx)
+	}
+	if settings, ok := j["settings"].(map[string]interface{}); !ok || settings["GossipInterval"] != s.Ctx.GossipInterval.String() {
+		t.Errorf("unexpected settings %v", j["settings"])
+	}
+	engines, ok := j["engines"].([]interface{})
+	if !ok || len(engines) != 1 {
+		t.Fatalf("expected 1 engine; got %v", j["engines"])
+	}
+	if typ := engines[0].(map[string]interface{})["type"]; typ != "*engine.InMem" {
+		t.Errorf("expected in-memory engine; got %v", typ)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// validateBalanceConfig returns an error if either of the thresholds
+// of a balance config is negative.
+func validateBalanceConfig(config *proto.BalanceConfig) error {
+	if config.RangeCountThreshold < 0 || config.BytesThreshold < 0 {
+		return util.Errorf("balance thresholds must not be negative: %g range count, %g bytes",
+			config.RangeCountThreshold, config.BytesThreshold)
+	}
+	return nil
+}
+
+// loadBalanceConfig reads the cluster's balance thresholds, which are
+// the defaults applied by the allocator if they've never been set.
+func loadBalanceConfig(db *client.KV) (*proto.BalanceConfig, error) {
+	call := client.GetCall(engine.KeyConfigBalance)
+	if err := db.Run(call); err != nil {
+		return nil, err
+	}
+	config := &proto.BalanceConfig{
+		RangeCountThreshold: storage.DefaultRangeCountThreshold,
+		BytesThreshold:      storage.DefaultBytesThreshold,
+	}
+	reply := call.Reply.(*proto.GetResponse)
+	if reply.Value == nil {
+		return config, nil
+	}
+	if err := gogoproto.Unmarshal(reply.Value.Bytes, config); err != nil {
+		return nil, util.Errorf("unable to unmarshal balance config: %s", err)
+	}
+	return config, nil
+}
+
+// handleBalance responds to GET requests with the cluster's balance
+// thresholds. PUT and POST requests first set them from the balance
+// config held in the body. They're stored at engine.KeyConfigBalance
+// and gossiped to the allocators of all stores; /_status/balance
+// reports the balance of the stores against them.
+func (s *adminServer) handleBalance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "PUT", "POST":
+		b, err := ioutil.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		config := &proto.BalanceConfig{}
+		if err := util.UnmarshalRequest(r, b, config, util.AllEncodings); err != nil {
+			http.Error(w, "balance config has invalid format: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateBalanceConfig(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.db.Run(client.PutProtoCall(engine.KeyConfigBalance, config)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	config, err := loadBalanceConfig(s.db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, contentType, err := util.MarshalResponse(r, config, util.AllEncodings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
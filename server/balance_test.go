@@ -0,0 +1,79 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestAdminBalance verifies that the balance thresholds are the
+// defaults until they're set, and that invalid thresholds are refused.
+func TestAdminBalance(t *testing.T) {
+	url, stopper := startAdminServer()
+	defer stopper.Stop()
+
+	send := func(method, body string) (*proto.BalanceConfig, error) {
+		req, err := http.NewRequest(method, url+balancePath, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(util.ContentTypeHeader, util.JSONContentType)
+		req.Header.Set(util.AcceptHeader, util.JSONContentType)
+		b, err := sendAdminRequest(testContext, req)
+		if err != nil {
+			return nil, err
+		}
+		config := &proto.BalanceConfig{}
+		if err := json.Unmarshal(b, config); err != nil {
+			t.Fatal(err)
+		}
+		return config, nil
+	}
+
+	config, err := send("GET", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.RangeCountThreshold != storage.DefaultRangeCountThreshold ||
+		config.BytesThreshold != storage.DefaultBytesThreshold {
+		t.Errorf("expected default thresholds; got %s", config)
+	}
+
+	if config, err = send("POST", `{"range_count_threshold": 0.2, "bytes_threshold": 0.3}`); err != nil {
+		t.Fatal(err)
+	}
+	if config.RangeCountThreshold != 0.2 || config.BytesThreshold != 0.3 {
+		t.Errorf("expected thresholds to be set; got %s", config)
+	}
+
+	if _, err := send("POST", `{"range_count_threshold": -1, "bytes_threshold": 0.3}`); err == nil ||
+		!strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("expected negative threshold to be refused; got %v", err)
+	}
+	if config, err = send("GET", ""); err != nil {
+		t.Fatal(err)
+	}
+	if config.RangeCountThreshold != 0.2 {
+		t.Errorf("expected refused thresholds not to be stored; got %s", config)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// checkpointContentType is the content type of streamed checkpoints.
+const checkpointContentType = "application/x-tar"
+
+// A CheckpointStatus describes a checkpoint of a store written by a
+// node to its filesystem.
+type CheckpointStatus struct {
+	StoreID proto.StoreID `json:"store_id"`
+	Dir     string        `json:"dir"`
+}
+
+// findCheckpointer returns the engine of the node's store with storeID
+// if it may be checkpointed.
+func findCheckpointer(n *Node, storeID proto.StoreID) (engine.Checkpointer, error) {
+	var e engine.Engine
+	n.lSender.VisitStores(func(s *storage.Store) error {
+		if s.StoreID() == storeID {
+			e = s.Engine()
+		}
+		return nil
+	})
+	if e == nil {
+		return nil, util.Errorf("store %d not found", storeID)
+	}
+	c, ok := e.(engine.Checkpointer)
+	if !ok || c.Dir() == "" {
+		return nil, util.Errorf("engine %T of store %d doesn't support checkpoints", e, storeID)
+	}
+	return c, nil
+}
+
+// handleCheckpoint handles requests for a consistent copy of the
+// engine of the node's store selected by the store parameter, with
+// which the store may be backed up or a replacement node provisioned
+// without replicating its ranges anew. A POST request writes the
+// copy to the dir parameter's directory, which must not exist, on the
+// node's filesystem and responds with a CheckpointStatus once it's
+// written; sstables are hard-linked rather than copied if the
+// directory is on the store's filesystem. A GET request responds with
+// the copy as a tar archive of the store's directory, which is
+// truncated if the node fails to read it. The copy holds the store's
+// identity, so the node it's restored to takes over the store's node
+// ID; the store's node must not be running by then.
+func (s *adminServer) handleCheckpoint(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	id, err := strconv.ParseInt(query.Get(checkpointParamStore), 10, 32)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid store ID "+strconv.Quote(query.Get(checkpointParamStore)), http.StatusBadRequest)
+		return
+	}
+	storeID := proto.StoreID(id)
+	dir := query.Get(checkpointParamDir)
+	switch {
+	case r.Method == "POST" && dir != "":
+	case r.Method == "GET" && dir == "":
+	default:
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	c, err := findCheckpointer(s.node, storeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if dir != "" {
+		log.Infof("checkpointing store %d into %s", storeID, dir)
+		if err := c.Checkpoint(dir); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body, contentType, err := util.MarshalResponse(r, CheckpointStatus{StoreID: storeID, Dir: dir},
+			[]util.EncodingType{util.JSONEncoding})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+		return
+	}
+
+	// Checkpoints to be streamed are taken within the store's
+	// directory, so that its sstables are linked rather than copied,
+	// and removed once they're sent.
+	tmpDir, err := ioutil.TempDir(c.Dir(), "checkpoint")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	checkpointDir := filepath.Join(tmpDir, "data")
+	if err := c.Checkpoint(checkpointDir); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", checkpointContentType)
+	if err := writeCheckpointArchive(w, checkpointDir); err != nil {
+		log.Warningf("unable to stream checkpoint of store %d: %s", storeID, err)
+	}
+}
+
+// writeCheckpointArchive writes the files of the checkpoint in dir to
+// w as a tar archive. The archive is left unterminated on failure, so
+// that readers don't mistake it for a complete one.
+func writeCheckpointArchive(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
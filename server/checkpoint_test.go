@@ -0,0 +1,108 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestWriteCheckpointArchive verifies that the files of a checkpoint
+// are archived with their paths relative to its directory.
+func TestWriteCheckpointArchive(t *testing.T) {
+	dir := util.CreateTempDir(t, "_checkpoint_archive_test")
+	defer util.CleanupDir(dir)
+	files := map[string]string{
+		"CURRENT":        "MANIFEST-000001\n",
+		"000003.sst":     "sstable",
+		"sub/000004.sst": "nested",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeCheckpointArchive(&buf, dir); err != nil {
+		t.Fatal(err)
+	}
+	archived := map[string]string{}
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archived[hdr.Name] = string(b)
+	}
+	if !reflect.DeepEqual(archived, files) {
+		t.Errorf("expected archive of %v; got %v", files, archived)
+	}
+}
+
+// TestCheckpointRequests verifies that checkpoint requests with
+// invalid parameters and checkpoints of in-memory stores are refused.
+func TestCheckpointRequests(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+
+	httpClient := client.CreateTestHTTPClient()
+	for i, test := range []struct {
+		method, query string
+		expStatus     int
+	}{
+		{"GET", "?store=1", http.StatusInternalServerError},
+		{"POST", "?store=1&dir=checkpoint", http.StatusInternalServerError},
+		{"GET", "?store=2", http.StatusInternalServerError},
+		{"GET", "?store=x", http.StatusBadRequest},
+		{"POST", "?store=1", http.StatusBadRequest},
+		{"GET", "?store=1&dir=checkpoint", http.StatusBadRequest},
+	} {
+		req, err := http.NewRequest(test.method, "https://"+s.ServingAddr()+checkpointPath+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.expStatus {
+			t.Errorf("%d: expected status %d; got %d", i, test.expStatus, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	gogoproto "github.com/gogo/protobuf/proto"
+
+	commander "code.google.com/p/go-commander"
+)
+
+// backupBatchSize is the number of keys scanned, or restored, per
+// request.
+const backupBatchSize = 1000
+
+// A backupCmd command writes the keys with a prefix to a file.
+var backupCmd = &commander.Command{
+	UsageLine: "backup [options] <file> [<prefix>]",
+	Short:     "backs up the keys with a prefix to a file\n",
+	Long: `
+Writes the key/value pairs whose keys begin with <prefix>, or all keys
+outside of the system key range if no prefix is specified, to <file>.
+The keys are read at a single timestamp, so the backup is a consistent
+snapshot of the prefix even while it's being written to. Backing up a
+single prefix allows the namespace of one tenant to be moved to another
+cluster; see "restore". The backup is recorded as a job, which may be
+listed and paused with the "job" commands; a paused backup is restarted
+by running the command again.
+`,
+	Run:  runBackup,
+	Flag: *flag.CommandLine,
+}
+
+func runBackup(cmd *commander.Command, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		cmd.Usage()
+		return
+	}
+	var prefix proto.Key
+	if len(args) == 2 {
+		prefix = proto.Key(args[1])
+	}
+	kv, err := makeKVClient()
+	if err != nil {
+		fmt.Fprintf(osStderr, "failed to initialize KV client: %s", err)
+		osExit(1)
+		return
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		fmt.Fprintf(osStderr, "unable to create backup file: %s\n", err)
+		osExit(1)
+		return
+	}
+	var count int
+	description := fmt.Sprintf("back up all keys to %s", args[0])
+	if len(prefix) > 0 {
+		description = fmt.Sprintf("back up prefix %q to %s", prefix, args[0])
+	}
+	err = runAsJob(kv, proto.JobType_BACKUP, description, func(job *server.Job) error {
+		w := bufio.NewWriter(f)
+		var err error
+		// The size of the backup isn't known in advance, so its progress
+		// is only reported to allow it to be paused.
+		count, err = backupPrefix(kv, prefix, w, func() error { return job.Progress(0) })
+		if err == nil {
+			err = w.Flush()
+		}
+		return err
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(osStderr, "backup failed: %s\n", err)
+		osExit(1)
+		return
+	}
+	fmt.Printf("backed up %d keys to %s\n", count, args[0])
+}
+
+// A restoreCmd command writes the keys held by a backup file.
+var restoreCmd = &commander.Command{
+	UsageLine: "restore [options] <file> [<prefix> <new-prefix>]",
+	Short:     "restores the keys held by a backup file\n",
+	Long: `
+Writes the key/value pairs held by <file>, as written by "backup", back
+to the cluster. If <prefix> and <new-prefix> are specified, every key of
+the backup must begin with <prefix>, which is replaced by <new-prefix>;
+this restores the namespace of a tenant under a different prefix, for
+example when moving it to a cluster which already holds a namespace
+with the original prefix. Existing values of the restored keys are
+overwritten. The restore is recorded as a job, which may be listed and
+paused with the "job" commands; a paused restore is restarted by
+running the command again.
+
+With -dry-run, nothing is written. Instead, the number of keys which
+would be restored and of bytes which would be written are displayed,
+along with how many of the keys already have values which would be
+overwritten.
+`,
+	Run:  runRestore,
+	Flag: *flag.CommandLine,
+}
+
+func runRestore(cmd *commander.Command, args []string) {
+	if len(args) != 1 && len(args) != 3 {
+		cmd.Usage()
+		return
+	}
+	var prefix, newPrefix proto.Key
+	if len(args) == 3 {
+		prefix, newPrefix = proto.Key(args[1]), proto.Key(args[2])
+	}
+	kv, err := makeKVClient()
+	if err != nil {
+		fmt.Fprintf(osStderr, "failed to initialize KV client: %s", err)
+		osExit(1)
+		return
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(osStderr, "unable to open backup file: %s\n", err)
+		osExit(1)
+		return
+	}
+	defer f.Close()
+	if Context.DryRun {
+		plan, err := planRestore(kv, bufio.NewReader(f), prefix, newPrefix)
+		if err != nil {
+			fmt.Fprintf(osStderr, "restore dry run failed: %s\n", err)
+			osExit(1)
+			return
+		}
+		fmt.Printf("dry run: restoring %s would write %d keys (%d bytes), overwriting %d existing values\n",
+			args[0], plan.keys, plan.bytes, plan.overwritten)
+		return
+	}
+	info, err := f.Stat()
+	if err != nil {
+		fmt.Fprintf(osStderr, "unable to open backup file: %s\n", err)
+		osExit(1)
+		return
+	}
+	var count int
+	description := fmt.Sprintf("restore %s", args[0])
+	if len(prefix) > 0 {
+		description += fmt.Sprintf(" from prefix %q to %q", prefix, newPrefix)
+	}
+	err = runAsJob(kv, proto.JobType_RESTORE, description, func(job *server.Job) error {
+		cr := &countingReader{r: f}
+		var err error
+		count, err = restoreBackup(kv, bufio.NewReader(cr), prefix, newPrefix, func() error {
+			if info.Size() == 0 {
+				return job.Progress(0)
+			}
+			return job.Progress(float64(cr.n) / float64(info.Size()))
+		})
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(osStderr, "restore failed after %d keys: %s\n", count, err)
+		osExit(1)
+		return
+	}
+	fmt.Printf("restored %d keys from %s\n", count, args[0])
+}
+
+// runAsJob runs fn as a job of the given type in this process. The job
+// is recorded in the cluster's job table, so it may be listed and
+// paused like the jobs run by nodes, but it can't be resumed.
+func runAsJob(kv *client.KV, typ proto.JobType, description string, fn server.JobFunc) error {
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+	jc := server.NewJobCoordinator(kv, hlc.NewClock(hlc.UnixNano), stopper)
+	jc.Register(typ, fn)
+	_, err := jc.Run(typ, description)
+	return err
+}
+
+// A countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// backupPrefix writes the key/value pairs whose keys begin with the
+// prefix, or all non-system key/value pairs if the prefix is empty, to
+// w and returns their number. Each pair is written as a proto.KeyValue
+// preceded by its varint-encoded length. All keys are scanned at the
+// timestamp at which the first key of the span is read. If progress
+// is not nil, it's called after each batch of keys, and the backup
+// stops with its error, if any.
+func backupPrefix(kv *client.KV, prefix proto.Key, w io.Writer, progress func() error) (int, error) {
+	start, end := engine.KeySystemMax, engine.KeyMax
+	if len(prefix) > 0 {
+		if bytes.Compare(prefix, engine.KeySystemMax) < 0 {
+			return 0, util.Errorf("unable to back up system keys: %s", prefix)
+		}
+		start, end = prefix, prefix.PrefixEnd()
+	}
+
+	get := client.GetCall(start)
+	if err := kv.Run(get); err != nil {
+		return 0, err
+	}
+	timestamp := get.Reply.Header().Timestamp
+
+	var count int
+	var buf [binary.MaxVarintLen64]byte
+	for {
+		scan := client.ScanCall(start, end, backupBatchSize)
+		scan.Args.Header().Timestamp = timestamp
+		if err := kv.Run(scan); err != nil {
+			return count, err
+		}
+		rows := scan.Reply.(*proto.ScanResponse).Rows
+		for i := range rows {
+			data, err := gogoproto.Marshal(&rows[i])
+			if err != nil {
+				return count, err
+			}
+			if _, err := w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(data)))]); err != nil {
+				return count, err
+			}
+			if _, err := w.Write(data); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(rows) < backupBatchSize {
+			return count, nil
+		}
+		if progress != nil {
+			if err := progress(); err != nil {
+				return count, err
+			}
+		}
+		start = rows[len(rows)-1].Key.Next()
+	}
+}
+
+// restoreBackup puts the key/value pairs read from r, as written by
+// backupPrefix, and returns the number restored. If prefix or
+// newPrefix is set, every key must begin with prefix, which is
+// replaced by newPrefix. If progress is not nil, it's called after
+// each batch of keys, and the restore stops with its error, if any.
+func restoreBackup(kv *client.KV, r *bufio.Reader, prefix, newPrefix proto.Key, progress func() error) (int, error) {
+	var count int
+	var calls []client.Call
+	flush := func() error {
+		if err := kv.Run(calls...); err != nil {
+			return err
+		}
+		count += len(calls)
+		calls = calls[:0]
+		if progress != nil {
+			return progress()
+		}
+		return nil
+	}
+	for {
+		key, value, err := readRestoreRecord(r, prefix, newPrefix)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return count, err
+		}
+		calls = append(calls, client.Call{
+			Args: &proto.PutRequest{
+				RequestHeader: proto.RequestHeader{Key: key},
+				Value:         value,
+			},
+			Reply: &proto.PutResponse{},
+		})
+		if len(calls) == backupBatchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, flush()
+}
+
+// A restorePlan describes what restoring a backup would write.
+type restorePlan struct {
+	keys        int   // Keys which would be put
+	bytes       int64 // Bytes of the keys and values which would be put
+	overwritten int   // Keys which already have a value
+}
+
+// planRestore reads the key/value pairs from r, as restoreBackup does,
+// and returns what restoring them would write, without writing them.
+// The keys are read to find those whose values would be overwritten.
+func planRestore(kv *client.KV, r *bufio.Reader, prefix, newPrefix proto.Key) (restorePlan, error) {
+	var plan restorePlan
+	var calls []client.Call
+	flush := func() error {
+		if err := kv.Run(calls...); err != nil {
+			return err
+		}
+		for _, call := range calls {
+			if call.Reply.(*proto.GetResponse).Value != nil {
+				plan.overwritten++
+			}
+		}
+		calls = calls[:0]
+		return nil
+	}
+	for {
+		key, value, err := readRestoreRecord(r, prefix, newPrefix)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return plan, err
+		}
+		plan.keys++
+		plan.bytes += int64(len(key) + len(value.Bytes))
+		if value.Integer != nil {
+			plan.bytes += 8
+		}
+		calls = append(calls, client.GetCall(key))
+		if len(calls) == backupBatchSize {
+			if err := flush(); err != nil {
+				return plan, err
+			}
+		}
+	}
+	return plan, flush()
+}
+
+// readRestoreRecord reads the next key/value pair of a backup and
+// returns the key it's restored to, the key with prefix replaced by
+// newPrefix, and the value to put. io.EOF is returned at the end of
+// the backup.
+func readRestoreRecord(r *bufio.Reader, prefix, newPrefix proto.Key) (proto.Key, proto.Value, error) {
+	var row proto.KeyValue
+	if err := readBackupRecord(r, &row); err != nil {
+		return nil, proto.Value{}, err
+	}
+	if !bytes.HasPrefix(row.Key, prefix) {
+		return nil, proto.Value{}, util.Errorf("key %s of backup doesn't begin with prefix %s", row.Key, prefix)
+	}
+	key := proto.MakeKey(newPrefix, row.Key[len(prefix):])
+	if bytes.Compare(key, engine.KeySystemMax) < 0 {
+		return nil, proto.Value{}, util.Errorf("unable to restore system key %s", key)
+	}
+	// The checksum covers the original key and the timestamp is
+	// assigned anew by the put.
+	return key, proto.Value{Bytes: row.Value.Bytes, Integer: row.Value.Integer, Tag: row.Value.Tag}, nil
+}
+
+// readBackupRecord reads the next length-prefixed key/value pair
+// written by backupPrefix. io.EOF is returned at the end of the
+// backup.
+func readBackupRecord(r *bufio.Reader, kv *proto.KeyValue) error {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return util.Errorf("backup truncated: %s", err)
+	}
+	return gogoproto.Unmarshal(data, kv)
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/server"
+)
+
+// TestBackupRestorePrefix verifies that only the keys with the backed
+// up prefix are written to a backup and that they're restored under a
+// new prefix.
+func TestBackupRestorePrefix(t *testing.T) {
+	s := server.StartTestServer(t)
+	defer s.Stop()
+	sender, err := client.NewHTTPSender(s.ServingAddr(), security.EmbeddedPrefix+"test_certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := client.NewKV(nil, sender)
+	kv.User = "root"
+
+	for _, key := range []string{"t1/a", "t1/b", "t2/a"} {
+		if err := kv.Run(client.PutCall(proto.Key(key), []byte(key))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kv.Run(client.IncrementCall(proto.Key("t1/n"), 5)); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if count, err := backupPrefix(kv, proto.Key("t1/"), &buf, nil); err != nil || count != 3 {
+		t.Fatalf("expected 3 keys backed up; got %d, %v", count, err)
+	}
+	backup := buf.Bytes()
+
+	// A dry run writes nothing and reports the keys which would be
+	// written under the new prefix; none of them exist yet.
+	plan, err := planRestore(kv, bufio.NewReader(bytes.NewReader(backup)), proto.Key("t1/"), proto.Key("t3/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (restorePlan{keys: 3, bytes: 4*3 + 4*2 + 8, overwritten: 0}); plan != exp {
+		t.Errorf("expected dry run plan %+v; got %+v", exp, plan)
+	}
+	if plan, err := planRestore(kv, bufio.NewReader(bytes.NewReader(backup)), nil, nil); err != nil || plan.overwritten != 3 {
+		t.Errorf("expected restore in place to overwrite 3 keys; got %+v, %v", plan, err)
+	}
+
+	if _, err := restoreBackup(kv, bufio.NewReader(bytes.NewReader(backup)), proto.Key("t2/"), proto.Key("t3/"), nil); err == nil {
+		t.Error("expected restore with mismatched prefix to fail")
+	}
+	if count, err := restoreBackup(kv, bufio.NewReader(bytes.NewReader(backup)), proto.Key("t1/"), proto.Key("t3/"), nil); err != nil || count != 3 {
+		t.Fatalf("expected 3 keys restored; got %d, %v", count, err)
+	}
+	scan := client.ScanCall(proto.Key("t3/"), proto.Key("t4/"), 0)
+	if err := kv.Run(scan); err != nil {
+		t.Fatal(err)
+	}
+	rows := scan.Reply.(*proto.ScanResponse).Rows
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 restored keys; got %+v", rows)
+	}
+	for i, key := range []string{"t3/a", "t3/b", "t3/n"} {
+		if string(rows[i].Key) != key {
+			t.Errorf("%d: expected key %s; got %s", i, key, rows[i].Key)
+		}
+	}
+	if string(rows[0].Value.Bytes) != "t1/a" {
+		t.Errorf("expected value t1/a; got %q", rows[0].Value.Bytes)
+	}
+	if v := rows[2].Value.Integer; v == nil || *v != 5 {
+		t.Errorf("expected integer 5; got %+v", rows[2].Value)
+	}
+}
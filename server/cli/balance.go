@@ -0,0 +1,90 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+	"strconv"
+
+	commander "code.google.com/p/go-commander"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// A getBalanceCmd command displays the cluster's balance thresholds.
+var getBalanceCmd = &commander.Command{
+	UsageLine: "get-balance [options]",
+	Short:     "fetches and displays the balance thresholds",
+	Long: `
+Fetches and displays the cluster's balance thresholds: the fractions
+by which the range count and the bytes used of a store may exceed the
+means of the stores before the allocator places replicas elsewhere.
+`,
+	Run:  runGetBalance,
+	Flag: *flag.CommandLine,
+}
+
+// runGetBalance invokes the REST API with GET action.
+func runGetBalance(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	if err := server.SendBalanceConfig(Context, nil); err != nil {
+		log.Error(err)
+	}
+}
+
+// A setBalanceCmd command sets the cluster's balance thresholds.
+var setBalanceCmd = &commander.Command{
+	UsageLine: "set-balance [options] <range-count-threshold> <bytes-threshold>",
+	Short:     "sets the balance thresholds",
+	Long: `
+Sets the cluster's balance thresholds: the fractions by which the range
+count and the bytes used of a store may exceed the means of the stores
+eligible for a replica before the allocator considers it overfull and
+places the replica elsewhere. Lower thresholds keep the stores closer
+to balanced at the cost of moving more replicas. The thresholds are
+gossiped to all nodes, which apply them without restarting; the balance
+of the stores against them is served by /_status/balance.
+`,
+	Run:  runSetBalance,
+	Flag: *flag.CommandLine,
+}
+
+// runSetBalance invokes the REST API with POST action and the balance
+// config as the body.
+func runSetBalance(cmd *commander.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	rangeCount, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		log.Errorf("invalid range count threshold %q: %s", args[0], err)
+		return
+	}
+	bytes, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		log.Errorf("invalid bytes threshold %q: %s", args[1], err)
+		return
+	}
+	config := &proto.BalanceConfig{RangeCountThreshold: rangeCount, BytesThreshold: bytes}
+	if err := server.SendBalanceConfig(Context, config); err != nil {
+		log.Error(err)
+	}
+}
@@ -75,3 +75,38 @@ func runCreateNodeCert(cmd *commander.Command, args []string) {
 		return
 	}
 }
+
+// A createClientCert command generates a client certificate and stores
+// it in the cert directory.
+var createClientCertCmd = &commander.Command{
+	UsageLine: "create-client-cert [options] <username>",
+	Short:     "create client cert and key\n",
+	Long: `
+Generates a new key pair, a new client certificate authenticating
+<username> and writes them to client.crt and client.key in the
+directory specified by -certs (required). The certs directory should
+contain a CA cert and key.
+
+Admin commands authenticate with the client certificate in the certs
+directory, or the node certificate if there is none, which
+authenticates the root user. Other users must be granted read or write
+permission in the default permission config to use admin commands.
+`,
+	Run:  runCreateClientCert,
+	Flag: *flag.CommandLine,
+}
+
+// runCreateClientCert generates key pair and client certificate and
+// writes them to their corresponding files.
+func runCreateClientCert(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	err := security.RunCreateClientCert(Context.Certs, args[0])
+	if err != nil {
+		fmt.Fprintf(osStderr, "failed to generate client certificate: %s\n", err)
+		osExit(1)
+		return
+	}
+}
@@ -71,10 +71,18 @@ var allCmds = &commander.Commander{
 		startCmd,
 		exterminateCmd,
 		quitCmd,
+		drainCmd,
+		cutoverCmd,
+		promoteCmd,
+		readOnlyCmd,
+		configCmd,
+		compactCmd,
+		checkpointCmd,
 
 		// Certificate commands.
 		createCACertCmd,
 		createNodeCertCmd,
+		createClientCertCmd,
 
 		// Key/value commands.
 		getCmd,
@@ -82,11 +90,14 @@ var allCmds = &commander.Commander{
 		incCmd,
 		delCmd,
 		scanCmd,
+		backupCmd,
+		restoreCmd,
 
 		// Range commands.
 		lsRangesCmd,
 		splitRangeCmd,
 		mergeRangeCmd,
+		checkMetaCmd,
 
 		// Accounting commands.
 		getAcctCmd,
@@ -103,17 +114,47 @@ var allCmds = &commander.Commander{
 		// Zone commands.
 		getZoneCmd,
 		lsZonesCmd,
+		resolveZoneCmd,
 		rmZoneCmd,
 		setZoneCmd,
 
+		// Balance commands.
+		getBalanceCmd,
+		setBalanceCmd,
+
+		// Job commands.
+		lsJobsCmd,
+		getJobCmd,
+		pauseJobCmd,
+		resumeJobCmd,
+
+		// Namespace commands.
+		createNamespaceCmd,
+		lsNamespacesCmd,
+		renameNamespaceCmd,
+		rmNamespaceCmd,
+
 		// Miscellaneous commands.
 		// TODO(pmattis): stats
 		listParamsCmd,
 		versionCmd,
 	},
+	Commanders: []*commander.Commander{
+		// Debug commands.
+		debugCmds,
+
+		// Node status commands.
+		nodeCmds,
+	},
 }
 
-// Run ...
+// Run runs the command named by args[0] with the remaining arguments.
+// Flags are first set from the COCKROACH_* environment variables named
+// after them, as in COCKROACH_ADDR for -addr; flags given in args
+// override the environment.
 func Run(args []string) error {
+	if err := applyEnv(flag.CommandLine, os.Getenv); err != nil {
+		return err
+	}
 	return allCmds.Run(args)
 }
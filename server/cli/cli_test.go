@@ -106,6 +106,7 @@ func ExampleSplitMergeRanges() {
 	c.Run("scan")
 	c.Run("split-range c c")
 	c.Run("ls-ranges")
+	c.Run("check-meta")
 	c.Run("scan")
 	c.Run("merge-range b")
 	c.Run("ls-ranges")
@@ -125,6 +126,8 @@ func ExampleSplitMergeRanges() {
 	// 	0: node-id=1 store-id=1 attrs=[]
 	// "c"-"\xff\xff" [2]
 	// 	0: node-id=1 store-id=1 attrs=[]
+	// check-meta
+	// found 0 inconsistent addressing records
 	// scan
 	// "a"	1
 	// "b"	2
@@ -0,0 +1,217 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/util"
+	yaml "gopkg.in/yaml.v1"
+)
+
+// configFileFlag is the flag naming the config file, which may not
+// itself be set by a config file.
+const configFileFlag = "config-file"
+
+// envPrefix is prepended to the name of a flag, upper-cased and with
+// dashes replaced by underscores, to name the environment variable
+// which sets it, as in COCKROACH_ADDR for -addr.
+const envPrefix = "COCKROACH_"
+
+// commandLineFlags records the flags set on the command line or by
+// the environment, rather than by the config file, so that they keep
+// overriding the file's values when it's reloaded.
+var commandLineFlags = map[string]bool{}
+
+// envFlags holds the values of the flags set by the environment.
+var envFlags = map[string]string{}
+
+// envVarName returns the name of the environment variable which sets
+// the named flag.
+func envVarName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flagName))
+}
+
+// applyEnv sets each flag of fs for which getenv returns a non-empty
+// value from its environment variable; see envVarName. It's applied
+// before the command line is parsed, so that flags given on the
+// command line override the environment.
+func applyEnv(fs *flag.FlagSet, getenv func(string) string) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envVarName(f.Name)
+		value := getenv(name)
+		if value == "" || err != nil {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = util.Errorf("invalid value %q for %s: %s", value, name, setErr)
+			return
+		}
+		envFlags[f.Name] = value
+	})
+	return err
+}
+
+// applyConfigFile sets the flags of fs, the flags of the running
+// command, and with them the fields of Context, from the config file
+// named by -config-file, if any. Flags set on the command line take
+// precedence, then those set by the environment, then the file's
+// values, then the defaults.
+func applyConfigFile(fs *flag.FlagSet) error {
+	fs.Visit(func(f *flag.Flag) { commandLineFlags[f.Name] = true })
+	for name, value := range envFlags {
+		if commandLineFlags[name] {
+			continue
+		}
+		// The environment was applied to the flags before the command
+		// line was parsed into fs; mark the flag set on fs as well so
+		// that the config file doesn't override it.
+		if err := fs.Set(name, value); err != nil {
+			return util.Errorf("invalid value %q for %s: %s", value, envVarName(name), err)
+		}
+		commandLineFlags[name] = true
+	}
+	if Context.ConfigFile == "" {
+		return nil
+	}
+	return loadConfigFile(fs, Context.ConfigFile)
+}
+
+// reloadConfigFile returns the reloadable settings held by the config
+// file named by -config-file, starting from the current settings, for
+// a running node to apply. As at startup, flags set on the command
+// line or by the environment override the file's values. Settings which are missing from the
+// file keep their current values, and those which only take effect on
+// a restart are ignored.
+func reloadConfigFile(current server.ReloadableSettings) (server.ReloadableSettings, error) {
+	if Context.ConfigFile == "" {
+		return current, util.Errorf("no config file to reload; start the node with -%s", configFileFlag)
+	}
+	values, err := readConfigFile(Context.ConfigFile)
+	if err != nil {
+		return current, err
+	}
+	settings := current
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	fs.DurationVar(&settings.ScanInterval, "scan-interval", settings.ScanInterval, "")
+	fs.DurationVar(&settings.GossipInterval, "gossip-interval", settings.GossipInterval, "")
+	fs.Var(bytesValue{&settings.CacheSize}, "cache-size", "")
+	fs.IntVar(&settings.Verbosity, "v", settings.Verbosity, "")
+	fs.Float64Var(&settings.TraceSampleRate, "trace-sample-rate", settings.TraceSampleRate, "")
+	for name, value := range values {
+		if name == configFileFlag || flag.Lookup(name) == nil {
+			return current, util.Errorf("config file %s: unknown setting %q", Context.ConfigFile, name)
+		}
+		if fs.Lookup(name) == nil || commandLineFlags[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return current, util.Errorf("config file %s: invalid value %q for %s: %s", Context.ConfigFile, value, name, err)
+		}
+	}
+	return settings, nil
+}
+
+// loadConfigFile sets the flags of fs named by the keys of the TOML
+// or YAML file at path, chosen by its extension, to the file's values.
+// Values are parsed as they would be on the command line. Flags which
+// have already been set keep their values.
+func loadConfigFile(fs *flag.FlagSet, path string) error {
+	settings, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, value := range settings {
+		if name == configFileFlag || fs.Lookup(name) == nil {
+			return util.Errorf("config file %s: unknown setting %q", path, name)
+		}
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return util.Errorf("config file %s: invalid value %q for %s: %s", path, value, name, err)
+		}
+	}
+	return nil
+}
+
+// readConfigFile returns the flag values of the TOML or YAML file at
+// path, keyed by flag name.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var settings map[string]string
+	switch ext := filepath.Ext(path); ext {
+	case ".toml":
+		settings, err = parseTOMLConfig(data)
+	case ".yaml", ".yml":
+		settings, err = parseYAMLConfig(data)
+	default:
+		return nil, util.Errorf("config file %s: unknown format %q; use .toml, .yaml or .yml", path, ext)
+	}
+	if err != nil {
+		return nil, util.Errorf("config file %s: %s", path, err)
+	}
+	return settings, nil
+}
+
+// parseYAMLConfig parses a YAML mapping of flag names to scalar
+// values.
+func parseYAMLConfig(data []byte) (map[string]string, error) {
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return scalarSettings(m)
+}
+
+// parseTOMLConfig parses TOML key/value pairs of flag names to scalar
+// values, outside of any table.
+func parseTOMLConfig(data []byte) (map[string]string, error) {
+	var m map[string]interface{}
+	if _, err := toml.Decode(string(data), &m); err != nil {
+		return nil, err
+	}
+	return scalarSettings(m)
+}
+
+// scalarSettings formats the decoded values of a config file as they
+// would be given on the command line, returning an error if any isn't
+// a scalar.
+func scalarSettings(m map[string]interface{}) (map[string]string, error) {
+	settings := map[string]string{}
+	for name, v := range m {
+		switch v.(type) {
+		case string, bool, int, int64, float64:
+			settings[name] = fmt.Sprint(v)
+		default:
+			return nil, util.Errorf("setting %q must be a scalar value", name)
+		}
+	}
+	return settings, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/server"
+)
+
+// TestLoadConfigFile verifies that flags are set from TOML and YAML
+// config files, and that flags set on the command line take
+// precedence over the file's values.
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name, body string
+	}{
+		{"test.toml", `# Base config.
+addr = "localhost:26257" # RPC and HTTP
+stores = 'ssd=/mnt/ssd1'
+cache-size = 2147483648
+max-offset = "500ms"
+linearizable = true
+`},
+		{"test.yaml", `addr: localhost:26257
+stores: ssd=/mnt/ssd1
+cache-size: 2147483648
+max-offset: 500ms
+linearizable: true
+`},
+	}
+	for _, test := range testCases {
+		path := filepath.Join(dir, test.name)
+		if err := ioutil.WriteFile(path, []byte(test.body), 0600); err != nil {
+			t.Fatal(err)
+		}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		addr := fs.String("addr", ":8080", "")
+		stores := fs.String("stores", "", "")
+		cacheSize := fs.Int64("cache-size", 0, "")
+		maxOffset := fs.Duration("max-offset", 0, "")
+		linearizable := fs.Bool("linearizable", false, "")
+		if err := fs.Parse([]string{"-stores=hdd=/mnt/hdd1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := loadConfigFile(fs, path); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if *addr != "localhost:26257" || *cacheSize != 2147483648 || *maxOffset != 500*time.Millisecond ||
+			!*linearizable {
+			t.Errorf("%s: expected flags to be set from file; got %s, %d, %s, %t",
+				test.name, *addr, *cacheSize, *maxOffset, *linearizable)
+		}
+		if *stores != "hdd=/mnt/hdd1" {
+			t.Errorf("%s: expected command line flag to override file; got %s", test.name, *stores)
+		}
+	}
+
+	for i, body := range []string{
+		"unknown = 1\n",
+		"cache-size = \"lots\"\n",
+		"[server]\naddr = \"localhost:26257\"\n",
+		"stores = [\"ssd=/mnt/ssd1\"]\n",
+		"addr = \"localhost:26257\" extra\n",
+		"addr\n",
+	} {
+		path := filepath.Join(dir, "invalid.toml")
+		if err := ioutil.WriteFile(path, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("addr", "", "")
+		fs.String("stores", "", "")
+		fs.Int64("cache-size", 0, "")
+		if err := loadConfigFile(fs, path); err == nil {
+			t.Errorf("%d: expected error loading %q", i, body)
+		}
+	}
+	path := filepath.Join(dir, "test.json")
+	if err := ioutil.WriteFile(path, []byte(`{"addr": "localhost:26257"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfigFile(flag.NewFlagSet("test", flag.ContinueOnError), path); err == nil {
+		t.Error("expected error loading file of unknown format")
+	}
+}
+
+// TestApplyEnv verifies that flags are set from the environment
+// variables named after them, that flags set on the command line take
+// precedence over the environment, and that the environment takes
+// precedence over the config file.
+func TestApplyEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.toml")
+	if err := ioutil.WriteFile(path, []byte("gossip = \"file:1\"\nmax-offset = \"1s\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(configFile string) { Context.ConfigFile = configFile }(Context.ConfigFile)
+	Context.ConfigFile = path
+	defer func() {
+		for name := range envFlags {
+			delete(envFlags, name)
+			delete(commandLineFlags, name)
+		}
+		delete(commandLineFlags, "addr")
+	}()
+
+	env := map[string]string{
+		"COCKROACH_ADDR":            "env:1",
+		"COCKROACH_STORES":          "mem=1GiB",
+		"COCKROACH_GOSSIP":          "env:2",
+		"COCKROACH_SCAN_INTERVAL":   "1m",
+		"COCKROACH_UNKNOWN_SETTING": "ignored",
+	}
+	var addr, stores, gossip string
+	var maxOffset, scanInterval time.Duration
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&addr, "addr", "", "")
+	fs.StringVar(&stores, "stores", "", "")
+	fs.StringVar(&gossip, "gossip", "", "")
+	fs.DurationVar(&maxOffset, "max-offset", 0, "")
+	fs.DurationVar(&scanInterval, "scan-interval", 0, "")
+	if err := applyEnv(fs, func(name string) string { return env[name] }); err != nil {
+		t.Fatal(err)
+	}
+	// As the command's flag set is a copy of the global one, the
+	// environment is applied to a different flag set than the one the
+	// command line is parsed into.
+	cmdFS := *fs
+	cmdFS.Init("cmd", flag.ContinueOnError)
+	if err := cmdFS.Parse([]string{"-addr=flag:1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFile(&cmdFS); err != nil {
+		t.Fatal(err)
+	}
+	if addr != "flag:1" || stores != "mem=1GiB" || gossip != "env:2" || maxOffset != time.Second || scanInterval != time.Minute {
+		t.Errorf("unexpected flag values: addr=%s stores=%s gossip=%s max-offset=%s scan-interval=%s",
+			addr, stores, gossip, maxOffset, scanInterval)
+	}
+	if !commandLineFlags["gossip"] || commandLineFlags["max-offset"] {
+		t.Errorf("expected only command line and environment flags to override reloads; got %v", commandLineFlags)
+	}
+
+	env = map[string]string{"COCKROACH_SCAN_INTERVAL": "soon"}
+	if err := applyEnv(fs, func(name string) string { return env[name] }); err == nil {
+		t.Error("expected error applying invalid environment value")
+	}
+}
+
+// TestReloadConfigFile verifies that a running node's reloadable
+// settings are read from its config file, that flags set on the
+// command line keep overriding the file, and that settings which need
+// a restart are ignored.
+func TestReloadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.yaml")
+	body := "addr: localhost:26257\nscan-interval: 1m\ngossip-interval: 3s\ncache-size: 512MiB\ntrace-sample-rate: 0.5\nv: 2\n"
+	if err := ioutil.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(configFile string) { Context.ConfigFile = configFile }(Context.ConfigFile)
+	Context.ConfigFile = path
+	commandLineFlags["gossip-interval"] = true
+	defer delete(commandLineFlags, "gossip-interval")
+
+	current := server.ReloadableSettings{
+		ScanInterval:   10 * time.Minute,
+		GossipInterval: 2 * time.Second,
+		CacheSize:      1 << 30,
+	}
+	settings, err := reloadConfigFile(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := current
+	expected.ScanInterval = time.Minute
+	expected.CacheSize = 512 << 20
+	expected.TraceSampleRate = 0.5
+	expected.Verbosity = 2
+	if settings != expected {
+		t.Errorf("expected settings %+v; got %+v", expected, settings)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("unknown: 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloadConfigFile(current); err == nil {
+		t.Error("expected error reloading unknown setting")
+	}
+	Context.ConfigFile = ""
+	if _, err := reloadConfigFile(current); err == nil {
+		t.Error("expected error reloading without a config file")
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	commander "code.google.com/p/go-commander"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// debugCmds are the commands which inspect and repair the data
+// directories of stopped nodes. Each takes the store's data directory
+// or, for striped or encrypted stores, its store specification as
+// given to -stores. A specification with read_only=true opens the
+// store without accepting writes or taking its lock, for inspection
+// of a store whose node is down; commands which only read open stores
+// read-only regardless.
+var debugCmds = &commander.Commander{
+	Name: "debug",
+	Commands: []*commander.Command{
+		debugNodeIDCmd,
+		debugKeysCmd,
+		debugRaftReplayCmd,
+		debugClearNodeIDCmd,
+	},
+}
+
+// A debugNodeIDCmd command prints the identity of a store.
+var debugNodeIDCmd = &commander.Command{
+	UsageLine: "node-id <store>",
+	Short:     "print the cluster, node and store IDs of a store",
+	Long: `
+Prints the IDs of the cluster, node and store to which the store
+belongs. The store is opened read-only.
+`,
+	Run:  runDebugNodeID,
+	Flag: *flag.CommandLine,
+}
+
+func runDebugNodeID(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	e, err := openStore(args[0], true)
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s\n", err)
+		osExit(1)
+		return
+	}
+	defer e.Close()
+	ident, pending, err := readStoreIdent(e)
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s\n", err)
+		osExit(1)
+		return
+	}
+	fmt.Printf("cluster-id: %s\nnode-id: %d\nstore-id: %d\n", ident.ClusterID, ident.NodeID, ident.StoreID)
+	if pending {
+		fmt.Printf("bootstrap of the cluster was interrupted\n")
+	}
+}
+
+// A debugKeysCmd command prints the keys of a store.
+var debugKeysCmd = &commander.Command{
+	UsageLine: "keys <store> [<start-key> [<end-key>]]",
+	Short:     "print the keys of a store and the sizes of their values",
+	Long: `
+Prints the raw, encoded keys of the store between the start key,
+inclusive, and the end key, exclusive, with the size of each value.
+Keys are quoted with Go escape sequences. The store is opened
+read-only.
+`,
+	Run:  runDebugKeys,
+	Flag: *flag.CommandLine,
+}
+
+func runDebugKeys(cmd *commander.Command, args []string) {
+	if len(args) < 1 || len(args) > 3 {
+		cmd.Usage()
+		return
+	}
+	start, end := proto.EncodedKey(engine.KeyMin), proto.EncodedKey(engine.KeyMax)
+	if len(args) > 1 {
+		start = proto.EncodedKey(args[1])
+	}
+	if len(args) > 2 {
+		end = proto.EncodedKey(args[2])
+	}
+	e, err := openStore(args[0], true)
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s\n", err)
+		osExit(1)
+		return
+	}
+	defer e.Close()
+	if err := e.Iterate(start, end, func(kv proto.RawKeyValue) (bool, error) {
+		fmt.Printf("%q: %d bytes\n", kv.Key, len(kv.Value))
+		return false, nil
+	}); err != nil {
+		fmt.Fprintf(osStderr, "store %s: unable to iterate: %s\n", e, err)
+		osExit(1)
+	}
+}
+
+// A debugRaftReplayCmd command replays the raft log of a range.
+var debugRaftReplayCmd = &commander.Command{
+	UsageLine: "raft-replay <store> <raft-id>",
+	Short:     "replay the raft log of a range in a sandbox",
+	Long: `
+Applies the raft log of the range held by the store to a fresh replica
+of the range in memory, printing the changes to the replica's raw,
+encoded keys made by each entry: "+" for a key written, "-" for a key
+deleted and "~" for a key whose value changed, followed by the key and
+its new value quoted with Go escape sequences. Replays are
+deterministic, so the replays of the logs of two replicas may be
+compared to find the entry at which they diverged. Entries truncated
+from the log are not replayed, so commands depending on them may fail
+where they succeeded originally. The store is opened read-only.
+`,
+	Run:  runDebugRaftReplay,
+	Flag: *flag.CommandLine,
+}
+
+func runDebugRaftReplay(cmd *commander.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	raftID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		fmt.Fprintf(osStderr, "invalid raft ID %q: %s\n", args[1], err)
+		osExit(1)
+		return
+	}
+	e, err := openStore(args[0], true)
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s\n", err)
+		osExit(1)
+		return
+	}
+	defer e.Close()
+	if err := storage.ReplayRaftLog(e, raftID, printReplayedEntry); err != nil {
+		fmt.Fprintf(osStderr, "store %s: unable to replay range %d: %s\n", e, raftID, err)
+		osExit(1)
+	}
+}
+
+// printReplayedEntry prints the command applied by a replayed raft log
+// entry, its error, if any, and its changes.
+func printReplayedEntry(re storage.ReplayedEntry) error {
+	method := "closed timestamp"
+	if args, ok := re.Cmd.Cmd.GetValue().(proto.Request); ok {
+		method = args.Method().String()
+	} else if len(re.Cmd.Coalesced) > 0 {
+		var methods []string
+		for _, c := range re.Cmd.Coalesced {
+			if args, ok := c.Cmd.Cmd.GetValue().(proto.Request); ok {
+				methods = append(methods, args.Method().String())
+			}
+		}
+		method = fmt.Sprintf("coalesced [%s]", strings.Join(methods, ", "))
+	}
+	fmt.Printf("%d: %s\n", re.Index, method)
+	if re.Err != nil {
+		fmt.Printf("  error: %s\n", re.Err)
+	}
+	for _, d := range re.Diffs {
+		switch {
+		case d.Old == nil:
+			fmt.Printf("  + %q: %q\n", d.Key, d.New)
+		case d.New == nil:
+			fmt.Printf("  - %q\n", d.Key)
+		default:
+			fmt.Printf("  ~ %q: %q\n", d.Key, d.New)
+		}
+	}
+	return nil
+}
+
+// A debugClearNodeIDCmd command clears the identity of a store.
+var debugClearNodeIDCmd = &commander.Command{
+	UsageLine: "clear-node-id <store> <cluster-id>",
+	Short:     "clear the identity and data of a store for reuse",
+	Long: `
+Clears the identity of the store in the data directory, along with all
+of its data, so that the directory may be reused by a node of another
+cluster, for example in a test environment. As a guard against
+clearing the wrong store, the ID of the cluster to which the store
+belongs must be given, as printed by "debug node-id". The node using
+the store must be stopped, and the store may not be read-only.
+`,
+	Run:  runDebugClearNodeID,
+	Flag: *flag.CommandLine,
+}
+
+func runDebugClearNodeID(cmd *commander.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	e, err := openStore(args[0], false)
+	if err != nil {
+		fmt.Fprintf(osStderr, "%s\n", err)
+		osExit(1)
+		return
+	}
+	defer e.Close()
+	if err := clearStoreIdent(e, args[1]); err != nil {
+		fmt.Fprintf(osStderr, "%s\n", err)
+		osExit(1)
+		return
+	}
+	fmt.Printf("cleared store %s of cluster %s\n", args[0], args[1])
+}
+
+// openStore opens the existing store given by arg, either its data
+// directory or its store specification. If readOnly is set, the store
+// is opened read-only; otherwise the specification may not be
+// read-only. Opening a store which isn't read-only fails if it's in
+// use by a running node.
+func openStore(arg string, readOnly bool) (engine.Engine, error) {
+	spec, err := server.ParseStoreSpec(arg)
+	if err != nil {
+		if strings.Contains(arg, "=") || strings.Contains(arg, "://") {
+			return nil, err
+		}
+		spec = server.StoreSpec{Location: "rocksdb://" + arg}
+	}
+	if spec.ReadOnly && !readOnly {
+		return nil, util.Errorf("store %q is read-only", spec.Location)
+	}
+	spec.ReadOnly = readOnly
+	return server.OpenStore(spec, Context.StoreKeyFile)
+}
+
+// readStoreIdent returns the identity of the store held by the engine
+// and whether the bootstrap of its cluster was interrupted. An error
+// is returned if the store was never bootstrapped.
+func readStoreIdent(e engine.Engine) (ident proto.StoreIdent, pending bool, err error) {
+	ok, err := engine.MVCCGetProto(e, engine.StoreIdentKey(), proto.ZeroTimestamp, true, nil, &ident)
+	if err != nil {
+		return ident, false, util.Errorf("store %s: unable to read identity: %s", e, err)
+	}
+	if !ok {
+		return ident, false, util.Errorf("store %s has not been bootstrapped", e)
+	}
+	var bootstrapIdent proto.StoreIdent
+	if pending, err = engine.MVCCGetProto(e, engine.StoreBootstrapKey(), proto.ZeroTimestamp, true, nil, &bootstrapIdent); err != nil {
+		return ident, false, util.Errorf("store %s: unable to read bootstrap state: %s", e, err)
+	}
+	return ident, pending, nil
+}
+
+// clearStoreIdent clears the contents of the engine, including the
+// store's identity, if the store belongs to the cluster with the
+// supplied ID. The contents are cleared in batches, as a store may
+// hold more data than fits in memory; the identity is cleared last so
+// that an interrupted clear may be repeated.
+func clearStoreIdent(e engine.Engine, clusterID string) error {
+	ident, _, err := readStoreIdent(e)
+	if err != nil {
+		return err
+	}
+	if ident.ClusterID != clusterID {
+		return util.Errorf("store %s belongs to cluster %s, not %s", e, ident.ClusterID, clusterID)
+	}
+	identKey := engine.MVCCEncodeKey(engine.StoreIdentKey())
+	bw := engine.NewBatchWriter(e, 0, nil)
+	if err := e.Iterate(proto.EncodedKey(engine.KeyMin), proto.EncodedKey(engine.KeyMax), func(kv proto.RawKeyValue) (bool, error) {
+		if bytes.Equal(kv.Key, identKey) {
+			return false, nil
+		}
+		return false, bw.Clear(kv.Key)
+	}); err != nil {
+		return util.Errorf("store %s: unable to clear: %s", e, err)
+	}
+	if err := bw.Flush(); err != nil {
+		return util.Errorf("store %s: unable to clear: %s", e, err)
+	}
+	if err := e.Clear(identKey); err != nil {
+		return util.Errorf("store %s: unable to clear identity: %s", e, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+)
+
+// TestClearStoreIdent verifies that a store's identity is read and that
+// it's only cleared given the ID of the store's cluster.
+func TestClearStoreIdent(t *testing.T) {
+	e := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	if _, _, err := readStoreIdent(e); err == nil {
+		t.Error("expected error reading identity of unbootstrapped store")
+	}
+
+	ident := proto.StoreIdent{ClusterID: "cluster", NodeID: 2, StoreID: 3}
+	if err := engine.MVCCPutProto(e, nil, engine.StoreIdentKey(), proto.ZeroTimestamp, nil, &ident); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.MVCCPutProto(e, nil, proto.Key("a"), proto.ZeroTimestamp, nil, &ident); err != nil {
+		t.Fatal(err)
+	}
+	readIdent, pending, err := readStoreIdent(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readIdent.ClusterID != ident.ClusterID || readIdent.NodeID != ident.NodeID ||
+		readIdent.StoreID != ident.StoreID || pending {
+		t.Errorf("expected identity %+v; got %+v, pending %t", ident, readIdent, pending)
+	}
+
+	if err := clearStoreIdent(e, "other-cluster"); err == nil {
+		t.Error("expected error clearing store of another cluster")
+	}
+	if err := clearStoreIdent(e, "cluster"); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := engine.Scan(e, proto.EncodedKey(engine.KeyMin), proto.EncodedKey(engine.KeyMax), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 0 {
+		t.Errorf("expected cleared store to be empty; got %d keys", len(kvs))
+	}
+}
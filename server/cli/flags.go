@@ -19,27 +19,93 @@ package cli
 
 import (
 	"flag"
+	"strconv"
+	"strings"
 
 	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
 )
 
+// bytesValue is a flag.Value for a number of bytes, which may be given
+// with a suffix or as a percentage of system memory; see
+// util.ParseBytes.
+type bytesValue struct {
+	val *int64
+}
+
+func (b bytesValue) String() string {
+	if b.val == nil {
+		return "0"
+	}
+	return strconv.FormatInt(*b.val, 10)
+}
+
+func (b bytesValue) Set(s string) error {
+	n, err := util.ParseBytes(s)
+	if err != nil {
+		return err
+	}
+	*b.val = n
+	return nil
+}
+
 // initFlags sets the server.Context values to flag values.
 // Keep in sync with "server/context.go". Values in Context should be
 // settable here.
 func initFlags(ctx *server.Context) {
 	// Server flags.
 	flag.StringVar(&ctx.Addr, "addr", ctx.Addr, "when run as the server the host:port to bind for "+
-		"HTTP/RPC traffic; when run as the client the address for connection to the cockroach cluster.")
+		"HTTP/RPC traffic; when run as the client the address for connection to the cockroach cluster. "+
+		"A unix socket may be given by its path, as in unix:///tmp/cockroach.sock.")
+
+	flag.StringVar(&ctx.HTTPAddr, "http-addr", ctx.HTTPAddr, "when run as the server the host:port to "+
+		"bind for HTTP traffic, if it should be served separately from RPC traffic on -addr, and listed at "+
+		"/_status/topology with the host of -advertise-addr, if set; when run as the client the address "+
+		"for HTTP connections to the node, defaulting to -addr.")
+
+	flag.StringVar(&ctx.AdvertiseAddr, "advertise-addr", ctx.AdvertiseAddr, "the host:port at which "+
+		"other nodes reach this one, if it differs from -addr; e.g. for nodes which bind 0.0.0.0 "+
+		"behind NAT or in containers. It's gossiped in the node's descriptor and used for "+
+		"self:// gossip bootstrap addresses.")
 
 	flag.StringVar(&ctx.Certs, "certs", ctx.Certs, "directory containing RSA key and x509 certs.")
 
+	flag.StringVar(&ctx.ConfigFile, configFileFlag, ctx.ConfigFile, "TOML (.toml) or YAML (.yaml, .yml) "+
+		"file holding the values of other flags, keyed by flag name, e.g. cache-size = 2147483648. "+
+		"Flags set on the command line override the file's values.")
+
 	flag.StringVar(&ctx.Stores, "stores", ctx.Stores, "specify a comma-separated list of stores, "+
 		"specified by a colon-separated list of device attributes followed by '=' and "+
-		"either a filepath for a persistent store or an integer size in bytes for an "+
-		"in-memory store. Device attributes typically include whether the store is "+
+		"either a filepath for a persistent store or a size in bytes for an "+
+		"in-memory store, e.g. 1073741824, 512MiB or 10%. Device attributes typically include whether the store is "+
 		"flash (ssd), spinny disk (hdd), fusion-io (fio), in-memory (mem); device "+
 		"attributes might also include speeds and other specs (7200rpm, 200kiops, etc.). "+
-		"For example, -store=hdd:7200rpm=/mnt/hda1,ssd=/mnt/ssd01,ssd=/mnt/ssd02,mem=1073741824.")
+		"For example, -store=hdd:7200rpm=/mnt/hda1,ssd=/mnt/ssd01,ssd=/mnt/ssd02,mem=1073741824. "+
+		"The engine type may be selected with a scheme prefix, e.g. ssd=rocksdb:///mnt/ssd01, "+
+		"and cache and maxsize options may follow the attributes, e.g. ssd,cache=2GiB=/mnt/ssd01. "+
+		"Stores may also be specified as URLs with attrs, cache and maxsize parameters, e.g. "+
+		"rocksdb:///mnt/ssd01?attrs=ssd&cache=2GiB&maxsize=80%, or as locations with a type "+
+		"parameter, e.g. /mnt/ssd01?type=rocksdb&attrs=ssd. Locations holding commas, '=' or '?' "+
+		"may be double-quoted, e.g. ssd=\"/mnt/ssd,01\". A store with an encrypt option or parameter, "+
+		"e.g. ssd,encrypt=key1=/mnt/ssd01, encrypts its files with that key of -store-key-file. "+
+		"Persistent stores given without attributes are labelled with those detected for their "+
+		"devices on Linux: ssd or hdd, and the name of the file system, e.g. ext4. The RocksDB "+
+		"options of a store may be tuned with compression, write_buffer, compaction_threads and "+
+		"bloom_bits options or parameters, e.g. ssd,compression=snappy,write_buffer=128MiB=/mnt/ssd01, "+
+		"overriding the -store-* flags of the same names. The provisioned throughput of a store's "+
+		"device may be declared with bw and iops options, e.g. ssd,bw=125MiB/s,iops=3000=/mnt/ssd01, "+
+		"from which its compactions and snapshots are paced instead of by -snapshot-apply-rate. "+
+		"A persistent store may spread its sstables across further directories, e.g. those of "+
+		"several small SSDs, by separating them with '+', e.g. ssd=/mnt/ssd01+/mnt/ssd02. "+
+		"A read_only option or parameter opens an existing store without accepting writes; only "+
+		"the debug commands, which also accept store specifications, may open read-only stores.")
+
+	flag.StringVar(&ctx.StoreKeyFile, "store-key-file", ctx.StoreKeyFile, "file holding the AES keys "+
+		"of encrypted stores, one per line as an ID and 16, 24 or 32 hex-encoded bytes. To rotate a "+
+		"store's key, add a key to the file and select it in the store's encrypt option; files "+
+		"encrypted with the old key are rewritten in the background, after which the store's "+
+		"capacity no longer reports it rotating and the old key may be removed.")
 
 	flag.StringVar(&ctx.Attrs, "attrs", ctx.Attrs, "specify an ordered, colon-separated list of node "+
 		"attributes. Attributes are arbitrary strings specifying topography or "+
@@ -58,31 +124,189 @@ func initFlags(ctx *server.Context) {
 		"of -max-offset, it will commit suicide. Setting this value too high may "+
 		"decrease transaction performance in the presence of contention.")
 
+	flag.StringVar(&ctx.SystemZone, "system-zone", ctx.SystemZone, "YAML file holding the zone "+
+		"config, in the format accepted by set-zone, of the system ranges, which hold the range "+
+		"addressing records and the cluster's configs. Applied by init, it allows them to be "+
+		"replicated more widely or on other devices than user data; if not set, they share the "+
+		"default zone.")
+
 	// Gossip flags.
 	flag.StringVar(&ctx.GossipBootstrap, "gossip", ctx.GossipBootstrap, "specify a "+
 		"comma-separated list of gossip addresses or resolvers for gossip bootstrap. "+
 		"Each item in the list has an optional type: [type=]<address>. "+
 		"Unspecified type means ip address or dns. Type can also be a load balancer (\"lb\"), "+
-		"a unix socket (\"unix\") or, for single-node systems, \"self\".")
+		"a unix socket (\"unix\"), a host name whose addresses are each tried and periodically "+
+		"re-resolved, as for a headless service (\"dns\"), the key=value[:port] tag or label of EC2 "+
+		"or GCE instances whose private addresses are each tried and periodically looked up again "+
+		"(\"aws-tag\", \"gce-label\"), or, for single-node systems, \"self\".")
 
 	flag.DurationVar(&ctx.GossipInterval, "gossip-interval", ctx.GossipInterval,
 		"approximate interval (time.Duration) for gossiping new information to peers.")
 
+	flag.IntVar(&ctx.GossipMaxOutgoing, "gossip-max-outgoing", ctx.GossipMaxOutgoing, "maximum number "+
+		"of outgoing gossip connections, the target fan-out of the gossip network. Larger clusters "+
+		"may raise it to propagate information over fewer hops at the cost of more connections.")
+
+	flag.IntVar(&ctx.GossipMaxIncoming, "gossip-max-incoming", ctx.GossipMaxIncoming, "maximum number "+
+		"of incoming gossip connections; further peers are redirected to connected ones.")
+
 	// KV flags.
 
 	flag.BoolVar(&ctx.Linearizable, "linearizable", ctx.Linearizable, "enables linearizable behaviour "+
 		"of operations on this node by making sure that no commit timestamp is reported "+
 		"back to the client until all other node clocks have necessarily passed it.")
 
+	flag.BoolVar(&ctx.PipelineWrites, "pipeline-writes", ctx.PipelineWrites, "enables pipelining "+
+		"of transactional writes, which are acknowledged before they achieve consensus; "+
+		"only the commit waits for them, reducing transaction latency over WAN replication.")
+
+	flag.IntVar(&ctx.MaxBatchRequests, "max-batch-requests", ctx.MaxBatchRequests, "maximum number "+
+		"of requests in a batch; larger batches are rejected with a retryable error asking the "+
+		"client to split them. 0 is unlimited.")
+
+	flag.Int64Var(&ctx.MaxBatchBytes, "max-batch-bytes", ctx.MaxBatchBytes, "maximum size in bytes "+
+		"of the requests in a batch; larger batches are rejected with a retryable error asking the "+
+		"client to split them. 0 is unlimited.")
+
+	flag.IntVar(&ctx.MaxClientRequestsPerUser, "max-client-requests-per-user", ctx.MaxClientRequestsPerUser,
+		"maximum number of key-value and structured data requests in flight for each user "+
+			"authenticated by a client certificate. 0 is unlimited.")
+
+	flag.IntVar(&ctx.MaxClientRequestsPerHost, "max-client-requests-per-host", ctx.MaxClientRequestsPerHost,
+		"maximum number of key-value and structured data requests in flight from each client host. "+
+			"0 is unlimited.")
+
+	flag.DurationVar(&ctx.ClientQueueTimeout, "client-queue-timeout", ctx.ClientQueueTimeout,
+		"time a client request beyond -max-client-requests-per-user or -max-client-requests-per-host "+
+			"waits for one in flight to finish before it's refused. 0 waits indefinitely.")
+
+	flag.DurationVar(&ctx.HedgeReadTimeout, "hedge-read-timeout", ctx.HedgeReadTimeout,
+		"time after which a read-only request which hasn't received a reply is also sent to the next "+
+			"replica, reducing the tail latency caused by a slow node. 0 selects 1s.")
+
+	flag.Float64Var(&ctx.RetryBudgetRatio, "retry-budget-ratio", ctx.RetryBudgetRatio,
+		"ratio of retries of failed RPCs to requests sent beyond which the node stops retrying, "+
+			"so that retries don't add to the load of an overloaded cluster. 0 disables the budget.")
+
+	flag.IntVar(&ctx.RetryBudgetMaxRetries, "retry-budget-max-retries", ctx.RetryBudgetMaxRetries,
+		"burst of retries permitted by -retry-budget-ratio. 0 selects 10.")
+
 	// Engine flags.
 
-	flag.Int64Var(&ctx.CacheSize, "cache-size", ctx.CacheSize, "total size in bytes for "+
-		"caches, shared evenly if there are multiple storage devices.")
+	flag.Var(bytesValue{&ctx.CacheSize}, "cache-size", "total size in bytes for "+
+		"caches, e.g. 2147483648, 512MiB, 2GB or 10% of system memory. What remains after the caches of stores which set their own, e.g. "+
+		"ssd,cache=2GiB=/mnt/ssd01, is shared evenly between the other stores.")
+
+	flag.StringVar(&ctx.StoreTuning.Compression, "store-compression", ctx.StoreTuning.Compression,
+		"algorithm with which stores compress their sstables: "+strings.Join(engine.CompressionTypes, ", ")+
+			". Empty selects snappy.")
+
+	flag.Var(bytesValue{&ctx.StoreTuning.WriteBufferSize}, "store-write-buffer", "size in bytes of "+
+		"the buffer in which each store holds writes before flushing them to an sstable, e.g. 128MiB; "+
+		"0 selects 64MiB. Larger buffers absorb bursts of writes at the cost of memory and restart time.")
+
+	flag.IntVar(&ctx.StoreTuning.CompactionThreads, "store-compaction-threads", ctx.StoreTuning.CompactionThreads,
+		"number of sstable compactions each store may run at once; 0 selects 1.")
+
+	flag.IntVar(&ctx.StoreTuning.BloomFilterBits, "store-bloom-bits", ctx.StoreTuning.BloomFilterBits, "bits "+
+		"per key of the bloom filters with which stores skip sstables without a key when reading; "+
+		"0 builds no filters. 10 bits give a false positive rate of about 1%.")
 
 	flag.DurationVar(&ctx.ScanInterval, "scan-interval", ctx.ScanInterval, "specify "+
 		"--scan_interval to adjust the target for the duration of a single scan "+
 		"through a store's ranges. The scan is slowed as necessary to approximately"+
 		"achieve this duration.")
+
+	flag.DurationVar(&ctx.ClosedTimestampLag, "closed-timestamp-lag", ctx.ClosedTimestampLag, "target lag "+
+		"behind the clock of the timestamps closed by range leaders, below which followers "+
+		"may serve bounded-staleness reads. Writes proposed at or below a range's closed "+
+		"timestamp are moved above it; 0 disables closing timestamps.")
+
+	flag.Int64Var(&ctx.SnapshotApplyRate, "snapshot-apply-rate", ctx.SnapshotApplyRate, "bytes of "+
+		"snapshot data per second each store admits for application, so that rebalancing "+
+		"doesn't compete with foreground writes; 0 leaves snapshots unthrottled. Stores which "+
+		"declare their provisioned bw or iops admit a quarter of that throughput instead.")
+
+	flag.DurationVar(&ctx.TxnAbandonTimeout, "txn-abandon-timeout", ctx.TxnAbandonTimeout, "time "+
+		"after its last heartbeat at which a pending transaction is considered abandoned by its "+
+		"coordinator and aborted, with its intents resolved, by range GC; 0 disables aborting "+
+		"abandoned transactions.")
+
+	flag.Float64Var(&ctx.TraceSampleRate, "trace-sample-rate", ctx.TraceSampleRate, "fraction of "+
+		"commands, between 0 and 1, which are traced. The most recent traces, with the steps "+
+		"of each command and their timings, are served at /_status/local/traces; 0 disables tracing.")
+
+	flag.DurationVar(&ctx.StoreIOProbeInterval, "store-io-probe-interval", ctx.StoreIOProbeInterval, "interval "+
+		"at which the sync and read latencies of each store's device are probed. A store whose probes "+
+		"repeatedly exceed -store-max-sync-latency or -store-max-read-latency is marked suspect in "+
+		"gossip, receives no new replicas and transfers its leader leases away; 0 disables probing.")
+
+	flag.DurationVar(&ctx.StoreMaxSyncLatency, "store-max-sync-latency", ctx.StoreMaxSyncLatency,
+		"longest a store's device may take to sync a small write before the store is suspect.")
+
+	flag.DurationVar(&ctx.StoreMaxReadLatency, "store-max-read-latency", ctx.StoreMaxReadLatency,
+		"longest a store's engine may take to serve a read before the store is suspect.")
+
+	flag.DurationVar(&ctx.StoreScrubInterval, "store-scrub-interval", ctx.StoreScrubInterval, "interval "+
+		"at which the block checksums of each store's sstables are verified. A store holding corrupt "+
+		"data is logged with the affected key ranges and marked suspect in gossip, so that it receives "+
+		"no new replicas and transfers its leader leases away; 0 disables scrubbing.")
+
+	flag.Int64Var(&ctx.StoreMinAvailable, "store-min-available", ctx.StoreMinAvailable, "free space, "+
+		"in bytes, below which a persistent store is nearly full: it's marked as such in gossip, "+
+		"receives no new replicas and refuses writes other than deletions, garbage collection and "+
+		"raft log truncation until space is freed. A new store is refused at startup if less is "+
+		"available; 0 disables the check.")
+
+	flag.Int64Var(&ctx.StoreBallastSize, "store-ballast-size", ctx.StoreBallastSize, "size, in bytes, "+
+		"of the BALLAST file kept in the data directory of each persistent store. Deleting the file "+
+		"frees space for a store whose device filled up; it's recreated once -store-min-available "+
+		"bytes would remain free. 0 disables the ballast.")
+
+	flag.IntVar(&ctx.ScanPrefetchSize, "scan-prefetch-size", ctx.ScanPrefetchSize, "number of bytes "+
+		"read ahead in the background by scans of whole ranges, such as those of the range scanner "+
+		"and of snapshot generation, on stores with the hdd attribute; 0 disables prefetching.")
+
+	flag.DurationVar(&ctx.OverloadDumpInterval, "overload-dump-interval", ctx.OverloadDumpInterval,
+		"minimum interval between the captures of the goroutine stacks and heap profile written to "+
+			"the log directory when the node is overloaded, as detected by -overload-latency and "+
+			"-overload-goroutines; 0 disables the captures.")
+
+	flag.DurationVar(&ctx.OverloadLatency, "overload-latency", ctx.OverloadLatency, "mean latency "+
+		"of the commands executed by the node over ten seconds above which it's overloaded.")
+
+	flag.IntVar(&ctx.OverloadGoroutines, "overload-goroutines", ctx.OverloadGoroutines, "number of "+
+		"goroutines above which the node is overloaded.")
+
+	flag.IntVar(&ctx.ReadCacheSize, "read-cache-size", ctx.ReadCacheSize, "number of read-hot keys "+
+		"whose values each store caches, as read by range leaders for consistent, non-transactional "+
+		"gets. A cached value is invalidated by any write to its range; 0 disables caching.")
+
+	flag.StringVar(&ctx.ReplicateTo, "replicate-to", ctx.ReplicateTo, "host:port of a node of a standby "+
+		"cluster to which the committed writes to the keys with the prefixes of -replicate-prefixes are "+
+		"shipped asynchronously, for disaster recovery. See \"cockroach cutover\".")
+
+	flag.StringVar(&ctx.ReplicatePrefixes, "replicate-prefixes", ctx.ReplicatePrefixes, "comma-separated "+
+		"list of the key prefixes whose writes are shipped to the standby cluster of -replicate-to.")
+
+	flag.StringVar(&ctx.ReplicateCerts, "replicate-certs", ctx.ReplicateCerts, "directory containing "+
+		"the certs used to connect to the standby cluster of -replicate-to; -certs if empty.")
+
+	flag.DurationVar(&ctx.ReplicateInterval, "replicate-interval", ctx.ReplicateInterval, "interval "+
+		"at which the committed writes to the replicated prefixes are shipped to the standby cluster, "+
+		"and at which a node started with -standby observes the progress of replication.")
+
+	flag.BoolVar(&ctx.Standby, "standby", ctx.Standby, "start the node as part of a standby cluster, "+
+		"to which a primary ships its writes with -replicate-to. Until the cluster is promoted with "+
+		"\"cockroach promote\", the node rejects writes and transactions and serves reads as of the "+
+		"timestamp at which it last observed the progress of replication.")
+
+	// TODO: decommission and relocate should honor -dry-run, reporting
+	// the replicas they would move, once those operations exist; they
+	// are left to a follow-up request.
+	flag.BoolVar(&ctx.DryRun, "dry-run", ctx.DryRun, "report what set-zone or restore would "+
+		"change, such as the replicas added and removed and the bytes copied to them, without "+
+		"changing it.")
 }
 
 func init() {
@@ -0,0 +1,110 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+
+	commander "code.google.com/p/go-commander"
+	"github.com/cockroachdb/cockroach/server"
+)
+
+// A lsJobsCmd command displays the records of all jobs.
+var lsJobsCmd = &commander.Command{
+	UsageLine: "ls-jobs [options]",
+	Short:     "list all jobs",
+	Long: `
+List all jobs along with their type, status, progress and, for failed
+jobs, the error which caused them to fail.
+`,
+	Run:  runLsJobs,
+	Flag: *flag.CommandLine,
+}
+
+// runLsJobs invokes the REST API with GET action and no path.
+func runLsJobs(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	server.RunLsJobs(Context)
+}
+
+// A getJobCmd command displays the record of a single job.
+var getJobCmd = &commander.Command{
+	UsageLine: "get-job [options] <job-id>",
+	Short:     "fetches and displays a job",
+	Long: `
+Fetches and displays the record of the job with ID <job-id>.
+`,
+	Run:  runGetJob,
+	Flag: *flag.CommandLine,
+}
+
+// runGetJob invokes the REST API with GET action and job ID as path.
+func runGetJob(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	server.RunGetJob(Context, args[0])
+}
+
+// A pauseJobCmd command pauses a queued or running job.
+var pauseJobCmd = &commander.Command{
+	UsageLine: "pause-job [options] <job-id>",
+	Short:     "pause a job",
+	Long: `
+Pauses the queued or running job with ID <job-id>. A running job
+stops the next time it reports its progress.
+`,
+	Run:  runPauseJob,
+	Flag: *flag.CommandLine,
+}
+
+// runPauseJob invokes the REST API with POST action and the job ID
+// and pause action as path.
+func runPauseJob(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	server.RunPauseJob(Context, args[0])
+}
+
+// A resumeJobCmd command resumes a paused job.
+var resumeJobCmd = &commander.Command{
+	UsageLine: "resume-job [options] <job-id>",
+	Short:     "resume a paused job\n",
+	Long: `
+Resumes the paused job with ID <job-id> on the node receiving the
+request. Backups and restores run in the process of the command which
+started them, so they can't be resumed; they're restarted by running
+the command again.
+`,
+	Run:  runResumeJob,
+	Flag: *flag.CommandLine,
+}
+
+// runResumeJob invokes the REST API with POST action and the job ID
+// and resume action as path.
+func runResumeJob(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	server.RunResumeJob(Context, args[0])
+}
@@ -39,7 +39,7 @@ var osExit = os.Exit
 var osStderr = os.Stderr
 
 func makeKVClient() (*client.KV, error) {
-	httpSender, err := client.NewHTTPSender(util.EnsureHost(Context.Addr), Context.Certs)
+	httpSender, err := client.NewHTTPSender(util.EnsureHost(Context.HTTPHost()), Context.Certs)
 	if err != nil {
 		return nil, err
 	}
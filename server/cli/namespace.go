@@ -0,0 +1,110 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+
+	commander "code.google.com/p/go-commander"
+	"github.com/cockroachdb/cockroach/server"
+)
+
+// A createNamespaceCmd command creates a namespace.
+var createNamespaceCmd = &commander.Command{
+	UsageLine: "create-namespace [options] <name>",
+	Short:     "create a namespace",
+	Long: `
+Creates a namespace called <name>, allocating it a key prefix which
+is displayed by ls-namespaces.
+`,
+	Run:  runCreateNamespace,
+	Flag: *flag.CommandLine,
+}
+
+// runCreateNamespace invokes the REST API with PUT action and the
+// namespace name as path.
+func runCreateNamespace(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	server.RunCreateNamespace(Context, args[0])
+}
+
+// A lsNamespacesCmd command displays all namespaces.
+var lsNamespacesCmd = &commander.Command{
+	UsageLine: "ls-namespaces [options]",
+	Short:     "list all namespaces",
+	Long: `
+List all namespaces along with their IDs and key prefixes.
+`,
+	Run:  runLsNamespaces,
+	Flag: *flag.CommandLine,
+}
+
+// runLsNamespaces invokes the REST API with GET action and no path.
+func runLsNamespaces(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	server.RunLsNamespaces(Context)
+}
+
+// A renameNamespaceCmd command renames a namespace.
+var renameNamespaceCmd = &commander.Command{
+	UsageLine: "rename-namespace [options] <name> <new-name>",
+	Short:     "rename a namespace",
+	Long: `
+Renames the namespace called <name> to <new-name>. The namespace keeps
+its key prefix, and so its keys.
+`,
+	Run:  runRenameNamespace,
+	Flag: *flag.CommandLine,
+}
+
+// runRenameNamespace invokes the REST API with POST action, the
+// namespace name and rename action as path and the new name as body.
+func runRenameNamespace(cmd *commander.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+		return
+	}
+	server.RunRenameNamespace(Context, args[0], args[1])
+}
+
+// A rmNamespaceCmd command drops a namespace.
+var rmNamespaceCmd = &commander.Command{
+	UsageLine: "rm-namespace [options] <name>",
+	Short:     "drop a namespace\n",
+	Long: `
+Drops the namespace called <name>. The name may be reused at once; the
+keys under its prefix are deleted in the background by a job, which
+is displayed by ls-jobs.
+`,
+	Run:  runRmNamespace,
+	Flag: *flag.CommandLine,
+}
+
+// runRmNamespace invokes the REST API with DELETE action and the
+// namespace name as path.
+func runRmNamespace(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	server.RunRmNamespace(Context, args[0])
+}
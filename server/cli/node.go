@@ -0,0 +1,92 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	commander "code.google.com/p/go-commander"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// nodeCmds are the commands which report on the nodes of a running
+// cluster.
+var nodeCmds = &commander.Commander{
+	Name: "node",
+	Commands: []*commander.Command{
+		nodeStatusCmd,
+	},
+}
+
+// A nodeStatusCmd command displays the replication report of the
+// cluster.
+var nodeStatusCmd = &commander.Command{
+	UsageLine: "status [options]",
+	Short:     "display the replicas and leases of the cluster's stores",
+	Long: `
+Displays, for each store of the cluster, its node, whether it's live in
+gossip, the number of replicas it holds and leader leases it holds, the
+bytes it uses and the key spans found corrupt by its most recent scrub,
+followed by the ranges which aren't fully replicated on live stores and
+their problems. The report is that of the node at
+-addr, as served by /_status/replication.
+`,
+	Run:  runNodeStatus,
+	Flag: *flag.CommandLine,
+}
+
+// runNodeStatus accesses the status replication path.
+func runNodeStatus(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	report, err := server.GetReplicationReport(Context)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	w := &tabwriter.Writer{}
+	w.Init(os.Stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintf(w, "store\tnode\tlive\treplicas\tleases\tbytes\n")
+	for _, s := range report.Stores {
+		fmt.Fprintf(w, "%d\t%d\t%t\t%d\t%d\t%d\n", s.StoreID, s.NodeID, s.Live, s.ReplicaCount, s.LeaseCount, s.Bytes)
+	}
+	w.Flush()
+	for _, s := range report.Stores {
+		for _, c := range s.Corruptions {
+			fmt.Printf("store %d: corrupt data in key range [%q, %q] of sstables %s: %s\n",
+				s.StoreID, c.Start, c.End, c.Files, c.Err)
+		}
+	}
+	if len(report.ProblemRanges) == 0 {
+		fmt.Println("\nall ranges fully replicated")
+		return
+	}
+	fmt.Printf("\n%d ranges with problems:\n", len(report.ProblemRanges))
+	w.Init(os.Stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintf(w, "range\tstart\tend\treplicas\tproblems\n")
+	for _, r := range report.ProblemRanges {
+		fmt.Fprintf(w, "%d\t%q\t%q\t%d/%d\t%s\n", r.RaftID, r.StartKey, r.EndKey, len(r.Replicas), r.WantReplicas,
+			strings.Join(r.Problems, ", "))
+	}
+	w.Flush()
+}
@@ -25,6 +25,7 @@ import (
 	commander "code.google.com/p/go-commander"
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	gogoproto "github.com/gogo/protobuf/proto"
 )
@@ -159,3 +160,51 @@ func runMergeRange(cmd *commander.Command, args []string) {
 		os.Exit(1)
 	}
 }
+
+// A checkMetaCmd command checks, and optionally repairs, the range
+// addressing records.
+var checkMetaCmd = &commander.Command{
+	UsageLine: "check-meta [options] [repair]",
+	Short:     "checks the range addressing records\n",
+	Long: `
+Cross-checks the meta1 and meta2 range addressing records against the
+descriptors of the ranges they address and lists the records which are
+missing, dangling (addressing no range) or stale (holding an outdated
+descriptor). If repair is specified, missing and stale records are
+rewritten from the range descriptors and dangling records are deleted.
+
+This is a recovery tool for clusters whose addressing records were left
+inconsistent, e.g. by partially applied splits.
+`,
+	Run:  runCheckMeta,
+	Flag: *flag.CommandLine,
+}
+
+func runCheckMeta(cmd *commander.Command, args []string) {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "repair") {
+		cmd.Usage()
+		return
+	}
+	repair := len(args) == 1
+
+	kv, err := makeKVClient()
+	if err != nil {
+		fmt.Fprintf(osStderr, "failed to initialize KV client: %s", err)
+		osExit(1)
+		return
+	}
+	problems, err := storage.CheckRangeAddressing(kv, repair)
+	if err != nil {
+		fmt.Fprintf(osStderr, "check failed: %s\n", err)
+		osExit(1)
+		return
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if repair {
+		fmt.Printf("repaired %d addressing records\n", len(problems))
+	} else {
+		fmt.Printf("found %d inconsistent addressing records\n", len(problems))
+	}
+}
@@ -20,8 +20,10 @@ package cli
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -50,12 +52,19 @@ range. If the storage location is already part of a pre-existing
 cluster, the bootstrap will fail.
 
 This command must be run before starting any nodes in the cluster.
+If it's interrupted, it may simply be run again with the same storage
+location.
 The storage location specified here must be used as a device in the
 -stores flag when starting this node in order to start the cluster.
 
+The system ranges, which hold the range addressing records and the
+cluster's configs, may be given a zone config of their own with the
+-system-zone flag, e.g. to replicate them more widely than user data.
+
 For example:
 
   cockroach init /mnt/ssd1
+  cockroach init -system-zone=system-zone.yaml /mnt/ssd1
 `,
 	Run:  runInit,
 	Flag: *flag.CommandLine,
@@ -69,12 +78,25 @@ func runInit(cmd *commander.Command, args []string) {
 		cmd.Usage()
 		return
 	}
+	if err := applyConfigFile(&cmd.Flag); err != nil {
+		log.Errorf("unable to load config file: %s", err)
+		return
+	}
+
+	var systemZone *proto.ZoneConfig
+	if Context.SystemZone != "" {
+		var err error
+		if systemZone, err = server.LoadZoneConfig(Context.SystemZone); err != nil {
+			log.Errorf("unable to load system zone config: %s", err)
+			return
+		}
+	}
 
 	// Generate a new UUID for cluster ID and bootstrap the cluster.
 	clusterID := uuid.New()
 	e := engine.NewRocksDB(proto.Attributes{}, args[0], 1<<20)
 	stopper := util.NewStopper()
-	if _, err := server.BootstrapCluster(clusterID, e, stopper); err != nil {
+	if _, err := server.BootstrapCluster(clusterID, e, systemZone, stopper); err != nil {
 		log.Errorf("unable to bootstrap cluster: %s", err)
 		return
 	}
@@ -108,10 +130,29 @@ For example:
 
   cockroach start -gossip=host1:port1,host2:port2 -stores=ssd=/mnt/ssd1,ssd=/mnt/ssd2
 
+Flags may also be kept in a TOML or YAML config file named by the
+-config-file flag, keyed by flag name. Flags given on the command line
+override the file's values, so a base config may be shared by all
+nodes:
+
+  cockroach start -config-file=cockroach.toml -stores=ssd=/mnt/ssd1
+
+Every flag may also be set by an environment variable named after it,
+upper-cased with dashes replaced by underscores and prefixed by
+COCKROACH_, as in COCKROACH_ADDR, COCKROACH_STORES and
+COCKROACH_GOSSIP. Flags given on the command line override the
+environment, which overrides the config file and the defaults.
+
+A running node rereads the scan-interval, gossip-interval, cache-size,
+v and trace-sample-rate settings of its config file on SIGHUP, or on a
+POST to /_admin/reload, and applies them without restarting. Other
+settings take effect on the next start.
+
 A node exports an HTTP API with the following endpoints:
 
   Health check:           /healthz
   Key-value REST:         ` + kv.RESTPrefix + `
+  Key-value batch:        ` + kv.BatchPrefix + `
   Structured Schema REST: ` + structured.StructuredKeyPrefix,
 	Run:  runStart,
 	Flag: *flag.CommandLine,
@@ -128,12 +169,22 @@ func runStart(cmd *commander.Command, args []string) {
 	log.Infof("build Time: %s", info.Time)
 	log.Infof("build Deps: %s", info.Deps)
 
-	// First initialize the Context as it is used in other places.
+	if err := applyConfigFile(&cmd.Flag); err != nil {
+		log.Errorf("unable to load config file: %s", err)
+		return
+	}
+	// First initialize the Context as it is used in other places. It's
+	// validated before any store is opened, reporting every problem
+	// with the configuration at once.
 	err := Context.Init()
 	if err != nil {
 		log.Errorf("failed to initialize context: %s", err)
 		return
 	}
+	if err := server.Preflight(Context); err != nil {
+		log.Error(err)
+		return
+	}
 
 	log.Info("starting cockroach cluster")
 	stopper := util.NewStopper()
@@ -150,6 +201,28 @@ func runStart(cmd *commander.Command, args []string) {
 		return
 	}
 
+	// Reload the config file on SIGHUP or a POST to the admin reload
+	// endpoint.
+	rc := s.Reloadable()
+	rc.SetSource(func() (server.ReloadableSettings, error) {
+		return reloadConfigFile(rc.Settings())
+	})
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	stopper.RunWorker(func() {
+		for {
+			select {
+			case <-hupCh:
+				log.Infof("received SIGHUP; reloading config file")
+				if err := rc.Reload(); err != nil {
+					log.Error(err)
+				}
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt, os.Kill)
 	// TODO(spencer): move this behind a build tag.
@@ -164,6 +237,7 @@ func runStart(cmd *commander.Command, args []string) {
 		log.Infof("initiating graceful shutdown of server")
 		stopper.SetStopped()
 		go func() {
+			s.Drain()
 			s.Stop()
 		}()
 	}
@@ -194,25 +268,27 @@ node, cycling through each store specified by the -stores flag.
 
 // runExterminate destroys the data held in the specified stores.
 func runExterminate(cmd *commander.Command, args []string) {
-	err := Context.Init()
-	if err != nil {
-		log.Errorf("failed to initialize context: %s", err)
-		return
-	}
-
-	// First attempt to shutdown the server. Note that an error of EOF just
-	// means the HTTP server shutdown before the request to quit returned.
+	// First attempt to shutdown the server, which holds the stores
+	// open, as initializing the context opens them to validate them.
+	// Note that an error of EOF just means the HTTP server shutdown
+	// before the request to quit returned.
 	if err := server.SendQuit(Context); err != nil {
 		log.Infof("shutdown node %s: %s", Context.Addr, err)
 	} else {
 		log.Infof("shutdown node in anticipation of data extermination")
 	}
 
+	err := Context.Init()
+	if err != nil {
+		log.Errorf("failed to initialize context: %s", err)
+		return
+	}
+
 	// Exterminate all data held in specified stores.
 	for _, e := range Context.Engines {
-		if rocksdb, ok := e.(*engine.RocksDB); ok {
+		if d, ok := e.(engine.DirEngine); ok {
 			log.Infof("exterminating data from store %s", e)
-			if err := rocksdb.Destroy(); err != nil {
+			if err := d.Destroy(); err != nil {
 				log.Fatalf("unable to destroy store %s: %s", e, err)
 			}
 		}
@@ -225,9 +301,10 @@ var quitCmd = &commander.Command{
 	UsageLine: "quit",
 	Short:     "drain and shutdown node\n",
 	Long: `
-Shutdown the server. The first stage is drain, where any new requests
-will be ignored by the server. When all extant requests have been
-completed, the server exits.
+Shutdown the server. The first stage is drain, in which the server
+refuses new client requests, transfers the leader leases of its stores
+to other nodes and flushes the stores. When all extant requests have
+been completed, the server exits.
 `,
 	Run:  runQuit,
 	Flag: *flag.CommandLine,
@@ -237,3 +314,285 @@ completed, the server exits.
 func runQuit(cmd *commander.Command, args []string) {
 	server.SendQuit(Context)
 }
+
+// A drainCmd command drains the node server without shutting it down.
+var drainCmd = &commander.Command{
+	UsageLine: "drain",
+	Short:     "drain node without shutting it down\n",
+	Long: `
+Drains the server: it refuses new client requests, transfers the leader
+leases of its stores to other nodes and flushes the stores, but keeps
+running until it's shut down. Waits until the drain is done, printing
+each of its phases. The progress of a drain is also served at
+/_admin/drain, for orchestration tools to poll before stopping a node.
+`,
+	Run:  runDrain,
+	Flag: *flag.CommandLine,
+}
+
+// runDrain accesses the drain path.
+func runDrain(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	if err := server.SendDrain(Context); err != nil {
+		log.Error(err)
+	}
+}
+
+// A cutoverCmd command makes a standby cluster take over from its
+// primary.
+var cutoverCmd = &commander.Command{
+	UsageLine: "cutover",
+	Short:     "make a standby cluster take over from its primary\n",
+	Long: `
+Makes the standby cluster of the node at -addr, to which a primary
+cluster ships its writes (see -replicate-to), take over from the
+primary: no more writes are accepted from the primary, and the
+timestamp up to which the writes to every replicated range were shipped
+is recorded and displayed. Writes to some ranges may have been shipped
+beyond it. Run it when the primary cluster is lost, before directing
+clients to the standby. A standby whose nodes were started with
+-standby is cut over by "cockroach promote" instead.
+`,
+	Run:  runCutover,
+	Flag: *flag.CommandLine,
+}
+
+// runCutover cuts the standby cluster over.
+func runCutover(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	kv, err := makeKVClient()
+	if err != nil {
+		log.Errorf("failed to initialize KV client: %s", err)
+		return
+	}
+	ts, err := server.Cutover(kv)
+	if err != nil {
+		log.Errorf("cutover failed: %s", err)
+		return
+	}
+	fmt.Printf("cut over; writes shipped up to %s\n", ts)
+}
+
+// A promoteCmd command promotes a read-only standby cluster.
+var promoteCmd = &commander.Command{
+	UsageLine: "promote",
+	Short:     "promote a read-only standby cluster during failover\n",
+	Long: `
+Promotes the standby cluster of the node at -addr, whose nodes were
+started with -standby: the cluster cuts over from its primary (see
+"cockroach cutover") and becomes fully writable. The node at -addr
+serves writes and transactions immediately, the other nodes once they
+observe the cutover, within -replicate-interval. Displays the timestamp
+up to which the primary's writes to every replicated range were shipped.
+`,
+	Run:  runPromote,
+	Flag: *flag.CommandLine,
+}
+
+// runPromote accesses the standby path.
+func runPromote(cmd *commander.Command, args []string) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	if err := server.SendPromote(Context); err != nil {
+		log.Error(err)
+	}
+}
+
+// A readOnlyCmd command puts the node into or out of read-only mode.
+var readOnlyCmd = &commander.Command{
+	UsageLine: "readonly [true|false]",
+	Short:     "set node read-only mode\n",
+	Long: `
+Puts the node into read-only mode, or takes it out of read-only mode if
+false is specified. A read-only node rejects writes and doesn't acquire
+leader leases, but continues to serve reads and to replicate the
+writes committed by other nodes. This is useful while investigating a
+node or the health of its storage.
+`,
+	Run:  runReadOnly,
+	Flag: *flag.CommandLine,
+}
+
+// runReadOnly accesses the read-only path.
+func runReadOnly(cmd *commander.Command, args []string) {
+	if len(args) > 1 {
+		cmd.Usage()
+		return
+	}
+	readOnly := true
+	if len(args) == 1 {
+		var err error
+		if readOnly, err = strconv.ParseBool(args[0]); err != nil {
+			log.Errorf("invalid read-only mode %q: %s", args[0], err)
+			return
+		}
+	}
+	if err := server.SendReadOnly(Context, readOnly); err != nil {
+		log.Error(err)
+	}
+}
+
+// A compactCmd command compacts the stores of a node.
+var compactCmd = &commander.Command{
+	UsageLine: "compact [options] [<store-id> [<start-key> [<end-key>]]]",
+	Short:     "compact the stores of a node\n",
+	Long: `
+Compacts the sstables of the store with <store-id> of the node at -addr,
+or of all its stores if no store is given, holding the keys from
+<start-key> through <end-key>, or through the last key if no end is
+given. Compaction rewrites the files of the store's engine to drop
+deleted and overwritten values and to bring the levels of files within
+their target sizes, reducing the compaction debt which stalls writes;
+see /_status/local/compactions. The command waits for the compaction
+to finish and prints the files remaining in each level.
+`,
+	Run:  runCompact,
+	Flag: *flag.CommandLine,
+}
+
+// runCompact accesses the compact path.
+func runCompact(cmd *commander.Command, args []string) {
+	if len(args) > 3 {
+		cmd.Usage()
+		return
+	}
+	var storeID proto.StoreID
+	var start, end proto.Key
+	if len(args) > 0 {
+		id, err := strconv.ParseInt(args[0], 10, 32)
+		if err != nil || id <= 0 {
+			log.Errorf("invalid store ID %q", args[0])
+			return
+		}
+		storeID = proto.StoreID(id)
+	}
+	if len(args) > 1 {
+		start = proto.Key(args[1])
+	}
+	if len(args) > 2 {
+		end = proto.Key(args[2])
+	}
+	if err := server.SendCompact(Context, storeID, start, end); err != nil {
+		log.Error(err)
+	}
+}
+
+// A checkpointCmd command checkpoints the engine of a store.
+var checkpointCmd = &commander.Command{
+	UsageLine: "checkpoint [options] <store-id> [<dir>]",
+	Short:     "copy the engine of a store of a running node\n",
+	Long: `
+Makes a consistent copy of the engine of the store with <store-id> of
+the node at -addr while the node is running. With <dir>, the node
+writes the copy to <dir>, which must not exist, on its own filesystem;
+sstables are hard-linked rather than copied if <dir> is on the store's
+filesystem, so the copy is nearly instant and takes little space until
+the store compacts. Otherwise, the copy is streamed to standard output
+as a tar archive of a store directory.
+
+Use it for whole-node backups or to provision a replacement node: a
+node started with the extracted directory as its store resumes as the
+store's node, catching up on the writes since the copy from the other
+replicas of its ranges. The original node must be stopped for good
+before the replacement starts.
+
+For example:
+
+  cockroach checkpoint 1 > store1.tar
+`,
+	Run:  runCheckpoint,
+	Flag: *flag.CommandLine,
+}
+
+// runCheckpoint accesses the checkpoint path.
+func runCheckpoint(cmd *commander.Command, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		cmd.Usage()
+		return
+	}
+	id, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil || id <= 0 {
+		log.Errorf("invalid store ID %q", args[0])
+		return
+	}
+	var dir string
+	if len(args) > 1 {
+		dir = args[1]
+	}
+	if err := server.SendCheckpoint(Context, proto.StoreID(id), dir, os.Stdout); err != nil {
+		log.Error(err)
+	}
+}
+
+// A configCmd command displays the effective configuration of a node.
+var configCmd = &commander.Command{
+	UsageLine: "config [options] [validate]",
+	Short:     "display the configuration of a running node\n",
+	Long: `
+Displays, as JSON, the configuration loaded by the node at -addr: each
+setting of its context, defaults included, the current values of its
+reloadable settings, and the engines and gossip bootstrap resolvers
+parsed from its -stores and -gossip flags. Use it to verify which
+flags, environment variables and config file values a node applied.
+
+With validate, the configuration given to this command by its flags,
+the environment and -config-file is validated instead, as it would be
+by "start", without contacting a node, opening stores or listening on
+addresses. It's displayed as the configuration of a running node is,
+along with the settings which differ from their defaults and the
+warnings of the preflight checks of certificates, file descriptors and
+clock synchronization. Run it before restarting a node with a new
+configuration to catch errors while the node is still running; the
+command fails, listing every problem found, if the node wouldn't start.
+
+For example:
+
+  cockroach config -config-file=/etc/cockroach.toml validate
+`,
+	Run:  runConfig,
+	Flag: *flag.CommandLine,
+}
+
+// runConfig accesses the config path or, with validate, checks the
+// configuration of the command.
+func runConfig(cmd *commander.Command, args []string) {
+	if len(args) == 1 && args[0] == "validate" {
+		runValidateConfig(cmd)
+		return
+	}
+	if len(args) != 0 {
+		cmd.Usage()
+		return
+	}
+	b, err := server.GetConfig(Context)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	os.Stdout.Write(b)
+	fmt.Println()
+}
+
+// runValidateConfig validates the configuration set by the command's
+// flags, the environment and the config file, and displays it.
+func runValidateConfig(cmd *commander.Command) {
+	if err := applyConfigFile(&cmd.Flag); err != nil {
+		log.Errorf("unable to load config file: %s", err)
+		return
+	}
+	b, err := server.CheckConfig(Context)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	os.Stdout.Write(b)
+	fmt.Println()
+}
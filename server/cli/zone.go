@@ -48,6 +48,33 @@ func runGetZone(cmd *commander.Command, args []string) {
 	server.RunGetZone(Context, args[0])
 }
 
+// A resolveZoneCmd command displays the zone config which applies to
+// the specified key, with its inherited fields resolved.
+var resolveZoneCmd = &commander.Command{
+	UsageLine: "resolve-zone [options] <key-prefix>",
+	Short:     "fetches and displays the zone config resolved for a key",
+	Long: `
+Fetches and displays the zone configuration which applies to
+<key-prefix>: that of its longest matching prefix, with the fields
+inherited from the zones of shorter prefixes filled in. For each
+field, the prefix of the zone which it was taken from is displayed.
+The key prefix should be escaped via URL query escaping if it contains
+non-ascii bytes or spaces.
+`,
+	Run:  runResolveZone,
+	Flag: *flag.CommandLine,
+}
+
+// runResolveZone invokes the REST API with GET action, the key prefix
+// as path and the resolved query parameter.
+func runResolveZone(cmd *commander.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+	server.RunResolveZone(Context, args[0])
+}
+
 // A lsZonesCmd command displays a list of zone configs by prefix.
 var lsZonesCmd = &commander.Command{
 	UsageLine: "ls-zones [options] [key-regexp]",
@@ -122,6 +149,33 @@ The zone config format has the following YAML schema:
     - ...
   range_min_bytes: <size-in-bytes>
   range_max_bytes: <size-in-bytes>
+  reads_per_second: <max-reads-per-range-per-second>
+  writes_per_second: <max-writes-per-range-per-second>
+  pinned_stores: [<store-id>, ...]
+  pin_expiration: <unix-time-in-seconds>
+  inherit: <true|false>
+  commit_coalescing_window_micros: <microseconds>
+
+The rate limits are optional and unlimited if omitted or zero.
+
+A zone which inherits takes each field it omits from the zone of the
+next shorter matching prefix, which may itself inherit, up to the
+default zone. The default zone can't inherit. For example, a zone for
+"db1" with only range_max_bytes and inherit set keeps the replicas of
+the default zone, and follows changes to them. Use resolve-zone to see
+the fields which apply to a key and where each comes from.
+
+Pinned stores, which are optional, override the allocator: until the
+pin expires, replicas of the zone's ranges are added to the pinned
+stores and then removed from the others. They're meant for experiments
+and for isolating a problematic workload, so a pin must expire. To pin
+a key span, give it a zone of its own.
+
+The commit coalescing window, which is optional and at most 100ms,
+holds the Raft proposals of each range in the zone for the given time
+so that the writes proposed within it are committed, and synced,
+together. It adds up to the window to the latency of every write in
+exchange for throughput on prefixes taking bulk writes.
 
 For example:
 
@@ -135,6 +189,11 @@ For example:
 Setting zone configs will guarantee that key ranges will be split
 such that no key range straddles two zone config specifications.
 This feature can be taken advantage of to pre-split ranges.
+
+With -dry-run, the zone config isn't set. Instead, the ranges whose
+replicas would be added, removed or split are displayed, along with an
+estimate of the bytes copied to new replicas and of how long copying
+them would take.
 `,
 	Run:  runSetZone,
 	Flag: *flag.CommandLine,
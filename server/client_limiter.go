@@ -0,0 +1,141 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxClientRequestsPerUser and
+	// defaultMaxClientRequestsPerHost are the default limits on the
+	// client requests in flight for any one user and client host.
+	defaultMaxClientRequestsPerUser = 512
+	defaultMaxClientRequestsPerHost = 128
+	// defaultClientQueueTimeout is the default time a client request
+	// waits for a slot before it's refused.
+	defaultClientQueueTimeout = 5 * time.Second
+)
+
+// A clientLimiter caps the key-value and structured data requests in
+// flight for each authenticated user and each client host, so that a
+// single misbehaving client process can't monopolize the node.
+// Requests beyond a cap wait in a queue for a slot and are refused
+// with 503, service unavailable, if none frees up within the queue
+// timeout. Client hosts stand in for connections, as an HTTP
+// connection carries one request at a time; a client with thousands
+// of goroutines opens thousands of connections from one host.
+type clientLimiter struct {
+	perUser, perHost int // Zero values are unlimited
+	timeout          time.Duration
+
+	mu    sync.Mutex
+	slots map[string]*clientSlots // Keyed by "user:" or "host:" prefix
+}
+
+// clientSlots is the semaphore of a single user or client host. It's
+// removed from the limiter when no request holds or awaits one of
+// its slots.
+type clientSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// newClientLimiter returns a clientLimiter with the given caps, either
+// of which may be zero to leave it unlimited.
+func newClientLimiter(perUser, perHost int, timeout time.Duration) *clientLimiter {
+	return &clientLimiter{
+		perUser: perUser,
+		perHost: perHost,
+		timeout: timeout,
+		slots:   map[string]*clientSlots{},
+	}
+}
+
+// acquire waits for a slot of the request's user, if it authenticated
+// with a client certificate, and of its client host. It returns a
+// function releasing the slots, or false if they weren't acquired
+// before the queue timeout.
+func (l *clientLimiter) acquire(r *http.Request) (func(), bool) {
+	var keys []string
+	var limits []int
+	if user, err := authenticatedUser(r); err == nil && l.perUser > 0 {
+		keys, limits = append(keys, "user:"+user), append(limits, l.perUser)
+	}
+	if l.perHost > 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		keys, limits = append(keys, "host:"+host), append(limits, l.perHost)
+	}
+	if len(keys) == 0 {
+		return func() {}, true
+	}
+
+	var timeout <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var held []*clientSlots
+	release := func() {
+		for _, s := range held {
+			<-s.sem
+		}
+		l.mu.Lock()
+		for _, key := range keys {
+			l.unrefLocked(key)
+		}
+		l.mu.Unlock()
+	}
+	l.mu.Lock()
+	slots := make([]*clientSlots, len(keys))
+	for i, key := range keys {
+		s, ok := l.slots[key]
+		if !ok {
+			s = &clientSlots{sem: make(chan struct{}, limits[i])}
+			l.slots[key] = s
+		}
+		s.refs++
+		slots[i] = s
+	}
+	l.mu.Unlock()
+
+	for _, s := range slots {
+		select {
+		case s.sem <- struct{}{}:
+			held = append(held, s)
+		case <-timeout:
+			release()
+			return nil, false
+		}
+	}
+	return release, true
+}
+
+// unrefLocked drops a reference to the slots of key, removing them
+// once unreferenced. l.mu must be held.
+func (l *clientLimiter) unrefLocked(key string) {
+	s := l.slots[key]
+	if s.refs--; s.refs == 0 {
+		delete(l.slots, key)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestClientLimiter verifies that requests beyond the cap of a client
+// host are queued until a slot frees up and refused once the queue
+// timeout elapses, and that other hosts are unaffected.
+func TestClientLimiter(t *testing.T) {
+	l := newClientLimiter(0, 2, 10*time.Millisecond)
+	hostA := &http.Request{RemoteAddr: "10.0.0.1:5000"}
+	hostB := &http.Request{RemoteAddr: "10.0.0.2:5000"}
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, ok := l.acquire(hostA)
+		if !ok {
+			t.Fatalf("%d: expected request within the cap to be admitted", i)
+		}
+		releases = append(releases, release)
+	}
+	if _, ok := l.acquire(hostA); ok {
+		t.Fatal("expected request beyond the cap to time out")
+	}
+	release, ok := l.acquire(hostB)
+	if !ok {
+		t.Fatal("expected request from another host to be admitted")
+	}
+	release()
+
+	// A queued request is admitted once a request in flight finishes.
+	l.timeout = time.Second
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		releases[0]()
+	}()
+	release, ok = l.acquire(hostA)
+	if !ok {
+		t.Fatal("expected queued request to be admitted")
+	}
+	release()
+	releases[1]()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.slots) != 0 {
+		t.Errorf("expected unreferenced slots to be removed; got %d", len(l.slots))
+	}
+}
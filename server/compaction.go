@@ -0,0 +1,154 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// StoreCompactionStats are the compaction stats of a store's engine.
+type StoreCompactionStats struct {
+	StoreID proto.StoreID          `json:"store_id"`
+	Stats   engine.CompactionStats `json:"stats"`
+}
+
+// compactionStats returns the compaction stats of the node's stores
+// whose engines report them, ordered by store ID, or those of the store
+// with storeID if it's non-zero.
+func compactionStats(n *Node, storeID proto.StoreID) []StoreCompactionStats {
+	stats := []StoreCompactionStats{}
+	if n == nil {
+		return stats
+	}
+	n.lSender.VisitStores(func(s *storage.Store) error {
+		if c, ok := s.Engine().(engine.Compactor); ok && (storeID == 0 || s.StoreID() == storeID) {
+			stats = append(stats, StoreCompactionStats{StoreID: s.StoreID(), Stats: c.CompactionStats()})
+		}
+		return nil
+	})
+	sort.Sort(compactionStatsByStore(stats))
+	return stats
+}
+
+type compactionStatsByStore []StoreCompactionStats
+
+func (c compactionStatsByStore) Len() int           { return len(c) }
+func (c compactionStatsByStore) Less(i, j int) bool { return c[i].StoreID < c[j].StoreID }
+func (c compactionStatsByStore) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// compactStores compacts the keys from start through end of the stores
+// of the node, or of the store with storeID if it's non-zero. Empty
+// keys leave the range unbounded, in which case the store's local keys
+// are compacted as well.
+func compactStores(n *Node, storeID proto.StoreID, start, end proto.Key) error {
+	var encStart, encEnd proto.EncodedKey
+	if len(start) > 0 {
+		encStart = engine.MVCCEncodeKey(start)
+	}
+	if len(end) > 0 {
+		encEnd = engine.MVCCEncodeKey(end)
+	}
+	// The stores are collected first so that the node's stores aren't
+	// locked while they're compacted.
+	var stores []*storage.Store
+	n.lSender.VisitStores(func(s *storage.Store) error {
+		if storeID == 0 || s.StoreID() == storeID {
+			stores = append(stores, s)
+		}
+		return nil
+	})
+	if len(stores) == 0 {
+		return util.Errorf("store %d not found", storeID)
+	}
+	for _, s := range stores {
+		c, ok := s.Engine().(engine.Compactor)
+		if !ok {
+			return util.Errorf("engine %T of store %d doesn't support compaction", s.Engine(), s.StoreID())
+		}
+		log.Infof("compacting keys %q through %q of store %d", start, end, s.StoreID())
+		if err := c.Compact(encStart, encEnd); err != nil {
+			return util.Errorf("unable to compact store %d: %s", s.StoreID(), err)
+		}
+	}
+	return nil
+}
+
+// handleLocalCompactions handles GET requests for the compaction stats
+// of the node's stores: the files and bytes of each level of their
+// engines, the level sizes compactions aim for and their estimated
+// compaction debt, with which write stalls are diagnosed.
+func (s *statusServer) handleLocalCompactions(w http.ResponseWriter, r *http.Request) {
+	stats := struct {
+		Stores []StoreCompactionStats `json:"stores"`
+	}{compactionStats(s.node, 0)}
+	b, contentType, err := util.MarshalResponse(r, stats, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// handleCompact handles POST requests to compact the engines of the
+// node's stores, selected by the store parameter, from the start
+// parameter's key through the end parameter's. The response, once the
+// compaction is done, holds the compaction stats of the compacted
+// stores, as served by the local compactions status endpoint.
+func (s *adminServer) handleCompact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	var storeID proto.StoreID
+	if param := query.Get(compactParamStore); len(param) > 0 {
+		id, err := strconv.ParseInt(param, 10, 32)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid store ID "+strconv.Quote(param), http.StatusBadRequest)
+			return
+		}
+		storeID = proto.StoreID(id)
+	}
+	start, end := proto.Key(query.Get(compactParamStart)), proto.Key(query.Get(compactParamEnd))
+	if len(end) > 0 && end.Less(start) {
+		http.Error(w, "end key must not precede start key", http.StatusBadRequest)
+		return
+	}
+	if err := compactStores(s.node, storeID, start, end); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stats := struct {
+		Stores []StoreCompactionStats `json:"stores"`
+	}{compactionStats(s.node, storeID)}
+	body, contentType, err := util.MarshalResponse(r, stats, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
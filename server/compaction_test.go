@@ -0,0 +1,85 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// TestCompactStores verifies that the stores of a node are compacted
+// through the admin compact endpoint and that their compaction stats
+// are served by the local compactions status endpoint.
+func TestCompactStores(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+
+	var stats struct {
+		Stores []StoreCompactionStats `json:"stores"`
+	}
+	body, err := getText("https://" + s.ServingAddr() + statusLocalCompactionsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Stores) != 1 || len(stats.Stores[0].Stats.Levels) == 0 {
+		t.Fatalf("expected the levels of 1 store; got %+v", stats)
+	}
+
+	httpClient := client.CreateTestHTTPClient()
+	for i, test := range []struct {
+		query     string
+		expStatus int
+	}{
+		{"", http.StatusOK},
+		{"?store=1&start=a&end=z", http.StatusOK},
+		{"?store=2", http.StatusInternalServerError},
+		{"?store=x", http.StatusBadRequest},
+		{"?start=z&end=a", http.StatusBadRequest},
+	} {
+		url := fmt.Sprintf("https://%s%s%s", s.ServingAddr(), compactPath, test.query)
+		resp, err := httpClient.Post(url, "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.expStatus {
+			t.Errorf("%d: expected status %d; got %d: %s", i, test.expStatus, resp.StatusCode, body)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+		if err := json.Unmarshal(body, &stats); err != nil {
+			t.Fatal(err)
+		}
+		if len(stats.Stores) != 1 || stats.Stores[0].StoreID != proto.StoreID(1) {
+			t.Errorf("%d: expected the stats of store 1; got %+v", i, stats)
+		}
+	}
+}
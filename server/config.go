@@ -53,7 +53,7 @@ func getFriendlyNameFromPrefix(prefix string) string {
 // runGetConfig invokes the REST API with GET action and key prefix as path.
 func runGetConfig(ctx *Context, prefix, keyPrefix string) {
 	friendlyName := getFriendlyNameFromPrefix(prefix)
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.Addr, prefix, keyPrefix), nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.HTTPHost(), prefix, keyPrefix), nil)
 	if err != nil {
 		log.Errorf("unable to create request to admin REST endpoint: %s", err)
 		return
@@ -82,6 +82,24 @@ func RunGetZone(ctx *Context, keyPrefix string) {
 	runGetConfig(ctx, zonePathPrefix, keyPrefix)
 }
 
+// RunResolveZone gets the zone which applies to the given key, with
+// its inherited fields resolved and the source of each.
+func RunResolveZone(ctx *Context, keyPrefix string) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s/%s?%s=true", adminScheme, ctx.HTTPHost(),
+		zonePathPrefix, keyPrefix, zoneParamResolved), nil)
+	if err != nil {
+		log.Errorf("unable to create request to admin REST endpoint: %s", err)
+		return
+	}
+	req.Header.Add("Accept", "text/yaml")
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		log.Errorf("admin REST request failed: %s", err)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "resolved zone config for key prefix %q:\n%s\n", keyPrefix, string(b))
+}
+
 // runLsConfigs invokes the REST API with GET action and no path, which
 // fetches a list of all configuration prefixes.
 // The type of config that is listed is based on the passed in prefix.
@@ -89,7 +107,7 @@ func RunGetZone(ctx *Context, keyPrefix string) {
 // displayed.
 func runLsConfigs(ctx *Context, prefix, pattern string) {
 	friendlyName := getFriendlyNameFromPrefix(prefix)
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.Addr, prefix), nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), prefix), nil)
 	if err != nil {
 		log.Errorf("unable to create request to admin REST endpoint: %s", err)
 		return
@@ -144,7 +162,7 @@ func RunLsZone(ctx *Context, pattern string) {
 // The type of config that is removed is based on the passed in prefix.
 func runRmConfig(ctx *Context, prefix, keyPrefix string) {
 	friendlyName := getFriendlyNameFromPrefix(prefix)
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.Addr, prefix, keyPrefix), nil)
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.HTTPHost(), prefix, keyPrefix), nil)
 	if err != nil {
 		log.Errorf("unable to create request to admin REST endpoint: %s", err)
 		return
@@ -185,7 +203,7 @@ func runSetConfig(ctx *Context, prefix, keyPrefix, configFileName string) {
 		return
 	}
 	// Send to admin REST API.
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.Addr, prefix, keyPrefix), bytes.NewReader(body))
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.HTTPHost(), prefix, keyPrefix), bytes.NewReader(body))
 	if err != nil {
 		log.Errorf("unable to create request to admin REST endpoint: %s", err)
 		return
@@ -209,8 +227,14 @@ func RunSetPerm(ctx *Context, keyPrefix, configFileName string) {
 	runSetConfig(ctx, permPathPrefix, keyPrefix, configFileName)
 }
 
-// RunSetZone sets the zone to the key given the yaml filename.
+// RunSetZone sets the zone to the key given the yaml filename. If
+// ctx.DryRun is set, the changes which setting it would make to the
+// replicas of the cluster's ranges are displayed instead.
 func RunSetZone(ctx *Context, keyPrefix, configFileName string) {
+	if ctx.DryRun {
+		runZonePlan(ctx, keyPrefix, configFileName)
+		return
+	}
 	runSetConfig(ctx, zonePathPrefix, keyPrefix, configFileName)
 }
 
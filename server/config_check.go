@@ -0,0 +1,57 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// A configCheck is the result of validating a node's configuration
+// before the node is started with it.
+type configCheck struct {
+	Config   nodeConfig             `json:"config"`
+	Changed  map[string]interface{} `json:"changed"`
+	Warnings []string               `json:"warnings"`
+}
+
+// CheckConfig initializes the context with a dry run, which neither
+// opens its stores nor listens on its addresses, and runs the
+// preflight checks which need neither. It returns, as JSON, the
+// configuration of a node started with the context, the settings of
+// the context which differ from their defaults and the warnings of
+// the checks. The error returned describes every problem found.
+func CheckConfig(ctx *Context) ([]byte, error) {
+	if err := ctx.InitDryRun(); err != nil {
+		return nil, err
+	}
+	warnings, err := runPreflightChecks(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	check := configCheck{
+		Config:   effectiveConfig(ctx, ctx.ReloadableSettings()),
+		Changed:  map[string]interface{}{},
+		Warnings: append([]string{}, warnings...),
+	}
+	defaults := redactContext(NewContext())
+	for name, value := range check.Config.Context {
+		if !reflect.DeepEqual(value, defaults[name]) {
+			check.Changed[name] = value
+		}
+	}
+	return json.MarshalIndent(check, "", "  ")
+}
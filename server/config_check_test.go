@@ -0,0 +1,80 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestCheckConfig verifies that a configuration is validated without
+// creating the directories of its stores, and that the configuration,
+// its settings which differ from the defaults and its warnings are
+// returned.
+func TestCheckConfig(t *testing.T) {
+	dir := util.CreateTempDir(t, "_config_check_test")
+	defer util.CleanupDir(dir)
+	storeDir := filepath.Join(dir, "ssd01")
+
+	ctx := newTestContext()
+	ctx.Stores = "ssd,cache=1MiB=" + storeDir
+	ctx.GossipBootstrap = "self://"
+	b, err := CheckConfig(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(storeDir); !os.IsNotExist(err) {
+		t.Errorf("expected store directory not to be created; got %v", err)
+	}
+	if len(ctx.Engines) != 0 {
+		t.Errorf("expected no engines; got %d", len(ctx.Engines))
+	}
+	var check struct {
+		Config struct {
+			Engines []engineConfig `json:"engines"`
+		} `json:"config"`
+		Changed  map[string]interface{} `json:"changed"`
+		Warnings []string               `json:"warnings"`
+	}
+	if err := json.Unmarshal(b, &check); err != nil {
+		t.Fatal(err)
+	}
+	if e := check.Config.Engines; len(e) != 1 || e[0].Spec == nil || e[0].Spec.Location != storeDir ||
+		e[0].Spec.CacheSize != 1<<20 {
+		t.Errorf("expected the store's spec; got %+v", e)
+	}
+	if check.Changed["Stores"] != ctx.Stores || check.Changed["GossipBootstrap"] != "self://" {
+		t.Errorf("expected stores and gossip bootstrap to differ from the defaults; got %+v", check.Changed)
+	}
+	if _, ok := check.Changed["Addr"]; ok {
+		t.Errorf("expected the default address not to differ; got %+v", check.Changed)
+	}
+	if check.Warnings == nil {
+		t.Error("expected a list of warnings")
+	}
+
+	ctx = newTestContext()
+	ctx.Stores = "ssd,cache=2XB=" + storeDir
+	ctx.MaxOffset = -1
+	if _, err := CheckConfig(ctx); err == nil || !strings.Contains(err.Error(), "max clock offset") {
+		t.Errorf("expected invalid clock offset; got %v", err)
+	}
+}
@@ -18,18 +18,24 @@
 package server
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
-	"regexp"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/kv"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -43,18 +49,54 @@ const (
 	defaultGossipInterval = 2 * time.Second
 	defaultCacheSize      = 1 << 30 // GB
 	defaultScanInterval   = 10 * time.Minute
+
+	// defaultReplicateInterval is how often the committed writes to the
+	// replicated prefixes are shipped to the standby cluster.
+	defaultReplicateInterval = time.Second
+
+	// maxMaxOffset is the largest permitted MaxOffset. Reads within
+	// uncertainty intervals of this length would restart most
+	// transactions.
+	maxMaxOffset = time.Minute
 )
 
 // Context holds parameters needed to setup a server.
 // Calling "server/cli".InitFlags(ctx *Context) will initialize Context using
-// command flags. Keep in sync with "server/cli/flags.go".
+// command flags, which may also be set by COCKROACH_* environment
+// variables; flags take precedence over the environment, which takes
+// precedence over the defaults. Keep in sync with "server/cli/flags.go".
+//
+// The exported fields are reported by the /_status/details endpoint.
+// Fields holding secrets must be tagged `redact:"true"` and fields
+// which can't be usefully displayed tagged `status:"-"`.
 type Context struct {
-	// Addr is the host:port to bind for HTTP/RPC traffic.
+	// Addr is the host:port to bind for RPC traffic, and for HTTP
+	// traffic unless HTTPAddr is set. Either may instead name a unix
+	// socket by its path, prefixed by util.UnixAddrPrefix, for
+	// local-only deployments and tests.
 	Addr string
 
+	// HTTPAddr, if set, is the host:port to bind for HTTP traffic: the
+	// admin UI, the status and admin endpoints and the HTTP KV API.
+	// Serving it on its own listener allows the RPC port to be
+	// firewalled off from clients and operators.
+	HTTPAddr string
+
+	// AdvertiseAddr is the host:port at which other nodes reach this
+	// one, gossiped in its node descriptor. It's only needed if that
+	// differs from Addr, e.g. for nodes which bind 0.0.0.0 behind NAT
+	// or in containers; if empty, the bound address is advertised.
+	AdvertiseAddr string
+
 	// Certs specifies a directory containing RSA key and x509 certs.
 	Certs string
 
+	// ConfigFile is the path of a TOML or YAML file from which the
+	// other fields are loaded, keyed by the names of their command line
+	// flags, e.g. cache-size = 2147483648. Flags set on the command line
+	// override the file's values.
+	ConfigFile string
+
 	// Stores is specified to enable durable key-value storage.
 	// Memory-backed key value stores may be optionally specified
 	// via mem=<integer byte size>.
@@ -66,8 +108,39 @@ type Context struct {
 	// flash (ssd), spinny disk (hdd), fusion-io (fio), in-memory (mem); device
 	// attributes might also include speeds and other specs (7200rpm, 200kiops, etc.).
 	// For example, -store=hdd:7200rpm=/mnt/hda1,ssd=/mnt/ssd01,ssd=/mnt/ssd02,mem=1073741824
+	//
+	// The location of a store may also name the engine type which
+	// creates it with a <scheme>://<location> prefix, e.g.
+	// ssd=rocksdb:///mnt/ssd01 or mem=mem://1073741824. Engine types
+	// are registered with engine.Register; those which implement
+	// engine.DirEngine have their directories validated before the
+	// node starts. The attributes may be
+	// followed by cache and maxsize options, e.g.
+	// ssd,cache=2GiB=/mnt/ssd01.
+	//
+	// Stores may also be specified as URLs whose query parameters hold
+	// the attributes and resource limits of the store, e.g.
+	// rocksdb:///mnt/ssd01?attrs=ssd&cache=2GiB&maxsize=80%, and the
+	// engine type may be given as a parameter instead of a scheme, e.g.
+	// /mnt/ssd01?type=rocksdb. Locations may be double-quoted to hold
+	// commas and other separators. See ParseStoreSpec.
+	//
+	// A persistent store may spread its sstables across further
+	// directories, separated by '+', e.g. ssd=/mnt/ssd01+/mnt/ssd02.
+	//
+	// A store whose spec has an encrypt parameter, e.g.
+	// ssd,encrypt=key1=/mnt/ssd01, encrypts its files with the key of
+	// that ID in StoreKeyFile; see engine.LoadEncryptionKeys for its
+	// format and the rotation of keys.
 	Stores string
 
+	// StoreKeyFile is the file holding the keys of encrypted stores.
+	StoreKeyFile string
+
+	// StoreTuning holds the RocksDB options of persistent stores whose
+	// specs don't tune them, e.g. ssd,compression=lz4=/mnt/ssd01.
+	StoreTuning engine.RocksDBTuning
+
 	// Attrs specifies a colon-separated list of node topography or machine
 	// capabilities, used to match capabilities or location preferences specified
 	// in zone configs.
@@ -84,31 +157,195 @@ type Context struct {
 	// communicated between hosts on the gossip network.
 	GossipInterval time.Duration
 
+	// GossipMaxOutgoing is the maximum number of outgoing gossip
+	// connections, the target fan-out of the gossip network, and
+	// GossipMaxIncoming the maximum number of incoming connections.
+	// Raising them reduces the hops infos take to propagate through
+	// large clusters, at the cost of more connections per node.
+	GossipMaxOutgoing int
+	GossipMaxIncoming int
+
 	// Enables linearizable behaviour of operations on this node by making sure
 	// that no commit timestamp is reported back to the client until all other
 	// node clocks have necessarily passed it.
 	Linearizable bool
 
+	// PipelineWrites enables the pipelining of transactional writes
+	// coordinated by this node: intermediate writes are acknowledged
+	// without waiting for consensus, and only the commit waits for them.
+	PipelineWrites bool
+
+	// MaxBatchRequests and MaxBatchBytes are the maximum number of
+	// requests and bytes in a batch coordinated by this node. Larger
+	// batches are rejected with a retryable error telling the client to
+	// split them; zero values are unlimited.
+	MaxBatchRequests int
+	MaxBatchBytes    int64
+
+	// MaxClientRequestsPerUser and MaxClientRequestsPerHost cap the
+	// key-value and structured data requests in flight for each user
+	// authenticated by a client certificate and each client host.
+	// Requests beyond a cap wait up to ClientQueueTimeout for a slot
+	// before they're refused; zero caps are unlimited.
+	MaxClientRequestsPerUser int
+	MaxClientRequestsPerHost int
+	ClientQueueTimeout       time.Duration
+
+	// HedgeReadTimeout is the duration after which a read-only request
+	// sent by this node which hasn't received a reply is also sent to
+	// the next replica; zero selects the DistSender's default.
+	// RetryBudgetRatio, if positive, limits the retries of failed RPCs
+	// to that ratio of the requests sent, in bursts of up to
+	// RetryBudgetMaxRetries, or the DistSender's default if zero.
+	HedgeReadTimeout      time.Duration
+	RetryBudgetRatio      float64
+	RetryBudgetMaxRetries int
+
 	// CacheSize is the amount of memory in bytes to use for caching data.
-	// The value is split evenly between the stores if there are more than one.
+	// What remains after the caches of stores which set their own is
+	// split evenly between the other stores.
 	CacheSize int64
 
+	// SystemZone is the path of a YAML file holding the zone config of
+	// the system ranges, which hold the range addressing records and
+	// the cluster's configs. It's written when the cluster is
+	// bootstrapped; if empty, the system ranges share the default zone.
+	SystemZone string
+
 	// Parsed values.
 
 	// Engines is the storage instances specified by Stores.
-	Engines []engine.Engine
+	Engines []engine.Engine `status:"-"`
 
 	// NodeAttributes is the parsed representation of Attrs.
 	NodeAttributes proto.Attributes
 
 	// GossipBootstrapResolvers is a list of gossip resolvers used
 	// to find bootstrap nodes for connecting to the gossip network.
-	GossipBootstrapResolvers []gossip.Resolver
+	GossipBootstrapResolvers []gossip.Resolver `status:"-"`
 
 	// ScanInterval determines a duration during which each range should be
 	// visited approximately once by the range scanner.
 	ScanInterval time.Duration
 
+	// ClosedTimestampLag is the target lag behind the clock of the
+	// timestamps which range leaders close and periodically publish to
+	// their followers, below which followers serve bounded-staleness
+	// reads. Writes to a range proposed at or below its closed timestamp
+	// are moved above it. Zero disables closing timestamps.
+	ClosedTimestampLag time.Duration
+
+	// SnapshotApplyRate is the number of bytes of incoming snapshot data
+	// per second each store admits for application. Zero leaves
+	// snapshots unthrottled.
+	SnapshotApplyRate int64
+
+	// TxnAbandonTimeout is how long after its last heartbeat a pending
+	// transaction is considered abandoned by its coordinator and
+	// aborted, with its intents resolved, by the GC queue. Zero
+	// disables aborting abandoned transactions.
+	TxnAbandonTimeout time.Duration
+
+	// TraceSampleRate is the fraction of commands executed by the
+	// node's stores which are traced. The most recent traces are served
+	// by the /_status/local/traces endpoint.
+	TraceSampleRate float64
+
+	// StoreIOProbeInterval is the interval at which the sync and read
+	// latencies of each store's device are probed. A store whose probes
+	// exceed StoreMaxSyncLatency or StoreMaxReadLatency is marked
+	// suspect in gossip and transfers its leader leases away. Zero
+	// disables probing.
+	StoreIOProbeInterval time.Duration
+	StoreMaxSyncLatency  time.Duration
+	StoreMaxReadLatency  time.Duration
+
+	// StoreScrubInterval is the interval at which the checksums of the
+	// data each store holds on disk are verified. A store found to hold
+	// corrupt data is marked suspect in gossip, so that its replicas
+	// may be replaced. Zero disables scrubbing.
+	StoreScrubInterval time.Duration
+
+	// StoreMinAvailable is the free space, in bytes, below which a
+	// persistent store is nearly full: it's marked as such in gossip,
+	// receives no new replicas and refuses writes other than those
+	// which free space, such as deletions. A new store is refused at
+	// startup if less space is available. StoreBallastSize is the size
+	// of the ballast file kept in the data directory of each persistent
+	// store, which may be deleted to free space in an emergency. Zero
+	// disables either.
+	StoreMinAvailable int64
+	StoreBallastSize  int64
+
+	// ScanPrefetchSize is the number of bytes read ahead in the
+	// background by scans of all of a range's data, such as those of
+	// the range scanner and of snapshot generation, on stores with the
+	// hdd attribute. Zero disables prefetching.
+	ScanPrefetchSize int
+
+	// OverloadDumpInterval is the minimum interval between the captures
+	// of the goroutine stacks and heap profile written to the log
+	// directory when the node is overloaded: when the mean latency of
+	// the commands it executes exceeds OverloadLatency, or its number of
+	// goroutines exceeds OverloadGoroutines. Zero disables the captures.
+	OverloadDumpInterval time.Duration
+	OverloadLatency      time.Duration
+	OverloadGoroutines   int
+
+	// ReadCacheSize is the number of read-hot keys whose values each
+	// store caches, as read by consistent, non-transactional Gets served
+	// by its range leaders. A cached value is invalidated by any write
+	// to its range. Zero disables caching.
+	ReadCacheSize int
+
+	// ReplicateTo, if set, is the host:port of a node of a standby
+	// cluster to which the committed writes to the keys with the
+	// comma-separated ReplicatePrefixes are shipped asynchronously,
+	// every ReplicateInterval, for disaster recovery. The standby's
+	// certificates are loaded from ReplicateCerts, or from Certs if it's
+	// empty. Writes are shipped up to the closed timestamps of their
+	// ranges, so ClosedTimestampLag must be positive. See Cutover.
+	ReplicateTo       string
+	ReplicatePrefixes string
+	ReplicateCerts    string
+	ReplicateInterval time.Duration
+
+	// Standby is set on the nodes of a standby cluster, to which a
+	// primary ships its writes. Until the cluster is promoted, the
+	// node's key-value endpoints reject writes and transactions, and
+	// serve reads as of the timestamp at which it last observed the
+	// progress of replication, every ReplicateInterval. See standbyGate.
+	// The node's stores apply the shipped writes at the timestamps at
+	// which the primary committed them.
+	Standby bool
+
+	// DryRun makes the commands which support it report what they
+	// would change, instead of changing it: set-zone reports the
+	// replicas which would be added and removed, and restore the keys
+	// which would be written.
+	DryRun bool `status:"-"`
+
+	// LookupHost, if not nil, is used in place of net.LookupHost to
+	// resolve the hosts of GossipBootstrap addresses.
+	LookupHost func(host string) ([]string, error) `status:"-"`
+
+	// Dial, if not nil, is used in place of net.Dial to connect to
+	// other nodes. Together with LookupHost, it allows tests and
+	// deployments with split-horizon DNS to control how the addresses of
+	// peers are resolved and reached.
+	Dial func(network, address string) (net.Conn, error) `status:"-"`
+
+	// Authorizer, if not nil, authorizes requests in place of the
+	// permission configs, allowing embedders to plug in their own
+	// policies. It's consulted for the requests of root too.
+	Authorizer kv.Authorizer `status:"-"`
+
+	// storeSpecs are the parsed specifications of Stores.
+	storeSpecs []StoreSpec
+	// sharedCacheEngines are the engines which don't set their own
+	// cache size and so follow CacheSize when it's reloaded.
+	sharedCacheEngines []engine.Engine
+
 	// httpClient is a lazily-initialized http client.
 	// It should be accessed through Context.GetHTTPClient() which will
 	// initialize if needed.
@@ -120,42 +357,75 @@ type Context struct {
 // NewContext returns a Context with default values.
 func NewContext() *Context {
 	return &Context{
-		Addr:           defaultAddr,
-		Certs:          defaultCertsDir,
-		MaxOffset:      defaultMaxOffset,
-		GossipInterval: defaultGossipInterval,
-		CacheSize:      defaultCacheSize,
-		ScanInterval:   defaultScanInterval,
+		Addr:               defaultAddr,
+		Certs:              defaultCertsDir,
+		MaxOffset:          defaultMaxOffset,
+		GossipInterval:     defaultGossipInterval,
+		GossipMaxOutgoing:  gossip.MaxPeers,
+		GossipMaxIncoming:  gossip.MaxPeers,
+		MaxBatchRequests:   kv.DefaultMaxBatchRequests,
+		MaxBatchBytes:      kv.DefaultMaxBatchBytes,
+		CacheSize:          defaultCacheSize,
+		ScanInterval:       defaultScanInterval,
+		ClosedTimestampLag: storage.DefaultClosedTimestampLag,
+		SnapshotApplyRate:  storage.DefaultSnapshotApplyRate,
+		TxnAbandonTimeout:  storage.DefaultTxnAbandonTimeout,
+		TraceSampleRate:    storage.DefaultTraceSampleRate,
+
+		StoreIOProbeInterval: storage.DefaultIOProbeInterval,
+		StoreMaxSyncLatency:  storage.DefaultMaxSyncLatency,
+		StoreMaxReadLatency:  storage.DefaultMaxReadLatency,
+		StoreScrubInterval:   storage.DefaultScrubInterval,
+		StoreMinAvailable:    storage.DefaultMinAvailable,
+		StoreBallastSize:     storage.DefaultBallastSize,
+		ScanPrefetchSize:     storage.DefaultScanPrefetchSize,
+
+		OverloadDumpInterval: defaultOverloadDumpInterval,
+		OverloadLatency:      defaultOverloadLatency,
+		OverloadGoroutines:   defaultOverloadGoroutines,
+
+		MaxClientRequestsPerUser: defaultMaxClientRequestsPerUser,
+		MaxClientRequestsPerHost: defaultMaxClientRequestsPerHost,
+		ClientQueueTimeout:       defaultClientQueueTimeout,
+
+		ReplicateInterval: defaultReplicateInterval,
 	}
 }
 
-// Init interprets the stores parameter to initialize a slice of
-// engine.Engine objects, parses node attributes, and initializes
-// the gossip bootstrap resolvers.
+// Init validates the context, interprets the stores parameter to
+// initialize a slice of engine.Engine objects, validates the
+// directories of the persistent stores, parses node attributes, and
+// initializes the gossip bootstrap resolvers.
 func (ctx *Context) Init() error {
-	var err error
-	storesRE := regexp.MustCompile(`([^=]+)=([^,]+)(,|$)`)
-	// Error if regexp doesn't match.
-	storeSpecs := storesRE.FindAllStringSubmatch(ctx.Stores, -1)
-	if storeSpecs == nil || len(storeSpecs) == 0 {
-		return fmt.Errorf("invalid or empty engines specification %q, "+
-			"did you specify -stores?", ctx.Stores)
-	}
+	return ctx.init(false)
+}
 
-	ctx.Engines = nil
-	for _, store := range storeSpecs {
-		if len(store) != 4 {
-			return util.Errorf("unable to parse attributes and path from store %q", store[0])
+// InitDryRun initializes the context as Init does, parsing its stores
+// and loading their encryption keys, but without instantiating,
+// opening or creating the directories of their engines, so that it may
+// be run against the configuration of a running node. Problems which
+// only an engine reports, such as a store directory which isn't
+// writable, are not found.
+func (ctx *Context) InitDryRun() error {
+	return ctx.init(true)
+}
+
+func (ctx *Context) init(dryRun bool) error {
+	if err := ctx.Validate(); err != nil {
+		return err
+	}
+	if dryRun {
+		if _, _, err := ctx.parseStores(); err != nil {
+			return err
 		}
-		// There are two matches for each store specification: the colon-separated
-		// list of attributes and the path.
-		engine, err := ctx.initEngine(store[1], store[2])
-		if err != nil {
-			return util.Errorf("unable to init engine for store %q: %s", store[0], err)
+	} else {
+		if err := ctx.initEngines(); err != nil {
+			return err
+		}
+		if err := validateStoreDirs(ctx.Engines); err != nil {
+			return err
 		}
-		ctx.Engines = append(ctx.Engines, engine)
 	}
-	log.Infof("initialized %d storage engine(s)", len(ctx.Engines))
 
 	ctx.NodeAttributes = parseAttributes(ctx.Attrs)
 
@@ -171,21 +441,255 @@ func (ctx *Context) Init() error {
 	return nil
 }
 
-// initEngine parses the store attributes as a colon-separated list
-// and instantiates an engine based on the dir parameter. If dir parses
-// to an integer, it's taken to mean an in-memory engine; otherwise,
-// dir is treated as a path and a RocksDB engine is created.
-func (ctx *Context) initEngine(attrsStr, path string) (engine.Engine, error) {
-	attrs := parseAttributes(attrsStr)
-	if size, err := strconv.ParseUint(path, 10, 64); err == nil {
-		if size == 0 {
-			return nil, util.Errorf("unable to initialize an in-memory store with capacity 0")
+// validationProblems collects the problems found by a validation pass,
+// so that they may be reported together rather than one at a time.
+type validationProblems []string
+
+func (p *validationProblems) addf(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the addresses, certificate directory, clock offset,
+// intervals, limits and node attributes of the context, returning an
+// error which lists every problem found. The stores and gossip
+// bootstrap addresses are validated as they're parsed by Init.
+func (ctx *Context) Validate() error {
+	var problems validationProblems
+	if ctx.Addr == "" {
+		problems.addf("-addr must be set")
+	} else {
+		validateAddr(&problems, "addr", ctx.Addr)
+	}
+	if ctx.HTTPAddr != "" {
+		validateAddr(&problems, "http-addr", ctx.HTTPAddr)
+	}
+	if ctx.AdvertiseAddr != "" {
+		validateAddr(&problems, "advertise-addr", ctx.AdvertiseAddr)
+	}
+
+	if ctx.Certs != "" && !strings.HasPrefix(ctx.Certs, security.EmbeddedPrefix) {
+		if info, err := os.Stat(ctx.Certs); err != nil {
+			problems.addf("certificate directory %s", err)
+		} else if !info.IsDir() {
+			problems.addf("certificate directory %s is not a directory", ctx.Certs)
 		}
-		return engine.NewInMem(attrs, int64(size)), nil
-		// TODO(spencer): should be using rocksdb for in-memory stores and
-		// relegate the InMem engine to usage only from unittests.
 	}
-	return engine.NewRocksDB(attrs, path, ctx.CacheSize), nil
+
+	if ctx.MaxOffset < 0 || ctx.MaxOffset > maxMaxOffset {
+		problems.addf("max clock offset must be between 0 and %s: %s", maxMaxOffset, ctx.MaxOffset)
+	}
+	ctx.ReloadableSettings().check(&problems)
+	if ctx.GossipMaxOutgoing < 1 || ctx.GossipMaxIncoming < 1 {
+		problems.addf("gossip connection limits must be positive: %d outgoing, %d incoming",
+			ctx.GossipMaxOutgoing, ctx.GossipMaxIncoming)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"closed timestamp lag", ctx.ClosedTimestampLag},
+		{"transaction abandon timeout", ctx.TxnAbandonTimeout},
+		{"store IO probe interval", ctx.StoreIOProbeInterval},
+		{"store scrub interval", ctx.StoreScrubInterval},
+		{"overload dump interval", ctx.OverloadDumpInterval},
+		{"client queue timeout", ctx.ClientQueueTimeout},
+		{"hedge read timeout", ctx.HedgeReadTimeout},
+	} {
+		if d.value < 0 {
+			problems.addf("%s must not be negative: %s", d.name, d.value)
+		}
+	}
+	if ctx.StoreIOProbeInterval > 0 && (ctx.StoreMaxSyncLatency <= 0 || ctx.StoreMaxReadLatency <= 0) {
+		problems.addf("store IO latency limits must be positive: %s sync, %s read",
+			ctx.StoreMaxSyncLatency, ctx.StoreMaxReadLatency)
+	}
+	if ctx.OverloadDumpInterval > 0 && (ctx.OverloadLatency <= 0 || ctx.OverloadGoroutines <= 0) {
+		problems.addf("overload limits must be positive: %s latency, %d goroutines",
+			ctx.OverloadLatency, ctx.OverloadGoroutines)
+	}
+	for _, n := range []struct {
+		name  string
+		value int64
+	}{
+		{"max batch requests", int64(ctx.MaxBatchRequests)},
+		{"max batch bytes", ctx.MaxBatchBytes},
+		{"max client requests per user", int64(ctx.MaxClientRequestsPerUser)},
+		{"max client requests per host", int64(ctx.MaxClientRequestsPerHost)},
+		{"retry budget max retries", int64(ctx.RetryBudgetMaxRetries)},
+		{"snapshot apply rate", ctx.SnapshotApplyRate},
+		{"read cache size", int64(ctx.ReadCacheSize)},
+		{"store min available", ctx.StoreMinAvailable},
+		{"store ballast size", ctx.StoreBallastSize},
+		{"scan prefetch size", int64(ctx.ScanPrefetchSize)},
+	} {
+		if n.value < 0 {
+			problems.addf("%s must not be negative: %d", n.name, n.value)
+		}
+	}
+	if ctx.RetryBudgetRatio < 0 || ctx.RetryBudgetRatio > 1 {
+		problems.addf("retry budget ratio must be between 0 and 1: %g", ctx.RetryBudgetRatio)
+	}
+
+	if ctx.ReplicateTo != "" {
+		validateAddr(&problems, "replicate-to", ctx.ReplicateTo)
+		if len(ctx.replicatePrefixes()) == 0 {
+			problems.addf("-replicate-prefixes must be set to replicate to %s", ctx.ReplicateTo)
+		}
+		if ctx.ClosedTimestampLag <= 0 {
+			problems.addf("closed timestamp lag must be positive to replicate to %s", ctx.ReplicateTo)
+		}
+		if ctx.ReplicateInterval <= 0 {
+			problems.addf("replicate interval must be positive: %s", ctx.ReplicateInterval)
+		}
+	}
+	if ctx.Standby && ctx.ReplicateInterval <= 0 {
+		problems.addf("replicate interval must be positive on a standby: %s", ctx.ReplicateInterval)
+	}
+	for _, prefix := range ctx.replicatePrefixes() {
+		if bytes.Compare(prefix, engine.KeySystemMax) < 0 {
+			problems.addf("replicated prefix %q must not hold system keys", prefix)
+		}
+	}
+
+	if err := ctx.StoreTuning.Validate(); err != nil {
+		problems.addf("store tuning: %s", err)
+	}
+
+	for _, attr := range parseAttributes(ctx.Attrs).Attrs {
+		if strings.IndexFunc(attr, unicode.IsSpace) >= 0 {
+			problems.addf("node attribute %q must not contain whitespace", attr)
+		}
+	}
+
+	if len(problems) > 0 {
+		return util.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// replicatePrefixes returns the parsed ReplicatePrefixes.
+func (ctx *Context) replicatePrefixes() []proto.Key {
+	var prefixes []proto.Key
+	for _, p := range strings.Split(ctx.ReplicatePrefixes, ",") {
+		if p != "" {
+			prefixes = append(prefixes, proto.Key(p))
+		}
+	}
+	return prefixes
+}
+
+// validateAddr adds a problem if addr, the value of the named flag,
+// names neither a host and a port nor a unix socket.
+func validateAddr(problems *validationProblems, flagName, addr string) {
+	if strings.HasPrefix(addr, util.UnixAddrPrefix) {
+		if addr == util.UnixAddrPrefix {
+			problems.addf("-%s %q must name the path of a unix socket", flagName, addr)
+		}
+		return
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		problems.addf("-%s %q must be of the form host:port: %s", flagName, addr, err)
+		return
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+		problems.addf("-%s %q must have a port between 0 and 65535", flagName, addr)
+	}
+}
+
+// parseStores parses the stores parameter into ctx.storeSpecs and
+// returns the cache size of the stores which don't set their own and
+// the keys of the encrypted stores.
+func (ctx *Context) parseStores() (int64, engine.EncryptionKeys, error) {
+	specs, err := ParseStoreSpecs(ctx.Stores)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(specs) == 0 {
+		return 0, nil, fmt.Errorf("invalid or empty engines specification %q, "+
+			"did you specify -stores?", ctx.Stores)
+	}
+
+	cacheSize, err := sharedCacheSize(ctx.CacheSize, specs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, spec := range specs {
+		if spec.ReadOnly {
+			return 0, nil, util.Errorf("store %q is read-only; read-only stores may only be opened "+
+				"by the debug commands", spec.Location)
+		}
+	}
+
+	var keys engine.EncryptionKeys
+	for _, spec := range specs {
+		if len(spec.EncryptionKeyID) == 0 || keys != nil {
+			continue
+		}
+		if len(ctx.StoreKeyFile) == 0 {
+			return 0, nil, util.Errorf("store %q is encrypted, so -store-key-file must be set", spec.Location)
+		}
+		if keys, err = engine.LoadEncryptionKeys(ctx.StoreKeyFile); err != nil {
+			return 0, nil, util.Errorf("unable to load store encryption keys: %s", err)
+		}
+	}
+	ctx.storeSpecs = specs
+	return cacheSize, keys, nil
+}
+
+// initEngines interprets the stores parameter to initialize a slice
+// of engine.Engine objects.
+func (ctx *Context) initEngines() error {
+	cacheSize, keys, err := ctx.parseStores()
+	if err != nil {
+		return err
+	}
+
+	ctx.Engines = nil
+	ctx.sharedCacheEngines = nil
+	for _, spec := range ctx.storeSpecs {
+		engine, err := spec.newEngine(cacheSize, ctx.StoreTuning, keys, ctx.StoreMinAvailable)
+		if err != nil {
+			return util.Errorf("unable to init engine for store %q: %s", spec.Location, err)
+		}
+		ctx.Engines = append(ctx.Engines, engine)
+		if spec.CacheSize == 0 {
+			ctx.sharedCacheEngines = append(ctx.sharedCacheEngines, engine)
+		}
+	}
+	log.Infof("initialized %d storage engine(s)", len(ctx.Engines))
+	return nil
+}
+
+// ReloadableSettings returns the settings of the context which may be
+// changed while a node is running; see ReloadableContext.
+func (ctx *Context) ReloadableSettings() ReloadableSettings {
+	return ReloadableSettings{
+		ScanInterval:    ctx.ScanInterval,
+		GossipInterval:  ctx.GossipInterval,
+		CacheSize:       ctx.CacheSize,
+		Verbosity:       log.Verbosity(),
+		TraceSampleRate: ctx.TraceSampleRate,
+	}
+}
+
+// advertisedAddr returns the address at which other nodes reach this
+// one: AdvertiseAddr if it's set and Addr otherwise.
+func (ctx *Context) advertisedAddr() string {
+	if ctx.AdvertiseAddr != "" {
+		return ctx.AdvertiseAddr
+	}
+	return ctx.Addr
+}
+
+// HTTPHost returns the host by which the URLs of HTTP requests name
+// this node: that of HTTPAddr if it's set and of Addr otherwise.
+func (ctx *Context) HTTPHost() string {
+	if ctx.HTTPAddr != "" {
+		return util.HTTPHost(ctx.HTTPAddr)
+	}
+	return util.HTTPHost(ctx.Addr)
 }
 
 // parseGossipBootstrapResolvers parses a comma-separated list of
@@ -202,9 +706,9 @@ func (ctx *Context) parseGossipBootstrapResolvers() ([]gossip.Resolver, error) {
 		// the port for single-node clusters twice (once in -addr,
 		// once in -gossip).
 		if strings.HasPrefix(address, "self://") {
-			address = util.EnsureHost(ctx.Addr)
+			address = ctx.advertisedAddr()
 		}
-		resolver, err := gossip.NewResolver(address)
+		resolver, err := gossip.NewResolverWithLookup(address, ctx.LookupHost)
 		if err != nil {
 			return nil, err
 		}
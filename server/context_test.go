@@ -19,13 +19,15 @@ package server
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/gossip"
 )
 
 func TestParseNodeAttributes(t *testing.T) {
-	ctx := NewContext()
+	ctx := newTestContext()
 	ctx.Attrs = "attr1=val1::attr2=val2"
 	ctx.Stores = "mem=1"
 	ctx.GossipBootstrap = "self://"
@@ -41,7 +43,7 @@ func TestParseNodeAttributes(t *testing.T) {
 // TestParseGossipBootstrapAddrs verifies that GossipBootstrap is
 // parsed correctly.
 func TestParseGossipBootstrapAddrs(t *testing.T) {
-	ctx := NewContext()
+	ctx := newTestContext()
 	ctx.GossipBootstrap = "localhost:12345,,localhost:23456"
 	ctx.Stores = "mem=1"
 	if err := ctx.Init(); err != nil {
@@ -60,3 +62,74 @@ func TestParseGossipBootstrapAddrs(t *testing.T) {
 		t.Fatalf("Unexpected bootstrap addresses: %v, expected: %v", ctx.GossipBootstrapResolvers, expected)
 	}
 }
+
+// TestParseGossipBootstrapSelfAdvertised verifies that a self://
+// bootstrap address resolves to the advertised address, if set.
+func TestParseGossipBootstrapSelfAdvertised(t *testing.T) {
+	ctx := newTestContext()
+	ctx.Addr = ":26257"
+	ctx.AdvertiseAddr = "10.0.0.1:36257"
+	ctx.GossipBootstrap = "self://"
+	ctx.Stores = "mem=1"
+	if err := ctx.Init(); err != nil {
+		t.Fatalf("Failed to initialize the context: %v", err)
+	}
+	r, err := gossip.NewResolver("tcp=10.0.0.1:36257")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []gossip.Resolver{r}
+	if !reflect.DeepEqual(ctx.GossipBootstrapResolvers, expected) {
+		t.Fatalf("Unexpected bootstrap addresses: %v, expected: %v", ctx.GossipBootstrapResolvers, expected)
+	}
+}
+
+// TestContextValidate verifies that Validate reports every problem
+// with a context at once.
+func TestContextValidate(t *testing.T) {
+	ctx := newTestContext()
+	if err := ctx.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Addr = "localhost:65536"
+	ctx.HTTPAddr = "localhost"
+	ctx.AdvertiseAddr = "unix://"
+	ctx.Certs = "/nonexistent-certs"
+	ctx.MaxOffset = -time.Second
+	ctx.ScanInterval = 0
+	ctx.GossipMaxOutgoing = 0
+	ctx.TxnAbandonTimeout = -time.Second
+	ctx.ReadCacheSize = -1
+	ctx.RetryBudgetRatio = 2
+	ctx.StoreTuning.Compression = "zip"
+	ctx.Attrs = "ssd:us east"
+	ctx.ReplicateTo = "standby:8080"
+	ctx.ClosedTimestampLag = 0
+	err := ctx.Validate()
+	if err == nil {
+		t.Fatal("expected invalid context")
+	}
+	for _, expected := range []string{
+		"-addr \"localhost:65536\" must have a port",
+		"-http-addr \"localhost\" must be of the form host:port",
+		"-advertise-addr \"unix://\" must name the path",
+		"certificate directory",
+		"max clock offset",
+		"scan interval must be positive",
+		"gossip connection limits",
+		"transaction abandon timeout must not be negative",
+		"read cache size must not be negative",
+		"retry budget ratio must be between 0 and 1",
+		"store tuning: unknown compression \"zip\"",
+		"node attribute \"us east\"",
+		"-replicate-prefixes must be set",
+		"closed timestamp lag must be positive",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q; got %s", expected, err)
+		}
+	}
+	if err := ctx.Init(); err == nil {
+		t.Error("expected Init to validate the context")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// A DrainPhase is a step of the sequence in which a node is drained.
+type DrainPhase string
+
+const (
+	// DrainServing is the phase of a node which isn't draining.
+	DrainServing DrainPhase = "serving"
+	// DrainTransferringLeases is the phase in which the node's stores
+	// transfer their leader leases to other replicas of their ranges.
+	DrainTransferringLeases DrainPhase = "transferring-leases"
+	// DrainFlushing is the phase in which the node's stores flush
+	// their engines.
+	DrainFlushing DrainPhase = "flushing"
+	// DrainDone is the phase of a node ready to be shut down.
+	DrainDone DrainPhase = "done"
+)
+
+const (
+	// drainLeaseTimeout is the longest a drain waits for the leader
+	// leases of a node's stores to be transferred before flushing them
+	// regardless.
+	drainLeaseTimeout = 20 * time.Second
+	// drainLeaseInterval is how often leases still held by draining
+	// stores are transferred again.
+	drainLeaseInterval = 500 * time.Millisecond
+)
+
+// DrainStatus describes the progress of a node's drain.
+type DrainStatus struct {
+	Phase DrainPhase `json:"phase"`
+	// StartedAt is when the drain began; zero if the node isn't
+	// draining.
+	StartedAt time.Time `json:"started_at"`
+	// LeasesTransferred is the number of leader lease transfers
+	// proposed by the most recent attempt to shed the stores' leases.
+	LeasesTransferred int `json:"leases_transferred"`
+	// Error holds the errors encountered flushing the stores, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// A drainer takes a node through the drain sequence before it's shut
+// down: client requests are refused, the leader leases held by its
+// stores are transferred to other replicas, and the stores' engines
+// are flushed. A drain can't be undone; the node is expected to exit
+// once it's done.
+type drainer struct {
+	node    *Node
+	stopper *util.Stopper
+	done    chan struct{} // Closed once the drain is done
+
+	mu     sync.Mutex
+	status DrainStatus
+}
+
+// newDrainer returns a drainer for the node's stores.
+func newDrainer(node *Node, stopper *util.Stopper) *drainer {
+	return &drainer{
+		node:    node,
+		stopper: stopper,
+		done:    make(chan struct{}),
+		status:  DrainStatus{Phase: DrainServing},
+	}
+}
+
+// draining returns whether a drain has been started.
+func (d *drainer) draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status.Phase != DrainServing
+}
+
+// getStatus returns the progress of the drain.
+func (d *drainer) getStatus() DrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// setPhase records that the drain entered the phase.
+func (d *drainer) setPhase(phase DrainPhase) {
+	log.Infof("drain: %s", phase)
+	d.mu.Lock()
+	d.status.Phase = phase
+	d.mu.Unlock()
+}
+
+// start starts the drain, unless it's already been started, and
+// returns a channel which is closed once it's done.
+func (d *drainer) start() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.status.Phase == DrainServing {
+		d.status.Phase = DrainTransferringLeases
+		d.status.StartedAt = time.Now()
+		go d.run()
+	}
+	return d.done
+}
+
+// run transfers the leases of the node's stores and flushes them.
+func (d *drainer) run() {
+	log.Infof("drain: %s", DrainTransferringLeases)
+	d.node.lSender.VisitStores(func(s *storage.Store) error {
+		s.SetDraining(true)
+		return nil
+	})
+	d.shedLeases()
+
+	d.setPhase(DrainFlushing)
+	var errs []string
+	d.node.lSender.VisitStores(func(s *storage.Store) error {
+		if err := s.Engine().Flush(); err != nil {
+			log.Errorf("drain: unable to flush store %s: %s", s, err)
+			errs = append(errs, err.Error())
+		}
+		return nil
+	})
+	d.mu.Lock()
+	d.status.Error = strings.Join(errs, "; ")
+	d.mu.Unlock()
+
+	d.setPhase(DrainDone)
+	close(d.done)
+}
+
+// shedLeases transfers the leader leases of the node's stores until
+// none remain to be transferred, the lease timeout elapses or the
+// node is stopped.
+func (d *drainer) shedLeases() {
+	deadline := time.After(drainLeaseTimeout)
+	ticker := time.NewTicker(drainLeaseInterval)
+	defer ticker.Stop()
+	for transferred := d.transferLeases(); transferred > 0; transferred = d.transferLeases() {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			log.Warningf("drain: %d leader leases not transferred within %s", transferred, drainLeaseTimeout)
+			return
+		case <-d.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// transferLeases transfers the leader leases of the node's stores and
+// returns the number of transfers proposed.
+func (d *drainer) transferLeases() int {
+	var transferred int
+	d.node.lSender.VisitStores(func(s *storage.Store) error {
+		transferred += s.TransferLeaderLeases()
+		return nil
+	})
+	d.mu.Lock()
+	d.status.LeasesTransferred = transferred
+	d.mu.Unlock()
+	return transferred
+}
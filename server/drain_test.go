@@ -0,0 +1,91 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/kv"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestDrain verifies that a drain started at the drain endpoint
+// completes, after which client requests are refused, the health
+// endpoint reports the drain and the node's stores don't request
+// leader leases.
+func TestDrain(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	httpClient := client.CreateTestHTTPClient()
+	url := "https://" + s.ServingAddr() + drainPath
+
+	getStatus := func(resp *http.Response, err error) DrainStatus {
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200; got %d: %s", resp.StatusCode, b)
+		}
+		var status DrainStatus
+		if err := json.Unmarshal(b, &status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+
+	if status := getStatus(httpClient.Get(url)); status.Phase != DrainServing {
+		t.Fatalf("expected phase %q before drain; got %+v", DrainServing, status)
+	}
+	if status := getStatus(httpClient.Post(url, "text/plain", nil)); status.Phase == DrainServing {
+		t.Fatalf("expected drain to start; got %+v", status)
+	}
+	util.SucceedsWithin(t, 5*time.Second, func() error {
+		if status := getStatus(httpClient.Get(url)); status.Phase != DrainDone {
+			return util.Errorf("drain not done: %+v", status)
+		}
+		return nil
+	})
+
+	for _, path := range []string{healthPath, kv.EntryPrefix + "a"} {
+		resp, err := httpClient.Get("https://" + s.ServingAddr() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected status 503; got %d", path, resp.StatusCode)
+		}
+	}
+	if err := s.node.lSender.VisitStores(func(store *storage.Store) error {
+		if !store.Draining() {
+			return util.Errorf("store %s is not draining", store)
+		}
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"code.google.com/p/go-uuid/uuid"
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/kv"
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/rpc"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+	"golang.org/x/net/context"
+)
+
+// An Embedded is a single-node cluster run within the calling process,
+// for unit tests and applications using the key-value store as a
+// library. It neither listens on the network nor joins a gossip
+// network: its stores serve the commands of its client directly, and
+// the gossip instance relaying system configs between its ranges and
+// stores is never started.
+type Embedded struct {
+	ctx     *Context
+	clock   *hlc.Clock
+	stopper *util.Stopper
+	lSender *kv.LocalSender
+	kv      *client.KV
+}
+
+// NewEmbedded starts an embedded single-node cluster on the engines
+// of ctx, which are created from its stores parameter if not already
+// set. If none of the engines belongs to a cluster, a new cluster is
+// bootstrapped on the first one, with the system zone config of ctx
+// if any. Other unbootstrapped engines are added to the cluster.
+func NewEmbedded(ctx *Context) (*Embedded, error) {
+	if len(ctx.Engines) == 0 {
+		if err := ctx.initEngines(); err != nil {
+			return nil, err
+		}
+	}
+	e := &Embedded{
+		ctx:     ctx,
+		clock:   hlc.NewClock(hlc.UnixNano),
+		stopper: util.NewStopper(),
+		lSender: kv.NewLocalSender(),
+	}
+	e.clock.SetMaxOffset(ctx.MaxOffset)
+	sender := kv.NewTxnCoordSender(e.lSender, e.clock, ctx.Linearizable, e.stopper)
+	sender.SetBatchLimits(ctx.MaxBatchRequests, ctx.MaxBatchBytes)
+	e.kv = client.NewKV(nil, sender)
+	e.kv.User = storage.UserRoot
+	if err := e.startStores(); err != nil {
+		e.Stop()
+		return nil, err
+	}
+	return e, nil
+}
+
+// startStores starts a store for each engine, bootstrapping the
+// cluster and any new stores as necessary.
+func (e *Embedded) startStores() error {
+	rpcContext := rpc.NewContext(e.clock, security.LoadInsecureTLSConfig(), e.stopper)
+	sCtx := storage.StoreContext{
+		Clock:              e.clock,
+		DB:                 e.kv,
+		Gossip:             gossip.New(rpcContext, e.ctx.GossipInterval, nil),
+		Transport:          multiraft.NewLocalRPCTransport(),
+		Context:            context.Background(),
+		ScanInterval:       e.ctx.ScanInterval,
+		ClosedTimestampLag: e.ctx.ClosedTimestampLag,
+		SnapshotApplyRate:  e.ctx.SnapshotApplyRate,
+		TxnAbandonTimeout:  e.ctx.TxnAbandonTimeout,
+	}
+	var ident proto.StoreIdent
+	var bootstraps []*storage.Store
+	for _, eng := range e.ctx.Engines {
+		s := storage.NewStore(sCtx, eng)
+		if err := s.Start(e.stopper); err != nil {
+			if _, ok := err.(*storage.NotBootstrappedError); !ok {
+				return util.Errorf("failed to start store: %s", err)
+			}
+			bootstraps = append(bootstraps, s)
+			continue
+		}
+		if ident.ClusterID == "" {
+			ident = s.Ident
+		} else if s.Ident.ClusterID != ident.ClusterID || s.Ident.NodeID != ident.NodeID {
+			return util.Errorf("store %s belongs to cluster %s, node %d; expected cluster %s, node %d",
+				s, s.Ident.ClusterID, s.Ident.NodeID, ident.ClusterID, ident.NodeID)
+		}
+		e.lSender.AddStore(s)
+	}
+
+	if ident.ClusterID == "" {
+		if err := e.bootstrapCluster(bootstraps[0]); err != nil {
+			return err
+		}
+		ident = bootstraps[0].Ident
+		bootstraps = bootstraps[1:]
+	}
+	if len(bootstraps) == 0 {
+		return nil
+	}
+	firstID, err := allocateStoreIDs(ident.NodeID, int64(len(bootstraps)), e.kv)
+	if err != nil {
+		return err
+	}
+	ident.StoreID = firstID
+	for _, s := range bootstraps {
+		if err := s.Bootstrap(ident, e.stopper); err != nil {
+			return err
+		}
+		if err := s.Start(e.stopper); err != nil {
+			return err
+		}
+		e.lSender.AddStore(s)
+		log.Infof("bootstrapped store %s", s)
+		ident.StoreID++
+	}
+	return nil
+}
+
+// bootstrapCluster bootstraps a new cluster on the engine of the
+// store and starts the store.
+func (e *Embedded) bootstrapCluster(s *storage.Store) error {
+	var systemZone *proto.ZoneConfig
+	if e.ctx.SystemZone != "" {
+		var err error
+		if systemZone, err = LoadZoneConfig(e.ctx.SystemZone); err != nil {
+			return err
+		}
+	}
+	stopper := util.NewStopper()
+	_, err := BootstrapCluster(uuid.New(), s.Engine(), systemZone, stopper)
+	stopper.Stop()
+	if err != nil {
+		return util.Errorf("unable to bootstrap cluster: %s", err)
+	}
+	if err := s.Start(e.stopper); err != nil {
+		return util.Errorf("failed to start store: %s", err)
+	}
+	e.lSender.AddStore(s)
+	log.Infof("bootstrapped cluster %s on store %s", s.Ident.ClusterID, s)
+	return nil
+}
+
+// KV returns a client of the cluster. Commands are sent directly to
+// the stores.
+func (e *Embedded) KV() *client.KV {
+	return e.kv
+}
+
+// Stop stops the cluster and closes its engines.
+func (e *Embedded) Stop() {
+	e.stopper.Stop()
+}
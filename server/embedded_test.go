@@ -0,0 +1,58 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+)
+
+// TestEmbedded starts an embedded cluster on two new stores, verifies
+// that it serves reads and writes, and restarts it on the same
+// engines.
+func TestEmbedded(t *testing.T) {
+	ctx := NewContext()
+	ctx.Engines = []engine.Engine{
+		engine.NewInMem(proto.Attributes{}, 1<<20),
+		engine.NewInMem(proto.Attributes{}, 1<<20),
+	}
+	e, err := NewEmbedded(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.KV().Run(client.PutCall(proto.Key("a"), []byte("value"))); err != nil {
+		t.Fatal(err)
+	}
+	e.Stop()
+
+	if e, err = NewEmbedded(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+	if n := e.lSender.GetStoreCount(); n != 2 {
+		t.Errorf("expected 2 stores; got %d", n)
+	}
+	call := client.GetCall(proto.Key("a"))
+	if err := e.KV().Run(call); err != nil {
+		t.Fatal(err)
+	}
+	if gr := call.Reply.(*proto.GetResponse); gr.Value == nil || string(gr.Value.Bytes) != "value" {
+		t.Errorf("expected value after restart; got %+v", gr.Value)
+	}
+}
@@ -0,0 +1,567 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+const (
+	// jobHeartbeatInterval is the interval at which the record of a
+	// running job is touched to show that the process running it is
+	// alive.
+	jobHeartbeatInterval = 10 * time.Second
+	// jobOrphanTimeout is the time after which a queued or running job
+	// whose record hasn't been modified is considered abandoned by the
+	// process which was running it, and is adopted by a node.
+	jobOrphanTimeout = 6 * jobHeartbeatInterval
+)
+
+// errJobPaused is returned by Job.Progress when the job has been
+// paused, either on request or because the node is shutting down.
+var errJobPaused = util.Error("job paused")
+
+// A JobFunc performs the work of a job. Implementations should report
+// their progress periodically via Job.Progress and return promptly
+// with its error if it fails; this is how pause requests and node
+// shutdown are delivered. A resumed job is run again from the start
+// with the progress of its record intact, which implementations may
+// use to skip work already completed.
+type JobFunc func(job *Job) error
+
+// A Job is the handle through which a JobFunc accesses its record.
+type Job struct {
+	coord *JobCoordinator
+	id    int64
+}
+
+// ID returns the job's ID.
+func (j *Job) ID() int64 {
+	return j.id
+}
+
+// Record returns the current persisted record of the job.
+func (j *Job) Record() (*proto.JobRecord, error) {
+	return j.coord.Get(j.id)
+}
+
+// Progress records the fraction of the job's work which has been
+// completed. It returns errJobPaused if the job has been paused or
+// the node is shutting down, in which case the JobFunc should stop
+// and return that error.
+func (j *Job) Progress(fraction float64) error {
+	select {
+	case <-j.coord.stopper.ShouldStop():
+		return errJobPaused
+	default:
+	}
+	return j.coord.update(j.id, func(r *proto.JobRecord) error {
+		if r.Status != proto.JobStatus_RUNNING {
+			return errJobPaused
+		}
+		r.Progress = fraction
+		return nil
+	})
+}
+
+// A JobCoordinator creates and runs jobs, persisting their records
+// under the job key prefix so that they may be queried and controlled
+// from any node. Once started, it adopts the jobs abandoned by
+// processes which died while running them.
+type JobCoordinator struct {
+	db      *client.KV
+	clock   *hlc.Clock
+	stopper *util.Stopper
+
+	heartbeatInterval time.Duration
+	orphanTimeout     time.Duration
+
+	mu    sync.Mutex
+	funcs map[proto.JobType]JobFunc
+}
+
+// NewJobCoordinator returns a new JobCoordinator. No jobs may be
+// created until a JobFunc has been registered for their type.
+func NewJobCoordinator(db *client.KV, clock *hlc.Clock, stopper *util.Stopper) *JobCoordinator {
+	return &JobCoordinator{
+		db:                db,
+		clock:             clock,
+		stopper:           stopper,
+		heartbeatInterval: jobHeartbeatInterval,
+		orphanTimeout:     jobOrphanTimeout,
+		funcs:             map[proto.JobType]JobFunc{},
+	}
+}
+
+// Start adopts the abandoned jobs now, as those left by an earlier
+// run of this node are, and then periodically until the stopper is
+// stopped. Jobs must be registered before the coordinator is started.
+func (jc *JobCoordinator) Start() {
+	jc.stopper.RunWorker(func() {
+		ticker := time.NewTicker(jc.orphanTimeout)
+		defer ticker.Stop()
+		for {
+			if err := jc.adoptOrphans(); err != nil {
+				log.Warningf("unable to adopt abandoned jobs: %s", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-jc.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// adoptOrphans restarts, on this node, the queued and running jobs
+// whose records haven't been modified within the orphan timeout. The
+// processes running them have died, as a running job's record is
+// touched every heartbeat interval. Jobs of a type this node doesn't
+// run, such as the backups and restores run by commands, are marked
+// failed instead.
+func (jc *JobCoordinator) adoptOrphans() error {
+	records, err := jc.List()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if !jc.isOrphan(&record) {
+			continue
+		}
+		id := record.ID
+		var fn JobFunc
+		if err := jc.update(id, func(r *proto.JobRecord) error {
+			// The job may have been adopted by another node since it
+			// was listed.
+			if !jc.isOrphan(r) {
+				return errJobPaused
+			}
+			var err error
+			if fn, err = jc.getFunc(r.Type); err != nil {
+				fn = nil
+				r.Status = proto.JobStatus_FAILED
+				r.Error = "abandoned by the process running it"
+				return nil
+			}
+			r.Status = proto.JobStatus_QUEUED
+			return nil
+		}); err != nil {
+			if err != errJobPaused {
+				log.Warningf("unable to adopt job %d: %s", id, err)
+			}
+			continue
+		}
+		if fn != nil {
+			log.Infof("adopting abandoned job %d: %s", id, record.Description)
+			jc.run(id, fn)
+		} else {
+			log.Warningf("marked abandoned job %d failed: %s", id, record.Description)
+		}
+	}
+	return nil
+}
+
+// isOrphan returns whether the job of the record is queued or running
+// but has been abandoned.
+func (jc *JobCoordinator) isOrphan(r *proto.JobRecord) bool {
+	if r.Status != proto.JobStatus_QUEUED && r.Status != proto.JobStatus_RUNNING {
+		return false
+	}
+	return jc.clock.PhysicalNow()-r.ModifiedNanos > jc.orphanTimeout.Nanoseconds()
+}
+
+// Register sets the function used to run jobs of the given type.
+func (jc *JobCoordinator) Register(typ proto.JobType, fn JobFunc) {
+	jc.mu.Lock()
+	defer jc.mu.Unlock()
+	jc.funcs[typ] = fn
+}
+
+func (jc *JobCoordinator) getFunc(typ proto.JobType) (JobFunc, error) {
+	jc.mu.Lock()
+	defer jc.mu.Unlock()
+	fn, ok := jc.funcs[typ]
+	if !ok {
+		return nil, util.Errorf("no function registered for %s jobs", typ)
+	}
+	return fn, nil
+}
+
+// Create persists a record for a new job of the given type and starts
+// running it on this node. Returns the ID of the new job.
+func (jc *JobCoordinator) Create(typ proto.JobType, description string) (int64, error) {
+	id, fn, err := jc.create(typ, description)
+	if err != nil {
+		return 0, err
+	}
+	jc.run(id, fn)
+	return id, nil
+}
+
+// Run persists a record for a new job of the given type and runs it
+// on the calling goroutine, returning the job's ID and the error with
+// which it stopped, if any. It allows processes other than nodes, such
+// as the backup and restore commands, to track the work they perform
+// themselves as jobs. Such a job may be paused from any node, but as
+// no node runs its type of job, it can't be resumed, and should the
+// process die, the node adopting the job marks it failed.
+func (jc *JobCoordinator) Run(typ proto.JobType, description string) (int64, error) {
+	id, fn, err := jc.create(typ, description)
+	if err != nil {
+		return 0, err
+	}
+	return id, jc.execute(id, fn)
+}
+
+// create persists a record for a new job of the given type, returning
+// its ID and the function registered to run it.
+func (jc *JobCoordinator) create(typ proto.JobType, description string) (int64, JobFunc, error) {
+	fn, err := jc.getFunc(typ)
+	if err != nil {
+		return 0, nil, err
+	}
+	call := client.IncrementCall(engine.KeyJobIDGenerator, 1)
+	if err := jc.db.Run(call); err != nil {
+		return 0, nil, util.Errorf("unable to allocate job ID: %s", err)
+	}
+	now := jc.clock.PhysicalNow()
+	record := &proto.JobRecord{
+		ID:            call.Reply.(*proto.IncrementResponse).NewValue,
+		Type:          typ,
+		Description:   description,
+		Status:        proto.JobStatus_QUEUED,
+		CreatedNanos:  now,
+		ModifiedNanos: now,
+	}
+	if err := jc.db.Run(client.PutProtoCall(engine.JobKey(record.ID), record)); err != nil {
+		return 0, nil, err
+	}
+	return record.ID, fn, nil
+}
+
+// Get returns the record of the job with the given ID.
+func (jc *JobCoordinator) Get(id int64) (*proto.JobRecord, error) {
+	record := &proto.JobRecord{}
+	if err := getJobRecord(jc.db.Run, id, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// List returns the records of all jobs, ordered by ID.
+func (jc *JobCoordinator) List() ([]proto.JobRecord, error) {
+	call := client.ScanCall(engine.KeyJobPrefix, engine.KeyJobPrefix.PrefixEnd(), maxGetResults)
+	call.Args.Header().User = storage.UserRoot
+	if err := jc.db.Run(call); err != nil {
+		return nil, err
+	}
+	rows := call.Reply.(*proto.ScanResponse).Rows
+	records := make([]proto.JobRecord, len(rows))
+	for i, kv := range rows {
+		if err := gogoproto.Unmarshal(kv.Value.Bytes, &records[i]); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// Pause requests that the job with the given ID stop running. A
+// running job stops the next time it reports its progress.
+func (jc *JobCoordinator) Pause(id int64) error {
+	return jc.update(id, func(r *proto.JobRecord) error {
+		if r.Status != proto.JobStatus_QUEUED && r.Status != proto.JobStatus_RUNNING {
+			return util.Errorf("job %d cannot be paused in state %s", id, r.Status)
+		}
+		r.Status = proto.JobStatus_PAUSED
+		return nil
+	})
+}
+
+// Resume restarts a paused job on this node.
+func (jc *JobCoordinator) Resume(id int64) error {
+	var fn JobFunc
+	if err := jc.update(id, func(r *proto.JobRecord) error {
+		if r.Status != proto.JobStatus_PAUSED {
+			return util.Errorf("job %d cannot be resumed in state %s", id, r.Status)
+		}
+		var err error
+		if fn, err = jc.getFunc(r.Type); err != nil {
+			return err
+		}
+		r.Status = proto.JobStatus_QUEUED
+		return nil
+	}); err != nil {
+		return err
+	}
+	jc.run(id, fn)
+	return nil
+}
+
+// run executes the job with the given ID in a worker goroutine.
+func (jc *JobCoordinator) run(id int64, fn JobFunc) {
+	jc.stopper.RunWorker(func() {
+		_ = jc.execute(id, fn)
+	})
+}
+
+// execute runs fn for the job with the given ID, records its outcome
+// and returns its error. Jobs interrupted by shutdown are left paused
+// so that they may be resumed.
+func (jc *JobCoordinator) execute(id int64, fn JobFunc) error {
+	job := &Job{coord: jc, id: id}
+	err := jc.update(id, func(r *proto.JobRecord) error {
+		if r.Status != proto.JobStatus_QUEUED {
+			return errJobPaused
+		}
+		r.Status = proto.JobStatus_RUNNING
+		return nil
+	})
+	if err == nil {
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			jc.heartbeat(id, done)
+		}()
+		err = fn(job)
+		close(done)
+		<-stopped
+	}
+	if uErr := jc.update(id, func(r *proto.JobRecord) error {
+		switch {
+		case err == errJobPaused:
+			if r.Status == proto.JobStatus_RUNNING {
+				r.Status = proto.JobStatus_PAUSED
+			}
+		case err != nil:
+			r.Status = proto.JobStatus_FAILED
+			r.Error = err.Error()
+		default:
+			r.Status = proto.JobStatus_SUCCEEDED
+			r.Progress = 1
+		}
+		return nil
+	}); uErr != nil {
+		log.Errorf("unable to record outcome of job %d: %s", id, uErr)
+	}
+	return err
+}
+
+// heartbeat touches the record of the running job with the given ID
+// every heartbeat interval until done is closed, so that the job isn't
+// adopted by another node while it's running.
+func (jc *JobCoordinator) heartbeat(id int64, done <-chan struct{}) {
+	ticker := time.NewTicker(jc.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := jc.update(id, func(r *proto.JobRecord) error {
+				if r.Status != proto.JobStatus_RUNNING {
+					return errJobPaused
+				}
+				return nil
+			}); err != nil && err != errJobPaused {
+				log.Warningf("unable to record heartbeat of job %d: %s", id, err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// update transactionally applies fn to the record of the job with the
+// given ID. The record is not written if fn returns an error.
+func (jc *JobCoordinator) update(id int64, fn func(*proto.JobRecord) error) error {
+	opts := &client.TransactionOptions{Name: fmt.Sprintf("update job %d", id)}
+	return jc.db.RunTransaction(opts, func(txn *client.Txn) error {
+		record := &proto.JobRecord{}
+		if err := getJobRecord(txn.Run, id, record); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+		record.ModifiedNanos = jc.clock.PhysicalNow()
+		return txn.Run(client.PutProtoCall(engine.JobKey(id), record))
+	})
+}
+
+// getJobRecord reads the record of the job with the given ID into
+// record using the supplied run function.
+func getJobRecord(run func(...client.Call) error, id int64, record *proto.JobRecord) error {
+	call := client.GetCall(engine.JobKey(id))
+	if err := run(call); err != nil {
+		return err
+	}
+	reply := call.Reply.(*proto.GetResponse)
+	if reply.Value == nil {
+		return util.Errorf("job %d not found", id)
+	}
+	return gogoproto.Unmarshal(reply.Value.Bytes, record)
+}
+
+// A jobHandler implements the adminHandler interface.
+type jobHandler struct {
+	coord *JobCoordinator
+}
+
+// parseJobPath splits a path of the form "/<id>[/<action>]".
+func parseJobPath(path string) (int64, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", util.Errorf("invalid job ID %q", parts[0])
+	}
+	if len(parts) == 1 {
+		return id, "", nil
+	}
+	return id, parts[1], nil
+}
+
+// Put pauses or resumes the job specified by path, which must be of
+// the form "/<id>/pause" or "/<id>/resume". The body is ignored.
+func (jh *jobHandler) Put(path string, body []byte, r *http.Request) error {
+	id, action, err := parseJobPath(path)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "pause":
+		return jh.coord.Pause(id)
+	case "resume":
+		return jh.coord.Resume(id)
+	default:
+		return util.Errorf("unknown job action %q", action)
+	}
+}
+
+// Get retrieves the record of the job specified by path, which has
+// the form "/<id>". If path is empty, the records of all jobs are
+// returned.
+func (jh *jobHandler) Get(path string, r *http.Request) (body []byte, contentType string, err error) {
+	if len(path) == 0 {
+		var records []proto.JobRecord
+		if records, err = jh.coord.List(); err != nil {
+			return
+		}
+		return util.MarshalResponse(r, records, util.AllEncodings)
+	}
+	id, action, err := parseJobPath(path)
+	if err != nil {
+		return
+	}
+	if action != "" {
+		err = util.Errorf("unexpected job action %q for GET", action)
+		return
+	}
+	record, err := jh.coord.Get(id)
+	if err != nil {
+		return
+	}
+	return util.MarshalResponse(r, record, util.AllEncodings)
+}
+
+// Delete is not supported; job records are retained as a history of
+// the operations performed on the cluster.
+func (jh *jobHandler) Delete(path string, r *http.Request) error {
+	return util.Errorf("job records cannot be deleted")
+}
+
+// RunLsJobs invokes the REST API with GET action and no path, which
+// fetches the records of all jobs, and displays them as a table.
+func RunLsJobs(ctx *Context) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), jobPathPrefix), nil)
+	if err != nil {
+		log.Errorf("unable to create request to admin REST endpoint: %s", err)
+		return
+	}
+	req.Header.Add("Accept", "application/json")
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		log.Errorf("admin REST request failed: %s", err)
+		return
+	}
+	var records []proto.JobRecord
+	if err = json.Unmarshal(b, &records); err != nil {
+		log.Errorf("unable to parse admin REST response: %s", err)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tType\tStatus\tProgress\tDescription\tError\n")
+	for _, r := range records {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.1f%%\t%s\t%s\n", r.ID, r.Type, r.Status, r.Progress*100, r.Description, r.Error)
+	}
+	w.Flush()
+}
+
+// RunGetJob invokes the REST API with GET action and job ID as path.
+func RunGetJob(ctx *Context, jobID string) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.HTTPHost(), jobPathPrefix, jobID), nil)
+	if err != nil {
+		log.Errorf("unable to create request to admin REST endpoint: %s", err)
+		return
+	}
+	req.Header.Add("Accept", "text/yaml")
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		log.Errorf("admin REST request failed: %s", err)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "job %s:\n%s\n", jobID, string(b))
+}
+
+// runJobAction invokes the REST API with POST action and the job ID
+// and action as path.
+func runJobAction(ctx *Context, jobID, action string) {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s%s/%s/%s", adminScheme, ctx.HTTPHost(), jobPathPrefix, jobID, action), nil)
+	if err != nil {
+		log.Errorf("unable to create request to admin REST endpoint: %s", err)
+		return
+	}
+	if _, err = sendAdminRequest(ctx, req); err != nil {
+		log.Errorf("admin REST request failed: %s", err)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "%sd job %s\n", action, jobID)
+}
+
+// RunPauseJob pauses the job with the given ID.
+func RunPauseJob(ctx *Context, jobID string) {
+	runJobAction(ctx, jobID, "pause")
+}
+
+// RunResumeJob resumes the paused job with the given ID.
+func RunResumeJob(ctx *Context, jobID string) {
+	runJobAction(ctx, jobID, "resume")
+}
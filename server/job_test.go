@@ -0,0 +1,215 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+)
+
+func createTestJobCoordinator(t *testing.T) (*JobCoordinator, *util.Stopper) {
+	stopper := util.NewStopper()
+	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), nil, stopper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewJobCoordinator(db, hlc.NewClock(hlc.UnixNano), stopper), stopper
+}
+
+// waitForJob waits until the job with the given ID reaches the given
+// status and progress.
+func waitForJob(t *testing.T, jc *JobCoordinator, id int64, status proto.JobStatus, progress float64) {
+	util.SucceedsWithin(t, 5*time.Second, func() error {
+		r, err := jc.Get(id)
+		if err != nil {
+			return err
+		}
+		if r.Status != status || r.Progress != progress {
+			return util.Errorf("expected job %d %s at %f; got %s at %f", id, status, progress, r.Status, r.Progress)
+		}
+		return nil
+	})
+}
+
+// TestJobPauseResume verifies that a running job stops when paused and
+// runs to completion once resumed.
+func TestJobPauseResume(t *testing.T) {
+	jc, stopper := createTestJobCoordinator(t)
+	defer stopper.Stop()
+
+	proceed := make(chan struct{})
+	jc.Register(proto.JobType_BACKUP, func(job *Job) error {
+		if err := job.Progress(0.5); err != nil {
+			return err
+		}
+		<-proceed
+		return job.Progress(0.75)
+	})
+
+	id, err := jc.Create(proto.JobType_BACKUP, "test backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, jc, id, proto.JobStatus_RUNNING, 0.5)
+	if err := jc.Resume(id); err == nil {
+		t.Error("expected error resuming a running job")
+	}
+	if err := jc.Pause(id); err != nil {
+		t.Fatal(err)
+	}
+	proceed <- struct{}{}
+	waitForJob(t, jc, id, proto.JobStatus_PAUSED, 0.5)
+
+	if err := jc.Resume(id); err != nil {
+		t.Fatal(err)
+	}
+	proceed <- struct{}{}
+	waitForJob(t, jc, id, proto.JobStatus_SUCCEEDED, 1)
+	if err := jc.Pause(id); err == nil {
+		t.Error("expected error pausing a completed job")
+	}
+}
+
+// TestJobFailure verifies that the error of a failed job is recorded
+// and that jobs are listed in order of creation.
+func TestJobFailure(t *testing.T) {
+	jc, stopper := createTestJobCoordinator(t)
+	defer stopper.Stop()
+
+	if _, err := jc.Create(proto.JobType_RESTORE, "unregistered"); err == nil {
+		t.Error("expected error creating job of unregistered type")
+	}
+	jc.Register(proto.JobType_RESTORE, func(job *Job) error {
+		return util.Errorf("restore failed")
+	})
+	jc.Register(proto.JobType_BACKUP, func(job *Job) error {
+		return nil
+	})
+	id1, err := jc.Create(proto.JobType_RESTORE, "test restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id2, err := jc.Create(proto.JobType_BACKUP, "test backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, jc, id1, proto.JobStatus_FAILED, 0)
+	waitForJob(t, jc, id2, proto.JobStatus_SUCCEEDED, 1)
+
+	records, err := jc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].ID != id1 || records[1].ID != id2 {
+		t.Fatalf("unexpected job records %+v", records)
+	}
+	if !strings.HasSuffix(records[0].Error, "restore failed") {
+		t.Errorf("expected error to be recorded; got %q", records[0].Error)
+	}
+}
+
+// TestJobRun verifies that a job run on the calling goroutine returns
+// its error, which is recorded along with its outcome.
+func TestJobRun(t *testing.T) {
+	jc, stopper := createTestJobCoordinator(t)
+	defer stopper.Stop()
+
+	var fail bool
+	jc.Register(proto.JobType_RESTORE, func(job *Job) error {
+		if err := job.Progress(0.5); err != nil {
+			return err
+		}
+		if fail {
+			return util.Errorf("restore failed")
+		}
+		return nil
+	})
+	id, err := jc.Run(proto.JobType_RESTORE, "test restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, jc, id, proto.JobStatus_SUCCEEDED, 1)
+
+	fail = true
+	if id, err = jc.Run(proto.JobType_RESTORE, "test restore"); err == nil {
+		t.Fatal("expected job to fail")
+	}
+	waitForJob(t, jc, id, proto.JobStatus_FAILED, 0.5)
+}
+
+// TestJobAdoptOrphans verifies that abandoned jobs are restarted if
+// the coordinator runs their type of job and marked failed otherwise,
+// and that jobs whose records were recently modified are left alone.
+func TestJobAdoptOrphans(t *testing.T) {
+	jc, stopper := createTestJobCoordinator(t)
+	defer stopper.Stop()
+	jc.orphanTimeout = time.Minute
+
+	jc.Register(proto.JobType_DROP_NAMESPACE, func(job *Job) error {
+		return nil
+	})
+	now := jc.clock.PhysicalNow()
+	stale := now - 2*time.Minute.Nanoseconds()
+	for _, r := range []*proto.JobRecord{
+		{ID: 1, Type: proto.JobType_DROP_NAMESPACE, Status: proto.JobStatus_RUNNING, ModifiedNanos: stale},
+		{ID: 2, Type: proto.JobType_RESTORE, Status: proto.JobStatus_RUNNING, ModifiedNanos: stale},
+		{ID: 3, Type: proto.JobType_DROP_NAMESPACE, Status: proto.JobStatus_RUNNING, ModifiedNanos: now},
+		{ID: 4, Type: proto.JobType_DROP_NAMESPACE, Status: proto.JobStatus_PAUSED, ModifiedNanos: stale},
+	} {
+		if err := jc.db.Run(client.PutProtoCall(engine.JobKey(r.ID), r)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jc.adoptOrphans(); err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, jc, 1, proto.JobStatus_SUCCEEDED, 1)
+	waitForJob(t, jc, 2, proto.JobStatus_FAILED, 0)
+	waitForJob(t, jc, 3, proto.JobStatus_RUNNING, 0)
+	waitForJob(t, jc, 4, proto.JobStatus_PAUSED, 0)
+}
+
+func TestParseJobPath(t *testing.T) {
+	testCases := []struct {
+		path   string
+		id     int64
+		action string
+		err    bool
+	}{
+		{"/1", 1, "", false},
+		{"/12/pause", 12, "pause", false},
+		{"/3/resume", 3, "resume", false},
+		{"/x/pause", 0, "", true},
+		{"", 0, "", true},
+	}
+	for i, test := range testCases {
+		id, action, err := parseJobPath(test.path)
+		if (err != nil) != test.err {
+			t.Errorf("%d: expected error %t; got %v", i, test.err, err)
+			continue
+		}
+		if id != test.id || action != test.action {
+			t.Errorf("%d: expected (%d, %q); got (%d, %q)", i, test.id, test.action, id, action)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// shipConcurrency is the largest number of keys whose writes are
+// applied to the standby at once.
+const shipConcurrency = 16
+
+// errCutOver is returned by a shipment to a standby cluster which has
+// taken over from its primary.
+var errCutOver = errors.New("standby cluster has cut over")
+
+// A logShipper asynchronously replicates the committed writes to a set
+// of key spans to a standby cluster, for disaster recovery across
+// regions. Every interval, the writes to the spans of each range whose
+// leader lease is held by one of the node's stores are shipped up to
+// the range's closed timestamp. Every version is applied by the
+// standby at the timestamp at which it was committed, see
+// StoreContext.Standby, after which the closed timestamp is recorded
+// as the range's progress; see Cutover.
+//
+// There is no change feed from which committed writes could be
+// streamed as they're applied: the shipper instead polls each range
+// for the versions written since its last shipment, with a time-bound
+// scan up to the range's closed timestamp, beyond which no writes may
+// still be applied. Writes thus reach the standby after between one
+// and two intervals plus the closed timestamp lag.
+//
+// The shipped timestamps are tracked in memory. A range for which none
+// is known, after its lease moves to another node or it's split off,
+// resumes from the progress recorded by the standby. Shipments are
+// idempotent.
+type logShipper struct {
+	node     *Node
+	standby  *client.KV
+	prefixes []proto.Key
+	interval time.Duration
+	stopper  *util.Stopper
+
+	mu      sync.Mutex
+	shipped map[int64]proto.Timestamp // Shipped timestamps by Raft ID
+}
+
+// newLogShipper returns a logShipper which ships the writes to the keys
+// with the prefixes to the standby.
+func newLogShipper(node *Node, standby *client.KV, prefixes []proto.Key, interval time.Duration,
+	stopper *util.Stopper) *logShipper {
+	return &logShipper{
+		node:     node,
+		standby:  standby,
+		prefixes: prefixes,
+		interval: interval,
+		stopper:  stopper,
+		shipped:  map[int64]proto.Timestamp{},
+	}
+}
+
+// start ships writes every interval until the node is stopped or the
+// standby cuts over.
+func (ls *logShipper) start() {
+	ls.stopper.RunWorker(func() {
+		ticker := time.NewTicker(ls.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := ls.shipAll(); err == errCutOver {
+					log.Warningf("no longer shipping writes: %s", err)
+					return
+				}
+			case <-ls.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// shipAll ships the writes to each range whose lease is held by the
+// node's stores. Ranges which fail to ship are retried on the next
+// call; errCutOver is returned if the standby has cut over.
+func (ls *logShipper) shipAll() error {
+	if !ls.stopper.StartTask() {
+		return nil
+	}
+	defer ls.stopper.FinishTask()
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	shipped := map[int64]proto.Timestamp{}
+	var progress []proto.KeyValue // Recorded progress, read on demand
+	err := ls.node.lSender.VisitStores(func(s *storage.Store) error {
+		for _, r := range s.LeaseholderRanges() {
+			desc := r.Desc()
+			if !ls.replicates(desc) {
+				continue
+			}
+			since, ok := ls.shipped[desc.RaftID]
+			if !ok {
+				var err error
+				if progress == nil {
+					if progress, err = ls.recordedProgress(); err != nil {
+						log.Warningf("unable to resume shipping writes to range %d: %s", desc.RaftID, err)
+						continue
+					}
+				}
+				if since, err = rangeProgress(progress, desc); err != nil {
+					log.Warningf("unable to resume shipping writes to range %d: %s", desc.RaftID, err)
+					continue
+				}
+			}
+			ts, err := ls.shipRange(r, since)
+			if err == errCutOver {
+				return err
+			} else if err != nil {
+				log.Warningf("unable to ship writes to range %d: %s", desc.RaftID, err)
+			}
+			shipped[desc.RaftID] = ts
+		}
+		return nil
+	})
+	ls.shipped = shipped
+	return err
+}
+
+// replicates returns whether the range holds keys with one of the
+// replicated prefixes.
+func (ls *logShipper) replicates(desc *proto.RangeDescriptor) bool {
+	for _, prefix := range ls.prefixes {
+		if bytes.Compare(prefix, desc.EndKey) < 0 && bytes.Compare(prefix.PrefixEnd(), desc.StartKey) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// recordedProgress returns the replication progress rows recorded by
+// the standby, in key order.
+func (ls *logShipper) recordedProgress() ([]proto.KeyValue, error) {
+	scan := client.ScanCall(engine.KeyReplicationProgressPrefix, engine.KeyReplicationProgressPrefix.PrefixEnd(), 0)
+	if err := ls.standby.Run(scan); err != nil {
+		return nil, err
+	}
+	rows := scan.Reply.(*proto.ScanResponse).Rows
+	if rows == nil {
+		rows = []proto.KeyValue{}
+	}
+	return rows, nil
+}
+
+// rangeProgress returns the timestamp up to which the writes to the
+// range were shipped according to the progress rows: the earliest of
+// the progress of the range which held its start key and of those
+// since merged into it. The zero timestamp is returned if no progress
+// was recorded for its start key.
+func rangeProgress(rows []proto.KeyValue, desc *proto.RangeDescriptor) (proto.Timestamp, error) {
+	start := engine.MakeKey(engine.KeyReplicationProgressPrefix, desc.StartKey)
+	end := engine.MakeKey(engine.KeyReplicationProgressPrefix, desc.EndKey)
+	i := sort.Search(len(rows), func(i int) bool { return bytes.Compare(rows[i].Key, start) > 0 })
+	if i == 0 {
+		return proto.ZeroTimestamp, nil
+	}
+	j := i
+	for j < len(rows) && bytes.Compare(rows[j].Key, end) < 0 {
+		j++
+	}
+	return minReplicationProgress(rows[i-1 : j])
+}
+
+// shipRange ships the writes to the range since the timestamp up to
+// its closed timestamp, returning the timestamp up to which they've
+// been shipped.
+func (ls *logShipper) shipRange(r *storage.Range, since proto.Timestamp) (proto.Timestamp, error) {
+	until := r.ClosedTimestamp()
+	if !since.Less(until) {
+		return since, nil
+	}
+	var versions []engine.MVCCVersion
+	for _, prefix := range ls.prefixes {
+		vs, err := r.CommittedWrites(prefix, prefix.PrefixEnd(), since, until)
+		if err != nil {
+			return since, err
+		}
+		versions = append(versions, vs...)
+	}
+	if len(versions) > 0 {
+		if err := ls.checkCutover(); err != nil {
+			return since, err
+		}
+		if err := ls.applyVersions(versions); err != nil {
+			return since, err
+		}
+	}
+	if err := ls.recordProgress(r.Desc(), until); err != nil {
+		return since, err
+	}
+	return until, nil
+}
+
+// checkCutover returns errCutOver if the standby has cut over.
+func (ls *logShipper) checkCutover() error {
+	get := client.GetCall(engine.KeyReplicationCutover)
+	if err := ls.standby.Run(get); err != nil {
+		return err
+	}
+	if get.Reply.(*proto.GetResponse).Value != nil {
+		return errCutOver
+	}
+	return nil
+}
+
+// applyVersions applies the versions, in key order and oldest first
+// for each key, to the standby. The versions of a key are applied in
+// order, and those of up to shipConcurrency keys at once.
+func (ls *logShipper) applyVersions(versions []engine.MVCCVersion) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var err error
+	sem := make(chan struct{}, shipConcurrency)
+	for len(versions) > 0 {
+		n := 1
+		for n < len(versions) && versions[n].Key.Equal(versions[0].Key) {
+			n++
+		}
+		keyVersions := versions[:n]
+		versions = versions[n:]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for _, v := range keyVersions {
+				if applyErr := ls.standby.Run(versionCall(v)); applyErr != nil {
+					mu.Lock()
+					if err == nil {
+						err = applyErr
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
+
+// versionCall returns a call which writes the version at the
+// timestamp at which the primary committed it. The call is sent on its
+// own rather than in a batch or transaction, which would be assigned
+// a single timestamp.
+func versionCall(v engine.MVCCVersion) client.Call {
+	header := proto.RequestHeader{Key: v.Key, Timestamp: v.Timestamp}
+	if v.Value.Deleted {
+		return client.Call{
+			Args:  &proto.DeleteRequest{RequestHeader: header},
+			Reply: &proto.DeleteResponse{},
+		}
+	}
+	value := *v.Value.Value
+	value.Timestamp = nil
+	return client.Call{
+		Args:  &proto.PutRequest{RequestHeader: header, Value: value},
+		Reply: &proto.PutResponse{},
+	}
+}
+
+// recordProgress records, in a transaction ordered against the
+// cutover, that the writes to the range have been shipped up to the
+// timestamp. errCutOver is returned if the standby has cut over.
+func (ls *logShipper) recordProgress(desc *proto.RangeDescriptor, until proto.Timestamp) error {
+	opts := &client.TransactionOptions{Name: "record replication progress"}
+	return ls.standby.RunTransaction(opts, func(txn *client.Txn) error {
+		// Reading the cutover key orders the record before or after
+		// the cutover.
+		get := client.GetCall(engine.KeyReplicationCutover)
+		if err := txn.Run(get); err != nil {
+			return err
+		}
+		if get.Reply.(*proto.GetResponse).Value != nil {
+			return errCutOver
+		}
+		// The progress of ranges since merged into this one is
+		// superseded by its own.
+		key := engine.MakeKey(engine.KeyReplicationProgressPrefix, desc.StartKey)
+		return txn.Run(
+			client.DeleteRangeCall(key.Next(), engine.MakeKey(engine.KeyReplicationProgressPrefix, desc.EndKey)),
+			client.PutProtoCall(key, &until))
+	})
+}
+
+// Cutover makes the standby cluster reached through db take over from
+// its primary: no more writes are shipped to it, and the timestamp up
+// to which the writes to every range of the primary were shipped is
+// recorded and returned. Writes to some ranges may have been shipped
+// beyond it. Calling Cutover again returns the recorded timestamp.
+func Cutover(db *client.KV) (proto.Timestamp, error) {
+	var cutover proto.Timestamp
+	opts := &client.TransactionOptions{Name: "cutover"}
+	err := db.RunTransaction(opts, func(txn *client.Txn) error {
+		get := client.GetCall(engine.KeyReplicationCutover)
+		if err := txn.Run(get); err != nil {
+			return err
+		}
+		if v := get.Reply.(*proto.GetResponse).Value; v != nil {
+			return gogoproto.Unmarshal(v.Bytes, &cutover)
+		}
+		scan := client.ScanCall(engine.KeyReplicationProgressPrefix, engine.KeyReplicationProgressPrefix.PrefixEnd(), 0)
+		if err := txn.Run(scan); err != nil {
+			return err
+		}
+		rows := scan.Reply.(*proto.ScanResponse).Rows
+		if len(rows) == 0 {
+			return util.Errorf("no writes have been shipped to this cluster")
+		}
+		var err error
+		if cutover, err = minReplicationProgress(rows); err != nil {
+			return err
+		}
+		return txn.Run(client.PutProtoCall(engine.KeyReplicationCutover, &cutover))
+	})
+	return cutover, err
+}
+
+// minReplicationProgress returns the earliest of the timestamps up to
+// which the writes to ranges were shipped, held in the replication
+// progress rows, or the zero timestamp if there are none.
+func minReplicationProgress(rows []proto.KeyValue) (proto.Timestamp, error) {
+	var min proto.Timestamp
+	for i, row := range rows {
+		var ts proto.Timestamp
+		if err := gogoproto.Unmarshal(row.Value.Bytes, &ts); err != nil {
+			return proto.Timestamp{}, util.Errorf("invalid replication progress at %s: %s", row.Key, err)
+		}
+		if i == 0 || ts.Less(min) {
+			min = ts
+		}
+	}
+	return min, nil
+}
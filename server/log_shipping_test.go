@@ -0,0 +1,136 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// TestLogShipping verifies that every version written to the
+// replicated prefixes of a primary cluster, and only those, is shipped
+// to its standby at its own timestamp, and that no writes are shipped
+// once the standby has cut over.
+func TestLogShipping(t *testing.T) {
+	standby := &TestServer{Ctx: NewTestContext()}
+	standby.Ctx.Standby = true
+	standby.Ctx.ReplicateInterval = 10 * time.Millisecond
+	if err := standby.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer standby.Stop()
+	primary := &TestServer{Ctx: NewTestContext()}
+	primary.Ctx.ReplicateTo = standby.ServingAddr()
+	primary.Ctx.ReplicatePrefixes = "t1/"
+	primary.Ctx.ReplicateInterval = 10 * time.Millisecond
+	primary.Ctx.ClosedTimestampLag = 50 * time.Millisecond
+	if err := primary.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Stop()
+
+	var timestamps []proto.Timestamp
+	for _, kv := range []struct{ key, value string }{{"t1/a", "1"}, {"t1/a", "2"}, {"t2/a", "1"}} {
+		call := client.PutCall(proto.Key(kv.key), []byte(kv.value))
+		if err := primary.kv.Run(call); err != nil {
+			t.Fatal(err)
+		}
+		timestamps = append(timestamps, call.Reply.Header().Timestamp)
+	}
+	get := func(key string, ts proto.Timestamp) *proto.Value {
+		call := client.GetCall(proto.Key(key))
+		call.Args.Header().Timestamp = ts
+		if err := standby.kv.Run(call); err != nil {
+			t.Fatal(err)
+		}
+		return call.Reply.(*proto.GetResponse).Value
+	}
+	util.SucceedsWithin(t, 5*time.Second, func() error {
+		if v := get("t1/a", proto.ZeroTimestamp); v == nil || string(v.Bytes) != "2" {
+			return util.Errorf("expected t1/a to be shipped; got %+v", v)
+		}
+		return nil
+	})
+	for i, value := range []string{"1", "2"} {
+		if v := get("t1/a", timestamps[i]); v == nil || string(v.Bytes) != value || !v.Timestamp.Equal(timestamps[i]) {
+			t.Errorf("expected t1/a=%s at %s; got %+v", value, timestamps[i], v)
+		}
+	}
+	if v := get("t2/a", proto.ZeroTimestamp); v != nil {
+		t.Errorf("expected t2/a not to be shipped; got %+v", v)
+	}
+
+	ts, err := Cutover(standby.kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Equal(proto.ZeroTimestamp) {
+		t.Error("expected a cutover timestamp")
+	}
+	if again, err := Cutover(standby.kv); err != nil || !again.Equal(ts) {
+		t.Errorf("expected repeated cutover at %s; got %s, %v", ts, again, err)
+	}
+	util.SucceedsWithin(t, 5*time.Second, func() error {
+		if err := primary.shipper.shipAll(); err != errCutOver {
+			return util.Errorf("expected shipment to fail with %q; got %v", errCutOver, err)
+		}
+		return nil
+	})
+}
+
+// TestRangeProgress verifies that a range resumes shipping from the
+// earliest progress recorded for the range which held its start key
+// and for those since merged into it.
+func TestRangeProgress(t *testing.T) {
+	row := func(key string, wallTime int64) proto.KeyValue {
+		ts := proto.Timestamp{WallTime: wallTime}
+		b, err := gogoproto.Marshal(&ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return proto.KeyValue{
+			Key:   engine.MakeKey(engine.KeyReplicationProgressPrefix, proto.Key(key)),
+			Value: proto.Value{Bytes: b},
+		}
+	}
+	rows := []proto.KeyValue{row("a", 5), row("c", 3), row("e", 7)}
+	testCases := []struct {
+		start, end string
+		expected   int64
+	}{
+		{"", "a", 0},  // No progress recorded for the start key
+		{"a", "c", 5}, // The range's own progress
+		{"b", "c", 5}, // Split off from the range at a
+		{"a", "e", 3}, // The range at c merged into that at a
+		{"e", "z", 7},
+	}
+	for i, test := range testCases {
+		desc := &proto.RangeDescriptor{StartKey: proto.Key(test.start), EndKey: proto.Key(test.end)}
+		ts, err := rangeProgress(rows, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts.WallTime != test.expected {
+			t.Errorf("%d: expected progress %d; got %s", i, test.expected, ts)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+const (
+	// maxNamespaceNameLength is the longest permitted namespace name.
+	maxNamespaceNameLength = 128
+	// namespaceDeleteBatchSize is the number of keys of a dropped
+	// namespace deleted per request. The job deleting them reports its
+	// progress, and so may be paused, between requests.
+	namespaceDeleteBatchSize = 1000
+)
+
+// A NamespaceManager creates, renames and drops namespaces: key
+// prefixes allocated by the cluster and known by name. Each operation
+// runs in a single transaction over the descriptors stored in the
+// system keyspace, so concurrent operations on the same names can't
+// interleave. A dropped namespace disappears at once, while its keys
+// are deleted in the background by a DROP_NAMESPACE job.
+type NamespaceManager struct {
+	db   *client.KV
+	jobs *JobCoordinator
+}
+
+// NewNamespaceManager returns a new NamespaceManager which runs the
+// jobs deleting the keys of dropped namespaces with the supplied
+// coordinator.
+func NewNamespaceManager(db *client.KV, jobs *JobCoordinator) *NamespaceManager {
+	nm := &NamespaceManager{db: db, jobs: jobs}
+	jobs.Register(proto.JobType_DROP_NAMESPACE, nm.deleteDropped)
+	return nm
+}
+
+// validateNamespaceName returns an error if name may not be used for
+// a namespace. Names appear in admin URL paths, so they may not
+// contain a slash.
+func validateNamespaceName(name string) error {
+	if len(name) == 0 {
+		return util.Errorf("namespace name must not be empty")
+	}
+	if len(name) > maxNamespaceNameLength {
+		return util.Errorf("namespace name %q is longer than %d bytes", name, maxNamespaceNameLength)
+	}
+	if strings.Contains(name, "/") {
+		return util.Errorf("namespace name %q must not contain \"/\"", name)
+	}
+	return nil
+}
+
+// Create allocates a key prefix to a new namespace with the given
+// name and returns its descriptor. Prefixes are never reused, even
+// once the namespaces they were allocated to are dropped.
+func (nm *NamespaceManager) Create(name string) (*proto.NamespaceDescriptor, error) {
+	if err := validateNamespaceName(name); err != nil {
+		return nil, err
+	}
+	desc := &proto.NamespaceDescriptor{Name: name}
+	opts := &client.TransactionOptions{Name: fmt.Sprintf("create namespace %q", name)}
+	if err := nm.db.RunTransaction(opts, func(txn *client.Txn) error {
+		if err := checkNamespaceAbsent(txn, name); err != nil {
+			return err
+		}
+		call := client.IncrementCall(engine.KeyNamespaceIDGenerator, 1)
+		if err := txn.Run(call); err != nil {
+			return util.Errorf("unable to allocate namespace ID: %s", err)
+		}
+		desc.ID = call.Reply.(*proto.IncrementResponse).NewValue
+		desc.Prefix = engine.NamespaceDataPrefix(desc.ID)
+		return txn.Run(client.PutProtoCall(engine.NamespaceKey(name), desc))
+	}); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// Rename changes the name of the namespace with the given name. The
+// namespace keeps its prefix and so its keys.
+func (nm *NamespaceManager) Rename(name, newName string) error {
+	if err := validateNamespaceName(newName); err != nil {
+		return err
+	}
+	opts := &client.TransactionOptions{Name: fmt.Sprintf("rename namespace %q", name)}
+	return nm.db.RunTransaction(opts, func(txn *client.Txn) error {
+		desc, err := getNamespace(txn.Run, name)
+		if err != nil {
+			return err
+		}
+		if err := checkNamespaceAbsent(txn, newName); err != nil {
+			return err
+		}
+		desc.Name = newName
+		return txn.Run(client.DeleteCall(engine.NamespaceKey(name)),
+			client.PutProtoCall(engine.NamespaceKey(newName), desc))
+	})
+}
+
+// Drop removes the namespace with the given name and creates a job to
+// delete its keys, returning the job's ID. The name may be reused as
+// soon as Drop returns. If the job can't be created, the namespace
+// remains dropped and its keys are deleted by the next drop's job.
+func (nm *NamespaceManager) Drop(name string) (int64, error) {
+	var desc *proto.NamespaceDescriptor
+	opts := &client.TransactionOptions{Name: fmt.Sprintf("drop namespace %q", name)}
+	if err := nm.db.RunTransaction(opts, func(txn *client.Txn) error {
+		var err error
+		if desc, err = getNamespace(txn.Run, name); err != nil {
+			return err
+		}
+		return txn.Run(client.DeleteCall(engine.NamespaceKey(name)),
+			client.PutProtoCall(engine.DroppedNamespaceKey(desc.ID), desc))
+	}); err != nil {
+		return 0, err
+	}
+	id, err := nm.jobs.Create(proto.JobType_DROP_NAMESPACE,
+		fmt.Sprintf("delete keys of namespace %q (ID %d)", desc.Name, desc.ID))
+	if err != nil {
+		return 0, util.Errorf("namespace %q dropped, but unable to start deleting its keys: %s", name, err)
+	}
+	return id, nil
+}
+
+// Get returns the descriptor of the namespace with the given name.
+func (nm *NamespaceManager) Get(name string) (*proto.NamespaceDescriptor, error) {
+	return getNamespace(nm.db.Run, name)
+}
+
+// List returns the descriptors of all namespaces, ordered by name.
+func (nm *NamespaceManager) List() ([]proto.NamespaceDescriptor, error) {
+	return nm.scan(engine.KeyNamespacePrefix)
+}
+
+// ListDropped returns the descriptors of the dropped namespaces whose
+// keys have yet to be deleted, ordered by ID.
+func (nm *NamespaceManager) ListDropped() ([]proto.NamespaceDescriptor, error) {
+	return nm.scan(engine.KeyNamespaceDroppedPrefix)
+}
+
+// scan returns the namespace descriptors stored under prefix.
+func (nm *NamespaceManager) scan(prefix proto.Key) ([]proto.NamespaceDescriptor, error) {
+	call := client.ScanCall(prefix, prefix.PrefixEnd(), maxGetResults)
+	call.Args.Header().User = storage.UserRoot
+	if err := nm.db.Run(call); err != nil {
+		return nil, err
+	}
+	rows := call.Reply.(*proto.ScanResponse).Rows
+	descs := make([]proto.NamespaceDescriptor, len(rows))
+	for i, kv := range rows {
+		if err := gogoproto.Unmarshal(kv.Value.Bytes, &descs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return descs, nil
+}
+
+// deleteDropped is the JobFunc of DROP_NAMESPACE jobs. It deletes the
+// keys of every dropped namespace, not only that of the drop which
+// created the job, so that none are left behind by a drop whose job
+// couldn't be created or by a failed job. The descriptor of a dropped
+// namespace is removed once its keys are gone.
+func (nm *NamespaceManager) deleteDropped(job *Job) error {
+	descs, err := nm.ListDropped()
+	if err != nil {
+		return err
+	}
+	for i, desc := range descs {
+		for {
+			call := client.DeleteRangeCall(desc.Prefix, desc.Prefix.PrefixEnd())
+			call.Args.(*proto.DeleteRangeRequest).MaxEntriesToDelete = namespaceDeleteBatchSize
+			if err := nm.db.Run(call); err != nil {
+				return util.Errorf("unable to delete keys of namespace %d: %s", desc.ID, err)
+			}
+			if err := job.Progress(float64(i) / float64(len(descs))); err != nil {
+				return err
+			}
+			if call.Reply.(*proto.DeleteRangeResponse).NumDeleted < namespaceDeleteBatchSize {
+				break
+			}
+		}
+		if err := nm.db.Run(client.DeleteCall(engine.DroppedNamespaceKey(desc.ID))); err != nil {
+			return err
+		}
+		log.Infof("deleted keys of dropped namespace %q (ID %d)", desc.Name, desc.ID)
+	}
+	return nil
+}
+
+// checkNamespaceAbsent returns an error if a namespace with the given
+// name exists.
+func checkNamespaceAbsent(txn *client.Txn, name string) error {
+	call := client.GetCall(engine.NamespaceKey(name))
+	if err := txn.Run(call); err != nil {
+		return err
+	}
+	if call.Reply.(*proto.GetResponse).Value != nil {
+		return util.Errorf("namespace %q already exists", name)
+	}
+	return nil
+}
+
+// getNamespace reads the descriptor of the namespace with the given
+// name using the supplied run function.
+func getNamespace(run func(...client.Call) error, name string) (*proto.NamespaceDescriptor, error) {
+	call := client.GetCall(engine.NamespaceKey(name))
+	if err := run(call); err != nil {
+		return nil, err
+	}
+	reply := call.Reply.(*proto.GetResponse)
+	if reply.Value == nil {
+		return nil, util.Errorf("namespace %q not found", name)
+	}
+	desc := &proto.NamespaceDescriptor{}
+	if err := gogoproto.Unmarshal(reply.Value.Bytes, desc); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// A namespaceHandler implements the adminHandler interface.
+type namespaceHandler struct {
+	nm *NamespaceManager
+}
+
+// parseNamespacePath splits a path of the form "/<name>[/<action>]".
+func parseNamespacePath(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// Put creates the namespace specified by path, which has the form
+// "/<name>", in which case the body is ignored, or renames it, if the
+// path has the form "/<name>/rename", to the name held by the body.
+func (nh *namespaceHandler) Put(path string, body []byte, r *http.Request) error {
+	name, action := parseNamespacePath(path)
+	switch action {
+	case "":
+		_, err := nh.nm.Create(name)
+		return err
+	case "rename":
+		return nh.nm.Rename(name, string(body))
+	default:
+		return util.Errorf("unknown namespace action %q", action)
+	}
+}
+
+// Get retrieves the descriptor of the namespace specified by path,
+// which has the form "/<name>". If path is empty, the descriptors of
+// all namespaces are returned.
+func (nh *namespaceHandler) Get(path string, r *http.Request) (body []byte, contentType string, err error) {
+	if len(path) == 0 {
+		var descs []proto.NamespaceDescriptor
+		if descs, err = nh.nm.List(); err != nil {
+			return
+		}
+		return util.MarshalResponse(r, descs, util.AllEncodings)
+	}
+	name, action := parseNamespacePath(path)
+	if action != "" {
+		err = util.Errorf("unexpected namespace action %q for GET", action)
+		return
+	}
+	desc, err := nh.nm.Get(name)
+	if err != nil {
+		return
+	}
+	return util.MarshalResponse(r, desc, util.AllEncodings)
+}
+
+// Delete drops the namespace specified by path, which has the form
+// "/<name>". Its keys are deleted by a job which is left running.
+func (nh *namespaceHandler) Delete(path string, r *http.Request) error {
+	name, action := parseNamespacePath(path)
+	if action != "" {
+		return util.Errorf("unexpected namespace action %q for DELETE", action)
+	}
+	_, err := nh.nm.Drop(name)
+	return err
+}
+
+// RunLsNamespaces invokes the REST API with GET action and no path,
+// which fetches the descriptors of all namespaces, and displays them
+// as a table.
+func RunLsNamespaces(ctx *Context) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", adminScheme, ctx.HTTPHost(), namespacePathPrefix), nil)
+	if err != nil {
+		log.Errorf("unable to create request to admin REST endpoint: %s", err)
+		return
+	}
+	req.Header.Add("Accept", "application/json")
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		log.Errorf("admin REST request failed: %s", err)
+		return
+	}
+	var descs []proto.NamespaceDescriptor
+	if err = json.Unmarshal(b, &descs); err != nil {
+		log.Errorf("unable to parse admin REST response: %s", err)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintf(w, "Name\tID\tPrefix\n")
+	for _, d := range descs {
+		fmt.Fprintf(w, "%s\t%d\t%q\n", d.Name, d.ID, d.Prefix)
+	}
+	w.Flush()
+}
+
+// runNamespaceAction invokes the REST API with the given method, path
+// and body and, if it succeeds, prints the given message.
+func runNamespaceAction(ctx *Context, method, path, body, msg string) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.HTTPHost(), namespacePathPrefix, path),
+		strings.NewReader(body))
+	if err != nil {
+		log.Errorf("unable to create request to admin REST endpoint: %s", err)
+		return
+	}
+	if _, err = sendAdminRequest(ctx, req); err != nil {
+		log.Errorf("admin REST request failed: %s", err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, msg)
+}
+
+// RunCreateNamespace creates a namespace with the given name.
+func RunCreateNamespace(ctx *Context, name string) {
+	runNamespaceAction(ctx, "PUT", url.QueryEscape(name), "", fmt.Sprintf("created namespace %q", name))
+}
+
+// RunRenameNamespace renames the namespace with the given name.
+func RunRenameNamespace(ctx *Context, name, newName string) {
+	runNamespaceAction(ctx, "POST", url.QueryEscape(name)+"/rename", newName,
+		fmt.Sprintf("renamed namespace %q to %q", name, newName))
+}
+
+// RunRmNamespace drops the namespace with the given name.
+func RunRmNamespace(ctx *Context, name string) {
+	runNamespaceAction(ctx, "DELETE", url.QueryEscape(name), "",
+		fmt.Sprintf("dropped namespace %q; its keys are being deleted by a job", name))
+}
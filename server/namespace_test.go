@@ -0,0 +1,141 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+)
+
+// TestNamespaceCreateRename verifies that namespaces are allocated
+// distinct prefixes, that names can't be used twice and that a
+// renamed namespace keeps its prefix.
+func TestNamespaceCreateRename(t *testing.T) {
+	jc, stopper := createTestJobCoordinator(t)
+	defer stopper.Stop()
+	nm := NewNamespaceManager(jc.db, jc)
+
+	a, err := nm.Create("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := nm.Create("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Prefix.Equal(b.Prefix) {
+		t.Errorf("expected distinct prefixes; got %q for both", a.Prefix)
+	}
+	for _, name := range []string{"a", "", "c/d"} {
+		if _, err := nm.Create(name); err == nil {
+			t.Errorf("expected error creating namespace %q", name)
+		}
+	}
+
+	if err := nm.Rename("a", "b"); err == nil {
+		t.Error("expected error renaming namespace to an existing name")
+	}
+	if err := nm.Rename("missing", "c"); err == nil {
+		t.Error("expected error renaming missing namespace")
+	}
+	if err := nm.Rename("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nm.Get("a"); err == nil {
+		t.Error("expected renamed namespace to be absent under its old name")
+	}
+	c, err := nm.Get("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != a.ID || !c.Prefix.Equal(a.Prefix) {
+		t.Errorf("expected renamed namespace to keep ID %d and prefix %q; got %+v", a.ID, a.Prefix, c)
+	}
+	descs, err := nm.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descs) != 2 || descs[0].Name != "b" || descs[1].Name != "c" {
+		t.Errorf("expected namespaces b and c; got %+v", descs)
+	}
+}
+
+// TestNamespaceDrop verifies that a dropped namespace's name may be
+// reused at once and that its keys are deleted by a job, leaving the
+// keys of other namespaces intact.
+func TestNamespaceDrop(t *testing.T) {
+	jc, stopper := createTestJobCoordinator(t)
+	defer stopper.Stop()
+	nm := NewNamespaceManager(jc.db, jc)
+
+	a, err := nm.Create("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := nm.Create("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write more keys than are deleted per request.
+	numKeys := namespaceDeleteBatchSize + 10
+	for _, desc := range []*proto.NamespaceDescriptor{a, b} {
+		var calls []client.Call
+		for i := 0; i < numKeys; i++ {
+			key := engine.MakeKey(desc.Prefix, proto.Key(fmt.Sprintf("%05d", i)))
+			calls = append(calls, client.PutCall(key, []byte("value")))
+		}
+		if err := jc.db.Run(calls...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id, err := nm.Drop("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nm.Drop("a"); err == nil {
+		t.Error("expected error dropping a dropped namespace")
+	}
+	recreated, err := nm.Create("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recreated.Prefix.Equal(a.Prefix) {
+		t.Errorf("expected recreated namespace to be allocated a new prefix; got %q", a.Prefix)
+	}
+
+	waitForJob(t, jc, id, proto.JobStatus_SUCCEEDED, 1)
+	count := func(prefix proto.Key) int {
+		call := client.ScanCall(prefix, prefix.PrefixEnd(), 0)
+		if err := jc.db.Run(call); err != nil {
+			t.Fatal(err)
+		}
+		return len(call.Reply.(*proto.ScanResponse).Rows)
+	}
+	if n := count(a.Prefix); n != 0 {
+		t.Errorf("expected keys of dropped namespace to be deleted; %d remain", n)
+	}
+	if n := count(b.Prefix); n != numKeys {
+		t.Errorf("expected %d keys of namespace b; got %d", numKeys, n)
+	}
+	if descs, err := nm.ListDropped(); err != nil || len(descs) != 0 {
+		t.Errorf("expected dropped namespace descriptor to be removed; got %+v, %v", descs, err)
+	}
+}
@@ -19,7 +19,10 @@ package server
 
 import (
 	"container/list"
+	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/client"
@@ -58,6 +61,33 @@ type Node struct {
 	Descriptor gossip.NodeDescriptor // Node ID, network/physical topology
 	ctx        storage.StoreContext  // Context to use and pass to stores
 	lSender    *kv.LocalSender       // Local KV sender for access to node-local stores
+	readOnly   int32                 // Non-zero if the node's stores are read-only; updated atomically
+	started    int32                 // Non-zero once the node's stores have been initialized; updated atomically
+	latency    latencyRecorder       // Latencies of executed commands
+
+	startupMu     sync.Mutex       // Protects the fields below
+	startupStores []*storage.Store // Stores initialized by start, in order
+	storeCount    int              // Number of engines passed to start
+}
+
+// StartupProgress summarizes the progress of a node's stores in
+// recovering their state after the node is started.
+type StartupProgress struct {
+	Stores              int   // Number of stores
+	StoresOpened        int   // Number of stores whose engines have been opened
+	ReplicasLoaded      int64 // Replicas loaded by all stores
+	RaftGroupsRecovered int64 // Raft groups recovered by all stores
+	Started             bool  // True once the node serves traffic
+}
+
+// String returns a human-readable summary of the progress.
+func (p StartupProgress) String() string {
+	state := "started"
+	if !p.Started {
+		state = "recovering"
+	}
+	return fmt.Sprintf("%s: %d of %d stores opened, %d replicas loaded, %d raft groups recovered",
+		state, p.StoresOpened, p.Stores, p.ReplicasLoaded, p.RaftGroupsRecovered)
 }
 
 // allocateNodeID increments the node id generator key to allocate
@@ -100,10 +130,17 @@ func allocateStoreIDs(nodeID proto.NodeID, inc int64, db *client.KV) (proto.Stor
 // BootstrapCluster bootstraps a store using the provided engine and
 // cluster ID. The bootstrapped store contains a single range spanning
 // all keys. Initial range lookup metadata is populated for the range.
+// If systemZone is not nil, it's written as the zone config of the
+// system keys, from which the system ranges are then split.
+//
+// The store is marked as bootstrapping until every step has completed,
+// so an engine whose bootstrap was interrupted by a crash may simply
+// be bootstrapped again.
 //
 // Returns a KV client for unittest purposes. Caller should close
 // the returned client.
-func BootstrapCluster(clusterID string, eng engine.Engine, stopper *util.Stopper) (*client.KV, error) {
+func BootstrapCluster(clusterID string, eng engine.Engine, systemZone *proto.ZoneConfig,
+	stopper *util.Stopper) (*client.KV, error) {
 	sIdent := proto.StoreIdent{
 		ClusterID: clusterID,
 		NodeID:    1,
@@ -128,7 +165,7 @@ func BootstrapCluster(clusterID string, eng engine.Engine, stopper *util.Stopper
 	}
 
 	// Bootstrap store to persist the store ident.
-	if err := s.Bootstrap(sIdent, stopper); err != nil {
+	if err := s.BeginBootstrap(sIdent, stopper); err != nil {
 		return nil, err
 	}
 	// Create first range, writing directly to engine. Note this does
@@ -152,6 +189,16 @@ func BootstrapCluster(clusterID string, eng engine.Engine, stopper *util.Stopper
 			sIdent.StoreID, storeID, err)
 	}
 
+	if systemZone != nil {
+		key := engine.MakeKey(engine.KeyConfigZonePrefix, engine.KeySystemPrefix)
+		if err := localDB.Run(client.PutProtoCall(key, systemZone)); err != nil {
+			return nil, util.Errorf("unable to write system zone config: %s", err)
+		}
+	}
+
+	if err := s.FinishBootstrap(); err != nil {
+		return nil, err
+	}
 	return localDB, nil
 }
 
@@ -209,10 +256,12 @@ func (n *Node) initNodeID(id proto.NodeID) {
 
 // start starts the node by registering the storage instance for the
 // RPC service "Node" and initializing stores for each specified
-// engine. Launches periodic store gossiping in a goroutine.
-func (n *Node) start(rpcServer *rpc.Server, engines []engine.Engine,
+// engine. The node descriptor holds addr, the address at which other
+// nodes reach this one. Launches periodic store gossiping in a
+// goroutine.
+func (n *Node) start(rpcServer *rpc.Server, addr net.Addr, engines []engine.Engine,
 	attrs proto.Attributes, stopper *util.Stopper) error {
-	n.initDescriptor(rpcServer.Addr(), attrs)
+	n.initDescriptor(addr, attrs)
 	if err := rpcServer.RegisterName("Node", n); err != nil {
 		log.Fatalf("unable to register node service with RPC server: %s", err)
 	}
@@ -221,6 +270,7 @@ func (n *Node) start(rpcServer *rpc.Server, engines []engine.Engine,
 	if err := n.initStores(engines, stopper); err != nil {
 		return err
 	}
+	atomic.StoreInt32(&n.started, 1)
 	n.startGossip(stopper)
 	log.Infof("Started node with %v engine(s) and attributes %v", engines, attrs.Attrs)
 	return nil
@@ -238,12 +288,24 @@ func (n *Node) initStores(engines []engine.Engine, stopper *util.Stopper) error
 	if len(engines) == 0 {
 		return util.Error("no engines")
 	}
-	for _, e := range engines {
+	n.startupMu.Lock()
+	n.storeCount = len(engines)
+	n.startupMu.Unlock()
+	for i, e := range engines {
 		s := storage.NewStore(n.ctx, e)
+		n.startupMu.Lock()
+		n.startupStores = append(n.startupStores, s)
+		n.startupMu.Unlock()
+		log.Infof("starting store %d of %d: %s", i+1, len(engines), e)
 		// Initialize each store in turn, handling un-bootstrapped errors by
 		// adding the store to the bootstraps list.
 		if err := s.Start(stopper); err != nil {
-			if _, ok := err.(*storage.NotBootstrappedError); ok {
+			if nbErr, ok := err.(*storage.NotBootstrappedError); ok {
+				// An interrupted bootstrap of a new cluster is retried by
+				// init, rather than having the store join another cluster.
+				if nbErr.Interrupted {
+					return util.Errorf("bootstrap of a new cluster on store %s was interrupted; run init again", e)
+				}
 				log.Infof("store %s not bootstrapped", s)
 				bootstraps.PushBack(s)
 				continue
@@ -259,6 +321,7 @@ func (n *Node) initStores(engines []engine.Engine, stopper *util.Stopper) error
 		}
 		log.Infof("initialized store %s: %+v", s, capacity)
 		n.lSender.AddStore(s)
+		s.SetReadOnly(n.ReadOnly())
 	}
 
 	// Verify all initialized stores agree on cluster and node IDs.
@@ -332,11 +395,52 @@ func (n *Node) bootstrapStores(bootstraps *list.List, stopper *util.Stopper) {
 			log.Fatal(err)
 		}
 		n.lSender.AddStore(s)
+		s.SetReadOnly(n.ReadOnly())
 		sIdent.StoreID++
 		log.Infof("bootstrapped store %s", s)
 	}
 }
 
+// StartupProgress returns the progress of the node's stores in
+// recovering their state.
+func (n *Node) StartupProgress() StartupProgress {
+	n.startupMu.Lock()
+	defer n.startupMu.Unlock()
+	p := StartupProgress{
+		Stores:  n.storeCount,
+		Started: atomic.LoadInt32(&n.started) != 0,
+	}
+	for _, s := range n.startupStores {
+		sp := s.StartupProgress()
+		if sp.Opened {
+			p.StoresOpened++
+		}
+		p.ReplicasLoaded += sp.ReplicasLoaded
+		p.RaftGroupsRecovered += sp.RaftGroupsRecovered
+	}
+	return p
+}
+
+// ReadOnly returns whether the node's stores are in read-only mode.
+func (n *Node) ReadOnly() bool {
+	return atomic.LoadInt32(&n.readOnly) != 0
+}
+
+// SetReadOnly puts all of the node's stores, including those yet to
+// be bootstrapped, into or out of read-only mode. See
+// storage.Store.SetReadOnly.
+func (n *Node) SetReadOnly(readOnly bool) {
+	var v int32
+	if readOnly {
+		v = 1
+	}
+	atomic.StoreInt32(&n.readOnly, v)
+	n.lSender.VisitStores(func(s *storage.Store) error {
+		s.SetReadOnly(readOnly)
+		return nil
+	})
+}
+
 // connectGossip connects to gossip network and reads cluster ID. If
 // this node is already part of a cluster, the cluster ID is verified
 // for a match. If not part of a cluster, the cluster ID is set. The
@@ -390,9 +494,12 @@ func (n *Node) gossipCapacities() {
 	})
 }
 
-// executeCmd creates a client.Call struct and sends if via our local sender.
+// executeCmd creates a client.Call struct and sends if via our local
+// sender, recording its latency.
 func (n *Node) executeCmd(args proto.Request, reply proto.Response) error {
+	start := time.Now()
 	n.lSender.Send(client.Call{Args: args, Reply: reply})
+	n.latency.record(time.Since(start))
 	return nil
 }
 
@@ -488,6 +595,11 @@ func (n *Node) InternalTruncateLog(args *proto.InternalTruncateLogRequest, reply
 	return n.executeCmd(args, reply)
 }
 
+// InternalIngest .
+func (n *Node) InternalIngest(args *proto.InternalIngestRequest, reply *proto.InternalIngestResponse) error {
+	return n.executeCmd(args, reply)
+}
+
 // InternalLeaderLease .
 func (n *Node) InternalLeaderLease(args *proto.InternalLeaderLeaseRequest,
 	reply *proto.InternalLeaderLeaseResponse) error {
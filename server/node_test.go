@@ -23,6 +23,7 @@ import (
 	"math"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
+	gogoproto "github.com/gogo/protobuf/proto"
 	"golang.org/x/net/context"
 )
 
@@ -83,7 +85,7 @@ func createTestNode(addr net.Addr, engines []engine.Engine, gossipBS net.Addr, t
 func createAndStartTestNode(addr net.Addr, engines []engine.Engine, gossipBS net.Addr, t *testing.T) (
 	*rpc.Server, *Node, *util.Stopper) {
 	rpcServer, _, node, stopper := createTestNode(addr, engines, gossipBS, t)
-	if err := node.start(rpcServer, engines, proto.Attributes{}, stopper); err != nil {
+	if err := node.start(rpcServer, rpcServer.Addr(), engines, proto.Attributes{}, stopper); err != nil {
 		t.Fatal(err)
 	}
 	return rpcServer, node, stopper
@@ -102,7 +104,7 @@ func formatKeys(keys []proto.Key) string {
 func TestBootstrapCluster(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	localDB, err := BootstrapCluster("cluster-1", e, stopper)
+	localDB, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -144,12 +146,44 @@ func TestBootstrapCluster(t *testing.T) {
 	// TODO(spencer): check values.
 }
 
+// TestBootstrapClusterSystemZone verifies that the zone config of
+// the system keys is written when a cluster is bootstrapped with one.
+func TestBootstrapClusterSystemZone(t *testing.T) {
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+	systemZone := &proto.ZoneConfig{
+		ReplicaAttrs:  []proto.Attributes{{Attrs: []string{"ssd"}}, {}, {}, {}, {}},
+		RangeMinBytes: 1 << 20,
+		RangeMaxBytes: 64 << 20,
+	}
+	localDB, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), systemZone, stopper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := client.GetCall(engine.MakeKey(engine.KeyConfigZonePrefix, engine.KeySystemPrefix))
+	call.Args.Header().User = storage.UserRoot
+	if err := localDB.Run(call); err != nil {
+		t.Fatal(err)
+	}
+	gr := call.Reply.(*proto.GetResponse)
+	if gr.Value == nil {
+		t.Fatal("expected system zone config to be written")
+	}
+	zone := &proto.ZoneConfig{}
+	if err := gogoproto.Unmarshal(gr.Value.Bytes, zone); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(zone, systemZone) {
+		t.Errorf("expected system zone config %+v; got %+v", systemZone, zone)
+	}
+}
+
 // TestBootstrapNewStore starts a cluster with two unbootstrapped
 // stores and verifies both stores are added and started.
 func TestBootstrapNewStore(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	_, err := BootstrapCluster("cluster-1", e, stopper)
+	_, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -183,12 +217,39 @@ func TestBootstrapNewStore(t *testing.T) {
 	}
 }
 
+// TestNodeStartupProgress verifies that a node reports the progress
+// of its stores in recovering their state.
+func TestNodeStartupProgress(t *testing.T) {
+	stopper := util.NewStopper()
+	e := engine.NewInMem(proto.Attributes{}, 1<<20)
+	if _, err := BootstrapCluster("cluster-1", e, nil, stopper); err != nil {
+		t.Fatal(err)
+	}
+	stopper.Stop()
+
+	engines := []engine.Engine{e}
+	rpcServer, _, node, stopper := createTestNode(util.CreateTestAddr("tcp"), engines, nil, t)
+	defer stopper.Stop()
+	if p := node.StartupProgress(); p.Started || p.StoresOpened != 0 {
+		t.Errorf("expected no progress before start; got %+v", p)
+	} else if !strings.HasPrefix(p.String(), "recovering: ") {
+		t.Errorf("expected progress to report recovery; got %q", p)
+	}
+	if err := node.start(rpcServer, rpcServer.Addr(), engines, proto.Attributes{}, stopper); err != nil {
+		t.Fatal(err)
+	}
+	p := node.StartupProgress()
+	if !p.Started || p.Stores != 1 || p.StoresOpened != 1 || p.ReplicasLoaded != 1 {
+		t.Errorf("expected one store with one replica to have started; got %+v", p)
+	}
+}
+
 // TestNodeJoin verifies a new node is able to join a bootstrapped
 // cluster consisting of one node.
 func TestNodeJoin(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	_, err := BootstrapCluster("cluster-1", e, stopper)
+	_, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -237,7 +298,7 @@ func TestNodeJoin(t *testing.T) {
 func TestCorruptedClusterID(t *testing.T) {
 	stopper := util.NewStopper()
 	e := engine.NewInMem(proto.Attributes{}, 1<<20)
-	_, err := BootstrapCluster("cluster-1", e, stopper)
+	_, err := BootstrapCluster("cluster-1", e, nil, stopper)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -255,7 +316,7 @@ func TestCorruptedClusterID(t *testing.T) {
 
 	engines := []engine.Engine{e}
 	server, _, node, stopper := createTestNode(util.CreateTestAddr("tcp"), engines, nil, t)
-	if err := node.start(server, engines, proto.Attributes{}, stopper); err == nil {
+	if err := node.start(server, server.Addr(), engines, proto.Attributes{}, stopper); err == nil {
 		t.Errorf("unexpected success")
 	}
 	stopper.Stop()
@@ -0,0 +1,225 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// defaultOverloadDumpInterval is the minimum interval between the
+	// profiles captured when a node is overloaded.
+	defaultOverloadDumpInterval = 10 * time.Minute
+	// defaultOverloadGoroutines is the number of goroutines above which
+	// a node is overloaded.
+	defaultOverloadGoroutines = 10000
+	// defaultOverloadLatency is the mean command latency above which a
+	// node is overloaded.
+	defaultOverloadLatency = time.Second
+
+	// overloadCheckInterval is the interval over which command
+	// latencies are averaged and at which the goroutines are counted.
+	overloadCheckInterval = 10 * time.Second
+	// minOverloadCommands is the number of commands which must be
+	// executed within an interval for their latency to be considered,
+	// so that a few slow commands on an idle node aren't mistaken for
+	// overload.
+	minOverloadCommands = 10
+	// maxOverloadDumps is the number of captures whose profiles are
+	// kept in the log directory; the profiles of older captures are
+	// removed.
+	maxOverloadDumps = 5
+	// overloadDumpPrefix prefixes the names of the profile files.
+	overloadDumpPrefix = "cockroach.overload."
+)
+
+// A latencyRecorder accumulates the count and total latency of the
+// commands executed by a node. It's updated atomically.
+type latencyRecorder struct {
+	count int64
+	nanos int64
+}
+
+// record adds a command which took d to execute.
+func (l *latencyRecorder) record(d time.Duration) {
+	atomic.AddInt64(&l.count, 1)
+	atomic.AddInt64(&l.nanos, d.Nanoseconds())
+}
+
+// reset returns the number and mean latency of the commands recorded
+// since the last reset.
+func (l *latencyRecorder) reset() (int64, time.Duration) {
+	count := atomic.SwapInt64(&l.count, 0)
+	nanos := atomic.SwapInt64(&l.nanos, 0)
+	if count == 0 {
+		return 0, 0
+	}
+	return count, time.Duration(nanos / count)
+}
+
+// An overloadMonitor captures the goroutine stacks and heap profile of
+// the process to the log directory when the node appears overloaded,
+// so that the evidence survives an incident no operator was attached
+// to. A node is overloaded when the mean latency of the commands it
+// executed over the last interval, or its number of goroutines,
+// exceeds a limit. Profiles are captured at most once per dump
+// interval, and only those of the last maxOverloadDumps captures are
+// kept.
+type overloadMonitor struct {
+	latency       *latencyRecorder
+	dir           string
+	maxGoroutines int
+	maxLatency    time.Duration
+	dumpInterval  time.Duration
+	stopper       *util.Stopper
+	numGoroutine  func() int // Replaced by tests
+	lastDump      time.Time
+}
+
+// newOverloadMonitor returns an overloadMonitor of the command
+// latencies recorded by latency which writes profiles to dir.
+func newOverloadMonitor(latency *latencyRecorder, dir string, ctx *Context,
+	stopper *util.Stopper) *overloadMonitor {
+	return &overloadMonitor{
+		latency:       latency,
+		dir:           dir,
+		maxGoroutines: ctx.OverloadGoroutines,
+		maxLatency:    ctx.OverloadLatency,
+		dumpInterval:  ctx.OverloadDumpInterval,
+		stopper:       stopper,
+		numGoroutine:  runtime.NumGoroutine,
+	}
+}
+
+// start checks for overload every interval until the node is stopped.
+func (m *overloadMonitor) start() {
+	m.stopper.RunWorker(func() {
+		ticker := time.NewTicker(overloadCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				m.check(now)
+			case <-m.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// check captures profiles if the node is overloaded and none were
+// captured within the dump interval, returning the paths of the files
+// written.
+func (m *overloadMonitor) check(now time.Time) []string {
+	reason := m.overloaded()
+	if reason == "" || (!m.lastDump.IsZero() && now.Sub(m.lastDump) < m.dumpInterval) {
+		return nil
+	}
+	m.lastDump = now
+	paths, err := m.dump(now)
+	if err == nil {
+		err = m.removeOldDumps()
+	}
+	if err != nil {
+		log.Errorf("node overloaded (%s); unable to capture profiles: %s", reason, err)
+	} else {
+		log.Warningf("node overloaded (%s); captured profiles to %s", reason, paths)
+	}
+	return paths
+}
+
+// overloaded returns why the node is overloaded, or the empty string
+// if it isn't. The command latencies are reset.
+func (m *overloadMonitor) overloaded() string {
+	count, mean := m.latency.reset()
+	if n := m.numGoroutine(); n > m.maxGoroutines {
+		return fmt.Sprintf("%d goroutines exceed the limit of %d", n, m.maxGoroutines)
+	}
+	if count >= minOverloadCommands && mean > m.maxLatency {
+		return fmt.Sprintf("mean latency %s of %d commands exceeds the limit of %s", mean, count, m.maxLatency)
+	}
+	return ""
+}
+
+// dump writes the stacks of all goroutines and a heap profile to files
+// in the directory, named for the time of the capture.
+func (m *overloadMonitor) dump(now time.Time) ([]string, error) {
+	prefix := filepath.Join(m.dir, overloadDumpPrefix+now.Format("20060102-150405"))
+	var paths []string
+	for _, p := range []struct {
+		suffix string
+		write  func(*os.File) error
+	}{
+		{".goroutines", func(f *os.File) error { return pprof.Lookup("goroutine").WriteTo(f, 2) }},
+		{".heap", func(f *os.File) error { return pprof.WriteHeapProfile(f) }},
+	} {
+		f, err := os.Create(prefix + p.suffix)
+		if err != nil {
+			return paths, err
+		}
+		err = p.write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, f.Name())
+	}
+	return paths, nil
+}
+
+// removeOldDumps removes the profiles of all but the last
+// maxOverloadDumps captures from the directory. Captures are ordered by
+// their names, which hold the time of the capture.
+func (m *overloadMonitor) removeOldDumps() error {
+	paths, err := filepath.Glob(filepath.Join(m.dir, overloadDumpPrefix+"*"))
+	if err != nil {
+		return err
+	}
+	byCapture := map[string][]string{}
+	var captures []string
+	for _, path := range paths {
+		capture := strings.TrimSuffix(path, filepath.Ext(path))
+		if _, ok := byCapture[capture]; !ok {
+			captures = append(captures, capture)
+		}
+		byCapture[capture] = append(byCapture[capture], path)
+	}
+	if len(captures) <= maxOverloadDumps {
+		return nil
+	}
+	sort.Strings(captures)
+	for _, capture := range captures[:len(captures)-maxOverloadDumps] {
+		for _, path := range byCapture[capture] {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestOverloadMonitor verifies that profiles are captured when the
+// mean command latency or the number of goroutines exceeds its limit,
+// and at most once per dump interval, and that the profiles of old
+// captures are removed.
+func TestOverloadMonitor(t *testing.T) {
+	dir := util.CreateTempDir(t, "_overload_test")
+	defer util.CleanupDir(dir)
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+
+	ctx := NewContext()
+	var latency latencyRecorder
+	m := newOverloadMonitor(&latency, dir, ctx, stopper)
+	goroutines := 10
+	m.numGoroutine = func() int { return goroutines }
+	recordCommands := func(count int, d time.Duration) {
+		for i := 0; i < count; i++ {
+			latency.record(d)
+		}
+	}
+
+	now := time.Now()
+	recordCommands(100, time.Millisecond)
+	if paths := m.check(now); len(paths) != 0 {
+		t.Errorf("expected no profiles for a healthy node; got %s", paths)
+	}
+	// Too few slow commands aren't an overload.
+	recordCommands(minOverloadCommands-1, 2*ctx.OverloadLatency)
+	if paths := m.check(now); len(paths) != 0 {
+		t.Errorf("expected no profiles for a few slow commands; got %s", paths)
+	}
+	recordCommands(minOverloadCommands, 2*ctx.OverloadLatency)
+	paths := m.check(now)
+	if len(paths) != 2 {
+		t.Fatalf("expected goroutine and heap profiles; got %s", paths)
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("expected non-empty profile at %s: %v", path, err)
+		}
+	}
+
+	// Captures are rate limited.
+	goroutines = ctx.OverloadGoroutines + 1
+	if paths := m.check(now.Add(ctx.OverloadDumpInterval / 2)); len(paths) != 0 {
+		t.Errorf("expected no profiles within the dump interval; got %s", paths)
+	}
+	if paths := m.check(now.Add(ctx.OverloadDumpInterval)); len(paths) != 2 {
+		t.Errorf("expected profiles once the dump interval elapsed; got %s", paths)
+	}
+
+	// Only the profiles of the last captures are kept.
+	for i := 2; i <= maxOverloadDumps+1; i++ {
+		paths = m.check(now.Add(time.Duration(i) * ctx.OverloadDumpInterval))
+	}
+	files, err := filepath.Glob(filepath.Join(dir, overloadDumpPrefix+"*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2*maxOverloadDumps {
+		t.Errorf("expected the profiles of %d captures; got %s", maxOverloadDumps, files)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected the latest profiles to be kept: %s", err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// certExpiryWarning is how long before its expiration a
+	// certificate is warned about.
+	certExpiryWarning = 30 * 24 * time.Hour
+	// minFileDescriptors is the file descriptor limit below which a
+	// node refuses to start.
+	minFileDescriptors = 256
+	// recommendedFileDescriptors is the file descriptor limit below
+	// which a warning is logged.
+	recommendedFileDescriptors = 10000
+	// maxStoreWriteLatency is the longest a synchronous write of
+	// preflightWriteSize bytes to a store may take.
+	maxStoreWriteLatency = 500 * time.Millisecond
+	preflightWriteSize   = 4096
+)
+
+// A preflightWarning is returned by preflight checks which find a
+// condition that doesn't prevent a node from starting.
+type preflightWarning struct {
+	error
+}
+
+// A preflightCheck validates one aspect of a node's configuration or
+// environment. Checks which may be run while the node is running, as
+// they don't use its engines or addresses, are run by dry runs.
+type preflightCheck struct {
+	name   string
+	check  func(ctx *Context) error
+	dryRun bool
+}
+
+var preflightChecks = []preflightCheck{
+	{"certificates", checkCerts, true},
+	{"file descriptors", checkFileDescriptors, true},
+	{"store write latency", checkStoreWriteLatency, false},
+	{"clock synchronization", checkClockSync, true},
+	{"address", checkAddr, false},
+}
+
+// Preflight validates the certificates, file descriptor limit, store
+// disk latency, clock synchronization and listening addresses of an
+// initialized context before a node is started with it. Warnings are
+// logged; an error describing each failed check is returned.
+func Preflight(ctx *Context) error {
+	warnings, err := runPreflightChecks(ctx, false)
+	for _, w := range warnings {
+		log.Warningf("preflight %s", w)
+	}
+	return err
+}
+
+// runPreflightChecks runs the preflight checks, or, for a dry run,
+// those which may be run while the node is running, returning the
+// warnings and an error describing each failed check.
+func runPreflightChecks(ctx *Context, dryRun bool) ([]string, error) {
+	var warnings, failures []string
+	for _, c := range preflightChecks {
+		if dryRun && !c.dryRun {
+			continue
+		}
+		err := c.check(ctx)
+		if w, ok := err.(preflightWarning); ok {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", c.name, w.error))
+		} else if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", c.name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return warnings, util.Errorf("preflight checks failed:\n  %s", strings.Join(failures, "\n  "))
+	}
+	return warnings, nil
+}
+
+// checkCerts verifies that the node and CA certificates are valid now
+// and warns if either expires soon. Embedded test certificates are
+// not checked.
+func checkCerts(ctx *Context) error {
+	if strings.HasPrefix(ctx.Certs, security.EmbeddedPrefix) {
+		return nil
+	}
+	now := time.Now()
+	var warning error
+	for _, name := range []string{"ca.crt", "node.crt"} {
+		path := filepath.Join(ctx.Certs, name)
+		certPEM, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s; create certificates with \"cockroach cert\" or set -certs", err)
+		}
+		block, _ := pem.Decode(certPEM)
+		if block == nil {
+			return fmt.Errorf("%s: no PEM data found", path)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		if now.Before(cert.NotBefore) {
+			return fmt.Errorf("%s is not valid until %s; check the system clock", path, cert.NotBefore)
+		}
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("%s expired at %s; create a new certificate with \"cockroach cert\"", path, cert.NotAfter)
+		}
+		if now.Add(certExpiryWarning).After(cert.NotAfter) && warning == nil {
+			warning = preflightWarning{fmt.Errorf("%s expires at %s", path, cert.NotAfter)}
+		}
+	}
+	return warning
+}
+
+// checkFileDescriptors verifies that the process may open enough
+// files for its stores and connections.
+func checkFileDescriptors(ctx *Context) error {
+	var rLimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit); err != nil {
+		return preflightWarning{fmt.Errorf("unable to determine limit: %s", err)}
+	}
+	if rLimit.Cur < minFileDescriptors {
+		return fmt.Errorf("limit of %d open files is less than the minimum of %d; raise it with \"ulimit -n\"",
+			rLimit.Cur, minFileDescriptors)
+	}
+	if rLimit.Cur < recommendedFileDescriptors {
+		return preflightWarning{fmt.Errorf("limit of %d open files is less than the recommended %d",
+			rLimit.Cur, recommendedFileDescriptors)}
+	}
+	return nil
+}
+
+// checkStoreWriteLatency times a synchronous write to the directory
+// of each persistent store, creating the directory if necessary.
+func checkStoreWriteLatency(ctx *Context) error {
+	for _, e := range ctx.Engines {
+		r, ok := e.(engine.DirEngine)
+		if !ok || r.Dir() == "" {
+			continue
+		}
+		latency, err := measureWriteLatency(r.Dir())
+		if err != nil {
+			return fmt.Errorf("store %s is not writable: %s", r, err)
+		}
+		if latency > maxStoreWriteLatency {
+			return fmt.Errorf("store %s took %s to sync a %d byte write, more than the maximum of %s; check the health of its device",
+				r, latency, preflightWriteSize, maxStoreWriteLatency)
+		}
+	}
+	return nil
+}
+
+// measureWriteLatency returns the time taken to write and sync a
+// temporary file in dir.
+func measureWriteLatency(dir string) (time.Duration, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	f, err := ioutil.TempFile(dir, "preflight")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	start := time.Now()
+	if _, err := f.Write(make([]byte, preflightWriteSize)); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// checkClockSync warns if the operating system reports that the
+// system clock is not synchronized. Offsets between nodes are
+// monitored once the node has started; this only catches hosts which
+// aren't running a time daemon at all.
+func checkClockSync(ctx *Context) error {
+	synced, err := clockSynchronized()
+	if err != nil {
+		return preflightWarning{fmt.Errorf("unable to determine clock status: %s", err)}
+	}
+	if !synced {
+		return preflightWarning{fmt.Errorf("system clock is not synchronized; run NTP to keep clock offsets below %s",
+			ctx.MaxOffset)}
+	}
+	return nil
+}
+
+// checkAddr verifies that the addresses the node serves on are free.
+func checkAddr(ctx *Context) error {
+	addr := util.ParseAddr(ctx.Addr)
+	ln, err := net.Listen(addr.Network(), addr.String())
+	if err != nil {
+		return fmt.Errorf("%s; is another node running? choose a different address with -addr", err)
+	}
+	if err := ln.Close(); err != nil || ctx.HTTPAddr == "" {
+		return err
+	}
+	addr = util.ParseAddr(ctx.HTTPAddr)
+	ln, err = net.Listen(addr.Network(), addr.String())
+	if err != nil {
+		return fmt.Errorf("%s; is another node running? choose a different address with -http-addr", err)
+	}
+	return ln.Close()
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+func TestPreflightCerts(t *testing.T) {
+	dirs := util.CreateNTempDirs(t, "_preflight_test", 2)
+	defer util.CleanupDirs(dirs)
+
+	if err := security.RunCreateCACert(dirs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := security.RunCreateNodeCert(dirs[0], []string{"localhost"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext()
+	for i, test := range []struct {
+		certs  string
+		expErr bool
+	}{
+		{dirs[0], false},
+		{dirs[1], true},
+		{security.EmbeddedPrefix + "test_certs", false},
+	} {
+		ctx.Certs = test.certs
+		if err := checkCerts(ctx); (err != nil) != test.expErr {
+			t.Errorf("%d: expected error %t; got %v", i, test.expErr, err)
+		}
+	}
+}
+
+func TestPreflightStoreWriteLatency(t *testing.T) {
+	dir := util.CreateTempDir(t, "_preflight_test")
+	defer util.CleanupDir(dir)
+
+	ctx := NewContext()
+	ctx.Engines = []engine.Engine{
+		engine.NewInMem(proto.Attributes{}, 1<<20),
+		engine.NewRocksDB(proto.Attributes{}, dir, 1<<20),
+	}
+	if err := checkStoreWriteLatency(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The temporary file written to the store must be removed.
+	if files, err := ioutil.ReadDir(dir); err != nil || len(files) != 0 {
+		t.Errorf("expected empty store directory; got %v, %v", files, err)
+	}
+}
+
+func TestPreflightAddr(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext()
+	ctx.Addr = ln.Addr().String()
+	if err := checkAddr(ctx); err == nil {
+		t.Error("expected error checking address in use")
+	}
+	ln.Close()
+	if err := checkAddr(ctx); err != nil {
+		t.Errorf("expected address to be available; got %s", err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/rpc"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// A connPrewarmer connects to each node as its descriptor is learned
+// through gossip, including those already known when it's started, so
+// that the first request sent to a new peer doesn't wait on dialing,
+// the TLS handshake and the heartbeat which precedes a connection
+// being ready. The connections are those of the process-wide RPC
+// client cache, which are kept alive by their heartbeats and shared
+// with the distributed sender and the raft transport.
+type connPrewarmer struct {
+	gossip     *gossip.Gossip
+	rpcContext *rpc.Context
+
+	mu      sync.Mutex
+	clients map[proto.NodeID]*rpc.Client
+}
+
+// newConnPrewarmer returns a connPrewarmer for the nodes gossiped by g
+// which connects using context.
+func newConnPrewarmer(g *gossip.Gossip, context *rpc.Context) *connPrewarmer {
+	return &connPrewarmer{
+		gossip:     g,
+		rpcContext: context,
+		clients:    map[proto.NodeID]*rpc.Client{},
+	}
+}
+
+// start registers for gossip of node descriptors. Connections aren't
+// prewarmed if the client cache is disabled, as they would never be
+// reused.
+func (p *connPrewarmer) start() {
+	if p.rpcContext.DisableCache {
+		return
+	}
+	p.gossip.RegisterCallback(gossip.MakePrefixPattern(gossip.KeyNodeIDPrefix), p.nodeGossipUpdate)
+}
+
+// nodeGossipUpdate connects to the node whose descriptor was gossiped
+// under key, unless it's this node or its connection is still open.
+// The descriptor is checked even if its contents are unchanged, to
+// reconnect to nodes whose connections have failed their heartbeats.
+func (p *connPrewarmer) nodeGossipUpdate(key string, contentsChanged bool) {
+	val, err := p.gossip.GetInfo(key)
+	if err != nil {
+		log.Errorf("unable to fetch node descriptor %s: %s", key, err)
+		return
+	}
+	desc, ok := val.(*gossip.NodeDescriptor)
+	if !ok {
+		log.Errorf("gossiped info for %s is not a node descriptor: %+v", key, val)
+		return
+	}
+	if desc.NodeID == p.gossip.GetNodeID() {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[desc.NodeID]; ok && c.Addr().String() == desc.Address.String() {
+		select {
+		case <-c.Closed:
+		default:
+			return
+		}
+	}
+	log.V(1).Infof("prewarming connection to node %d at %s", desc.NodeID, desc.Address)
+	p.clients[desc.NodeID] = rpc.NewClient(desc.Address, nil, p.rpcContext)
+}
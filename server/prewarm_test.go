@@ -0,0 +1,60 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/rpc"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestConnPrewarming verifies that a server connects to a node, and
+// completes a heartbeat with it, as soon as the node's descriptor is
+// gossiped, and that it doesn't connect to itself.
+func TestConnPrewarming(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	peer := StartTestServer(t)
+	defer peer.Stop()
+
+	const peerID = proto.NodeID(100)
+	desc := &gossip.NodeDescriptor{NodeID: peerID, Address: peer.rpc.Addr()}
+	if err := s.Gossip().AddInfo(gossip.MakeNodeIDKey(peerID), desc, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	client := func(nodeID proto.NodeID) *rpc.Client {
+		s.prewarmer.mu.Lock()
+		defer s.prewarmer.mu.Unlock()
+		return s.prewarmer.clients[nodeID]
+	}
+	util.SucceedsWithin(t, 5*time.Second, func() error {
+		c := client(peerID)
+		if c == nil {
+			return util.Errorf("expected connection to node %d", peerID)
+		}
+		if !c.IsHealthy() {
+			return util.Errorf("expected connection to node %d to complete a heartbeat", peerID)
+		}
+		return nil
+	})
+	if c := client(s.Gossip().GetNodeID()); c != nil {
+		t.Errorf("expected no connection to self; got one to %s", c.Addr())
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// ReloadableSettings are the settings of a Context which a running node
+// may change without restarting.
+type ReloadableSettings struct {
+	// ScanInterval is the interval in which each store's range scanner
+	// completes a full scan.
+	ScanInterval time.Duration
+	// GossipInterval is the interval at which fresh info is gossiped.
+	GossipInterval time.Duration
+	// CacheSize is the total capacity of the block caches of the
+	// stores, split evenly between those which don't set their own
+	// after the caches of those which do.
+	CacheSize int64
+	// Verbosity is the level of V-style logging.
+	Verbosity int
+	// TraceSampleRate is the fraction of commands which are traced.
+	TraceSampleRate float64
+}
+
+// String formats the settings as the flags and config file keys which
+// set them, one per line.
+func (rs ReloadableSettings) String() string {
+	return fmt.Sprintf("scan-interval: %s\ngossip-interval: %s\ncache-size: %d\nv: %d\ntrace-sample-rate: %g\n",
+		rs.ScanInterval, rs.GossipInterval, rs.CacheSize, rs.Verbosity, rs.TraceSampleRate)
+}
+
+// validate returns an error listing each of the settings which is out
+// of range.
+func (rs ReloadableSettings) validate() error {
+	var problems validationProblems
+	rs.check(&problems)
+	if len(problems) > 0 {
+		return util.Error(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// check adds a problem for each of the settings which is out of range.
+func (rs ReloadableSettings) check(problems *validationProblems) {
+	if rs.ScanInterval <= 0 {
+		problems.addf("scan interval must be positive: %s", rs.ScanInterval)
+	}
+	if rs.GossipInterval <= 0 {
+		problems.addf("gossip interval must be positive: %s", rs.GossipInterval)
+	}
+	if rs.CacheSize <= 0 {
+		problems.addf("cache size must be positive: %d", rs.CacheSize)
+	}
+	if rs.Verbosity < 0 {
+		problems.addf("log verbosity must not be negative: %d", rs.Verbosity)
+	}
+	if rs.TraceSampleRate < 0 || rs.TraceSampleRate > 1 {
+		problems.addf("trace sample rate must be between 0 and 1: %g", rs.TraceSampleRate)
+	}
+}
+
+// A ReloadableContext holds the reloadable settings of a running node.
+// Components which apply a setting subscribe to be notified when the
+// settings change, whether on an explicit Update or on a Reload from
+// the context's source, such as the node's config file.
+type ReloadableContext struct {
+	mu          sync.Mutex
+	settings    ReloadableSettings
+	source      func() (ReloadableSettings, error)
+	subscribers []func(ReloadableSettings)
+}
+
+// NewReloadableContext returns a ReloadableContext holding the supplied
+// initial settings.
+func NewReloadableContext(settings ReloadableSettings) *ReloadableContext {
+	return &ReloadableContext{settings: settings}
+}
+
+// Settings returns the current settings.
+func (rc *ReloadableContext) Settings() ReloadableSettings {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.settings
+}
+
+// Subscribe registers fn to be invoked with the new settings each time
+// they change. Subscribers are invoked in the order in which they
+// subscribed, while holding the context's lock, so updates are applied
+// in order and fn must not call back into the context.
+func (rc *ReloadableContext) Subscribe(fn func(ReloadableSettings)) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.subscribers = append(rc.subscribers, fn)
+}
+
+// SetSource sets the function from which Reload reads the settings.
+func (rc *ReloadableContext) SetSource(source func() (ReloadableSettings, error)) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.source = source
+}
+
+// Update validates the settings and, if they differ from the current
+// ones, replaces them and notifies the subscribers.
+func (rc *ReloadableContext) Update(settings ReloadableSettings) error {
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if settings == rc.settings {
+		return nil
+	}
+	log.Infof("reloading settings:\n%s", settings)
+	rc.settings = settings
+	for _, fn := range rc.subscribers {
+		fn(settings)
+	}
+	return nil
+}
+
+// Reload reads the settings from the context's source and updates the
+// context with them.
+func (rc *ReloadableContext) Reload() error {
+	rc.mu.Lock()
+	source := rc.source
+	rc.mu.Unlock()
+	if source == nil {
+		return util.Errorf("settings have no source to be reloaded from")
+	}
+	settings, err := source()
+	if err != nil {
+		return util.Errorf("unable to reload settings: %s", err)
+	}
+	return rc.Update(settings)
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestReloadableContext verifies that subscribers are notified of
+// changed settings only, and that invalid settings are rejected.
+func TestReloadableContext(t *testing.T) {
+	initial := ReloadableSettings{
+		ScanInterval:   10 * time.Minute,
+		GossipInterval: 2 * time.Second,
+		CacheSize:      1 << 30,
+	}
+	rc := NewReloadableContext(initial)
+	var notified []ReloadableSettings
+	rc.Subscribe(func(s ReloadableSettings) { notified = append(notified, s) })
+
+	if err := rc.Update(initial); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 0 {
+		t.Errorf("expected no notification of unchanged settings; got %+v", notified)
+	}
+
+	updated := initial
+	updated.ScanInterval = time.Minute
+	updated.Verbosity = 2
+	if err := rc.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 || notified[0] != updated {
+		t.Errorf("expected notification of %+v; got %+v", updated, notified)
+	}
+
+	invalid := updated
+	invalid.GossipInterval = 0
+	if err := rc.Update(invalid); err == nil {
+		t.Error("expected error updating to a zero gossip interval")
+	}
+	invalid = updated
+	invalid.TraceSampleRate = 1.5
+	if err := rc.Update(invalid); err == nil {
+		t.Error("expected error updating to a trace sample rate above 1")
+	}
+	if s := rc.Settings(); s != updated {
+		t.Errorf("expected invalid settings to be rejected; got %+v", s)
+	}
+
+	// Reloading requires a source.
+	if err := rc.Reload(); err == nil {
+		t.Error("expected error reloading without a source")
+	}
+	rc.SetSource(func() (ReloadableSettings, error) {
+		return ReloadableSettings{}, util.Errorf("unreadable")
+	})
+	if err := rc.Reload(); err == nil {
+		t.Error("expected error from source")
+	}
+	rc.SetSource(func() (ReloadableSettings, error) { return initial, nil })
+	if err := rc.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 2 || notified[1] != initial || rc.Settings() != initial {
+		t.Errorf("expected reload to restore %+v; got %+v", initial, notified)
+	}
+}
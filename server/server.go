@@ -20,6 +20,7 @@ package server
 
 import (
 	"compress/gzip"
+	"crypto/tls"
 	"io"
 	"net"
 	"net/http"
@@ -36,6 +37,7 @@ import (
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/security"
 	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/structured"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
@@ -57,17 +59,31 @@ type Server struct {
 
 	mux            *http.ServeMux
 	clock          *hlc.Clock
+	tlsConfig      *security.TLSConfig
 	rpc            *rpc.Server
+	httpListener   net.Listener // Only set if HTTP is served apart from RPC
 	gossip         *gossip.Gossip
+	prewarmer      *connPrewarmer
 	kv             *client.KV
 	kvDB           *kv.DBServer
 	kvREST         *kv.RESTServer
+	kvBatch        *kv.BatchServer
+	kvBulkLoad     *kv.BulkLoadServer
 	node           *Node
+	drainer        *drainer
+	limiter        *clientLimiter
+	overload       *overloadMonitor // Nil unless capturing profiles on overload
+	shipper        *logShipper      // Nil unless replicating to a standby cluster
+	standby        *standbyGate     // Nil unless started as a standby
 	admin          *adminServer
+	jobs           *JobCoordinator
+	namespaces     *NamespaceManager
 	status         *statusServer
 	structuredDB   structured.DB
 	structuredREST *structured.RESTServer
 	raftTransport  multiraft.Transport
+	reloadable     *ReloadableContext
+	traces         *storage.TraceLog
 	stopper        *util.Stopper
 }
 
@@ -77,12 +93,27 @@ func NewServer(ctx *Context, stopper *util.Stopper) (*Server, error) {
 		return nil, util.Error("ctx must not be null")
 	}
 
-	addr := ctx.Addr
-	_, err := net.ResolveTCPAddr("tcp", addr)
-	if err != nil {
-		return nil, util.Errorf("unable to resolve RPC address %q: %v", addr, err)
+	addr := util.ParseAddr(ctx.Addr)
+	if err := resolveAddr(addr); err != nil {
+		return nil, util.Errorf("unable to resolve RPC address %q: %v", ctx.Addr, err)
+	}
+	if ctx.AdvertiseAddr != "" && !strings.HasPrefix(ctx.AdvertiseAddr, util.UnixAddrPrefix) {
+		host, _, err := net.SplitHostPort(ctx.AdvertiseAddr)
+		if err != nil {
+			return nil, util.Errorf("invalid advertise address %q: %v", ctx.AdvertiseAddr, err)
+		}
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			return nil, util.Errorf("advertise address %q must specify a host reachable by other nodes",
+				ctx.AdvertiseAddr)
+		}
+	}
+	if ctx.HTTPAddr != "" {
+		if err := resolveAddr(util.ParseAddr(ctx.HTTPAddr)); err != nil {
+			return nil, util.Errorf("unable to resolve HTTP address %q: %v", ctx.HTTPAddr, err)
+		}
 	}
 
+	var err error
 	var tlsConfig *security.TLSConfig
 	if ctx.Certs == "" {
 		tlsConfig = security.LoadInsecureTLSConfig()
@@ -93,22 +124,34 @@ func NewServer(ctx *Context, stopper *util.Stopper) (*Server, error) {
 	}
 
 	s := &Server{
-		ctx:     ctx,
-		mux:     http.NewServeMux(),
-		clock:   hlc.NewClock(hlc.UnixNano),
-		stopper: stopper,
+		ctx:       ctx,
+		mux:       http.NewServeMux(),
+		clock:     hlc.NewClock(hlc.UnixNano),
+		tlsConfig: tlsConfig,
+		stopper:   stopper,
 	}
 	s.clock.SetMaxOffset(ctx.MaxOffset)
 
 	rpcContext := rpc.NewContext(s.clock, tlsConfig, stopper)
+	rpcContext.Dial = ctx.Dial
 	go rpcContext.RemoteClocks.MonitorRemoteOffsets()
 
-	s.rpc = rpc.NewServer(util.MakeRawAddr("tcp", addr), rpcContext)
+	s.rpc = rpc.NewServer(addr, rpcContext)
 	s.stopper.AddCloser(s.rpc)
 	s.gossip = gossip.New(rpcContext, s.ctx.GossipInterval, s.ctx.GossipBootstrapResolvers)
-
-	ds := kv.NewDistSender(&kv.DistSenderContext{Clock: s.clock}, s.gossip)
+	s.gossip.SetMaxPeers(s.ctx.GossipMaxOutgoing, s.ctx.GossipMaxIncoming)
+	s.prewarmer = newConnPrewarmer(s.gossip, rpcContext)
+
+	ds := kv.NewDistSender(&kv.DistSenderContext{
+		Clock:                 s.clock,
+		HedgeReadTimeout:      ctx.HedgeReadTimeout,
+		RetryBudgetRatio:      ctx.RetryBudgetRatio,
+		RetryBudgetMaxRetries: ctx.RetryBudgetMaxRetries,
+		Authorizer:            ctx.Authorizer,
+	}, s.gossip)
 	sender := kv.NewTxnCoordSender(ds, s.clock, ctx.Linearizable, s.stopper)
+	sender.SetPipelineWrites(ctx.PipelineWrites)
+	sender.SetBatchLimits(ctx.MaxBatchRequests, ctx.MaxBatchBytes)
 	s.kv = client.NewKV(nil, sender)
 	s.kv.User = storage.UserRoot
 
@@ -118,55 +161,233 @@ func NewServer(ctx *Context, stopper *util.Stopper) (*Server, error) {
 	}
 	s.stopper.AddCloser(s.raftTransport)
 
-	s.kvDB = kv.NewDBServer(sender)
-	s.kvREST = kv.NewRESTServer(s.kv)
+	// The key-value endpoints of a standby node are gated until its
+	// cluster is promoted.
+	clientSender := client.KVSender(sender)
+	if ctx.Standby {
+		s.standby = newStandbyGate(sender, s.kv, s.clock, ctx.ReplicateInterval, s.stopper)
+		clientSender = s.standby
+	}
+	clientKV := client.NewKV(nil, clientSender)
+	clientKV.User = storage.UserRoot
+	s.kvDB = kv.NewDBServer(clientSender)
+	s.kvREST = kv.NewRESTServer(clientKV)
+	s.kvBatch = kv.NewBatchServer(clientKV)
+	s.kvBulkLoad = kv.NewBulkLoadServer(clientKV)
+	s.traces = storage.NewTraceLog(storage.TraceLogSize, ctx.TraceSampleRate)
 	// TODO(bdarnell): make StoreConfig configurable.
 	nCtx := storage.StoreContext{
-		Clock:        s.clock,
-		DB:           s.kv,
-		Gossip:       s.gossip,
-		Transport:    s.raftTransport,
-		Context:      context.Background(),
-		ScanInterval: s.ctx.ScanInterval,
+		Clock:              s.clock,
+		DB:                 s.kv,
+		Gossip:             s.gossip,
+		Transport:          s.raftTransport,
+		Context:            context.Background(),
+		ScanInterval:       s.ctx.ScanInterval,
+		ClosedTimestampLag: s.ctx.ClosedTimestampLag,
+		Standby:            s.ctx.Standby,
+		SnapshotApplyRate:  s.ctx.SnapshotApplyRate,
+		TxnAbandonTimeout:  s.ctx.TxnAbandonTimeout,
+		Traces:             s.traces,
+		IOProbeInterval:    s.ctx.StoreIOProbeInterval,
+		MaxSyncLatency:     s.ctx.StoreMaxSyncLatency,
+		MaxReadLatency:     s.ctx.StoreMaxReadLatency,
+		ScrubInterval:      s.ctx.StoreScrubInterval,
+		MinAvailable:       s.ctx.StoreMinAvailable,
+		BallastSize:        s.ctx.StoreBallastSize,
+		ReadCacheSize:      s.ctx.ReadCacheSize,
+		ScanPrefetchSize:   s.ctx.ScanPrefetchSize,
 	}
 	s.node = NewNode(nCtx)
-	s.admin = newAdminServer(s.kv, s.stopper)
-	s.status = newStatusServer(s.kv, s.gossip)
-	s.structuredDB = structured.NewDB(s.kv)
+	s.drainer = newDrainer(s.node, s.stopper)
+	s.limiter = newClientLimiter(ctx.MaxClientRequestsPerUser, ctx.MaxClientRequestsPerHost, ctx.ClientQueueTimeout)
+	if ctx.OverloadDumpInterval > 0 {
+		s.overload = newOverloadMonitor(&s.node.latency, log.Dir(), ctx, s.stopper)
+	}
+	if ctx.ReplicateTo != "" {
+		certs := ctx.ReplicateCerts
+		if certs == "" {
+			certs = ctx.Certs
+		}
+		sender, err := client.NewHTTPSender(ctx.ReplicateTo, certs)
+		if err != nil {
+			return nil, util.Errorf("unable to connect to standby cluster %s: %s", ctx.ReplicateTo, err)
+		}
+		standby := client.NewKV(nil, sender)
+		standby.User = storage.UserReplication
+		s.shipper = newLogShipper(s.node, standby, ctx.replicatePrefixes(), ctx.ReplicateInterval, s.stopper)
+	}
+	s.jobs = NewJobCoordinator(s.kv, s.clock, s.stopper)
+	s.namespaces = NewNamespaceManager(s.kv, s.jobs)
+	s.reloadable = NewReloadableContext(ctx.ReloadableSettings())
+	s.reloadable.Subscribe(s.applySettings)
+	s.admin = newAdminServer(s.kv, s.stopper, s.jobs, s.namespaces, s.node, s.drainer, s.standby, ctx, s.reloadable, ctx.Certs == "")
+	s.status = newStatusServer(s.kv, s.gossip, ctx, s.node)
+	s.structuredDB = structured.NewDB(clientKV)
 	s.structuredREST = structured.NewRESTServer(s.structuredDB)
 
 	return s, nil
 }
 
 // Start runs the RPC and HTTP servers, starts the gossip instance (if
-// selfBootstrap is true, uses the rpc server's address as the gossip
-// bootstrap), and starts the node using the supplied engines slice.
+// selfBootstrap is true, uses the node's advertised address as the
+// gossip bootstrap), and starts the node using the supplied engines
+// slice.
 func (s *Server) Start(selfBootstrap bool) error {
 	if err := s.rpc.Listen(); err != nil {
 		return util.Errorf("could not listen on %s: %s", s.ctx.Addr, err)
 	}
 
+	// Other nodes reach this one at its advertised address, which
+	// defaults to the address the rpc server is listening on.
+	addr := s.rpc.Addr()
+	if s.ctx.AdvertiseAddr != "" {
+		addr = util.ParseAddr(s.ctx.AdvertiseAddr)
+		log.Infof("advertising address %s", addr)
+	}
+	s.gossip.SetAdvertiseAddr(addr)
+
 	// Handle self-bootstrapping case for a single node.
 	if selfBootstrap {
-		selfResolver, err := gossip.NewResolver(s.rpc.Addr().String())
-		if err != nil {
+		s.gossip.SetResolvers([]gossip.Resolver{gossip.NewResolverFromAddress(addr)})
+	}
+	s.gossip.Start(s.rpc, s.stopper)
+	s.prewarmer.start()
+	s.status.topology.start(s.stopper)
+
+	// Serve before starting the node, so that the health endpoint can
+	// report the progress of stores which are slow to recover.
+	// TODO(spencer): go1.5 is supposed to allow shutdown of running http server.
+	s.initHTTP()
+	if s.ctx.HTTPAddr == "" {
+		log.Infof("starting https server at %s", s.rpc.Addr())
+		s.rpc.Serve(s)
+	} else {
+		if err := s.listenHTTP(); err != nil {
 			return err
 		}
-		s.gossip.SetResolvers([]gossip.Resolver{selfResolver})
+		log.Infof("starting rpc server at %s and https server at %s", s.rpc.Addr(), s.HTTPAddr())
+		s.rpc.Serve(nil)
+		go http.Serve(s.httpListener, s)
 	}
-	s.gossip.Start(s.rpc, s.stopper)
 
-	if err := s.node.start(s.rpc, s.ctx.Engines, s.ctx.NodeAttributes, s.stopper); err != nil {
+	s.node.Descriptor.HTTPAddress = s.advertiseHTTPAddr()
+	if err := s.node.start(s.rpc, addr, s.ctx.Engines, s.ctx.NodeAttributes, s.stopper); err != nil {
 		return err
 	}
+	for _, e := range s.ctx.Engines {
+		if enc, ok := e.(*engine.Encrypted); ok {
+			s.rekey(enc)
+		}
+	}
+	s.jobs.Start()
+	if s.overload != nil {
+		s.overload.start()
+	}
+	if s.shipper != nil {
+		log.Infof("shipping writes to %s to standby cluster at %s", s.ctx.ReplicatePrefixes, s.ctx.ReplicateTo)
+		s.shipper.start()
+	}
+	if s.standby != nil {
+		log.Infof("serving reads only until the standby cluster is promoted")
+		s.standby.start()
+	}
+	return nil
+}
 
-	log.Infof("starting https server at %s", s.rpc.Addr())
-	// TODO(spencer): go1.5 is supposed to allow shutdown of running http server.
-	s.initHTTP()
-	s.rpc.Serve(s)
+// rekey rewrites, in the background, the sstables of the encrypted
+// engine which were encrypted with keys other than its own.
+func (s *Server) rekey(e *engine.Encrypted) {
+	s.stopper.RunWorker(func() {
+		n, err := e.Rekey(s.stopper.ShouldStop())
+		if err != nil {
+			log.Errorf("unable to re-encrypt store %s: %s", e, err)
+		} else if n > 0 {
+			log.Infof("rewrote sstables of store %s encrypted with other keys in %d compactions", e, n)
+		}
+	})
+}
+
+// listenHTTP listens for HTTP traffic on the context's HTTPAddr,
+// using TLS unless the server is insecure.
+func (s *Server) listenHTTP() error {
+	addr := util.ParseAddr(s.ctx.HTTPAddr)
+	var ln net.Listener
+	var err error
+	if cfg := s.tlsConfig.Config(); cfg == nil {
+		if addr.Network() != "unix" {
+			log.Warningf("listening via tcp to %s without TLS", addr)
+		}
+		ln, err = net.Listen(addr.Network(), addr.String())
+	} else {
+		ln, err = tls.Listen(addr.Network(), addr.String(), cfg)
+	}
+	if err != nil {
+		return util.Errorf("could not listen on %s: %s", s.ctx.HTTPAddr, err)
+	}
+	s.httpListener = ln
+	s.stopper.AddCloser(listenerCloser{ln})
 	return nil
 }
 
+// HTTPAddr returns the address at which the server serves HTTP, which
+// is the rpc server's address unless HTTP is served on its own
+// listener.
+func (s *Server) HTTPAddr() net.Addr {
+	if s.httpListener != nil {
+		return s.httpListener.Addr()
+	}
+	return s.rpc.Addr()
+}
+
+// advertiseHTTPAddr returns the address at which other nodes and
+// clients reach the HTTP listener, or nil if HTTP is served by the rpc
+// server. The host of the advertised address, if any, is used with the
+// port being listened on.
+func (s *Server) advertiseHTTPAddr() net.Addr {
+	if s.httpListener == nil {
+		return nil
+	}
+	addr := s.httpListener.Addr()
+	if s.ctx.AdvertiseAddr == "" || addr.Network() != "tcp" {
+		return addr
+	}
+	advertised := util.ParseAddr(s.ctx.AdvertiseAddr)
+	if advertised.Network() != "tcp" {
+		return addr
+	}
+	host, _, err := net.SplitHostPort(advertised.String())
+	if err != nil {
+		return addr
+	}
+	_, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr
+	}
+	return util.MakeRawAddr("tcp", net.JoinHostPort(host, port))
+}
+
+// resolveAddr verifies that addr, as returned by util.ParseAddr,
+// resolves.
+func resolveAddr(addr net.Addr) error {
+	var err error
+	if addr.Network() == "unix" {
+		_, err = net.ResolveUnixAddr("unix", addr.String())
+	} else {
+		_, err = net.ResolveTCPAddr("tcp", addr.String())
+	}
+	return err
+}
+
+// listenerCloser adapts a net.Listener to util.Closer.
+type listenerCloser struct {
+	net.Listener
+}
+
+// Close closes the listener, ignoring errors.
+func (lc listenerCloser) Close() {
+	lc.Listener.Close()
+}
+
 func (s *Server) initHTTP() {
 	s.mux.Handle("/", http.FileServer(
 		&assetfs.AssetFS{Asset: resource.Asset, AssetDir: resource.AssetDir, Prefix: "./ui/"}))
@@ -179,9 +400,56 @@ func (s *Server) initHTTP() {
 
 	s.mux.Handle(kv.RESTPrefix, s.kvREST)
 	s.mux.Handle(kv.DBPrefix, s.kvDB)
+	s.mux.Handle(kv.BatchPrefix, s.kvBatch)
+	s.mux.Handle(kv.BulkLoadPrefix, s.kvBulkLoad)
 	s.mux.Handle(structured.StructuredKeyPrefix, s.structuredREST)
 }
 
+// Jobs returns the server's job coordinator, with which subsystems
+// register the functions that run their jobs.
+func (s *Server) Jobs() *JobCoordinator {
+	return s.jobs
+}
+
+// Reloadable returns the server's reloadable settings, through which
+// they're changed while the server is running.
+func (s *Server) Reloadable() *ReloadableContext {
+	return s.reloadable
+}
+
+// applySettings applies reloaded settings to the gossip instance, the
+// stores and their engines, the log and the trace log.
+func (s *Server) applySettings(settings ReloadableSettings) {
+	s.gossip.SetInterval(settings.GossipInterval)
+	if err := s.node.lSender.VisitStores(func(store *storage.Store) error {
+		store.SetScanInterval(settings.ScanInterval)
+		return nil
+	}); err != nil {
+		log.Errorf("unable to set scan interval: %s", err)
+	}
+	if cacheSize, err := sharedCacheSize(settings.CacheSize, s.ctx.storeSpecs); err != nil {
+		log.Errorf("unable to set cache size: %s", err)
+	} else {
+		for _, e := range s.ctx.sharedCacheEngines {
+			if cs, ok := e.(cacheSizer); ok {
+				cs.SetCacheSize(cacheSize)
+			}
+		}
+	}
+	if err := log.SetVerbosity(settings.Verbosity); err != nil {
+		log.Errorf("unable to set log verbosity: %s", err)
+	}
+	s.traces.SetSampleRate(settings.TraceSampleRate)
+}
+
+// Drain refuses further client requests, transfers the leader leases
+// held by the node's stores to other replicas of their ranges and
+// flushes the stores, returning once the node is ready to be stopped.
+// Its progress is served at the admin drain endpoint.
+func (s *Server) Drain() {
+	<-s.drainer.start()
+}
+
 // Stop stops the server.
 func (s *Server) Stop() {
 	s.stopper.Stop()
@@ -197,6 +465,22 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer s.stopper.FinishTask()
 
+	// Refuse client requests once the node is draining, so that they're
+	// retried on other nodes. The admin and status endpoints are still
+	// served to report the drain's progress.
+	if s.drainer.draining() && isClientRequest(r.URL.Path) {
+		http.Error(w, "node is draining", http.StatusServiceUnavailable)
+		return
+	}
+	if isClientRequest(r.URL.Path) {
+		release, ok := s.limiter.acquire(r)
+		if !ok {
+			http.Error(w, "too many client requests in flight", http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+	}
+
 	// Disable caching of responses.
 	w.Header().Set("Cache-control", "no-cache")
 
@@ -216,6 +500,17 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
 }
 
+// isClientRequest returns whether the path is that of one of the
+// key-value or structured data endpoints.
+func isClientRequest(path string) bool {
+	for _, prefix := range []string{kv.RESTPrefix, kv.DBPrefix, kv.BatchPrefix, kv.BulkLoadPrefix, structured.StructuredKeyPrefix} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 type gzipResponseWriter struct {
 	io.WriteCloser
 	http.ResponseWriter
@@ -86,7 +86,7 @@ func TestInitEngine(t *testing.T) {
 		{"hdd=", proto.Attributes{}, true, false},
 	}
 	for _, spec := range testCases {
-		ctx := NewContext()
+		ctx := newTestContext()
 		ctx.Stores, ctx.GossipBootstrap = spec.key, "self://"
 		err := ctx.Init()
 		engines := ctx.Engines
@@ -101,8 +101,7 @@ func TestInitEngine(t *testing.T) {
 			if e.Attrs().SortedString() != spec.expAttrs.SortedString() {
 				t.Errorf("wrong engine attributes, expected %v but got %v: %+v", spec.expAttrs, e.Attrs(), spec)
 			}
-			_, ok := e.(*engine.InMem)
-			if spec.isMem != ok {
+			if ok := isMemEngine(e); spec.isMem != ok {
 				t.Errorf("expected in memory? %t, got %t: %+v", spec.isMem, ok, spec)
 			}
 		} else if !spec.wantError {
@@ -111,13 +110,19 @@ func TestInitEngine(t *testing.T) {
 	}
 }
 
+// isMemEngine returns whether e is a RocksDB engine kept in memory.
+func isMemEngine(e engine.Engine) bool {
+	r, ok := e.(*engine.RocksDB)
+	return ok && r.Dir() == ""
+}
+
 // TestInitEngines tests whether multiple engines specified as a
 // single comma-separated list are parsed correctly.
 func TestInitEngines(t *testing.T) {
 	tmp := util.CreateNTempDirs(t, "_server_test", 2)
 	defer util.CleanupDirs(tmp)
 
-	ctx := NewContext()
+	ctx := newTestContext()
 	ctx.Stores = fmt.Sprintf("mem=1000,mem:ddr3=1000,ssd=%s,hdd:7200rpm=%s", tmp[0], tmp[1])
 	ctx.GossipBootstrap = "self://"
 	expEngines := []struct {
@@ -142,8 +147,7 @@ func TestInitEngines(t *testing.T) {
 		if e.Attrs().SortedString() != expEngines[i].attrs.SortedString() {
 			t.Errorf("wrong engine attributes, expected %v but got %v: %+v", expEngines[i].attrs, e.Attrs(), expEngines[i])
 		}
-		_, ok := e.(*engine.InMem)
-		if expEngines[i].isMem != ok {
+		if ok := isMemEngine(e); expEngines[i].isMem != ok {
 			t.Errorf("expected in memory? %t, got %t: %+v", expEngines[i].isMem, ok, expEngines[i])
 		}
 	}
@@ -156,6 +160,33 @@ func TestSelfBootstrap(t *testing.T) {
 	s.Stop()
 }
 
+// TestAdvertiseAddrValidation verifies that a server may not advertise
+// an address without a host other nodes can reach.
+func TestAdvertiseAddrValidation(t *testing.T) {
+	testCases := []struct {
+		addr  string
+		valid bool
+	}{
+		{"", true},
+		{"10.0.0.1:26257", true},
+		{"node1.example.com:26257", true},
+		{"10.0.0.1", false},
+		{":26257", false},
+		{"0.0.0.0:26257", false},
+		{"[::]:26257", false},
+	}
+	for i, test := range testCases {
+		ctx := NewTestContext()
+		ctx.AdvertiseAddr = test.addr
+		stopper := util.NewStopper()
+		_, err := NewServer(ctx, stopper)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%d: expected valid %t for %q; got %v", i, test.valid, test.addr, err)
+		}
+		stopper.Stop()
+	}
+}
+
 // TestHealth verifies that health endpoint return "ok".
 func TestHealth(t *testing.T) {
 	s := StartTestServer(t)
@@ -176,6 +207,61 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+// TestHTTPAddr verifies that a server with an HTTP address serves
+// HTTP traffic on it and RPC traffic alone on its RPC address.
+func TestHTTPAddr(t *testing.T) {
+	s := &TestServer{Ctx: NewTestContext()}
+	s.Ctx.HTTPAddr = "127.0.0.1:0"
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if s.ServingHTTPAddr() == s.ServingAddr() {
+		t.Fatalf("expected HTTP to be served apart from RPC at %s", s.ServingAddr())
+	}
+	for addr, expStatus := range map[string]int{
+		s.ServingHTTPAddr(): http.StatusOK,
+		s.ServingAddr():     http.StatusNotFound,
+	} {
+		resp, err := client.CreateTestHTTPClient().Get("https://" + addr + healthPath)
+		if err != nil {
+			t.Fatalf("error requesting health at %s: %s", addr, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expStatus {
+			t.Errorf("%s: expected status %d; got %d", addr, expStatus, resp.StatusCode)
+		}
+	}
+}
+
+// TestUnixSocket verifies that a server serves RPC and HTTP traffic
+// on a unix socket.
+func TestUnixSocket(t *testing.T) {
+	s := &TestServer{Ctx: NewTestContext()}
+	s.Ctx.Addr = util.UnixAddrPrefix + util.CreateTestAddr("unix").String()
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if network := s.HTTPAddr().Network(); network != "unix" {
+		t.Fatalf("expected server to listen on a unix socket; got %s", network)
+	}
+	url := "https://" + util.HTTPHost(s.Ctx.Addr) + healthPath
+	resp, err := client.CreateTestHTTPClient().Get(url)
+	if err != nil {
+		t.Fatalf("error requesting health at %s: %s", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200; got %d", resp.StatusCode)
+	}
+	db := client.NewKV(nil, client.CreateTestHTTPSender(s.Ctx.Addr))
+	db.User = storage.UserRoot
+	if err := db.Run(client.PutCall(proto.Key("a"), []byte("1"))); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestAcceptEncoding hits the health endpoint while explicitly
 // disabling decompression on a custom client's Transport and setting
 // it conditionally via the request's Accept-Encoding headers.
@@ -406,3 +492,51 @@ func TestMultiRangeScanWithMaxResults(t *testing.T) {
 		defer s.Stop()
 	}
 }
+
+// TestReadOnly verifies that writes are rejected and reads served
+// while the node is in read-only mode.
+func TestReadOnly(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	key := proto.Key("a")
+	if err := s.kv.Run(client.PutCall(key, []byte("1"))); err != nil {
+		t.Fatal(err)
+	}
+
+	setReadOnly := func(readOnly bool) {
+		url := "https://" + s.ServingAddr() + readOnlyPath
+		body := strings.NewReader(fmt.Sprint(readOnly))
+		resp, err := client.CreateTestHTTPClient().Post(url, "text/plain", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200; got %d: %s", resp.StatusCode, b)
+		}
+		if expected := fmt.Sprintln(readOnly); string(b) != expected {
+			t.Errorf("expected %q; got %q", expected, b)
+		}
+	}
+
+	setReadOnly(true)
+	if err := s.kv.Run(client.PutCall(key, []byte("2"))); err == nil {
+		t.Error("expected write to read-only node to fail")
+	}
+	get := client.GetCall(key)
+	if err := s.kv.Run(get); err != nil {
+		t.Fatal(err)
+	}
+	if gr := get.Reply.(*proto.GetResponse); gr.Value == nil || string(gr.Value.Bytes) != "1" {
+		t.Errorf("expected value 1; got %+v", gr.Value)
+	}
+
+	setReadOnly(false)
+	if err := s.kv.Run(client.PutCall(key, []byte("2"))); err != nil {
+		t.Fatal(err)
+	}
+}
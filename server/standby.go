@@ -0,0 +1,228 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// StandbyStatus describes the read-only mode of a node of a standby
+// cluster.
+type StandbyStatus struct {
+	// Promoted is set once the cluster has taken over from its primary,
+	// after which the node serves writes.
+	Promoted bool `json:"promoted"`
+	// ReplicatedTimestamp is the timestamp of the primary cluster up to
+	// which the writes to every replicated range had been shipped when
+	// the node last observed the progress of replication, or, once
+	// promoted, as of the cutover. Until the node is promoted, reads are
+	// served at this timestamp.
+	ReplicatedTimestamp proto.Timestamp `json:"replicated_timestamp"`
+}
+
+// A standbyGate is the sender of the key-value endpoints of a node of
+// a standby cluster to which a primary ships its writes; see
+// logShipper. Until the cluster is promoted, it rejects writes and
+// transactions which aren't made by the replication user and serves
+// reads at the earliest timestamp up to which the writes to every
+// replicated range have been shipped, at which the standby holds a
+// consistent state of the primary: shipped writes keep the timestamps
+// at which the primary committed them, and those in flight lie beyond
+// it. Once promoted, it rejects the writes of the replication user.
+type standbyGate struct {
+	wrapped  client.KVSender
+	db       *client.KV // Internal client, not subject to the gate
+	clock    *hlc.Clock
+	interval time.Duration
+	stopper  *util.Stopper
+
+	mu     sync.Mutex
+	status StandbyStatus
+}
+
+// newStandbyGate returns a standbyGate which sends admitted requests
+// to wrapped and observes the progress of replication through db
+// every interval.
+func newStandbyGate(wrapped client.KVSender, db *client.KV, clock *hlc.Clock, interval time.Duration,
+	stopper *util.Stopper) *standbyGate {
+	return &standbyGate{
+		wrapped:  wrapped,
+		db:       db,
+		clock:    clock,
+		interval: interval,
+		stopper:  stopper,
+	}
+}
+
+// start observes the progress of replication every interval until the
+// cluster is promoted or the node is stopped.
+func (g *standbyGate) start() {
+	g.stopper.RunWorker(func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			if err := g.observe(); err != nil {
+				log.Warningf("unable to observe the progress of replication: %s", err)
+			}
+			if g.getStatus().Promoted {
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-g.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// getStatus returns a copy of the gate's status.
+func (g *standbyGate) getStatus() StandbyStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status
+}
+
+// observe reads the cutover record and the progress of replication,
+// whose earliest timestamp across the replicated ranges becomes the
+// read timestamp. The node is promoted if the cluster has cut over.
+func (g *standbyGate) observe() error {
+	now := g.clock.Now()
+	get := client.GetCall(engine.KeyReplicationCutover)
+	get.Args.Header().Timestamp = now
+	if err := g.db.Run(get); err != nil {
+		return err
+	}
+	if v := get.Reply.(*proto.GetResponse).Value; v != nil {
+		var cutover proto.Timestamp
+		if err := gogoproto.Unmarshal(v.Bytes, &cutover); err != nil {
+			return util.Errorf("invalid cutover record: %s", err)
+		}
+		g.promote(cutover)
+		return nil
+	}
+	scan := client.ScanCall(engine.KeyReplicationProgressPrefix, engine.KeyReplicationProgressPrefix.PrefixEnd(), 0)
+	scan.Args.Header().Timestamp = now
+	if err := g.db.Run(scan); err != nil {
+		return err
+	}
+	replicated, err := minReplicationProgress(scan.Reply.(*proto.ScanResponse).Rows)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.status.Promoted {
+		g.status.ReplicatedTimestamp = replicated
+	}
+	return nil
+}
+
+// Promote cuts the cluster over from its primary, see Cutover, and
+// makes the node serve writes. The other nodes of the cluster serve
+// writes once they observe the cutover.
+func (g *standbyGate) Promote() error {
+	cutover, err := Cutover(g.db)
+	if err != nil {
+		return err
+	}
+	g.promote(cutover)
+	return nil
+}
+
+// promote makes the node serve writes following the cutover at the
+// timestamp.
+func (g *standbyGate) promote(cutover proto.Timestamp) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.status.Promoted {
+		log.Infof("standby cluster cut over with writes shipped up to %s; serving writes", cutover)
+	}
+	g.status.Promoted = true
+	g.status.ReplicatedTimestamp = cutover
+}
+
+// Send implements client.KVSender, rejecting the calls which aren't
+// admitted until the cluster is promoted; see admit.
+func (g *standbyGate) Send(call client.Call) {
+	if err := g.admit(call.Args); err != nil {
+		call.Reply.Header().SetGoError(err)
+		return
+	}
+	g.wrapped.Send(call)
+}
+
+// admit returns an error unless the request may be served by the
+// node. Until the cluster is promoted, those are the requests made by
+// the replication user, and non-transactional reads, individually or
+// in batches, at or before the replicated timestamp; reads without a
+// timestamp are assigned it. Once promoted, the writes of the
+// replication user are rejected.
+func (g *standbyGate) admit(args proto.Request) error {
+	status := g.getStatus()
+	reqs := []proto.Request{args}
+	if batch, ok := args.(*proto.BatchRequest); ok {
+		reqs = reqs[:0]
+		for _, union := range batch.Requests {
+			if req, ok := union.GetValue().(proto.Request); ok {
+				reqs = append(reqs, req)
+			}
+		}
+	}
+	if args.Header().User == storage.UserReplication {
+		if !status.Promoted {
+			return nil
+		}
+		for _, req := range reqs {
+			if !proto.IsReadOnly(req) {
+				return util.Errorf("standby cluster has been promoted; %s by the primary is rejected", req.Method())
+			}
+		}
+		return nil
+	}
+	if status.Promoted {
+		return nil
+	}
+	if args.Header().Txn != nil {
+		return util.Errorf("node is a read-only standby; transactions are served once its cluster is promoted")
+	}
+	replicated := status.ReplicatedTimestamp
+	for _, req := range reqs {
+		if !proto.IsReadOnly(req) || req.Header().Txn != nil {
+			return util.Errorf("node is a read-only standby; %s is served once its cluster is promoted", req.Method())
+		}
+		if replicated.Equal(proto.ZeroTimestamp) {
+			return util.Errorf("node is a read-only standby which hasn't yet observed the progress of replication")
+		}
+		if ts := req.Header().Timestamp; ts.Equal(proto.ZeroTimestamp) {
+			req.Header().Timestamp = replicated
+		} else if replicated.Less(ts) {
+			return util.Errorf("node is a read-only standby; reads at %s are served once writes are shipped beyond it, up to %s so far",
+				ts, replicated)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestStandby verifies that a standby node rejects writes and
+// transactions while serving the shipped writes to reads at the
+// replicated timestamp, and that it serves writes, other than those
+// shipped by the primary, once its cluster is promoted.
+func TestStandby(t *testing.T) {
+	standby := &TestServer{Ctx: NewTestContext()}
+	standby.Ctx.Standby = true
+	standby.Ctx.ReplicateInterval = 10 * time.Millisecond
+	if err := standby.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer standby.Stop()
+	primary := &TestServer{Ctx: NewTestContext()}
+	primary.Ctx.ReplicateTo = standby.ServingAddr()
+	primary.Ctx.ReplicatePrefixes = "t1/"
+	primary.Ctx.ReplicateInterval = 10 * time.Millisecond
+	primary.Ctx.ClosedTimestampLag = 50 * time.Millisecond
+	if err := primary.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Stop()
+
+	db := client.NewKV(nil, standby.standby)
+	db.User = storage.UserRoot
+	if err := db.Run(client.PutCall(proto.Key("t1/b"), []byte("b"))); err == nil {
+		t.Error("expected standby to reject put")
+	}
+	opts := &client.TransactionOptions{Name: "test"}
+	if err := db.RunTransaction(opts, func(txn *client.Txn) error {
+		return txn.Run(client.GetCall(proto.Key("t1/a")))
+	}); err == nil {
+		t.Error("expected standby to reject transaction")
+	}
+
+	if err := primary.kv.Run(client.PutCall(proto.Key("t1/a"), []byte("a"))); err != nil {
+		t.Fatal(err)
+	}
+	util.SucceedsWithin(t, 5*time.Second, func() error {
+		call := client.GetCall(proto.Key("t1/a"))
+		if err := db.Run(call); err != nil {
+			return err
+		}
+		if v := call.Reply.(*proto.GetResponse).Value; v == nil || string(v.Bytes) != "a" {
+			return util.Errorf("expected t1/a to be readable; got %+v", v)
+		}
+		return nil
+	})
+	status := standby.standby.getStatus()
+	if status.Promoted || status.ReplicatedTimestamp.Equal(proto.ZeroTimestamp) {
+		t.Errorf("expected unpromoted standby with replication progress; got %+v", status)
+	}
+	call := client.GetCall(proto.Key("t1/a"))
+	call.Args.Header().Timestamp = status.ReplicatedTimestamp.Add(time.Hour.Nanoseconds(), 0)
+	if err := db.Run(call); err == nil {
+		t.Error("expected standby to reject read beyond the replicated timestamp")
+	}
+
+	if err := standby.standby.Promote(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Run(client.PutCall(proto.Key("t1/b"), []byte("b"))); err != nil {
+		t.Errorf("expected promoted standby to serve put; got %s", err)
+	}
+	put := client.PutCall(proto.Key("t1/b"), []byte("c"))
+	put.Args.Header().User = storage.UserReplication
+	if err := db.Run(put); err == nil {
+		t.Error("expected promoted standby to reject put by the primary")
+	}
+}
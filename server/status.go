@@ -18,12 +18,21 @@
 package server
 
 import (
+	"encoding/json"
+	"expvar"
+	"fmt"
 	"net/http"
+	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/server/status"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 )
@@ -37,6 +46,15 @@ const (
 
 	// statusGossipKeyPrefix exposes a view of the gossip network.
 	statusGossipKeyPrefix = statusKeyPrefix + "gossip"
+	// statusGossipGraphKey exposes the connections of the gossip
+	// network's nodes and the hops and age of each info, as JSON or,
+	// if the format parameter is "dot", as a Graphviz graph.
+	statusGossipGraphKey   = statusGossipKeyPrefix + "/graph"
+	gossipGraphParamFormat = "format"
+
+	// statusDetailsKey exposes the build, enabled features and
+	// configuration of the node serving the request.
+	statusDetailsKey = statusKeyPrefix + "details"
 
 	// statusLocalKeyPrefix is the key prefix for all local status
 	// info. Unadorned, the URL exposes the status of the node serving
@@ -48,6 +66,26 @@ const (
 	// statusLocalStacksKey exposes stack traces of running goroutines.
 	statusLocalStacksKey = statusLocalKeyPrefix + "stacks"
 
+	// statusLocalRangesKey exposes the ranges of the node's stores and
+	// their leader leases.
+	statusLocalRangesKey = statusLocalKeyPrefix + "ranges"
+
+	// statusLocalContentionKey exposes a sample of recent pushes of
+	// conflicting transactions by the node's stores.
+	statusLocalContentionKey = statusLocalKeyPrefix + "contention"
+
+	// statusLocalCompactionsKey exposes the sstable levels and
+	// compaction debt of the node's stores.
+	statusLocalCompactionsKey = statusLocalKeyPrefix + "compactions"
+
+	// statusLocalTracesKey exposes the traces of a sample of recent
+	// commands executed by the node's stores.
+	statusLocalTracesKey = statusLocalKeyPrefix + "traces"
+	// tracesParamMinDuration is the query parameter which, if set,
+	// limits the traces served to those of commands which took at
+	// least the duration.
+	tracesParamMinDuration = "min_duration"
+
 	// statusNodesKeyPrefix exposes status for each of the nodes the cluster.
 	// GETing statusNodesKeyPrefix will list all nodes.
 	// Individual node status can be queried at statusNodesKeyPrefix/NodeID.
@@ -58,19 +96,62 @@ const (
 
 	// statusTransactionsKeyPrefix exposes transaction statistics.
 	statusTransactionsKeyPrefix = statusKeyPrefix + "txns/"
+
+	// statusVarsKey exposes the node's metrics, along with the
+	// variables published via expvar, in the format served by expvar.
+	statusVarsKey = statusKeyPrefix + "vars"
+
+	// statusBalanceKey exposes the balance of the range counts and
+	// bytes used of the cluster's stores against the allocator's
+	// thresholds.
+	statusBalanceKey = statusKeyPrefix + "balance"
+
+	// statusReplicationKey exposes the replica and lease counts and
+	// bytes of the cluster's stores and the ranges which aren't fully
+	// replicated on live stores.
+	statusReplicationKey = statusKeyPrefix + "replication"
+
+	// statusTopologyKey exposes the nodes of the cluster, with their
+	// addresses and attributes, to clients which route requests
+	// themselves.
+	statusTopologyKey = statusKeyPrefix + "topology"
+	// topologyParamVersion is the query parameter which, if set to the
+	// version of the topology held by the client, delays the response
+	// until the topology changes or the wait elapses.
+	topologyParamVersion = "version"
+	// topologyParamWait is the query parameter which sets the longest
+	// wait for a topology change, defaulting to and capped at
+	// maxTopologyWait.
+	topologyParamWait = "wait"
+	maxTopologyWait   = time.Minute
 )
 
+// features reports which optional features are compiled into this
+// binary, by name. Encryption is reported by enabledFeatures, as
+// enabled if any of the node's stores encrypts its values.
+var features = map[string]bool{
+	"encryption": false,
+	"grpc":       false,
+	"sql":        false,
+}
+
 // A statusServer provides a RESTful status API.
 type statusServer struct {
-	db     *client.KV
-	gossip *gossip.Gossip
+	db       *client.KV
+	gossip   *gossip.Gossip
+	ctx      *Context
+	node     *Node
+	topology *topologyWatcher
 }
 
 // newStatusServer allocates and returns a statusServer.
-func newStatusServer(db *client.KV, gossip *gossip.Gossip) *statusServer {
+func newStatusServer(db *client.KV, gossip *gossip.Gossip, ctx *Context, node *Node) *statusServer {
 	return &statusServer{
-		db:     db,
-		gossip: gossip,
+		db:       db,
+		gossip:   gossip,
+		ctx:      ctx,
+		node:     node,
+		topology: newTopologyWatcher(gossip),
 	}
 }
 
@@ -78,12 +159,22 @@ func newStatusServer(db *client.KV, gossip *gossip.Gossip) *statusServer {
 // serve mux.
 func (s *statusServer) registerHandlers(mux *http.ServeMux) {
 	mux.HandleFunc(statusKeyPrefix, s.handleStatus)
+	mux.HandleFunc(statusBalanceKey, s.handleBalance)
+	mux.HandleFunc(statusDetailsKey, s.handleDetails)
 	mux.HandleFunc(statusGossipKeyPrefix, s.handleGossipStatus)
+	mux.HandleFunc(statusGossipGraphKey, s.handleGossipGraph)
 	mux.HandleFunc(statusLocalKeyPrefix, s.handleLocalStatus)
+	mux.HandleFunc(statusLocalCompactionsKey, s.handleLocalCompactions)
+	mux.HandleFunc(statusLocalContentionKey, s.handleLocalContention)
+	mux.HandleFunc(statusLocalRangesKey, s.handleLocalRanges)
 	mux.HandleFunc(statusLocalStacksKey, s.handleLocalStacks)
+	mux.HandleFunc(statusLocalTracesKey, s.handleLocalTraces)
 	mux.HandleFunc(statusNodesKeyPrefix, s.handleNodeStatus)
+	mux.HandleFunc(statusReplicationKey, s.handleReplication)
 	mux.HandleFunc(statusStoresKeyPrefix, s.handleStoresStatus)
+	mux.HandleFunc(statusTopologyKey, s.handleTopology)
 	mux.HandleFunc(statusTransactionsKeyPrefix, s.handleTransactionStatus)
+	mux.HandleFunc(statusVarsKey, s.handleVars)
 }
 
 // handleStatus handles GET requests for cluster status.
@@ -110,6 +201,32 @@ func (s *statusServer) handleGossipStatus(w http.ResponseWriter, r *http.Request
 	w.Write(b)
 }
 
+// handleGossipGraph handles GET requests for the graph of the gossip
+// network as seen by the node, with which partial partitions may be
+// found: nodes which are missing, whose infos are old or many hops
+// away, or whose connections aren't returned by their peers.
+func (s *statusServer) handleGossipGraph(w http.ResponseWriter, r *http.Request) {
+	graph := s.gossip.Graph()
+	switch format := r.URL.Query().Get(gossipGraphParamFormat); format {
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.Write([]byte(graph.DOT()))
+		return
+	case "", "json":
+	default:
+		http.Error(w, "unknown format "+strconv.Quote(format), http.StatusBadRequest)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, graph, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
 // handleLocalStatus handles GET requests for local-node status.
 func (s *statusServer) handleLocalStatus(w http.ResponseWriter, r *http.Request) {
 	local := struct {
@@ -127,6 +244,401 @@ func (s *statusServer) handleLocalStatus(w http.ResponseWriter, r *http.Request)
 	w.Write(b)
 }
 
+// handleDetails handles GET requests for the build info, features
+// and context of the node serving the request.
+func (s *statusServer) handleDetails(w http.ResponseWriter, r *http.Request) {
+	details := struct {
+		BuildInfo util.BuildInfo         `json:"buildInfo"`
+		Features  map[string]bool        `json:"features"`
+		Context   map[string]interface{} `json:"context"`
+	}{
+		BuildInfo: util.GetBuildInfo(),
+		Features:  s.enabledFeatures(),
+		Context:   redactContext(s.ctx),
+	}
+	b, contentType, err := util.MarshalResponse(r, details, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// enabledFeatures returns the features of the node by name: those
+// compiled into the binary, and encryption if any of its stores are
+// encrypted engines.
+func (s *statusServer) enabledFeatures() map[string]bool {
+	enabled := make(map[string]bool, len(features))
+	for name, ok := range features {
+		enabled[name] = ok
+	}
+	for _, e := range s.ctx.Engines {
+		if _, ok := e.(*engine.Encrypted); ok {
+			enabled["encryption"] = true
+		}
+	}
+	return enabled
+}
+
+// redactContext returns the exported fields of ctx by name, omitting
+// those tagged `status:"-"` and replacing the values of those tagged
+// `redact:"true"`. Durations are formatted for readability.
+func redactContext(ctx *Context) map[string]interface{} {
+	return redactFields(reflect.ValueOf(ctx).Elem())
+}
+
+// redactFields returns the exported fields of the struct v by name, as
+// described by redactContext.
+func redactFields(v reflect.Value) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" || f.Tag.Get("status") == "-" {
+			continue
+		}
+		val := v.Field(i).Interface()
+		if f.Tag.Get("redact") == "true" {
+			val = "<redacted>"
+		} else if d, ok := val.(time.Duration); ok {
+			val = d.String()
+		}
+		fields[f.Name] = val
+	}
+	return fields
+}
+
+// An engineConfig describes one of the engines of a node.
+type engineConfig struct {
+	Type  string     `json:"type,omitempty"`
+	Attrs []string   `json:"attrs"`
+	Spec  *StoreSpec `json:"spec,omitempty"`
+}
+
+// A nodeConfig is a node's effective configuration, as served by the
+// /_admin/config endpoint.
+type nodeConfig struct {
+	Context   map[string]interface{} `json:"context"`
+	Settings  map[string]interface{} `json:"settings"`
+	Engines   []engineConfig         `json:"engines"`
+	Resolvers []string               `json:"resolvers"`
+}
+
+// effectiveConfig returns the configuration of a node started with ctx
+// whose reloadable settings are now settings. The specifications of
+// the engines are included if they were parsed from ctx.Stores, rather
+// than the engines being supplied directly. The engines of a context
+// initialized by a dry run are described by their specifications alone.
+func effectiveConfig(ctx *Context, settings ReloadableSettings) nodeConfig {
+	cfg := nodeConfig{
+		Context:   redactContext(ctx),
+		Settings:  redactFields(reflect.ValueOf(settings)),
+		Engines:   []engineConfig{},
+		Resolvers: []string{},
+	}
+	for i, e := range ctx.Engines {
+		ec := engineConfig{Type: fmt.Sprintf("%T", e), Attrs: e.Attrs().Attrs}
+		if len(ctx.storeSpecs) == len(ctx.Engines) {
+			ec.Spec = &ctx.storeSpecs[i]
+		}
+		cfg.Engines = append(cfg.Engines, ec)
+	}
+	if len(ctx.Engines) == 0 {
+		for i := range ctx.storeSpecs {
+			spec := &ctx.storeSpecs[i]
+			cfg.Engines = append(cfg.Engines, engineConfig{Attrs: spec.Attrs.Attrs, Spec: spec})
+		}
+	}
+	for _, r := range ctx.GossipBootstrapResolvers {
+		cfg.Resolvers = append(cfg.Resolvers, r.Type()+"="+r.Addr())
+	}
+	return cfg
+}
+
+// handleVars handles GET requests for the node's metrics. The response
+// is a JSON object mapping the name of each metric to its value, as
+// served by expvar at /debug/vars, and includes the variables
+// published via expvar, so that tools which consume expvar can monitor
+// nodes without changes. Metric names are stable; those of store
+// metrics are prefixed by "store.<store ID>." and those of gossip by
+// "gossip.".
+func (s *statusServer) handleVars(w http.ResponseWriter, r *http.Request) {
+	vars := map[string]interface{}{}
+	expvar.Do(func(kv expvar.KeyValue) {
+		vars[kv.Key] = json.RawMessage(kv.Value.String())
+	})
+	if s.node != nil {
+		for name, value := range nodeVars(s.node) {
+			vars[name] = value
+		}
+	}
+	if s.gossip != nil {
+		for name, value := range gossipVars(s.gossip) {
+			vars[name] = value
+		}
+	}
+	b, err := json.Marshal(vars)
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// nodeVars returns the metrics of the node and its stores by name.
+// Lease metrics are reported for each store and summed for the node.
+// The version of each store's cached system config maps is reported so
+// that the propagation of config changes may be followed, and the
+// reads of each store's engine so that its read amplification may be.
+func nodeVars(n *Node) map[string]interface{} {
+	vars := map[string]interface{}{
+		"node.id":          n.Descriptor.NodeID,
+		"node.read_only":   n.ReadOnly(),
+		"node.store_count": n.lSender.GetStoreCount(),
+	}
+	leaseVars := map[string]int64{}
+	n.lSender.VisitStores(func(store *storage.Store) error {
+		m := store.Metrics()
+		prefix := fmt.Sprintf("store.%d.", store.StoreID())
+		for name, value := range map[string]interface{}{
+			"range_count":               m.RangeCount,
+			"read_only":                 m.ReadOnly,
+			"nearly_full":               m.NearlyFull,
+			"scan_count":                m.ScanCount,
+			"scan_over_budget":          m.ScanOverBudget,
+			"scan_skipped":              m.ScanSkipped,
+			"abandoned_txns":            m.AbandonedTxns,
+			"abandoned_intents":         m.AbandonedIntents,
+			"throttled_cmds":            m.ThrottledCmds,
+			"throttled_cmds_per_minute": m.ThrottledCmdsPerMinute,
+			"replicas_gced":             m.ReplicasGCed,
+			"local_reads":               m.LocalReads,
+			"consensus_reads":           m.ConsensusReads,
+			"unexpected_raft_reads":     m.UnexpectedRaftReads,
+			"mvcc.live_bytes":           m.MVCC.LiveBytes,
+			"mvcc.key_bytes":            m.MVCC.KeyBytes,
+			"mvcc.val_bytes":            m.MVCC.ValBytes,
+			"mvcc.intent_bytes":         m.MVCC.IntentBytes,
+			"mvcc.live_count":           m.MVCC.LiveCount,
+			"mvcc.key_count":            m.MVCC.KeyCount,
+			"mvcc.val_count":            m.MVCC.ValCount,
+			"mvcc.intent_count":         m.MVCC.IntentCount,
+			"engine.disk_bytes_read":    m.Engine.DiskBytesRead,
+			"engine.bytes_returned":     m.Engine.BytesReturned,
+			"engine.read_amplification": m.Engine.ReadAmplification(),
+			"engine.seeks":              m.Engine.Seeks,
+			"engine.steps":              m.Engine.Steps,
+			"scrubs":                    m.Scrubs,
+			"corruptions":               m.Corruptions,
+		} {
+			vars[prefix+name] = value
+		}
+		for name, value := range map[string]int64{
+			"leases_held":                   int64(m.LeasesHeld),
+			"lease_acquisitions":            m.LeaseAcquisitions,
+			"lease_acquisitions_per_minute": m.LeaseAcquisitionsPerMinute,
+			"lease_transfers":               m.LeaseTransfers,
+			"lease_transfers_per_minute":    m.LeaseTransfersPerMinute,
+			"lease_errors":                  m.LeaseErrors,
+			"lease_errors_per_minute":       m.LeaseErrorsPerMinute,
+		} {
+			vars[prefix+name] = value
+			leaseVars[name] += value
+		}
+		for key, v := range m.ConfigVersions {
+			vars[prefix+"config."+key+".version"] = v.Version
+			vars[prefix+"config."+key+".updated_at"] = v.UpdatedAt
+		}
+		return nil
+	})
+	for name, value := range leaseVars {
+		vars["node."+name] = value
+	}
+	return vars
+}
+
+// gossipVars returns the metrics of the node's gossip instance by
+// name.
+func gossipVars(g *gossip.Gossip) map[string]interface{} {
+	m := g.Metrics()
+	return map[string]interface{}{
+		"gossip.incoming":           m.Incoming,
+		"gossip.max_incoming":       m.MaxIncoming,
+		"gossip.refused_incoming":   m.RefusedIncoming,
+		"gossip.outgoing":           m.Outgoing,
+		"gossip.max_outgoing":       m.MaxOutgoing,
+		"gossip.max_hops":           m.MaxHops,
+		"gossip.max_tolerated_hops": m.MaxToleratedHops,
+		"gossip.distant_nodes":      m.DistantNodes,
+		"gossip.tighten_connects":   m.TightenConnects,
+		"gossip.tighten_closes":     m.TightenCloses,
+	}
+}
+
+// handleLocalContention handles GET requests for a sample of the most
+// recent contention events of the node's stores, oldest first. Each
+// event is the push of a transaction whose intent conflicted with a
+// command, identifying the key, the transactions, their priorities and
+// whether the push succeeded, so that the keys causing transaction
+// retries can be found.
+func (s *statusServer) handleLocalContention(w http.ResponseWriter, r *http.Request) {
+	contention := struct {
+		Events []storage.ContentionEvent `json:"events"`
+	}{}
+	if s.node != nil {
+		s.node.lSender.VisitStores(func(store *storage.Store) error {
+			contention.Events = append(contention.Events, store.ContentionEvents()...)
+			return nil
+		})
+	}
+	sort.Sort(contentionEventsByTime(contention.Events))
+	b, contentType, err := util.MarshalResponse(r, contention, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// handleBalance handles GET requests for the balance of the stores
+// known to the node through gossip: the deviation of the range count
+// and bytes used of each store from the means, and whether it exceeds
+// the allocator's thresholds. All of the node's stores see the same
+// gossip, so the report is that of its first store.
+func (s *statusServer) handleBalance(w http.ResponseWriter, r *http.Request) {
+	var report *storage.BalanceReport
+	if s.node != nil {
+		if err := s.node.lSender.VisitStores(func(store *storage.Store) error {
+			if report != nil {
+				return nil
+			}
+			rep, err := store.BalanceReport()
+			report = &rep
+			return err
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if report == nil {
+		http.Error(w, "node has no stores", http.StatusServiceUnavailable)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, report, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// handleReplication handles GET requests for the replication report
+// of the cluster: the replica count, lease count and bytes used of each
+// store and the ranges with problems, such as too few replicas or
+// replicas on stores missing from gossip. Replica counts are read from
+// the ranges' addressing records, and lease counts and bytes from the
+// stores' gossiped descriptors, by the node's first store.
+func (s *statusServer) handleReplication(w http.ResponseWriter, r *http.Request) {
+	var report *storage.ReplicationReport
+	if s.node != nil {
+		if err := s.node.lSender.VisitStores(func(store *storage.Store) error {
+			if report != nil {
+				return nil
+			}
+			rep, err := store.ReplicationReport()
+			report = &rep
+			return err
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if report == nil {
+		http.Error(w, "node has no stores", http.StatusServiceUnavailable)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, report, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// handleLocalTraces handles GET requests for the traces of a sample of
+// the most recent commands executed by the node's stores, in the order
+// in which the commands finished. Each trace holds the steps of its
+// command and their timings, such as pushes of conflicting
+// transactions and retries, so that the causes of rare slow commands
+// can be found. The sample rate is a reloadable setting.
+func (s *statusServer) handleLocalTraces(w http.ResponseWriter, r *http.Request) {
+	var minDuration time.Duration
+	if param := r.URL.Query().Get(tracesParamMinDuration); len(param) > 0 {
+		var err error
+		if minDuration, err = time.ParseDuration(param); err != nil {
+			http.Error(w, "error parsing "+tracesParamMinDuration+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	traces := struct {
+		Traces []storage.Trace `json:"traces"`
+	}{}
+	if s.node != nil {
+		for _, t := range s.node.ctx.Traces.Traces() {
+			if t.Duration >= minDuration {
+				traces.Traces = append(traces.Traces, t)
+			}
+		}
+	}
+	b, contentType, err := util.MarshalResponse(r, traces, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// contentionEventsByTime sorts contention events by time.
+type contentionEventsByTime []storage.ContentionEvent
+
+func (c contentionEventsByTime) Len() int           { return len(c) }
+func (c contentionEventsByTime) Less(i, j int) bool { return c[i].Time < c[j].Time }
+func (c contentionEventsByTime) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// handleLocalRanges handles GET requests for the ranges of the node's
+// stores, including the holder and expiration of each range's leader
+// lease and the range's recent splits and merges.
+func (s *statusServer) handleLocalRanges(w http.ResponseWriter, r *http.Request) {
+	ranges := struct {
+		Ranges []storage.RangeStatus `json:"ranges"`
+	}{}
+	if s.node != nil {
+		s.node.lSender.VisitStores(func(store *storage.Store) error {
+			ranges.Ranges = append(ranges.Ranges, store.RangeStatuses()...)
+			return nil
+		})
+	}
+	b, contentType, err := util.MarshalResponse(r, ranges, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
 // handleLocalStacks handles GET requests for goroutines stack traces.
 func (s *statusServer) handleLocalStacks(w http.ResponseWriter, r *http.Request) {
 	bufSize := runtime.NumGoroutine() * stackTraceApproxSize
@@ -165,6 +677,55 @@ func (s *statusServer) handleStoresStatus(w http.ResponseWriter, r *http.Request
 	w.Write([]byte(`{"stores": []}`))
 }
 
+// handleTopology handles GET requests for the nodes of the cluster.
+// If the version parameter is the current version of the topology, the
+// response is delayed until the topology changes, the wait parameter
+// elapses or the client goes away, so that clients may long-poll for
+// changes. The response always holds the current topology.
+func (s *statusServer) handleTopology(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	wait := maxTopologyWait
+	if param := query.Get(topologyParamWait); len(param) > 0 {
+		var err error
+		if wait, err = time.ParseDuration(param); err != nil {
+			http.Error(w, "error parsing "+topologyParamWait+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if wait > maxTopologyWait {
+			wait = maxTopologyWait
+		}
+	}
+	topology, changed := s.topology.topology()
+	if param := query.Get(topologyParamVersion); len(param) > 0 {
+		version, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			http.Error(w, "error parsing "+topologyParamVersion+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if version == topology.Version {
+			var closed <-chan bool
+			if cn, ok := w.(http.CloseNotifier); ok {
+				closed = cn.CloseNotify()
+			}
+			select {
+			case <-changed:
+			case <-time.After(wait):
+			case <-closed:
+				return
+			}
+			topology, _ = s.topology.topology()
+		}
+	}
+	b, contentType, err := util.MarshalResponse(r, topology, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
 // handleTransactionStatus handles GET requests for transaction status.
 func (s *statusServer) handleTransactionStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -18,16 +18,21 @@
 package server
 
 import (
+	"crypto/aes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
@@ -41,11 +46,11 @@ import (
 // Cockroach KV client address is set to the address of the test server.
 func startStatusServer() (*httptest.Server, *util.Stopper) {
 	stopper := util.NewStopper()
-	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), stopper)
+	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), nil, stopper)
 	if err != nil {
 		log.Fatal(err)
 	}
-	status := newStatusServer(db, nil)
+	status := newStatusServer(db, nil, NewContext(), nil)
 	mux := http.NewServeMux()
 	status.registerHandlers(mux)
 	httpServer := httptest.NewTLSServer(mux)
@@ -68,6 +73,150 @@ func TestStatusLocalStacks(t *testing.T) {
 	}
 }
 
+// TestStatusDetails verifies that the build info, features and context
+// of the node are available via the /_status/details endpoint.
+func TestStatusDetails(t *testing.T) {
+	s, stopper := startStatusServer()
+	defer stopper.Stop()
+	body, err := getText(s.URL + statusDetailsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var details struct {
+		BuildInfo util.BuildInfo
+		Features  map[string]bool
+		Context   map[string]interface{}
+	}
+	if err := json.Unmarshal(body, &details); err != nil {
+		t.Fatal(err)
+	}
+	if details.BuildInfo.Vers != runtime.Version() {
+		t.Errorf("expected go version %s; got %s", runtime.Version(), details.BuildInfo.Vers)
+	}
+	if _, ok := details.Features["sql"]; !ok {
+		t.Errorf("expected sql feature to be reported; got %v", details.Features)
+	}
+	if enabled, ok := details.Features["encryption"]; !ok || enabled {
+		t.Errorf("expected encryption to be reported disabled; got %v", details.Features)
+	}
+	if addr := details.Context["Addr"]; addr != defaultAddr {
+		t.Errorf("expected addr %s; got %v", defaultAddr, addr)
+	}
+	if maxOffset := details.Context["MaxOffset"]; maxOffset != defaultMaxOffset.String() {
+		t.Errorf("expected max offset %s; got %v", defaultMaxOffset, maxOffset)
+	}
+	if _, ok := details.Context["Engines"]; ok {
+		t.Error("expected engines to be omitted")
+	}
+}
+
+// TestStatusEncryptionFeature verifies that encryption is reported as
+// enabled for nodes with an encrypted store.
+func TestStatusEncryptionFeature(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	// The encrypted engine needn't be opened.
+	r := engine.NewRocksDB(proto.Attributes{}, "/tmp/encrypted", 1<<20)
+	enc, err := engine.NewEncrypted(r, engine.EncryptionKeys{"key1": block}, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext()
+	ctx.Engines = []engine.Engine{e}
+	s := &statusServer{ctx: ctx}
+	if s.enabledFeatures()["encryption"] {
+		t.Error("expected encryption to be disabled without encrypted stores")
+	}
+	ctx.Engines = append(ctx.Engines, enc)
+	if !s.enabledFeatures()["encryption"] {
+		t.Error("expected encryption to be enabled with an encrypted store")
+	}
+	if features["encryption"] {
+		t.Error("expected the compiled-in features not to be modified")
+	}
+}
+
+// TestStatusVars verifies that the metrics of the node, its stores
+// and its gossip instance are served along with the variables published via expvar.
+func TestStatusVars(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	body, err := getText("https://" + s.ServingAddr() + statusVarsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal(body, &vars); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"cmdline", "memstats", "node.id", "node.read_only", "gossip.max_outgoing"}
+	s.node.lSender.VisitStores(func(store *storage.Store) error {
+		prefix := fmt.Sprintf("store.%d.", store.StoreID())
+		expected = append(expected, prefix+"range_count", prefix+"engine.read_amplification")
+		return nil
+	})
+	for _, name := range expected {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("expected %s to be served; got %s", name, body)
+		}
+	}
+	if id := vars["node.id"]; id != float64(s.node.Descriptor.NodeID) {
+		t.Errorf("expected node ID %d; got %v", s.node.Descriptor.NodeID, id)
+	}
+}
+
+// TestStatusLocalRanges verifies that the ranges of the node's stores
+// are served with their leader leases.
+func TestStatusLocalRanges(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	body, err := getText("https://" + s.ServingAddr() + statusLocalRangesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ranges struct {
+		Ranges []storage.RangeStatus
+	}
+	if err := json.Unmarshal(body, &ranges); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges.Ranges) == 0 {
+		t.Fatalf("expected ranges; got %s", body)
+	}
+	if rs := ranges.Ranges[0]; rs.RaftID != 1 || !rs.StartKey.Equal(engine.KeyMin) {
+		t.Errorf("expected first range; got %+v", rs)
+	}
+}
+
+// TestStatusReplication verifies that the replication report lists
+// the node's store with its replicas.
+func TestStatusReplication(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	// The store is live once its descriptor is gossiped.
+	util.SucceedsWithin(t, time.Second, func() error {
+		body, err := getText("https://" + s.ServingAddr() + statusReplicationKey)
+		if err != nil {
+			return err
+		}
+		var report storage.ReplicationReport
+		if err := json.Unmarshal(body, &report); err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Stores) != 1 {
+			return util.Errorf("expected 1 store; got %s", body)
+		}
+		if sr := report.Stores[0]; !sr.Live || sr.ReplicaCount == 0 {
+			return util.Errorf("expected live store with replicas; got %+v", sr)
+		}
+		return nil
+	})
+}
+
 // TestStatusJson verifies that status endpoints return expected
 // Json results. The content type of the responses is always
 // "application/json".
@@ -212,3 +361,117 @@ func TestStatusGossipJson(t *testing.T) {
 		}
 	}
 }
+
+// TestStatusGossipGraph verifies that the gossip graph is served as
+// JSON and as DOT.
+func TestStatusGossipGraph(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+
+	body, err := getText("https://" + s.ServingAddr() + statusGossipGraphKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var graph gossip.Graph
+	if err := json.Unmarshal(body, &graph); err != nil {
+		t.Fatal(err)
+	}
+	if graph.NodeID != 1 || len(graph.Nodes) == 0 || graph.Nodes[0].NodeID != 1 {
+		t.Errorf("expected graph of node 1; got %s", body)
+	}
+	body, err = getText("https://" + s.ServingAddr() + statusGossipGraphKey + "?format=dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), "digraph gossip {") {
+		t.Errorf("expected DOT graph; got %s", body)
+	}
+}
+
+// TestStatusTopology verifies that the nodes of the cluster are served
+// via the /_status/topology endpoint and that a request for the
+// current version waits for the next change, be it a node joining or
+// a node's descriptor expiring.
+func TestStatusTopology(t *testing.T) {
+	s := StartTestServer(t)
+	defer s.Stop()
+	url := "https://" + s.ServingAddr() + statusTopologyKey
+	getTopology := func(query string) Topology {
+		body, err := getText(url + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var topology Topology
+		if err := json.Unmarshal(body, &topology); err != nil {
+			t.Fatal(err)
+		}
+		return topology
+	}
+
+	var topology Topology
+	util.SucceedsWithin(t, time.Second, func() error {
+		if topology = getTopology(""); len(topology.Nodes) != 1 {
+			return util.Errorf("expected 1 node; got %+v", topology)
+		}
+		return nil
+	})
+	if n := topology.Nodes[0]; n.NodeID != s.node.Descriptor.NodeID || n.Address != s.ServingAddr() || n.HTTPAddress != n.Address {
+		t.Errorf("unexpected node %+v", n)
+	}
+
+	// A wait for a change which doesn't happen returns the same version.
+	query := fmt.Sprintf("?%s=%d&%s=10ms", topologyParamVersion, topology.Version, topologyParamWait)
+	if unchanged := getTopology(query); unchanged.Version != topology.Version {
+		t.Errorf("expected version %d; got %+v", topology.Version, unchanged)
+	}
+
+	// A wait for a change returns once a node joins.
+	changedCh := make(chan Topology, 1)
+	go func() {
+		changedCh <- getTopology(fmt.Sprintf("?%s=%d", topologyParamVersion, topology.Version))
+	}()
+	desc := &gossip.NodeDescriptor{
+		NodeID:      2,
+		Address:     util.MakeRawAddr("tcp", "localhost:26257"),
+		HTTPAddress: util.MakeRawAddr("tcp", "localhost:8080"),
+		Attrs:       proto.Attributes{Attrs: []string{"dc1"}},
+	}
+	if err := s.Gossip().AddInfo(gossip.MakeNodeIDKey(desc.NodeID), desc, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case changed := <-changedCh:
+		if changed.Version <= topology.Version || len(changed.Nodes) != 2 {
+			t.Fatalf("expected a new version with 2 nodes; got %+v", changed)
+		}
+		expected := TopologyNode{NodeID: 2, Address: "localhost:26257", HTTPAddress: "localhost:8080", Attrs: []string{"dc1"}}
+		if n := changed.Nodes[1]; !reflect.DeepEqual(n, expected) {
+			t.Errorf("expected %+v; got %+v", expected, n)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for topology change")
+	}
+
+	// A wait for a change returns once a node's descriptor expires.
+	desc = &gossip.NodeDescriptor{NodeID: 3, Address: util.MakeRawAddr("tcp", "localhost:26258")}
+	if err := s.Gossip().AddInfo(gossip.MakeNodeIDKey(desc.NodeID), desc, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	util.SucceedsWithin(t, time.Second, func() error {
+		if topology = getTopology(""); len(topology.Nodes) != 3 {
+			return util.Errorf("expected 3 nodes; got %+v", topology)
+		}
+		return nil
+	})
+	go func() {
+		changedCh <- getTopology(fmt.Sprintf("?%s=%d", topologyParamVersion, topology.Version))
+	}()
+	select {
+	case changed := <-changedCh:
+		if changed.Version <= topology.Version || len(changed.Nodes) != 2 {
+			t.Fatalf("expected a new version with 2 nodes; got %+v", changed)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for topology change")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// The classes of device detected for store directories, which are
+// used as store attributes.
+const (
+	deviceSSD = "ssd"
+	deviceHDD = "hdd"
+)
+
+// A storeDevice describes the device and file system holding a store
+// directory. Properties which can't be determined are left empty, and
+// the available space negative.
+type storeDevice struct {
+	class      string // deviceSSD or deviceHDD
+	fileSystem string // e.g. ext4 or xfs
+	available  int64  // Bytes available to the store
+}
+
+// attrs returns the store attributes of the device: its class and the
+// name of its file system.
+func (d storeDevice) attrs() proto.Attributes {
+	var attrs proto.Attributes
+	for _, attr := range []string{d.class, d.fileSystem} {
+		if len(attr) > 0 {
+			attrs.Attrs = append(attrs.Attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// checkAvailable checks the space available on the device to the store
+// in dir against minAvailable, the space below which a store is nearly
+// full. A new store, whose directory doesn't exist yet or is empty,
+// which would start nearly full is refused. An existing store is only
+// warned about: it starts nearly full, accepting the deletions which
+// free space, rather than not at all.
+func (d storeDevice) checkAvailable(dir string, minAvailable int64) error {
+	if minAvailable <= 0 || d.available < 0 || d.available >= minAvailable {
+		return nil
+	}
+	if names, err := ioutil.ReadDir(dir); os.IsNotExist(err) || (err == nil && len(names) == 0) {
+		return util.Errorf("store %s has %d bytes available, less than the minimum of %d",
+			dir, d.available, minAvailable)
+	}
+	log.Warningf("store %s has %d bytes available, less than the minimum of %d; it's nearly full "+
+		"and refuses writes other than those which free space", dir, d.available, minAvailable)
+	return nil
+}
+
+// detectStoreDevice describes the device holding dir. A store
+// directory which doesn't exist yet is described by its nearest
+// existing parent.
+func detectStoreDevice(dir string) (storeDevice, error) {
+	for {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return statStoreDevice(dir)
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+// +build linux
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// fileSystemNames maps the magic numbers reported by statfs to the
+// names of common file systems. ext2, ext3 and ext4 share a number.
+var fileSystemNames = map[uint32]string{
+	0xef53:     "ext4",
+	0x58465342: "xfs",
+	0x9123683e: "btrfs",
+	0x2fc12fc1: "zfs",
+	0x01021994: "tmpfs",
+	0x6969:     "nfs",
+	0x794c7630: "overlay",
+}
+
+// statStoreDevice describes the device holding the existing path. Its
+// class is read from the rotational flag which sysfs reports for its
+// block device; devices without one, such as those of tmpfs, are of
+// an unknown class.
+func statStoreDevice(path string) (storeDevice, error) {
+	var d storeDevice
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return d, err
+	}
+	d.fileSystem = fileSystemNames[uint32(fs.Type)]
+	d.available = int64(fs.Bsize) * int64(fs.Bavail)
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return d, err
+	}
+	if rotational, ok := deviceRotational(uint64(st.Dev)); ok {
+		d.class = deviceSSD
+		if rotational {
+			d.class = deviceHDD
+		}
+	}
+	return d, nil
+}
+
+// deviceRotational returns whether the block device dev has spinning
+// disks; ok is false if sysfs doesn't say. The flag of a partition is
+// that of the disk holding it.
+func deviceRotational(dev uint64) (rotational bool, ok bool) {
+	major := uint32((dev>>8)&0xfff) | uint32((dev>>32)&^0xfff)
+	minor := uint32(dev&0xff) | uint32((dev>>12)&^0xff)
+	path, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		return false, false
+	}
+	for _, dir := range []string{path, filepath.Dir(path)} {
+		flag, err := ioutil.ReadFile(filepath.Join(dir, "queue", "rotational"))
+		if err == nil {
+			return strings.TrimSpace(string(flag)) == "1", true
+		}
+	}
+	return false, false
+}
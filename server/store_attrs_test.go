@@ -0,0 +1,137 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestDetectStoreDevice verifies that a store directory which doesn't
+// exist yet is described by its nearest existing parent and that only
+// known attributes are detected.
+func TestDetectStoreDevice(t *testing.T) {
+	dir := util.CreateTempDir(t, "_store_attrs_test")
+	defer util.CleanupDir(dir)
+
+	device, err := detectStoreDevice(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err := detectStoreDevice(filepath.Join(dir, "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.class != missing.class || device.fileSystem != missing.fileSystem {
+		t.Errorf("expected missing directory on the device of its parent %+v; got %+v", device, missing)
+	}
+	switch device.class {
+	case "", deviceSSD, deviceHDD:
+	default:
+		t.Errorf("unexpected device class %q", device.class)
+	}
+	if runtime.GOOS == "linux" && device.available <= 0 {
+		t.Errorf("expected available space to be detected; got %+v", device)
+	}
+
+	attrs := storeDevice{class: deviceSSD, fileSystem: "xfs"}.attrs()
+	if !reflect.DeepEqual(attrs.Attrs, []string{"ssd", "xfs"}) {
+		t.Errorf("unexpected attributes %s", attrs.Attrs)
+	}
+	if attrs := (storeDevice{}).attrs(); len(attrs.Attrs) != 0 {
+		t.Errorf("expected no attributes; got %s", attrs.Attrs)
+	}
+}
+
+// TestStoreSpecDetectedAttrs verifies that stores without attributes
+// get those detected for their devices and that given attributes
+// override them.
+func TestStoreSpecDetectedAttrs(t *testing.T) {
+	dir := util.CreateTempDir(t, "_store_attrs_test")
+	defer util.CleanupDir(dir)
+	device, err := detectStoreDevice(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		spec     string
+		expAttrs proto.Attributes
+	}{
+		{"rocksdb://" + dir, device.attrs()},
+		{"rocksdb://" + dir + "?attrs=fio", proto.Attributes{Attrs: []string{"fio"}}},
+		{"mem://1000", proto.Attributes{}},
+	}
+	for i, test := range testCases {
+		spec, err := ParseStoreSpec(test.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := spec.newEngine(1<<20, engine.RocksDBTuning{}, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Attrs().SortedString() != test.expAttrs.SortedString() {
+			t.Errorf("%d: expected attributes %s; got %s", i, test.expAttrs.Attrs, e.Attrs().Attrs)
+		}
+		if spec.Location == "mem://1000" {
+			e.Close()
+		}
+	}
+}
+
+// TestStoreDeviceCheckAvailable verifies that a new store is refused if
+// it would start nearly full, and that an existing store isn't.
+func TestStoreDeviceCheckAvailable(t *testing.T) {
+	dir := util.CreateTempDir(t, "_store_attrs_test")
+	defer util.CleanupDir(dir)
+	device := storeDevice{available: 1 << 20}
+	newDir := filepath.Join(dir, "new")
+
+	testCases := []struct {
+		dir          string
+		minAvailable int64
+		expErr       bool
+	}{
+		{newDir, 0, false},
+		{newDir, 1 << 19, false},
+		{newDir, 1 << 21, true},
+		{dir, 1 << 21, true},
+	}
+	for i, test := range testCases {
+		if err := device.checkAvailable(test.dir, test.minAvailable); (err != nil) != test.expErr {
+			t.Errorf("%d: expected error %t; got %v", i, test.expErr, err)
+		}
+	}
+
+	// Once the store directory holds data, it's only warned about.
+	if err := ioutil.WriteFile(filepath.Join(dir, "CURRENT"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.checkAvailable(dir, 1<<21); err != nil {
+		t.Errorf("expected existing store to be accepted; got %s", err)
+	}
+	if err := (storeDevice{available: -1}).checkAvailable(newDir, 1<<21); err != nil {
+		t.Errorf("expected unknown available space to be accepted; got %s", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// validateStoreDirs verifies that the directory of each persistent
+// store among engines is usable before any of them is opened, so that
+// misconfigurations are reported clearly rather than as engine errors
+// during bootstrap. Each directory, including the stripe directories
+// of striped stores, is created if necessary and must be writable, no
+// directory may be used twice, and the stores which already hold data
+// must belong to the same cluster. Directories sharing a device are
+// only warned about, as they're common in testing.
+func validateStoreDirs(engines []engine.Engine) error {
+	dirs := map[string]string{}       // Canonical directory to store directory
+	devices := map[uint64]string{}    // Device ID to store directory
+	clusters := map[string][]string{} // Cluster ID to store directories
+	for _, e := range engines {
+		r, ok := e.(engine.DirEngine)
+		if !ok || r.Dir() == "" {
+			continue
+		}
+		dir := r.Dir()
+		storeDirs := []string{dir}
+		if s, ok := e.(engine.Striped); ok {
+			storeDirs = append(storeDirs, s.StripeDirs()...)
+		}
+		for _, d := range storeDirs {
+			canonical, device, err := checkStoreDir(d)
+			if err != nil {
+				return util.Errorf("store %s: %s", d, err)
+			}
+			if other, ok := dirs[canonical]; ok {
+				if other == dir {
+					return util.Errorf("store %s uses the directory %s twice", dir, canonical)
+				}
+				return util.Errorf("stores %s and %s share the directory %s", other, dir, canonical)
+			}
+			dirs[canonical] = dir
+			if other, ok := devices[device]; !ok {
+				devices[device] = dir
+			} else if other == dir {
+				log.Warningf("store %s stripes its sstables across directories of one device, "+
+					"which gains no throughput", dir)
+			} else {
+				log.Warningf("stores %s and %s share a device; their replicas won't survive its failure "+
+					"independently and its capacity is counted twice", other, dir)
+			}
+		}
+		clusterID, err := readStoreClusterID(r)
+		if err != nil {
+			return util.Errorf("store %s: %s", dir, err)
+		}
+		if clusterID != "" {
+			clusters[clusterID] = append(clusters[clusterID], dir)
+		}
+	}
+	if len(clusters) > 1 {
+		var stores []string
+		for clusterID, storeDirs := range clusters {
+			stores = append(stores, clusterID+": "+strings.Join(storeDirs, ", "))
+		}
+		sort.Strings(stores)
+		return util.Errorf("stores belong to different clusters:\n  %s", strings.Join(stores, "\n  "))
+	}
+	return nil
+}
+
+// checkStoreDir creates dir if it doesn't exist and verifies that it's
+// a writable directory, returning the directory with symbolic links
+// resolved and the ID of its device.
+func checkStoreDir(dir string) (string, uint64, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", 0, err
+	}
+	if !info.IsDir() {
+		return "", 0, util.Errorf("not a directory")
+	}
+	f, err := ioutil.TempFile(dir, "writable")
+	if err != nil {
+		return "", 0, util.Errorf("not writable: %s", err)
+	}
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return "", 0, err
+	}
+	canonical, err := filepath.Abs(dir)
+	if err == nil {
+		canonical, err = filepath.EvalSymlinks(canonical)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	var device uint64
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		device = uint64(st.Dev)
+	}
+	return canonical, device, nil
+}
+
+// readStoreClusterID returns the ID of the cluster to which the store
+// of e belongs, or an empty string if its directory doesn't hold a
+// store or the store hasn't been bootstrapped. The engine is opened and
+// closed again, so that the files of encrypted stores are decrypted.
+func readStoreClusterID(e engine.DirEngine) (string, error) {
+	if _, err := os.Stat(filepath.Join(e.Dir(), "CURRENT")); os.IsNotExist(err) {
+		return "", nil
+	}
+	if err := e.Open(); err != nil {
+		return "", util.Errorf("unable to open; is another node using it? %s", err)
+	}
+	defer e.Close()
+	var ident proto.StoreIdent
+	if _, err := engine.MVCCGetProto(e, engine.StoreIdentKey(), proto.ZeroTimestamp, true, nil, &ident); err != nil {
+		return "", util.Errorf("unable to read store identity: %s", err)
+	}
+	return ident.ClusterID, nil
+}
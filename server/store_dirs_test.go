@@ -0,0 +1,108 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// writeStoreIdent bootstraps a store in dir as a member of the cluster
+// with the supplied ID.
+func writeStoreIdent(t *testing.T, dir, clusterID string) {
+	e := engine.NewRocksDB(proto.Attributes{}, dir, 1<<20)
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	ident := &proto.StoreIdent{ClusterID: clusterID, NodeID: 1, StoreID: 1}
+	if err := engine.MVCCPutProto(e, nil, engine.StoreIdentKey(), proto.ZeroTimestamp, nil, ident); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestValidateStoreDirs verifies that store directories, including
+// stripe directories, must be writable and distinct, and that their
+// stores must belong to the same cluster.
+func TestValidateStoreDirs(t *testing.T) {
+	tmp := util.CreateNTempDirs(t, "_store_dirs_test", 3)
+	defer util.CleanupDirs(tmp)
+
+	file := filepath.Join(tmp[0], "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(tmp[0], "link")
+	if err := os.Symlink(tmp[1], link); err != nil {
+		t.Fatal(err)
+	}
+	writeStoreIdent(t, tmp[1], "cluster-a")
+	writeStoreIdent(t, tmp[2], "cluster-b")
+	missing := filepath.Join(tmp[0], "missing")
+
+	testCases := []struct {
+		dirs   []string
+		expErr bool
+	}{
+		{[]string{tmp[0], tmp[1]}, false},
+		{[]string{missing}, false},
+		{[]string{file}, true},
+		{[]string{tmp[1], tmp[1]}, true},
+		{[]string{tmp[1], link}, true},
+		{[]string{tmp[1], tmp[2]}, true},
+	}
+	for i, test := range testCases {
+		engines := []engine.Engine{engine.NewInMem(proto.Attributes{}, 1<<20)}
+		for _, dir := range test.dirs {
+			engines = append(engines, engine.NewRocksDB(proto.Attributes{}, dir, 1<<20))
+		}
+		err := validateStoreDirs(engines)
+		if test.expErr != (err != nil) {
+			t.Errorf("%d: expected error %t; got %v", i, test.expErr, err)
+		}
+		engines[0].Close()
+	}
+
+	// Stripe directories may not repeat the store's own directories or
+	// those of other stores.
+	stripeCases := []struct {
+		stripes []string
+		expErr  bool
+	}{
+		{[]string{missing, tmp[0]}, false},
+		{[]string{tmp[1]}, true},
+		{[]string{link}, true},
+		{[]string{tmp[0], tmp[0]}, true},
+		{[]string{file}, true},
+	}
+	for i, test := range stripeCases {
+		striped := engine.NewRocksDB(proto.Attributes{}, tmp[1], 1<<20)
+		striped.SetStripeDirs(test.stripes)
+		err := validateStoreDirs([]engine.Engine{striped})
+		if test.expErr != (err != nil) {
+			t.Errorf("%d: expected error %t; got %v", i, test.expErr, err)
+		}
+	}
+	if _, err := os.Stat(missing); err != nil {
+		t.Errorf("expected missing store directory to be created: %s", err)
+	}
+}
@@ -0,0 +1,547 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// A StoreSpec describes a store: the attributes, type and location of
+// its engine and limits on the resources it may use.
+type StoreSpec struct {
+	// Attrs are the attributes of the store's device. If none are given,
+	// those detected for the device of a persistent store are used.
+	Attrs proto.Attributes
+	// Location is the location of the store in the form accepted by
+	// engine.NewEngine; e.g. rocksdb:///mnt/ssd01 or mem://1073741824.
+	Location string
+	// CacheSize is the number of bytes of memory the store may use for
+	// caching data. Zero means the store uses Context.CacheSize.
+	CacheSize int64
+	// MaxSize is the maximum number of bytes the store may use, or, if
+	// MaxSizePercent is non-zero, the maximum is that percentage of the
+	// capacity of its device. Zero values leave the store unlimited.
+	MaxSize        int64
+	MaxSizePercent float64
+	// EncryptionKeyID, if set, is the ID of the key in
+	// Context.StoreKeyFile with which the store encrypts its files.
+	EncryptionKeyID string
+	// Tuning holds the RocksDB options of the store. Zero values mean
+	// the store uses those of Context.StoreTuning.
+	Tuning engine.RocksDBTuning
+	// Provisioning is the declared throughput of the store's device. If
+	// it's set, the store's compactions and snapshots are paced to
+	// shares of it instead of by Context.SnapshotApplyRate.
+	Provisioning engine.Provisioning
+	// StripeDirs are the directories beyond that of Location across
+	// which the sstables of a persistent store are spread, e.g. those of
+	// further devices.
+	StripeDirs []string
+	// ReadOnly opens an existing persistent store without accepting
+	// writes, for inspection by the debug commands. Nodes may not be
+	// started with read-only stores.
+	ReadOnly bool
+}
+
+// A StoreSpecError describes an invalid store specification.
+type StoreSpecError struct {
+	Spec   string // The offending specification
+	Index  int    // Position of the specification in its list, from 1; zero if parsed alone
+	Reason string // What's wrong with the specification
+}
+
+// Error implements the error interface.
+func (e *StoreSpecError) Error() string {
+	if e.Index > 0 {
+		return fmt.Sprintf("store %d (%q): %s", e.Index, e.Spec, e.Reason)
+	}
+	return fmt.Sprintf("store %q: %s", e.Spec, e.Reason)
+}
+
+// ParseStoreSpecs parses a comma-separated list of store
+// specifications; see ParseStoreSpec. Commas within quoted locations
+// don't separate specifications. Empty items are skipped. Errors are
+// of type *StoreSpecError.
+func ParseStoreSpecs(specs string) ([]StoreSpec, error) {
+	var result []StoreSpec
+	items, err := splitStoreSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range items {
+		if len(s) == 0 {
+			continue
+		}
+		spec, err := ParseStoreSpec(s)
+		if err != nil {
+			err.(*StoreSpecError).Index = i + 1
+			return nil, err
+		}
+		result = append(result, spec)
+	}
+	return result, nil
+}
+
+// legacyOptions are the parameters which may follow the attributes of
+// a store specification in the legacy form, separated by commas.
+var legacyOptions = []string{"cache", "maxsize", "encrypt", "compression", "write_buffer",
+	"compaction_threads", "bloom_bits", "bw", "iops", "read_only"}
+
+// splitStoreSpecs splits a list of store specifications at the commas
+// outside of quoted strings, except those which separate the options
+// of a legacy specification from its attributes.
+func splitStoreSpecs(specs string) ([]string, error) {
+	var items []string
+	start := 0
+	for i := 0; i < len(specs); i++ {
+		switch specs[i] {
+		case '"':
+			end, err := quotedStringEnd(specs[i:])
+			if err != nil {
+				return nil, &StoreSpecError{Spec: specs[start:], Index: len(items) + 1, Reason: err.Error()}
+			}
+			i += end - 1
+		case ',':
+			if isLegacyOption(specs[start:i], specs[i+1:]) {
+				continue
+			}
+			items = append(items, specs[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, specs[start:]), nil
+}
+
+// isLegacyOption returns whether next, the text following a comma,
+// begins with an option of a legacy specification whose head, the text
+// preceding the comma, holds only attributes and options. Only the
+// values of options, such as bw=125MiB/s, may hold a '/'.
+func isLegacyOption(head, next string) bool {
+	if strings.ContainsAny(head, "\"?") {
+		return false
+	}
+	segments := strings.Split(head, ",")
+	if strings.ContainsAny(segments[0], "=/") {
+		return false
+	}
+	for _, segment := range segments[1:] {
+		if strings.Count(segment, "=") != 1 {
+			return false
+		}
+	}
+	for _, option := range legacyOptions {
+		if strings.HasPrefix(next, option+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLegacyStoreSpec splits a store specification of the form
+// <attrs>[,<key>=<value>...]=<location> into its attributes, options
+// and location. ok is false if the specification isn't of the form.
+func splitLegacyStoreSpec(s string) (attrs string, options []string, location string, ok bool) {
+	i := strings.IndexAny(s, "=,\"?/")
+	if i <= 0 || (s[i] != '=' && s[i] != ',') {
+		return "", nil, "", false
+	}
+	attrs, s = s[:i], s[i:]
+	for s[0] == ',' {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			return "", nil, "", false
+		}
+		end := strings.IndexAny(s[eq+1:], ",=")
+		if end < 0 {
+			return "", nil, "", false
+		}
+		options = append(options, s[1:eq+1+end])
+		s = s[eq+1+end:]
+	}
+	return attrs, options, s[1:], true
+}
+
+// quotedStringEnd returns the length of the double-quoted string, with
+// backslash escapes, with which s begins.
+func quotedStringEnd(s string) (int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, util.Errorf("unterminated quoted string %s", s)
+}
+
+// ParseStoreSpec parses a store specification in either of two forms.
+// The legacy form is a colon-separated list of attributes followed by
+// '=' and a location, e.g. ssd:7200rpm=/mnt/ssd01. The attributes may
+// be followed by comma-separated parameters, e.g.
+// ssd,cache=2GiB,maxsize=50%=/mnt/ssd01. The URL form is a
+// location with a scheme, optionally followed by query parameters,
+// e.g. rocksdb:///mnt/ssd01?attrs=ssd:7200rpm&cache=2GiB&maxsize=80%.
+// The parameters are:
+//
+//   attrs:   colon-separated list of attributes
+//   type:    engine type of a location without a scheme, e.g. mem
+//   cache:   size of the store's cache
+//   maxsize: maximum size of the store, or percentage of its device
+//   encrypt: ID of the key with which the store encrypts its files
+//   compression:        algorithm with which sstables are compressed,
+//                       one of engine.CompressionTypes
+//   write_buffer:       size of the buffer of writes in memory
+//   compaction_threads: number of compactions which may run at once
+//   bloom_bits:         bits per key of the bloom filters of sstables
+//   bw:      provisioned bandwidth of the store's device, e.g. 125MiB/s
+//   iops:    provisioned IOPS of the store's device
+//   read_only:          true to open an existing store without
+//                       accepting writes, for the debug commands
+//
+// Sizes are in bytes, with an optional suffix such as MiB or GB.
+//
+// The directory of a persistent store may be followed by further
+// directories, separated by '+', across which its sstables are spread,
+// e.g. ssd=/mnt/a+/mnt/b, so that a store may use several devices
+// without RAID.
+//
+// In either form, the location may be double-quoted, in which case it
+// may hold any characters, with Go escape sequences, and be followed
+// by parameters; e.g. ssd="/mnt/data,1"?cache=1GiB or
+// "/mnt/data?1"?type=rocksdb. Unquoted locations in the URL form may
+// not contain '?', in the legacy form are never followed by
+// parameters, and in either form are split at each '+'. Errors are of
+// type *StoreSpecError.
+func ParseStoreSpec(s string) (StoreSpec, error) {
+	var spec StoreSpec
+	fail := func(format string, args ...interface{}) (StoreSpec, error) {
+		return StoreSpec{}, &StoreSpecError{Spec: s, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	// In the legacy form, the attributes are followed by '=' or an
+	// option before any quote, query or path separator.
+	location, legacy := s, false
+	var params string
+	if attrs, options, l, ok := splitLegacyStoreSpec(s); ok {
+		for _, option := range options {
+			known := false
+			for _, key := range legacyOptions {
+				known = known || strings.HasPrefix(option, key+"=")
+			}
+			if !known {
+				return fail("unknown option %q; options are %s", option, legacyOptions)
+			}
+		}
+		spec.Attrs = parseAttributes(attrs)
+		location, legacy, params = l, true, strings.Join(options, "&")
+	}
+	quoted := strings.HasPrefix(location, "\"")
+	if quoted {
+		end, err := quotedStringEnd(location)
+		if err != nil {
+			return fail("%s", err)
+		}
+		quoted, rest := location[:end], location[end:]
+		if location, err = strconv.Unquote(quoted); err != nil {
+			return fail("invalid quoted location %s: %s", quoted, err)
+		}
+		if len(rest) > 0 {
+			if rest[0] != '?' {
+				return fail("unexpected %q after quoted location", rest)
+			}
+			if len(params) > 0 {
+				return fail("parameters may not follow the location of a specification with options")
+			}
+			params = rest[1:]
+		}
+	} else if i := strings.Index(location, "?"); i >= 0 && !legacy {
+		location, params = location[:i], location[i+1:]
+	}
+	if !quoted && strings.Contains(location, "+") {
+		dirs := strings.Split(location, "+")
+		for _, dir := range dirs[1:] {
+			if len(dir) == 0 || strings.Contains(dir, "://") {
+				return fail("invalid stripe directory %q; expected <location>+<dir>[+<dir>...]", dir)
+			}
+		}
+		location, spec.StripeDirs = dirs[0], dirs[1:]
+	}
+	if len(location) == 0 {
+		return fail("missing location")
+	}
+	spec.Location = location
+
+	var engineType string
+	if len(params) > 0 {
+		for _, param := range strings.Split(params, "&") {
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) != 2 || len(kv[1]) == 0 {
+				return fail("expected <key>=<value> parameter; got %q", param)
+			}
+			var err error
+			switch key, value := kv[0], kv[1]; key {
+			case "attrs":
+				spec.Attrs = parseAttributes(value)
+			case "type":
+				engineType = value
+			case "cache":
+				spec.CacheSize, err = util.ParseBytes(value)
+			case "maxsize":
+				if strings.HasSuffix(value, "%") {
+					spec.MaxSizePercent, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+					if err == nil && (spec.MaxSizePercent <= 0 || spec.MaxSizePercent > 100) {
+						err = util.Errorf("percentage %q is not in (0, 100]", value)
+					}
+				} else {
+					spec.MaxSize, err = util.ParseBytes(value)
+				}
+			case "encrypt":
+				spec.EncryptionKeyID = value
+			case "compression":
+				spec.Tuning.Compression = value
+			case "write_buffer":
+				spec.Tuning.WriteBufferSize, err = util.ParseBytes(value)
+			case "compaction_threads":
+				spec.Tuning.CompactionThreads, err = strconv.Atoi(value)
+			case "bloom_bits":
+				spec.Tuning.BloomFilterBits, err = strconv.Atoi(value)
+			case "bw":
+				spec.Provisioning.Bandwidth, err = util.ParseBytes(strings.TrimSuffix(value, "/s"))
+				if err == nil && spec.Provisioning.Bandwidth <= 0 {
+					err = util.Errorf("bandwidth %q must be positive", value)
+				}
+			case "iops":
+				spec.Provisioning.IOPS, err = strconv.ParseInt(value, 10, 64)
+				if err == nil && spec.Provisioning.IOPS <= 0 {
+					err = util.Errorf("IOPS %q must be positive", value)
+				}
+			case "read_only":
+				spec.ReadOnly, err = strconv.ParseBool(value)
+			default:
+				err = util.Errorf("unknown parameter %q", key)
+			}
+			if err != nil {
+				return fail("%s", err)
+			}
+		}
+	}
+
+	if err := spec.Tuning.Validate(); err != nil {
+		return fail("%s", err)
+	}
+
+	hasScheme := strings.Contains(location, "://")
+	if len(engineType) > 0 {
+		if hasScheme {
+			return fail("location %q has a scheme, so the type parameter may not be given", location)
+		}
+		known := false
+		for _, scheme := range engine.Schemes() {
+			known = known || scheme == engineType
+		}
+		if !known {
+			return fail("unknown type %q; known types are %s", engineType, engine.Schemes())
+		}
+		spec.Location = engineType + "://" + location
+	} else if !legacy && !hasScheme {
+		return fail("expected <attrs>=<location>, <scheme>://<location>[?<params>] or " +
+			"<location>?type=<type>[&<params>]")
+	}
+	return spec, nil
+}
+
+// sharedCacheSize returns the cache size of each store which doesn't
+// set its own: what remains of total after the caches of the others,
+// split evenly between them.
+func sharedCacheSize(total int64, specs []StoreSpec) (int64, error) {
+	var explicit, shared int64
+	for _, spec := range specs {
+		if spec.CacheSize > 0 {
+			explicit += spec.CacheSize
+		} else {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0, nil
+	}
+	size := (total - explicit) / shared
+	if size <= 0 {
+		return 0, util.Errorf("cache size of %d bytes leaves nothing for %d store(s) after %d bytes of per-store caches",
+			total, shared, explicit)
+	}
+	return size, nil
+}
+
+// A maxSizer is an engine whose capacity may be limited.
+type maxSizer interface {
+	SetMaxSize(maxSize int64, percent float64)
+}
+
+// A cacheSizer is an engine whose cache may be resized while it's open.
+type cacheSizer interface {
+	SetCacheSize(size int64)
+}
+
+// A tuner is an engine whose RocksDB options and provisioning may be
+// set before it's opened.
+type tuner interface {
+	engine.DirEngine
+	SetTuning(t engine.RocksDBTuning)
+	SetProvisioning(p engine.Provisioning)
+}
+
+// A striper is an engine whose sstables may be spread across further
+// directories, set before it's opened.
+type striper interface {
+	engine.DirEngine
+	SetStripeDirs(dirs []string)
+}
+
+// An attrSetter is an engine whose attributes may be set before it's
+// opened.
+type attrSetter interface {
+	engine.DirEngine
+	SetAttrs(attrs proto.Attributes)
+}
+
+// A readOnlySetter is an engine which may be opened read-only.
+type readOnlySetter interface {
+	engine.DirEngine
+	SetReadOnly(readOnly bool)
+}
+
+// newEngine instantiates the engine of the store spec. defaultCacheSize
+// is used if the spec doesn't set a cache size, and the values of
+// defaultTuning for the options it doesn't tune. A persistent engine of
+// a spec without attributes is given those detected for its device,
+// whose available space is checked against minAvailable; see
+// storeDevice.checkAvailable. Zero skips the check. The files of the engine of a spec with an encryption
+// key are encrypted with keys; only persistent engines, which are
+// opened later, may be encrypted.
+func (spec StoreSpec) newEngine(defaultCacheSize int64, defaultTuning engine.RocksDBTuning,
+	keys engine.EncryptionKeys, minAvailable int64) (engine.Engine, error) {
+	cacheSize := spec.CacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultCacheSize
+	}
+	e, err := engine.NewEngine(spec.Attrs, spec.Location, cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := e.(attrSetter); ok && r.Dir() != "" {
+		device, err := detectStoreDevice(r.Dir())
+		if err != nil {
+			log.Warningf("unable to detect the device of store %s: %s", r.Dir(), err)
+		} else {
+			if attrs := device.attrs(); len(spec.Attrs.Attrs) == 0 && len(attrs.Attrs) > 0 {
+				log.Infof("store %s has detected attributes %s", r.Dir(), attrs.Attrs)
+				r.SetAttrs(attrs)
+			}
+			if err := device.checkAvailable(r.Dir(), minAvailable); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// In-memory engines are opened when they're instantiated, so only
+	// persistent engines may be tuned.
+	if t, ok := e.(tuner); ok && t.Dir() != "" {
+		t.SetTuning(spec.Tuning.Merge(defaultTuning))
+		t.SetProvisioning(spec.Provisioning)
+	} else if spec.Tuning != (engine.RocksDBTuning{}) || spec.Provisioning != (engine.Provisioning{}) {
+		return nil, util.Errorf("engine %T doesn't support tuning", e)
+	}
+	if len(spec.StripeDirs) > 0 {
+		s, ok := e.(striper)
+		if !ok || s.Dir() == "" {
+			return nil, util.Errorf("engine %T doesn't support stripe directories", e)
+		}
+		s.SetStripeDirs(spec.StripeDirs)
+	}
+	if spec.ReadOnly {
+		ro, ok := e.(readOnlySetter)
+		if !ok || ro.Dir() == "" {
+			return nil, util.Errorf("engine %T may not be opened read-only", e)
+		}
+		ro.SetReadOnly(true)
+	}
+	if spec.MaxSize > 0 || spec.MaxSizePercent > 0 {
+		ms, ok := e.(maxSizer)
+		if !ok {
+			return nil, util.Errorf("engine %T doesn't support a maximum size", e)
+		}
+		ms.SetMaxSize(spec.MaxSize, spec.MaxSizePercent)
+	}
+	if len(spec.EncryptionKeyID) > 0 {
+		r, ok := e.(*engine.RocksDB)
+		if !ok {
+			return nil, util.Errorf("engine %T doesn't support encryption", e)
+		}
+		enc, err := engine.NewEncrypted(r, keys, spec.EncryptionKeyID)
+		if err != nil {
+			return nil, err
+		}
+		return enc, nil
+	}
+	return e, nil
+}
+
+// openStoreCacheSize is the cache size of the stores opened by
+// OpenStore.
+const openStoreCacheSize = 1 << 20
+
+// OpenStore opens the existing store of the spec for the debug
+// commands, which inspect and repair the stores of stopped nodes. The
+// keys of an encrypted store are loaded from keyFile.
+func OpenStore(spec StoreSpec, keyFile string) (engine.Engine, error) {
+	var keys engine.EncryptionKeys
+	if len(spec.EncryptionKeyID) > 0 {
+		if len(keyFile) == 0 {
+			return nil, util.Errorf("store %q is encrypted, so -store-key-file must be set", spec.Location)
+		}
+		var err error
+		if keys, err = engine.LoadEncryptionKeys(keyFile); err != nil {
+			return nil, util.Errorf("unable to load store encryption keys: %s", err)
+		}
+	}
+	e, err := spec.newEngine(openStoreCacheSize, engine.RocksDBTuning{}, keys, 0)
+	if err != nil {
+		return nil, util.Errorf("unable to init engine for store %q: %s", spec.Location, err)
+	}
+	r, ok := e.(engine.DirEngine)
+	if !ok || r.Dir() == "" {
+		return nil, util.Errorf("store %q is not persistent", spec.Location)
+	}
+	if _, err := os.Stat(r.Dir()); err != nil {
+		return nil, util.Errorf("no store at %s: %s", r.Dir(), err)
+	}
+	if err := e.Open(); err != nil {
+		if spec.ReadOnly {
+			return nil, util.Errorf("unable to open store %s: %s", r.Dir(), err)
+		}
+		return nil, util.Errorf("unable to open store %s; is its node running? %s", r.Dir(), err)
+	}
+	return e, nil
+}
@@ -0,0 +1,314 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// TestParseStoreSpec verifies parsing of store specifications in the
+// legacy and URL forms.
+func TestParseStoreSpec(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected StoreSpec
+		expErr   bool
+	}{
+		{"ssd:7200rpm=/mnt/ssd01", StoreSpec{Attrs: proto.Attributes{Attrs: []string{"ssd", "7200rpm"}}, Location: "/mnt/ssd01"}, false},
+		{"mem=1000", StoreSpec{Attrs: proto.Attributes{Attrs: []string{"mem"}}, Location: "1000"}, false},
+		{"ssd=rocksdb:///mnt/ssd01", StoreSpec{Attrs: proto.Attributes{Attrs: []string{"ssd"}}, Location: "rocksdb:///mnt/ssd01"}, false},
+		{"rocksdb:///mnt/ssd01", StoreSpec{Location: "rocksdb:///mnt/ssd01"}, false},
+		{"rocksdb:///mnt/ssd01?attrs=ssd:fast&cache=2GiB&maxsize=80%", StoreSpec{
+			Attrs:          proto.Attributes{Attrs: []string{"ssd", "fast"}},
+			Location:       "rocksdb:///mnt/ssd01",
+			CacheSize:      2 << 30,
+			MaxSizePercent: 80,
+		}, false},
+		{"mem://1000000?maxsize=500KB&cache=1024", StoreSpec{Location: "mem://1000000", CacheSize: 1024, MaxSize: 500000}, false},
+		{"ssd,encrypt=key1=/mnt/ssd01", StoreSpec{Attrs: proto.Attributes{Attrs: []string{"ssd"}}, Location: "/mnt/ssd01", EncryptionKeyID: "key1"}, false},
+		{"rocksdb:///mnt/ssd01?encrypt=key1", StoreSpec{Location: "rocksdb:///mnt/ssd01", EncryptionKeyID: "key1"}, false},
+		{"ssd=/mnt/a=b", StoreSpec{Attrs: proto.Attributes{Attrs: []string{"ssd"}}, Location: "/mnt/a=b"}, false},
+		{`ssd="/mnt/a,b?c"?cache=1KiB`, StoreSpec{Attrs: proto.Attributes{Attrs: []string{"ssd"}}, Location: "/mnt/a,b?c", CacheSize: 1 << 10}, false},
+		{`"/mnt/a=b"?type=rocksdb&attrs=hdd`, StoreSpec{Attrs: proto.Attributes{Attrs: []string{"hdd"}}, Location: "rocksdb:///mnt/a=b"}, false},
+		{"/mnt/ssd01?type=rocksdb", StoreSpec{Location: "rocksdb:///mnt/ssd01"}, false},
+		{"ssd,cache=2GiB=/mnt/ssd01", StoreSpec{Attrs: proto.Attributes{Attrs: []string{"ssd"}}, Location: "/mnt/ssd01", CacheSize: 2 << 30}, false},
+		{"ssd:fast,cache=1KiB,maxsize=50%=/mnt/a=b", StoreSpec{
+			Attrs:          proto.Attributes{Attrs: []string{"ssd", "fast"}},
+			Location:       "/mnt/a=b",
+			CacheSize:      1 << 10,
+			MaxSizePercent: 50,
+		}, false},
+		{`mem,cache=1024="1000"`, StoreSpec{Attrs: proto.Attributes{Attrs: []string{"mem"}}, Location: "1000", CacheSize: 1024}, false},
+		{"1000?type=mem", StoreSpec{Location: "mem://1000"}, false},
+		{"ssd,compression=snappy,write_buffer=128MiB=/mnt/ssd01", StoreSpec{
+			Attrs:    proto.Attributes{Attrs: []string{"ssd"}},
+			Location: "/mnt/ssd01",
+			Tuning:   engine.RocksDBTuning{Compression: "snappy", WriteBufferSize: 128 << 20},
+		}, false},
+		{"rocksdb:///mnt/ssd01?compaction_threads=4&bloom_bits=10&compression=lz4", StoreSpec{
+			Location: "rocksdb:///mnt/ssd01",
+			Tuning:   engine.RocksDBTuning{CompactionThreads: 4, BloomFilterBits: 10, Compression: "lz4"},
+		}, false},
+		{"ssd,bw=125MiB/s,iops=3000=/mnt/ssd01", StoreSpec{
+			Attrs:        proto.Attributes{Attrs: []string{"ssd"}},
+			Location:     "/mnt/ssd01",
+			Provisioning: engine.Provisioning{Bandwidth: 125 << 20, IOPS: 3000},
+		}, false},
+		{"rocksdb:///mnt/ssd01?bw=1GB", StoreSpec{Location: "rocksdb:///mnt/ssd01", Provisioning: engine.Provisioning{Bandwidth: 1e9}}, false},
+		{"ssd=/mnt/a+/mnt/b", StoreSpec{
+			Attrs:      proto.Attributes{Attrs: []string{"ssd"}},
+			Location:   "/mnt/a",
+			StripeDirs: []string{"/mnt/b"},
+		}, false},
+		{"rocksdb:///mnt/a+/mnt/b+/mnt/c?cache=1GiB", StoreSpec{
+			Location:   "rocksdb:///mnt/a",
+			CacheSize:  1 << 30,
+			StripeDirs: []string{"/mnt/b", "/mnt/c"},
+		}, false},
+		{`ssd="/mnt/a+b"`, StoreSpec{Attrs: proto.Attributes{Attrs: []string{"ssd"}}, Location: "/mnt/a+b"}, false},
+		{"ssd=/mnt/a+", StoreSpec{}, true},
+		{"ssd=/mnt/a++/mnt/b", StoreSpec{}, true},
+		{"ssd=/mnt/a+rocksdb:///mnt/b", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?read_only=true", StoreSpec{Location: "rocksdb:///mnt/ssd01", ReadOnly: true}, false},
+		{"ssd,read_only=true=/mnt/ssd01", StoreSpec{
+			Attrs:    proto.Attributes{Attrs: []string{"ssd"}},
+			Location: "/mnt/ssd01",
+			ReadOnly: true,
+		}, false},
+		{"ssd,read_only=maybe=/mnt/ssd01", StoreSpec{}, true},
+		{"/mnt/ssd01", StoreSpec{}, true},
+		{"/mnt/ssd01?type=floppy", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?type=mem", StoreSpec{}, true},
+		{`ssd="/mnt/ssd01`, StoreSpec{}, true},
+		{`ssd="/mnt/ssd01"/x`, StoreSpec{}, true},
+		{`ssd="\q"`, StoreSpec{}, true},
+		{`""?type=rocksdb`, StoreSpec{}, true},
+		{"=/mnt/ssd01", StoreSpec{}, true},
+		{"ssd=", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?attrs", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?size=1GB", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?cache=2XB", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?maxsize=120%", StoreSpec{}, true},
+		{"ssd,cache=2XB=/mnt/ssd01", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?encrypt=", StoreSpec{}, true},
+		{"ssd,type=mem=1000", StoreSpec{}, true},
+		{"ssd,compression=zip=/mnt/ssd01", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?write_buffer=-1", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?compaction_threads=many", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?bloom_bits=-10", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?bw=0", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?bw=fast", StoreSpec{}, true},
+		{"rocksdb:///mnt/ssd01?iops=-1", StoreSpec{}, true},
+		{"ssd,cache=2GiB", StoreSpec{}, true},
+		{`ssd,cache=1KiB="/mnt/ssd01"?maxsize=1GB`, StoreSpec{}, true},
+	}
+	for i, test := range testCases {
+		spec, err := ParseStoreSpec(test.spec)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%d: expected error parsing %q; got %+v", i, test.spec, spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %s", i, err)
+		} else if !reflect.DeepEqual(spec, test.expected) {
+			t.Errorf("%d: expected %+v; got %+v", i, test.expected, spec)
+		}
+	}
+}
+
+// TestParseStoreSpecs verifies that lists of store specifications are
+// split outside of quoted locations and that errors identify the
+// offending specification.
+func TestParseStoreSpecs(t *testing.T) {
+	specs, err := ParseStoreSpecs(`ssd="/mnt/a,b",,mem=1000`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0].Location != "/mnt/a,b" || specs[1].Location != "1000" {
+		t.Errorf("expected 2 stores at /mnt/a,b and 1000; got %+v", specs)
+	}
+	// The options of a legacy specification don't separate it from its
+	// attributes, but an attribute named for an option is a new store.
+	specs, err = ParseStoreSpecs("ssd,cache=2GiB,maxsize=1GB=/mnt/ssd01,cache=/mnt/ssd02,hdd,cache=1GiB=/mnt/hdd01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 3 || specs[0].CacheSize != 2<<30 || specs[0].MaxSize != 1e9 ||
+		specs[1].Location != "/mnt/ssd02" || specs[2].Location != "/mnt/hdd01" || specs[2].CacheSize != 1<<30 {
+		t.Errorf("expected 3 stores with caches of 2GiB, none and 1GiB; got %+v", specs)
+	}
+
+	for i, test := range []struct {
+		specs    string
+		expIndex int
+		expSpec  string
+	}{
+		{"ssd=/mnt/ssd01,/mnt/ssd02", 2, "/mnt/ssd02"},
+		{`ssd=/mnt/ssd01,ssd="/mnt/ssd02,mem=1000`, 2, `ssd="/mnt/ssd02,mem=1000`},
+		{"ssd,bw=125MiB/s,iops=3000=/mnt/ssd01,/mnt/ssd02", 2, "/mnt/ssd02"},
+	} {
+		_, err := ParseStoreSpecs(test.specs)
+		if ssErr, ok := err.(*StoreSpecError); !ok {
+			t.Errorf("%d: expected store spec error; got %v", i, err)
+		} else if ssErr.Index != test.expIndex || ssErr.Spec != test.expSpec {
+			t.Errorf("%d: expected error in store %d (%q); got %s", i, test.expIndex, test.expSpec, ssErr)
+		}
+	}
+}
+
+// TestStoreSpecMaxSize verifies that the maximum size of a store spec
+// limits the capacity of its engine.
+func TestStoreSpecMaxSize(t *testing.T) {
+	ctx := NewContext()
+	ctx.Stores = "mem://1000000?maxsize=1MiB,mem=1000000"
+	if err := ctx.initEngines(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.Engines) != 2 {
+		t.Fatalf("expected 2 engines; got %d", len(ctx.Engines))
+	}
+	capacity, err := ctx.Engines[0].Capacity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capacity.Capacity != 1<<20 || capacity.Available > 1<<20 {
+		t.Errorf("expected capacity limited to 1MiB; got %+v", capacity)
+	}
+	if capacity, err = ctx.Engines[1].Capacity(); err != nil {
+		t.Fatal(err)
+	} else if capacity.Capacity <= 1<<20 {
+		t.Errorf("expected unlimited capacity; got %+v", capacity)
+	}
+}
+
+// TestStoreSpecTuning verifies that persistent stores are opened with
+// their tuning, or that of the context for the options they don't
+// tune, and that in-memory stores may not be tuned.
+func TestStoreSpecTuning(t *testing.T) {
+	dir := util.CreateTempDir(t, "_store_spec_test")
+	defer util.CleanupDir(dir)
+
+	ctx := NewContext()
+	ctx.StoreTuning = engine.RocksDBTuning{BloomFilterBits: 10, Compression: "none"}
+	ctx.Stores = "ssd,compression=zlib,write_buffer=1MiB,compaction_threads=2=" + dir
+	if err := ctx.initEngines(); err != nil {
+		t.Fatal(err)
+	}
+	e := ctx.Engines[0]
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if err := e.Put(engine.MVCCEncodeKey(proto.Key("a")), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stores := range []string{"mem,bloom_bits=10=1000000", "mem,bw=100MiB/s=1000000"} {
+		ctx.Stores = stores
+		if err := ctx.initEngines(); err == nil {
+			t.Errorf("expected error tuning in-memory store %q", stores)
+		}
+	}
+}
+
+// TestOpenStore verifies that an existing store may be opened
+// read-only, rejecting writes, but that nodes may not be started with
+// read-only stores.
+func TestOpenStore(t *testing.T) {
+	dir := util.CreateTempDir(t, "_store_spec_test")
+	defer util.CleanupDir(dir)
+
+	key := engine.MVCCEncodeKey(proto.Key("a"))
+	e, err := OpenStore(StoreSpec{Location: dir}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Put(key, []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+
+	spec, err := ParseStoreSpec("ssd,read_only=true=" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err = OpenStore(spec, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if val, err := e.Get(key); err != nil || string(val) != "value" {
+		t.Errorf("expected value; got %q, %v", val, err)
+	}
+	if err := e.Put(key, []byte("other")); err == nil {
+		t.Error("expected error writing to read-only store")
+	}
+
+	ctx := NewContext()
+	ctx.Stores = "ssd,read_only=true=" + dir
+	if err := ctx.initEngines(); err == nil {
+		t.Error("expected error initializing engines of read-only store")
+	}
+	for _, spec := range []StoreSpec{
+		{Location: "mem://1000000", ReadOnly: true},
+		{Location: filepath.Join(dir, "missing"), ReadOnly: true},
+	} {
+		if _, err := OpenStore(spec, ""); err == nil {
+			t.Errorf("expected error opening %+v", spec)
+		}
+	}
+}
+
+// TestSharedCacheSize verifies that the cache size is split evenly
+// between the stores which don't set their own, after the caches of
+// those which do.
+func TestSharedCacheSize(t *testing.T) {
+	testCases := []struct {
+		total    int64
+		caches   []int64
+		expected int64
+		expErr   bool
+	}{
+		{1000, []int64{0}, 1000, false},
+		{1000, []int64{0, 0}, 500, false},
+		{1000, []int64{400, 0, 0}, 300, false},
+		{1000, []int64{400, 2000}, 0, false},
+		{1000, []int64{1000, 0}, 0, true},
+	}
+	for i, test := range testCases {
+		var specs []StoreSpec
+		for _, cache := range test.caches {
+			specs = append(specs, StoreSpec{CacheSize: cache})
+		}
+		size, err := sharedCacheSize(test.total, specs)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%d: expected error; got %d", i, size)
+			}
+		} else if err != nil {
+			t.Errorf("%d: %s", i, err)
+		} else if size != test.expected {
+			t.Errorf("%d: expected %d; got %d", i, test.expected, size)
+		}
+	}
+}
@@ -122,7 +122,7 @@ func (ts *TestServer) Start() error {
 	ts.Ctx.Engines = []engine.Engine{ts.Engine}
 	if !ts.SkipBootstrap {
 		stopper := util.NewStopper()
-		_, err := BootstrapCluster("cluster-1", ts.Engine, stopper)
+		_, err := BootstrapCluster("cluster-1", ts.Engine, nil, stopper)
 		if err != nil {
 			return util.Errorf("could not bootstrap cluster: %s", err)
 		}
@@ -141,6 +141,12 @@ func (ts *TestServer) ServingAddr() string {
 	return ts.rpc.Addr().String()
 }
 
+// ServingHTTPAddr returns the address at which the server serves
+// HTTP, which differs from ServingAddr if Ctx.HTTPAddr is set.
+func (ts *TestServer) ServingHTTPAddr() string {
+	return ts.HTTPAddr().String()
+}
+
 // Stop stops the TestServer.
 func (ts *TestServer) Stop() {
 	ts.Server.Stop()
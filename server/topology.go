@@ -0,0 +1,173 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// topologyExpiryInterval is the interval at which the topology is
+// checked for nodes whose descriptors have expired.
+const topologyExpiryInterval = 1 * time.Second
+
+// TopologyNode describes a node of the cluster to clients which route
+// requests themselves.
+type TopologyNode struct {
+	NodeID proto.NodeID `json:"nodeID"`
+	// Address is the address at which the node serves RPCs.
+	Address string `json:"address"`
+	// HTTPAddress is the address at which the node serves HTTP. It's
+	// the same as Address unless the node was started with -http-addr.
+	HTTPAddress string `json:"httpAddress"`
+	// Attrs are the node's attributes, which describe its locality,
+	// e.g. its datacenter and rack.
+	Attrs []string `json:"attrs"`
+}
+
+// A Topology lists the nodes of a cluster, ordered by node ID. Its
+// version is incremented on each change to the list, so that a client
+// may wait for the next change to the version it holds.
+type Topology struct {
+	Version int64          `json:"version"`
+	Nodes   []TopologyNode `json:"nodes"`
+}
+
+// A topologyWatcher keeps the node descriptors learned through gossip
+// and notifies waiters each time one is added or changed. The
+// descriptors of nodes which stop gossiping expire; they're dropped,
+// as a change, within topologyExpiryInterval or the next time the
+// topology is read, whichever is sooner.
+type topologyWatcher struct {
+	gossip *gossip.Gossip
+
+	mu      sync.Mutex
+	version int64
+	nodes   map[proto.NodeID]*gossip.NodeDescriptor
+	changed chan struct{} // Closed and replaced on each change
+}
+
+// newTopologyWatcher returns a topologyWatcher for the nodes gossiped
+// by g.
+func newTopologyWatcher(g *gossip.Gossip) *topologyWatcher {
+	return &topologyWatcher{
+		gossip:  g,
+		nodes:   map[proto.NodeID]*gossip.NodeDescriptor{},
+		changed: make(chan struct{}),
+	}
+}
+
+// start registers for gossip of node descriptors, including those
+// already known, and starts a goroutine which drops the expired ones.
+func (tw *topologyWatcher) start(stopper *util.Stopper) {
+	tw.gossip.RegisterCallback(gossip.MakePrefixPattern(gossip.KeyNodeIDPrefix), tw.nodeGossipUpdate)
+	stopper.RunWorker(func() {
+		ticker := time.NewTicker(topologyExpiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				tw.mu.Lock()
+				tw.expireLocked()
+				tw.mu.Unlock()
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// nodeGossipUpdate records the node descriptor gossiped under key if
+// its contents changed.
+func (tw *topologyWatcher) nodeGossipUpdate(key string, contentsChanged bool) {
+	if !contentsChanged {
+		return
+	}
+	val, err := tw.gossip.GetInfo(key)
+	if err != nil {
+		log.Errorf("unable to fetch node descriptor %s: %s", key, err)
+		return
+	}
+	desc, ok := val.(*gossip.NodeDescriptor)
+	if !ok {
+		log.Errorf("gossiped info for %s is not a node descriptor: %+v", key, val)
+		return
+	}
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.nodes[desc.NodeID] = desc
+	tw.changedLocked()
+}
+
+// changedLocked increments the version and wakes the waiters for the
+// previous one. The mutex is assumed held by the caller.
+func (tw *topologyWatcher) changedLocked() {
+	tw.version++
+	close(tw.changed)
+	tw.changed = make(chan struct{})
+}
+
+// expireLocked drops the nodes whose descriptors have expired, waking
+// the waiters if there are any. The mutex is assumed held by the
+// caller.
+func (tw *topologyWatcher) expireLocked() {
+	var expired bool
+	for nodeID := range tw.nodes {
+		if _, err := tw.gossip.GetInfo(gossip.MakeNodeIDKey(nodeID)); err != nil {
+			delete(tw.nodes, nodeID)
+			expired = true
+		}
+	}
+	if expired {
+		tw.changedLocked()
+	}
+}
+
+// topology returns the current topology and a channel which is closed
+// once it changes.
+func (tw *topologyWatcher) topology() (Topology, <-chan struct{}) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.expireLocked()
+	t := Topology{Version: tw.version, Nodes: []TopologyNode{}}
+	for _, desc := range tw.nodes {
+		n := TopologyNode{
+			NodeID:      desc.NodeID,
+			Address:     desc.Address.String(),
+			HTTPAddress: desc.Address.String(),
+			Attrs:       desc.Attrs.Attrs,
+		}
+		if desc.HTTPAddress != nil {
+			n.HTTPAddress = desc.HTTPAddress.String()
+		}
+		t.Nodes = append(t.Nodes, n)
+	}
+	sort.Sort(topologyNodesByID(t.Nodes))
+	return t, tw.changed
+}
+
+// topologyNodesByID sorts topology nodes by node ID.
+type topologyNodesByID []TopologyNode
+
+func (t topologyNodesByID) Len() int           { return len(t) }
+func (t topologyNodesByID) Less(i, j int) bool { return t[i].NodeID < t[j].NodeID }
+func (t topologyNodesByID) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
@@ -0,0 +1,171 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/ts"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// usagePath reports the live bytes of the cluster broken down by
+	// top-level key prefix.
+	usagePath = adminEndpoint + "usage"
+	// usageScanBatchSize is the number of rows read per scan while
+	// computing a usage report.
+	usageScanBatchSize = 1000
+)
+
+// A keyUsage is the live data stored under one key prefix. User data
+// is broken down by the prefixes of the accounting configs, which
+// divide the key space into namespaces; data under no other prefix is
+// attributed to the default config's empty prefix.
+type keyUsage struct {
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"` // query escaped, as by the config endpoints
+	LiveBytes int64  `json:"live_bytes"`
+	LiveCount int64  `json:"live_count"`
+}
+
+// A usageReport lists the live data of the cluster by key prefix, in
+// descending order of live bytes.
+type usageReport struct {
+	Usage     []*keyUsage `json:"usage"`
+	LiveBytes int64       `json:"live_bytes"`
+	LiveCount int64       `json:"live_count"`
+}
+
+// systemUsagePrefixes names the prefixes of the system key space which
+// are reported separately, most specific first. Other system keys are
+// reported under the system prefix.
+var systemUsagePrefixes = []struct {
+	name   string
+	prefix proto.Key
+}{
+	{"meta", engine.KeyMetaPrefix},
+	{"timeseries", ts.KeyDataPrefix},
+	{"system", engine.KeySystemPrefix},
+}
+
+// handleUsage responds with a usageReport. The report is computed by
+// scanning the entire addressable key space, so its cost is
+// proportional to the size of the cluster's data.
+func (s *adminServer) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	report, err := computeUsage(s.db)
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, contentType, err := util.MarshalResponse(r, report, []util.EncodingType{util.JSONEncoding})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// computeUsage scans the key space in batches, attributing the size of
+// each live key and value to its prefix.
+func computeUsage(db *client.KV) (*usageReport, error) {
+	acctMap, err := loadAcctConfigs(db)
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]*keyUsage{}
+	add := func(name string, prefix proto.Key, kv proto.KeyValue) {
+		u, ok := usage[name]
+		if !ok {
+			u = &keyUsage{Name: name, Prefix: url.QueryEscape(string(prefix))}
+			usage[name] = u
+		}
+		u.LiveBytes += int64(len(kv.Key) + kv.Value.Size())
+		u.LiveCount++
+	}
+
+	report := &usageReport{}
+	for start := engine.KeyMetaPrefix; ; {
+		call := client.ScanCall(start, engine.KeyMax, usageScanBatchSize)
+		if err := db.Run(call); err != nil {
+			return nil, err
+		}
+		rows := call.Reply.(*proto.ScanResponse).Rows
+	RowLoop:
+		for _, kv := range rows {
+			report.LiveBytes += int64(len(kv.Key) + kv.Value.Size())
+			report.LiveCount++
+			for _, sp := range systemUsagePrefixes {
+				if bytes.HasPrefix(kv.Key, sp.prefix) {
+					add(sp.name, sp.prefix, kv)
+					continue RowLoop
+				}
+			}
+			prefix := acctMap.MatchByPrefix(kv.Key).Prefix
+			add("user/"+url.QueryEscape(string(prefix)), prefix, kv)
+		}
+		if len(rows) < usageScanBatchSize {
+			break
+		}
+		start = rows[len(rows)-1].Key.Next()
+	}
+
+	for _, u := range usage {
+		report.Usage = append(report.Usage, u)
+	}
+	sort.Sort(keyUsagesByBytes(report.Usage))
+	return report, nil
+}
+
+// loadAcctConfigs returns the accounting configs, whose prefixes
+// delimit the namespaces of the user key space.
+func loadAcctConfigs(db *client.KV) (storage.PrefixConfigMap, error) {
+	prefix := engine.KeyConfigAccountingPrefix
+	call := client.ScanCall(prefix, prefix.PrefixEnd(), maxGetResults)
+	if err := db.Run(call); err != nil {
+		return nil, err
+	}
+	var configs []*storage.PrefixConfig
+	for _, kv := range call.Reply.(*proto.ScanResponse).Rows {
+		configs = append(configs, &storage.PrefixConfig{Prefix: bytes.TrimPrefix(kv.Key, prefix)})
+	}
+	return storage.NewPrefixConfigMap(configs)
+}
+
+type keyUsagesByBytes []*keyUsage
+
+func (k keyUsagesByBytes) Len() int      { return len(k) }
+func (k keyUsagesByBytes) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k keyUsagesByBytes) Less(i, j int) bool {
+	if k[i].LiveBytes != k[j].LiveBytes {
+		return k[i].LiveBytes > k[j].LiveBytes
+	}
+	return k[i].Name < k[j].Name
+}
@@ -0,0 +1,84 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// TestComputeUsage verifies that live data is attributed to the
+// system prefixes and to the namespaces of the accounting configs.
+func TestComputeUsage(t *testing.T) {
+	stopper := util.NewStopper()
+	defer stopper.Stop()
+	db, err := BootstrapCluster("cluster-1", engine.NewInMem(proto.Attributes{}, 1<<20), nil, stopper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acct, err := gogoproto.Marshal(&proto.AcctConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := []client.Call{
+		client.PutCall(engine.MakeKey(engine.KeyConfigAccountingPrefix, proto.Key("db1")), acct),
+		client.PutCall(proto.Key("a"), []byte("value")),
+		client.PutCall(proto.Key("db1/a"), []byte("value")),
+		client.PutCall(proto.Key("db1/b"), []byte("value")),
+	}
+	if err := db.Run(calls...); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := computeUsage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := map[string]*keyUsage{}
+	var bytes, count int64
+	for i, u := range report.Usage {
+		usage[u.Name] = u
+		bytes += u.LiveBytes
+		count += u.LiveCount
+		if i > 0 && u.LiveBytes > report.Usage[i-1].LiveBytes {
+			t.Errorf("expected usage sorted by live bytes; got %+v", report.Usage)
+		}
+	}
+	if bytes != report.LiveBytes || count != report.LiveCount {
+		t.Errorf("expected totals %d bytes, %d keys; got %d bytes, %d keys",
+			bytes, count, report.LiveBytes, report.LiveCount)
+	}
+	for _, name := range []string{"meta", "system"} {
+		if u, ok := usage[name]; !ok || u.LiveCount == 0 {
+			t.Errorf("expected %s usage; got %+v", name, u)
+		}
+	}
+	for name, expCount := range map[string]int64{"user/": 1, "user/db1": 2} {
+		if u, ok := usage[name]; !ok || u.LiveCount != expCount {
+			t.Errorf("expected %d keys under %q; got %+v", expCount, name, u)
+		}
+	}
+	value := proto.Value{Bytes: []byte("value")}
+	if u, exp := usage["user/db1"], int64(2*(len("db1/a")+value.Size())); u != nil && u.LiveBytes != exp {
+		t.Errorf("expected %d bytes under db1; got %d", exp, u.LiveBytes)
+	}
+}
@@ -18,20 +18,46 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
 
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
 	gogoproto "github.com/gogo/protobuf/proto"
+	yaml "gopkg.in/yaml.v1"
 )
 
 const (
 	// minRangeMaxBytes is the minimum value for range max bytes.
 	minRangeMaxBytes = 1 << 20
+	// maxCommitCoalescingWindowMicros is the maximum value for the commit
+	// coalescing window, beyond which the latency added to every write
+	// would outweigh any gain in throughput.
+	maxCommitCoalescingWindowMicros = 100000 // 100ms
+	// zoneParamResolved is the query parameter which, if true, makes a
+	// zone config request return the resolved zone config of the prefix.
+	zoneParamResolved = "resolved"
 )
 
+// A resolvedZoneConfig is the zone config which applies to a key
+// prefix once the fields it inherits are filled in, along with the
+// URL query escaped prefix of the zone each field was taken from.
+type resolvedZoneConfig struct {
+	Zone    *proto.ZoneConfig `json:"zone" yaml:"zone"`
+	Sources map[string]string `json:"sources" yaml:"sources"`
+}
+
 // A zoneHandler implements the adminHandler interface.
 type zoneHandler struct {
 	db *client.KV // Key-value database client
@@ -50,16 +76,110 @@ func validateZoneConfig(config gogoproto.Message) error {
 		return util.Errorf("RangeMinBytes %d is greater than or equal to RangeMaxBytes %d",
 			zConfig.RangeMinBytes, zConfig.RangeMaxBytes)
 	}
+	if len(zConfig.PinnedStores) > 0 && zConfig.PinExpiration == 0 {
+		return util.Errorf("pinned stores %v require a pin expiration", zConfig.PinnedStores)
+	}
+	seen := map[proto.StoreID]bool{}
+	for _, storeID := range zConfig.PinnedStores {
+		if seen[storeID] {
+			return util.Errorf("store %d is pinned more than once", storeID)
+		}
+		seen[storeID] = true
+	}
+	if w := zConfig.CommitCoalescingWindowMicros; w < 0 || w > maxCommitCoalescingWindowMicros {
+		return util.Errorf("CommitCoalescingWindowMicros %d must be between 0 and %d",
+			w, maxCommitCoalescingWindowMicros)
+	}
 	return nil
 }
 
+// LoadZoneConfig reads and validates a YAML-encoded zone config from
+// the named file.
+func LoadZoneConfig(path string) (*proto.ZoneConfig, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &proto.ZoneConfig{}
+	if err := yaml.Unmarshal(body, config); err != nil {
+		return nil, util.Errorf("zone config %s has invalid format: %s", path, err)
+	}
+	if err := validateZoneConfig(config); err != nil {
+		return nil, util.Errorf("zone config %s is invalid: %s", path, err)
+	}
+	return config, nil
+}
+
 // Put writes a zone config for the specified key prefix (which is
 // treated as a key). The zone config is parsed from the input
 // "body". The specified body must validly parse into a zone config
 // struct.
 func (zh *zoneHandler) Put(path string, body []byte, r *http.Request) error {
 	return putConfig(zh.db, engine.KeyConfigZonePrefix, &proto.ZoneConfig{},
-		path, body, r, validateZoneConfig)
+		path, body, r, func(config gogoproto.Message) error {
+			return zh.validateInherited(path, config.(*proto.ZoneConfig))
+		})
+}
+
+// validateInherited validates a zone config to be written for the key
+// prefix of path. A zone which inherits is validated with the fields
+// it doesn't set filled in from the zones of shorter prefixes. The
+// zones of longer prefixes which inherit from it aren't revalidated.
+func (zh *zoneHandler) validateInherited(path string, zone *proto.ZoneConfig) error {
+	if !zone.Inherit {
+		return validateZoneConfig(zone)
+	}
+	if path == "/" {
+		return util.Errorf("the default zone config has no zone to inherit from")
+	}
+	prefix := proto.Key(path[1:])
+	zoneMap, err := zh.loadZoneMap(&storage.PrefixConfig{Prefix: prefix, Config: zone})
+	if err != nil {
+		return err
+	}
+	resolved, _ := zoneMap.ResolveZoneConfig(prefix)
+	if err := validateZoneConfig(resolved); err != nil {
+		return util.Errorf("zone config with inherited fields is invalid: %s", err)
+	}
+	return nil
+}
+
+// loadZoneMap scans the zone configs and returns them as a zone config
+// map, without resolving their inheritance. If override is not nil, it
+// replaces the zone config stored for its prefix.
+func (zh *zoneHandler) loadZoneMap(override *storage.PrefixConfig) (storage.PrefixConfigMap, error) {
+	sr := &proto.ScanResponse{}
+	if err := zh.db.Run(client.Call{
+		Args: &proto.ScanRequest{
+			RequestHeader: proto.RequestHeader{
+				Key:    engine.KeyConfigZonePrefix,
+				EndKey: engine.KeyConfigZonePrefix.PrefixEnd(),
+				User:   storage.UserRoot,
+			},
+			MaxResults: maxGetResults,
+		},
+		Reply: sr}); err != nil {
+		return nil, err
+	}
+	if len(sr.Rows) == maxGetResults {
+		log.Warningf("retrieved maximum number of zone configs (%d); some may be missing", maxGetResults)
+	}
+	var configs []*storage.PrefixConfig
+	for _, kv := range sr.Rows {
+		prefix := bytes.TrimPrefix(kv.Key, engine.KeyConfigZonePrefix)
+		if override != nil && override.Prefix.Equal(prefix) {
+			continue
+		}
+		zone := &proto.ZoneConfig{}
+		if err := gogoproto.Unmarshal(kv.Value.Bytes, zone); err != nil {
+			return nil, util.Errorf("unable to unmarshal zone config for prefix %q: %s", prefix, err)
+		}
+		configs = append(configs, &storage.PrefixConfig{Prefix: proto.Key(prefix), Config: zone})
+	}
+	if override != nil {
+		configs = append(configs, override)
+	}
+	return storage.NewPrefixConfigMap(configs)
 }
 
 // Get retrieves the zone configuration for the specified key. If the
@@ -70,11 +190,146 @@ func (zh *zoneHandler) Put(path string, body []byte, r *http.Request) error {
 // configs if "key" is equal to "". The body result contains
 // JSON-formatted output for a listing of keys and JSON-formatted
 // output for retrieval of a zone config.
+//
+// If the resolved query parameter is true, the zone config which
+// applies to the key is returned instead, with its inherited fields
+// filled in, along with the prefix of the zone each field came from.
 func (zh *zoneHandler) Get(path string, r *http.Request) (body []byte, contentType string, err error) {
+	if param := r.URL.Query().Get(zoneParamResolved); len(path) > 0 && len(param) > 0 {
+		var resolved bool
+		if resolved, err = strconv.ParseBool(param); err != nil {
+			return nil, "", util.Errorf("error parsing %s: %s", zoneParamResolved, err)
+		}
+		if resolved {
+			return zh.getResolved(path, r)
+		}
+	}
 	return getConfig(zh.db, engine.KeyConfigZonePrefix, &proto.ZoneConfig{}, path, r)
 }
 
+// getResolved returns the resolved zone config of the key prefix of
+// path.
+func (zh *zoneHandler) getResolved(path string, r *http.Request) (body []byte, contentType string, err error) {
+	zoneMap, err := zh.loadZoneMap(nil)
+	if err != nil {
+		return nil, "", err
+	}
+	zone, sources := zoneMap.ResolveZoneConfig(proto.Key(path[1:]))
+	result := resolvedZoneConfig{Zone: zone, Sources: map[string]string{}}
+	for field, prefix := range sources {
+		result.Sources[field] = url.QueryEscape(string(prefix))
+	}
+	return util.MarshalResponse(r, result, util.AllEncodings)
+}
+
 // Delete removes the zone config specified by key.
 func (zh *zoneHandler) Delete(path string, r *http.Request) error {
 	return deleteConfig(zh.db, engine.KeyConfigZonePrefix, path, r)
 }
+
+// handleZonePlan responds to POST requests holding a zone config for
+// the key prefix of the path with a storage.ZoneChangePlan of the
+// replicas which setting it would add and remove, without setting it.
+// The zone config is validated as it is by Put.
+func (s *adminServer) handleZonePlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	path, err := unescapePath(r.URL.Path, zonePlanPathPrefix)
+	if err != nil || len(path) == 0 {
+		http.Error(w, "no key prefix specified", http.StatusBadRequest)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	zone := &proto.ZoneConfig{}
+	if err := util.UnmarshalRequest(r, body, zone, util.AllEncodings); err != nil {
+		http.Error(w, fmt.Sprintf("config has invalid format: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := s.zone.validateInherited(path, zone); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prefix := proto.Key(path[1:])
+	oldMap, err := s.zone.loadZoneMap(nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	newMap, err := s.zone.loadZoneMap(&storage.PrefixConfig{Prefix: prefix, Config: zone})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var plan *storage.ZoneChangePlan
+	if s.node != nil {
+		if err := s.node.lSender.VisitStores(func(store *storage.Store) error {
+			if plan != nil {
+				return nil
+			}
+			p, err := store.ZoneChangePlan(prefix, oldMap, newMap)
+			plan = &p
+			return err
+		}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if plan == nil {
+		http.Error(w, "node has no stores", http.StatusServiceUnavailable)
+		return
+	}
+	b, contentType, err := util.MarshalResponse(r, plan, util.AllEncodings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(b)
+}
+
+// runZonePlan invokes the zone plan REST API with POST action, the key
+// prefix as path and the zone config read from the yaml file as body,
+// and displays the plan.
+func runZonePlan(ctx *Context, keyPrefix, configFileName string) {
+	body, err := ioutil.ReadFile(configFileName)
+	if err != nil {
+		log.Errorf("unable to read zone config file %q: %s", configFileName, err)
+		return
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s%s/%s", adminScheme, ctx.HTTPHost(), zonePlanPathPrefix, keyPrefix), bytes.NewReader(body))
+	if err != nil {
+		log.Errorf("unable to create request to admin REST endpoint: %s", err)
+		return
+	}
+	req.Header.Add("Content-Type", "text/yaml")
+	req.Header.Add("Accept", "application/json")
+	b, err := sendAdminRequest(ctx, req)
+	if err != nil {
+		log.Errorf("admin REST request failed: %s", err)
+		return
+	}
+	var plan storage.ZoneChangePlan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		log.Errorf("unable to parse admin REST response: %s", err)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "dry run: setting the zone config for key prefix %q would move replicas of %d ranges, "+
+		"copying about %d bytes in %s\n", keyPrefix, plan.RangesMoved, plan.BytesRewritten, plan.EstimatedDuration)
+	if len(plan.Ranges) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 1, 2, ' ', 0)
+	fmt.Fprintf(w, "RaftID\tStartKey\tEndKey\tAdd\tRemove\tSplits\tBytes\n")
+	for _, c := range plan.Ranges {
+		fmt.Fprintf(w, "%d\t%q\t%q\t%d\t%d\t%d\t%d\n", c.RaftID, c.StartKey, c.EndKey,
+			c.AddReplicas, c.RemoveReplicas, c.Splits, c.Bytes)
+	}
+	w.Flush()
+}
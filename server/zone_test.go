@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"testing"
 
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/storage/engine"
@@ -263,7 +264,12 @@ func ExampleZoneContentTypes() {
 	//     }
 	//   ],
 	//   "range_min_bytes": 1048576,
-	//   "range_max_bytes": 67108864
+	//   "range_max_bytes": 67108864,
+	//   "reads_per_second": 0,
+	//   "writes_per_second": 0,
+	//   "pin_expiration": 0,
+	//   "inherit": false,
+	//   "commit_coalescing_window_micros": 0
 	// }
 	// {
 	//   "replica_attrs": [
@@ -287,7 +293,12 @@ func ExampleZoneContentTypes() {
 	//     }
 	//   ],
 	//   "range_min_bytes": 1048576,
-	//   "range_max_bytes": 67108864
+	//   "range_max_bytes": 67108864,
+	//   "reads_per_second": 0,
+	//   "writes_per_second": 0,
+	//   "pin_expiration": 0,
+	//   "inherit": false,
+	//   "commit_coalescing_window_micros": 0
 	// }
 	// replicas:
 	// - attrs: [dc1, ssd]
@@ -303,3 +314,135 @@ func ExampleZoneContentTypes() {
 	// range_min_bytes: 1048576
 	// range_max_bytes: 67108864
 }
+
+// ExampleResolveZone sets a zone config which inherits from the
+// default zone and verifies that its resolved config holds the fields
+// of both, along with their sources.
+func ExampleResolveZone() {
+	_, stopper := startAdminServer()
+	defer stopper.Stop()
+
+	testConfigFn := createTestConfigFile(testZoneConfig)
+	defer os.Remove(testConfigFn)
+	inheritFn := createTestConfigFile("range_max_bytes: 33554432\ninherit: true\n")
+	defer os.Remove(inheritFn)
+
+	RunSetZone(testContext, "", testConfigFn)
+	RunSetZone(testContext, "db1", inheritFn)
+	RunResolveZone(testContext, "db1%2Fusers")
+	// Output:
+	// set zone config for key prefix ""
+	// set zone config for key prefix "db1"
+	// resolved zone config for key prefix "db1%2Fusers":
+	// zone:
+	//   replicas:
+	//   - attrs: [dc1, ssd]
+	//   - attrs: [dc2, ssd]
+	//   - attrs: [dc3, ssd]
+	//   range_min_bytes: 1048576
+	//   range_max_bytes: 33554432
+	// sources:
+	//   range_max_bytes: db1
+	//   range_min_bytes: ""
+	//   replicas: ""
+}
+
+// TestZoneInheritanceValidation verifies that a zone which inherits is
+// validated with its inherited fields and that the default zone may
+// not inherit.
+func TestZoneInheritanceValidation(t *testing.T) {
+	_, stopper := startAdminServer()
+	defer stopper.Stop()
+
+	putZone := func(key, body string) error {
+		req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s%s%s", adminScheme, testContext.Addr, zonePathPrefix, key),
+			bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Content-Type", "text/yaml")
+		_, err = sendAdminRequest(testContext, req)
+		return err
+	}
+	if err := putZone("/", testZoneConfig); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		key, body string
+		expErr    bool
+	}{
+		{"/db1", "range_max_bytes: 33554432\ninherit: true\n", false},
+		{"/db1", "range_max_bytes: 33554432\n", true},
+		{"/db1", "range_min_bytes: 67108864\ninherit: true\n", true},
+		{"/db1/users", "reads_per_second: 10\ninherit: true\n", false},
+		{"/", "range_max_bytes: 33554432\ninherit: true\n", true},
+	}
+	for i, test := range testCases {
+		if err := putZone(test.key, test.body); (err != nil) != test.expErr {
+			t.Errorf("%d: expected error %t putting %q to %s; got %v", i, test.expErr, test.body, test.key, err)
+		}
+	}
+}
+
+// TestLoadZoneConfig verifies that zone configs are loaded from files
+// and validated.
+func TestLoadZoneConfig(t *testing.T) {
+	validFn := createTestConfigFile(testZoneConfig)
+	defer os.Remove(validFn)
+	zone, err := LoadZoneConfig(validFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zone.ReplicaAttrs) != 3 || zone.ReplicaAttrs[0].Attrs[1] != "ssd" || zone.RangeMaxBytes != 67108864 {
+		t.Errorf("unexpected zone config %+v", zone)
+	}
+
+	invalidFn := createTestConfigFile("range_min_bytes: 1048576\nrange_max_bytes: 67108864\n")
+	defer os.Remove(invalidFn)
+	if _, err := LoadZoneConfig(invalidFn); err == nil {
+		t.Error("expected zone config without replicas to be invalid")
+	}
+	if _, err := LoadZoneConfig(validFn + ".missing"); err == nil {
+		t.Error("expected error loading missing file")
+	}
+
+	// Pins must expire and name each store once.
+	for i, test := range []struct {
+		pin    string
+		expErr bool
+	}{
+		{"pinned_stores: [1, 2]\npin_expiration: 1500000000\n", false},
+		{"pinned_stores: [1, 2]\n", true},
+		{"pinned_stores: [1, 1]\npin_expiration: 1500000000\n", true},
+	} {
+		fn := createTestConfigFile(testZoneConfig + test.pin)
+		defer os.Remove(fn)
+		zone, err := LoadZoneConfig(fn)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%d: expected error loading pin %q", i, test.pin)
+			}
+		} else if err != nil {
+			t.Errorf("%d: %s", i, err)
+		} else if len(zone.PinnedStores) != 2 || zone.PinExpiration != 1500000000 {
+			t.Errorf("%d: unexpected pin in zone config %+v", i, zone)
+		}
+	}
+
+	// Commit coalescing windows are bounded.
+	for i, test := range []struct {
+		window int64
+		expErr bool
+	}{
+		{0, false},
+		{2000, false},
+		{-1, true},
+		{maxCommitCoalescingWindowMicros + 1, true},
+	} {
+		fn := createTestConfigFile(fmt.Sprintf("%scommit_coalescing_window_micros: %d\n", testZoneConfig, test.window))
+		defer os.Remove(fn)
+		if _, err := LoadZoneConfig(fn); (err != nil) != test.expErr {
+			t.Errorf("%d: expected error %t loading window %d; got %v", i, test.expErr, test.window, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// AddressingProblemType is the type of an AddressingProblem.
+type AddressingProblemType int
+
+const (
+	// MissingRecord is a range addressing record which doesn't exist.
+	MissingRecord AddressingProblemType = iota
+	// DanglingRecord is a range addressing record which addresses no
+	// range, e.g. one left behind by a failed split.
+	DanglingRecord
+	// StaleRecord is a range addressing record which holds an outdated
+	// or undecodable range descriptor.
+	StaleRecord
+)
+
+// An AddressingProblem describes a meta1 or meta2 range addressing
+// record which doesn't match the range descriptors it indexes.
+type AddressingProblem struct {
+	Type AddressingProblemType
+	// Key is the key of the addressing record.
+	Key proto.Key
+	// Record is the descriptor held by the record; nil if the record is
+	// missing or can't be decoded.
+	Record *proto.RangeDescriptor
+	// Desc is the descriptor of the range the record should address;
+	// nil if the record is dangling.
+	Desc *proto.RangeDescriptor
+}
+
+func (p AddressingProblem) String() string {
+	switch p.Type {
+	case MissingRecord:
+		return fmt.Sprintf("%s: missing record for range %d %s-%s",
+			p.Key, p.Desc.RaftID, p.Desc.StartKey, p.Desc.EndKey)
+	case DanglingRecord:
+		if p.Record == nil {
+			return fmt.Sprintf("%s: dangling undecodable record", p.Key)
+		}
+		return fmt.Sprintf("%s: dangling record for range %d %s-%s",
+			p.Key, p.Record.RaftID, p.Record.StartKey, p.Record.EndKey)
+	default:
+		if p.Record == nil {
+			return fmt.Sprintf("%s: undecodable record for range %d %s-%s",
+				p.Key, p.Desc.RaftID, p.Desc.StartKey, p.Desc.EndKey)
+		}
+		return fmt.Sprintf("%s: stale record for range %d %s-%s; range %d is %s-%s",
+			p.Key, p.Record.RaftID, p.Record.StartKey, p.Record.EndKey,
+			p.Desc.RaftID, p.Desc.StartKey, p.Desc.EndKey)
+	}
+}
+
+// addressingProblemsByKey sorts addressing problems by record key.
+type addressingProblemsByKey []AddressingProblem
+
+func (p addressingProblemsByKey) Len() int           { return len(p) }
+func (p addressingProblemsByKey) Less(i, j int) bool { return p[i].Key.Less(p[j].Key) }
+func (p addressingProblemsByKey) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// CheckRangeAddressing cross-checks the meta1 and meta2 range
+// addressing records against the range descriptors, which are found
+// by following the chain of ranges from KeyMin, and returns a problem
+// for each record which is missing, dangling or stale, in key order. If repair is
+// true, the problems are fixed within the same transaction: missing
+// and stale records are written and dangling ones are deleted. This
+// is meant for recovery from failures which left the addressing
+// records inconsistent with the ranges, such as partial splits.
+func CheckRangeAddressing(db *client.KV, repair bool) ([]AddressingProblem, error) {
+	var problems []AddressingProblem
+	txnOpts := &client.TransactionOptions{Name: "check range addressing"}
+	err := db.RunTransaction(txnOpts, func(txn *client.Txn) error {
+		problems = nil
+		expected, err := expectedRangeAddressing(txn)
+		if err != nil {
+			return err
+		}
+
+		call := client.ScanCall(engine.KeyMetaPrefix, engine.KeyMetaMax, 0)
+		if err := txn.Run(call); err != nil {
+			return util.Errorf("unable to scan range addressing records: %s", err)
+		}
+		for _, kv := range call.Reply.(*proto.ScanResponse).Rows {
+			problem := AddressingProblem{Key: kv.Key}
+			record := &proto.RangeDescriptor{}
+			if err := gogoproto.Unmarshal(kv.Value.Bytes, record); err == nil {
+				problem.Record = record
+			}
+			desc, ok := expected[string(kv.Key)]
+			delete(expected, string(kv.Key))
+			if !ok {
+				problem.Type = DanglingRecord
+			} else if data, err := gogoproto.Marshal(desc); err != nil {
+				return err
+			} else if !bytes.Equal(data, kv.Value.Bytes) {
+				problem.Type, problem.Desc = StaleRecord, desc
+			} else {
+				continue
+			}
+			problems = append(problems, problem)
+		}
+		for key, desc := range expected {
+			problems = append(problems, AddressingProblem{Type: MissingRecord, Key: proto.Key(key), Desc: desc})
+		}
+		sort.Sort(addressingProblemsByKey(problems))
+
+		if !repair {
+			return nil
+		}
+		for _, p := range problems {
+			if p.Type == DanglingRecord {
+				txn.Prepare(client.DeleteCall(p.Key))
+			} else {
+				txn.Prepare(client.PutProtoCall(p.Key, p.Desc))
+			}
+		}
+		return txn.Flush()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
+// expectedRangeAddressing reads the descriptor of every range, in key
+// order, and returns the range addressing records which should exist
+// for them, keyed by record key.
+func expectedRangeAddressing(txn *client.Txn) (map[string]*proto.RangeDescriptor, error) {
+	expected := map[string]*proto.RangeDescriptor{}
+	collect := func(calls []client.Call, key proto.Key, desc *proto.RangeDescriptor) []client.Call {
+		expected[string(key)] = desc
+		return calls
+	}
+	for key := engine.KeyMin; key.Less(engine.KeyMax); {
+		call := client.GetCall(engine.RangeDescriptorKey(key))
+		if err := txn.Run(call); err != nil {
+			return nil, util.Errorf("unable to read descriptor of range starting at %s: %s", key, err)
+		}
+		reply := call.Reply.(*proto.GetResponse)
+		if reply.Value == nil {
+			return nil, util.Errorf("no range descriptor found at %s", key)
+		}
+		desc := &proto.RangeDescriptor{}
+		if err := gogoproto.Unmarshal(reply.Value.Bytes, desc); err != nil {
+			return nil, util.Errorf("unable to decode descriptor of range starting at %s: %s", key, err)
+		}
+		if !desc.StartKey.Equal(key) || !key.Less(desc.EndKey) {
+			return nil, util.Errorf("descriptor of range %d found at %s spans %s-%s",
+				desc.RaftID, key, desc.StartKey, desc.EndKey)
+		}
+		if _, err := updateRangeAddressing(nil, desc, collect); err != nil {
+			return nil, err
+		}
+		key = desc.EndKey
+	}
+	return expected, nil
+}
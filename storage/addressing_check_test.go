@@ -0,0 +1,87 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestCheckRangeAddressing verifies that missing, dangling and stale
+// range addressing records are found and repaired.
+func TestCheckRangeAddressing(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	aDesc, bDesc, err := createSplitRanges(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := store.DB()
+	if problems, err := storage.CheckRangeAddressing(db, false); err != nil {
+		t.Fatal(err)
+	} else if len(problems) != 0 {
+		t.Fatalf("expected no problems; got %v", problems)
+	}
+
+	// Remove the record of the first range, record a range which
+	// doesn't exist and make the record of the second range stale.
+	missingKey := engine.RangeMetaKey(proto.Key("b"))
+	danglingKey := engine.RangeMetaKey(proto.Key("x"))
+	staleKey := engine.RangeMetaKey(engine.KeyMax)
+	dangling := &proto.RangeDescriptor{RaftID: 10, StartKey: proto.Key("b"), EndKey: proto.Key("x")}
+	if err := db.Run(client.DeleteCall(missingKey), client.PutProtoCall(danglingKey, dangling),
+		client.PutProtoCall(staleKey, aDesc)); err != nil {
+		t.Fatal(err)
+	}
+
+	expProblems := []storage.AddressingProblem{
+		{Type: storage.MissingRecord, Key: missingKey, Desc: aDesc},
+		{Type: storage.DanglingRecord, Key: danglingKey, Record: dangling},
+		{Type: storage.StaleRecord, Key: staleKey, Record: aDesc, Desc: bDesc},
+	}
+	for _, repair := range []bool{false, true} {
+		problems, err := storage.CheckRangeAddressing(db, repair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(problems) != len(expProblems) {
+			t.Fatalf("repair %t: expected %d problems; got %v", repair, len(expProblems), problems)
+		}
+		for i, p := range problems {
+			exp := expProblems[i]
+			if p.Type != exp.Type || !p.Key.Equal(exp.Key) ||
+				(exp.Record != nil) != (p.Record != nil) || (exp.Desc != nil) != (p.Desc != nil) ||
+				(exp.Record != nil && p.Record.RaftID != exp.Record.RaftID) ||
+				(exp.Desc != nil && p.Desc.RaftID != exp.Desc.RaftID) {
+				t.Errorf("repair %t: %d: expected %s; got %s", repair, i, exp, p)
+			}
+		}
+	}
+
+	// The repair leaves the records consistent again.
+	if problems, err := storage.CheckRangeAddressing(db, false); err != nil {
+		t.Fatal(err)
+	} else if len(problems) != 0 {
+		t.Fatalf("expected no problems after repair; got %v", problems)
+	}
+}
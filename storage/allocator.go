@@ -20,6 +20,7 @@ package storage
 
 import (
 	"math/rand"
+	"sync"
 
 	"github.com/cockroachdb/cockroach/proto"
 	"github.com/cockroachdb/cockroach/util"
@@ -33,6 +34,9 @@ import (
 type allocator struct {
 	storeFinder FindStoreFunc
 	rand        rand.Rand
+
+	mu         sync.Mutex
+	thresholds BalanceThresholds
 }
 
 // newAllocator creates a new allocator.
@@ -40,8 +44,57 @@ func newAllocator(f FindStoreFunc) *allocator {
 	return &allocator{
 		storeFinder: f,
 		// TODO(bdarnell): use a real random seed.
-		rand: *rand.New(rand.NewSource(0)),
+		rand:       *rand.New(rand.NewSource(0)),
+		thresholds: defaultBalanceThresholds,
+	}
+}
+
+// balanceThresholds returns the thresholds beyond which stores are
+// considered overfull.
+func (a *allocator) balanceThresholds() BalanceThresholds {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.thresholds
+}
+
+// setBalanceThresholds sets the thresholds beyond which stores are
+// considered overfull.
+func (a *allocator) setBalanceThresholds(thresholds BalanceThresholds) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.thresholds = thresholds
+}
+
+// balance returns a report of the balance of the stores with the
+// supplied attributes which aren't suspect or nearly full.
+func (a *allocator) balance(required proto.Attributes) (BalanceReport, error) {
+	stores, err := a.storeFinder(required)
+	if err != nil {
+		return BalanceReport{}, err
+	}
+	var healthy []*StoreDescriptor
+	for _, s := range stores {
+		if !s.Suspect && !s.NearlyFull {
+			healthy = append(healthy, s)
+		}
+	}
+	return computeBalance(healthy, a.balanceThresholds()), nil
+}
+
+// findStore returns the descriptor of the store with the supplied ID,
+// bypassing the allocation policy; it's used to place replicas on
+// pinned stores. Returns an error if the store isn't available.
+func (a *allocator) findStore(storeID proto.StoreID) (*StoreDescriptor, error) {
+	stores, err := a.storeFinder(proto.Attributes{})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stores {
+		if s.StoreID == storeID {
+			return s, nil
+		}
 	}
+	return nil, util.Errorf("store %d is not available", storeID)
 }
 
 // allocate returns a suitable store based on the supplied
@@ -49,6 +102,9 @@ func newAllocator(f FindStoreFunc) *allocator {
 // error. It uses the allocator's StoreFinder to select the set of
 // available stores matching attributes for missing replicas and picks
 // using randomly weighted selection based on available capacities.
+// Suspect and nearly full stores are never picked, and stores which
+// are overfull against the allocator's balance thresholds only if no
+// other store is available.
 func (a *allocator) allocate(required proto.Attributes, existingReplicas []proto.Replica) (
 	*StoreDescriptor, error) {
 	// Get a set of current nodes -- we never want to allocate on an existing node.
@@ -62,15 +118,33 @@ func (a *allocator) allocate(required proto.Attributes, existingReplicas []proto
 		return nil, err
 	}
 
-	// Randomly pick a node weighted by capacity.
-	var candidates []*StoreDescriptor
-	var capacityTotal float64
+	var healthy []*StoreDescriptor
 	for _, s := range stores {
-		if _, ok := usedNodes[s.Node.NodeID]; !ok {
+		if !s.Suspect && !s.NearlyFull {
+			healthy = append(healthy, s)
+		}
+	}
+	report := computeBalance(healthy, a.balanceThresholds())
+	var candidates, overfull []*StoreDescriptor
+	for _, s := range healthy {
+		if _, ok := usedNodes[s.Node.NodeID]; ok {
+			continue
+		}
+		if report.overfull(s.StoreID) {
+			overfull = append(overfull, s)
+		} else {
 			candidates = append(candidates, s)
-			capacityTotal += s.Capacity.PercentAvail()
 		}
 	}
+	if len(candidates) == 0 {
+		candidates = overfull
+	}
+
+	// Randomly pick a node weighted by capacity.
+	var capacityTotal float64
+	for _, c := range candidates {
+		capacityTotal += c.Capacity.PercentAvail()
+	}
 
 	var capacitySeen float64
 	targetCapacity := a.rand.Float64() * capacityTotal
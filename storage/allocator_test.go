@@ -295,3 +295,94 @@ func TestExistingReplica(t *testing.T) {
 		t.Errorf("expected result to have node 3 and store 4: %+v", result)
 	}
 }
+
+func TestFindPinnedStore(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	var a = allocator{
+		storeFinder: sameDCStores,
+		rand:        *rand.New(rand.NewSource(0)),
+	}
+	result, err := a.findStore(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Node.NodeID != 2 || result.StoreID != 3 {
+		t.Errorf("expected result to have node 2 and store 3: %+v", result)
+	}
+	if _, err := a.findStore(10); err == nil {
+		t.Error("expected error finding missing store")
+	}
+}
+
+func TestSuspectStoreNotAllocated(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	suspectStore := func(a proto.Attributes) ([]*StoreDescriptor, error) {
+		stores, err := singleStore(a)
+		for _, s := range stores {
+			s.Suspect = true
+		}
+		return stores, err
+	}
+	var a = allocator{
+		storeFinder: suspectStore,
+		rand:        *rand.New(rand.NewSource(0)),
+	}
+	if result, err := a.allocate(simpleZoneConfig.ReplicaAttrs[0], []proto.Replica{}); err == nil {
+		t.Errorf("expected suspect store not to be allocated; got %+v", result)
+	}
+}
+
+func TestNearlyFullStoreNotAllocated(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	nearlyFullStore := func(a proto.Attributes) ([]*StoreDescriptor, error) {
+		stores, err := singleStore(a)
+		for _, s := range stores {
+			s.NearlyFull = true
+		}
+		return stores, err
+	}
+	var a = allocator{
+		storeFinder: nearlyFullStore,
+		rand:        *rand.New(rand.NewSource(0)),
+	}
+	if result, err := a.allocate(simpleZoneConfig.ReplicaAttrs[0], []proto.Replica{}); err == nil {
+		t.Errorf("expected nearly full store not to be allocated; got %+v", result)
+	}
+}
+
+// TestOverfullStoreAvoided verifies that replicas aren't allocated to
+// stores whose range counts exceed the mean by more than the balance
+// threshold, unless no other store is available.
+func TestOverfullStoreAvoided(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rangeCounts := map[proto.StoreID]int{1: 10, 2: 1}
+	unbalancedStores := func(a proto.Attributes) ([]*StoreDescriptor, error) {
+		stores, err := sameDCStores(a)
+		for _, s := range stores {
+			s.RangeCount = rangeCounts[s.StoreID]
+		}
+		return stores, err
+	}
+	var a = allocator{
+		storeFinder: unbalancedStores,
+		rand:        *rand.New(rand.NewSource(0)),
+		thresholds:  BalanceThresholds{RangeCount: 0.5, Bytes: 0.5},
+	}
+	for i := 0; i < 10; i++ {
+		result, err := a.allocate(simpleZoneConfig.ReplicaAttrs[0], []proto.Replica{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.StoreID != 2 {
+			t.Fatalf("expected store 2 to be allocated; got store %d", result.StoreID)
+		}
+	}
+	// Once the other store is in use, the overfull one is allocated.
+	result, err := a.allocate(simpleZoneConfig.ReplicaAttrs[0], []proto.Replica{{NodeID: 2, StoreID: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.StoreID != 1 {
+		t.Errorf("expected store 1 to be allocated; got store %d", result.StoreID)
+	}
+}
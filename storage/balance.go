@@ -0,0 +1,144 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+const (
+	// DefaultRangeCountThreshold is the default fraction by which the
+	// range count of a store may exceed the mean before the allocator
+	// considers it overfull.
+	DefaultRangeCountThreshold = 0.05
+	// DefaultBytesThreshold is the default fraction by which the bytes
+	// used by a store may exceed the mean before the allocator considers
+	// it overfull.
+	DefaultBytesThreshold = 0.1
+)
+
+// BalanceThresholds are the fractions by which the range count and the
+// bytes used of a store may exceed the means of the stores eligible for
+// a replica before the allocator considers it overfull and places the
+// replica elsewhere, if possible. Lower thresholds keep the stores
+// closer to balanced at the cost of moving more replicas.
+type BalanceThresholds struct {
+	RangeCount float64
+	Bytes      float64
+}
+
+// defaultBalanceThresholds are applied by the allocator until the
+// cluster's thresholds, a proto.BalanceConfig stored at
+// engine.KeyConfigBalance, are set and gossiped.
+var defaultBalanceThresholds = BalanceThresholds{
+	RangeCount: DefaultRangeCountThreshold,
+	Bytes:      DefaultBytesThreshold,
+}
+
+// A StoreBalance describes how far the range count and bytes used of a
+// store deviate from the means of the stores, as fractions of the
+// means.
+type StoreBalance struct {
+	StoreID             proto.StoreID
+	NodeID              proto.NodeID
+	RangeCount          int
+	Bytes               int64
+	RangeCountDeviation float64
+	BytesDeviation      float64
+	// Overfull is set if either deviation exceeds its threshold.
+	Overfull bool
+}
+
+// A BalanceReport describes the balance of a set of stores against the
+// thresholds which the allocator applies to them.
+type BalanceReport struct {
+	Thresholds     BalanceThresholds
+	MeanRangeCount float64
+	MeanBytes      float64
+	// MaxRangeCountDeviation and MaxBytesDeviation are the largest
+	// deviations of any store above the means.
+	MaxRangeCountDeviation float64
+	MaxBytesDeviation      float64
+	// Stores are sorted by store ID.
+	Stores []StoreBalance
+}
+
+// storeBytes returns the number of bytes used by the store.
+func storeBytes(s *StoreDescriptor) int64 {
+	return s.Capacity.Capacity - s.Capacity.Available
+}
+
+// deviation returns the fraction by which v exceeds mean, which is
+// negative if v is below it.
+func deviation(v, mean float64) float64 {
+	if mean == 0 {
+		return 0
+	}
+	return (v - mean) / mean
+}
+
+// computeBalance returns a report of the balance of the stores against
+// the thresholds.
+func computeBalance(stores []*StoreDescriptor, thresholds BalanceThresholds) BalanceReport {
+	report := BalanceReport{Thresholds: thresholds, Stores: []StoreBalance{}}
+	if len(stores) == 0 {
+		return report
+	}
+	for _, s := range stores {
+		report.MeanRangeCount += float64(s.RangeCount)
+		report.MeanBytes += float64(storeBytes(s))
+	}
+	report.MeanRangeCount /= float64(len(stores))
+	report.MeanBytes /= float64(len(stores))
+	for _, s := range stores {
+		b := StoreBalance{
+			StoreID:             s.StoreID,
+			NodeID:              s.Node.NodeID,
+			RangeCount:          s.RangeCount,
+			Bytes:               storeBytes(s),
+			RangeCountDeviation: deviation(float64(s.RangeCount), report.MeanRangeCount),
+			BytesDeviation:      deviation(float64(storeBytes(s)), report.MeanBytes),
+		}
+		b.Overfull = b.RangeCountDeviation > thresholds.RangeCount || b.BytesDeviation > thresholds.Bytes
+		if b.RangeCountDeviation > report.MaxRangeCountDeviation {
+			report.MaxRangeCountDeviation = b.RangeCountDeviation
+		}
+		if b.BytesDeviation > report.MaxBytesDeviation {
+			report.MaxBytesDeviation = b.BytesDeviation
+		}
+		report.Stores = append(report.Stores, b)
+	}
+	sort.Sort(storeBalancesByID(report.Stores))
+	return report
+}
+
+// overfull returns whether the store is overfull in the report.
+func (r BalanceReport) overfull(storeID proto.StoreID) bool {
+	for _, b := range r.Stores {
+		if b.StoreID == storeID {
+			return b.Overfull
+		}
+	}
+	return false
+}
+
+type storeBalancesByID []StoreBalance
+
+func (s storeBalancesByID) Len() int           { return len(s) }
+func (s storeBalancesByID) Less(i, j int) bool { return s[i].StoreID < s[j].StoreID }
+func (s storeBalancesByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,61 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestComputeBalance verifies the means and deviations of a balance
+// report and that stores beyond the thresholds are overfull.
+func TestComputeBalance(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store := func(id int, rangeCount int, used int64) *StoreDescriptor {
+		return &StoreDescriptor{
+			StoreID:    proto.StoreID(id),
+			Node:       gossip.NodeDescriptor{NodeID: proto.NodeID(id)},
+			Capacity:   engine.StoreCapacity{Capacity: 1000, Available: 1000 - used},
+			RangeCount: rangeCount,
+		}
+	}
+	stores := []*StoreDescriptor{store(3, 30, 100), store(1, 10, 100), store(2, 20, 400)}
+	report := computeBalance(stores, BalanceThresholds{RangeCount: 0.25, Bytes: 0.5})
+	if report.MeanRangeCount != 20 || report.MeanBytes != 200 {
+		t.Fatalf("expected means of 20 ranges and 200 bytes; got %+v", report)
+	}
+	if math.Abs(report.MaxRangeCountDeviation-0.5) > 1e-9 || math.Abs(report.MaxBytesDeviation-1) > 1e-9 {
+		t.Errorf("expected max deviations of 0.5 and 1; got %+v", report)
+	}
+	expOverfull := []bool{false, true, true}
+	for i, b := range report.Stores {
+		if b.StoreID != proto.StoreID(i+1) {
+			t.Errorf("%d: expected stores sorted by ID; got store %d", i, b.StoreID)
+		}
+		if b.Overfull != expOverfull[i] {
+			t.Errorf("store %d: expected overfull %t; got %+v", b.StoreID, expOverfull[i], b)
+		}
+	}
+
+	if report := computeBalance(nil, BalanceThresholds{}); len(report.Stores) != 0 || report.MeanRangeCount != 0 {
+		t.Errorf("expected empty report; got %+v", report)
+	}
+}
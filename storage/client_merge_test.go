@@ -182,6 +182,63 @@ func TestStoreRangeMergeWithData(t *testing.T) {
 	}
 }
 
+// TestStoreRangeMergeHistory verifies that splits and merges are
+// recorded in the histories of the ranges taking part in them.
+func TestStoreRangeMergeHistory(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	aDesc, bDesc, err := createSplitRanges(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	split := proto.RangeHistoryEvent{
+		Type:         proto.SPLIT,
+		ParentRaftID: aDesc.RaftID,
+		ChildRaftID:  bDesc.RaftID,
+		Key:          proto.Key("b"),
+	}
+	// The new range inherits the history of the original.
+	for _, key := range []string{"a", "c"} {
+		hist, err := store.LookupRange(proto.Key(key), nil).GetHistory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hist.Events) != 1 {
+			t.Fatalf("%s: expected one event; got %+v", key, hist.Events)
+		}
+		event := hist.Events[0]
+		if event.Timestamp.Equal(proto.ZeroTimestamp) {
+			t.Errorf("%s: expected split timestamp to be set", key)
+		}
+		event.Timestamp = proto.ZeroTimestamp
+		if !reflect.DeepEqual(event, split) {
+			t.Errorf("%s: expected %+v; got %+v", key, split, event)
+		}
+	}
+
+	args, reply := adminMergeArgs(engine.KeyMin, 1, store.StoreID())
+	if err := store.ExecuteCmd(args, reply); err != nil {
+		t.Fatal(err)
+	}
+	hist, err := store.LookupRange(proto.Key("a"), nil).GetHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist.Events) != 2 {
+		t.Fatalf("expected split and merge events; got %+v", hist.Events)
+	}
+	merge := hist.Events[1]
+	if merge.Type != proto.MERGE || merge.ParentRaftID != aDesc.RaftID ||
+		merge.ChildRaftID != bDesc.RaftID || !merge.Key.Equal(proto.Key("b")) {
+		t.Errorf("unexpected merge event %+v", merge)
+	}
+	if merge.Timestamp.Less(hist.Events[0].Timestamp) {
+		t.Errorf("expected merge at %s to follow split at %s", merge.Timestamp, hist.Events[0].Timestamp)
+	}
+}
+
 // TestStoreRangeMergeLastRange verifies that merging the last range is a noop.
 func TestStoreRangeMergeLastRange(t *testing.T) {
 	defer leaktest.AfterTest(t)
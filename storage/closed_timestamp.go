@@ -0,0 +1,171 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"math/rand"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// DefaultClosedTimestampLag is the default for how far the closed
+// timestamp proposed by a range leader trails its clock. Writes
+// proposed at or below the closed timestamp have their timestamp moved
+// forward, so the lag must comfortably exceed the time a write takes
+// to be proposed.
+const DefaultClosedTimestampLag = 3 * time.Second
+
+// closedTimestampPublications is the number of times per closed
+// timestamp lag that the leader of a range without writes publishes
+// its closed timestamp. Followers' closed timestamps are thus expected
+// to trail their clocks by between 1 and 1+1/closedTimestampPublications
+// times the lag.
+const closedTimestampPublications = 2
+
+// closeTimestamp returns the closed timestamp to propose with the next
+// command: the leader's clock less the store's closed timestamp lag,
+// never regressing below a closed timestamp previously proposed or
+// applied. A zero timestamp is returned if the store doesn't close
+// timestamps. proposeMu must be held.
+func (r *Range) closeTimestamp() proto.Timestamp {
+	lag := r.rm.closedTimestampLag()
+	if lag <= 0 {
+		return proto.ZeroTimestamp
+	}
+	closed := proto.Timestamp{WallTime: r.rm.Clock().PhysicalNow() - lag.Nanoseconds()}
+	closed.Forward(r.proposedClosedTS)
+	closed.Forward(r.ClosedTimestamp())
+	r.proposedClosedTS = closed
+	return closed
+}
+
+// ClosedTimestamp returns the timestamp at or below which no command
+// applied by this replica from now on may write. All writes at or
+// below it have been applied, so reads up to it may be served without
+// consulting the leader.
+func (r *Range) ClosedTimestamp() proto.Timestamp {
+	r.RLock()
+	defer r.RUnlock()
+	return r.closedTS
+}
+
+// staleReadTimestamp returns the timestamp at which a follower serves
+// a bounded-staleness read: the read's own timestamp if it has been
+// closed, or else the closed timestamp, provided it lies within the
+// read's maximum staleness. Otherwise, a NotLeaderError is returned to
+// redirect the read to the leader.
+func (r *Range) staleReadTimestamp(header *proto.RequestHeader) (proto.Timestamp, error) {
+	closed := r.ClosedTimestamp()
+	if closed.Equal(proto.ZeroTimestamp) {
+		return proto.ZeroTimestamp, &proto.NotLeaderError{}
+	}
+	if !closed.Less(header.Timestamp) {
+		return header.Timestamp, nil
+	}
+	if closed.Less(header.Timestamp.Add(-header.MaxStaleness, 0)) {
+		return proto.ZeroTimestamp, &proto.NotLeaderError{}
+	}
+	return closed, nil
+}
+
+// CommittedWrites returns the versions of the keys of the span from
+// start to end within the range which were written at a timestamp in
+// (since, until]. Until must not exceed the range's closed timestamp:
+// as no command applied from now on may write at or below it, the
+// versions are final, save for intents. A WriteIntentError is returned
+// if one is encountered, and the call should be retried once it's
+// been resolved. Versions are returned in key order and, for each key,
+// oldest first; deletions have Value.Deleted set.
+func (r *Range) CommittedWrites(start, end proto.Key, since, until proto.Timestamp) ([]engine.MVCCVersion, error) {
+	if closed := r.ClosedTimestamp(); closed.Less(until) {
+		return nil, util.Errorf("range %d: %s exceeds the closed timestamp %s", r.Desc().RaftID, until, closed)
+	}
+	desc := r.Desc()
+	if bytes.Compare(start, desc.StartKey) < 0 {
+		start = desc.StartKey
+	}
+	if bytes.Compare(end, desc.EndKey) > 0 {
+		end = desc.EndKey
+	}
+	if bytes.Compare(start, end) >= 0 || !since.Less(until) {
+		return nil, nil
+	}
+	var versions []engine.MVCCVersion
+	first := 0 // Index of the first version of the current key
+	err := engine.MVCCIterateIncremental(r.rm.Engine(), start, end, since, until, func(v engine.MVCCVersion) (bool, error) {
+		// Versions of a key are iterated newest first, and reversed
+		// once the next key is reached.
+		if n := len(versions); n > 0 && !versions[n-1].Key.Equal(v.Key) {
+			reverseVersions(versions[first:])
+			first = n
+		}
+		versions = append(versions, v)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	reverseVersions(versions[first:])
+	return versions, nil
+}
+
+// reverseVersions reverses the order of the versions in place.
+func reverseVersions(versions []engine.MVCCVersion) {
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+}
+
+// maybePublishClosedTimestamp proposes a Raft command which carries
+// nothing but a newly closed timestamp if this replica is the leader
+// and the range hasn't proposed a command closing a recent enough
+// timestamp. As leader leases aren't renewed yet, the holder of the
+// most recent lease, which is requested upon election, publishes.
+func (r *Range) maybePublishClosedTimestamp() {
+	lag := r.rm.closedTimestampLag()
+	if lag <= 0 || !r.IsLeader() || r.rm.ReadOnly() {
+		return
+	}
+	if l := r.getLease(); l == nil || l.RaftNodeID != uint64(r.rm.RaftNodeID()) {
+		return
+	}
+	if !r.stopper.StartTask() {
+		return
+	}
+	defer r.stopper.FinishTask()
+	r.proposeMu.Lock()
+	defer r.proposeMu.Unlock()
+	wallTime := r.rm.Clock().PhysicalNow()
+	interval := lag / closedTimestampPublications
+	if target := wallTime - (lag + interval).Nanoseconds(); target < r.proposedClosedTS.WallTime {
+		return
+	}
+	cmd := proto.InternalRaftCommand{
+		RaftID:          r.Desc().RaftID,
+		ClosedTimestamp: r.closeTimestamp(),
+	}
+	idKey := makeCmdIDKey(proto.ClientCmdID{
+		WallTime: wallTime,
+		Random:   rand.Int63(),
+	})
+	// A publication which fails is superseded by the next one, so the
+	// error channel is not waited on.
+	r.proposeRaftCommand(idKey, cmd)
+}
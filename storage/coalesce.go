@@ -0,0 +1,142 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// A proposalCoalescer holds the Raft proposals of a range for the
+// commit coalescing window of its zone, so that the commands proposed
+// within a window are submitted to Raft as a single command, appended
+// to the log and applied in one write rather than one each. This
+// trades up to a window of latency for throughput on prefixes taking
+// bulk writes. Commands are coalesced in the order they're proposed,
+// so that they enter the log in closed timestamp order.
+type proposalCoalescer struct {
+	sync.Mutex
+	window  time.Duration       // Zero if proposals aren't held
+	pending []coalescedProposal // Proposals held until the window ends
+}
+
+// A coalescedProposal is a proposal held by a proposalCoalescer, with
+// the channel to which the result of its submission is forwarded.
+type coalescedProposal struct {
+	idKey cmdIDKey
+	cmd   proto.InternalRaftCommand
+	ch    chan error
+}
+
+// SetCommitCoalescingWindow sets the time for which the range holds
+// its Raft proposals, as specified by its zone config. Zero proposes
+// each command immediately.
+func (r *Range) SetCommitCoalescingWindow(window time.Duration) {
+	r.coalescer.Lock()
+	defer r.coalescer.Unlock()
+	r.coalescer.window = window
+}
+
+// proposeRaftCommand submits the command to Raft, or, if the range has
+// a commit coalescing window, holds it until the window which began
+// with the first held proposal ends. The returned channel receives the
+// result of the submission.
+func (r *Range) proposeRaftCommand(idKey cmdIDKey, cmd proto.InternalRaftCommand) <-chan error {
+	c := &r.coalescer
+	c.Lock()
+	defer c.Unlock()
+	if c.window == 0 && len(c.pending) == 0 {
+		return r.rm.ProposeRaftCommand(idKey, cmd)
+	}
+	ch := make(chan error, 1)
+	c.pending = append(c.pending, coalescedProposal{idKey: idKey, cmd: cmd, ch: ch})
+	if len(c.pending) == 1 {
+		time.AfterFunc(c.window, r.flushProposals)
+	}
+	return ch
+}
+
+// flushProposals submits the held proposals to Raft, coalesced into a
+// single command, and forwards the result of its submission to each. A
+// command with a commit trigger, which may change the range's replicas
+// or split or merge it, must be applied on its own and is submitted
+// alone, after the proposals held before it and before those held
+// after it. The proposals fail if the range is stopping.
+func (r *Range) flushProposals() {
+	c := &r.coalescer
+	c.Lock()
+	defer c.Unlock()
+	pending := c.pending
+	c.pending = nil
+	if !r.stopper.StartTask() {
+		for _, p := range pending {
+			p.ch <- util.Errorf("%s is stopping", r)
+		}
+		return
+	}
+	defer r.stopper.FinishTask()
+	var batch []coalescedProposal
+	for _, p := range pending {
+		if coalescable(p.cmd) {
+			batch = append(batch, p)
+			continue
+		}
+		r.submitProposals(batch)
+		r.submitProposals([]coalescedProposal{p})
+		batch = nil
+	}
+	r.submitProposals(batch)
+}
+
+// submitProposals submits the proposals to Raft as a single command and
+// forwards the result of its submission to each.
+func (r *Range) submitProposals(proposals []coalescedProposal) {
+	var raftChan <-chan error
+	switch len(proposals) {
+	case 0:
+		return
+	case 1:
+		raftChan = r.rm.ProposeRaftCommand(proposals[0].idKey, proposals[0].cmd)
+	default:
+		cmd := proto.InternalRaftCommand{RaftID: r.Desc().RaftID}
+		for _, p := range proposals {
+			cmd.Coalesced = append(cmd.Coalesced, proto.CoalescedRaftCommand{CmdIDKey: string(p.idKey), Cmd: p.cmd})
+		}
+		idKey := makeCmdIDKey(proto.ClientCmdID{
+			WallTime: r.rm.Clock().PhysicalNow(),
+			Random:   rand.Int63(),
+		})
+		raftChan = r.rm.ProposeRaftCommand(idKey, cmd)
+	}
+	go func() {
+		err := <-raftChan
+		for _, p := range proposals {
+			p.ch <- err
+		}
+	}()
+}
+
+// coalescable returns whether the command may be coalesced with others.
+// Commands with commit triggers are applied as barriers, on their own,
+// and replica changes must moreover be understood by Raft.
+func coalescable(cmd proto.InternalRaftCommand) bool {
+	et, ok := cmd.Cmd.GetValue().(*proto.EndTransactionRequest)
+	return !ok || et.InternalCommitTrigger == nil
+}
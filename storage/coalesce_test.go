@@ -0,0 +1,132 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// TestRangeCommitCoalescing verifies that the commit coalescing window
+// of a zone config is applied to its ranges, that proposals are held
+// for the window and that the writes proposed within a window are
+// committed together, as a single raft command, rather than a window
+// apart.
+func TestRangeCommitCoalescing(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	const window = 20 * time.Millisecond
+	zoneMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{CommitCoalescingWindowMicros: int64(window / time.Microsecond)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.gossip.AddInfo(gossip.KeyConfigZone, zoneMap, 0*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	util.SucceedsWithin(t, time.Second, func() error {
+		tc.rng.coalescer.Lock()
+		defer tc.rng.coalescer.Unlock()
+		if w := tc.rng.coalescer.window; w != window {
+			return util.Errorf("expected commit coalescing window %s; got %s", window, w)
+		}
+		return nil
+	})
+
+	put := func(key string) error {
+		pArgs, pReply := putArgs([]byte(key), []byte("value"), 1, tc.store.StoreID())
+		pArgs.Timestamp = tc.clock.Now()
+		return tc.rng.AddCmd(pArgs, pReply, true)
+	}
+	start := time.Now()
+	if err := put("a"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < window {
+		t.Errorf("expected write to be held for %s; took %s", window, elapsed)
+	}
+
+	const writes = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writes)
+	start = time.Now()
+	for i := 0; i < writes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- put(fmt.Sprintf("b%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= writes/2*window {
+		t.Errorf("expected concurrent writes to be coalesced; %d took %s", writes, elapsed)
+	}
+
+	// The coalesced writes entered the log as single commands.
+	ents, err := loadRaftLog(tc.engine, tc.rng.Desc().RaftID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coalesced := 0
+	for _, ent := range ents {
+		_, data, err := multiraft.DecodeEntry(ent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		var cmd proto.InternalRaftCommand
+		if err := gogoproto.Unmarshal(data, &cmd); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range cmd.Coalesced {
+			if _, ok := c.Cmd.Cmd.GetValue().(*proto.PutRequest); ok {
+				coalesced++
+			}
+		}
+	}
+	if coalesced < writes/2 {
+		t.Errorf("expected the concurrent writes to be proposed in coalesced commands; %d of %d were", coalesced, writes)
+	}
+	for i := 0; i < writes; i++ {
+		gArgs, gReply := getArgs([]byte(fmt.Sprintf("b%d", i)), 1, tc.store.StoreID())
+		gArgs.Timestamp = tc.clock.Now()
+		if err := tc.rng.AddCmd(gArgs, gReply, true); err != nil || gReply.Value == nil {
+			t.Errorf("expected b%d to be written; got %+v, %v", i, gReply.Value, err)
+		}
+	}
+}
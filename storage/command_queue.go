@@ -46,6 +46,12 @@ import (
 // possibly signaling waiting commands who were gated by the executing
 // command's affected key(s).
 //
+// Alternatively, Enqueue() adds a command to the queue before it has
+// finished waiting. Commands added this way are ordered by priority
+// class: a command doesn't wait on overlapping commands of a lower
+// class which are themselves still waiting, but instead makes them
+// wait on it.
+//
 // CommandQueue is not thread safe.
 type CommandQueue struct {
 	cache *util.IntervalCache
@@ -53,7 +59,17 @@ type CommandQueue struct {
 
 type cmd struct {
 	readOnly bool
-	pending  []*sync.WaitGroup // Pending commands gated on cmd
+	priority proto.PriorityClass
+	waiting  int             // Number of commands gating cmd
+	wg       *sync.WaitGroup // Signaled as each command gating cmd completes
+	pending  []*cmd          // Pending commands gated on cmd
+}
+
+// gate makes c wait for the completion of gating.
+func (c *cmd) gate(gating *cmd) {
+	gating.pending = append(gating.pending, c)
+	c.waiting++
+	c.wg.Add(1)
 }
 
 // NewCommandQueue returns a new command queue.
@@ -69,8 +85,9 @@ func NewCommandQueue() *CommandQueue {
 // tree. This happens on calls to Remove() and to Clear().
 func (cq *CommandQueue) onEvicted(key, value interface{}) {
 	c := value.(*cmd)
-	for _, wg := range c.pending {
-		wg.Done()
+	for _, p := range c.pending {
+		p.waiting--
+		p.wg.Done()
 	}
 }
 
@@ -87,12 +104,12 @@ func (cq *CommandQueue) GetWait(start, end proto.Key, readOnly bool, wg *sync.Wa
 		end = start.Next()
 		start = end[:len(start)]
 	}
+	waiter := &cmd{readOnly: readOnly, wg: wg}
 	for _, c := range cq.cache.GetOverlaps(start, end) {
 		c := c.Value.(*cmd)
 		// Only add to the wait group if one of the commands isn't read-only.
 		if !readOnly || !c.readOnly {
-			c.pending = append(c.pending, wg)
-			wg.Add(1)
+			waiter.gate(c)
 		}
 	}
 }
@@ -115,6 +132,36 @@ func (cq *CommandQueue) Add(start, end proto.Key, readOnly bool) interface{} {
 	return key
 }
 
+// Enqueue adds a command of the specified priority class to the queue
+// and initializes the supplied wait group with the number of
+// overlapping commands which must complete before it may execute. The
+// caller should call wg.Wait() before executing the command. As with
+// Add, the returned key must be supplied to Remove() on completion.
+//
+// The command doesn't wait for overlapping, enqueued commands of a
+// lower priority class which are still waiting; those are made to
+// wait for the new command instead.
+func (cq *CommandQueue) Enqueue(start, end proto.Key, readOnly bool, priority proto.PriorityClass, wg *sync.WaitGroup) interface{} {
+	if len(end) == 0 {
+		end = start.Next()
+	}
+	newCmd := &cmd{readOnly: readOnly, priority: priority, wg: wg}
+	for _, c := range cq.cache.GetOverlaps(start, end) {
+		c := c.Value.(*cmd)
+		if readOnly && c.readOnly {
+			continue
+		}
+		if c.waiting > 0 && priority.Outranks(c.priority) {
+			c.gate(newCmd)
+		} else {
+			newCmd.gate(c)
+		}
+	}
+	key := cq.cache.NewKey(start, end)
+	cq.cache.Add(key, newCmd)
+	return key
+}
+
 // Remove is invoked to signal that the command associated with the
 // specified key has completed and should be removed. Any pending
 // commands waiting on this command will be signaled if this is the
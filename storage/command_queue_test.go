@@ -171,3 +171,46 @@ func TestCommandQueueClear(t *testing.T) {
 		t.Fatal("commands should finish when clearing queue")
 	}
 }
+
+// TestCommandQueuePriority verifies that an enqueued command doesn't
+// wait on waiting commands of a lower priority class, which instead
+// wait on it.
+func TestCommandQueuePriority(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	cq := NewCommandQueue()
+	var wg1, wg2, wg3, wg4 sync.WaitGroup
+
+	// An executing command, followed by waiting commands of low, high
+	// and normal priority.
+	key1 := cq.Enqueue(proto.Key("a"), nil, false, proto.NORMAL_PRIORITY, &wg1)
+	wg1.Wait()
+	key2 := cq.Enqueue(proto.Key("a"), nil, false, proto.LOW_PRIORITY, &wg2)
+	key3 := cq.Enqueue(proto.Key("a"), nil, false, proto.HIGH_PRIORITY, &wg3)
+	key4 := cq.Enqueue(proto.Key("a"), nil, false, proto.NORMAL_PRIORITY, &wg4)
+	lowDone, highDone, normalDone := waitForCmd(&wg2), waitForCmd(&wg3), waitForCmd(&wg4)
+	if testCmdDone(highDone, 1*time.Millisecond) {
+		t.Fatal("high priority command should not finish with command executing")
+	}
+
+	// The high priority command runs first, then the normal priority
+	// one, which arrived after the low priority command but outranks it.
+	cq.Remove(key1)
+	if !testCmdDone(highDone, 5*time.Millisecond) {
+		t.Fatal("high priority command should finish")
+	}
+	if testCmdDone(normalDone, 1*time.Millisecond) || testCmdDone(lowDone, 1*time.Millisecond) {
+		t.Fatal("commands should not finish with high priority command executing")
+	}
+	cq.Remove(key3)
+	if !testCmdDone(normalDone, 5*time.Millisecond) {
+		t.Fatal("normal priority command should finish")
+	}
+	if testCmdDone(lowDone, 1*time.Millisecond) {
+		t.Fatal("low priority command should not finish with normal priority command executing")
+	}
+	cq.Remove(key4)
+	if !testCmdDone(lowDone, 5*time.Millisecond) {
+		t.Fatal("low priority command should finish")
+	}
+	cq.Remove(key2)
+}
@@ -0,0 +1,136 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// configGossipKeys are the gossip keys of the system config maps
+// cached by each store.
+var configGossipKeys = []string{
+	gossip.KeyConfigAccounting,
+	gossip.KeyConfigPermission,
+	gossip.KeyConfigZone,
+}
+
+// A ConfigVersion describes a store's cached copy of a system config
+// map. Version starts at zero and is incremented each time gossip
+// delivers new contents.
+type ConfigVersion struct {
+	Version int64 `json:"version"`
+	// UpdatedAt is the wall time, in unix nanos, at which the current
+	// version was cached. It's zero if the config has never been
+	// received.
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// A configCacheEntry is the cached copy of one config map.
+type configCacheEntry struct {
+	configMap PrefixConfigMap
+	ConfigVersion
+}
+
+// A configCache holds a store's copies of the accounting, permission
+// and zone config maps, keyed by gossip key, so that the operations
+// which consult them needn't look them up and type-assert them in
+// gossip each time. Entries are replaced by the store's gossip
+// callback as configs change. It's safe for concurrent use.
+type configCache struct {
+	sync.RWMutex
+	entries map[string]*configCacheEntry
+}
+
+// newConfigCache returns an empty configCache.
+func newConfigCache() *configCache {
+	return &configCache{entries: map[string]*configCacheEntry{}}
+}
+
+// get returns the cached config map for the gossip key, if any.
+func (cc *configCache) get(key string) (PrefixConfigMap, bool) {
+	cc.RLock()
+	defer cc.RUnlock()
+	e, ok := cc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return e.configMap, true
+}
+
+// update replaces the cached config map for the gossip key and
+// returns its new version.
+func (cc *configCache) update(key string, configMap PrefixConfigMap, now int64) int64 {
+	cc.Lock()
+	defer cc.Unlock()
+	e, ok := cc.entries[key]
+	if !ok {
+		e = &configCacheEntry{}
+		cc.entries[key] = e
+	}
+	e.configMap = configMap
+	e.Version++
+	e.UpdatedAt = now
+	return e.Version
+}
+
+// versions returns the version of each cached config map, keyed by
+// gossip key. Configs which have never been received have version
+// zero.
+func (cc *configCache) versions() map[string]ConfigVersion {
+	cc.RLock()
+	defer cc.RUnlock()
+	versions := make(map[string]ConfigVersion, len(configGossipKeys))
+	for _, key := range configGossipKeys {
+		if e, ok := cc.entries[key]; ok {
+			versions[key] = e.ConfigVersion
+		} else {
+			versions[key] = ConfigVersion{}
+		}
+	}
+	return versions
+}
+
+// systemConfig returns the config map for the gossip key. The store's
+// cached copy is returned if one has been received; otherwise the map
+// is looked up in gossip. Maps looked up in gossip aren't cached, so
+// as not to race with the callback delivering a newer version.
+func (s *Store) systemConfig(key string) (PrefixConfigMap, error) {
+	if configMap, ok := s.configs.get(key); ok {
+		return configMap, nil
+	}
+	if s.ctx.Gossip == nil {
+		return nil, util.Errorf("unable to fetch %s config: no gossip", key)
+	}
+	info, err := s.ctx.Gossip.GetInfo(key)
+	if err != nil {
+		return nil, util.Errorf("unable to fetch %s config from gossip: %s", key, err)
+	}
+	configMap, ok := info.(PrefixConfigMap)
+	if !ok {
+		return nil, util.Errorf("gossiped info is not a prefix configuration map: %+v", info)
+	}
+	return configMap, nil
+}
+
+// ConfigVersions returns the version of the store's cached copy of
+// each system config map, keyed by gossip key. The versions of the
+// stores of a cluster converge as a config change propagates.
+func (s *Store) ConfigVersions() map[string]ConfigVersion {
+	return s.configs.versions()
+}
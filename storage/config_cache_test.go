@@ -0,0 +1,86 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// waitForConfig waits until the store has cached configMap for the
+// gossip key. Gossip callbacks are asynchronous, so configs added to
+// gossip by a test aren't seen by the store right away.
+func waitForConfig(t *testing.T, s *Store, key string, configMap PrefixConfigMap) {
+	util.SucceedsWithin(t, time.Second, func() error {
+		if cached, _ := s.configs.get(key); !reflect.DeepEqual(cached, configMap) {
+			return util.Errorf("expected %s config %s; got %s", key, configMap, cached)
+		}
+		return nil
+	})
+}
+
+// TestStoreConfigCache verifies that the store caches each of the
+// system configs gossiped by the first range and that a changed config
+// replaces the cached copy and increments its version.
+func TestStoreConfigCache(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	util.SucceedsWithin(t, time.Second, func() error {
+		for key, v := range tc.store.ConfigVersions() {
+			if v.Version == 0 || v.UpdatedAt == 0 {
+				return util.Errorf("expected %s config to be cached; got %+v", key, v)
+			}
+		}
+		return nil
+	})
+	version := tc.store.ConfigVersions()[gossip.KeyConfigZone].Version
+
+	zoneMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{RangeMaxBytes: 1 << 20}},
+		{proto.Key("/db1"), nil, &proto.ZoneConfig{RangeMaxBytes: 2 << 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.gossip.AddInfo(gossip.KeyConfigZone, zoneMap, 0*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	waitForConfig(t, tc.store, gossip.KeyConfigZone, zoneMap)
+
+	if v := tc.store.ConfigVersions()[gossip.KeyConfigZone].Version; v <= version {
+		t.Errorf("expected zone config version above %d; got %d", version, v)
+	}
+	cached, err := tc.store.systemConfig(gossip.KeyConfigZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cached, zoneMap) {
+		t.Errorf("expected cached zone config %s; got %s", zoneMap, cached)
+	}
+	if v := tc.store.Metrics().ConfigVersions[gossip.KeyConfigZone].Version; v <= version {
+		t.Errorf("expected zone config version metric above %d; got %d", version, v)
+	}
+}
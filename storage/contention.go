@@ -0,0 +1,126 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+const (
+	// contentionLogSize is the number of contention events retained by
+	// each store.
+	contentionLogSize = 1000
+	// contentionSampleRate is the fraction of contention events which
+	// are recorded.
+	contentionSampleRate = 0.1
+)
+
+// Outcomes of the push of a transaction.
+const (
+	ContentionPushed  = "pushed"  // The pushee's timestamp was pushed
+	ContentionAborted = "aborted" // The pushee was aborted
+	ContentionFailed  = "failed"  // The push failed; the pusher must retry
+)
+
+// A ContentionEvent records the push of a transaction whose intent on
+// Key conflicted with a command. Non-transactional pushers have an
+// empty name and the command's user priority.
+type ContentionEvent struct {
+	// Time is the wall time, in unix nanos, at which the push completed.
+	Time           int64         `json:"time"`
+	StoreID        proto.StoreID `json:"store_id"`
+	RaftID         int64         `json:"raft_id"`
+	Key            proto.Key     `json:"key"`
+	PusherName     string        `json:"pusher_name"`
+	PusherPriority int32         `json:"pusher_priority"`
+	PusheeName     string        `json:"pushee_name"`
+	PusheePriority int32         `json:"pushee_priority"`
+	// Abort is true if the pusher attempted to abort the pushee rather
+	// than push its timestamp.
+	Abort   bool   `json:"abort"`
+	Outcome string `json:"outcome"`
+}
+
+// A contentionLog retains a sample of the most recent contention
+// events in a ring buffer. It's safe for concurrent use.
+type contentionLog struct {
+	sync.Mutex
+	sampleRate float64
+	events     []ContentionEvent
+	next       int // Index at which to record the next event
+	full       bool
+}
+
+// newContentionLog returns a contentionLog retaining up to size
+// events, of which sampleRate are recorded.
+func newContentionLog(size int, sampleRate float64) *contentionLog {
+	return &contentionLog{
+		sampleRate: sampleRate,
+		events:     make([]ContentionEvent, size),
+	}
+}
+
+// record adds the event to the log, unless it's not sampled, replacing
+// the oldest event if the log is full.
+func (cl *contentionLog) record(event ContentionEvent) {
+	if cl.sampleRate < 1 && rand.Float64() >= cl.sampleRate {
+		return
+	}
+	cl.Lock()
+	defer cl.Unlock()
+	cl.events[cl.next] = event
+	cl.next++
+	if cl.next == len(cl.events) {
+		cl.next = 0
+		cl.full = true
+	}
+}
+
+// Events returns the events in the log, oldest first.
+func (cl *contentionLog) Events() []ContentionEvent {
+	cl.Lock()
+	defer cl.Unlock()
+	if !cl.full {
+		return append([]ContentionEvent(nil), cl.events[:cl.next]...)
+	}
+	return append(append([]ContentionEvent(nil), cl.events[cl.next:]...), cl.events[:cl.next]...)
+}
+
+// newContentionEvent returns an event for the push of the pushee txn
+// by the command with header pusher, which resulted in outcome.
+func newContentionEvent(storeID proto.StoreID, raftID int64, key proto.Key, pusher *proto.RequestHeader,
+	pushee *proto.Transaction, abort bool, outcome string) ContentionEvent {
+	event := ContentionEvent{
+		Time:           time.Now().UnixNano(),
+		StoreID:        storeID,
+		RaftID:         raftID,
+		Key:            key,
+		PusherPriority: pusher.GetUserPriority(),
+		PusheeName:     pushee.Name,
+		PusheePriority: pushee.Priority,
+		Abort:          abort,
+		Outcome:        outcome,
+	}
+	if pusher.Txn != nil {
+		event.PusherName = pusher.Txn.Name
+		event.PusherPriority = pusher.Txn.Priority
+	}
+	return event
+}
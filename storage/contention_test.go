@@ -0,0 +1,55 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestContentionLog verifies that the most recent events are retained,
+// oldest first, and that unsampled events are dropped.
+func TestContentionLog(t *testing.T) {
+	cl := newContentionLog(3, 1)
+	times := func() []int64 {
+		var ts []int64
+		for _, e := range cl.Events() {
+			ts = append(ts, e.Time)
+		}
+		return ts
+	}
+	if ts := times(); len(ts) != 0 {
+		t.Errorf("expected no events; got %v", ts)
+	}
+	for i := int64(1); i <= 2; i++ {
+		cl.record(ContentionEvent{Time: i})
+	}
+	if ts := times(); !reflect.DeepEqual(ts, []int64{1, 2}) {
+		t.Errorf("expected events [1 2]; got %v", ts)
+	}
+	for i := int64(3); i <= 5; i++ {
+		cl.record(ContentionEvent{Time: i})
+	}
+	if ts := times(); !reflect.DeepEqual(ts, []int64{3, 4, 5}) {
+		t.Errorf("expected events [3 4 5]; got %v", ts)
+	}
+
+	cl = newContentionLog(3, 0)
+	cl.record(ContentionEvent{Time: 1})
+	if ts := times(); len(ts) != 0 {
+		t.Errorf("expected unsampled event to be dropped; got %v", ts)
+	}
+}
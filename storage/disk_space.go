@@ -0,0 +1,193 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// DefaultBallastSize is the default size of the ballast file kept
+	// in the data directory of each persistent store.
+	DefaultBallastSize = 1 << 30
+	// DefaultMinAvailable is the default free space below which a
+	// persistent store is nearly full.
+	DefaultMinAvailable = 1 << 30
+	// diskSpaceCheckInterval is the interval at which the free space
+	// of persistent stores is checked.
+	diskSpaceCheckInterval = 10 * time.Second
+	// ballastFileName is the name of the ballast file in the data
+	// directory of a store.
+	ballastFileName = "BALLAST"
+	// ballastChunkSize is the size of the writes filling a ballast
+	// file.
+	ballastChunkSize = 1 << 20
+)
+
+// diskSpace holds the results of a store's free space checks.
+type diskSpace struct {
+	nearlyFull      int32 // Non-zero if the store refuses writes for want of space; updated atomically
+	ballastDeferred bool  // Set while there's no room for the ballast file; only used by checkDiskSpace
+}
+
+// NearlyFull returns whether the space available to the store fell
+// below StoreContext.MinAvailable when it was last checked.
+func (s *Store) NearlyFull() bool { return atomic.LoadInt32(&s.space.nearlyFull) != 0 }
+
+// setNearlyFull records whether the store is nearly full, returning
+// whether that changed.
+func (s *Store) setNearlyFull(nearlyFull bool) bool {
+	var v int32
+	if nearlyFull {
+		v = 1
+	}
+	return atomic.SwapInt32(&s.space.nearlyFull, v) != v
+}
+
+// allowedNearlyFull returns whether a write may be executed by a
+// nearly full store: deletions, garbage collection, raft log
+// truncation and the resolution of intents, all of which free space,
+// or at least allow an operator to, and the pushes, aborts and
+// heartbeats of transactions and leader leases, without which the
+// store's ranges would stall. Other writes are refused.
+func allowedNearlyFull(args proto.Request) bool {
+	switch t := args.(type) {
+	case *proto.DeleteRequest, *proto.DeleteRangeRequest, *proto.InternalGCRequest,
+		*proto.InternalTruncateLogRequest, *proto.InternalResolveIntentRequest,
+		*proto.InternalPushTxnRequest, *proto.InternalHeartbeatTxnRequest,
+		*proto.InternalLeaderLeaseRequest:
+		return true
+	case *proto.EndTransactionRequest:
+		return !t.Commit
+	}
+	return false
+}
+
+// monitorDiskSpace periodically checks the free space of the store, if
+// it's persistent.
+func (s *Store) monitorDiskSpace() {
+	if d, ok := s.engine.(engine.DirEngine); !ok || d.Dir() == "" ||
+		(s.ctx.MinAvailable <= 0 && s.ctx.BallastSize <= 0) {
+		return
+	}
+	s.stopper.RunWorker(func() {
+		ticker := time.NewTicker(diskSpaceCheckInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.checkDiskSpace(); err != nil {
+				log.Warningf("store %s: disk space check failed: %s", s, err)
+			}
+			select {
+			case <-ticker.C:
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// checkDiskSpace checks the space available to a persistent store.
+// When the store becomes nearly full, or has space again, its
+// descriptor is gossiped right away, so that the allocator stops or
+// resumes placing replicas on it. The store's ballast file is then
+// created, or recreated if it was deleted, provided MinAvailable bytes
+// remain free.
+func (s *Store) checkDiskSpace() error {
+	d, ok := s.engine.(engine.DirEngine)
+	if !ok || d.Dir() == "" {
+		return nil
+	}
+	capacity, err := s.engine.Capacity()
+	if err != nil {
+		return err
+	}
+	if s.ctx.MinAvailable > 0 && s.setNearlyFull(capacity.Available < s.ctx.MinAvailable) {
+		if s.NearlyFull() {
+			log.Warningf("store %s is nearly full: %d bytes available, less than the minimum of %d; "+
+				"only writes which free space are accepted", s, capacity.Available, s.ctx.MinAvailable)
+		} else {
+			log.Infof("store %s is no longer nearly full: %d bytes available", s, capacity.Available)
+		}
+		s.mu.RLock()
+		n := s.nodeDesc
+		s.mu.RUnlock()
+		if n != nil && s.ctx.Gossip != nil {
+			s.GossipCapacity(n)
+		}
+	}
+	if s.ctx.BallastSize <= 0 {
+		return nil
+	}
+	path := filepath.Join(d.Dir(), ballastFileName)
+	placed, err := maintainBallast(path, s.ctx.BallastSize, capacity.Available-s.ctx.MinAvailable)
+	if err != nil {
+		return err
+	}
+	if !placed && !s.space.ballastDeferred {
+		log.Warningf("store %s: no room for ballast file %s of %d bytes", s, path, s.ctx.BallastSize)
+	}
+	s.space.ballastDeferred = !placed
+	return nil
+}
+
+// maintainBallast creates or resizes the ballast file at path to hold
+// size bytes, unless growing it would take more than spare bytes of
+// free space, and returns whether the file holds size bytes. The file
+// is written rather than allocated sparsely, so that its space is
+// actually reserved.
+func maintainBallast(path string, size, spare int64) (bool, error) {
+	var existing int64
+	if fi, err := os.Stat(path); err == nil {
+		existing = fi.Size()
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+	switch {
+	case existing == size:
+		return true, nil
+	case existing > size:
+		return true, os.Truncate(path, size)
+	case size-existing > spare:
+		return false, nil
+	}
+	log.Infof("writing ballast file %s of %d bytes", path, size)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return false, err
+	}
+	chunk := make([]byte, ballastChunkSize)
+	for n := existing; n < size; n += int64(len(chunk)) {
+		if size-n < int64(len(chunk)) {
+			chunk = chunk[:size-n]
+		}
+		if _, err := f.Write(chunk); err != nil {
+			f.Close()
+			return false, err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return false, err
+	}
+	return true, f.Close()
+}
@@ -0,0 +1,195 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// spaceEngine is an engine with a data directory which reports the
+// available space given.
+type spaceEngine struct {
+	engine.Engine
+	dir       string
+	available int64
+}
+
+func (e *spaceEngine) Dir() string    { return e.dir }
+func (e *spaceEngine) Destroy() error { return nil }
+
+func (e *spaceEngine) Capacity() (engine.StoreCapacity, error) {
+	return engine.StoreCapacity{Capacity: 100 << 20, Available: e.available}, nil
+}
+
+// TestMaintainBallast verifies that ballast files are created and
+// resized only if space allows.
+func TestMaintainBallast(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir, err := ioutil.TempDir("", "ballast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ballastFileName)
+
+	testCases := []struct {
+		size, spare int64
+		expPlaced   bool
+		expSize     int64 // -1 if the file mustn't exist
+	}{
+		{3 << 20, 2 << 20, false, -1},
+		{3<<20 + 100, 4 << 20, true, 3<<20 + 100},
+		{1 << 20, 0, true, 1 << 20},
+		{2 << 20, 512 << 10, false, 1 << 20},
+		{2 << 20, 1 << 20, true, 2 << 20},
+	}
+	for i, test := range testCases {
+		placed, err := maintainBallast(path, test.size, test.spare)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if placed != test.expPlaced {
+			t.Errorf("%d: expected placed %t; got %t", i, test.expPlaced, placed)
+		}
+		fi, err := os.Stat(path)
+		if test.expSize < 0 {
+			if !os.IsNotExist(err) {
+				t.Errorf("%d: expected no ballast file; got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if fi.Size() != test.expSize {
+			t.Errorf("%d: expected ballast of %d bytes; got %d", i, test.expSize, fi.Size())
+		}
+	}
+}
+
+// TestStoreNearlyFull verifies that a store running out of space is
+// described as nearly full and only accepts writes which free space or
+// keep its ranges' transactions and leases moving until space is
+// available again, when its ballast file is created.
+func TestStoreNearlyFull(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	dir, err := ioutil.TempDir("", "nearly-full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	eng := &spaceEngine{Engine: store.engine, dir: dir, available: 1 << 20}
+	store.engine = eng
+	store.ctx.MinAvailable = 2 << 20
+	store.ctx.BallastSize = 1 << 20
+	nodeDesc := &gossip.NodeDescriptor{NodeID: 1}
+
+	if err := store.checkDiskSpace(); err != nil {
+		t.Fatal(err)
+	}
+	if desc, err := store.Descriptor(nodeDesc); err != nil {
+		t.Fatal(err)
+	} else if !store.NearlyFull() || !desc.NearlyFull || !store.Metrics().NearlyFull {
+		t.Errorf("expected store to be nearly full; got %+v", desc)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ballastFileName)); !os.IsNotExist(err) {
+		t.Errorf("expected no ballast file on a nearly full store; got %v", err)
+	}
+	pArgs, pReply := putArgs([]byte("a"), []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(pArgs, pReply); err == nil {
+		t.Error("expected put to nearly full store to fail")
+	}
+	dArgs, dReply := deleteArgs(proto.Key("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(dArgs, dReply); err != nil {
+		t.Errorf("expected delete from nearly full store to succeed; got %s", err)
+	}
+	gArgs, gReply := getArgs([]byte("a"), 1, store.StoreID())
+	if err := store.ExecuteCmd(gArgs, gReply); err != nil {
+		t.Errorf("expected get from nearly full store to succeed; got %s", err)
+	}
+
+	// Transactions may be heartbeated, pushed and aborted, but not
+	// committed, and leader leases renewed.
+	pushee := newTransaction("pushee", proto.Key("a"), 1, proto.SERIALIZABLE, store.ctx.Clock)
+	pushee.Priority = 1
+	hArgs, hReply := heartbeatArgs(pushee, 1, store.StoreID())
+	hArgs.Timestamp = store.ctx.Clock.Now()
+	if err := store.ExecuteCmd(hArgs, hReply); err != nil {
+		t.Errorf("expected heartbeat by nearly full store to succeed; got %s", err)
+	}
+	pusher := newTransaction("pusher", proto.Key("a"), 1, proto.SERIALIZABLE, store.ctx.Clock)
+	pusher.Priority = 2
+	pushArgs, pushReply := pushTxnArgs(pusher, pushee, true, 1, store.StoreID())
+	if err := store.ExecuteCmd(pushArgs, pushReply); err != nil {
+		t.Errorf("expected push by nearly full store to succeed; got %s", err)
+	}
+	for _, commit := range []bool{false, true} {
+		txn := newTransaction("test", proto.Key("a"), 1, proto.SERIALIZABLE, store.ctx.Clock)
+		eArgs, eReply := endTxnArgs(txn, commit, 1, store.StoreID())
+		eArgs.Timestamp = txn.Timestamp
+		if err := store.ExecuteCmd(eArgs, eReply); (err == nil) == commit {
+			t.Errorf("expected commit=%t by nearly full store to succeed=%t; got %v", commit, !commit, err)
+		}
+	}
+	lease := proto.Lease{
+		Expiration: store.ctx.Clock.PhysicalNow() + int64(defaultLeaderLeaseDuration),
+		Duration:   int64(defaultLeaderLeaseDuration),
+		RaftNodeID: uint64(store.RaftNodeID()),
+	}
+	if rng, err := store.GetRange(1); err != nil {
+		t.Fatal(err)
+	} else if l := rng.getLease(); l != nil {
+		lease.Term = l.Term
+	}
+	lArgs := &proto.InternalLeaderLeaseRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:     engine.KeyMin,
+			RaftID:  1,
+			Replica: proto.Replica{StoreID: store.StoreID()},
+		},
+		Lease: lease,
+	}
+	if err := store.ExecuteCmd(lArgs, &proto.InternalLeaderLeaseResponse{}); err != nil {
+		t.Errorf("expected leader lease by nearly full store to succeed; got %s", err)
+	}
+
+	eng.available = 4 << 20
+	if err := store.checkDiskSpace(); err != nil {
+		t.Fatal(err)
+	}
+	if desc, err := store.Descriptor(nodeDesc); err != nil {
+		t.Fatal(err)
+	} else if store.NearlyFull() || desc.NearlyFull {
+		t.Errorf("expected store not to be nearly full; got %+v", desc)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, ballastFileName)); err != nil || fi.Size() != 1<<20 {
+		t.Errorf("expected ballast file of %d bytes; got %+v, %v", 1<<20, fi, err)
+	}
+	pArgs, pReply = putArgs([]byte("b"), []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import "github.com/cockroachdb/cockroach/proto"
+
+const (
+	// minChunkSize and maxChunkSize bound the chunks of memory in which
+	// a chunkAlloc copies keys and values.
+	minChunkSize = 1 << 10
+	maxChunkSize = 64 << 10
+)
+
+// An unsafeIterator is an iterator whose current key and value may be
+// read without being copied. The slices are only valid until the
+// iterator is next moved.
+type unsafeIterator interface {
+	Iterator
+	unsafeKey() proto.EncodedKey
+	unsafeValue() []byte
+}
+
+// A chunkAlloc copies the keys and values read by an iterator into
+// shared chunks of memory, so that an iteration makes one allocation
+// per chunk rather than one per key and value. The chunks double in
+// size, up to maxChunkSize, so that short iterations stay small. A
+// copy keeps its whole chunk alive, so a chunkAlloc suits copies which
+// are kept or discarded together, such as the keys of a scan.
+type chunkAlloc []byte
+
+// copy returns a copy of b. Its capacity is limited to its length, so
+// that appending to it doesn't overwrite later copies.
+func (a *chunkAlloc) copy(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	if len(b) > cap(*a)-len(*a) {
+		size := 2 * cap(*a)
+		if size < minChunkSize {
+			size = minChunkSize
+		} else if size > maxChunkSize {
+			size = maxChunkSize
+		}
+		if size < len(b) {
+			size = len(b)
+		}
+		*a = make([]byte, 0, size)
+	}
+	start := len(*a)
+	*a = append(*a, b...)
+	return (*a)[start:len(*a):len(*a)]
+}
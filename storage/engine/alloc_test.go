@@ -0,0 +1,59 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestChunkAlloc verifies that copies share chunks without overlapping,
+// that appending to a copy doesn't overwrite the next, and that copies
+// larger than a chunk get chunks of their own.
+func TestChunkAlloc(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	var a chunkAlloc
+	if a.copy(nil) != nil {
+		t.Error("expected copy of nil to be nil")
+	}
+	src := []byte("key")
+	first := a.copy(src)
+	second := a.copy([]byte("other"))
+	src[0] = 'x'
+	if string(first) != "key" || string(second) != "other" {
+		t.Errorf("expected independent copies; got %q, %q", first, second)
+	}
+	if cap(first) != len(first) {
+		t.Errorf("expected capacity limited to %d; got %d", len(first), cap(first))
+	}
+	_ = append(first, 'z')
+	if string(second) != "other" {
+		t.Errorf("expected append to leave the next copy; got %q", second)
+	}
+	if cap(a) != minChunkSize {
+		t.Errorf("expected first chunk of %d bytes; got %d", minChunkSize, cap(a))
+	}
+
+	large := bytes.Repeat([]byte("v"), 2*maxChunkSize)
+	if c := a.copy(large); !bytes.Equal(c, large) {
+		t.Error("expected copy of large value")
+	}
+	if cap(a) != len(large) {
+		t.Errorf("expected chunk of %d bytes for large value; got %d", len(large), cap(a))
+	}
+}
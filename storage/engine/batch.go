@@ -21,6 +21,7 @@ package engine
 
 import (
 	"bytes"
+	"fmt"
 	"runtime/debug"
 
 	"github.com/biogo/store/llrb"
@@ -35,7 +36,8 @@ import (
 // of Commit(). Reads are passed through to the wrapped engine. In the
 // event that reads access keys for which there are already-batched
 // updates, reads from the wrapped engine are combined on the fly with
-// pending write, delete, and merge updates.
+// pending write, delete, and merge updates. The wrapped engine may
+// itself be a Batch, in which case Commit() adds the mutations to it.
 //
 // This struct is not thread safe.
 type Batch struct {
@@ -200,6 +202,18 @@ func (b *Batch) Merge(key proto.EncodedKey, value []byte) error {
 // Commit writes all pending updates to the underlying engine in
 // an atomic write batch.
 func (b *Batch) Commit() error {
+	return b.engine.WriteBatch(b.flushUpdates())
+}
+
+// CommitSync commits the batch like Commit, then syncs the wrapped
+// engine's write-ahead log.
+func (b *Batch) CommitSync() error {
+	return b.engine.WriteBatchSync(b.flushUpdates())
+}
+
+// flushUpdates marks the batch committed and returns its updates in
+// key order.
+func (b *Batch) flushUpdates() []interface{} {
 	if b.committed {
 		panic("this batch was already committed")
 	}
@@ -209,7 +223,7 @@ func (b *Batch) Commit() error {
 		return false
 	}, proto.RawKeyValue{Key: proto.EncodedKey(KeyMin)}, proto.RawKeyValue{Key: proto.EncodedKey(KeyMax)})
 	b.committed = true
-	return b.engine.WriteBatch(batch)
+	return batch
 }
 
 // Open returns an error if called on a Batch.
@@ -226,9 +240,32 @@ func (b *Batch) Attrs() proto.Attributes {
 	return proto.Attributes{}
 }
 
-// WriteBatch returns an error if called on a Batch.
-func (b *Batch) WriteBatch([]interface{}) error {
-	return util.Errorf("cannot write batch from a Batch")
+// WriteBatch adds the updates committed by a nested batch to the
+// batch.
+func (b *Batch) WriteBatch(cmds []interface{}) error {
+	for i, e := range cmds {
+		var err error
+		switch v := e.(type) {
+		case BatchDelete:
+			err = b.Clear(v.Key)
+		case BatchPut:
+			err = b.Put(v.Key, v.Value)
+		case BatchMerge:
+			err = b.Merge(v.Key, v.Value)
+		default:
+			panic(fmt.Sprintf("illegal operation #%d passed to WriteBatch: %T", i, v))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBatchSync is like WriteBatch; the batch's updates are synced
+// once it's committed with CommitSync.
+func (b *Batch) WriteBatchSync(cmds []interface{}) error {
+	return b.WriteBatch(cmds)
 }
 
 // Capacity returns an error if called on a Batch.
@@ -261,9 +298,10 @@ func (b *Batch) NewSnapshot() Engine {
 	return nil
 }
 
-// NewBatch returns a new Batch instance wrapping same underlying engine.
+// NewBatch returns a new Batch nested in b. Its updates are added to
+// b when it's committed, and written to the underlying engine with b.
 func (b *Batch) NewBatch() Engine {
-	return &Batch{engine: b.engine}
+	return &Batch{engine: b}
 }
 
 type batchIterator struct {
@@ -271,6 +309,16 @@ type batchIterator struct {
 	updates *llrb.Tree
 	pending []proto.RawKeyValue
 	err     error
+	// The following spare the iterator allocations on each step:
+	// pendingBuf is the array reused by pending once it's consumed,
+	// next holds the successor of the last key, and keyAlloc and
+	// valueAlloc hold the copies of the keys and values read from an
+	// unsafeIterator. The keys and values are copied apart so that
+	// keys kept by the caller don't keep values alive.
+	pendingBuf []proto.RawKeyValue
+	next       proto.EncodedKey
+	keyAlloc   chunkAlloc
+	valueAlloc chunkAlloc
 }
 
 // newBatchIterator returns a new iterator over the supplied Batch instance.
@@ -287,7 +335,7 @@ func (bi *batchIterator) Close() {
 }
 
 func (bi *batchIterator) Seek(key []byte) {
-	bi.pending = []proto.RawKeyValue{}
+	bi.pending = bi.pendingBuf[:0]
 	bi.err = nil
 	bi.iter.Seek(key)
 	bi.mergeUpdates(key)
@@ -302,11 +350,12 @@ func (bi *batchIterator) Next() {
 		bi.err = util.Errorf("next called with invalid iterator")
 		return
 	}
-	last := bi.pending[0].Key.Next()
+	last := bi.nextKey(bi.pending[0].Key)
 	if len(bi.pending) > 0 {
 		bi.pending = bi.pending[1:]
 	}
 	if len(bi.pending) == 0 {
+		bi.pending = bi.pendingBuf[:0]
 		bi.mergeUpdates(last)
 	}
 }
@@ -336,16 +385,32 @@ func (bi *batchIterator) Error() error {
 	return bi.err
 }
 
+// nextKey returns the successor of key, in a buffer which is reused by
+// the following call.
+func (bi *batchIterator) nextKey(key proto.EncodedKey) proto.EncodedKey {
+	bi.next = append(append(bi.next[:0], key...), 0)
+	return bi.next
+}
+
 // mergeUpdates combines the next key/value from the engine iterator
 // with all batch updates which preceed it. The final batch update
 // which might overlap the next key/value is merged. The start
 // parameter indicates the first possible key to merge from either
-// iterator.
+// iterator. pending must be empty and backed by pendingBuf.
 func (bi *batchIterator) mergeUpdates(start proto.EncodedKey) {
+	unsafeIter, _ := bi.iter.(unsafeIterator)
 	// Use a for-loop because deleted entries might cause nothing
 	// to be added to bi.pending; in this case, we loop to next key.
 	for len(bi.pending) == 0 && bi.iter.Valid() {
-		kv := proto.RawKeyValue{Key: bi.iter.Key(), Value: bi.iter.Value()}
+		var kv proto.RawKeyValue
+		if unsafeIter != nil {
+			kv = proto.RawKeyValue{
+				Key:   bi.keyAlloc.copy(unsafeIter.unsafeKey()),
+				Value: bi.valueAlloc.copy(unsafeIter.unsafeValue()),
+			}
+		} else {
+			kv = proto.RawKeyValue{Key: bi.iter.Key(), Value: bi.iter.Value()}
+		}
 		bi.iter.Next()
 
 		// Get updates up to the engine iterator's current key.
@@ -367,12 +432,15 @@ func (bi *batchIterator) mergeUpdates(start proto.EncodedKey) {
 		} else {
 			bi.pending = append(bi.pending, kv)
 		}
-		start = kv.Key.Next()
+		start = bi.nextKey(kv.Key)
 	}
 
 	if len(bi.pending) == 0 {
 		bi.getUpdates(start, proto.EncodedKey(KeyMax))
 	}
+	if cap(bi.pending) > cap(bi.pendingBuf) {
+		bi.pendingBuf = bi.pending[:0]
+	}
 }
 
 // getUpdates scans the updates tree from start to end, adding
@@ -94,6 +94,82 @@ func TestBatchBasics(t *testing.T) {
 	}
 }
 
+// TestBatchCommitSync verifies that batches committed with and without
+// a sync are applied in order, that committing twice panics, and that
+// an empty batch may be committed with a sync.
+func TestBatchCommitSync(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+
+	b1 := e.NewBatch()
+	if err := b1.Put(proto.EncodedKey("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b1.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	b2 := e.NewBatch()
+	if err := b2.Put(proto.EncodedKey("a"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b2.CommitSync(); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := e.Get(proto.EncodedKey("a")); err != nil || !bytes.Equal(val, []byte("2")) {
+		t.Errorf("expected value 2; got %q, %v", val, err)
+	}
+	if err := e.NewBatch().CommitSync(); err != nil {
+		t.Errorf("unexpected error syncing empty batch: %s", err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic committing batch twice")
+		}
+	}()
+	b2.CommitSync()
+}
+
+// TestBatchNested verifies that a batch nested in another reads the
+// other's updates and adds its own to it when committed, and that
+// neither reaches the engine until the outer batch is committed.
+func TestBatchNested(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+
+	outer := e.NewBatch()
+	if err := outer.Put(proto.EncodedKey("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	inner := outer.NewBatch()
+	if val, err := inner.Get(proto.EncodedKey("a")); err != nil || !bytes.Equal(val, []byte("1")) {
+		t.Errorf("expected nested batch to read 1; got %q, %v", val, err)
+	}
+	if err := inner.Put(proto.EncodedKey("b"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Clear(proto.EncodedKey("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := e.Get(proto.EncodedKey("b")); err != nil || val != nil {
+		t.Errorf("expected nested batch not to write the engine; got %q, %v", val, err)
+	}
+	if err := outer.CommitSync(); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := e.Get(proto.EncodedKey("a")); err != nil || val != nil {
+		t.Errorf("expected a to be cleared; got %q, %v", val, err)
+	}
+	if val, err := e.Get(proto.EncodedKey("b")); err != nil || !bytes.Equal(val, []byte("2")) {
+		t.Errorf("expected b to be 2; got %q, %v", val, err)
+	}
+}
+
 func TestBatchGet(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	e := NewInMem(proto.Attributes{}, 1<<20)
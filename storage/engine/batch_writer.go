@@ -0,0 +1,125 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import "github.com/cockroachdb/cockroach/proto"
+
+// DefaultBatchWriterSize is the number of bytes of keys and values a
+// BatchWriter accumulates before flushing if no threshold is given.
+const DefaultBatchWriterSize = 4 << 20 // 4 MB
+
+// A BatchWriter accumulates puts, deletions and merges and writes them
+// to an engine in batches, flushing whenever the keys and values held
+// reach a byte threshold. This bounds the memory used by callers which
+// write more data than should be held in a single batch. Unlike a
+// Batch, the updates are not applied atomically: each flushed batch is
+// atomic, but a failure leaves the batches flushed before it applied.
+// Updates are applied in the order they're made. Reads are not
+// supported, as flushed and pending updates aren't merged.
+//
+// It's used to clear stores, to destroy replicas and to apply snapshots
+// to in-memory engines. Ranges aren't migrated ahead of merges yet (see
+// storage.Range.AdminMerge); once they are, the migrated data should be
+// written through a BatchWriter too.
+//
+// This struct is not thread safe.
+type BatchWriter struct {
+	engine   Engine
+	maxBytes int64
+	onFlush  func(count int, bytes int64)
+	updates  []interface{}
+	bytes    int64
+}
+
+// NewBatchWriter returns a BatchWriter which flushes updates to engine
+// once they hold maxBytes of keys and values, or DefaultBatchWriterSize
+// if maxBytes isn't positive. If onFlush is not nil, it's invoked with
+// the number of updates and bytes written after each flush.
+func NewBatchWriter(engine Engine, maxBytes int64, onFlush func(count int, bytes int64)) *BatchWriter {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBatchWriterSize
+	}
+	return &BatchWriter{engine: engine, maxBytes: maxBytes, onFlush: onFlush}
+}
+
+// Put adds a put of the key / value, flushing if the threshold is
+// reached.
+func (bw *BatchWriter) Put(key proto.EncodedKey, value []byte) error {
+	if len(key) == 0 {
+		return emptyKeyError()
+	}
+	return bw.add(BatchPut{bw.copy(key, value)})
+}
+
+// Clear adds a deletion of the key, flushing if the threshold is
+// reached.
+func (bw *BatchWriter) Clear(key proto.EncodedKey) error {
+	if len(key) == 0 {
+		return emptyKeyError()
+	}
+	return bw.add(BatchDelete{bw.copy(key, nil)})
+}
+
+// Merge adds a merge of the value into the key, flushing if the
+// threshold is reached.
+func (bw *BatchWriter) Merge(key proto.EncodedKey, value []byte) error {
+	if len(key) == 0 {
+		return emptyKeyError()
+	}
+	return bw.add(BatchMerge{bw.copy(key, value)})
+}
+
+// Pending returns the number of updates and bytes not yet flushed.
+func (bw *BatchWriter) Pending() (int, int64) {
+	return len(bw.updates), bw.bytes
+}
+
+// Flush writes the pending updates to the engine. It must be called
+// once the last update has been added.
+func (bw *BatchWriter) Flush() error {
+	if len(bw.updates) == 0 {
+		return nil
+	}
+	count, bytes := len(bw.updates), bw.bytes
+	if err := bw.engine.WriteBatch(bw.updates); err != nil {
+		return err
+	}
+	bw.updates, bw.bytes = nil, 0
+	if bw.onFlush != nil {
+		bw.onFlush(count, bytes)
+	}
+	return nil
+}
+
+// copy returns a key value holding copies of the key and value, which
+// the caller may reuse, and adds their sizes to the pending bytes.
+func (bw *BatchWriter) copy(key proto.EncodedKey, value []byte) proto.RawKeyValue {
+	bw.bytes += int64(len(key) + len(value))
+	kv := proto.RawKeyValue{Key: append(proto.EncodedKey(nil), key...)}
+	if value != nil {
+		kv.Value = append([]byte(nil), value...)
+	}
+	return kv
+}
+
+// add appends the update, flushing if the threshold is reached.
+func (bw *BatchWriter) add(update interface{}) error {
+	bw.updates = append(bw.updates, update)
+	if bw.bytes >= bw.maxBytes {
+		return bw.Flush()
+	}
+	return nil
+}
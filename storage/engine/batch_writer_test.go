@@ -0,0 +1,97 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestBatchWriter verifies that a batch writer flushes its updates
+// once they reach its threshold, that each flush is reported and that
+// updates are applied in order.
+func TestBatchWriter(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+
+	if err := e.Put(proto.EncodedKey("deleted"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	var flushes []int
+	var flushed int64
+	bw := NewBatchWriter(e, 100, func(count int, bytes int64) {
+		flushes = append(flushes, count)
+		flushed += bytes
+	})
+	// Each put holds 10 bytes, so every tenth put flushes the batch.
+	for i := 0; i < 25; i++ {
+		if err := bw.Put(proto.EncodedKey(fmt.Sprintf("key%02d", i)), []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(flushes) != 2 || flushes[0] != 10 || flushes[1] != 10 || flushed != 200 {
+		t.Errorf("expected two flushes of 10 updates and 200 bytes; got %v and %d", flushes, flushed)
+	}
+	if count, bytes := bw.Pending(); count != 5 || bytes != 50 {
+		t.Errorf("expected 5 pending updates of 50 bytes; got %d and %d", count, bytes)
+	}
+	if kvs, err := Scan(e, proto.EncodedKey("key"), proto.EncodedKey("key99"), 0); err != nil || len(kvs) != 20 {
+		t.Errorf("expected 20 flushed keys; got %d: %v", len(kvs), err)
+	}
+
+	// Later updates to a key override earlier ones.
+	if err := bw.Clear(proto.EncodedKey("key24")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Clear(proto.EncodedKey("deleted")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Merge(proto.EncodedKey("merged"), appender("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Merge(proto.EncodedKey("merged"), appender("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Put(proto.EncodedKey{}, nil); err == nil {
+		t.Error("expected an error putting an empty key")
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(flushes) != 3 {
+		t.Errorf("expected a third flush; got %v", flushes)
+	}
+	if count, _ := bw.Pending(); count != 0 {
+		t.Errorf("expected no pending updates after flush; got %d", count)
+	}
+	if kvs, err := Scan(e, proto.EncodedKey("key"), proto.EncodedKey("key99"), 0); err != nil || len(kvs) != 24 {
+		t.Errorf("expected 24 keys; got %d: %v", len(kvs), err)
+	}
+	if val, err := e.Get(proto.EncodedKey("deleted")); err != nil || val != nil {
+		t.Errorf("expected deleted key to be cleared; got %q: %v", val, err)
+	}
+	val, err := e.Get(proto.EncodedKey("merged"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expVal := appender("foobar"); string(val) != string(expVal) {
+		t.Errorf("expected merged value %q; got %q", expVal, val)
+	}
+}
@@ -17,15 +17,25 @@
 // Author: Spencer Kimball (spencer.kimball@gmail.com)
 
 #include <algorithm>
+#include <atomic>
 #include <limits>
+#include <memory>
+#include <mutex>
+#include <vector>
 #include <google/protobuf/repeated_field.h>
 #include "rocksdb/cache.h"
 #include "rocksdb/compaction_filter.h"
 #include "rocksdb/db.h"
 #include "rocksdb/env.h"
+#include "rocksdb/filter_policy.h"
+#include "rocksdb/iostats_context.h"
 #include "rocksdb/merge_operator.h"
 #include "rocksdb/options.h"
+#include "rocksdb/rate_limiter.h"
+#include "rocksdb/sst_file_writer.h"
 #include "rocksdb/table.h"
+#include "rocksdb/table_properties.h"
+#include "rocksdb/utilities/checkpoint.h"
 #include "cockroach/proto/api.pb.h"
 #include "cockroach/proto/data.pb.h"
 #include "cockroach/proto/internal.pb.h"
@@ -38,17 +48,42 @@ extern "C" {
 
 extern "C" {
 
+// A TimestampRange is the minimum and maximum timestamps of a set of
+// MVCC versions, encoded by EncodeTimestamp. Both are empty if the set
+// holds no versions.
+struct TimestampRange {
+  std::string min;
+  std::string max;
+};
+
 struct DBBatch {
   rocksdb::WriteBatch rep;
+  // The timestamps of the versions put or merged into the batch.
+  TimestampRange ts;
 };
 
 struct DBEngine {
   rocksdb::DB* rep;
   rocksdb::Env* memenv;
+  // encenv, if set, is the EncryptedEnv of an encrypted database.
+  rocksdb::Env* encenv;
+  std::shared_ptr<rocksdb::Cache> block_cache;
+  // The counters reported by DBGetReadStats().
+  std::atomic<int64_t> disk_bytes_read{0};
+  std::atomic<int64_t> bytes_returned{0};
+  std::atomic<int64_t> seeks{0};
+  std::atomic<int64_t> steps{0};
+  // mem_ts covers the timestamps of the versions written since the
+  // mem-tables were last seen empty, and pending_writes counts the
+  // writes in progress; see MemTableWrite.
+  std::mutex mem_mu;
+  TimestampRange mem_ts;
+  int pending_writes{0};
 };
 
 struct DBIterator {
   rocksdb::Iterator* rep;
+  DBEngine* db;
 };
 
 struct DBSnapshot {
@@ -69,6 +104,12 @@ const rocksdb::Slice kKeyLocalRangeKeyPrefix("\x00\xff\x00\xff\x00\xffk", 7);
 const rocksdb::Slice kKeyLocalResponseCacheSuffix("res-", 4);
 const rocksdb::Slice kKeyLocalTransactionSuffix("\x00\x01txn-", 6);
 
+// NOTE: kMVCCVersionTimestampSize must be kept in sync with the value
+// in storage/engine/mvcc.go.
+const int kMVCCVersionTimestampSize = 12;
+const char kTimestampMinProperty[] = "crdb.ts.min";
+const char kTimestampMaxProperty[] = "crdb.ts.max";
+
 const DBStatus kSuccess = { NULL, 0 };
 
 std::string ToString(DBSlice s) {
@@ -136,6 +177,8 @@ const cockroach::proto::ResponseHeader* GetResponseHeader(const cockroach::proto
     return &rwResp.internal_merge().header();
   } else if (rwResp.has_internal_truncate_log()) {
     return &rwResp.internal_truncate_log().header();
+  } else if (rwResp.has_internal_ingest()) {
+    return &rwResp.internal_ingest().header();
   }
   return NULL;
 }
@@ -739,53 +782,761 @@ class DBLogger : public rocksdb::Logger {
   const bool enabled_;
 };
 
+// EncodeTimestamp encodes a timestamp such that the byte-wise order of
+// encoded timestamps is their order.
+std::string EncodeTimestamp(DBTimestamp ts) {
+  std::string s;
+  for (int shift = 56; shift >= 0; shift -= 8) {
+    s.push_back(static_cast<char>(static_cast<uint64_t>(ts.wall_time) >> shift));
+  }
+  for (int shift = 24; shift >= 0; shift -= 8) {
+    s.push_back(static_cast<char>(static_cast<uint32_t>(ts.logical) >> shift));
+  }
+  return s;
+}
+
+// AddTimestamps extends range to cover the timestamps of other.
+void AddTimestamps(TimestampRange* range, const TimestampRange& other) {
+  if (other.min.empty()) {
+    return;
+  }
+  if (range->min.empty() || other.min < range->min) {
+    range->min = other.min;
+  }
+  if (range->max.empty() || other.max > range->max) {
+    range->max = other.max;
+  }
+}
+
+// AddKeyTimestamp extends range to cover the timestamp of key, if it's
+// an MVCC version.
+void AddKeyTimestamp(TimestampRange* range, const rocksdb::Slice& key) {
+  rocksdb::Slice buf(key);
+  std::string decoded;
+  if (!DecodeBytes(&buf, &decoded) || buf.size() != kMVCCVersionTimestampSize) {
+    // Not an MVCC version.
+    return;
+  }
+  // Versions are encoded with decreasing timestamps; inverting the
+  // bytes yields the increasing encoding of EncodeTimestamp.
+  std::string ts(buf.data(), buf.size());
+  for (size_t i = 0; i < ts.size(); ++i) {
+    ts[i] = ~ts[i];
+  }
+  TimestampRange key_range = { ts, ts };
+  AddTimestamps(range, key_range);
+}
+
+// TimeBoundTblPropCollector records the minimum and maximum timestamps
+// of the MVCC versions in an sstable in its table properties, encoded
+// by EncodeTimestamp. Both are empty if the sstable holds no versions.
+class TimeBoundTblPropCollector : public rocksdb::TablePropertiesCollector {
+ public:
+  virtual const char* Name() const override {
+    return "TimeBoundTblPropCollector";
+  }
+
+  virtual rocksdb::Status Add(const rocksdb::Slice& key, const rocksdb::Slice& value) override {
+    AddKeyTimestamp(&ts_, key);
+    return rocksdb::Status::OK();
+  }
+
+  virtual rocksdb::Status Finish(rocksdb::UserCollectedProperties* properties) override {
+    (*properties)[kTimestampMinProperty] = ts_.min;
+    (*properties)[kTimestampMaxProperty] = ts_.max;
+    return rocksdb::Status::OK();
+  }
+
+  virtual rocksdb::UserCollectedProperties GetReadableProperties() const override {
+    return rocksdb::UserCollectedProperties();
+  }
+
+ private:
+  TimestampRange ts_;
+};
+
+// A MemTableWrite adds the timestamps of the versions of a write to
+// the engine's record of the versions in its mem-tables, and counts
+// the write as pending for its lifetime, which must enclose the write.
+class MemTableWrite {
+ public:
+  MemTableWrite(DBEngine* db, const TimestampRange& ts)
+      : db_(db) {
+    std::lock_guard<std::mutex> lock(db_->mem_mu);
+    AddTimestamps(&db_->mem_ts, ts);
+    db_->pending_writes++;
+  }
+
+  ~MemTableWrite() {
+    std::lock_guard<std::mutex> lock(db_->mem_mu);
+    db_->pending_writes--;
+  }
+
+ private:
+  DBEngine* const db_;
+};
+
+// MemTablesMayHoldTimestamps returns whether the mem-tables of the
+// engine may hold versions with timestamps in [min_ts,max_ts]. Once
+// the mem-tables are empty, with no writes pending, the record of their
+// timestamps starts afresh.
+bool MemTablesMayHoldTimestamps(DBEngine* db, DBTimestamp min_ts, DBTimestamp max_ts) {
+  std::lock_guard<std::mutex> lock(db->mem_mu);
+  if (db->pending_writes > 0) {
+    return true;
+  }
+  uint64_t active = 0, immutable = 0;
+  if (!db->rep->GetIntProperty("rocksdb.num-entries-active-mem-table", &active) ||
+      !db->rep->GetIntProperty("rocksdb.num-entries-imm-mem-tables", &immutable)) {
+    return true;
+  }
+  if (active == 0 && immutable == 0) {
+    db->mem_ts = TimestampRange();
+    return false;
+  }
+  return !db->mem_ts.min.empty() &&
+      db->mem_ts.min <= EncodeTimestamp(max_ts) &&
+      db->mem_ts.max >= EncodeTimestamp(min_ts);
+}
+
+class TimeBoundTblPropCollectorFactory : public rocksdb::TablePropertiesCollectorFactory {
+ public:
+  virtual rocksdb::TablePropertiesCollector* CreateTablePropertiesCollector() override {
+    return new TimeBoundTblPropCollector;
+  }
+
+  virtual const char* Name() const override {
+    return "TimeBoundTblPropCollectorFactory";
+  }
+};
+
+// A KeySpan is the range of keys [start,end] of one or more sstables.
+struct KeySpan {
+  std::string start;
+  std::string end;
+};
+
+// TimeBoundSpans returns the sorted, disjoint key spans of the
+// sstables which may hold MVCC versions with timestamps in
+// [min_ts,max_ts]. Sstables without timestamp properties, written
+// before they were recorded, are always included.
+std::vector<KeySpan> TimeBoundSpans(rocksdb::DB* db, DBTimestamp min_ts, DBTimestamp max_ts) {
+  const std::string min = EncodeTimestamp(min_ts);
+  const std::string max = EncodeTimestamp(max_ts);
+
+  std::vector<rocksdb::LiveFileMetaData> files;
+  db->GetLiveFilesMetaData(&files);
+  rocksdb::TablePropertiesCollection props;
+  if (!db->GetPropertiesOfAllTables(&props).ok()) {
+    props.clear();
+  }
+
+  std::vector<KeySpan> spans;
+  for (const auto& file : files) {
+    auto p = props.find(file.db_path + file.name);
+    if (p != props.end()) {
+      const auto& user_props = p->second->user_collected_properties;
+      auto file_min = user_props.find(kTimestampMinProperty);
+      auto file_max = user_props.find(kTimestampMaxProperty);
+      if (file_min != user_props.end() && file_max != user_props.end() &&
+          (file_min->second.empty() || file_min->second > max || file_max->second < min)) {
+        continue;
+      }
+    }
+    KeySpan span = { file.smallestkey, file.largestkey };
+    spans.push_back(span);
+  }
+
+  std::sort(spans.begin(), spans.end(), [](const KeySpan& a, const KeySpan& b) {
+      return a.start < b.start;
+    });
+  std::vector<KeySpan> merged;
+  for (const auto& span : spans) {
+    if (!merged.empty() && span.start <= merged.back().end) {
+      merged.back().end = std::max(merged.back().end, span.end);
+    } else {
+      merged.push_back(span);
+    }
+  }
+  return merged;
+}
+
+// TimeBoundIterator wraps a database iterator, skipping the keys which
+// fall outside a set of key spans. Only forward iteration skips keys.
+class TimeBoundIterator : public rocksdb::Iterator {
+ public:
+  // TimeBoundIterator takes ownership of iter. spans must be sorted
+  // and disjoint.
+  TimeBoundIterator(rocksdb::Iterator* iter, const std::vector<KeySpan>& spans)
+      : iter_(iter),
+        spans_(spans),
+        done_(false) {
+  }
+
+  virtual ~TimeBoundIterator() {
+    delete iter_;
+  }
+
+  virtual bool Valid() const override {
+    return !done_ && iter_->Valid();
+  }
+
+  virtual void SeekToFirst() override {
+    done_ = false;
+    iter_->SeekToFirst();
+    Skip();
+  }
+
+  virtual void SeekToLast() override {
+    done_ = false;
+    iter_->SeekToLast();
+  }
+
+  virtual void Seek(const rocksdb::Slice& target) override {
+    done_ = false;
+    iter_->Seek(target);
+    Skip();
+  }
+
+  virtual void Next() override {
+    iter_->Next();
+    Skip();
+  }
+
+  virtual void Prev() override {
+    iter_->Prev();
+  }
+
+  virtual rocksdb::Slice key() const override {
+    return iter_->key();
+  }
+
+  virtual rocksdb::Slice value() const override {
+    return iter_->value();
+  }
+
+  virtual rocksdb::Status status() const override {
+    return iter_->status();
+  }
+
+ private:
+  // Skip advances the wrapped iterator to the first key at or after
+  // its position which falls within a span.
+  void Skip() {
+    while (iter_->Valid()) {
+      const rocksdb::Slice key = iter_->key();
+      // Find the first span ending at or after key.
+      auto span = std::lower_bound(spans_.begin(), spans_.end(), key,
+          [](const KeySpan& s, const rocksdb::Slice& k) {
+            return rocksdb::Slice(s.end).compare(k) < 0;
+          });
+      if (span == spans_.end()) {
+        done_ = true;
+        return;
+      }
+      if (rocksdb::Slice(span->start).compare(key) <= 0) {
+        return;
+      }
+      iter_->Seek(span->start);
+    }
+  }
+
+  rocksdb::Iterator* const iter_;
+  const std::vector<KeySpan> spans_;
+  bool done_;
+};
+
+// A DiskReadCounter adds the bytes read from disk by the current
+// thread during its lifetime to the read stats of an engine. RocksDB
+// serves reads on the calling thread and counts the bytes each thread
+// reads from its files, so the difference covers exactly the reads of
+// the enclosing call.
+class DiskReadCounter {
+ public:
+  explicit DiskReadCounter(DBEngine* db)
+      : db_(db),
+        start_(rocksdb::iostats_context.bytes_read) {
+  }
+
+  ~DiskReadCounter() {
+    db_->disk_bytes_read += rocksdb::iostats_context.bytes_read - start_;
+  }
+
+ private:
+  DBEngine* const db_;
+  const uint64_t start_;
+};
+
+// The background compaction threads of the default environment are
+// shared by the databases of the process, so the pool is only grown,
+// to the most threads requested by any of them.
+std::mutex compaction_threads_mu;
+int compaction_threads = 1;
+
+void GrowCompactionThreads(rocksdb::Env* env, int n) {
+  std::lock_guard<std::mutex> lock(compaction_threads_mu);
+  if (n > compaction_threads) {
+    compaction_threads = n;
+    env->SetBackgroundThreads(n, rocksdb::Env::LOW);
+  }
+}
+
+// CountIterPosition adds the key and value at the position of iter,
+// if any, to the bytes returned by its engine.
+void CountIterPosition(DBIterator* iter) {
+  if (iter->rep->Valid()) {
+    iter->db->bytes_returned += iter->rep->key().size() + iter->rep->value().size();
+  }
+}
+
+// SetDBPaths sets the directories in which the sstables of a database
+// are placed.
+void SetDBPaths(rocksdb::Options* options, DBPath* paths, int num_paths) {
+  for (int i = 0; i < num_paths; i++) {
+    options->db_paths.push_back(
+        rocksdb::DbPath(ToString(paths[i].path), paths[i].target_size));
+  }
+}
+
+// The files of an encrypted database begin with a header: this magic
+// string, the length and ID of the key with which the rest of the file
+// is encrypted, and the file's random initialization vector.
+const char kEncryptionMagic[] = "crdbenc1";
+const size_t kEncryptionMagicSize = sizeof(kEncryptionMagic) - 1;
+const size_t kEncryptionIVSize = 16;
+const size_t kMaxEncryptionHeaderSize = kEncryptionMagicSize + 1 + 255 + kEncryptionIVSize;
+
+// rocksDBEncryptionXOR slices the data it's passed as a Go array of at
+// most 1GiB, so larger buffers are passed to it in pieces.
+const size_t kMaxEncryptionChunkSize = 1 << 30;
+
+// A FileCipher encrypts and decrypts the contents of a file of an
+// encrypted database, which are XORed with the AES-CTR keystream of
+// the file's key and initialization vector at their offset. The keys
+// are held by Go, which looks them up by the handle of the database.
+struct FileCipher {
+  int handle;
+  std::string key_id;
+  std::string iv;
+  // The size of the file's header, which precedes the offsets of its
+  // contents. Zero if the file is empty and so has no header.
+  size_t header_size;
+
+  rocksdb::Status XOR(uint64_t offset, char* data, size_t n) const {
+    while (n > 0) {
+      const size_t chunk = std::min(n, kMaxEncryptionChunkSize);
+      if (rocksDBEncryptionXOR(handle, const_cast<char*>(key_id.data()), key_id.size(),
+                               const_cast<char*>(iv.data()), offset, data, chunk) != 0) {
+        return rocksdb::Status::IOError("unknown encryption key", key_id);
+      }
+      offset += chunk;
+      data += chunk;
+      n -= chunk;
+    }
+    return rocksdb::Status::OK();
+  }
+};
+
+// ReadEncryptionHeader parses the header of the file fname into
+// cipher, calling read(offset, n, result, scratch) to read its bytes.
+template <typename ReadFn>
+rocksdb::Status ReadEncryptionHeader(const std::string& fname, ReadFn read, FileCipher* cipher) {
+  char scratch[kMaxEncryptionHeaderSize];
+  rocksdb::Slice prefix;
+  rocksdb::Status status = read(0, kEncryptionMagicSize + 1, &prefix, scratch);
+  if (!status.ok()) {
+    return status;
+  }
+  if (prefix.empty()) {
+    cipher->header_size = 0;
+    return rocksdb::Status::OK();
+  }
+  if (prefix.size() < kEncryptionMagicSize + 1 ||
+      memcmp(prefix.data(), kEncryptionMagic, kEncryptionMagicSize) != 0) {
+    return rocksdb::Status::Corruption("file is not encrypted", fname);
+  }
+  const size_t key_id_size = static_cast<unsigned char>(prefix[kEncryptionMagicSize]);
+  rocksdb::Slice rest;
+  status = read(prefix.size(), key_id_size + kEncryptionIVSize, &rest, scratch + prefix.size());
+  if (!status.ok()) {
+    return status;
+  }
+  if (rest.size() < key_id_size + kEncryptionIVSize) {
+    return rocksdb::Status::Corruption("truncated encryption header", fname);
+  }
+  cipher->key_id.assign(rest.data(), key_id_size);
+  cipher->iv.assign(rest.data() + key_id_size, kEncryptionIVSize);
+  cipher->header_size = prefix.size() + rest.size();
+  return rocksdb::Status::OK();
+}
+
+// CopyToScratch copies the bytes of result to scratch, if they aren't
+// already there, so that they may be decrypted in place.
+void CopyToScratch(rocksdb::Slice* result, char* scratch) {
+  if (result->data() != scratch) {
+    memmove(scratch, result->data(), result->size());
+    *result = rocksdb::Slice(scratch, result->size());
+  }
+}
+
+class EncryptedSequentialFile : public rocksdb::SequentialFile {
+ public:
+  EncryptedSequentialFile(std::unique_ptr<rocksdb::SequentialFile> target, const FileCipher& cipher)
+      : target_(std::move(target)),
+        cipher_(cipher),
+        offset_(0) {
+  }
+
+  virtual rocksdb::Status Read(size_t n, rocksdb::Slice* result, char* scratch) override {
+    if (cipher_.header_size == 0) {
+      *result = rocksdb::Slice();
+      return rocksdb::Status::OK();
+    }
+    rocksdb::Status status = target_->Read(n, result, scratch);
+    if (!status.ok()) {
+      return status;
+    }
+    CopyToScratch(result, scratch);
+    status = cipher_.XOR(offset_, scratch, result->size());
+    offset_ += result->size();
+    return status;
+  }
+
+  virtual rocksdb::Status Skip(uint64_t n) override {
+    rocksdb::Status status = target_->Skip(n);
+    if (status.ok()) {
+      offset_ += n;
+    }
+    return status;
+  }
+
+ private:
+  std::unique_ptr<rocksdb::SequentialFile> target_;
+  const FileCipher cipher_;
+  uint64_t offset_;
+};
+
+class EncryptedRandomAccessFile : public rocksdb::RandomAccessFile {
+ public:
+  EncryptedRandomAccessFile(std::unique_ptr<rocksdb::RandomAccessFile> target, const FileCipher& cipher)
+      : target_(std::move(target)),
+        cipher_(cipher) {
+  }
+
+  virtual rocksdb::Status Read(uint64_t offset, size_t n, rocksdb::Slice* result,
+                               char* scratch) const override {
+    if (cipher_.header_size == 0) {
+      *result = rocksdb::Slice();
+      return rocksdb::Status::OK();
+    }
+    rocksdb::Status status = target_->Read(offset + cipher_.header_size, n, result, scratch);
+    if (!status.ok()) {
+      return status;
+    }
+    CopyToScratch(result, scratch);
+    return cipher_.XOR(offset, scratch, result->size());
+  }
+
+  virtual size_t GetUniqueId(char* id, size_t max_size) const override {
+    return target_->GetUniqueId(id, max_size);
+  }
+
+  virtual void Hint(AccessPattern pattern) override {
+    target_->Hint(pattern);
+  }
+
+  virtual rocksdb::Status InvalidateCache(size_t offset, size_t length) override {
+    return target_->InvalidateCache(offset + cipher_.header_size, length);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::RandomAccessFile> target_;
+  const FileCipher cipher_;
+};
+
+class EncryptedWritableFile : public rocksdb::WritableFile {
+ public:
+  EncryptedWritableFile(std::unique_ptr<rocksdb::WritableFile> target, const FileCipher& cipher)
+      : target_(std::move(target)),
+        cipher_(cipher),
+        offset_(0) {
+  }
+
+  virtual rocksdb::Status Append(const rocksdb::Slice& data) override {
+    buf_.assign(data.data(), data.size());
+    rocksdb::Status status = cipher_.XOR(offset_, &buf_[0], buf_.size());
+    if (!status.ok()) {
+      return status;
+    }
+    status = target_->Append(buf_);
+    if (status.ok()) {
+      offset_ += data.size();
+    }
+    return status;
+  }
+
+  virtual rocksdb::Status Truncate(uint64_t size) override {
+    rocksdb::Status status = target_->Truncate(size + cipher_.header_size);
+    if (status.ok()) {
+      offset_ = size;
+    }
+    return status;
+  }
+
+  virtual rocksdb::Status Close() override {
+    return target_->Close();
+  }
+
+  virtual rocksdb::Status Flush() override {
+    return target_->Flush();
+  }
+
+  virtual rocksdb::Status Sync() override {
+    return target_->Sync();
+  }
+
+  virtual rocksdb::Status Fsync() override {
+    return target_->Fsync();
+  }
+
+  virtual bool IsSyncThreadSafe() const override {
+    return target_->IsSyncThreadSafe();
+  }
+
+  virtual uint64_t GetFileSize() override {
+    return offset_;
+  }
+
+  virtual rocksdb::Status RangeSync(uint64_t offset, uint64_t nbytes) override {
+    return target_->RangeSync(offset + cipher_.header_size, nbytes);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::WritableFile> target_;
+  const FileCipher cipher_;
+  // The offset of the next byte appended, and the buffer into which
+  // appended bytes are encrypted.
+  uint64_t offset_;
+  std::string buf_;
+};
+
+// An EncryptedEnv encrypts every file written by a database, including
+// its sstables, write-ahead log and manifest, with the key of key_id,
+// and decrypts the files it reads with the keys named by their
+// headers. Files are otherwise handled by the wrapped environment.
+class EncryptedEnv : public rocksdb::EnvWrapper {
+ public:
+  EncryptedEnv(rocksdb::Env* target, int handle, const std::string& key_id)
+      : rocksdb::EnvWrapper(target),
+        handle_(handle),
+        key_id_(key_id) {
+  }
+
+  virtual rocksdb::Status NewSequentialFile(const std::string& fname,
+                                            std::unique_ptr<rocksdb::SequentialFile>* result,
+                                            const rocksdb::EnvOptions& options) override {
+    std::unique_ptr<rocksdb::SequentialFile> file;
+    rocksdb::Status status = target()->NewSequentialFile(fname, &file, options);
+    if (!status.ok()) {
+      return status;
+    }
+    FileCipher cipher{handle_};
+    status = ReadEncryptionHeader(fname, [&file](uint64_t offset, size_t n, rocksdb::Slice* result, char* scratch) {
+        return file->Read(n, result, scratch);
+      }, &cipher);
+    if (!status.ok()) {
+      return status;
+    }
+    result->reset(new EncryptedSequentialFile(std::move(file), cipher));
+    return rocksdb::Status::OK();
+  }
+
+  virtual rocksdb::Status NewRandomAccessFile(const std::string& fname,
+                                              std::unique_ptr<rocksdb::RandomAccessFile>* result,
+                                              const rocksdb::EnvOptions& options) override {
+    std::unique_ptr<rocksdb::RandomAccessFile> file;
+    rocksdb::Status status = target()->NewRandomAccessFile(fname, &file, options);
+    if (!status.ok()) {
+      return status;
+    }
+    FileCipher cipher{handle_};
+    status = ReadEncryptionHeader(fname, [&file](uint64_t offset, size_t n, rocksdb::Slice* result, char* scratch) {
+        return file->Read(offset, n, result, scratch);
+      }, &cipher);
+    if (!status.ok()) {
+      return status;
+    }
+    result->reset(new EncryptedRandomAccessFile(std::move(file), cipher));
+    return rocksdb::Status::OK();
+  }
+
+  virtual rocksdb::Status NewWritableFile(const std::string& fname,
+                                          std::unique_ptr<rocksdb::WritableFile>* result,
+                                          const rocksdb::EnvOptions& options) override {
+    std::unique_ptr<rocksdb::WritableFile> file;
+    rocksdb::Status status = target()->NewWritableFile(fname, &file, options);
+    if (!status.ok()) {
+      return status;
+    }
+    FileCipher cipher{handle_, key_id_, std::string(kEncryptionIVSize, '\0')};
+    if (rocksDBEncryptionIV(&cipher.iv[0], cipher.iv.size()) != 0) {
+      return rocksdb::Status::IOError("unable to generate an initialization vector", fname);
+    }
+    std::string header(kEncryptionMagic, kEncryptionMagicSize);
+    header.push_back(static_cast<char>(key_id_.size()));
+    header.append(key_id_);
+    header.append(cipher.iv);
+    cipher.header_size = header.size();
+    status = file->Append(header);
+    if (!status.ok()) {
+      return status;
+    }
+    result->reset(new EncryptedWritableFile(std::move(file), cipher));
+    return rocksdb::Status::OK();
+  }
+
+  virtual rocksdb::Status ReuseWritableFile(const std::string& fname,
+                                            const std::string& old_fname,
+                                            std::unique_ptr<rocksdb::WritableFile>* result,
+                                            const rocksdb::EnvOptions& options) override {
+    // The reused file is rewritten from its start, with a new header.
+    rocksdb::Status status = target()->RenameFile(old_fname, fname);
+    if (!status.ok()) {
+      return status;
+    }
+    return NewWritableFile(fname, result, options);
+  }
+
+  virtual rocksdb::Status GetFileSize(const std::string& fname, uint64_t* size) override {
+    FileCipher cipher{handle_};
+    rocksdb::Status status = ReadHeader(fname, &cipher);
+    if (!status.ok()) {
+      return status;
+    }
+    status = target()->GetFileSize(fname, size);
+    if (status.ok()) {
+      *size -= cipher.header_size;
+    }
+    return status;
+  }
+
+  // GetKeyID sets key_id to the ID of the key with which the file
+  // fname is encrypted, or clears it if the file is empty.
+  rocksdb::Status GetKeyID(const std::string& fname, std::string* key_id) {
+    FileCipher cipher{handle_};
+    rocksdb::Status status = ReadHeader(fname, &cipher);
+    *key_id = cipher.key_id;
+    return status;
+  }
+
+ private:
+  rocksdb::Status ReadHeader(const std::string& fname, FileCipher* cipher) {
+    std::unique_ptr<rocksdb::RandomAccessFile> file;
+    rocksdb::Status status = target()->NewRandomAccessFile(fname, &file, rocksdb::EnvOptions());
+    if (!status.ok()) {
+      return status;
+    }
+    return ReadEncryptionHeader(fname, [&file](uint64_t offset, size_t n, rocksdb::Slice* result, char* scratch) {
+        return file->Read(offset, n, result, scratch);
+      }, cipher);
+  }
+
+  const int handle_;
+  const std::string key_id_;
+};
+
 }  // namespace
 
 DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   rocksdb::BlockBasedTableOptions table_options;
   table_options.block_cache = rocksdb::NewLRUCache(
       db_opts.cache_size, 4 /* num-shard-bits */);
+  if (db_opts.bloom_bits > 0) {
+    table_options.filter_policy.reset(rocksdb::NewBloomFilterPolicy(db_opts.bloom_bits));
+  }
 
   rocksdb::Options options;
   options.allow_os_buffer = db_opts.allow_os_buffer;
-  options.compression = rocksdb::kSnappyCompression;
+  options.compression = static_cast<rocksdb::CompressionType>(db_opts.compression);
   options.compaction_filter_factory.reset(new DBCompactionFilterFactory());
   options.create_if_missing = true;
   options.info_log.reset(new DBLogger(db_opts.logging_enabled));
   options.merge_operator.reset(new DBMergeOperator);
   options.table_factory.reset(rocksdb::NewBlockBasedTableFactory(table_options));
+  options.table_properties_collector_factories.push_back(
+      std::make_shared<TimeBoundTblPropCollectorFactory>());
   options.write_buffer_size = 64 << 20;           // 64 MB
   options.target_file_size_base = 64 << 20;       // 64 MB
   options.max_bytes_for_level_base = 512 << 20;   // 512 MB
+  if (db_opts.write_buffer_size > 0) {
+    options.write_buffer_size = db_opts.write_buffer_size;
+  }
+  if (db_opts.compaction_threads > 0) {
+    options.max_background_compactions = db_opts.compaction_threads;
+    GrowCompactionThreads(rocksdb::Env::Default(), db_opts.compaction_threads);
+  }
+  if (db_opts.rate_limit > 0) {
+    options.rate_limiter.reset(rocksdb::NewGenericRateLimiter(db_opts.rate_limit));
+  }
+  SetDBPaths(&options, db_opts.paths, db_opts.num_paths);
 
   rocksdb::Env* memenv = NULL;
   if (dir.len == 0) {
     memenv = rocksdb::NewMemEnv(rocksdb::Env::Default());
     options.env = memenv;
   }
+  std::unique_ptr<rocksdb::Env> encenv;
+  if (db_opts.encryption_handle != 0) {
+    encenv.reset(new EncryptedEnv(options.env, db_opts.encryption_handle,
+                                  ToString(db_opts.encryption_key_id)));
+    options.env = encenv.get();
+  }
 
   rocksdb::DB *db_ptr;
-  rocksdb::Status status = rocksdb::DB::Open(options, ToString(dir), &db_ptr);
+  rocksdb::Status status;
+  if (db_opts.read_only) {
+    options.create_if_missing = false;
+    status = rocksdb::DB::OpenForReadOnly(options, ToString(dir), &db_ptr);
+  } else {
+    status = rocksdb::DB::Open(options, ToString(dir), &db_ptr);
+  }
   if (!status.ok()) {
     return ToDBStatus(status);
   }
-  *db = new DBEngine;
+  *db = new DBEngine();
   (*db)->rep = db_ptr;
   (*db)->memenv = memenv;
+  (*db)->encenv = encenv.release();
+  (*db)->block_cache = table_options.block_cache;
   return kSuccess;
 }
 
-DBStatus DBDestroy(DBSlice dir) {
+DBStatus DBDestroy(DBSlice dir, DBPath* paths, int num_paths) {
   rocksdb::Options options;
+  SetDBPaths(&options, paths, num_paths);
   return ToDBStatus(rocksdb::DestroyDB(ToString(dir), options));
 }
 
 void DBClose(DBEngine* db) {
   delete db->rep;
+  delete db->encenv;
   delete db->memenv;
   delete db;
 }
 
+void DBSetCacheSize(DBEngine* db, int64_t size) {
+  db->block_cache->SetCapacity(size);
+}
+
+DBReadStats DBGetReadStats(DBEngine* db) {
+  DBReadStats stats;
+  stats.disk_bytes_read = db->disk_bytes_read;
+  stats.bytes_returned = db->bytes_returned;
+  stats.seeks = db->seeks;
+  stats.steps = db->steps;
+  return stats;
+}
+
 DBStatus DBFlush(DBEngine* db) {
   rocksdb::FlushOptions options;
   options.wait = true;
@@ -821,17 +1572,184 @@ uint64_t DBApproximateSize(DBEngine* db, DBSlice start, DBSlice end) {
   return result;
 }
 
+DBCompactionStats DBGetCompactionStats(DBEngine* db) {
+  const rocksdb::Options options = db->rep->GetOptions();
+  DBCompactionStats stats = {};
+  stats.num_levels = options.num_levels;
+  stats.levels = static_cast<DBLevelStats*>(calloc(options.num_levels, sizeof(DBLevelStats)));
+  stats.l0_compaction_files = options.level0_file_num_compaction_trigger;
+  stats.l0_slowdown_files = options.level0_slowdown_writes_trigger;
+  stats.l0_stop_files = options.level0_stop_writes_trigger;
+
+  std::vector<rocksdb::LiveFileMetaData> files;
+  db->rep->GetLiveFilesMetaData(&files);
+  for (size_t i = 0; i < files.size(); i++) {
+    if (files[i].level < 0 || files[i].level >= options.num_levels) {
+      continue;
+    }
+    stats.levels[files[i].level].files++;
+    stats.levels[files[i].level].bytes += files[i].size;
+  }
+
+  // Level 0 is compacted whole once it holds the trigger's number of
+  // files; each later level is compacted into the next by what it
+  // holds beyond its target size.
+  if (stats.levels[0].files >= stats.l0_compaction_files) {
+    stats.pending_bytes += stats.levels[0].bytes;
+  }
+  double target = options.max_bytes_for_level_base;
+  for (int level = 1; level < options.num_levels; level++) {
+    stats.levels[level].target_bytes = static_cast<int64_t>(target);
+    if (stats.levels[level].bytes > stats.levels[level].target_bytes) {
+      stats.pending_bytes += stats.levels[level].bytes - stats.levels[level].target_bytes;
+    }
+    target *= options.max_bytes_for_level_multiplier;
+  }
+
+  std::string value;
+  if (db->rep->GetProperty("rocksdb.compaction-pending", &value)) {
+    stats.compaction_pending = value != "0";
+  }
+  if (db->rep->GetProperty("rocksdb.stats", &value)) {
+    stats.stats = ToDBString(value);
+  }
+  return stats;
+}
+
+DBSSTable* DBGetSSTables(DBEngine* db, int* n) {
+  std::vector<rocksdb::LiveFileMetaData> files;
+  db->rep->GetLiveFilesMetaData(&files);
+  *n = files.size();
+  if (files.empty()) {
+    return NULL;
+  }
+  DBSSTable* tables = static_cast<DBSSTable*>(malloc(files.size() * sizeof(DBSSTable)));
+  for (size_t i = 0; i < files.size(); i++) {
+    const std::string name = files[i].db_path + files[i].name;
+    tables[i].name = ToDBString(name);
+    tables[i].smallest_key = ToDBString(files[i].smallestkey);
+    tables[i].largest_key = ToDBString(files[i].largestkey);
+    std::string key_id;
+    if (db->encenv != NULL) {
+      static_cast<EncryptedEnv*>(db->encenv)->GetKeyID(name, &key_id);
+    }
+    tables[i].key_id = ToDBString(key_id);
+  }
+  return tables;
+}
+
+DBStatus DBVerifyRange(DBEngine* db, DBSlice start, DBSlice end) {
+  rocksdb::ReadOptions options;
+  options.verify_checksums = true;
+  options.fill_cache = false;
+  std::unique_ptr<rocksdb::Iterator> iter(db->rep->NewIterator(options));
+  const rocksdb::Slice end_key = ToSlice(end);
+  for (iter->Seek(ToSlice(start)); iter->Valid(); iter->Next()) {
+    if (iter->key().compare(end_key) > 0) {
+      break;
+    }
+    // The value is read so that the block holding it is verified.
+    iter->value();
+  }
+  return ToDBStatus(iter->status());
+}
+
+struct DBSSTableWriter {
+  rocksdb::Options options;
+  std::unique_ptr<rocksdb::SstFileWriter> rep;
+};
+
+DBStatus DBSSTableWriterOpen(DBSSTableWriter** w, DBEngine* db, DBSlice path) {
+  std::unique_ptr<DBSSTableWriter> writer(new DBSSTableWriter);
+  if (db != NULL) {
+    writer->options.env = db->rep->GetEnv();
+  }
+  // The sstable records the timestamp bounds of its versions, as
+  // those flushed by a database do, so that time-bound iterators
+  // don't skip it.
+  writer->options.table_properties_collector_factories.push_back(
+      std::make_shared<TimeBoundTblPropCollectorFactory>());
+  writer->rep.reset(new rocksdb::SstFileWriter(
+      rocksdb::EnvOptions(), writer->options, writer->options.comparator));
+  rocksdb::Status status = writer->rep->Open(ToString(path));
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+  *w = writer.release();
+  return kSuccess;
+}
+
+DBStatus DBSSTableWriterAdd(DBSSTableWriter* w, DBSlice key, DBSlice value) {
+  return ToDBStatus(w->rep->Add(ToSlice(key), ToSlice(value)));
+}
+
+DBStatus DBSSTableWriterDelete(DBSSTableWriter* w, DBSlice key) {
+  return ToDBStatus(w->rep->Delete(ToSlice(key)));
+}
+
+DBStatus DBSSTableWriterFinish(DBSSTableWriter* w) {
+  return ToDBStatus(w->rep->Finish());
+}
+
+void DBSSTableWriterClose(DBSSTableWriter* w) {
+  delete w;
+}
+
+DBStatus DBIngestExternalFiles(DBEngine* db, DBSlice* paths, int num_paths, bool move_files) {
+  if (db->memenv != NULL) {
+    return ToDBStatus(rocksdb::Status::NotSupported("in-memory engines can't ingest files"));
+  }
+  std::vector<std::string> files;
+  for (int i = 0; i < num_paths; i++) {
+    files.push_back(ToString(paths[i]));
+    // The sstables ingested by an encrypted database must have been
+    // written through its environment, and so be encrypted too.
+    std::string key_id;
+    if (db->encenv != NULL) {
+      rocksdb::Status status = static_cast<EncryptedEnv*>(db->encenv)->GetKeyID(files.back(), &key_id);
+      if (!status.ok()) {
+        return ToDBStatus(status);
+      }
+    }
+  }
+  rocksdb::IngestExternalFileOptions options;
+  options.move_files = move_files;
+  return ToDBStatus(db->rep->IngestExternalFile(files, options));
+}
+
+DBStatus DBCheckpoint(DBEngine* db, DBSlice dir) {
+  if (db->memenv != NULL) {
+    return ToDBStatus(rocksdb::Status::NotSupported("in-memory engines can't be checkpointed"));
+  }
+  rocksdb::Checkpoint* checkpoint;
+  rocksdb::Status status = rocksdb::Checkpoint::Create(db->rep, &checkpoint);
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+  status = checkpoint->CreateCheckpoint(ToString(dir));
+  delete checkpoint;
+  return ToDBStatus(status);
+}
+
 DBStatus DBPut(DBEngine* db, DBSlice key, DBSlice value) {
+  TimestampRange ts;
+  AddKeyTimestamp(&ts, ToSlice(key));
+  MemTableWrite write(db, ts);
   rocksdb::WriteOptions options;
   return ToDBStatus(db->rep->Put(options, ToSlice(key), ToSlice(value)));
 }
 
 DBStatus DBMerge(DBEngine* db, DBSlice key, DBSlice value) {
+  TimestampRange ts;
+  AddKeyTimestamp(&ts, ToSlice(key));
+  MemTableWrite write(db, ts);
   rocksdb::WriteOptions options;
   return ToDBStatus(db->rep->Merge(options, ToSlice(key), ToSlice(value)));
 }
 
 DBStatus DBGet(DBEngine* db, DBSnapshot* snap, DBSlice key, DBString* value) {
+  DiskReadCounter counter(db);
+  db->seeks++;
   std::string tmp;
   rocksdb::Status s = db->rep->Get(MakeReadOptions(snap), ToSlice(key), &tmp);
   if (!s.ok()) {
@@ -844,6 +1762,7 @@ DBStatus DBGet(DBEngine* db, DBSnapshot* snap, DBSlice key, DBString* value) {
     }
     return ToDBStatus(s);
   }
+  db->bytes_returned += tmp.size();
   *value = ToDBString(tmp);
   return kSuccess;
 }
@@ -853,8 +1772,10 @@ DBStatus DBDelete(DBEngine* db, DBSlice key) {
   return ToDBStatus(db->rep->Delete(options, ToSlice(key)));
 }
 
-DBStatus DBWrite(DBEngine* db, DBBatch *batch) {
+DBStatus DBWrite(DBEngine* db, DBBatch *batch, bool sync) {
+  MemTableWrite write(db, batch->ts);
   rocksdb::WriteOptions options;
+  options.sync = sync;
   return ToDBStatus(db->rep->Write(options, &batch->rep));
 }
 
@@ -873,6 +1794,29 @@ void DBSnapshotRelease(DBSnapshot* snap) {
 DBIterator* DBNewIter(DBEngine* db, DBSnapshot* snap) {
   DBIterator* iter = new DBIterator;
   iter->rep = db->rep->NewIterator(MakeReadOptions(snap));
+  iter->db = db;
+  return iter;
+}
+
+DBIterator* DBNewTimeBoundIter(DBEngine* db, DBSnapshot* snap,
+                               DBTimestamp min_ts, DBTimestamp max_ts) {
+  DBIterator* iter = new DBIterator;
+  iter->db = db;
+  // The iterator is created before the mem-tables are examined so
+  // that the versions it observes within the time window are in the
+  // sstables whose spans it's given. The mem-tables are only flushed
+  // if they may hold such versions.
+  rocksdb::Iterator* rep = db->rep->NewIterator(MakeReadOptions(snap));
+  if (MemTablesMayHoldTimestamps(db, min_ts, max_ts)) {
+    rocksdb::FlushOptions flush_options;
+    flush_options.wait = true;
+    if (!db->rep->Flush(flush_options).ok()) {
+      // Without the flush, mem-table data may fall outside the spans.
+      iter->rep = rep;
+      return iter;
+    }
+  }
+  iter->rep = new TimeBoundIterator(rep, TimeBoundSpans(db->rep, min_ts, max_ts));
   return iter;
 }
 
@@ -882,15 +1826,24 @@ void DBIterDestroy(DBIterator* iter) {
 }
 
 void DBIterSeek(DBIterator* iter, DBSlice key) {
+  DiskReadCounter counter(iter->db);
+  iter->db->seeks++;
   iter->rep->Seek(ToSlice(key));
+  CountIterPosition(iter);
 }
 
 void DBIterSeekToFirst(DBIterator* iter) {
+  DiskReadCounter counter(iter->db);
+  iter->db->seeks++;
   iter->rep->SeekToFirst();
+  CountIterPosition(iter);
 }
 
 void DBIterSeekToLast(DBIterator* iter) {
+  DiskReadCounter counter(iter->db);
+  iter->db->seeks++;
   iter->rep->SeekToLast();
+  CountIterPosition(iter);
 }
 
 int DBIterValid(DBIterator* iter) {
@@ -898,7 +1851,10 @@ int DBIterValid(DBIterator* iter) {
 }
 
 void DBIterNext(DBIterator* iter) {
+  DiskReadCounter counter(iter->db);
+  iter->db->steps++;
   iter->rep->Next();
+  CountIterPosition(iter);
 }
 
 DBSlice DBIterKey(DBIterator* iter) {
@@ -922,10 +1878,12 @@ void DBBatchDestroy(DBBatch* batch) {
 }
 
 void DBBatchPut(DBBatch* batch, DBSlice key, DBSlice value) {
+  AddKeyTimestamp(&batch->ts, ToSlice(key));
   batch->rep.Put(ToSlice(key), ToSlice(value));
 }
 
 void DBBatchMerge(DBBatch* batch, DBSlice key, DBSlice value) {
+  AddKeyTimestamp(&batch->ts, ToSlice(key));
   batch->rep.Merge(ToSlice(key), ToSlice(value));
 }
 
@@ -48,24 +48,111 @@ typedef struct DBEngine DBEngine;
 typedef struct DBIterator DBIterator;
 typedef struct DBSnapshot DBSnapshot;
 
-// DBOptions contains local database options.
+// A DBTimestamp is a hybrid logical clock timestamp, as in
+// proto.Timestamp.
+typedef struct {
+  int64_t wall_time;
+  int32_t logical;
+} DBTimestamp;
+
+// A DBPath is a directory holding sstables of a database and the
+// number of bytes of sstables it's intended to hold.
+typedef struct {
+  DBSlice path;
+  int64_t target_size;
+} DBPath;
+
+// DBOptions contains local database options. compression is a
+// rocksdb::CompressionType. Zero write_buffer_size,
+// compaction_threads and bloom_bits leave the defaults; zero
+// bloom_bits builds no bloom filters. rate_limit, if positive, limits
+// the bytes per second written by flushes and compactions. If
+// num_paths is positive, the sstables are placed in paths rather than
+// the database's directory: each level in the first path with room
+// for it and the levels before it. If read_only is set, the database
+// must exist and rejects writes. If encryption_handle is non-zero,
+// every file of the database is encrypted with the key of
+// encryption_key_id; the keys are looked up by the handle with
+// rocksDBEncryptionXOR.
 typedef struct {
   int64_t cache_size;
   bool allow_os_buffer;
   bool logging_enabled;
+  int64_t write_buffer_size;
+  int compaction_threads;
+  int bloom_bits;
+  int compression;
+  int64_t rate_limit;
+  DBPath* paths;
+  int num_paths;
+  bool read_only;
+  int encryption_handle;
+  DBSlice encryption_key_id;
 } DBOptions;
 
 // Opens the database located in "dir", creating it if it doesn't
-// exist.
+// exist, unless it's opened read-only.
 DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions options);
 
-// Destroys the database located in "dir". As the name implies, this
-// operation is destructive. Use with caution.
-DBStatus DBDestroy(DBSlice dir);
+// Destroys the database located in "dir", including its sstables in
+// the num_paths paths, if any. As the name implies, this operation is
+// destructive. Use with caution.
+DBStatus DBDestroy(DBSlice dir, DBPath* paths, int num_paths);
 
 // Closes the database, freeing memory and other resources.
 void DBClose(DBEngine* db);
 
+// Sets the capacity of the database's block cache. If it's shrunk,
+// unused entries are evicted to bring the cache within its capacity.
+void DBSetCacheSize(DBEngine* db, int64_t size);
+
+// DBReadStats counts the reads served by a database since it was
+// opened. disk_bytes_read is the number of bytes read from its files
+// to serve gets and iterators, and bytes_returned the number of bytes
+// of the values and iterator keys and values they returned. seeks
+// counts gets and iterator seeks and steps the iterator steps to the
+// next key.
+typedef struct {
+  int64_t disk_bytes_read;
+  int64_t bytes_returned;
+  int64_t seeks;
+  int64_t steps;
+} DBReadStats;
+
+// Returns the read stats of the database.
+DBReadStats DBGetReadStats(DBEngine* db);
+
+// DBLevelStats describes the sstables of a level of the database:
+// their number and total size and the size the level is compacted
+// to stay within, which is zero for level 0, whose limit is its
+// number of files.
+typedef struct {
+  int files;
+  int64_t bytes;
+  int64_t target_bytes;
+} DBLevelStats;
+
+// DBCompactionStats describes the levels of a database and its
+// compaction debt. levels holds num_levels entries and, like stats,
+// the database's own report of its compactions and stalls, must be
+// freed. pending_bytes estimates the bytes which must be compacted
+// for every level to be within its target. Level 0 is compacted once
+// it holds l0_compaction_files files; writes are slowed once it holds
+// l0_slowdown_files and stopped at l0_stop_files.
+typedef struct {
+  DBLevelStats* levels;
+  int num_levels;
+  int64_t pending_bytes;
+  bool compaction_pending;
+  int l0_compaction_files;
+  int l0_slowdown_files;
+  int l0_stop_files;
+  DBString stats;
+} DBCompactionStats;
+
+// Returns the compaction stats of the database.
+DBCompactionStats DBGetCompactionStats(DBEngine* db);
+
 // Flushes all mem-table data to disk, blocking until the operation is
 // complete.
 DBStatus DBFlush(DBEngine* db);
@@ -84,6 +171,65 @@ DBStatus DBCompactRange(DBEngine* db, DBSlice* start, DBSlice* end);
 // range [start,end].
 uint64_t DBApproximateSize(DBEngine* db, DBSlice start, DBSlice end);
 
+// DBSSTable describes a live sstable of the database: the path of its
+// file, the smallest and largest keys it holds and, if the database is
+// encrypted, the ID of the key with which the file is encrypted.
+typedef struct {
+  DBString name;
+  DBString smallest_key;
+  DBString largest_key;
+  DBString key_id;
+} DBSSTable;
+
+// Returns the live sstables of the database, setting n to their
+// number. The array and the strings of its elements must be freed.
+DBSSTable* DBGetSSTables(DBEngine* db, int* n);
+
+// Reads the keys and values in the range [start,end], verifying the
+// checksum of each block read from the database's files. The blocks
+// aren't added to the block cache. Returns an error describing the
+// first corrupt or unreadable block.
+DBStatus DBVerifyRange(DBEngine* db, DBSlice start, DBSlice end);
+
+// A DBSSTableWriter builds an sstable outside of any database, to be
+// linked into one by DBIngestExternalFiles.
+typedef struct DBSSTableWriter DBSSTableWriter;
+
+// Creates a writer of a new sstable at path. If db is non-NULL, the
+// sstable is written through the database's environment, so that
+// it's encrypted if the database is.
+DBStatus DBSSTableWriterOpen(DBSSTableWriter** w, DBEngine* db, DBSlice path);
+
+// Adds "key" and "value" to the sstable. Keys must be added in
+// increasing order.
+DBStatus DBSSTableWriterAdd(DBSSTableWriter* w, DBSlice key, DBSlice value);
+
+// Adds a deletion of "key" to the sstable, which shadows the key's
+// value in the database once the sstable is ingested. Keys must be
+// added in increasing order.
+DBStatus DBSSTableWriterDelete(DBSSTableWriter* w, DBSlice key);
+
+// Finishes and syncs the sstable.
+DBStatus DBSSTableWriterFinish(DBSSTableWriter* w);
+
+// Frees the writer. The file of an unfinished sstable is left
+// incomplete and must be removed.
+void DBSSTableWriterClose(DBSSTableWriter* w);
+
+// Atomically links the sstables at paths, whose keys must not overlap,
+// into the database, bypassing its memtable. If move_files is true,
+// the files are moved into the database rather than copied. In-memory
+// databases can't ingest files, and encrypted ones only those written
+// through them.
+DBStatus DBIngestExternalFiles(DBEngine* db, DBSlice* paths, int num_paths,
+                               bool move_files);
+
+// Creates a consistent copy of the database in dir, which must not
+// exist. The database's sstables are hard-linked into dir if it's on
+// the same filesystem and copied otherwise; the rest of its files are
+// copied. In-memory databases can't be checkpointed.
+DBStatus DBCheckpoint(DBEngine* db, DBSlice dir);
+
 // Sets the database entry for "key" to "value".
 DBStatus DBPut(DBEngine* db, DBSlice key, DBSlice value);
 
@@ -99,8 +245,9 @@ DBStatus DBGet(DBEngine* db, DBSnapshot* snapshot, DBSlice key, DBString* value)
 DBStatus DBDelete(DBEngine* db, DBSlice key);
 
 // Applies a batch of operations (puts, merges and deletes) to the
-// database atomically.
-DBStatus DBWrite(DBEngine* db, DBBatch *batch);
+// database atomically. If sync is true, the write-ahead log is synced
+// before returning.
+DBStatus DBWrite(DBEngine* db, DBBatch *batch, bool sync);
 
 // Creates a new snapshot of the database for use in DBGet() and
 // DBNewIter(). It is the callers responsibility to call
@@ -116,6 +263,15 @@ void DBSnapshotRelease(DBSnapshot* snapshot);
 // callers responsibility to call DBIterDestroy().
 DBIterator* DBNewIter(DBEngine* db, DBSnapshot* snapshot);
 
+// Creates a new database iterator like DBNewIter(), which skips the
+// key spans of the sstables holding no MVCC versions with timestamps
+// in [min_ts,max_ts], whose timestamps are recorded in their table
+// properties. The mem-tables are flushed first if they may hold
+// versions within the time window. Keys outside the time window may
+// still be returned; only forward iteration skips keys.
+DBIterator* DBNewTimeBoundIter(DBEngine* db, DBSnapshot* snapshot,
+                               DBTimestamp min_ts, DBTimestamp max_ts);
+
 // Destroys an iterator, freeing up any associated memory.
 void DBIterDestroy(DBIterator* iter);
 
@@ -0,0 +1,239 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+// #include <stdlib.h>
+// #include "db.h"
+import "C"
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// EncryptionKeys are the AES keys, by ID, with which the files of
+// encrypted engines are encrypted.
+type EncryptionKeys map[string]cipher.Block
+
+// LoadEncryptionKeys reads the AES keys in the file at path. Each line
+// which is neither empty nor begins with '#' holds the ID of a key and
+// the key itself, 16, 24 or 32 hex-encoded bytes, separated by
+// whitespace. Every key in the file may decrypt files, while each
+// engine encrypts new files with the key it was created with. Keys
+// are rotated by adding a key to the file and creating engines with
+// it; the old key may be removed once Rekey has rewritten the files
+// encrypted with it.
+func LoadEncryptionKeys(path string) (EncryptionKeys, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseEncryptionKeys(path, string(data))
+}
+
+// parseEncryptionKeys parses the contents of the key file at path.
+func parseEncryptionKeys(path, data string) (EncryptionKeys, error) {
+	keys := EncryptionKeys{}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, util.Errorf("%s:%d: expected <id> <hex-encoded key>", path, i+1)
+		}
+		id := fields[0]
+		if len(id) > 255 {
+			return nil, util.Errorf("%s:%d: key ID is longer than 255 bytes", path, i+1)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, util.Errorf("%s:%d: duplicate key ID %q", path, i+1, id)
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, util.Errorf("%s:%d: %s", path, i+1, err)
+		}
+		if keys[id], err = aes.NewCipher(key); err != nil {
+			return nil, util.Errorf("%s:%d: %s", path, i+1, err)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, util.Errorf("%s holds no keys", path)
+	}
+	return keys, nil
+}
+
+// Encrypted is a RocksDB engine every file of which, including its
+// sstables, write-ahead log and manifest, is encrypted with AES in CTR
+// mode by its RocksDB environment. Each file begins with a header
+// holding the ID of its key and a random initialization vector, so
+// that files encrypted with different keys may be read alongside each
+// other while the engine's key is rotated. RocksDB reads and writes
+// plaintext above its environment, so merges, the GC of compactions
+// and the engine's stats are those of an unencrypted engine. The files
+// of an unencrypted engine can't be read by an encrypted one.
+type Encrypted struct {
+	*RocksDB
+	// rekeyed is set once Rekey has found no sstables encrypted with
+	// keys other than the engine's.
+	rekeyed int32
+}
+
+// fileEncryption holds the keys with which the environment of a
+// RocksDB engine encrypts and decrypts its files.
+type fileEncryption struct {
+	keys  EncryptionKeys
+	keyID string
+	// handle identifies keys to rocksDBEncryptionXOR while the engine
+	// is open.
+	handle int
+}
+
+// NewEncrypted returns an engine which encrypts the files of r, which
+// must not have been opened, with the key of keys identified by keyID,
+// and decrypts its files with any of keys.
+func NewEncrypted(r *RocksDB, keys EncryptionKeys, keyID string) (*Encrypted, error) {
+	if _, ok := keys[keyID]; !ok {
+		return nil, util.Errorf("unknown encryption key %q", keyID)
+	}
+	if r.rdb != nil {
+		return nil, util.Errorf("engine %s is open, so its files can't be encrypted", r)
+	}
+	r.encryption = &fileEncryption{keys: keys, keyID: keyID}
+	return &Encrypted{RocksDB: r}, nil
+}
+
+// Capacity returns the capacity of the engine along with the status of
+// its encryption.
+func (e *Encrypted) Capacity() (StoreCapacity, error) {
+	capacity, err := e.RocksDB.Capacity()
+	capacity.EncryptionKeyID = e.encryption.keyID
+	capacity.EncryptionRotating = atomic.LoadInt32(&e.rekeyed) == 0
+	return capacity, err
+}
+
+// Rekey rewrites the sstables encrypted with keys other than the
+// engine's, by compacting the keys they hold, so that those keys may
+// be removed from the key file, and returns the number of compactions.
+// The engine's write-ahead log and manifest are replaced with files
+// encrypted with its key when it's opened. Rekey returns early,
+// between compactions, if stop is closed. Once no sstables remain
+// encrypted with other keys, Capacity no longer reports the engine to
+// be rotating its key.
+func (e *Encrypted) Rekey(stop <-chan struct{}) (int, error) {
+	compacted := map[string]bool{}
+	for {
+		var stale *sstable
+		for _, t := range e.sstables() {
+			if t.keyID != e.encryption.keyID {
+				stale = &t
+				break
+			}
+		}
+		if stale == nil {
+			atomic.StoreInt32(&e.rekeyed, 1)
+			return len(compacted), nil
+		}
+		if compacted[stale.name] {
+			return len(compacted), util.Errorf("sstable %s wasn't rewritten by its compaction", stale.name)
+		}
+		select {
+		case <-stop:
+			return len(compacted), nil
+		default:
+		}
+		if err := e.Compact(stale.smallestKey, stale.largestKey); err != nil {
+			return len(compacted), err
+		}
+		compacted[stale.name] = true
+	}
+}
+
+// encryptionHandles holds the keys of the open encrypted engines by
+// the handles with which their RocksDB environments refer to them.
+var encryptionHandles = struct {
+	sync.RWMutex
+	last int
+	keys map[int]EncryptionKeys
+}{keys: map[int]EncryptionKeys{}}
+
+// registerEncryptionKeys returns a new handle referring to keys.
+func registerEncryptionKeys(keys EncryptionKeys) int {
+	encryptionHandles.Lock()
+	defer encryptionHandles.Unlock()
+	encryptionHandles.last++
+	encryptionHandles.keys[encryptionHandles.last] = keys
+	return encryptionHandles.last
+}
+
+// unregisterEncryptionKeys releases the keys referred to by handle.
+func unregisterEncryptionKeys(handle int) {
+	encryptionHandles.Lock()
+	defer encryptionHandles.Unlock()
+	delete(encryptionHandles.keys, handle)
+}
+
+// xorKeyStreamAt XORs data with the AES-CTR keystream of block and iv,
+// starting offset bytes into the keystream.
+func xorKeyStreamAt(block cipher.Block, iv []byte, offset uint64, data []byte) {
+	// The counter of the block of the keystream holding offset is iv
+	// plus the block's index, as a big-endian integer.
+	ctr := make([]byte, aes.BlockSize)
+	copy(ctr, iv)
+	n, carry := offset/aes.BlockSize, uint64(0)
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		sum := uint64(ctr[i]) + n&0xff + carry
+		ctr[i] = byte(sum)
+		n, carry = n>>8, sum>>8
+	}
+	stream := cipher.NewCTR(block, ctr)
+	if skip := offset % aes.BlockSize; skip > 0 {
+		var pad [aes.BlockSize]byte
+		stream.XORKeyStream(pad[:skip], pad[:skip])
+	}
+	stream.XORKeyStream(data, data)
+}
+
+//export rocksDBEncryptionXOR
+func rocksDBEncryptionXOR(handle C.int, keyID *C.char, keyIDLen C.int, iv *C.char,
+	offset C.uint64_t, data *C.char, n C.size_t) C.int {
+	encryptionHandles.RLock()
+	block, ok := encryptionHandles.keys[int(handle)][C.GoStringN(keyID, keyIDLen)]
+	encryptionHandles.RUnlock()
+	if !ok {
+		return 1
+	}
+	xorKeyStreamAt(block, C.GoBytes(unsafe.Pointer(iv), aes.BlockSize), uint64(offset),
+		(*[1 << 30]byte)(unsafe.Pointer(data))[:n:n])
+	return 0
+}
+
+//export rocksDBEncryptionIV
+func rocksDBEncryptionIV(iv *C.char, n C.int) C.int {
+	if _, err := rand.Read((*[1 << 30]byte)(unsafe.Pointer(iv))[:n:n]); err != nil {
+		return 1
+	}
+	return 0
+}
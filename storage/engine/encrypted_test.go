@@ -0,0 +1,287 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+const testKeyFile = `
+# Keys of the encrypted engine tests.
+key1 000102030405060708090a0b0c0d0e0f
+key2 000102030405060708090a0b0c0d0e0f1011121314151617
+`
+
+// TestParseEncryptionKeys verifies the parsing of key files.
+func TestParseEncryptionKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	keys, err := parseEncryptionKeys("keys", testKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys["key1"] == nil || keys["key2"] == nil {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	for i, data := range []string{
+		"",
+		"# no keys",
+		"key1",
+		"key1 00010203",
+		"key1 not-hex-encoded-at-all-000",
+		"key1 000102030405060708090a0b0c0d0e0f extra",
+		"key1 000102030405060708090a0b0c0d0e0f\nkey1 000102030405060708090a0b0c0d0e0f",
+	} {
+		if _, err := parseEncryptionKeys("keys", data); err == nil {
+			t.Errorf("%d: expected error parsing %q", i, data)
+		}
+	}
+}
+
+// TestXORKeyStreamAt verifies that data encrypted in pieces at their
+// offsets matches the data encrypted at once.
+func TestXORKeyStreamAt(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The initialization vector's low bytes carry when the counter is
+	// advanced.
+	iv := bytes.Repeat([]byte{0xff}, aes.BlockSize)
+	iv[0] = 0
+	data := make([]byte, 10*aes.BlockSize+7)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	expected := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(expected, data)
+
+	for _, n := range []int{1, 5, aes.BlockSize, 3*aes.BlockSize + 1} {
+		actual := append([]byte(nil), data...)
+		for offset := 0; offset < len(actual); offset += n {
+			end := offset + n
+			if end > len(actual) {
+				end = len(actual)
+			}
+			xorKeyStreamAt(block, iv, uint64(offset), actual[offset:end])
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%d: pieces encrypted at their offsets differ from the data encrypted at once", n)
+		}
+	}
+}
+
+// openTestEncrypted opens an engine in dir encrypting its files with
+// the test key of keyID, decrypting them with keys.
+func openTestEncrypted(t *testing.T, dir string, keys EncryptionKeys, keyID string) *Encrypted {
+	e, err := NewEncrypted(NewRocksDB(inMemAttrs, dir, testCacheSize), keys, keyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// testEncryptionKeys returns the keys of testKeyFile.
+func testEncryptionKeys(t *testing.T) EncryptionKeys {
+	keys, err := parseEncryptionKeys("keys", testKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// TestEncrypted verifies that neither the keys nor the values written
+// to an encrypted engine, including those merged, appear in its files,
+// which an unencrypted engine can't open, and that they're read back
+// once it's reopened.
+func TestEncrypted(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_encrypted_test")
+	defer util.CleanupDir(dir)
+	keys := testEncryptionKeys(t)
+	if _, err := NewEncrypted(NewRocksDB(inMemAttrs, dir, testCacheSize), keys, "key3"); err == nil {
+		t.Error("expected error creating an engine with an unknown key")
+	}
+
+	e := openTestEncrypted(t, dir, keys, "key1")
+	if _, err := NewEncrypted(e.RocksDB, keys, "key1"); err == nil {
+		t.Error("expected error encrypting an open engine")
+	}
+	if err := e.Put(proto.EncodedKey("secret-key"), []byte("secret-value")); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"x", "y"} {
+		if err := e.Merge(proto.EncodedKey("merged"), appender(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The memtable is flushed to an sstable, and the write-ahead log is
+	// left holding the last write.
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Put(proto.EncodedKey("logged-key"), []byte("logged-value")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := e.Get(proto.EncodedKey("secret-key")); err != nil || string(value) != "secret-value" {
+		t.Errorf("expected secret-value; got %q, %v", value, err)
+	}
+	if stats := e.ReadStats(); stats.Seeks == 0 {
+		t.Errorf("expected the read to be counted; got %+v", stats)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{"secret", "logged"} {
+			if bytes.Contains(data, []byte(s)) {
+				t.Errorf("expected %s to be encrypted; found %q", f.Name(), s)
+			}
+		}
+	}
+
+	e.Close()
+	if err := NewRocksDB(inMemAttrs, dir, testCacheSize).Open(); err == nil {
+		t.Error("expected error opening an encrypted engine unencrypted")
+	}
+	e = openTestEncrypted(t, dir, keys, "key1")
+	defer e.Close()
+	for key, expected := range map[string]string{"secret-key": "secret-value", "logged-key": "logged-value"} {
+		if value, err := e.Get(proto.EncodedKey(key)); err != nil || string(value) != expected {
+			t.Errorf("expected %s; got %q, %v", expected, value, err)
+		}
+	}
+	var meta proto.MVCCMetadata
+	if ok, _, _, err := e.GetProto(proto.EncodedKey("merged"), &meta); !ok || err != nil {
+		t.Fatalf("expected merged value; got %t, %v", ok, err)
+	} else if string(meta.Value.Bytes) != "xy" {
+		t.Errorf("expected merged value xy; got %q", meta.Value.Bytes)
+	}
+}
+
+// TestEncryptedIngest verifies that an encrypted engine ingests the
+// sstables written through it, but not plaintext ones.
+func TestEncryptedIngest(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_encrypted_ingest_test")
+	defer util.CleanupDir(dir)
+	e := openTestEncrypted(t, filepath.Join(dir, "db"), testEncryptionKeys(t), "key1")
+	defer e.Close()
+
+	for i, newWriter := range []func(string) (*SSTableWriter, error){NewSSTableWriter, e.NewSSTableWriter} {
+		path := filepath.Join(dir, fmt.Sprintf("%d.sst", i))
+		w, err := newWriter(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := proto.EncodedKey(fmt.Sprintf("ingested%d", i))
+		if err := w.Add(key, []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		err = e.IngestExternalFiles([]string{path}, false)
+		if plaintext := i == 0; plaintext != (err != nil) {
+			t.Errorf("%d: expected plaintext sstables alone to be rejected; got %v", i, err)
+		}
+	}
+	if value, err := e.Get(proto.EncodedKey("ingested1")); err != nil || string(value) != "value" {
+		t.Errorf("expected ingested value; got %q, %v", value, err)
+	}
+}
+
+// TestEncryptedRekey verifies that rotating the key of an encrypted
+// engine leaves the files written with the old key readable, and that
+// Rekey rewrites them with the new one.
+func TestEncryptedRekey(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_encrypted_rekey_test")
+	defer util.CleanupDir(dir)
+	keys := testEncryptionKeys(t)
+	old := openTestEncrypted(t, dir, keys, "key1")
+	const count = 100
+	for i := 0; i < count; i++ {
+		if err := old.Put(proto.EncodedKey(encodeTestKey(i)), []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := old.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	e := openTestEncrypted(t, dir, keys, "key2")
+	if value, err := e.Get(proto.EncodedKey(encodeTestKey(0))); err != nil || string(value) != "value" {
+		t.Errorf("expected value written with old key; got %q, %v", value, err)
+	}
+	if c, err := e.Capacity(); err != nil || c.EncryptionKeyID != "key2" || !c.EncryptionRotating {
+		t.Errorf("expected capacity to report rotation to key2; got %+v, %v", c, err)
+	}
+	if n, err := e.Rekey(make(chan struct{})); err != nil || n == 0 {
+		t.Fatalf("expected sstables to be rewritten; got %d, %v", n, err)
+	}
+	if c, err := e.Capacity(); err != nil || c.EncryptionRotating {
+		t.Errorf("expected capacity to report completed rotation; got %+v, %v", c, err)
+	}
+	if n, err := e.Rekey(make(chan struct{})); n != 0 || err != nil {
+		t.Errorf("expected no sstables to rewrite; got %d, %v", n, err)
+	}
+	e.Close()
+
+	// Without the old key, every value remains readable.
+	delete(keys, "key1")
+	e = openTestEncrypted(t, dir, keys, "key2")
+	defer e.Close()
+	n := 0
+	if err := e.Iterate(proto.EncodedKey(KeyMin), proto.EncodedKey(KeyMax), func(kv proto.RawKeyValue) (bool, error) {
+		if string(kv.Value) != "value" {
+			t.Errorf("unexpected value %q of %q", kv.Value, kv.Key)
+		}
+		n++
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n != count {
+		t.Errorf("expected %d values; got %d", count, n)
+	}
+}
+
+// encodeTestKey returns a key which sorts in the order of i.
+func encodeTestKey(i int) string {
+	return fmt.Sprintf("key%05d", i)
+}
@@ -32,6 +32,12 @@ import (
 type StoreCapacity struct {
 	Capacity  int64
 	Available int64
+	// EncryptionKeyID is the ID of the key with which an encrypted
+	// store encrypts new files, or empty if the store is unencrypted.
+	// EncryptionRotating is set until the store has verified that none
+	// of its sstables remain encrypted with other keys.
+	EncryptionKeyID    string
+	EncryptionRotating bool
 }
 
 // PercentAvail computes the percentage of disk space that is available.
@@ -39,6 +45,156 @@ func (sc StoreCapacity) PercentAvail() float64 {
 	return float64(sc.Available) / float64(sc.Capacity)
 }
 
+// ReadStats counts the reads served by an engine since it was opened.
+// The ratio of DiskBytesRead to BytesReturned is the engine's read
+// amplification, which grows with its compaction debt; a high ratio of
+// Seeks or Steps to BytesReturned points at scans which skip over many
+// keys, e.g. deleted or older MVCC versions.
+type ReadStats struct {
+	DiskBytesRead int64 // Bytes read from disk to serve gets and iterators
+	BytesReturned int64 // Bytes of values and iterator keys and values returned
+	Seeks         int64 // Gets and iterator seeks
+	Steps         int64 // Iterator steps to the next key
+}
+
+// ReadAmplification returns the number of bytes read from disk for
+// each byte returned, or zero if none have been returned.
+func (rs ReadStats) ReadAmplification() float64 {
+	if rs.BytesReturned == 0 {
+		return 0
+	}
+	return float64(rs.DiskBytesRead) / float64(rs.BytesReturned)
+}
+
+// A ReadCounter is an engine which counts the reads it serves.
+type ReadCounter interface {
+	Engine
+	ReadStats() ReadStats
+}
+
+// A Corruption describes data of an engine which failed checksum
+// verification: the sstables holding keys in the range [Start, End],
+// at least one of which is corrupt or unreadable.
+type Corruption struct {
+	Start proto.EncodedKey `json:"start"`
+	End   proto.EncodedKey `json:"end"`
+	Files []string         `json:"files"` // Paths of the sstables
+	Err   string           `json:"err"`   // The error of the first bad block found
+}
+
+// A Scrubber is an engine which can verify the checksums of the data
+// it holds on disk.
+type Scrubber interface {
+	Engine
+	// Scrub reads every block of the engine's sstables, verifying its
+	// checksum, and returns the corruption found. It stops early, with
+	// what was found so far, once stop is closed.
+	Scrub(stop <-chan struct{}) ([]Corruption, error)
+}
+
+// LevelStats describes the sstables of a level of an engine.
+type LevelStats struct {
+	Level       int   `json:"level"`
+	Files       int   `json:"files"`
+	Bytes       int64 `json:"bytes"`
+	TargetBytes int64 `json:"target_bytes"` // Size to which the level is compacted; zero for level 0
+}
+
+// CompactionStats describes the levels of an engine and the compaction
+// work it has fallen behind on. When compactions can't keep up with
+// writes, files accumulate in level 0 and writes are first slowed,
+// once it holds L0SlowdownFiles, then stopped at L0StopFiles.
+type CompactionStats struct {
+	Levels []LevelStats `json:"levels"`
+	// PendingBytes estimates the bytes which must be compacted for
+	// every level to be within its target size.
+	PendingBytes      int64 `json:"pending_bytes"`
+	CompactionPending bool  `json:"compaction_pending"`
+	L0CompactionFiles int   `json:"l0_compaction_files"`
+	L0SlowdownFiles   int   `json:"l0_slowdown_files"`
+	L0StopFiles       int   `json:"l0_stop_files"`
+	// Stats is the engine's own report of its compactions and stalls.
+	Stats string `json:"stats"`
+}
+
+// A Compactor is an engine whose sstables may be compacted on demand
+// and which reports its compaction stats.
+type Compactor interface {
+	Engine
+	// Compact compacts the sstables holding keys between start and end,
+	// inclusive. A nil start or end leaves the range unbounded.
+	Compact(start, end proto.EncodedKey) error
+	CompactionStats() CompactionStats
+}
+
+// A Checkpointer is an engine of which consistent copies, usable as
+// the data directories of new engines, may be made while it's open.
+type Checkpointer interface {
+	DirEngine
+	// Checkpoint creates a copy of the engine's data as of now in dir,
+	// which must not exist. Files which don't change once written are
+	// hard-linked into dir if it's on the same filesystem as the
+	// engine's directory, so checkpoints taken there are cheap. In-memory
+	// instances return an error.
+	Checkpoint(dir string) error
+}
+
+// An Ingester is an engine into which sstables built outside of it,
+// with an SSTableWriter, may be linked. Ingestion bypasses the
+// memtable and write-ahead log, so it's far cheaper than writing the
+// same keys in batches.
+type Ingester interface {
+	DirEngine
+	// IngestExternalFiles atomically links the sstables at paths, whose
+	// keys must not overlap, into the engine. If move is true, the files
+	// are moved into the engine's directory rather than copied, and so
+	// must be on the same filesystem. In-memory instances return an
+	// error.
+	IngestExternalFiles(paths []string, move bool) error
+	// NewSSTableWriter creates a writer of a new sstable at path which
+	// may be ingested by the engine, encrypted if the engine is.
+	NewSSTableWriter(path string) (*SSTableWriter, error)
+}
+
+// ProvisionedIOSize is the size of the IOs counted by provisioned
+// IOPS; cloud block devices count sequential IOs of up to 256KiB as
+// one operation.
+const ProvisionedIOSize = 256 << 10
+
+// A Provisioning is the throughput provisioned for a store's device,
+// from which the pace of background IO is derived. Zero values are
+// undeclared.
+type Provisioning struct {
+	Bandwidth int64 // Bytes per second
+	IOPS      int64 // Operations per second
+}
+
+// Throughput returns the bytes per second the device sustains: the
+// lesser of its bandwidth and its IOPS in ProvisionedIOSize IOs, or
+// zero if neither is declared.
+func (p Provisioning) Throughput() int64 {
+	throughput := p.Bandwidth
+	if iopsThroughput := p.IOPS * ProvisionedIOSize; p.IOPS > 0 && (throughput == 0 || iopsThroughput < throughput) {
+		throughput = iopsThroughput
+	}
+	return throughput
+}
+
+// A Provisioned engine is one whose device has a declared provisioned
+// throughput.
+type Provisioned interface {
+	Engine
+	Provisioning() Provisioning
+}
+
+// A Striped engine is a persistent engine whose sstables are spread
+// across directories beyond its own, typically on further devices.
+type Striped interface {
+	DirEngine
+	// StripeDirs returns the directories beyond Dir holding sstables.
+	StripeDirs() []string
+}
+
 // Iterator is an interface for iterating over key/value pairs in an
 // engine. Iterator implementation are thread safe unless otherwise
 // noted.
@@ -99,6 +255,10 @@ type Engine interface {
 	// merges. The list passed to WriteBatch must only contain elements
 	// of type Batch{Put,Merge,Delete}.
 	WriteBatch([]interface{}) error
+	// WriteBatchSync is like WriteBatch, but also syncs the engine's
+	// write-ahead log before returning, making the writes and all
+	// writes applied before them durable.
+	WriteBatchSync([]interface{}) error
 	// Merge is a high-performance write operation used for values which are
 	// accumulated over several writes. Multiple values can be merged
 	// sequentially into a single key; a subsequent read will return a "merged"
@@ -145,6 +305,36 @@ type Engine interface {
 	// Commit atomically applies any batched updates to the underlying
 	// engine. This is a noop unless the engine was created via NewBatch().
 	Commit() error
+	// CommitSync is like Commit, but applies the updates via
+	// WriteBatchSync. Committing the last of several consecutive batches
+	// with CommitSync makes all of them durable with a single sync.
+	CommitSync() error
+}
+
+// A TimeBoundIterable is an engine which can skip data written outside
+// a time window when iterating.
+type TimeBoundIterable interface {
+	// NewTimeBoundIterator returns an iterator over the engine which
+	// yields at least every MVCC version with a timestamp in [start,
+	// end], but may skip data holding no such versions. Other keys may
+	// still be yielded and must be filtered by the caller. The caller
+	// must invoke Iterator.Close() when finished with the iterator.
+	NewTimeBoundIterator(start, end proto.Timestamp) Iterator
+}
+
+// A DirEngine is an engine which stores its data in a directory of
+// the local filesystem. Engines registered by other packages should
+// implement it if they're persistent, so that their directories are
+// validated at startup, probed for I/O health and removed by
+// exterminate like those of RocksDB engines.
+type DirEngine interface {
+	Engine
+	// Dir returns the data directory of the engine, which is empty for
+	// in-memory instances.
+	Dir() string
+	// Destroy removes the data held in the directory of the engine,
+	// which must be closed.
+	Destroy() error
 }
 
 // A BatchDelete is a delete operation executed as part of an atomic batch.
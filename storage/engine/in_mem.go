@@ -20,6 +20,8 @@ package engine
 import "github.com/cockroachdb/cockroach/proto"
 
 // InMem wraps RocksDB and configures it for in-memory only storage.
+// It's used by tests; the stores of mem specs are created with
+// NewRocksDBInMem.
 type InMem struct {
 	*RocksDB
 }
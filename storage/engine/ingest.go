@@ -0,0 +1,190 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// MVCCIngest writes the rows, which must be sorted by key and have
+// distinct keys, as values at timestamp to the span from key to
+// endKey, which may hold no other keys. It's meant for initial loads
+// of data: unless the span is empty, no intent or version has to be
+// reconciled with the rows, so their MVCC keys and values may be
+// written directly. If ingester isn't nil, they're written to an
+// sstable in its directory which is linked into it, and engine, which
+// must read through to ingester, is left for the stats counters only;
+// otherwise they're written to engine.
+//
+// A span already holding exactly the versions of the rows, at the
+// same timestamp, was ingested by an earlier application of the same
+// command whose batch wasn't committed; it's left as is, with its
+// stats counted again.
+func MVCCIngest(engine Engine, ingester Ingester, ms *proto.MVCCStats, key, endKey proto.Key,
+	timestamp proto.Timestamp, rows []proto.KeyValue) error {
+	kvs, err := mvccIngestKeyValues(key, endKey, timestamp, rows)
+	if err != nil {
+		return err
+	}
+	ingested, err := mvccIngested(engine, key, endKey, kvs)
+	if err != nil {
+		return err
+	}
+	if !ingested {
+		if ingester != nil {
+			err = IngestKeyValues(ingester, kvs, nil)
+		} else {
+			for _, kv := range kvs {
+				if err = engine.Put(kv.Key, kv.Value); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if ms != nil {
+		rowStats, err := MVCCComputeStats(engine, key, endKey, timestamp.WallTime)
+		if err != nil {
+			return err
+		}
+		rowStats.LastUpdateNanos = 0
+		Accumulate(ms, rowStats)
+	}
+	return nil
+}
+
+// mvccIngestKeyValues returns the MVCC metadata and version of each
+// row, in key order, as MVCCPut writes them for a key with no prior
+// versions.
+func mvccIngestKeyValues(key, endKey proto.Key, timestamp proto.Timestamp,
+	rows []proto.KeyValue) ([]proto.RawKeyValue, error) {
+	if timestamp.Equal(proto.ZeroTimestamp) {
+		return nil, util.Errorf("cannot ingest rows without a timestamp")
+	}
+	kvs := make([]proto.RawKeyValue, 0, 2*len(rows))
+	for i := range rows {
+		row := &rows[i]
+		switch {
+		case len(row.Key) == 0:
+			return nil, emptyKeyError()
+		case row.Key.Less(KeyLocalMax):
+			return nil, util.Errorf("cannot ingest local key %q", row.Key)
+		case row.Key.Less(key) || !row.Key.Less(endKey):
+			return nil, util.Errorf("key %q is outside of the ingested span %q-%q", row.Key, key, endKey)
+		case i > 0 && !rows[i-1].Key.Less(row.Key):
+			return nil, util.Errorf("key %q follows %q; ingested rows must be sorted with distinct keys",
+				row.Key, rows[i-1].Key)
+		case row.Value.Bytes != nil && row.Value.Integer != nil:
+			return nil, util.Errorf("key %q value contains both a byte slice and an integer value: %+v", row.Key, row.Value)
+		case row.Value.Timestamp != nil && !row.Value.Timestamp.Equal(timestamp):
+			return nil, util.Errorf("the timestamp %+v provided in value does not match the timestamp %+v in request",
+				row.Value.Timestamp, timestamp)
+		}
+		value := row.Value
+		value.Timestamp = nil
+		valBytes, err := gogoproto.Marshal(&proto.MVCCValue{Value: &value})
+		if err != nil {
+			return nil, err
+		}
+		metaBytes, err := gogoproto.Marshal(&proto.MVCCMetadata{
+			Timestamp: timestamp,
+			KeyBytes:  mvccVersionTimestampSize,
+			ValBytes:  int64(len(valBytes)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		metaKey := MVCCEncodeKey(row.Key)
+		versionKey := mvccEncodeTimestamp(append(proto.EncodedKey(nil), metaKey...), timestamp)
+		kvs = append(kvs, proto.RawKeyValue{Key: metaKey, Value: metaBytes},
+			proto.RawKeyValue{Key: versionKey, Value: valBytes})
+	}
+	return kvs, nil
+}
+
+// mvccIngested returns whether the span from key to endKey holds
+// exactly kvs, or an error if it holds anything else.
+func mvccIngested(engine Engine, key, endKey proto.Key, kvs []proto.RawKeyValue) (bool, error) {
+	var n int
+	matches := true
+	err := engine.Iterate(MVCCEncodeKey(key), MVCCEncodeKey(endKey), func(kv proto.RawKeyValue) (bool, error) {
+		if n >= len(kvs) || !bytes.Equal(kv.Key, kvs[n].Key) || !bytes.Equal(kv.Value, kvs[n].Value) {
+			matches = false
+			return true, nil
+		}
+		n++
+		return false, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if n == 0 && matches {
+		return false, nil
+	}
+	if !matches || n != len(kvs) {
+		return false, util.Errorf("cannot ingest rows into %q-%q, which already holds keys", key, endKey)
+	}
+	return true, nil
+}
+
+// IngestKeyValues atomically replaces the keys of clear, which must be
+// sorted, with kvs, which must be sorted with distinct keys: an sstable
+// holding the values of kvs and deletions of the other keys of clear
+// is written to the directory of ingester and moved into it.
+func IngestKeyValues(ingester Ingester, kvs []proto.RawKeyValue, clear []proto.EncodedKey) error {
+	f, err := ioutil.TempFile(ingester.Dir(), "ingest")
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	if err := f.Close(); err != nil {
+		return err
+	}
+	w, err := ingester.NewSSTableWriter(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for len(kvs) > 0 || len(clear) > 0 {
+		switch {
+		case len(kvs) == 0 || (len(clear) > 0 && clear[0].Less(kvs[0].Key)):
+			err = w.Delete(clear[0])
+			clear = clear[1:]
+		default:
+			if len(clear) > 0 && clear[0].Equal(kvs[0].Key) {
+				clear = clear[1:]
+			}
+			err = w.Add(kvs[0].Key, kvs[0].Value)
+			kvs = kvs[1:]
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := w.Finish(); err != nil {
+		return err
+	}
+	return ingester.IngestExternalFiles([]string{path}, true)
+}
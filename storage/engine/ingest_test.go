@@ -0,0 +1,167 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// TestMVCCIngest verifies that ingested rows are read and counted as
+// if they had been put, that ingesting them again is a no-op and that
+// spans holding other keys are refused.
+func TestMVCCIngest(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	ts := makeTS(1E9, 0)
+	rows := []proto.KeyValue{
+		{Key: proto.Key("a"), Value: proto.Value{Bytes: []byte("1")}},
+		{Key: proto.Key("b"), Value: proto.Value{Integer: gogoproto.Int64(2)}},
+	}
+	endKey := proto.Key("c")
+
+	ingested, put := createTestEngine(), createTestEngine()
+	ingestedMS, putMS := &proto.MVCCStats{}, &proto.MVCCStats{}
+	if err := MVCCIngest(ingested, nil, ingestedMS, rows[0].Key, endKey, ts, rows); err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range rows {
+		if err := MVCCPut(put, putMS, kv.Key, ts, kv.Value, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(ingestedMS, putMS) {
+		t.Errorf("expected stats %+v; got %+v", putMS, ingestedMS)
+	}
+	for _, kv := range rows {
+		value, err := MVCCGet(ingested, kv.Key, ts, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value == nil || !reflect.DeepEqual(value.Bytes, kv.Value.Bytes) || value.GetInteger() != kv.Value.GetInteger() {
+			t.Errorf("%q: expected value %+v; got %+v", kv.Key, kv.Value, value)
+		}
+	}
+
+	// Ingesting the same rows again, as a re-applied command would,
+	// leaves them as they are.
+	if err := MVCCIngest(ingested, nil, nil, rows[0].Key, endKey, ts, rows); err != nil {
+		t.Errorf("expected re-ingest to succeed; got %s", err)
+	}
+	// Other rows, or the same rows at another timestamp, may not be
+	// ingested into a span which holds keys.
+	if err := MVCCIngest(ingested, nil, nil, rows[0].Key, endKey, makeTS(2E9, 0), rows); err == nil {
+		t.Error("expected error ingesting into a span which holds keys")
+	}
+	if err := MVCCIngest(ingested, nil, nil, rows[0].Key, endKey, ts, rows[:1]); err == nil {
+		t.Error("expected error ingesting into a span which holds keys")
+	}
+
+	for i, bad := range [][]proto.KeyValue{
+		{rows[1], rows[0]},
+		{rows[0], rows[0]},
+		{{Key: proto.Key("d"), Value: proto.Value{Bytes: []byte("4")}}},
+	} {
+		if err := MVCCIngest(createTestEngine(), nil, nil, rows[0].Key, endKey, ts, bad); err == nil {
+			t.Errorf("%d: expected error ingesting malformed rows", i)
+		}
+	}
+}
+
+// TestRocksDBIngestExternalFiles verifies that the keys of an ingested
+// sstable are read from the engine, and that ingesting no sstables is
+// an error.
+func TestRocksDBIngestExternalFiles(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_ingest_test")
+	defer util.CleanupDir(dir)
+
+	rocksdb := NewRocksDB(proto.Attributes{}, filepath.Join(dir, "db"), testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+
+	path := filepath.Join(dir, "ingest.sst")
+	w, err := NewSSTableWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if err := w.Add(proto.EncodedKey(key), []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if err := rocksdb.IngestExternalFiles(nil, false); err == nil {
+		t.Error("expected error ingesting no sstables")
+	}
+	if err := rocksdb.IngestExternalFiles([]string{path}, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if val, err := rocksdb.Get(proto.EncodedKey(key)); err != nil || string(val) != key {
+			t.Errorf("expected %q; got %q, %v", key, val, err)
+		}
+	}
+}
+
+// TestIngestKeyValues verifies that ingested key values replace those
+// of the engine, and that the other keys cleared by the ingestion are
+// deleted.
+func TestIngestKeyValues(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_ingest_test")
+	defer util.CleanupDir(dir)
+
+	rocksdb := NewRocksDB(proto.Attributes{}, filepath.Join(dir, "db"), testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+
+	for _, key := range []string{"a", "b", "c", "e"} {
+		if err := rocksdb.Put(proto.EncodedKey(key), []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kvs := []proto.RawKeyValue{
+		{Key: proto.EncodedKey("b"), Value: []byte("2")},
+		{Key: proto.EncodedKey("d"), Value: []byte("4")},
+	}
+	clear := []proto.EncodedKey{proto.EncodedKey("a"), proto.EncodedKey("b"), proto.EncodedKey("c")}
+	if err := IngestKeyValues(rocksdb, kvs, clear); err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{"a": "", "b": "2", "c": "", "d": "4", "e": "e"} {
+		if val, err := rocksdb.Get(proto.EncodedKey(key)); err != nil || string(val) != expected {
+			t.Errorf("%s: expected %q; got %q, %v", key, expected, val, err)
+		}
+	}
+
+	if err := IngestKeyValues(rocksdb, []proto.RawKeyValue{kvs[1], kvs[0]}, nil); err == nil {
+		t.Error("expected error ingesting unsorted key values")
+	}
+}
@@ -44,6 +44,12 @@ func StoreIdentKey() proto.Key {
 	return MakeStoreKey(KeyLocalStoreIdentSuffix, proto.Key{})
 }
 
+// StoreBootstrapKey returns a store-local key for the marker of a
+// bootstrap in progress.
+func StoreBootstrapKey() proto.Key {
+	return MakeStoreKey(KeyLocalStoreBootstrapSuffix, proto.Key{})
+}
+
 // StoreStatKey returns the key for accessing the named stat.
 func StoreStatKey(stat proto.Key) proto.Key {
 	return MakeStoreKey(KeyLocalStoreStatSuffix, stat)
@@ -55,6 +61,30 @@ func StoreStatusKey(storeID int32) proto.Key {
 	return MakeKey(KeyStatusStorePrefix, encoding.EncodeUvarint(nil, uint64(storeID)))
 }
 
+// JobKey returns the key for accessing the record of the job with the
+// specified ID.
+func JobKey(jobID int64) proto.Key {
+	return MakeKey(KeyJobPrefix, encoding.EncodeUvarint(nil, uint64(jobID)))
+}
+
+// NamespaceKey returns the key for accessing the descriptor of the
+// live namespace with the specified name.
+func NamespaceKey(name string) proto.Key {
+	return MakeKey(KeyNamespacePrefix, proto.Key(name))
+}
+
+// DroppedNamespaceKey returns the key for accessing the descriptor of
+// the dropped namespace with the specified ID.
+func DroppedNamespaceKey(id int64) proto.Key {
+	return MakeKey(KeyNamespaceDroppedPrefix, encoding.EncodeUvarint(nil, uint64(id)))
+}
+
+// NamespaceDataPrefix returns the key prefix allocated to the
+// namespace with the specified ID.
+func NamespaceDataPrefix(id int64) proto.Key {
+	return MakeKey(KeyNamespaceDataPrefix, encoding.EncodeUvarint(nil, uint64(id)))
+}
+
 // MakeRangeIDKey creates a range-local key based on the range's
 // Raft ID, metadata key suffix, and optional detail (e.g. the
 // encoded command ID for a response cache entry, etc.).
@@ -160,6 +190,19 @@ func RangeLastVerificationTimestampKey(raftID int64) proto.Key {
 	return MakeRangeIDKey(raftID, KeyLocalRangeLastVerificationTimestampSuffix, proto.Key{})
 }
 
+// RangeLastReplicaGCTimestampKey returns a range-local key for the
+// time at which the range's replica was last checked for membership
+// in the range by the replica GC queue.
+func RangeLastReplicaGCTimestampKey(raftID int64) proto.Key {
+	return MakeRangeIDKey(raftID, KeyLocalRangeLastReplicaGCTimestampSuffix, proto.Key{})
+}
+
+// RangeHistoryKey returns a range-local key for the range's history of
+// splits and merges.
+func RangeHistoryKey(raftID int64) proto.Key {
+	return MakeRangeIDKey(raftID, KeyLocalRangeHistorySuffix, proto.Key{})
+}
+
 // RangeTreeNodeKey returns a range-local key for the the range's
 // node in the range tree.
 func RangeTreeNodeKey(key proto.Key) proto.Key {
@@ -350,6 +393,10 @@ var (
 	// KeyLocalStoreIdentSuffix stores an immutable identifier for this
 	// store, created when the store is first bootstrapped.
 	KeyLocalStoreIdentSuffix = proto.Key("iden")
+	// KeyLocalStoreBootstrapSuffix marks a store whose bootstrap of a
+	// new cluster is in progress, holding the store's ident until the
+	// bootstrap completes.
+	KeyLocalStoreBootstrapSuffix = proto.Key("bstr")
 	// KeyLocalStoreStatSuffix is the suffix for store statistics.
 	KeyLocalStoreStatSuffix = proto.Key("sst-")
 
@@ -376,6 +423,13 @@ var (
 	// KeyLocalRangeLastVerificationTimestampSuffix is the suffix for a range's
 	// last verification timestamp (for checking integrity of on-disk data).
 	KeyLocalRangeLastVerificationTimestampSuffix = proto.Key("rlvt")
+	// KeyLocalRangeLastReplicaGCTimestampSuffix is the suffix for a
+	// range's last replica GC timestamp (for removing replicas which are
+	// no longer members of the range).
+	KeyLocalRangeLastReplicaGCTimestampSuffix = proto.Key("rlrt")
+	// KeyLocalRangeHistorySuffix is the suffix for a range's history of
+	// splits and merges.
+	KeyLocalRangeHistorySuffix = proto.Key("rhst")
 	// KeyLocalRangeStatSuffix is the suffix for range statistics.
 	KeyLocalRangeStatSuffix = proto.Key("rst-")
 	// KeyLocalResponseCacheSuffix is the suffix for keys storing
@@ -433,16 +487,47 @@ var (
 	// KeyConfigAccountingPrefix specifies the key prefix for accounting
 	// configurations. The suffix is the affected key prefix.
 	KeyConfigAccountingPrefix = MakeKey(KeySystemPrefix, proto.Key("acct"))
+	// KeyConfigBalance is the key of the cluster's balance thresholds.
+	// The value is a struct of type BalanceConfig.
+	KeyConfigBalance = MakeKey(KeySystemPrefix, proto.Key("balance"))
 	// KeyConfigPermissionPrefix specifies the key prefix for accounting
 	// configurations. The suffix is the affected key prefix.
 	KeyConfigPermissionPrefix = MakeKey(KeySystemPrefix, proto.Key("perm"))
 	// KeyConfigZonePrefix specifies the key prefix for zone
 	// configurations. The suffix is the affected key prefix.
 	KeyConfigZonePrefix = MakeKey(KeySystemPrefix, proto.Key("zone"))
+	// KeyJobIDGenerator is the global job ID generator sequence.
+	KeyJobIDGenerator = MakeKey(KeySystemPrefix, proto.Key("job-idgen"))
+	// KeyJobPrefix specifies the key prefix for job records. The suffix
+	// is the encoded job ID.
+	KeyJobPrefix = MakeKey(KeySystemPrefix, proto.Key("jobs-"))
+	// KeyNamespaceIDGenerator is the global namespace ID generator
+	// sequence.
+	KeyNamespaceIDGenerator = MakeKey(KeySystemPrefix, proto.Key("ns-idgen"))
+	// KeyNamespacePrefix specifies the key prefix for the descriptors
+	// of live namespaces. The suffix is the namespace's name.
+	KeyNamespacePrefix = MakeKey(KeySystemPrefix, proto.Key("ns-names-"))
+	// KeyNamespaceDroppedPrefix specifies the key prefix for the
+	// descriptors of dropped namespaces whose keys have yet to be
+	// deleted. The suffix is the encoded namespace ID.
+	KeyNamespaceDroppedPrefix = MakeKey(KeySystemPrefix, proto.Key("ns-dropped-"))
+	// KeyNamespaceDataPrefix is the prefix of the key prefixes
+	// allocated to namespaces, which immediately follow the system
+	// keys. It's followed by the encoded namespace ID.
+	KeyNamespaceDataPrefix = MakeKey(KeySystemMax, proto.Key("ns"))
 	// KeyNodeIDGenerator is the global node ID generator sequence.
 	KeyNodeIDGenerator = MakeKey(KeySystemPrefix, proto.Key("node-idgen"))
 	// KeyRaftIDGenerator is the global Raft consensus group ID generator sequence.
 	KeyRaftIDGenerator = MakeKey(KeySystemPrefix, proto.Key("raft-idgen"))
+	// KeyReplicationCutover is the key at which a standby cluster
+	// records the timestamp up to which it was consistent with its
+	// primary when it took over; no writes are shipped to it afterwards.
+	KeyReplicationCutover = MakeKey(KeySystemPrefix, proto.Key("repl-cutover"))
+	// KeyReplicationProgressPrefix specifies the key prefix at which a
+	// standby cluster records the timestamps up to which the writes to
+	// the ranges of its primary were shipped. The suffix is the start
+	// key of the primary's range.
+	KeyReplicationProgressPrefix = MakeKey(KeySystemPrefix, proto.Key("repl-progress-"))
 	// KeySchemaPrefix specifies key prefixes for schema definitions.
 	KeySchemaPrefix = MakeKey(KeySystemPrefix, proto.Key("schema"))
 	// KeyStoreIDGenerator is the global store ID generator sequence.
@@ -34,7 +34,8 @@ import (
 const (
 	// The size of the reservoir used by FindSplitKey.
 	splitReservoirSize = 100
-	// The size of the timestamp portion of MVCC version keys (used to update stats).
+	// The size of the timestamp portion of MVCC version keys (used to
+	// update stats). NOTE: must be kept in sync with the value in db.cc.
 	mvccVersionTimestampSize int64 = 12
 )
 
@@ -403,9 +404,10 @@ func MVCCPutProto(engine Engine, ms *proto.MVCCStats, key proto.Key, timestamp p
 }
 
 type getBuffer struct {
-	meta  proto.MVCCMetadata
-	value proto.MVCCValue
-	key   [1024]byte
+	meta    proto.MVCCMetadata
+	value   proto.MVCCValue
+	key     [1024]byte
+	version [1024]byte // Holds the key of the latest version
 }
 
 var getBufferPool = sync.Pool{
@@ -514,7 +516,7 @@ func mvccGetInternal(engine Engine, key proto.Key, metaKey proto.EncodedKey, tim
 			// intent; the reader will have to act on this.
 			return nil, &proto.WriteIntentError{Key: key, Txn: *meta.Txn}
 		}
-		latestKey := mvccEncodeTimestamp(metaKey, meta.Timestamp)
+		latestKey := mvccEncodeTimestamp(append(buf.version[:0], metaKey...), meta.Timestamp)
 
 		// Check for case where we're reading our own txn's intent
 		// but it's got a different epoch. This can happen if the
@@ -784,6 +786,16 @@ func mvccPutInternal(engine Engine, ms *proto.MVCCStats, key proto.Key, timestam
 // an "integer" type, increments it by inc and stores the new
 // value. The newly incremented value is returned.
 func MVCCIncrement(engine Engine, ms *proto.MVCCStats, key proto.Key, timestamp proto.Timestamp, txn *proto.Transaction, inc int64) (int64, error) {
+	return MVCCIncrementBounded(engine, ms, key, timestamp, txn, inc, 0, nil, nil)
+}
+
+// MVCCIncrementBounded is like MVCCIncrement, but increments initial
+// if no value exists for key. If min or max are not nil and the newly
+// incremented value would fall outside of [*min, *max], the value is
+// left unchanged and an IncrementBoundsError containing the current
+// value is returned.
+func MVCCIncrementBounded(engine Engine, ms *proto.MVCCStats, key proto.Key, timestamp proto.Timestamp, txn *proto.Transaction,
+	inc, initial int64, min, max *int64) (int64, error) {
 	// Handle check for non-existence of key. In order to detect
 	// the potential write intent by another concurrent transaction
 	// with a newer timestamp, we need to use the max timestamp
@@ -793,7 +805,7 @@ func MVCCIncrement(engine Engine, ms *proto.MVCCStats, key proto.Key, timestamp
 		return 0, err
 	}
 
-	var int64Val int64
+	int64Val := initial
 	// If the value exists, verify it's an integer type not a byte slice.
 	if value != nil {
 		if value.Bytes != nil || value.Integer == nil {
@@ -807,12 +819,16 @@ func MVCCIncrement(engine Engine, ms *proto.MVCCStats, key proto.Key, timestamp
 		return 0, util.Errorf("key %s with value %d incremented by %d results in overflow", key, int64Val, inc)
 	}
 
+	r := int64Val + inc
+	if (min != nil && r < *min) || (max != nil && r > *max) {
+		return int64Val, &proto.IncrementBoundsError{CurrentValue: int64Val}
+	}
+
 	// Skip writing the value in the event the value already exists.
 	if inc == 0 && value != nil {
 		return int64Val, nil
 	}
 
-	r := int64Val + inc
 	newValue := proto.Value{Integer: gogoproto.Int64(r)}
 	newValue.InitChecksum(key)
 	return r, MVCCPut(engine, ms, key, timestamp, newValue, txn)
@@ -903,7 +919,7 @@ func MVCCDeleteRange(engine Engine, ms *proto.MVCCStats, key, endKey proto.Key,
 // scans.
 func MVCCScan(engine Engine, key, endKey proto.Key, max int64, timestamp proto.Timestamp,
 	consistent bool, txn *proto.Transaction) ([]proto.KeyValue, error) {
-	res := []proto.KeyValue{}
+	res := proto.NewScanRows()
 	if err := MVCCIterate(engine, key, endKey, timestamp, consistent, txn, func(kv proto.KeyValue) (bool, error) {
 		res = append(res, kv)
 		if max != 0 && max == int64(len(res)) {
@@ -916,12 +932,43 @@ func MVCCScan(engine Engine, key, endKey proto.Key, max int64, timestamp proto.T
 	return res, nil
 }
 
+// MVCCScanSkippingIntents is like a consistent MVCCScan, but omits the
+// keys holding intents of other transactions instead of returning a
+// WriteIntentError for the first of them. The omitted keys are
+// returned in order, and don't count towards max.
+func MVCCScanSkippingIntents(engine Engine, key, endKey proto.Key, max int64, timestamp proto.Timestamp,
+	txn *proto.Transaction) ([]proto.KeyValue, []proto.Key, error) {
+	res := proto.NewScanRows()
+	var skipped []proto.Key
+	skip := func(key proto.Key) {
+		skipped = append(skipped, key)
+	}
+	if err := mvccIterateInternal(engine, key, endKey, timestamp, true, txn, skip, func(kv proto.KeyValue) (bool, error) {
+		res = append(res, kv)
+		if max != 0 && max == int64(len(res)) {
+			return true, nil
+		}
+		return false, nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	return res, skipped, nil
+}
+
 // MVCCIterate iterates over the key range specified by start and end
 // keys, At each step of the iteration, f() is invoked with the
 // current key/value pair. If f returns true (done) or an error, the
 // iteration stops and the error is propagated.
 func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp,
 	consistent bool, txn *proto.Transaction, f func(proto.KeyValue) (bool, error)) error {
+	return mvccIterateInternal(engine, key, endKey, timestamp, consistent, txn, nil, f)
+}
+
+// mvccIterateInternal implements MVCCIterate. If skipIntent is not nil,
+// it's invoked with the keys holding intents of other transactions,
+// which are skipped, instead of a WriteIntentError being returned.
+func mvccIterateInternal(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp,
+	consistent bool, txn *proto.Transaction, skipIntent func(proto.Key), f func(proto.KeyValue) (bool, error)) error {
 	if !consistent && txn != nil {
 		return util.Errorf("cannot allow inconsistent reads within a transaction")
 	}
@@ -939,8 +986,13 @@ func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp
 	encKey := mvccEncodeKey(keyBuf, key)
 
 	// Get a new iterator and define our getEarlierFunc using iter.Seek.
+	// The keys of an unsafeIterator are copied into shared chunks, and
+	// the successor of each key is built in a reused buffer.
 	iter := engine.NewIterator()
 	defer iter.Close()
+	unsafeIter, _ := iter.(unsafeIterator)
+	var keys chunkAlloc
+	var next proto.Key
 	getValue := func(engine Engine, start, end proto.EncodedKey,
 		msg gogoproto.Message) (proto.EncodedKey, error) {
 		iter.Seek(start)
@@ -959,7 +1011,12 @@ func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp
 		if !iter.Valid() {
 			return iter.Error()
 		}
-		metaKey := iter.Key()
+		var metaKey proto.EncodedKey
+		if unsafeIter != nil {
+			metaKey = keys.copy(unsafeIter.unsafeKey())
+		} else {
+			metaKey = iter.Key()
+		}
 		if bytes.Compare(metaKey, encEndKey) >= 0 {
 			return iter.Error()
 		}
@@ -971,6 +1028,10 @@ func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp
 			return err
 		}
 		value, err := mvccGetInternal(engine, key, metaKey, timestamp, consistent, txn, getValue, buf)
+		if _, ok := err.(*proto.WriteIntentError); ok && skipIntent != nil {
+			skipIntent(key)
+			value, err = nil, nil
+		}
 		if err != nil {
 			return err
 		}
@@ -980,8 +1041,81 @@ func MVCCIterate(engine Engine, key, endKey proto.Key, timestamp proto.Timestamp
 				return err
 			}
 		}
-		encKey = mvccEncodeKey(keyBuf, key.Next())
+		next = append(append(next[:0], key...), 0)
+		encKey = mvccEncodeKey(keyBuf, next)
+	}
+}
+
+// An MVCCVersion is a version of the value of a key written at a
+// timestamp. Value.Deleted is set for deletion tombstones.
+type MVCCVersion struct {
+	Key       proto.Key
+	Timestamp proto.Timestamp
+	Value     proto.MVCCValue
+}
+
+// MVCCIterateIncremental iterates over the versions of the keys in the
+// range specified by start and end keys which were written at
+// timestamps in (startTime, endTime], as needed by incremental backups.
+// f() is invoked with each version, in key order and newest first for
+// each key. If f returns true (done) or an error, the iteration stops
+// and the error is propagated. A WriteIntentError is returned if an
+// intent within the time window is encountered.
+//
+// Engines implementing TimeBoundIterable skip sstables holding no
+// versions within the time window, so that the cost of the iteration
+// is proportional to the data written within it rather than to all
+// the data in the key range.
+func MVCCIterateIncremental(engine Engine, key, endKey proto.Key, startTime, endTime proto.Timestamp,
+	f func(MVCCVersion) (bool, error)) error {
+	if len(endKey) == 0 {
+		return emptyKeyError()
+	}
+	if endTime.Less(startTime) {
+		return util.Errorf("end time %s precedes start time %s", endTime, startTime)
+	}
+
+	var iter Iterator
+	if tb, ok := engine.(TimeBoundIterable); ok {
+		iter = tb.NewTimeBoundIterator(startTime.Next(), endTime)
+	} else {
+		iter = engine.NewIterator()
+	}
+	defer iter.Close()
+
+	encEndKey := MVCCEncodeKey(endKey)
+	var meta proto.MVCCMetadata
+	var metaKey proto.Key
+	for iter.Seek(MVCCEncodeKey(key)); iter.Valid(); iter.Next() {
+		encKey := iter.Key()
+		if bytes.Compare(encKey, encEndKey) >= 0 {
+			break
+		}
+		key, ts, isValue := MVCCDecodeKey(encKey)
+		if !isValue || !startTime.Less(ts) || endTime.Less(ts) {
+			continue
+		}
+		// The metadata isn't necessarily returned by a time-bound
+		// iterator, so it's read separately to recognize intents.
+		if !key.Equal(metaKey) {
+			meta.Reset()
+			if _, _, _, err := engine.GetProto(MVCCEncodeKey(key), &meta); err != nil {
+				return err
+			}
+			metaKey = key
+		}
+		if meta.Txn != nil && ts.Equal(meta.Timestamp) {
+			return &proto.WriteIntentError{Key: key, Txn: *meta.Txn}
+		}
+		version := MVCCVersion{Key: key, Timestamp: ts}
+		if err := iter.ValueProto(&version.Value); err != nil {
+			return err
+		}
+		if done, err := f(version); done || err != nil {
+			return err
+		}
 	}
+	return iter.Error()
 }
 
 // MVCCResolveWriteIntent either commits or aborts (rolls back) an
@@ -223,6 +223,50 @@ func TestMVCCIncrement(t *testing.T) {
 	}
 }
 
+// TestMVCCIncrementBounded verifies that increments start from the
+// initial value for missing keys and that increments which would
+// exceed the bounds are not applied.
+func TestMVCCIncrementBounded(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+	min, max := int64(0), int64(10)
+	newVal, err := MVCCIncrementBounded(engine, nil, testKey1, makeTS(0, 1), nil, 2, 5, &min, &max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newVal != 7 {
+		t.Errorf("expected new value of 7; got %d", newVal)
+	}
+
+	testCases := []struct {
+		inc    int64
+		expVal int64
+		expErr bool
+	}{
+		{3, 10, false},
+		{1, 10, true},
+		{-10, 0, false},
+		{-1, 0, true},
+	}
+	for i, test := range testCases {
+		newVal, err := MVCCIncrementBounded(engine, nil, testKey1, makeTS(0, int32(i+2)), nil, test.inc, 5, &min, &max)
+		if test.expErr {
+			bErr, ok := err.(*proto.IncrementBoundsError)
+			if !ok {
+				t.Fatalf("%d: expected IncrementBoundsError; got %v", i, err)
+			}
+			if bErr.CurrentValue != test.expVal {
+				t.Errorf("%d: expected current value %d; got %d", i, test.expVal, bErr.CurrentValue)
+			}
+		} else if err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+		if newVal != test.expVal {
+			t.Errorf("%d: expected value %d; got %d", i, test.expVal, newVal)
+		}
+	}
+}
+
 func TestMVCCUpdateExistingKey(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
@@ -757,6 +801,66 @@ func TestMVCCScanInconsistent(t *testing.T) {
 	}
 }
 
+// TestMVCCScanSkippingIntents verifies that a scan skipping intents
+// omits and returns the keys holding intents of other transactions,
+// but reads those of its own.
+func TestMVCCScanSkippingIntents(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	engine := createTestEngine()
+
+	ts1 := makeTS(1, 0)
+	if err := MVCCPut(engine, nil, testKey1, ts1, value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(engine, nil, testKey2, ts1, value2, txn1); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(engine, nil, testKey3, ts1, value3, txn2); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(engine, nil, testKey4, ts1, value4, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	kvs, skipped, err := MVCCScanSkippingIntents(engine, testKey1, testKey4.Next(), 0, makeTS(2, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expKVs := []proto.KeyValue{
+		{Key: testKey1, Value: proto.Value{Bytes: value1.Bytes, Timestamp: &ts1}},
+		{Key: testKey4, Value: proto.Value{Bytes: value4.Bytes, Timestamp: &ts1}},
+	}
+	if !reflect.DeepEqual(kvs, expKVs) {
+		t.Errorf("expected key values equal %v != %v", kvs, expKVs)
+	}
+	if expSkipped := []proto.Key{testKey2, testKey3}; !reflect.DeepEqual(skipped, expSkipped) {
+		t.Errorf("expected skipped intents %v; got %v", expSkipped, skipped)
+	}
+
+	// Skipped keys don't count towards the maximum, and a transaction
+	// reads its own intents.
+	kvs, skipped, err = MVCCScanSkippingIntents(engine, testKey1, testKey4.Next(), 2, makeTS(2, 0), txn1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || !bytes.Equal(kvs[0].Key, testKey1) || !bytes.Equal(kvs[1].Key, testKey2) {
+		t.Errorf("expected keys %q and %q; got %v", testKey1, testKey2, kvs)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("expected no skipped intents; got %v", skipped)
+	}
+	kvs, skipped, err = MVCCScanSkippingIntents(engine, testKey2, testKey4.Next(), 1, makeTS(2, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || !bytes.Equal(kvs[0].Key, testKey4) {
+		t.Errorf("expected key %q; got %v", testKey4, kvs)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("expected 2 skipped intents; got %v", skipped)
+	}
+}
+
 func TestMVCCDeleteRange(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	engine := createTestEngine()
@@ -1949,3 +2053,57 @@ func TestMVCCGarbageCollectIntent(t *testing.T) {
 		t.Fatal("expected error garbage collecting an intent")
 	}
 }
+
+// TestMVCCIterateIncremental verifies that only the versions written
+// within the time window are returned, both by a plain engine and by
+// one whose versions are spread across flushed sstables.
+func TestMVCCIterateIncremental(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rocksdb := NewInMem(proto.Attributes{}, 1<<20)
+	defer rocksdb.Close()
+
+	for _, engine := range []Engine{createTestEngine(), rocksdb} {
+		for i, ts := range []proto.Timestamp{makeTS(1, 0), makeTS(2, 0), makeTS(3, 0)} {
+			for _, key := range []proto.Key{testKey1, testKey2} {
+				value := proto.Value{Bytes: []byte(fmt.Sprintf("%s-%d", key, i))}
+				if err := MVCCPut(engine, nil, key, ts, value, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if r, ok := engine.(*RocksDB); ok {
+				if err := r.Flush(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		var versions []string
+		if err := MVCCIterateIncremental(engine, testKey1, testKey4, makeTS(1, 0), makeTS(2, 0),
+			func(v MVCCVersion) (bool, error) {
+				versions = append(versions, fmt.Sprintf("%s@%d=%s", v.Key, v.Timestamp.WallTime, v.Value.Value.Bytes))
+				return false, nil
+			}); err != nil {
+			t.Fatal(err)
+		}
+		expVersions := []string{"/db1@2=/db1-1", "/db2@2=/db2-1"}
+		if !reflect.DeepEqual(versions, expVersions) {
+			t.Errorf("%T: expected versions %s; got %s", engine, expVersions, versions)
+		}
+
+		// An intent within the window can't be returned.
+		if err := MVCCPut(engine, nil, testKey3, makeTS(4, 0), value3, makeTxn(txn1, makeTS(4, 0))); err != nil {
+			t.Fatal(err)
+		}
+		noop := func(MVCCVersion) (bool, error) { return false, nil }
+		err := MVCCIterateIncremental(engine, testKey1, testKey4, makeTS(3, 0), makeTS(4, 0), noop)
+		if wiErr, ok := err.(*proto.WriteIntentError); !ok || !wiErr.Key.Equal(testKey3) {
+			t.Errorf("%T: expected write intent error on %q; got %v", engine, testKey3, err)
+		}
+		if err := MVCCIterateIncremental(engine, testKey1, testKey4, makeTS(1, 0), makeTS(3, 0), noop); err != nil {
+			t.Errorf("%T: expected intent outside of window to be ignored; got %s", engine, err)
+		}
+		if err := MVCCIterateIncremental(engine, testKey1, testKey4, makeTS(2, 0), makeTS(1, 0), noop); err == nil {
+			t.Errorf("%T: expected error on end time preceding start time", engine)
+		}
+	}
+}
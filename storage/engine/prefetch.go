@@ -0,0 +1,181 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"github.com/cockroachdb/cockroach/proto"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+const (
+	// minPrefetchBatch is the size in bytes of the first batch read
+	// ahead after a seek. Batches double in size up to half the
+	// iterator's prefetch size, so that short iterations, such as those
+	// of a range's local keys, read little beyond their end.
+	minPrefetchBatch = 4 << 10
+	// prefetchBatches is the number of batches which may be read ahead
+	// of the iteration.
+	prefetchBatches = 2
+)
+
+// A prefetchBatch is a batch of key/value pairs read ahead by a
+// prefetchIterator. The last batch of an iteration is done, holding
+// the iterator's error, if any.
+type prefetchBatch struct {
+	kvs  []proto.RawKeyValue
+	done bool
+	err  error
+}
+
+// A prefetchIterator reads ahead of the iteration of another iterator
+// in a goroutine, so that the reads from disk of a long scan overlap
+// with the processing of the keys already read.
+type prefetchIterator struct {
+	iter    Iterator
+	size    int // Bytes of keys and values read ahead
+	batches chan prefetchBatch
+	stopper chan struct{}
+	stopped chan struct{}
+	batch   prefetchBatch // The batch holding the current key
+	pos     int
+}
+
+// NewPrefetchIterator returns an iterator reading ahead of iter by up
+// to size bytes of keys and values, for long scans on devices with
+// high read latency such as spinning disks. The prefetching iterator
+// takes ownership of iter, which it closes; iter must only be used by
+// one goroutine at a time, as any Iterator. The keys and values read
+// ahead are held in memory, so short iterations should use iter
+// directly.
+func NewPrefetchIterator(iter Iterator, size int) Iterator {
+	return &prefetchIterator{iter: iter, size: size, batch: prefetchBatch{done: true}}
+}
+
+// Close stops reading ahead and closes the underlying iterator.
+func (p *prefetchIterator) Close() {
+	p.stop()
+	p.iter.Close()
+}
+
+// Seek stops reading ahead of the current position, seeks the
+// underlying iterator and waits for the first batch read from the new
+// position.
+func (p *prefetchIterator) Seek(key []byte) {
+	p.stop()
+	p.iter.Seek(key)
+	p.batches = make(chan prefetchBatch, prefetchBatches)
+	p.stopper = make(chan struct{})
+	p.stopped = make(chan struct{})
+	go p.prefetch(p.batches, p.stopper, p.stopped)
+	p.batch, p.pos = <-p.batches, 0
+	p.fill()
+}
+
+func (p *prefetchIterator) Valid() bool {
+	return p.pos < len(p.batch.kvs)
+}
+
+func (p *prefetchIterator) Next() {
+	p.pos++
+	p.fill()
+}
+
+func (p *prefetchIterator) Key() proto.EncodedKey {
+	return p.batch.kvs[p.pos].Key
+}
+
+func (p *prefetchIterator) Value() []byte {
+	return p.batch.kvs[p.pos].Value
+}
+
+func (p *prefetchIterator) ValueProto(msg gogoproto.Message) error {
+	return gogoproto.Unmarshal(p.Value(), msg)
+}
+
+// Error returns the error, if any, which the underlying iterator
+// encountered once the iteration reached it.
+func (p *prefetchIterator) Error() error {
+	if p.Valid() {
+		return nil
+	}
+	return p.batch.err
+}
+
+// fill receives the next batch once the current one is exhausted.
+func (p *prefetchIterator) fill() {
+	for p.pos >= len(p.batch.kvs) && !p.batch.done {
+		p.batch, p.pos = <-p.batches, 0
+	}
+}
+
+// stop stops the goroutine reading ahead, if any, and waits for it to
+// exit, so that the underlying iterator may be used again.
+func (p *prefetchIterator) stop() {
+	if p.stopper == nil {
+		return
+	}
+	close(p.stopper)
+	<-p.stopped
+	p.stopper = nil
+	p.batch, p.pos = prefetchBatch{done: true}, 0
+}
+
+// prefetch reads batches of key/value pairs from the underlying
+// iterator until it becomes invalid or stopper is closed.
+func (p *prefetchIterator) prefetch(batches chan<- prefetchBatch, stopper <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	unsafeIter, _ := p.iter.(unsafeIterator)
+	var keyAlloc, valueAlloc chunkAlloc
+	batchSize, maxBatchSize := minPrefetchBatch, p.size/prefetchBatches
+	if maxBatchSize < batchSize {
+		maxBatchSize = batchSize
+	}
+	for {
+		select {
+		case <-stopper:
+			return
+		default:
+		}
+		var b prefetchBatch
+		for bytes := 0; bytes < batchSize; {
+			if !p.iter.Valid() {
+				b.done, b.err = true, p.iter.Error()
+				break
+			}
+			var kv proto.RawKeyValue
+			if unsafeIter != nil {
+				kv.Key = keyAlloc.copy(unsafeIter.unsafeKey())
+				kv.Value = valueAlloc.copy(unsafeIter.unsafeValue())
+			} else {
+				kv.Key, kv.Value = p.iter.Key(), p.iter.Value()
+			}
+			b.kvs = append(b.kvs, kv)
+			bytes += len(kv.Key) + len(kv.Value)
+			p.iter.Next()
+		}
+		select {
+		case batches <- b:
+		case <-stopper:
+			return
+		}
+		if b.done {
+			return
+		}
+		if batchSize *= 2; batchSize > maxBatchSize {
+			batchSize = maxBatchSize
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestPrefetchIterator verifies that a prefetching iterator returns
+// the same keys and values as the iterator it wraps, across seeks, and
+// that closing it before the end of the iteration stops reading ahead.
+func TestPrefetchIterator(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	e := NewInMem(proto.Attributes{}, 1<<20)
+	defer e.Close()
+	const count = 1000
+	for i := 0; i < count; i++ {
+		key := proto.EncodedKey(fmt.Sprintf("%05d", i))
+		if err := e.Put(key, []byte(fmt.Sprintf("value %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	iter := NewPrefetchIterator(e.NewIterator(), 1<<10)
+	if iter.Valid() {
+		t.Error("expected iterator to be invalid before seeking")
+	}
+	for _, start := range []int{0, 500, 990, count} {
+		iter.Seek([]byte(fmt.Sprintf("%05d", start)))
+		i := start
+		for ; iter.Valid(); iter.Next() {
+			if key := fmt.Sprintf("%05d", i); string(iter.Key()) != key {
+				t.Fatalf("expected key %q; got %q", key, iter.Key())
+			}
+			if value := fmt.Sprintf("value %d", i); string(iter.Value()) != value {
+				t.Fatalf("expected value %q; got %q", value, iter.Value())
+			}
+			i++
+		}
+		if i != count {
+			t.Errorf("seek to %d: expected iteration to end at %d; got %d", start, count, i)
+		}
+		if err := iter.Error(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Seek again and stop partway through.
+	iter.Seek(nil)
+	for i := 0; i < 10; i++ {
+		iter.Next()
+	}
+	if string(iter.Key()) != "00010" {
+		t.Errorf("expected key %q; got %q", "00010", iter.Key())
+	}
+	iter.Close()
+}
@@ -0,0 +1,114 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// A Constructor returns a new engine with the given attributes for
+// the location of a store specification, whose meaning depends on the
+// engine type; e.g. a directory or a size in bytes. cacheSize is the
+// number of bytes of memory the engine may use for caching data.
+type Constructor func(attrs proto.Attributes, location string, cacheSize int64) (Engine, error)
+
+var registry struct {
+	sync.Mutex
+	constructors map[string]Constructor
+}
+
+// Register makes an engine constructor available under scheme, so
+// that store specifications may select it with a location of the
+// form <scheme>://<location>. It's meant to be called from the init
+// function of the package implementing the engine, and panics if
+// scheme is empty or already registered. Engines which persist their
+// data in a directory should implement DirEngine.
+func Register(scheme string, ctor Constructor) {
+	registry.Lock()
+	defer registry.Unlock()
+	if len(scheme) == 0 || ctor == nil {
+		panic("engine: Register requires a scheme and constructor")
+	}
+	if _, ok := registry.constructors[scheme]; ok {
+		panic("engine: Register called twice for scheme " + scheme)
+	}
+	if registry.constructors == nil {
+		registry.constructors = map[string]Constructor{}
+	}
+	registry.constructors[scheme] = ctor
+}
+
+// Schemes returns the sorted list of registered engine schemes.
+func Schemes() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	var schemes []string
+	for scheme := range registry.constructors {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// NewEngine returns a new engine for the location of a store
+// specification. A location of the form <scheme>://<location> is
+// passed to the constructor registered for scheme. Otherwise, for
+// backwards compatibility, a location which util.ParseBytes accepts,
+// e.g. 1073741824 or 512MiB, is taken to mean an in-memory engine of
+// that size and anything else the directory of a
+// RocksDB engine.
+func NewEngine(attrs proto.Attributes, location string, cacheSize int64) (Engine, error) {
+	scheme := "rocksdb"
+	if i := strings.Index(location, "://"); i >= 0 {
+		scheme, location = location[:i], location[i+len("://"):]
+	} else if _, err := util.ParseBytes(location); err == nil {
+		scheme = "mem"
+	}
+	registry.Lock()
+	ctor, ok := registry.constructors[scheme]
+	registry.Unlock()
+	if !ok {
+		return nil, util.Errorf("unknown engine scheme %q; registered schemes are %s", scheme, Schemes())
+	}
+	return ctor(attrs, location, cacheSize)
+}
+
+func init() {
+	Register("rocksdb", func(attrs proto.Attributes, dir string, cacheSize int64) (Engine, error) {
+		if len(dir) == 0 {
+			return nil, util.Errorf("unable to initialize a rocksdb store without a directory")
+		}
+		return NewRocksDB(attrs, dir, cacheSize), nil
+	})
+	// The size of an in-memory engine is its capacity, which may be
+	// given with a suffix or as a percentage of system memory; its
+	// cache is sized to match.
+	Register("mem", func(attrs proto.Attributes, sizeStr string, _ int64) (Engine, error) {
+		size, err := util.ParseBytes(sizeStr)
+		if err != nil {
+			return nil, util.Errorf("unable to parse size of in-memory store: %s", err)
+		}
+		if size == 0 {
+			return nil, util.Errorf("unable to initialize an in-memory store with capacity 0")
+		}
+		return NewRocksDBInMem(attrs, size)
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestNewEngine verifies that store locations are dispatched to the
+// constructor registered for their scheme, and that locations without
+// a scheme select the built-in engines.
+func TestNewEngine(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	var location string
+	Register("test", func(attrs proto.Attributes, loc string, cacheSize int64) (Engine, error) {
+		location = loc
+		return newMemRocksDB(attrs, cacheSize), nil
+	})
+	defer func() {
+		registry.Lock()
+		delete(registry.constructors, "test")
+		registry.Unlock()
+	}()
+
+	if schemes := Schemes(); !reflect.DeepEqual(schemes, []string{"mem", "rocksdb", "test"}) {
+		t.Errorf("unexpected schemes %v", schemes)
+	}
+	if _, err := NewEngine(proto.Attributes{}, "test://bucket/path", 0); err != nil {
+		t.Fatal(err)
+	}
+	if location != "bucket/path" {
+		t.Errorf("expected location %q; got %q", "bucket/path", location)
+	}
+
+	testCases := []struct {
+		location string
+		dir      string
+		expErr   bool
+	}{
+		{"rocksdb:///mnt/ssd01", "/mnt/ssd01", false},
+		{"/mnt/ssd01", "/mnt/ssd01", false},
+		{"rocksdb://", "", true},
+		{"1000", "", false},
+		{"mem://1000", "", false},
+		{"mem://0", "", true},
+		{"mem://lots", "", true},
+		{"1MiB", "", false},
+		{"mem://512KiB", "", false},
+		{"unknown://x", "", true},
+	}
+	for i, test := range testCases {
+		e, err := NewEngine(proto.Attributes{Attrs: []string{"ssd"}}, test.location, 1<<20)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%d: expected error for %q", i, test.location)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %s", i, err)
+			continue
+		}
+		// Persistent and in-memory stores are both RocksDB engines; only
+		// persistent ones have directories.
+		switch r := e.(type) {
+		case *RocksDB:
+			if r.Dir() != test.dir {
+				t.Errorf("%d: expected dir %q; got %q", i, test.dir, r.Dir())
+			}
+			// In-memory engines are opened when they're created.
+			if r.Dir() == "" {
+				r.Close()
+			}
+		default:
+			t.Errorf("%d: unexpected engine type %T", i, e)
+		}
+	}
+}
+
+// TestRegisterTwice verifies that registering a scheme twice panics.
+func TestRegisterTwice(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic registering mem scheme twice")
+		}
+	}()
+	Register("mem", func(proto.Attributes, string, int64) (Engine, error) { return nil, nil })
+}
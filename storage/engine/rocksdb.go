@@ -27,6 +27,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"os"
 	"sync/atomic"
 	"syscall"
 	"unsafe"
@@ -44,6 +45,100 @@ type RocksDB struct {
 	attrs     proto.Attributes // Attributes for this engine
 	dir       string           // The data directory
 	cacheSize int64            // Memory to use to cache values.
+	// maxSize and maxSizePercent limit the reported capacity; see SetMaxSize.
+	maxSize        int64
+	maxSizePercent float64
+	tuning         RocksDBTuning
+	provisioning   Provisioning
+	stripeDirs     []string        // Directories beyond dir holding sstables
+	readOnly       bool            // Opened without accepting writes
+	encryption     *fileEncryption // Set if the engine's files are encrypted
+}
+
+// compactionsShare is the fraction of the provisioned throughput of
+// an engine's device to which its flushes and compactions are limited,
+// leaving the rest for foreground writes, reads and snapshots.
+const compactionsShare = 0.5
+
+// CompressionTypes are the names of the algorithms with which RocksDB
+// may compress the blocks of sstables, in the order of RocksDB's
+// CompressionType enum.
+var CompressionTypes = []string{"none", "snappy", "zlib", "bzip2", "lz4", "lz4hc"}
+
+// defaultCompression is the compression used unless another is set.
+const defaultCompression = "snappy"
+
+// The sizes of the levels of an engine, as configured by DBOpen and
+// as RocksDB counts them when placing sstables in the directories of
+// a striped engine: levels 0 and 1 are each allotted levelBaseBytes,
+// and each further level levelMultiplier times its predecessor.
+const (
+	levelBaseBytes  = 512 << 20
+	levelMultiplier = 10
+	numLevels       = 7
+)
+
+// RocksDBTuning holds the options of a RocksDB engine which may be
+// tuned to its device and workload. Zero values leave the defaults.
+type RocksDBTuning struct {
+	// WriteBufferSize is the number of bytes of writes buffered in
+	// memory before they're flushed to an sstable; 64MiB by default.
+	// Larger buffers absorb write bursts at the cost of memory and of
+	// the time taken to replay the log on restart.
+	WriteBufferSize int64
+	// CompactionThreads is the number of sstable compactions which may
+	// run at once; one by default.
+	CompactionThreads int
+	// BloomFilterBits is the number of bits per key of the bloom filter
+	// built for each sstable, which spares reads of sstables without a
+	// key; no filters are built by default.
+	BloomFilterBits int
+	// Compression is the algorithm, one of CompressionTypes, with which
+	// sstable blocks are compressed; snappy by default.
+	Compression string
+}
+
+// Merge returns the tuning with its zero values replaced by those of
+// defaults.
+func (t RocksDBTuning) Merge(defaults RocksDBTuning) RocksDBTuning {
+	if t.WriteBufferSize == 0 {
+		t.WriteBufferSize = defaults.WriteBufferSize
+	}
+	if t.CompactionThreads == 0 {
+		t.CompactionThreads = defaults.CompactionThreads
+	}
+	if t.BloomFilterBits == 0 {
+		t.BloomFilterBits = defaults.BloomFilterBits
+	}
+	if t.Compression == "" {
+		t.Compression = defaults.Compression
+	}
+	return t
+}
+
+// Validate returns an error if a value of the tuning is negative or
+// the compression is unknown.
+func (t RocksDBTuning) Validate() error {
+	if t.WriteBufferSize < 0 || t.CompactionThreads < 0 || t.BloomFilterBits < 0 {
+		return util.Errorf("write buffer size, compaction threads and bloom filter bits must not be negative: %+v", t)
+	}
+	_, err := t.compressionType()
+	return err
+}
+
+// compressionType returns the index of the tuning's compression in
+// CompressionTypes.
+func (t RocksDBTuning) compressionType() (int, error) {
+	compression := t.Compression
+	if compression == "" {
+		compression = defaultCompression
+	}
+	for i, name := range CompressionTypes {
+		if name == compression {
+			return i, nil
+		}
+	}
+	return 0, util.Errorf("unknown compression %q; known compressions are %s", t.Compression, CompressionTypes)
 }
 
 // NewRocksDB allocates and returns a new RocksDB object.
@@ -58,6 +153,19 @@ func NewRocksDB(attrs proto.Attributes, dir string, cacheSize int64) *RocksDB {
 	}
 }
 
+// NewRocksDBInMem allocates and returns a new, opened RocksDB object
+// whose files are kept in memory by RocksDB's memory environment, so
+// that in-memory stores have the sstables, compactions and snapshots of
+// persistent ones. Its data is lost once it's closed for the last
+// time.
+func NewRocksDBInMem(attrs proto.Attributes, cacheSize int64) (*RocksDB, error) {
+	r := newMemRocksDB(attrs, cacheSize)
+	if err := r.Open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 func newMemRocksDB(attrs proto.Attributes, cacheSize int64) *RocksDB {
 	return &RocksDB{
 		attrs: attrs,
@@ -71,6 +179,11 @@ func (r *RocksDB) String() string {
 	return fmt.Sprintf("%s=%s", r.attrs.Attrs, r.dir)
 }
 
+// Dir implements DirEngine.
+func (r *RocksDB) Dir() string {
+	return r.dir
+}
+
 // Open creates options and opens the database. If the database
 // doesn't yet exist at the specified directory, one is initialized
 // from scratch. The RocksDB Open and Close methods are reference
@@ -84,17 +197,63 @@ func (r *RocksDB) Open() error {
 		return nil
 	}
 
-	log.Infof("opening rocksdb instance at %q", r.dir)
+	if err := r.tuning.Validate(); err != nil {
+		return err
+	}
+	compression, _ := r.tuning.compressionType()
+	if r.readOnly && r.dir == "" {
+		return util.Errorf("in-memory rocksdb instances may not be opened read-only")
+	}
+	if len(r.stripeDirs) > 0 && !r.readOnly {
+		// The directories are measured before RocksDB creates them.
+		for _, dir := range append([]string{r.dir}, r.stripeDirs...) {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return util.Errorf("could not create rocksdb directory %s: %s", dir, err)
+			}
+		}
+	}
+	paths, numPaths, err := r.dbPaths()
+	if err != nil {
+		return util.Errorf("could not open rocksdb instance: %s", err)
+	}
+	defer freeDBPaths(paths, numPaths)
+	var encryptionHandle int
+	var encryptionKeyID C.DBSlice
+	if r.encryption != nil {
+		encryptionHandle = registerEncryptionKeys(r.encryption.keys)
+		encryptionKeyID = goToCMallocSlice(r.encryption.keyID)
+		defer C.free(unsafe.Pointer(encryptionKeyID.data))
+	}
+	if r.readOnly {
+		log.Infof("opening rocksdb instance at %q read-only", r.dir)
+	} else {
+		log.Infof("opening rocksdb instance at %q", r.dir)
+	}
 	status := C.DBOpen(&r.rdb, goToCSlice([]byte(r.dir)),
 		C.DBOptions{
-			cache_size:      C.int64_t(r.cacheSize),
-			allow_os_buffer: C.bool(true),
-			logging_enabled: C.bool(log.V(1)),
+			cache_size:         C.int64_t(r.cacheSize),
+			allow_os_buffer:    C.bool(true),
+			logging_enabled:    C.bool(log.V(1)),
+			write_buffer_size:  C.int64_t(r.tuning.WriteBufferSize),
+			compaction_threads: C.int(r.tuning.CompactionThreads),
+			bloom_bits:         C.int(r.tuning.BloomFilterBits),
+			compression:        C.int(compression),
+			rate_limit:         C.int64_t(float64(r.provisioning.Throughput()) * compactionsShare),
+			paths:              paths,
+			num_paths:          C.int(numPaths),
+			read_only:          C.bool(r.readOnly),
+			encryption_handle:  C.int(encryptionHandle),
+			encryption_key_id:  encryptionKeyID,
 		})
-	err := statusToError(status)
-	if err != nil {
+	if err := statusToError(status); err != nil {
+		if r.encryption != nil {
+			unregisterEncryptionKeys(encryptionHandle)
+		}
 		return util.Errorf("could not open rocksdb instance: %s", err)
 	}
+	if r.encryption != nil {
+		r.encryption.handle = encryptionHandle
+	}
 
 	atomic.AddInt32(&r.refcount, 1)
 	return nil
@@ -109,6 +268,9 @@ func (r *RocksDB) Close() {
 	if r.rdb != nil {
 		C.DBClose(r.rdb)
 		r.rdb = nil
+		if r.encryption != nil {
+			unregisterEncryptionKeys(r.encryption.handle)
+		}
 	}
 }
 
@@ -120,6 +282,12 @@ func (r *RocksDB) Attrs() proto.Attributes {
 	return r.attrs
 }
 
+// SetAttrs sets the attributes of the engine. It must be called before
+// the engine is opened.
+func (r *RocksDB) SetAttrs(attrs proto.Attributes) {
+	r.attrs = attrs
+}
+
 func emptyKeyError() error {
 	return util.ErrorSkipFrames(1, "attempted access to empty key")
 }
@@ -249,7 +417,17 @@ func (r *RocksDB) iterateInternal(start, end proto.EncodedKey, f func(proto.RawK
 // the RocksDB write batch facility. The list must only contain
 // elements of type Batch{Put,Merge,Delete}.
 func (r *RocksDB) WriteBatch(cmds []interface{}) error {
-	if len(cmds) == 0 {
+	return r.writeBatch(cmds, false)
+}
+
+// WriteBatchSync is like WriteBatch, but syncs the write-ahead log
+// before returning. The log is synced even if cmds is empty.
+func (r *RocksDB) WriteBatchSync(cmds []interface{}) error {
+	return r.writeBatch(cmds, true)
+}
+
+func (r *RocksDB) writeBatch(cmds []interface{}, sync bool) error {
+	if len(cmds) == 0 && !sync {
 		return nil
 	}
 	batch := C.DBNewBatch()
@@ -273,23 +451,227 @@ func (r *RocksDB) WriteBatch(cmds []interface{}) error {
 		}
 	}
 
-	return statusToError(C.DBWrite(r.rdb, batch))
+	return statusToError(C.DBWrite(r.rdb, batch, C.bool(sync)))
+}
+
+// SetTuning sets the options of the engine which are tuned to its
+// device and workload. It must be called before the engine is opened.
+func (r *RocksDB) SetTuning(t RocksDBTuning) {
+	r.tuning = t
+}
+
+// SetProvisioning declares the provisioned throughput of the engine's
+// device, a share of which its flushes and compactions are limited
+// to. It must be called before the engine is opened.
+func (r *RocksDB) SetProvisioning(p Provisioning) {
+	r.provisioning = p
+}
+
+// Provisioning implements Provisioned.
+func (r *RocksDB) Provisioning() Provisioning {
+	return r.provisioning
+}
+
+// SetStripeDirs sets the directories beyond the engine's own across
+// which its sstables are spread, e.g. those of further devices. It must
+// be called before the engine is opened.
+func (r *RocksDB) SetStripeDirs(dirs []string) {
+	r.stripeDirs = dirs
+}
+
+// StripeDirs implements Striped.
+func (r *RocksDB) StripeDirs() []string {
+	return r.stripeDirs
+}
+
+// SetReadOnly sets whether the engine is opened in read-only mode, in
+// which the existing database is opened without being locked and
+// writes fail, so that a store may be inspected without modifying it.
+// It must be called before the engine is opened.
+func (r *RocksDB) SetReadOnly(readOnly bool) {
+	r.readOnly = readOnly
+}
+
+// ReadOnly returns whether the engine is opened in read-only mode.
+func (r *RocksDB) ReadOnly() bool {
+	return r.readOnly
+}
+
+// dbPaths returns the directories holding the sstables of an engine
+// with stripe directories, with the numbers of bytes they're intended
+// to hold; see stripeTargetSizes. RocksDB places the sstables of each
+// level in the first directory with room for the level and the levels
+// before it, so recent data lives in the engine's own directory and
+// the larger, older levels in the following ones; the write-ahead log
+// always lives in the engine's directory. It returns nil if the engine
+// has no stripe directories.
+//
+// As the paths are passed to C within DBOptions, they're allocated in
+// C memory and must be released with freeDBPaths.
+func (r *RocksDB) dbPaths() (*C.DBPath, int, error) {
+	if len(r.stripeDirs) == 0 {
+		return nil, 0, nil
+	}
+	dirs := append([]string{r.dir}, r.stripeDirs...)
+	capacities := make([]int64, len(dirs))
+	for i, dir := range dirs {
+		var fs syscall.Statfs_t
+		if err := syscall.Statfs(dir, &fs); err != nil {
+			return nil, 0, err
+		}
+		capacities[i] = int64(fs.Bsize) * int64(fs.Blocks)
+	}
+	targetSizes := stripeTargetSizes(capacities)
+	paths := (*C.DBPath)(C.malloc(C.size_t(len(dirs)) * C.size_t(unsafe.Sizeof(C.DBPath{}))))
+	cPaths := (*[1 << 20]C.DBPath)(unsafe.Pointer(paths))[:len(dirs):len(dirs)]
+	for i, dir := range dirs {
+		cPaths[i] = C.DBPath{
+			path:        goToCMallocSlice(dir),
+			target_size: C.int64_t(targetSizes[i]),
+		}
+	}
+	return paths, len(dirs), nil
+}
+
+// stripeTargetSizes returns the numbers of bytes of sstables intended
+// for directories with the capacities, such that the levels of the
+// engine are spread across them rather than filling the first: the
+// deepest level needed to fill all the directories is placed in the
+// last one, each level above it in the directory before, and the
+// remaining levels in the first directory. Each directory but the last
+// is intended to hold its levels, up to its capacity; the last, which
+// RocksDB falls back to, its capacity. With more directories than
+// levels, the first ones are left without sstables.
+func stripeTargetSizes(capacities []int64) []int64 {
+	n := len(capacities)
+	var total int64
+	for _, c := range capacities {
+		total += c
+	}
+	deepest := 0
+	for size := levelBytes(0); size < total && deepest < numLevels-1; size += levelBytes(deepest) {
+		deepest++
+	}
+	if deepest < n-1 {
+		deepest = n - 1
+		if deepest > numLevels-1 {
+			deepest = numLevels - 1
+		}
+	}
+	targets := make([]int64, n)
+	for i := 0; i < n-1; i++ {
+		// Directory i holds the levels from first through last.
+		last := deepest - (n - 1 - i)
+		first := last
+		if i == 0 {
+			first = 0
+		}
+		for level := first; level >= 0 && level <= last; level++ {
+			targets[i] += levelBytes(level)
+		}
+		if targets[i] > capacities[i] {
+			targets[i] = capacities[i]
+		}
+	}
+	targets[n-1] = capacities[n-1]
+	return targets
+}
+
+// levelBytes returns the number of bytes allotted to the level.
+func levelBytes(level int) int64 {
+	size := int64(levelBaseBytes)
+	for l := 2; l <= level; l++ {
+		size *= levelMultiplier
+	}
+	return size
+}
+
+// freeDBPaths releases the C memory of n paths returned by dbPaths.
+func freeDBPaths(paths *C.DBPath, n int) {
+	if paths == nil {
+		return
+	}
+	for _, path := range (*[1 << 20]C.DBPath)(unsafe.Pointer(paths))[:n:n] {
+		C.free(unsafe.Pointer(path.path.data))
+	}
+	C.free(unsafe.Pointer(paths))
+}
+
+// SetMaxSize limits the capacity reported by the engine to maxSize
+// bytes or, if percent is non-zero, to that percentage of the capacity
+// of its file system, so that a store may share a device with other
+// data. Zero values leave the capacity unlimited.
+func (r *RocksDB) SetMaxSize(maxSize int64, percent float64) {
+	r.maxSize = maxSize
+	r.maxSizePercent = percent
+}
+
+// SetCacheSize sets the capacity of the engine's block cache to size
+// bytes, evicting unused blocks if the cache shrinks.
+func (r *RocksDB) SetCacheSize(size int64) {
+	C.DBSetCacheSize(r.rdb, C.int64_t(size))
+}
+
+// ReadStats implements ReadCounter, returning the counts of the reads
+// served by the engine and its snapshots since it was opened. Reads of
+// in-memory engines don't touch the disk and aren't counted in
+// DiskBytesRead.
+func (r *RocksDB) ReadStats() ReadStats {
+	stats := C.DBGetReadStats(r.rdb)
+	return ReadStats{
+		DiskBytesRead: int64(stats.disk_bytes_read),
+		BytesReturned: int64(stats.bytes_returned),
+		Seeks:         int64(stats.seeks),
+		Steps:         int64(stats.steps),
+	}
 }
 
 // Capacity queries the underlying file system for disk capacity
-// information.
+// information. If a maximum size is set, the capacity is limited to it
+// and the available bytes to the difference between it and the
+// approximate size of the engine's data.
 func (r *RocksDB) Capacity() (StoreCapacity, error) {
-	var fs syscall.Statfs_t
 	var capacity StoreCapacity
 	dir := r.dir
 	if dir == "" {
 		dir = "/tmp"
 	}
-	if err := syscall.Statfs(dir, &fs); err != nil {
-		return capacity, err
+	// The devices of stripe directories are counted once each.
+	devices := map[uint64]bool{}
+	for _, dir := range append([]string{dir}, r.stripeDirs...) {
+		var st syscall.Stat_t
+		if err := syscall.Stat(dir, &st); err != nil {
+			return capacity, err
+		}
+		if devices[uint64(st.Dev)] {
+			continue
+		}
+		devices[uint64(st.Dev)] = true
+		var fs syscall.Statfs_t
+		if err := syscall.Statfs(dir, &fs); err != nil {
+			return capacity, err
+		}
+		capacity.Capacity += int64(fs.Bsize) * int64(fs.Blocks)
+		capacity.Available += int64(fs.Bsize) * int64(fs.Bavail)
+	}
+
+	maxSize := r.maxSize
+	if r.maxSizePercent > 0 {
+		maxSize = int64(float64(capacity.Capacity) * r.maxSizePercent / 100)
+	}
+	if maxSize > 0 && maxSize < capacity.Capacity {
+		used, err := r.ApproximateSize(proto.EncodedKey(KeyMin), proto.EncodedKey(KeyMax))
+		if err != nil {
+			return capacity, err
+		}
+		capacity.Capacity = maxSize
+		if available := maxSize - int64(used); available < capacity.Available {
+			capacity.Available = available
+		}
+		if capacity.Available < 0 {
+			capacity.Available = 0
+		}
 	}
-	capacity.Capacity = int64(fs.Bsize) * int64(fs.Blocks)
-	capacity.Available = int64(fs.Bsize) * int64(fs.Bavail)
 	return capacity, nil
 }
 
@@ -304,6 +686,13 @@ func (r *RocksDB) SetGCTimeouts(minTxnTS, minRCacheTS int64) {
 // last key. Note that the use of the word "Range" here does not refer
 // to Cockroach ranges, just to a generalized key range.
 func (r *RocksDB) CompactRange(start, end proto.EncodedKey) {
+	if err := r.Compact(start, end); err != nil {
+		log.Warningf("compact range: %s", err)
+	}
+}
+
+// Compact implements Compactor.
+func (r *RocksDB) Compact(start, end proto.EncodedKey) error {
 	var (
 		s, e       C.DBSlice
 		sPtr, ePtr *C.DBSlice
@@ -316,15 +705,42 @@ func (r *RocksDB) CompactRange(start, end proto.EncodedKey) {
 		ePtr = &e
 		e = goToCSlice(end)
 	}
-	err := statusToError(C.DBCompactRange(r.rdb, sPtr, ePtr))
-	if err != nil {
-		log.Warningf("compact range: %s", err)
+	return statusToError(C.DBCompactRange(r.rdb, sPtr, ePtr))
+}
+
+// CompactionStats implements Compactor.
+func (r *RocksDB) CompactionStats() CompactionStats {
+	cStats := C.DBGetCompactionStats(r.rdb)
+	defer C.free(unsafe.Pointer(cStats.levels))
+	stats := CompactionStats{
+		PendingBytes:      int64(cStats.pending_bytes),
+		CompactionPending: bool(cStats.compaction_pending),
+		L0CompactionFiles: int(cStats.l0_compaction_files),
+		L0SlowdownFiles:   int(cStats.l0_slowdown_files),
+		L0StopFiles:       int(cStats.l0_stop_files),
+		Stats:             cStringToGoString(cStats.stats),
 	}
+	n := int(cStats.num_levels)
+	for i, l := range (*[1 << 10]C.DBLevelStats)(unsafe.Pointer(cStats.levels))[:n:n] {
+		stats.Levels = append(stats.Levels, LevelStats{
+			Level:       i,
+			Files:       int(l.files),
+			Bytes:       int64(l.bytes),
+			TargetBytes: int64(l.target_bytes),
+		})
+	}
+	return stats
 }
 
-// Destroy destroys the underlying filesystem data associated with the database.
+// Destroy implements DirEngine, destroying the underlying filesystem
+// data associated with the database.
 func (r *RocksDB) Destroy() error {
-	return statusToError(C.DBDestroy(goToCSlice([]byte(r.dir))))
+	paths, numPaths, err := r.dbPaths()
+	if err != nil {
+		return err
+	}
+	defer freeDBPaths(paths, numPaths)
+	return statusToError(C.DBDestroy(goToCSlice([]byte(r.dir)), paths, C.int(numPaths)))
 }
 
 // ApproximateSize returns the approximate number of bytes on disk that RocksDB
@@ -338,6 +754,72 @@ func (r *RocksDB) Flush() error {
 	return statusToError(C.DBFlush(r.rdb))
 }
 
+// Scrub implements Scrubber. The sstables whose keys overlap are
+// verified together, as a single span of keys, so that each block is
+// read once; a corrupt span lists all of them.
+func (r *RocksDB) Scrub(stop <-chan struct{}) ([]Corruption, error) {
+	var corruptions []Corruption
+	for _, span := range mergeSSTableSpans(r.sstables()) {
+		select {
+		case <-stop:
+			return corruptions, nil
+		default:
+		}
+		if err := statusToError(C.DBVerifyRange(r.rdb, goToCSlice(span.Start), goToCSlice(span.End))); err != nil {
+			span.Err = err.Error()
+			corruptions = append(corruptions, span)
+		}
+	}
+	return corruptions, nil
+}
+
+// IngestExternalFiles implements Ingester.
+func (r *RocksDB) IngestExternalFiles(paths []string, move bool) error {
+	if len(paths) == 0 {
+		return util.Errorf("no sstables to ingest")
+	}
+	// The array of paths is passed to C, so it and the paths it refers
+	// to must be allocated in C memory.
+	cPathsPtr := (*C.DBSlice)(C.malloc(C.size_t(len(paths)) * C.size_t(unsafe.Sizeof(C.DBSlice{}))))
+	defer C.free(unsafe.Pointer(cPathsPtr))
+	cPaths := (*[1 << 20]C.DBSlice)(unsafe.Pointer(cPathsPtr))[:len(paths):len(paths)]
+	for i, path := range paths {
+		cPaths[i] = goToCMallocSlice(path)
+		defer C.free(unsafe.Pointer(cPaths[i].data))
+	}
+	return statusToError(C.DBIngestExternalFiles(r.rdb, cPathsPtr, C.int(len(paths)), C.bool(move)))
+}
+
+// NewSSTableWriter implements Ingester.
+func (r *RocksDB) NewSSTableWriter(path string) (*SSTableWriter, error) {
+	return newSSTableWriter(r.rdb, path)
+}
+
+// Checkpoint implements Checkpointer.
+func (r *RocksDB) Checkpoint(dir string) error {
+	return statusToError(C.DBCheckpoint(r.rdb, goToCSlice([]byte(dir))))
+}
+
+// sstables returns the live sstables of the engine.
+func (r *RocksDB) sstables() []sstable {
+	var n C.int
+	tables := C.DBGetSSTables(r.rdb, &n)
+	if tables == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(tables))
+	result := make([]sstable, n)
+	for i, t := range (*[1 << 20]C.DBSSTable)(unsafe.Pointer(tables))[:n:n] {
+		result[i] = sstable{
+			name:        cStringToGoString(t.name),
+			smallestKey: cStringToGoBytes(t.smallest_key),
+			largestKey:  cStringToGoBytes(t.largest_key),
+			keyID:       cStringToGoString(t.key_id),
+		}
+	}
+	return result
+}
+
 // goToCSlice converts a go byte slice to a DBSlice. Note that this is
 // potentially dangerous as the DBSlice holds a reference to the go
 // byte slice memory that the Go GC does not know about. This method
@@ -354,6 +836,20 @@ func goToCSlice(b []byte) C.DBSlice {
 	}
 }
 
+// goToCMallocSlice copies a go string into C memory, returning a
+// DBSlice which refers to the copy. Unlike goToCSlice, the result may
+// be stored in memory which is itself passed to C. The caller must
+// free the slice's data.
+func goToCMallocSlice(s string) C.DBSlice {
+	if len(s) == 0 {
+		return C.DBSlice{data: nil, len: 0}
+	}
+	return C.DBSlice{
+		data: C.CString(s),
+		len:  C.int(len(s)),
+	}
+}
+
 func cStringToGoString(s C.DBString) string {
 	if s.data == nil {
 		return ""
@@ -418,6 +914,15 @@ func (r *RocksDB) NewIterator() Iterator {
 	return newRocksDBIterator(r.rdb, nil)
 }
 
+// NewTimeBoundIterator implements TimeBoundIterable. The skipped data
+// are the key spans of sstables with no MVCC versions in [start, end],
+// according to the timestamp bounds recorded in their properties when
+// they're written. The mem-tables are flushed first if they may hold
+// versions in [start, end].
+func (r *RocksDB) NewTimeBoundIterator(start, end proto.Timestamp) Iterator {
+	return newRocksDBTimeBoundIterator(r.rdb, nil, start, end)
+}
+
 // NewSnapshot creates a snapshot handle from engine and returns a
 // read-only rocksDBSnapshot engine.
 func (r *RocksDB) NewSnapshot() Engine {
@@ -440,6 +945,11 @@ func (r *RocksDB) Commit() error {
 	return nil
 }
 
+// CommitSync is a noop for RocksDB engine.
+func (r *RocksDB) CommitSync() error {
+	return nil
+}
+
 type rocksDBSnapshot struct {
 	parent *RocksDB
 	handle *C.DBSnapshot
@@ -493,6 +1003,11 @@ func (r *rocksDBSnapshot) WriteBatch([]interface{}) error {
 	return util.Errorf("cannot WriteBatch to a snapshot")
 }
 
+// WriteBatchSync is illegal for snapshot and returns an error.
+func (r *rocksDBSnapshot) WriteBatchSync([]interface{}) error {
+	return util.Errorf("cannot WriteBatchSync to a snapshot")
+}
+
 // Merge is illegal for snapshot and returns an error.
 func (r *rocksDBSnapshot) Merge(key proto.EncodedKey, value []byte) error {
 	return util.Errorf("cannot Merge to a snapshot")
@@ -524,6 +1039,12 @@ func (r *rocksDBSnapshot) NewIterator() Iterator {
 	return newRocksDBIterator(r.parent.rdb, r.handle)
 }
 
+// NewTimeBoundIterator implements TimeBoundIterable, iterating over
+// the snapshot.
+func (r *rocksDBSnapshot) NewTimeBoundIterator(start, end proto.Timestamp) Iterator {
+	return newRocksDBTimeBoundIterator(r.parent.rdb, r.handle, start, end)
+}
+
 // NewSnapshot is illegal for snapshot and returns nil.
 func (r *rocksDBSnapshot) NewSnapshot() Engine {
 	panic("cannot create a NewSnapshot from a snapshot")
@@ -539,6 +1060,11 @@ func (r *rocksDBSnapshot) Commit() error {
 	return util.Errorf("cannot Commit to a snapshot")
 }
 
+// CommitSync is illegal for snapshot and returns an error.
+func (r *rocksDBSnapshot) CommitSync() error {
+	return util.Errorf("cannot CommitSync to a snapshot")
+}
+
 type rocksDBIterator struct {
 	iter *C.DBIterator
 }
@@ -557,6 +1083,24 @@ func newRocksDBIterator(rdb *C.DBEngine, snapshotHandle *C.DBSnapshot) *rocksDBI
 	}
 }
 
+// newRocksDBTimeBoundIterator returns a new iterator over the supplied
+// RocksDB instance which skips sstables holding no MVCC versions with
+// timestamps in [start, end]. If snapshotHandle is not nil, uses the
+// indicated snapshot.
+func newRocksDBTimeBoundIterator(rdb *C.DBEngine, snapshotHandle *C.DBSnapshot,
+	start, end proto.Timestamp) *rocksDBIterator {
+	return &rocksDBIterator{
+		iter: C.DBNewTimeBoundIter(rdb, snapshotHandle, goToCTimestamp(start), goToCTimestamp(end)),
+	}
+}
+
+func goToCTimestamp(ts proto.Timestamp) C.DBTimestamp {
+	return C.DBTimestamp{
+		wall_time: C.int64_t(ts.WallTime),
+		logical:   C.int32_t(ts.Logical),
+	}
+}
+
 // The following methods implement the Iterator interface.
 func (r *rocksDBIterator) Close() {
 	C.DBIterDestroy(r.iter)
@@ -593,6 +1137,18 @@ func (r *rocksDBIterator) Value() []byte {
 	return cSliceToGoBytes(data)
 }
 
+// unsafeKey implements unsafeIterator, returning the key of the
+// iterator without copying it.
+func (r *rocksDBIterator) unsafeKey() proto.EncodedKey {
+	return cSliceToUnsafeGoBytes(C.DBIterKey(r.iter))
+}
+
+// unsafeValue implements unsafeIterator, returning the value of the
+// iterator without copying it.
+func (r *rocksDBIterator) unsafeValue() []byte {
+	return cSliceToUnsafeGoBytes(C.DBIterValue(r.iter))
+}
+
 func (r *rocksDBIterator) ValueProto(msg gogoproto.Message) error {
 	result := C.DBIterValue(r.iter)
 	if result.len <= 0 {
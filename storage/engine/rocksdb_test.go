@@ -22,7 +22,9 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
+	"syscall"
 	"testing"
 	"time"
 
@@ -133,6 +135,478 @@ func TestRocksDBCompaction(t *testing.T) {
 	}
 }
 
+// TestRocksDBCompactionStats verifies that flushed sstables are
+// counted in level 0 and moved to later levels by compaction.
+func TestRocksDBCompactionStats(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_rocksdb_compaction_stats_test")
+	defer util.CleanupDir(dir)
+	rocksdb := NewRocksDB(proto.Attributes{}, dir, testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := rocksdb.Put(proto.EncodedKey(fmt.Sprintf("key%d", i)), []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+		if err := rocksdb.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := rocksdb.CompactionStats()
+	if len(stats.Levels) < 2 || stats.Levels[0].Files != 2 || stats.Levels[0].Bytes == 0 {
+		t.Fatalf("expected 2 files in level 0; got %+v", stats.Levels)
+	}
+	if stats.Levels[1].TargetBytes == 0 || stats.L0StopFiles < stats.L0SlowdownFiles || stats.Stats == "" {
+		t.Errorf("expected level targets, stall triggers and stats; got %+v", stats)
+	}
+
+	if err := rocksdb.Compact(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	stats = rocksdb.CompactionStats()
+	if stats.Levels[0].Files != 0 || stats.PendingBytes != 0 {
+		t.Errorf("expected compaction to empty level 0; got %+v", stats)
+	}
+}
+
+// TestRocksDBInMem verifies that an in-memory RocksDB engine flushes
+// to sstables and serves snapshots.
+func TestRocksDBInMem(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rocksdb, err := NewRocksDBInMem(proto.Attributes{Attrs: []string{"mem"}}, testCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+
+	if err := rocksdb.Put(proto.EncodedKey("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	snap := rocksdb.NewSnapshot()
+	defer snap.Close()
+	if err := rocksdb.Put(proto.EncodedKey("a"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rocksdb.CompactRange(nil, nil)
+	if val, err := snap.Get(proto.EncodedKey("a")); err != nil || string(val) != "1" {
+		t.Errorf("expected snapshot to read 1; got %q, %v", val, err)
+	}
+	if val, err := rocksdb.Get(proto.EncodedKey("a")); err != nil || string(val) != "2" {
+		t.Errorf("expected 2; got %q, %v", val, err)
+	}
+	if used, err := rocksdb.ApproximateSize(proto.EncodedKey(KeyMin), proto.EncodedKey(KeyMax)); err != nil || used == 0 {
+		t.Errorf("expected flushed data to take space; got %d, %v", used, err)
+	}
+}
+
+// TestRocksDBStripeDirs verifies that a striped engine creates its
+// stripe directories, serves its data after being reopened and counts
+// the capacity of a device shared by its directories once.
+func TestRocksDBStripeDirs(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dirs := util.CreateNTempDirs(t, "_stripe_test", 2)
+	defer util.CleanupDirs(dirs)
+	stripe := filepath.Join(dirs[1], "stripe")
+
+	open := func() *RocksDB {
+		rocksdb := NewRocksDB(proto.Attributes{}, dirs[0], testCacheSize)
+		rocksdb.SetStripeDirs([]string{stripe})
+		if err := rocksdb.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return rocksdb
+	}
+	rocksdb := open()
+	if _, err := os.Stat(stripe); err != nil {
+		t.Fatalf("expected stripe directory to be created: %s", err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := rocksdb.Put(proto.EncodedKey(fmt.Sprintf("key-%03d", i)), []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rocksdb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rocksdb.CompactRange(nil, nil)
+	rocksdb.Close()
+
+	rocksdb = open()
+	defer rocksdb.Close()
+	if val, err := rocksdb.Get(proto.EncodedKey("key-050")); err != nil || string(val) != "value" {
+		t.Errorf("expected value; got %q, %v", val, err)
+	}
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dirs[0], &fs); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := rocksdb.Capacity(); err != nil || c.Capacity != int64(fs.Bsize)*int64(fs.Blocks) {
+		t.Errorf("expected capacity of one device, %d; got %+v, %v", int64(fs.Bsize)*int64(fs.Blocks), c, err)
+	}
+}
+
+// TestStripeTargetSizes verifies that the levels of a striped engine
+// are spread across its directories, the deepest in the last one.
+func TestStripeTargetSizes(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	const gib = 1 << 30
+	testCases := []struct {
+		capacities, expected []int64
+	}{
+		// Levels 0 through 4 in the first directory, level 5 in the second.
+		{[]int64{1000 * gib, 1000 * gib}, []int64{556 * gib, 1000 * gib}},
+		// Levels 0 through 3, 4 and 5.
+		{[]int64{100 * gib, 1000 * gib, 4000 * gib}, []int64{56 * gib, 500 * gib, 4000 * gib}},
+		// Levels 0 and 1, then level 2, limited by the capacities.
+		{[]int64{gib, 2 * gib}, []int64{gib, 2 * gib}},
+		// A first directory too small for its levels.
+		{[]int64{gib, 1000 * gib}, []int64{gib, 1000 * gib}},
+	}
+	for i, test := range testCases {
+		if targets := stripeTargetSizes(test.capacities); !reflect.DeepEqual(targets, test.expected) {
+			t.Errorf("%d: expected target sizes %v; got %v", i, test.expected, targets)
+		}
+	}
+}
+
+// TestRocksDBReadOnly verifies that an existing database opened
+// read-only serves its data and rejects writes, and that missing and
+// in-memory databases may not be opened read-only.
+func TestRocksDBReadOnly(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_read_only_test")
+	defer util.CleanupDir(dir)
+
+	rocksdb := NewRocksDB(proto.Attributes{}, dir, testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Put(proto.EncodedKey("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	rocksdb.Close()
+
+	rocksdb = NewRocksDB(proto.Attributes{}, dir, testCacheSize)
+	rocksdb.SetReadOnly(true)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+	if val, err := rocksdb.Get(proto.EncodedKey("a")); err != nil || string(val) != "1" {
+		t.Errorf("expected 1; got %q, %v", val, err)
+	}
+	if err := rocksdb.Put(proto.EncodedKey("a"), []byte("2")); err == nil {
+		t.Error("expected error writing to read-only engine")
+	}
+	if err := rocksdb.WriteBatch([]interface{}{BatchDelete{proto.RawKeyValue{Key: proto.EncodedKey("a")}}}); err == nil {
+		t.Error("expected error writing batch to read-only engine")
+	}
+
+	for _, dir := range []string{filepath.Join(dir, "missing"), ""} {
+		r := NewRocksDB(proto.Attributes{}, dir, testCacheSize)
+		r.SetReadOnly(true)
+		if err := r.Open(); err == nil {
+			r.Close()
+			t.Errorf("expected error opening %q read-only", dir)
+		}
+	}
+}
+
+// TestRocksDBCheckpoint verifies that a checkpoint may be opened as an
+// engine holding the data written before it, but not after.
+func TestRocksDBCheckpoint(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_checkpoint_test")
+	defer util.CleanupDir(dir)
+
+	rocksdb := NewRocksDB(proto.Attributes{}, filepath.Join(dir, "db"), testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+	if err := rocksdb.Put(proto.EncodedKey("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	checkpointDir := filepath.Join(dir, "checkpoint")
+	if err := rocksdb.Checkpoint(checkpointDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Put(proto.EncodedKey("b"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Checkpoint(checkpointDir); err == nil {
+		t.Error("expected error checkpointing into an existing directory")
+	}
+
+	checkpoint := NewRocksDB(proto.Attributes{}, checkpointDir, testCacheSize)
+	if err := checkpoint.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer checkpoint.Close()
+	if val, err := checkpoint.Get(proto.EncodedKey("a")); err != nil || string(val) != "1" {
+		t.Errorf("expected 1; got %q, %v", val, err)
+	}
+	if val, err := checkpoint.Get(proto.EncodedKey("b")); err != nil || val != nil {
+		t.Errorf("expected write after checkpoint to be missing; got %q, %v", val, err)
+	}
+
+	inMem := NewInMem(proto.Attributes{}, 1<<20)
+	defer inMem.Close()
+	if err := inMem.Checkpoint(filepath.Join(dir, "in_mem")); err == nil {
+		t.Error("expected error checkpointing in-memory engine")
+	}
+}
+
+// TestMergeSSTableSpans verifies that the key ranges of overlapping
+// sstables are merged into a single span listing them.
+func TestMergeSSTableSpans(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tables := []sstable{
+		{"3.sst", proto.EncodedKey("m"), proto.EncodedKey("p"), ""},
+		{"1.sst", proto.EncodedKey("a"), proto.EncodedKey("f"), ""},
+		{"2.sst", proto.EncodedKey("c"), proto.EncodedKey("h"), ""},
+		{"4.sst", proto.EncodedKey("p"), proto.EncodedKey("r"), ""},
+		{"5.sst", proto.EncodedKey("s"), proto.EncodedKey("t"), ""},
+	}
+	expected := []Corruption{
+		{Start: proto.EncodedKey("a"), End: proto.EncodedKey("h"), Files: []string{"1.sst", "2.sst"}},
+		{Start: proto.EncodedKey("m"), End: proto.EncodedKey("r"), Files: []string{"3.sst", "4.sst"}},
+		{Start: proto.EncodedKey("s"), End: proto.EncodedKey("t"), Files: []string{"5.sst"}},
+	}
+	if spans := mergeSSTableSpans(tables); !reflect.DeepEqual(spans, expected) {
+		t.Errorf("expected spans %+v; got %+v", expected, spans)
+	}
+}
+
+// TestProvisioningThroughput verifies that the throughput of a device
+// is the lesser of its provisioned bandwidth and IOPS.
+func TestProvisioningThroughput(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	testCases := []struct {
+		p        Provisioning
+		expected int64
+	}{
+		{Provisioning{}, 0},
+		{Provisioning{Bandwidth: 125 << 20}, 125 << 20},
+		{Provisioning{IOPS: 100}, 100 * ProvisionedIOSize},
+		{Provisioning{Bandwidth: 125 << 20, IOPS: 100}, 100 * ProvisionedIOSize},
+		{Provisioning{Bandwidth: 1 << 20, IOPS: 100}, 1 << 20},
+	}
+	for i, test := range testCases {
+		if throughput := test.p.Throughput(); throughput != test.expected {
+			t.Errorf("%d: expected throughput %d; got %d", i, test.expected, throughput)
+		}
+	}
+
+	// A provisioned engine opens and flushes with its writes rate
+	// limited.
+	dir := util.CreateTempDir(t, "_rocksdb_provisioning_test")
+	defer util.CleanupDir(dir)
+	rocksdb := NewRocksDB(proto.Attributes{}, dir, testCacheSize)
+	rocksdb.SetProvisioning(Provisioning{Bandwidth: 2 << 20})
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+	if err := rocksdb.Put(proto.EncodedKey("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if p := rocksdb.Provisioning(); p.Bandwidth != 2<<20 {
+		t.Errorf("expected provisioned bandwidth of 2MiB/s; got %+v", p)
+	}
+}
+
+// TestRocksDBScrub verifies that scrubbing finds no corruption in
+// intact sstables and reports the key range of a corrupt one.
+func TestRocksDBScrub(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_rocksdb_scrub_test")
+	defer util.CleanupDir(dir)
+	rocksdb := NewRocksDB(proto.Attributes{}, dir, testCacheSize)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	value := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		if err := rocksdb.Put(proto.EncodedKey(fmt.Sprintf("key%05d", i)), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rocksdb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if corruptions, err := rocksdb.Scrub(nil); err != nil || len(corruptions) != 0 {
+		t.Fatalf("expected no corruption; got %+v, %v", corruptions, err)
+	}
+	rocksdb.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sst"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected 1 sstable; got %s, %v", files, err)
+	}
+	f, err := os.OpenFile(files[0], os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first data block is at the start of the file.
+	if _, err := f.WriteAt([]byte("corrupt"), 16); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rocksdb.Close()
+	corruptions, err := rocksdb.Scrub(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptions) != 1 {
+		t.Fatalf("expected 1 corrupt span; got %+v", corruptions)
+	}
+	c := corruptions[0]
+	if string(c.Start) != "key00000" || string(c.End) != "key00999" || len(c.Files) != 1 || c.Err == "" {
+		t.Errorf("unexpected corruption %+v", c)
+	}
+}
+
+// TestRocksDBTimeBoundIterator verifies that a time-bound iterator
+// skips sstables holding no versions within its time window.
+func TestRocksDBTimeBoundIterator(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rocksdb := NewInMem(proto.Attributes{}, testCacheSize)
+	defer rocksdb.Close()
+
+	// Each timestamp's versions are flushed to their own sstable, apart
+	// from the last, which the iterator flushes itself.
+	ts := []proto.Timestamp{makeTS(1, 0), makeTS(3, 0), makeTS(5, 0)}
+	keys := [][]string{{"a", "b"}, {"m", "n"}, {"x", "y"}}
+	for i := range ts {
+		for _, key := range keys[i] {
+			if err := MVCCPut(rocksdb, nil, proto.Key(key), ts[i], value1, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i < len(ts)-1 {
+			if err := rocksdb.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	testCases := []struct {
+		start, end proto.Timestamp
+		expKeys    []string
+	}{
+		{makeTS(0, 0), makeTS(10, 0), []string{"a", "b", "m", "n", "x", "y"}},
+		{makeTS(3, 0), makeTS(3, 0), []string{"m", "n"}},
+		{makeTS(2, 0), makeTS(5, 0), []string{"m", "n", "x", "y"}},
+		{makeTS(6, 0), makeTS(10, 0), nil},
+	}
+	for i, test := range testCases {
+		iter := rocksdb.NewTimeBoundIterator(test.start, test.end)
+		var keys []string
+		for iter.Seek(nil); iter.Valid(); iter.Next() {
+			key, _, isValue := MVCCDecodeKey(iter.Key())
+			if isValue {
+				keys = append(keys, string(key))
+			}
+		}
+		if err := iter.Error(); err != nil {
+			t.Fatal(err)
+		}
+		iter.Close()
+		if !reflect.DeepEqual(keys, test.expKeys) {
+			t.Errorf("%d: expected keys %s; got %s", i, test.expKeys, keys)
+		}
+	}
+}
+
+// TestRocksDBTimeBoundIteratorFlush verifies that a time-bound
+// iterator only flushes the mem-tables if they may hold versions within
+// its time window.
+func TestRocksDBTimeBoundIteratorFlush(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rocksdb := NewInMem(proto.Attributes{}, testCacheSize)
+	defer rocksdb.Close()
+
+	if err := MVCCPut(rocksdb, nil, proto.Key("a"), makeTS(1, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := rocksdb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(rocksdb, nil, proto.Key("b"), makeTS(5, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		start, end proto.Timestamp
+		expTables  int
+	}{
+		// The mem-tables hold no versions within the window.
+		{makeTS(6, 0), makeTS(10, 0), 1},
+		{makeTS(6, 0), makeTS(10, 0), 1},
+		// The mem-tables are flushed once, after which they're empty.
+		{makeTS(0, 0), makeTS(10, 0), 2},
+		{makeTS(0, 0), makeTS(10, 0), 2},
+	}
+	for i, test := range testCases {
+		rocksdb.NewTimeBoundIterator(test.start, test.end).Close()
+		if tables := len(rocksdb.sstables()); tables != test.expTables {
+			t.Errorf("%d: expected %d sstables; got %d", i, test.expTables, tables)
+		}
+	}
+}
+
+// TestRocksDBReadStats verifies that the gets, seeks and steps of an
+// engine and its snapshots are counted with the bytes they return.
+func TestRocksDBReadStats(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rocksdb := NewInMem(proto.Attributes{}, testCacheSize)
+	defer rocksdb.Close()
+
+	for _, key := range []string{"a", "b"} {
+		if err := rocksdb.Put(proto.EncodedKey(key), []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := rocksdb.ReadStats(); stats != (ReadStats{}) {
+		t.Fatalf("expected no reads; got %+v", stats)
+	}
+
+	if _, err := rocksdb.Get(proto.EncodedKey("a")); err != nil {
+		t.Fatal(err)
+	}
+	snap := rocksdb.NewSnapshot()
+	defer snap.Close()
+	if err := snap.Iterate(proto.EncodedKey("a"), proto.EncodedKey("z"), func(proto.RawKeyValue) (bool, error) {
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The get returns a value and the scan two keys with their values;
+	// its last step leaves the iterator past the end.
+	stats := rocksdb.ReadStats()
+	expected := ReadStats{DiskBytesRead: stats.DiskBytesRead, BytesReturned: 5 + 2*(1+5), Seeks: 2, Steps: 2}
+	if stats != expected {
+		t.Errorf("expected read stats %+v; got %+v", expected, stats)
+	}
+	if amp, expAmp := stats.ReadAmplification(), float64(stats.DiskBytesRead)/17; amp != expAmp {
+		t.Errorf("expected read amplification %f; got %f", expAmp, amp)
+	}
+}
+
 // setupMVCCData writes up to numVersions values at each of numKeys
 // keys. The number of versions written for each key is chosen
 // randomly according to a uniform distribution. Each successive
@@ -0,0 +1,58 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// An sstable describes a live sstable of a RocksDB engine: the path of
+// its file, the smallest and largest keys it holds and, if the engine
+// is encrypted, the ID of the key with which the file is encrypted.
+type sstable struct {
+	name                    string
+	smallestKey, largestKey proto.EncodedKey
+	keyID                   string
+}
+
+// sstablesByKey sorts sstables by their smallest keys.
+type sstablesByKey []sstable
+
+func (s sstablesByKey) Len() int           { return len(s) }
+func (s sstablesByKey) Less(i, j int) bool { return s[i].smallestKey.Less(s[j].smallestKey) }
+func (s sstablesByKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// mergeSSTableSpans returns the disjoint key spans covered by the
+// sstables, ordered by key, each listing the sstables whose keys it
+// covers. The spans' errors are empty.
+func mergeSSTableSpans(tables []sstable) []Corruption {
+	sort.Sort(sstablesByKey(tables))
+	var spans []Corruption
+	for _, t := range tables {
+		if n := len(spans); n > 0 && !spans[n-1].End.Less(t.smallestKey) {
+			last := &spans[n-1]
+			if last.End.Less(t.largestKey) {
+				last.End = t.largestKey
+			}
+			last.Files = append(last.Files, t.name)
+			continue
+		}
+		spans = append(spans, Corruption{Start: t.smallestKey, End: t.largestKey, Files: []string{t.name}})
+	}
+	return spans
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package engine
+
+// #include <stdlib.h>
+// #include "db.h"
+import "C"
+import (
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+)
+
+// An SSTableWriter builds an sstable in a file of its own, outside of
+// any engine, to be linked into one by Ingester.IngestExternalFiles.
+// Encrypted engines only ingest sstables built by the writers they
+// return, which encrypt them.
+type SSTableWriter struct {
+	w       *C.DBSSTableWriter
+	lastKey proto.EncodedKey
+}
+
+// NewSSTableWriter creates a writer of a new sstable at path.
+func NewSSTableWriter(path string) (*SSTableWriter, error) {
+	return newSSTableWriter(nil, path)
+}
+
+// newSSTableWriter creates a writer of a new sstable at path, written
+// through the environment of db if it's non-nil.
+func newSSTableWriter(db *C.DBEngine, path string) (*SSTableWriter, error) {
+	s := &SSTableWriter{}
+	if err := statusToError(C.DBSSTableWriterOpen(&s.w, db, goToCSlice([]byte(path)))); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add adds the key and value to the sstable. Keys must be added in
+// increasing order.
+func (s *SSTableWriter) Add(key proto.EncodedKey, value []byte) error {
+	if err := s.checkOrder(key); err != nil {
+		return err
+	}
+	return statusToError(C.DBSSTableWriterAdd(s.w, goToCSlice(key), goToCSlice(value)))
+}
+
+// Delete adds a deletion of the key to the sstable, which shadows the
+// key's value in the engine into which it's ingested. Keys must be
+// added in increasing order.
+func (s *SSTableWriter) Delete(key proto.EncodedKey) error {
+	if err := s.checkOrder(key); err != nil {
+		return err
+	}
+	return statusToError(C.DBSSTableWriterDelete(s.w, goToCSlice(key)))
+}
+
+// checkOrder returns an error unless the key follows the last key
+// added to the sstable, which it then becomes.
+func (s *SSTableWriter) checkOrder(key proto.EncodedKey) error {
+	if len(key) == 0 {
+		return emptyKeyError()
+	}
+	if s.lastKey != nil && !s.lastKey.Less(key) {
+		return util.Errorf("key %q added to sstable after %q", key, s.lastKey)
+	}
+	s.lastKey = append(s.lastKey[:0], key...)
+	return nil
+}
+
+// Finish completes and syncs the sstable, which may then be ingested.
+func (s *SSTableWriter) Finish() error {
+	return statusToError(C.DBSSTableWriterFinish(s.w))
+}
+
+// Close frees the writer's resources. The file of an unfinished
+// sstable is left incomplete and must be removed by the caller.
+func (s *SSTableWriter) Close() {
+	C.DBSSTableWriterClose(s.w)
+}
@@ -18,8 +18,10 @@
 package storage
 
 import (
+	"bytes"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/cockroach/client"
@@ -47,6 +49,11 @@ const (
 	intentAgeThreshold = 2 * time.Hour // 2 hour
 )
 
+// DefaultTxnAbandonTimeout is the default for how long after its last
+// heartbeat a pending transaction is considered abandoned by its
+// coordinator and aborted by the GC queue.
+const DefaultTxnAbandonTimeout = 1 * time.Minute
+
 // gcQueue manages a queue of ranges slated to be scanned in their
 // entirety using the MVCC versions iterator. The gc queue manages the
 // following tasks:
@@ -55,11 +62,20 @@ const (
 //    as implemented going forward).
 //  - Resolve extant write intents and determine oldest non-resolvable
 //    intent.
+//  - Abort pending transactions whose records haven't been heartbeat
+//    within the store's transaction abandon timeout, as their
+//    coordinators have died, and resolve their intents in the range.
+//    The aborted records are retained, as intents in other ranges may
+//    still refer to them; pushes of those intents succeed immediately.
 //
-// The shouldQueue function combines the need for both tasks into a
+// The shouldQueue function combines the need for all tasks into a
 // single priority. If any task is overdue, shouldQueue returns true.
+// The queue's commands are of LOW_PRIORITY class, yielding to client
+// traffic.
 type gcQueue struct {
 	*baseQueue
+	abandonedTxns    int64 // Abandoned transactions aborted; updated atomically
+	abandonedIntents int64 // Intents of abandoned transactions sent for resolution; updated atomically
 }
 
 // newGCQueue returns a new instance of gcQueue.
@@ -90,7 +106,15 @@ func (gcq *gcQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool,
 
 	// Intent score. This computes the average age of outstanding intents
 	// and normalizes.
-	intentScore := rng.stats.GetAvgIntentAge(now.WallTime) / float64(intentAgeNormalization.Nanoseconds()/1E9)
+	avgIntentAge := rng.stats.GetAvgIntentAge(now.WallTime)
+	intentScore := avgIntentAge / float64(intentAgeNormalization.Nanoseconds()/1E9)
+
+	// Abandoned score. Intents older on average than the transaction
+	// abandon timeout are likely those of abandoned transactions.
+	var abandonedScore float64
+	if timeout := rng.rm.txnAbandonTimeout(); timeout > 0 {
+		abandonedScore = avgIntentAge / timeout.Seconds()
+	}
 
 	// Compute priority.
 	if gcScore > 1 {
@@ -99,6 +123,9 @@ func (gcq *gcQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool,
 	if intentScore > 1 {
 		priority += intentScore
 	}
+	if abandonedScore > 1 {
+		priority += abandonedScore
+	}
 	shouldQ = priority > 0
 	return
 }
@@ -106,7 +133,9 @@ func (gcq *gcQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool,
 // process iterates through all keys in a range, calling the garbage
 // collector for each key and associated set of values. GC'd keys are
 // batched into InternalGC calls. Extant intents are resolved if
-// intents are older than intentAgeThreshold.
+// intents are older than intentAgeThreshold or belong to a transaction
+// found abandoned, and aborted, while scanning the range's transaction
+// records, which precede its data.
 func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	if !rng.IsLeader() {
 		log.Infof("not leader of range %s; skipping GC", rng)
@@ -114,9 +143,9 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	}
 
 	snap := rng.rm.Engine().NewSnapshot()
-	iter := newRangeDataIterator(rng, snap)
-	defer iter.Close()
 	defer snap.Close()
+	iter := newRangeScanIterator(rng, snap)
+	defer iter.Close()
 
 	// Lookup the GC policy for the zone containing this key range.
 	policy, err := gcq.lookupGCPolicy(rng)
@@ -131,11 +160,19 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	intentExp := now
 	intentExp.WallTime -= intentAgeThreshold.Nanoseconds()
 
+	// Compute the heartbeat before which pending transactions are
+	// abandoned. abandoned holds the IDs of those aborted.
+	abandonTimeout := rng.rm.txnAbandonTimeout()
+	abandonExp := now
+	abandonExp.WallTime -= abandonTimeout.Nanoseconds()
+	abandoned := map[string]struct{}{}
+
 	gcArgs := &proto.InternalGCRequest{
 		RequestHeader: proto.RequestHeader{
-			Key:       rng.Desc().StartKey,
-			Timestamp: now,
-			RaftID:    rng.Desc().RaftID,
+			Key:           rng.Desc().StartKey,
+			Timestamp:     now,
+			RaftID:        rng.Desc().RaftID,
+			PriorityClass: proto.LOW_PRIORITY,
 		},
 	}
 	var mu sync.Mutex
@@ -159,6 +196,13 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 	// resolution and values after the MVCC metadata, and possible
 	// intent, are sent for garbage collection.
 	processKeysAndValues := func() {
+		// Transaction records are stored inline, without versions.
+		if len(keys) == 1 && abandonTimeout > 0 && isTransactionKey(expBaseKey) {
+			if txn, ok := gcq.maybeAbortAbandonedTxn(rng, vals[0], abandonExp); ok {
+				abandoned[string(txn.ID)] = struct{}{}
+			}
+			return
+		}
 		// If there's more than a single value for the key, possibly send for GC.
 		if len(keys) > 1 {
 			meta := &proto.MVCCMetadata{}
@@ -170,8 +214,13 @@ func (gcq *gcQueue) process(now proto.Timestamp, rng *Range) error {
 				startIdx := 1
 				if meta.Txn != nil {
 					// Resolve intent asynchronously in a goroutine if the intent
-					// is older than the intent expiration threshold.
-					if meta.Timestamp.Less(intentExp) {
+					// is older than the intent expiration threshold or its
+					// transaction has been abandoned.
+					_, isAbandoned := abandoned[string(meta.Txn.ID)]
+					if isAbandoned {
+						atomic.AddInt64(&gcq.abandonedIntents, 1)
+					}
+					if meta.Timestamp.Less(intentExp) || isAbandoned {
 						wg.Add(1)
 						go gcq.resolveIntent(rng, expBaseKey, meta, updateOldestIntent, &wg)
 					} else {
@@ -253,19 +302,8 @@ func (gcq *gcQueue) resolveIntent(rng *Range, key proto.Key, meta *proto.MVCCMet
 
 	// Attempt to push the transaction which created the intent.
 	now := rng.rm.Clock().Now()
-	pushArgs := &proto.InternalPushTxnRequest{
-		RequestHeader: proto.RequestHeader{
-			Timestamp:    now,
-			Key:          meta.Txn.Key,
-			User:         UserRoot,
-			UserPriority: gogoproto.Int32(proto.MaxPriority),
-			Txn:          nil,
-		},
-		PusheeTxn: *meta.Txn,
-		Abort:     true,
-	}
-	pushReply := &proto.InternalPushTxnResponse{}
-	if err := rng.rm.DB().Run(client.Call{Args: pushArgs, Reply: pushReply}); err != nil {
+	pushee, err := gcq.abortTxn(rng, meta.Txn, now)
+	if err != nil {
 		log.Warningf("push of txn %s failed: %s", meta.Txn, err)
 		updateOldestIntent(meta.Timestamp.WallTime)
 		return
@@ -274,10 +312,11 @@ func (gcq *gcQueue) resolveIntent(rng *Range, key proto.Key, meta *proto.MVCCMet
 	// We pushed the transaction successfully, so resolve the intent.
 	resolveArgs := &proto.InternalResolveIntentRequest{
 		RequestHeader: proto.RequestHeader{
-			Timestamp: now,
-			Key:       key,
-			User:      UserRoot,
-			Txn:       pushReply.PusheeTxn,
+			Timestamp:     now,
+			Key:           key,
+			User:          UserRoot,
+			Txn:           pushee,
+			PriorityClass: proto.LOW_PRIORITY,
 		},
 	}
 	if err := rng.AddCmd(resolveArgs, &proto.InternalResolveIntentResponse{}, true); err != nil {
@@ -286,25 +325,91 @@ func (gcq *gcQueue) resolveIntent(rng *Range, key proto.Key, meta *proto.MVCCMet
 	}
 }
 
-// lookupGCPolicy queries the gossip prefix config map based on the
-// supplied range's start key. It queries all matching config prefixes
+// abortTxn pushes the transaction with maximum priority, aborting it
+// unless it has already committed, and returns the pushed transaction.
+func (gcq *gcQueue) abortTxn(rng *Range, txn *proto.Transaction, now proto.Timestamp) (*proto.Transaction, error) {
+	pushArgs := &proto.InternalPushTxnRequest{
+		RequestHeader: proto.RequestHeader{
+			Timestamp:     now,
+			Key:           txn.Key,
+			User:          UserRoot,
+			UserPriority:  gogoproto.Int32(proto.MaxPriority),
+			Txn:           nil,
+			PriorityClass: proto.LOW_PRIORITY,
+		},
+		PusheeTxn: *txn,
+		Abort:     true,
+	}
+	pushReply := &proto.InternalPushTxnResponse{}
+	if err := rng.rm.DB().Run(client.Call{Args: pushArgs, Reply: pushReply}); err != nil {
+		return nil, err
+	}
+	return pushReply.PusheeTxn, nil
+}
+
+// maybeAbortAbandonedTxn aborts the transaction whose record has the
+// supplied MVCC metadata if it's pending and was last heartbeat, or
+// began if it was never heartbeat, before abandonExp. Returns the
+// transaction and whether it was aborted.
+func (gcq *gcQueue) maybeAbortAbandonedTxn(rng *Range, metaBytes []byte, abandonExp proto.Timestamp) (*proto.Transaction, bool) {
+	meta := &proto.MVCCMetadata{}
+	if err := gogoproto.Unmarshal(metaBytes, meta); err != nil || meta.Value == nil {
+		log.Errorf("unable to unmarshal transaction record: %v", err)
+		return nil, false
+	}
+	txn := &proto.Transaction{}
+	if err := gogoproto.Unmarshal(meta.Value.Bytes, txn); err != nil {
+		log.Errorf("unable to unmarshal transaction record: %s", err)
+		return nil, false
+	}
+	if txn.Status != proto.PENDING {
+		return nil, false
+	}
+	heartbeat := txn.Timestamp
+	if txn.LastHeartbeat != nil {
+		heartbeat = *txn.LastHeartbeat
+	}
+	if !heartbeat.Less(abandonExp) {
+		return nil, false
+	}
+	log.Infof("aborting abandoned txn %s, last heartbeat at %s", txn, heartbeat)
+	pushee, err := gcq.abortTxn(rng, txn, rng.rm.Clock().Now())
+	if err != nil {
+		log.Warningf("abort of abandoned txn %s failed: %s", txn, err)
+		return nil, false
+	}
+	if pushee.Status != proto.ABORTED {
+		return nil, false
+	}
+	atomic.AddInt64(&gcq.abandonedTxns, 1)
+	return pushee, true
+}
+
+// isTransactionKey returns whether the key is that of a transaction
+// record.
+func isTransactionKey(key proto.Key) bool {
+	if !bytes.HasPrefix(key, engine.KeyLocalRangeKeyPrefix) {
+		return false
+	}
+	_, suffix, _ := engine.DecodeRangeKey(key)
+	return suffix.Equal(engine.KeyLocalTransactionSuffix)
+}
+
+// lookupGCPolicy queries the zone prefix config map based on the
+// supplied range's zone key. It queries all matching config prefixes
 // and then iterates from most specific to least, returning the first
 // non-nil GC policy.
 func (gcq *gcQueue) lookupGCPolicy(rng *Range) (proto.GCPolicy, error) {
-	info, err := rng.rm.Gossip().GetInfo(gossip.KeyConfigZone)
+	configMap, err := rng.rm.systemConfig(gossip.KeyConfigZone)
 	if err != nil {
-		return proto.GCPolicy{}, util.Errorf("unable to fetch zone config from gossip: %s", err)
-	}
-	configMap, ok := info.(PrefixConfigMap)
-	if !ok {
-		return proto.GCPolicy{}, util.Errorf("gossiped info is not a prefix configuration map: %+v", info)
+		return proto.GCPolicy{}, err
 	}
 
 	// Verify that the range doesn't cross over the zone config prefix.
 	// This could be the case if the zone config is new and the range
 	// hasn't been split yet along the new boundary.
 	var gc *proto.GCPolicy
-	if err = configMap.VisitPrefixesHierarchically(rng.Desc().StartKey, func(start, end proto.Key, config interface{}) (bool, error) {
+	if err = configMap.VisitPrefixesHierarchically(zoneKey(rng.Desc()), func(start, end proto.Key, config interface{}) (bool, error) {
 		zone := config.(*proto.ZoneConfig)
 		if zone.GC != nil {
 			rng.RLock()
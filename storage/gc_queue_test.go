@@ -279,6 +279,70 @@ func TestGCQueueProcess(t *testing.T) {
 	}
 }
 
+// TestGCQueueAbandonedTxn verifies that the GC queue aborts pending
+// transactions which haven't been heartbeat within the abandon timeout
+// and resolves their intents, leaving live transactions alone.
+func TestGCQueueAbandonedTxn(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	tc.store.ctx.TxnAbandonTimeout = time.Minute
+
+	const now int64 = 48 * 60 * 60 * 1E9 // 2d past the epoch
+	tc.manualClock.Set(now)
+
+	txns := map[string]*proto.Transaction{}
+	for key, ts := range map[string]proto.Timestamp{
+		"a": makeTS(now-2*time.Minute.Nanoseconds(), 0), // abandoned
+		"b": makeTS(now-1E9, 0),                         // live
+	} {
+		txn := newTransaction("test", proto.Key(key), 1, proto.SERIALIZABLE, tc.clock)
+		txn.Timestamp = ts
+		hbArgs, hbReply := heartbeatArgs(txn, tc.rng.Desc().RaftID, tc.store.StoreID())
+		hbArgs.Timestamp = ts
+		if err := tc.rng.AddCmd(hbArgs, hbReply, true); err != nil {
+			t.Fatal(err)
+		}
+		pArgs, pReply := putArgs(proto.Key(key), []byte("value"), tc.rng.Desc().RaftID, tc.store.StoreID())
+		pArgs.Timestamp = ts
+		pArgs.Txn = txn
+		if err := tc.rng.AddCmd(pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+		txns[key] = txn
+	}
+
+	gcQ := newGCQueue()
+	if err := gcQ.process(tc.clock.Now(), tc.rng); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expStatus := range map[string]proto.TransactionStatus{"a": proto.ABORTED, "b": proto.PENDING} {
+		txn := &proto.Transaction{}
+		txnKey := engine.TransactionKey(txns[key].Key, txns[key].ID)
+		if ok, err := engine.MVCCGetProto(tc.store.Engine(), txnKey, proto.ZeroTimestamp, true, nil, txn); !ok || err != nil {
+			t.Fatalf("%s: expected transaction record; got %t, %v", key, ok, err)
+		}
+		if txn.Status != expStatus {
+			t.Errorf("%s: expected status %s; got %s", key, expStatus, txn.Status)
+		}
+	}
+	// The abandoned transaction's intent was aborted and removed.
+	for key, expIntent := range map[string]bool{"a": false, "b": true} {
+		meta := &proto.MVCCMetadata{}
+		if _, _, _, err := tc.store.Engine().GetProto(engine.MVCCEncodeKey(proto.Key(key)), meta); err != nil {
+			t.Fatal(err)
+		}
+		if hasIntent := meta.Txn != nil; hasIntent != expIntent {
+			t.Errorf("%s: expected intent %t; got %t", key, expIntent, hasIntent)
+		}
+	}
+	if gcQ.abandonedTxns != 1 || gcQ.abandonedIntents != 1 {
+		t.Errorf("expected 1 abandoned txn and intent; got %d and %d", gcQ.abandonedTxns, gcQ.abandonedIntents)
+	}
+}
+
 // TestGCQueueLookupGCPolicy verifies the hierarchical lookup of GC
 // policy in the event that the longest matching key prefix does not
 // have a zone configured.
@@ -317,6 +381,7 @@ func TestGCQueueLookupGCPolicy(t *testing.T) {
 	if err := tc.rng.rm.Gossip().AddInfo(gossip.KeyConfigZone, pcc, 0*time.Second); err != nil {
 		t.Fatal(err)
 	}
+	waitForConfig(t, tc.store, gossip.KeyConfigZone, pcc)
 
 	// Create a new range within "/db1" and verify that lookup of
 	// zone config results in the
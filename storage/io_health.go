@@ -0,0 +1,135 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+)
+
+const (
+	// DefaultIOProbeInterval is the default interval at which the
+	// latency of a store's device is probed.
+	DefaultIOProbeInterval = 10 * time.Second
+	// DefaultMaxSyncLatency is the default longest a probe may take to
+	// sync a write to a store's directory before the store is suspect.
+	DefaultMaxSyncLatency = 500 * time.Millisecond
+	// DefaultMaxReadLatency is the default longest a probe may take to
+	// read from a store's engine before the store is suspect.
+	DefaultMaxReadLatency = 100 * time.Millisecond
+	// ioProbeSize is the size of the write synced by each probe.
+	ioProbeSize = 4096
+	// ioSuspectProbes is the number of consecutive probes which must
+	// exceed a threshold for a store to become suspect, and which must
+	// be within the thresholds for it to become healthy again.
+	ioSuspectProbes = 3
+)
+
+// IOLatency holds the latencies measured by a probe of a store's
+// device. Sync is zero for stores without a directory, such as
+// in-memory stores.
+type IOLatency struct {
+	Sync time.Duration `json:"sync"`
+	Read time.Duration `json:"read"`
+}
+
+// An ioHealth tracks the latencies of a store's device and decides
+// whether the store is suspect: a store is suspect once
+// ioSuspectProbes consecutive probes failed or exceeded a threshold,
+// and is healthy again once as many consecutive probes were within
+// the thresholds. Zero thresholds aren't checked.
+type ioHealth struct {
+	maxSync, maxRead time.Duration
+
+	sync.Mutex
+	last    IOLatency
+	slow    int // Consecutive probes which failed or exceeded a threshold
+	fast    int // Consecutive probes within the thresholds
+	suspect bool
+}
+
+func newIOHealth(maxSync, maxRead time.Duration) *ioHealth {
+	return &ioHealth{maxSync: maxSync, maxRead: maxRead}
+}
+
+// record records the result of a probe and returns whether the store
+// became suspect or healthy as a result.
+func (h *ioHealth) record(l IOLatency, err error) bool {
+	h.Lock()
+	defer h.Unlock()
+	h.last = l
+	if err != nil || (h.maxSync > 0 && l.Sync > h.maxSync) || (h.maxRead > 0 && l.Read > h.maxRead) {
+		h.slow, h.fast = h.slow+1, 0
+	} else {
+		h.slow, h.fast = 0, h.fast+1
+	}
+	if !h.suspect && h.slow >= ioSuspectProbes {
+		h.suspect = true
+		return true
+	}
+	if h.suspect && h.fast >= ioSuspectProbes {
+		h.suspect = false
+		return true
+	}
+	return false
+}
+
+// isSuspect returns whether the store is suspect.
+func (h *ioHealth) isSuspect() bool {
+	h.Lock()
+	defer h.Unlock()
+	return h.suspect
+}
+
+// latency returns the latencies measured by the most recent probe.
+func (h *ioHealth) latency() IOLatency {
+	h.Lock()
+	defer h.Unlock()
+	return h.last
+}
+
+// probeIO measures the latency of syncing a write to dir, if it isn't
+// empty, and of reading key from eng.
+func probeIO(dir string, eng engine.Engine, key proto.EncodedKey) (IOLatency, error) {
+	var l IOLatency
+	if dir != "" {
+		f, err := ioutil.TempFile(dir, "io-probe")
+		if err != nil {
+			return l, err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		start := time.Now()
+		if _, err := f.Write(make([]byte, ioProbeSize)); err != nil {
+			return l, err
+		}
+		if err := f.Sync(); err != nil {
+			return l, err
+		}
+		l.Sync = time.Since(start)
+	}
+	start := time.Now()
+	if _, err := eng.Get(key); err != nil {
+		return l, err
+	}
+	l.Read = time.Since(start)
+	return l, nil
+}
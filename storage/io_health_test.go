@@ -0,0 +1,100 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestIOHealth verifies that a store becomes suspect after
+// consecutive slow or failed probes only, and healthy again after as
+// many consecutive fast probes.
+func TestIOHealth(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	h := newIOHealth(100*time.Millisecond, 10*time.Millisecond)
+	fast := IOLatency{Sync: time.Millisecond, Read: time.Millisecond}
+	slowSync := IOLatency{Sync: time.Second, Read: time.Millisecond}
+	slowRead := IOLatency{Sync: time.Millisecond, Read: time.Second}
+	testCases := []struct {
+		latency    IOLatency
+		err        error
+		expChanged bool
+		expSuspect bool
+	}{
+		{slowSync, nil, false, false},
+		{slowRead, nil, false, false},
+		{fast, nil, false, false}, // resets the count of slow probes
+		{slowSync, nil, false, false},
+		{slowRead, nil, false, false},
+		{fast, errors.New("failed"), true, true},
+		{slowSync, nil, false, true},
+		{fast, nil, false, true},
+		{fast, nil, false, true},
+		{fast, nil, true, false},
+	}
+	for i, test := range testCases {
+		if changed := h.record(test.latency, test.err); changed != test.expChanged {
+			t.Errorf("%d: expected changed %t; got %t", i, test.expChanged, changed)
+		}
+		if suspect := h.isSuspect(); suspect != test.expSuspect {
+			t.Errorf("%d: expected suspect %t; got %t", i, test.expSuspect, suspect)
+		}
+		if l := h.latency(); l != test.latency {
+			t.Errorf("%d: expected latency %+v; got %+v", i, test.latency, l)
+		}
+	}
+}
+
+// TestProbeIO verifies that syncs are only probed for stores with a
+// directory and that the probe cleans up after itself.
+func TestProbeIO(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	key := engine.MVCCEncodeKey(engine.StoreIdentKey())
+	l, err := probeIO("", eng, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Sync != 0 {
+		t.Errorf("expected no sync probe without a directory; got %s", l.Sync)
+	}
+
+	dir, err := ioutil.TempDir("", "io-probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if l, err = probeIO(dir, eng, key); err != nil {
+		t.Fatal(err)
+	}
+	if l.Sync == 0 {
+		t.Error("expected sync latency to be measured")
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil || len(files) != 0 {
+		t.Errorf("expected probe file to be removed; got %v, %v", files, err)
+	}
+	if _, err := probeIO(dir+"/missing", eng, key); err == nil {
+		t.Error("expected error probing a missing directory")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// rateWindow is the period over which a rateCounter's rate is measured.
+const rateWindow = time.Minute
+
+// A rateCounter counts events, both in total and over the most recent
+// rateWindow, in one second buckets.
+type rateCounter struct {
+	sync.Mutex
+	total   int64
+	seconds [rateWindow / time.Second]int64 // Unix second of each bucket
+	counts  [rateWindow / time.Second]int64 // Events counted in each bucket
+}
+
+// inc counts an event occurring at now.
+func (rc *rateCounter) inc(now time.Time) {
+	rc.Lock()
+	defer rc.Unlock()
+	rc.total++
+	sec := now.Unix()
+	i := sec % int64(len(rc.seconds))
+	if rc.seconds[i] != sec {
+		rc.seconds[i] = sec
+		rc.counts[i] = 0
+	}
+	rc.counts[i]++
+}
+
+// Total returns the number of events counted.
+func (rc *rateCounter) Total() int64 {
+	rc.Lock()
+	defer rc.Unlock()
+	return rc.total
+}
+
+// Rate returns the number of events counted within rateWindow of now.
+func (rc *rateCounter) Rate(now time.Time) int64 {
+	rc.Lock()
+	defer rc.Unlock()
+	sec := now.Unix()
+	var rate int64
+	for i, s := range rc.seconds {
+		if s > sec-int64(len(rc.seconds)) && s <= sec {
+			rate += rc.counts[i]
+		}
+	}
+	return rate
+}
+
+// leaseMetrics counts the leader leases acquired by a store's
+// replicas and the errors encountered obtaining them. It's safe for
+// concurrent use.
+type leaseMetrics struct {
+	acquisitions rateCounter // Leases granted to a replica which didn't hold it
+	transfers    rateCounter // Acquisitions of leases held by another replica
+	errors       rateCounter // Failed lease requests and not-leader errors
+}
+
+// readMetrics counts the read-only commands served by a store's
+// replicas. It's safe for concurrent use.
+type readMetrics struct {
+	local      rateCounter // Reads served from replica state, without Raft
+	consensus  rateCounter // Reads proposed to Raft as they require CONSENSUS
+	unexpected rateCounter // Other reads applied through Raft
+}
+
+// recordLease counts the acquisition of lease by a replica of the
+// store, if the replica didn't already hold prev, the range's former
+// lease, when lease began.
+func (lm *leaseMetrics) recordLease(prev, lease *proto.Lease, now time.Time) {
+	start := lease.Expiration - lease.Duration
+	if prev != nil && prev.RaftNodeID == lease.RaftNodeID && prev.Expiration >= start {
+		// An extension of the replica's lease.
+		return
+	}
+	lm.acquisitions.inc(now)
+	if prev != nil && prev.RaftNodeID != lease.RaftNodeID {
+		lm.transfers.inc(now)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// TestRateCounter verifies that events are counted in total and over
+// the last minute.
+func TestRateCounter(t *testing.T) {
+	var rc rateCounter
+	start := time.Unix(1000, 0)
+	for i := 0; i < 90; i++ {
+		rc.inc(start.Add(time.Duration(i) * time.Second))
+	}
+	if total := rc.Total(); total != 90 {
+		t.Errorf("expected total of 90; got %d", total)
+	}
+	now := start.Add(89 * time.Second)
+	if rate := rc.Rate(now); rate != 60 {
+		t.Errorf("expected rate of 60; got %d", rate)
+	}
+	if rate := rc.Rate(now.Add(30 * time.Second)); rate != 30 {
+		t.Errorf("expected rate of 30; got %d", rate)
+	}
+	if rate := rc.Rate(now.Add(time.Hour)); rate != 0 {
+		t.Errorf("expected rate of 0; got %d", rate)
+	}
+}
+
+// TestLeaseMetrics verifies that new leases are counted as
+// acquisitions and transfers, but extensions aren't.
+func TestLeaseMetrics(t *testing.T) {
+	lease := func(raftNodeID uint64, start int64) *proto.Lease {
+		return &proto.Lease{RaftNodeID: raftNodeID, Expiration: start + 10, Duration: 10}
+	}
+	testCases := []struct {
+		prev, lease           *proto.Lease
+		acquired, transferred bool
+	}{
+		{nil, lease(1, 0), true, false},
+		{lease(1, 0), lease(1, 5), false, false},
+		{lease(1, 0), lease(1, 20), true, false},
+		{lease(2, 0), lease(1, 5), true, true},
+	}
+	for i, c := range testCases {
+		var lm leaseMetrics
+		lm.recordLease(c.prev, c.lease, time.Now())
+		if a := lm.acquisitions.Total() == 1; a != c.acquired {
+			t.Errorf("%d: expected acquired %t; got %t", i, c.acquired, a)
+		}
+		if tr := lm.transfers.Total() == 1; tr != c.transferred {
+			t.Errorf("%d: expected transferred %t; got %t", i, c.transferred, tr)
+		}
+	}
+}
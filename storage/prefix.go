@@ -309,3 +309,43 @@ func (p PrefixConfigMap) SplitRangeByPrefixes(start, end proto.Key) ([]*RangeRes
 	}
 	return results, nil
 }
+
+// ResolveZoneConfig returns the zone config which applies to key in a
+// zone config map: that of the longest matching prefix, with the
+// fields it doesn't set inherited from the zones of successively
+// shorter matching prefixes for as long as those zones inherit. The
+// returned map holds, for each field set in the resolved zone, the
+// prefix of the zone its value was taken from.
+func (p PrefixConfigMap) ResolveZoneConfig(key proto.Key) (*proto.ZoneConfig, map[string]proto.Key) {
+	resolved := &proto.ZoneConfig{}
+	sources := map[string]proto.Key{}
+	for _, pc := range p.MatchesByPrefix(key) {
+		zone := pc.Config.(*proto.ZoneConfig)
+		for _, field := range resolved.InheritFrom(zone) {
+			sources[field] = pc.Prefix
+		}
+		if !zone.Inherit {
+			break
+		}
+	}
+	return resolved, sources
+}
+
+// resolveZoneInheritance replaces the config of each prefix in a zone
+// config map with its resolved zone config, so that the users of the
+// map needn't be aware of inheritance.
+func (p PrefixConfigMap) resolveZoneInheritance() {
+	resolved := map[string]*proto.ZoneConfig{}
+	for _, pc := range p {
+		if pc.Canonical == nil {
+			resolved[string(pc.Prefix)], _ = p.ResolveZoneConfig(pc.Prefix)
+		}
+	}
+	for _, pc := range p {
+		prefix := pc.Prefix
+		if pc.Canonical != nil {
+			prefix = pc.Canonical
+		}
+		pc.Config = resolved[string(prefix)]
+	}
+}
@@ -452,3 +452,58 @@ func TestVisitPrefixes(t *testing.T) {
 		t.Errorf("expected configs %+v; got %+v", expConfigs, configs)
 	}
 }
+
+// TestResolveZoneConfig verifies that zones inherit the fields they
+// don't set from the zones of shorter prefixes for as long as those
+// zones inherit, and that the source of each field is reported.
+func TestResolveZoneConfig(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	replicas := []proto.Attributes{{Attrs: []string{"ssd"}}}
+	configs := []*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{ReplicaAttrs: replicas, RangeMinBytes: 1 << 20, RangeMaxBytes: 64 << 20}},
+		{proto.Key("/db1"), nil, &proto.ZoneConfig{RangeMaxBytes: 32 << 20, Inherit: true}},
+		{proto.Key("/db1/table"), nil, &proto.ZoneConfig{ReadsPerSecond: 10, Inherit: true}},
+		{proto.Key("/db2"), nil, &proto.ZoneConfig{ReplicaAttrs: replicas, RangeMaxBytes: 16 << 20}},
+	}
+	pcc, err := NewPrefixConfigMap(configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zone, sources := pcc.ResolveZoneConfig(proto.Key("/db1/table/1"))
+	expZone := &proto.ZoneConfig{ReplicaAttrs: replicas, RangeMinBytes: 1 << 20, RangeMaxBytes: 32 << 20, ReadsPerSecond: 10}
+	if !reflect.DeepEqual(zone, expZone) {
+		t.Errorf("expected zone %+v; got %+v", expZone, zone)
+	}
+	expSources := map[string]proto.Key{
+		"replicas":         engine.KeyMin,
+		"range_min_bytes":  engine.KeyMin,
+		"range_max_bytes":  proto.Key("/db1"),
+		"reads_per_second": proto.Key("/db1/table"),
+	}
+	if !reflect.DeepEqual(sources, expSources) {
+		t.Errorf("expected sources %+v; got %+v", expSources, sources)
+	}
+
+	// A zone which doesn't inherit leaves its unset fields unset.
+	if zone, _ := pcc.ResolveZoneConfig(proto.Key("/db2/a")); zone.RangeMinBytes != 0 {
+		t.Errorf("expected range min bytes of non-inheriting zone to be unset; got %+v", zone)
+	}
+
+	// Resolving the map fills in the inherited fields of each prefix,
+	// including those of the entries marking the ends of prefixes.
+	pcc.resolveZoneInheritance()
+	for _, key := range []string{"/db1/a", "/db1/tablf"} {
+		if zone := pcc.MatchByPrefix(proto.Key(key)).Config.(*proto.ZoneConfig); zone.RangeMinBytes != 1<<20 || zone.RangeMaxBytes != 32<<20 {
+			t.Errorf("%s: expected zone of /db1 with inherited fields; got %+v", key, zone)
+		}
+	}
+	for _, pc := range pcc {
+		if pc.Canonical == nil {
+			continue
+		}
+		if canonical := pcc.MatchByPrefix(pc.Canonical); pc.Config != canonical.Config {
+			t.Errorf("expected end of prefix %q to hold the resolved zone of %q", pc.Prefix, pc.Canonical)
+		}
+	}
+}
@@ -50,6 +50,7 @@ func init() {
 	gob.Register(StoreDescriptor{})
 	gob.Register(PrefixConfigMap{})
 	gob.Register(&proto.AcctConfig{})
+	gob.Register(&proto.BalanceConfig{})
 	gob.Register(&proto.PermConfig{})
 	gob.Register(&proto.ZoneConfig{})
 	gob.Register(proto.RangeDescriptor{})
@@ -93,6 +94,10 @@ const (
 	defaultLeaderLeaseDuration = time.Second
 )
 
+// maxRangeHistoryEvents is the number of splits and merges retained
+// in a range's history; older events are dropped.
+const maxRangeHistoryEvents = 50
+
 // configDescriptor describes administrative configuration maps
 // affecting ranges of the key-value map by key prefix.
 type configDescriptor struct {
@@ -134,6 +139,14 @@ func usesTimestampCache(r proto.Request) bool {
 	return tsCacheMethods[m]
 }
 
+// isReplicatedWrite returns true if args is a write shipped by a
+// primary cluster to a standby, which is applied at the timestamp at
+// which the primary committed it.
+func isReplicatedWrite(rm RangeManager, args proto.Request) bool {
+	header := args.Header()
+	return rm.standby() && header.User == UserReplication && header.Txn == nil && !proto.IsReadOnly(args)
+}
+
 // A pendingCmd holds the reply buffer and a done channel for a command
 // sent to Raft. Once committed to the Raft log, the command is
 // executed and the result returned via the done channel.
@@ -155,6 +168,10 @@ type RangeManager interface {
 	Allocator() *allocator
 	Gossip() *gossip.Gossip
 	SplitQueue() *splitQueue
+	ReadOnly() bool
+	IOSuspect() bool
+	Corrupt() bool
+	Draining() bool
 
 	// Range manipulation methods.
 	AddRange(rng *Range) error
@@ -166,6 +183,15 @@ type RangeManager interface {
 	RemoveRange(rng *Range) error
 	SplitRange(origRng, newRng *Range) error
 
+	closedTimestampLag() time.Duration
+	standby() bool
+	leaseMetrics() *leaseMetrics
+	readMetrics() *readMetrics
+	systemConfig(key string) (PrefixConfigMap, error)
+	throttledCmds() *rateCounter
+	readCache() *readCache
+	scanPrefetchSize() int
+	txnAbandonTimeout() time.Duration
 	startGroup(raftID int64) error
 }
 
@@ -180,6 +206,7 @@ type Range struct {
 	rm       RangeManager   // Makes some store methods available
 	stats    *rangeStats    // Range statistics
 	maxBytes int64          // Max bytes before split.
+	limits   unsafe.Pointer // Atomic pointer for *rateLimits of the range's zone
 	// Held while a split, merge, or replica change is underway.
 	metaLock sync.Mutex
 	// Last index persisted to the raft log (not necessarily committed).
@@ -191,12 +218,23 @@ type Range struct {
 	stopper      *util.Stopper
 	// TODO(tschottdorf)
 	election chan struct{}
+	// Number of commands and snapshots applied, which invalidate the
+	// entries of the range in the read cache. Updated atomically.
+	writes uint64
+	// Held while assigning a closed timestamp to a command and proposing
+	// it, so that commands enter the Raft log in closed timestamp order.
+	proposeMu        sync.Mutex
+	proposedClosedTS proto.Timestamp // Protected by proposeMu
+	// Holds proposals for the commit coalescing window of the range's
+	// zone.
+	coalescer proposalCoalescer
 
 	sync.RWMutex                 // Protects the following fields (and Desc)
 	cmdQ         *CommandQueue   // Enforce at most one command is running per key(s)
 	tsCache      *TimestampCache // Most recent timestamps for keys / key ranges
 	respCache    *ResponseCache  // Provides idempotence for retries
 	pendingCmds  map[cmdIDKey]*pendingCmd
+	closedTS     proto.Timestamp // Closed timestamp of applied commands
 }
 
 var _ multiraft.WriteableGroupStorage = &Range{}
@@ -247,21 +285,48 @@ func (r *Range) start(stopper *util.Stopper) {
 	r.maybeGossipClusterID()
 	r.maybeGossipFirstRange()
 	r.maybeGossipConfigs(configDescriptors...)
+	r.maybeGossipBalanceConfig()
 	// Only start gossiping if this range is the first range.
 	if r.IsFirstRange() {
 		r.startGossip()
 	}
 }
 
-// Destroy cleans up all data associated with this range.
+// Destroy cleans up all data associated with this range. The data is
+// cleared in batches, as a range may hold more than should be held in
+// memory at once. The range descriptor is cleared last, so that a
+// replica whose destruction is interrupted is loaded, and destroyed
+// again by the replica GC queue, when the store restarts.
 func (r *Range) Destroy() error {
-	var deletes []interface{}
+	descPrefix := engine.MVCCEncodeKey(engine.RangeDescriptorKey(r.Desc().StartKey))
+	var descKeys []proto.EncodedKey
+	bw := engine.NewBatchWriter(r.rm.Engine(), 0, nil)
 	iter := newRangeDataIterator(r, r.rm.Engine())
-	defer iter.Close()
 	for ; iter.Valid(); iter.Next() {
-		deletes = append(deletes, engine.BatchDelete{RawKeyValue: proto.RawKeyValue{Key: iter.Key()}})
+		key := iter.Key()
+		if bytes.HasPrefix(key, descPrefix) {
+			descKeys = append(descKeys, append(proto.EncodedKey(nil), key...))
+			continue
+		}
+		if err := bw.Clear(key); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	err := iter.Error()
+	iter.Close()
+	if err != nil {
+		return err
 	}
-	return r.rm.Engine().WriteBatch(deletes)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	for _, key := range descKeys {
+		if err := bw.Clear(key); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
 }
 
 // GetMaxBytes atomically gets the range maximum byte limit.
@@ -275,6 +340,18 @@ func (r *Range) SetMaxBytes(maxBytes int64) {
 	atomic.StoreInt64(&r.maxBytes, maxBytes)
 }
 
+// setRateLimits sets the rate limits of the range's zone, which the
+// range shares with the other ranges of the zone on the store.
+func (r *Range) setRateLimits(rl *rateLimits) {
+	atomic.StorePointer(&r.limits, unsafe.Pointer(rl))
+}
+
+// getRateLimits returns the rate limits of the range's zone, or nil if
+// they haven't been set.
+func (r *Range) getRateLimits() *rateLimits {
+	return (*rateLimits)(atomic.LoadPointer(&r.limits))
+}
+
 // IsFirstRange returns true if this is the first range.
 func (r *Range) IsFirstRange() bool {
 	return bytes.Equal(r.Desc().StartKey, engine.KeyMin)
@@ -300,16 +377,19 @@ func (r *Range) getLease() *proto.Lease {
 func (r *Range) canServiceCmd(args proto.Request) error {
 	header := args.Header()
 	if !r.IsLeader() {
-		if !proto.IsReadOnly(args) || header.ReadConsistency == proto.CONSISTENT {
+		if !proto.IsReadOnly(args) || (header.ReadConsistency != proto.INCONSISTENT && header.MaxStaleness <= 0) {
 			// TODO(spencer): when we happen to know the leader, fill it in here via replica.
+			r.rm.leaseMetrics().errors.inc(time.Now())
 			return &proto.NotLeaderError{}
 		}
 	}
 	if proto.IsReadOnly(args) {
-		if header.ReadConsistency == proto.CONSENSUS {
-			return util.Errorf("consensus reads not implemented")
+		if header.ReadConsistency == proto.CONSENSUS && header.MaxStaleness > 0 {
+			return util.Errorf("cannot allow bounded-staleness reads requiring consensus")
 		} else if header.ReadConsistency == proto.INCONSISTENT && header.Txn != nil {
 			return util.Errorf("cannot allow inconsistent reads within a transaction")
+		} else if header.MaxStaleness > 0 && header.Txn != nil {
+			return util.Errorf("cannot allow bounded-staleness reads within a transaction")
 		}
 	}
 	if !r.ContainsKeyRange(header.Key, header.EndKey) {
@@ -391,6 +471,50 @@ func (r *Range) SetLastVerificationTimestamp(timestamp proto.Timestamp) error {
 	return engine.MVCCPutProto(r.rm.Engine(), nil, key, proto.ZeroTimestamp, nil, &timestamp)
 }
 
+// GetLastReplicaGCTimestamp reads the timestamp at which the replica
+// was last checked for membership in the range by the replica GC
+// queue.
+func (r *Range) GetLastReplicaGCTimestamp() (proto.Timestamp, error) {
+	key := engine.RangeLastReplicaGCTimestampKey(r.Desc().RaftID)
+	timestamp := proto.Timestamp{}
+	_, err := engine.MVCCGetProto(r.rm.Engine(), key, proto.ZeroTimestamp, true, nil, &timestamp)
+	if err != nil {
+		return proto.ZeroTimestamp, err
+	}
+	return timestamp, nil
+}
+
+// SetLastReplicaGCTimestamp writes the timestamp at which the replica
+// was last checked for membership in the range by the replica GC
+// queue.
+func (r *Range) SetLastReplicaGCTimestamp(timestamp proto.Timestamp) error {
+	key := engine.RangeLastReplicaGCTimestampKey(r.Desc().RaftID)
+	return engine.MVCCPutProto(r.rm.Engine(), nil, key, proto.ZeroTimestamp, nil, &timestamp)
+}
+
+// GetHistory reads the splits and merges in which the range took
+// part, oldest first.
+func (r *Range) GetHistory() (*proto.RangeHistory, error) {
+	key := engine.RangeHistoryKey(r.Desc().RaftID)
+	hist := &proto.RangeHistory{}
+	if _, err := engine.MVCCGetProto(r.rm.Engine(), key, proto.ZeroTimestamp, true, nil, hist); err != nil {
+		return nil, err
+	}
+	return hist, nil
+}
+
+// putHistory writes hist with event appended as the history of the
+// range with the given raft ID, dropping the oldest events in excess
+// of maxRangeHistoryEvents.
+func putHistory(batch engine.Engine, raftID int64, hist *proto.RangeHistory, event proto.RangeHistoryEvent) error {
+	events := append(append([]proto.RangeHistoryEvent(nil), hist.Events...), event)
+	if len(events) > maxRangeHistoryEvents {
+		events = events[len(events)-maxRangeHistoryEvents:]
+	}
+	return engine.MVCCPutProto(batch, nil, engine.RangeHistoryKey(raftID), proto.ZeroTimestamp, nil,
+		&proto.RangeHistory{Events: events})
+}
+
 // AddCmd adds a command for execution on this range. The command's
 // affected keys are verified to be contained within the range and the
 // range's leadership is confirmed. The command is then dispatched
@@ -402,6 +526,10 @@ func (r *Range) AddCmd(args proto.Request, reply proto.Response, wait bool) erro
 		reply.Header().SetGoError(err)
 		return err
 	}
+	if err := r.throttle(args); err != nil {
+		reply.Header().SetGoError(err)
+		return err
+	}
 
 	// Differentiate between read-only and read-write.
 	if proto.IsAdmin(args) {
@@ -412,17 +540,40 @@ func (r *Range) AddCmd(args proto.Request, reply proto.Response, wait bool) erro
 	return r.addReadWriteCmd(args, reply, wait)
 }
 
+// throttle delays a command subject to the rate limits of the range's
+// zone until its token bucket admits it, so that a burst of commands to
+// one zone doesn't starve the ranges of others of the store's
+// resources.
+func (r *Range) throttle(args proto.Request) error {
+	tb := r.getRateLimits().bucket(args)
+	if tb == nil {
+		return nil
+	}
+	now := time.Now()
+	wait := tb.reserve(now, args.Header().PriorityClass)
+	if wait == 0 {
+		return nil
+	}
+	r.rm.throttledCmds().inc(now)
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-r.stopper.ShouldStop():
+		return util.Errorf("%s: stopped while throttling %s", r, args.Method())
+	}
+}
+
 // beginCmd waits for any overlapping, already-executing commands via
 // the command queue and adds itself to the queue to gate follow-on
 // commands which overlap its key range. This method will block if
-// there are any overlapping commands already in the queue. Returns
-// the command queue insertion key, to be supplied to subsequent
-// invocation of cmdQ.Remove().
-func (r *Range) beginCmd(start, end proto.Key, readOnly bool) interface{} {
+// there are any overlapping commands already in the queue, other than
+// waiting commands of a lower priority class. Returns the command
+// queue insertion key, to be supplied to subsequent invocation of
+// cmdQ.Remove().
+func (r *Range) beginCmd(start, end proto.Key, readOnly bool, priority proto.PriorityClass) interface{} {
 	r.Lock()
 	var wg sync.WaitGroup
-	r.cmdQ.GetWait(start, end, readOnly, &wg)
-	cmdKey := r.cmdQ.Add(start, end, readOnly)
+	cmdKey := r.cmdQ.Enqueue(start, end, readOnly, priority, &wg)
 	r.Unlock()
 	wg.Wait()
 	return cmdKey
@@ -445,18 +596,31 @@ func (r *Range) addAdminCmd(args proto.Request, reply proto.Response) error {
 
 // addReadOnlyCmd updates the read timestamp cache and waits for any
 // overlapping writes currently processing through Raft ahead of us to
-// clear via the read queue.
+// clear via the read queue. Reads are served from the replica's state
+// without going through Raft, unless they require CONSENSUS.
 func (r *Range) addReadOnlyCmd(args proto.Request, reply proto.Response) error {
 	header := args.Header()
 
 	// If read-consistency is set to INCONSISTENT, run directly.
 	if header.ReadConsistency == proto.INCONSISTENT {
-		return r.executeCmd(0, args, reply)
+		r.rm.readMetrics().local.inc(time.Now())
+		return r.executeCmd(0, false, args, reply)
+	}
+
+	// A bounded-staleness read served by a follower reads at the
+	// newest timestamp it may.
+	if header.MaxStaleness > 0 && !r.IsLeader() {
+		ts, err := r.staleReadTimestamp(header)
+		if err != nil {
+			reply.Header().SetGoError(err)
+			return err
+		}
+		header.Timestamp = ts
 	}
 
 	// Add the read to the command queue to gate subsequent
 	// overlapping, commands until this command completes.
-	cmdKey := r.beginCmd(header.Key, header.EndKey, true)
+	cmdKey := r.beginCmd(header.Key, header.EndKey, true, header.PriorityClass)
 
 	// It's possible that arbitrary delays (e.g. major GC, VM
 	// de-prioritization, etc.) could cause the execution of this read
@@ -476,7 +640,17 @@ func (r *Range) addReadOnlyCmd(args proto.Request, reply proto.Response) error {
 	if err := r.canServiceCmd(args); err != nil {
 		return err
 	}
-	err := r.executeCmd(0, args, reply)
+	var err error
+	if header.ReadConsistency == proto.CONSENSUS {
+		r.rm.readMetrics().consensus.inc(time.Now())
+		cmd, raftChan := r.proposeCmd(args, reply)
+		if err = <-raftChan; err == nil {
+			err = <-cmd.done
+		}
+	} else {
+		r.rm.readMetrics().local.inc(time.Now())
+		err = r.executeLocalRead(args, reply)
+	}
 
 	// Only update the timestamp cache if the command succeeded.
 	r.Lock()
@@ -489,6 +663,50 @@ func (r *Range) addReadOnlyCmd(args proto.Request, reply proto.Response) error {
 	return err
 }
 
+// executeLocalRead executes a read-only command without Raft. If the
+// store has a read cache, consistent, non-transactional Gets served by
+// the leader are answered from the cache where possible, and cached
+// otherwise if they read the key's most recent version.
+func (r *Range) executeLocalRead(args proto.Request, reply proto.Response) error {
+	get, ok := args.(*proto.GetRequest)
+	cache := r.rm.readCache()
+	if !ok || cache == nil || get.Txn != nil || get.ReadConsistency != proto.CONSISTENT ||
+		!r.IsLeader() || !r.ContainsKey(get.Key) {
+		return r.executeCmd(0, false, args, reply)
+	}
+	if v, ok := cache.get(r, get.Key, get.Timestamp); ok {
+		reply.(*proto.GetResponse).Value = v
+		reply.Header().Timestamp = get.Timestamp
+		return nil
+	}
+	gen := r.writeGen()
+	err := r.executeCmd(0, false, args, reply)
+	if err == nil && r.readsLatest(get.Key, get.Timestamp) {
+		cache.add(r, gen, get.Key, get.Timestamp, reply.(*proto.GetResponse).Value)
+	}
+	return err
+}
+
+// readsLatest returns whether a read of key at timestamp sees the
+// key's most recent version: the key has neither an intent nor a
+// version above the timestamp. Other reads aren't cached, as reads at
+// later timestamps answered by them would miss the newer version or
+// the conflict with the intent.
+func (r *Range) readsLatest(key proto.Key, timestamp proto.Timestamp) bool {
+	meta := &proto.MVCCMetadata{}
+	ok, _, _, err := r.rm.Engine().GetProto(engine.MVCCEncodeKey(key), meta)
+	if err != nil {
+		return false
+	}
+	return !ok || (meta.Txn == nil && !timestamp.Less(meta.Timestamp))
+}
+
+// writeGen returns the number of commands and snapshots applied to
+// the range.
+func (r *Range) writeGen() uint64 {
+	return atomic.LoadUint64(&r.writes)
+}
+
 // getCmdID will create a ClientCmdId if it's empty in Request, otherwise
 // just return it.
 func (r *Range) getCmdID(args proto.Request) (cmdID proto.ClientCmdID) {
@@ -535,7 +753,7 @@ func (r *Range) addReadWriteCmd(args proto.Request, reply proto.Response, wait b
 	// done before getting the max timestamp for the key(s), as
 	// timestamp cache is only updated after preceding commands have
 	// been run to successful completion.
-	cmdKey := r.beginCmd(header.Key, header.EndKey, false)
+	cmdKey := r.beginCmd(header.Key, header.EndKey, false, header.PriorityClass)
 
 	// Two important invariants of Cockroach: 1) encountering a more
 	// recently written value means transaction restart. 2) values must
@@ -544,8 +762,9 @@ func (r *Range) addReadWriteCmd(args proto.Request, reply proto.Response, wait b
 	// are at least as recent as the timestamp of this write. For
 	// writes, send WriteTooOldError; for reads, update the write's
 	// timestamp. When the write returns, the updated timestamp will
-	// inform the final commit timestamp.
-	if usesTimestampCache(args) {
+	// inform the final commit timestamp. Replicated writes keep the
+	// timestamps of the primary.
+	if usesTimestampCache(args) && !isReplicatedWrite(r.rm, args) {
 		r.Lock()
 		rTS, wTS := r.tsCache.GetMax(header.Key, header.EndKey, txnMD5)
 		r.Unlock()
@@ -572,26 +791,7 @@ func (r *Range) addReadWriteCmd(args proto.Request, reply proto.Response, wait b
 	}
 
 	// Create command and enqueue for Raft.
-	pendingCmd := &pendingCmd{
-		Reply: reply,
-		done:  make(chan error, 1),
-	}
-	raftCmd := proto.InternalRaftCommand{
-		RaftID: r.Desc().RaftID,
-	}
-	cmdID := r.getCmdID(args)
-	ok := raftCmd.Cmd.SetValue(args)
-	if !ok {
-		log.Fatalf("unknown command type %T", args)
-	}
-	idKey := makeCmdIDKey(cmdID)
-	r.Lock()
-	r.pendingCmds[idKey] = pendingCmd
-	r.Unlock()
-	// TODO(bdarnell): In certain raft failover scenarios, proposed
-	// commands may be abandoned. We need to re-propose the command
-	// if too much time passes with no response on the done channel.
-	raftChan := r.rm.ProposeRaftCommand(idKey, raftCmd)
+	pendingCmd, raftChan := r.proposeCmd(args, reply)
 
 	// Create a completion func for mandatory cleanups which we either
 	// run synchronously if we're waiting or in a goroutine otherwise.
@@ -629,18 +829,87 @@ func (r *Range) addReadWriteCmd(args proto.Request, reply proto.Response, wait b
 	return nil
 }
 
+// proposeCmd proposes the command to Raft. It returns the pending
+// command, whose done channel receives the result of its execution,
+// and the channel on which Raft signals that it has been committed or
+// aborted. A timestamp is closed with the command, after moving a
+// write above it if necessary; the timestamp is ours to change as the
+// timestamp cache has already been checked.
+func (r *Range) proposeCmd(args proto.Request, reply proto.Response) (*pendingCmd, <-chan error) {
+	header := args.Header()
+	cmd := &pendingCmd{
+		Reply: reply,
+		done:  make(chan error, 1),
+	}
+	raftCmd := proto.InternalRaftCommand{
+		RaftID: r.Desc().RaftID,
+	}
+	cmdID := r.getCmdID(args)
+	ok := raftCmd.Cmd.SetValue(args)
+	if !ok {
+		log.Fatalf("unknown command type %T", args)
+	}
+	idKey := makeCmdIDKey(cmdID)
+	r.proposeMu.Lock()
+	raftCmd.ClosedTimestamp = r.closeTimestamp()
+	if closed := raftCmd.ClosedTimestamp; usesTimestampCache(args) && !proto.IsReadOnly(args) &&
+		!isReplicatedWrite(r.rm, args) && !closed.Equal(proto.ZeroTimestamp) && !closed.Less(header.Timestamp) {
+		header.Timestamp = closed.Next()
+	}
+	r.Lock()
+	r.pendingCmds[idKey] = cmd
+	r.Unlock()
+	// TODO(bdarnell): In certain raft failover scenarios, proposed
+	// commands may be abandoned. We need to re-propose the command
+	// if too much time passes with no response on the done channel.
+	raftChan := r.proposeRaftCommand(idKey, raftCmd)
+	r.proposeMu.Unlock()
+	return cmd, raftChan
+}
+
+// processRaftCommand executes a command committed to the raft log at
+// index. If sync is true, the engine's log is synced once the
+// command's writes are applied; see executeCmd. The returned function
+// notifies the command's proposer, if any, of the result of the
+// command; it must only be invoked once the writes are durable.
 func (r *Range) processRaftCommand(idKey cmdIDKey, index uint64,
-	raftCmd proto.InternalRaftCommand) error {
+	raftCmd proto.InternalRaftCommand, sync bool) (func(), error) {
 	if index == 0 {
 		log.Fatal("processRaftCommand requires a non-zero index")
 	}
+	if len(raftCmd.Coalesced) > 0 {
+		return r.processCoalescedCommand(index, raftCmd, sync)
+	}
+	// A command without a request only publishes a closed timestamp.
+	if raftCmd.Cmd.GetValue() == nil {
+		r.advanceAppliedIndex(index, sync)
+		r.Lock()
+		r.closedTS.Forward(raftCmd.ClosedTimestamp)
+		r.Unlock()
+		return func() {}, nil
+	}
+	cmd, args, reply := r.takePendingCmd(idKey, raftCmd)
+	err := r.executeCmd(index, sync, args, reply)
+	return r.cmdApplied(cmd, args, raftCmd, err), err
+}
+
+// takePendingCmd returns the pending command proposed by this replica
+// as idKey, if any, and the request of the raft command with the reply
+// to be filled in by its execution.
+func (r *Range) takePendingCmd(idKey cmdIDKey, raftCmd proto.InternalRaftCommand) (
+	*pendingCmd, proto.Request, proto.Response) {
 	r.Lock()
 	cmd := r.pendingCmds[idKey]
 	delete(r.pendingCmds, idKey)
 	r.Unlock()
 
 	args := raftCmd.Cmd.GetValue().(proto.Request)
-	method := args.Method()
+	if proto.IsReadOnly(args) && args.Header().ReadConsistency != proto.CONSENSUS {
+		// Reads are served by the leader without going through Raft;
+		// only those requiring consensus are expected here.
+		r.rm.readMetrics().unexpected.inc(time.Now())
+		log.Warningf("%s: %s read applied through raft without requiring consensus", r, args.Method())
+	}
 
 	var reply proto.Response
 	if cmd != nil {
@@ -650,13 +919,120 @@ func (r *Range) processRaftCommand(idKey cmdIDKey, index uint64,
 		// This command originated elsewhere so we must create a new reply buffer.
 		reply = args.CreateReply()
 	}
-	err := r.executeCmd(index, args, reply)
-	if cmd != nil {
-		cmd.done <- err
-	} else if err != nil {
-		log.Errorf("error executing raft command %s: %s", method, err)
+	return cmd, args, reply
+}
+
+// cmdApplied records the application of a raft command, once its
+// batch was committed, and returns the function which notifies its
+// proposer, if any, of its result.
+func (r *Range) cmdApplied(cmd *pendingCmd, args proto.Request,
+	raftCmd proto.InternalRaftCommand, err error) func() {
+	// The command's writes, if any, are now visible; reads which began
+	// before they were may no longer be cached.
+	atomic.AddUint64(&r.writes, 1)
+	if cmd == nil && err != nil {
+		log.Errorf("error executing raft command %s: %s", args.Method(), err)
 	}
-	return err
+	r.Lock()
+	r.closedTS.Forward(raftCmd.ClosedTimestamp)
+	r.Unlock()
+	return func() {
+		if cmd != nil {
+			cmd.done <- err
+		}
+	}
+}
+
+// A coalescedResult is the result of one of the commands of a
+// coalesced raft command.
+type coalescedResult struct {
+	cmd     *pendingCmd
+	args    proto.Request
+	reply   proto.Response
+	raftCmd proto.InternalRaftCommand
+	write   bool // Executed write, inflight in the response cache
+	err     error
+}
+
+// processCoalescedCommand executes the commands coalesced into the
+// raft command at index, in order, through a single engine batch which
+// also advances the applied index. The commands are thus applied
+// atomically: after a restart, either all of them or none are
+// replayed. The returned function notifies the proposers of the
+// commands of their results.
+func (r *Range) processCoalescedCommand(index uint64, raftCmd proto.InternalRaftCommand,
+	sync bool) (func(), error) {
+	batch := r.rm.Engine().NewBatch()
+	var results []coalescedResult
+	var ms proto.MVCCStats
+	var wrote bool
+	var nowNanos int64
+	for _, c := range raftCmd.Coalesced {
+		if c.Cmd.Cmd.GetValue() == nil {
+			results = append(results, coalescedResult{raftCmd: c.Cmd})
+			continue
+		}
+		cmd, args, reply := r.takePendingCmd(cmdIDKey(c.CmdIDKey), c.Cmd)
+		cmdBatch, cmdMS, err := r.applyCmd(batch, 0, args, reply)
+		if cErr := cmdBatch.Commit(); cErr != nil && err == nil {
+			reply.Header().SetGoError(cErr)
+			err = cErr
+		}
+		if cmdMS != nil && err == nil {
+			engine.Accumulate(&ms, *cmdMS)
+			wrote = true
+			nowNanos = args.Header().Timestamp.WallTime
+		}
+		results = append(results, coalescedResult{cmd: cmd, args: args, reply: reply,
+			raftCmd: c.Cmd, write: cmdMS != nil, err: err})
+	}
+	// The applied index is accounted for in the stats only if a write
+	// succeeded, as for a command proposed alone.
+	var indexMS *proto.MVCCStats
+	if wrote {
+		indexMS = &ms
+	}
+	err := r.putAppliedIndex(batch, indexMS, index)
+	if err == nil {
+		if indexMS != nil {
+			r.stats.MergeMVCCStats(batch, &ms, nowNanos)
+		}
+		err = commitBatch(batch, sync)
+	}
+	if err != nil {
+		log.Errorf("failed to commit coalesced commands: %s", err)
+	} else if indexMS != nil {
+		r.stats.Update(ms)
+	}
+
+	var dones []func()
+	for _, res := range results {
+		if res.args == nil {
+			r.Lock()
+			r.closedTS.Forward(res.raftCmd.ClosedTimestamp)
+			r.Unlock()
+			continue
+		}
+		if err != nil && res.err == nil {
+			res.reply.Header().SetGoError(err)
+			res.err = err
+		}
+		if res.write {
+			if res.err == nil {
+				r.cmdCommitted(res.args)
+			}
+			r.respCache.removeInflight(res.args.Header().CmdID)
+		}
+		dones = append(dones, r.cmdApplied(res.cmd, res.args, res.raftCmd, res.err))
+	}
+	r.Lock()
+	r.closedTS.Forward(raftCmd.ClosedTimestamp)
+	r.Unlock()
+	return func() {
+		for _, done := range dones {
+			done()
+		}
+	}, err
 }
 
 // startGossip periodically gossips the cluster ID if it's the
@@ -726,7 +1102,8 @@ func (r *Range) maybeGossipConfigs(dirtyConfigs ...*configDescriptor) {
 
 // loadConfigMap scans the config entries under keyPrefix and
 // instantiates/returns a config map. Prefix configuration maps
-// include accounting, permissions, and zones.
+// include accounting, permissions, and zones; the inheritance of
+// zones is resolved.
 func (r *Range) loadConfigMap(keyPrefix proto.Key, configI interface{}) (PrefixConfigMap, error) {
 	kvs, err := engine.MVCCScan(r.rm.Engine(), keyPrefix, keyPrefix.PrefixEnd(), 0, proto.MaxTimestamp, true, nil)
 	if err != nil {
@@ -742,11 +1119,43 @@ func (r *Range) loadConfigMap(keyPrefix proto.Key, configI interface{}) (PrefixC
 		}
 		configs = append(configs, &PrefixConfig{Prefix: bytes.TrimPrefix(kv.Key, keyPrefix), Config: config})
 	}
-	return NewPrefixConfigMap(configs)
+	configMap, err := NewPrefixConfigMap(configs)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := configI.(proto.ZoneConfig); ok {
+		configMap.resolveZoneInheritance()
+	}
+	return configMap, nil
+}
+
+// maybeGossipBalanceConfig gossips the cluster's balance thresholds if
+// the range holds them and is the leader. Until they're set, nothing is
+// gossiped and the stores' allocators apply the default thresholds.
+func (r *Range) maybeGossipBalanceConfig() {
+	if r.rm.Gossip() == nil || !r.IsLeader() || !r.ContainsKey(engine.KeyConfigBalance) {
+		return
+	}
+	config := &proto.BalanceConfig{}
+	ok, err := engine.MVCCGetProto(r.rm.Engine(), engine.KeyConfigBalance, proto.MaxTimestamp, true, nil, config)
+	if err != nil {
+		log.Errorf("failed loading balance config: %s", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if err := r.rm.Gossip().AddInfo(gossip.KeyConfigBalance, config, 0*time.Second); err != nil {
+		log.Errorf("failed to gossip balance config: %s", err)
+	}
 }
 
 // maybeUpdateGossipConfigs is used to update gossip configs.
 func (r *Range) maybeUpdateGossipConfigs(key proto.Key) {
+	if key.Equal(engine.KeyConfigBalance) {
+		r.maybeGossipBalanceConfig()
+		return
+	}
 	// Check whether this put has modified a configuration map.
 	for _, cd := range configDescriptors {
 		if bytes.HasPrefix(key, cd.keyPrefix) {
@@ -772,6 +1181,12 @@ func (r *Range) maybeSplit() {
 // executeCmd switches over the method and multiplexes to execute the
 // appropriate storage API command.
 //
+// A raft command, with a non-zero index, is applied through a single
+// engine batch which also holds the applied index update and the
+// command's entry in the response cache. If sync is true, the batch is
+// committed with a sync of the engine's log, making it and the batches
+// of all commands applied before it durable.
+//
 // TODO(Spencer): Differentiate between errors caused by the normal culprits --
 // bad inputs from clients, stale information, etc. and errors which might
 // cause the range replicas to diverge -- running out of disk space, underlying
@@ -779,24 +1194,62 @@ func (r *Range) maybeSplit() {
 // errors which should be classified as a ReplicaCorruptionError--when those
 // bubble up to the point where we've just tried to execute a Raft command, the
 // Raft replica would need to stall itself.
-func (r *Range) executeCmd(index uint64, args proto.Request,
+func (r *Range) executeCmd(index uint64, sync bool, args proto.Request,
 	reply proto.Response) error {
+	batch, ms, err := r.applyCmd(r.rm.Engine(), index, args, reply)
+	if ms != nil {
+		defer r.respCache.removeInflight(args.Header().CmdID)
+		if err == nil {
+			r.stats.MergeMVCCStats(batch, ms, args.Header().Timestamp.WallTime)
+		}
+	}
+
+	// Read-only commands only write if applying a raft command.
+	if index == 0 && !proto.IsWrite(args) {
+		return err
+	}
+	if cErr := commitBatch(batch, sync); cErr != nil {
+		if err == nil {
+			reply.Header().SetGoError(cErr)
+		} else {
+			log.Errorf("failed to commit batch: %s", cErr)
+		}
+	} else if err == nil && ms != nil {
+		// After successful commit, update cached stats values.
+		r.stats.Update(*ms)
+		r.cmdCommitted(args)
+	}
+
+	// Return the error (if any) set in the reply.
+	return reply.Header().GoError()
+}
+
+// applyCmd executes the command in a batch nested in eng, which is the
+// range's engine or the batch of a coalesced raft command, and returns
+// the batch for the caller to commit. A non-zero index is written to
+// the batch as the applied index. For a write which was executed, the
+// batch holds its response cache entry and the stats of its writes are
+// returned, to be merged into the range's if it succeeded; the caller
+// must remove the command from the response cache's inflight commands
+// once the batch is committed.
+func (r *Range) applyCmd(eng engine.Engine, index uint64, args proto.Request,
+	reply proto.Response) (engine.Engine, *proto.MVCCStats, error) {
 	// Verify key is contained within range here to catch any range split
 	// or merge activity.
 	header := args.Header()
 	if !r.ContainsKeyRange(header.Key, header.EndKey) {
 		err := proto.NewRangeKeyMismatchError(header.Key, header.EndKey, r.Desc())
 		reply.Header().SetGoError(err)
-		return err
+		return r.appliedIndexBatch(eng, index), nil, err
 	}
 
 	// If a unittest filter was installed, check for an injected error; otherwise, continue.
 	if TestingCommandFilter != nil && TestingCommandFilter(args, reply) {
-		return reply.Header().GoError()
+		return r.appliedIndexBatch(eng, index), nil, reply.Header().GoError()
 	}
 
 	// Create a new batch for the command to ensure all or nothing semantics.
-	batch := r.rm.Engine().NewBatch()
+	batch := eng.NewBatch()
 	// Create an proto.MVCCStats instance.
 	ms := proto.MVCCStats{}
 
@@ -835,57 +1288,20 @@ func (r *Range) executeCmd(index uint64, args proto.Request,
 		r.InternalTruncateLog(batch, &ms, args.(*proto.InternalTruncateLogRequest), reply.(*proto.InternalTruncateLogResponse))
 	case *proto.InternalLeaderLeaseRequest:
 		r.InternalLeaderLease(args.(*proto.InternalLeaderLeaseRequest), reply.(*proto.InternalLeaderLeaseResponse))
+	case *proto.InternalIngestRequest:
+		r.InternalIngest(batch, &ms, args.(*proto.InternalIngestRequest), reply.(*proto.InternalIngestResponse))
 	default:
-		return util.Errorf("unrecognized command %s", args.Method())
+		reply.Header().SetGoError(util.Errorf("unrecognized command %s", args.Method()))
 	}
 
-	// On success, flush the MVCC stats to the batch and commit.
-	if err := reply.Header().GoError(); err == nil {
-		// If we are applying a raft command, update the applied index.
-		if index > 0 {
-			if oldIndex := atomic.LoadUint64(&r.appliedIndex); oldIndex >= index {
-				log.Fatalf("applied index moved backwards: %d >= %d", oldIndex, index)
-			}
-			atomic.StoreUint64(&r.appliedIndex, index)
-			err := engine.MVCCPut(batch, &ms, engine.RaftAppliedIndexKey(r.Desc().RaftID),
-				proto.ZeroTimestamp, proto.Value{Bytes: encoding.EncodeUint64(nil, index)}, nil)
-			if err != nil {
-				reply.Header().SetGoError(err)
-			}
-		}
-
-		if proto.IsWrite(args) {
-			r.stats.MergeMVCCStats(batch, &ms, header.Timestamp.WallTime)
-			if err := batch.Commit(); err != nil {
-				reply.Header().SetGoError(err)
-			} else {
-				// After successful commit, update cached stats values.
-				r.stats.Update(ms)
-				// If the commit succeeded, potentially add range to split queue.
-				r.maybeSplit()
-				// Maybe update gossip configs on a put.
-				switch args.(type) {
-				case *proto.PutRequest, *proto.ConditionalPutRequest:
-					if header.Key.Less(engine.KeySystemMax) {
-						r.maybeUpdateGossipConfigs(header.Key)
-					}
-				}
-			}
-		}
-	} else {
-		if index > 0 {
-			atomic.StoreUint64(&r.appliedIndex, index)
-			// On failure, abandon the batch we've built up, but still update the
-			// applied index so we won't retry this command on restart.
-			if err := engine.MVCCPut(r.rm.Engine(), nil, engine.RaftAppliedIndexKey(r.Desc().RaftID),
-				proto.ZeroTimestamp, proto.Value{Bytes: encoding.EncodeUint64(nil, index)}, nil); err != nil {
-				// The reply header already contains an error which is going to be more useful
-				// to the caller than this one, so just log it.
-				log.Errorf("failed to advance applied index: %s", err)
-			}
-		}
+	err := reply.Header().GoError()
+	if err != nil {
+		// On failure, abandon the batch we've built up. A new batch
+		// records the applied index, so we won't retry this command on
+		// restart, and the response.
+		batch = eng.NewBatch()
 
-		if err, ok := reply.Header().GoError().(*proto.ReadWithinUncertaintyIntervalError); ok {
+		if err, ok := err.(*proto.ReadWithinUncertaintyIntervalError); ok {
 			// A ReadUncertaintyIntervalError contains the timestamp of the value
 			// that provoked the conflict. However, we forward the timestamp to the
 			// node's time here. The reason is that the caller (which is always
@@ -899,6 +1315,24 @@ func (r *Range) executeCmd(index uint64, args proto.Request,
 		}
 	}
 
+	// If we are applying a raft command, update the applied index. Its
+	// stats are only accounted for if the command's writes are kept.
+	if index > 0 {
+		indexMS := &ms
+		if err != nil {
+			indexMS = nil
+		}
+		if err := r.putAppliedIndex(batch, indexMS, index); err != nil {
+			if reply.Header().GoError() == nil {
+				reply.Header().SetGoError(err)
+			} else {
+				// The reply header already contains an error which is going to be more useful
+				// to the caller than this one, so just log it.
+				log.Errorf("failed to advance applied index: %s", err)
+			}
+		}
+	}
+
 	// Propagate the request timestamp (which may have changed).
 	reply.Header().Timestamp = args.Header().Timestamp
 
@@ -908,15 +1342,78 @@ func (r *Range) executeCmd(index uint64, args proto.Request,
 	// read/write method. This must be done as part of the execution of
 	// raft commands so that every replica maintains the same responses
 	// to continue request idempotence when leadership changes.
-	if proto.IsWrite(args) {
-		if putErr := r.respCache.PutResponse(args.Header().CmdID, reply); putErr != nil {
-			log.Errorf("unable to write result of %+v: %+v to the response cache: %s",
-				args, reply, putErr)
+	if !proto.IsWrite(args) {
+		return batch, nil, err
+	}
+	if putErr := r.respCache.writeResponse(batch, header.CmdID, reply); putErr != nil {
+		log.Errorf("unable to write result of %+v: %+v to the response cache: %s",
+			args, reply, putErr)
+	}
+	return batch, &ms, err
+}
+
+// appliedIndexBatch returns a batch nested in eng which advances the
+// applied index to index, if it's non-zero, for a raft command rejected
+// before execution, so that we won't retry it on restart.
+func (r *Range) appliedIndexBatch(eng engine.Engine, index uint64) engine.Engine {
+	batch := eng.NewBatch()
+	if index > 0 {
+		if err := r.putAppliedIndex(batch, nil, index); err != nil {
+			log.Errorf("failed to advance applied index: %s", err)
 		}
 	}
+	return batch
+}
 
-	// Return the error (if any) set in the reply.
-	return reply.Header().GoError()
+// cmdCommitted follows the commit of the batch of a write command
+// which succeeded: the range may need to be split, and a put may have
+// modified a configuration map.
+func (r *Range) cmdCommitted(args proto.Request) {
+	// If the commit succeeded, potentially add range to split queue.
+	r.maybeSplit()
+	// Maybe update gossip configs on a put.
+	switch args.(type) {
+	case *proto.PutRequest, *proto.ConditionalPutRequest:
+		if key := args.Header().Key; key.Less(engine.KeySystemMax) {
+			r.maybeUpdateGossipConfigs(key)
+		}
+	}
+}
+
+// putAppliedIndex advances the applied index to index, writing it to
+// batch.
+func (r *Range) putAppliedIndex(batch engine.Engine, ms *proto.MVCCStats, index uint64) error {
+	if oldIndex := atomic.LoadUint64(&r.appliedIndex); oldIndex >= index {
+		log.Fatalf("applied index moved backwards: %d >= %d", oldIndex, index)
+	}
+	atomic.StoreUint64(&r.appliedIndex, index)
+	return engine.MVCCPut(batch, ms, engine.RaftAppliedIndexKey(r.Desc().RaftID),
+		proto.ZeroTimestamp, proto.Value{Bytes: encoding.EncodeUint64(nil, index)}, nil)
+}
+
+// advanceAppliedIndex advances the applied index for a raft command
+// which only publishes a closed timestamp, so we won't retry it on
+// restart. This is a noop if index is zero.
+func (r *Range) advanceAppliedIndex(index uint64, sync bool) {
+	if index == 0 {
+		return
+	}
+	batch := r.rm.Engine().NewBatch()
+	err := r.putAppliedIndex(batch, nil, index)
+	if err == nil {
+		err = commitBatch(batch, sync)
+	}
+	if err != nil {
+		log.Errorf("failed to advance applied index: %s", err)
+	}
+}
+
+// commitBatch commits batch, syncing the engine's log if sync is true.
+func commitBatch(batch engine.Engine, sync bool) error {
+	if sync {
+		return batch.CommitSync()
+	}
+	return batch.Commit()
 }
 
 // Contains verifies the existence of a key in the key value store.
@@ -954,9 +1451,12 @@ func (r *Range) ConditionalPut(batch engine.Engine, ms *proto.MVCCStats, args *p
 
 // Increment increments the value (interpreted as varint64 encoded) and
 // returns the newly incremented value (encoded as varint64). If no value
-// exists for the key, zero is incremented.
+// exists for the key, the request's initial value is incremented. If the
+// new value would fall outside of the request's bounds, the value is
+// left unchanged and an IncrementBoundsError is returned.
 func (r *Range) Increment(batch engine.Engine, ms *proto.MVCCStats, args *proto.IncrementRequest, reply *proto.IncrementResponse) {
-	val, err := engine.MVCCIncrement(batch, ms, args.Key, args.Timestamp, args.Txn, args.Increment)
+	val, err := engine.MVCCIncrementBounded(batch, ms, args.Key, args.Timestamp, args.Txn, args.Increment,
+		args.InitialValue, args.MinValue, args.MaxValue)
 	reply.NewValue = val
 	reply.SetGoError(err)
 }
@@ -976,10 +1476,22 @@ func (r *Range) DeleteRange(batch engine.Engine, ms *proto.MVCCStats, args *prot
 
 // Scan scans the key range specified by start key through end key up
 // to some maximum number of results. The last key of the iteration is
-// returned with the reply.
+// returned with the reply. Consistent scans with the SKIP_INTENTS
+// policy omit the keys holding intents of other transactions and
+// return them in the reply's SkippedIntents.
 func (r *Range) Scan(batch engine.Engine, args *proto.ScanRequest, reply *proto.ScanResponse) {
-	kvs, err := engine.MVCCScan(batch, args.Key, args.EndKey, args.MaxResults, args.Timestamp, args.ReadConsistency == proto.CONSISTENT, args.Txn)
+	var kvs []proto.KeyValue
+	var err error
+	consistent := args.ReadConsistency == proto.CONSISTENT
+	if consistent && args.IntentPolicy == proto.SKIP_INTENTS {
+		kvs, reply.SkippedIntents, err = engine.MVCCScanSkippingIntents(batch, args.Key, args.EndKey, args.MaxResults, args.Timestamp, args.Txn)
+	} else {
+		kvs, err = engine.MVCCScan(batch, args.Key, args.EndKey, args.MaxResults, args.Timestamp, consistent, args.Txn)
+	}
 	reply.Rows = kvs
+	if args.CompressKeys {
+		reply.CompressKeys()
+	}
 	reply.SetGoError(err)
 }
 
@@ -1079,9 +1591,9 @@ func (r *Range) EndTransaction(batch engine.Engine, ms *proto.MVCCStats, args *p
 		// Run appropriate trigger.
 		if reply.Txn.Status == proto.COMMITTED {
 			if ct.SplitTrigger != nil {
-				reply.SetGoError(r.splitTrigger(batch, ct.SplitTrigger))
+				reply.SetGoError(r.splitTrigger(batch, ct.SplitTrigger, reply.Txn.Timestamp))
 			} else if ct.MergeTrigger != nil {
-				reply.SetGoError(r.mergeTrigger(batch, ct.MergeTrigger))
+				reply.SetGoError(r.mergeTrigger(batch, ct.MergeTrigger, reply.Txn.Timestamp))
 			} else if ct.ChangeReplicasTrigger != nil {
 				reply.SetGoError(r.changeReplicasTrigger(ct.ChangeReplicasTrigger))
 			}
@@ -1426,23 +1938,54 @@ func (r *Range) InternalTruncateLog(batch engine.Engine, ms *proto.MVCCStats, ar
 	reply.SetGoError(err)
 }
 
+// InternalIngest writes the rows of an initial load of data to an
+// empty span of the range. On a persistent store they're written to an
+// sstable which is linked into its engine, bypassing the memtable;
+// otherwise they're added to the batch.
+func (r *Range) InternalIngest(batch engine.Engine, ms *proto.MVCCStats, args *proto.InternalIngestRequest, reply *proto.InternalIngestResponse) {
+	var ingester engine.Ingester
+	if e, ok := r.rm.Engine().(engine.Ingester); ok && e.Dir() != "" {
+		ingester = e
+	}
+	reply.SetGoError(engine.MVCCIngest(batch, ingester, ms, args.Key, args.EndKey, args.Timestamp, args.Rows))
+}
+
 // InternalLeaderLease evaluates and responds to a request to grant a leader lease.
 func (r *Range) InternalLeaderLease(args *proto.InternalLeaderLeaseRequest, reply *proto.InternalLeaderLeaseResponse) {
 	// TODO(tschottdorf) stub for now to get tests working.
+	prev := r.getLease()
 	r.setLease(&args.Lease)
+	if args.Lease.RaftNodeID == uint64(r.rm.RaftNodeID()) {
+		r.rm.leaseMetrics().recordLease(prev, &args.Lease, time.Now())
+	}
 	// r.grantLeaderLease(args.Lease)
 }
 
 // requestLeaderLease sends a request to obtain or extend a leader lease for
 // this replica. Being a first mover, it registers itself as a task with the
-// stopper.
+// stopper. No lease is requested while the store is read-only, suspect,
+// corrupt or draining.
 func (r *Range) requestLeaderLease(term uint64) {
+	if r.rm.ReadOnly() || r.rm.IOSuspect() || r.rm.Corrupt() || r.rm.Draining() {
+		return
+	}
+	r.proposeLeaderLease(term, r.rm.RaftNodeID())
+}
+
+// transferLeaderLease sends a request to grant the leader lease
+// currently held by this replica, for the same term, to another
+// replica of the range.
+func (r *Range) transferLeaderLease(lease *proto.Lease, target proto.Replica) {
+	r.proposeLeaderLease(lease.Term, MakeRaftNodeID(target.NodeID, target.StoreID))
+}
+
+// proposeLeaderLease proposes a Raft command granting a leader lease
+// for the term to the replica with the supplied Raft node ID.
+func (r *Range) proposeLeaderLease(term uint64, raftNodeID multiraft.NodeID) {
 	if !r.stopper.StartTask() {
 		return
 	}
 	defer r.stopper.FinishTask()
-	// Prepare a Raft command to get a leader lease for the replica
-	// of that group that lives in our store.
 	wallTime := r.rm.Clock().PhysicalNow()
 	// TODO: get this from configuration, either as a config flag
 	// or, later, dynamically adjusted.
@@ -1460,7 +2003,7 @@ func (r *Range) requestLeaderLease(term uint64) {
 			Expiration: wallTime + duration,
 			Duration:   duration,
 			Term:       term,
-			RaftNodeID: uint64(r.rm.RaftNodeID()),
+			RaftNodeID: uint64(raftNodeID),
 		},
 	}
 
@@ -1474,6 +2017,7 @@ func (r *Range) requestLeaderLease(term uint64) {
 		select {
 		case err := <-errCh:
 			if err != nil {
+				r.rm.leaseMetrics().errors.inc(time.Now())
 				log.Warning(err)
 			}
 		case <-r.stopper.ShouldStop():
@@ -1483,10 +2027,11 @@ func (r *Range) requestLeaderLease(term uint64) {
 }
 
 // splitTrigger is called on a successful commit of an AdminSplit
-// transaction. It copies the response cache for the new range and
-// recomputes stats for both the existing, updated range and the new
-// range.
-func (r *Range) splitTrigger(batch engine.Engine, split *proto.SplitTrigger) error {
+// transaction. It copies the response cache for the new range,
+// records the split, made at the transaction's commit timestamp, in
+// the history of both ranges and recomputes stats for both the
+// existing, updated range and the new range.
+func (r *Range) splitTrigger(batch engine.Engine, split *proto.SplitTrigger, timestamp proto.Timestamp) error {
 	if !bytes.Equal(r.Desc().StartKey, split.UpdatedDesc.StartKey) ||
 		!bytes.Equal(r.Desc().EndKey, split.NewDesc.EndKey) {
 		return util.Errorf("range does not match splits: %s-%s + %s-%s != %s-%s", split.UpdatedDesc.StartKey,
@@ -1511,6 +2056,25 @@ func (r *Range) splitTrigger(batch engine.Engine, split *proto.SplitTrigger) err
 		return util.Errorf("unable to copy last verification timestamp: %s", err)
 	}
 
+	// Record the split in the history of both ranges; the new range
+	// inherits the history of the original.
+	hist, err := r.GetHistory()
+	if err != nil {
+		return util.Errorf("unable to fetch range history: %s", err)
+	}
+	event := proto.RangeHistoryEvent{
+		Type:         proto.SPLIT,
+		Timestamp:    timestamp,
+		ParentRaftID: r.Desc().RaftID,
+		ChildRaftID:  split.NewDesc.RaftID,
+		Key:          split.NewDesc.StartKey,
+	}
+	for _, raftID := range []int64{r.Desc().RaftID, split.NewDesc.RaftID} {
+		if err := putHistory(batch, raftID, hist, event); err != nil {
+			return util.Errorf("unable to record split in range history: %s", err)
+		}
+	}
+
 	// Compute stats for updated range.
 	now := r.rm.Clock().Timestamp()
 	ms, err := engine.MVCCComputeStats(r.rm.Engine(), split.UpdatedDesc.StartKey, split.UpdatedDesc.EndKey, now.WallTime)
@@ -1547,8 +2111,10 @@ func (r *Range) splitTrigger(batch engine.Engine, split *proto.SplitTrigger) err
 }
 
 // mergeTrigger is called on a successful commit of an AdminMerge
-// transaction. It recomputes stats for the receiving range.
-func (r *Range) mergeTrigger(batch engine.Engine, merge *proto.MergeTrigger) error {
+// transaction. It records the merge, made at the transaction's commit
+// timestamp, in the receiving range's history and recomputes its
+// stats.
+func (r *Range) mergeTrigger(batch engine.Engine, merge *proto.MergeTrigger, timestamp proto.Timestamp) error {
 	if !bytes.Equal(r.Desc().StartKey, merge.UpdatedDesc.StartKey) {
 		return util.Errorf("range and updated range start keys do not match: %s != %s",
 			r.Desc().StartKey, merge.UpdatedDesc.StartKey)
@@ -1568,6 +2134,22 @@ func (r *Range) mergeTrigger(batch engine.Engine, merge *proto.MergeTrigger) err
 		return util.Errorf("unable to copy response cache to new split range: %s", err)
 	}
 
+	// Record the merge in the history of the subsuming range.
+	hist, err := r.GetHistory()
+	if err != nil {
+		return util.Errorf("unable to fetch range history: %s", err)
+	}
+	event := proto.RangeHistoryEvent{
+		Type:         proto.MERGE,
+		Timestamp:    timestamp,
+		ParentRaftID: r.Desc().RaftID,
+		ChildRaftID:  merge.SubsumedRaftID,
+		Key:          r.Desc().EndKey,
+	}
+	if err := putHistory(batch, r.Desc().RaftID, hist, event); err != nil {
+		return util.Errorf("unable to record merge in range history: %s", err)
+	}
+
 	// Compute stats for updated range.
 	now := r.rm.Clock().Timestamp()
 	ms, err := engine.MVCCComputeStats(r.rm.Engine(), merge.UpdatedDesc.StartKey,
@@ -1807,10 +2389,12 @@ func (r *Range) Snapshot() (raftpb.Snapshot, error) {
 
 	// Iterate over all the data in the range, including local-only data like
 	// the response cache.
-	for iter := newRangeDataIterator(r, snap); iter.Valid(); iter.Next() {
+	iter := newRangeScanIterator(r, snap)
+	for ; iter.Valid(); iter.Next() {
 		snapData.KV = append(snapData.KV,
 			&proto.RaftSnapshotData_KeyValue{Key: iter.Key(), Value: iter.Value()})
 	}
+	iter.Close()
 
 	data, err := gogoproto.Marshal(&snapData)
 	if err != nil {
@@ -1858,6 +2442,9 @@ func (r *Range) Append(entries []raftpb.Entry) error {
 }
 
 // ApplySnapshot implements the multiraft.WriteableGroupStorage interface.
+// On a persistent store, the snapshot is ingested as an sstable; see
+// ingestSnapshot. Otherwise, it's written in batches; see
+// writeSnapshot.
 func (r *Range) ApplySnapshot(snap raftpb.Snapshot) error {
 	snapData := proto.RaftSnapshotData{}
 	err := gogoproto.Unmarshal(snap.Data, &snapData)
@@ -1865,64 +2452,102 @@ func (r *Range) ApplySnapshot(snap raftpb.Snapshot) error {
 		return nil
 	}
 
-	// First, save the HardState.  The HardState must not be changed
-	// because it may record a previous vote cast by this node.
 	hardStateKey := engine.RaftHardStateKey(r.Desc().RaftID)
-	hardState, err := engine.MVCCGet(r.rm.Engine(), hardStateKey, proto.ZeroTimestamp, true, nil)
-	if err != nil {
-		return nil
-	}
-
-	batch := engine.NewBatch(r.rm.Engine())
-
-	// Delete everything in the range and recreate it from the snapshot.
-	for iter := newRangeDataIterator(r, r.rm.Engine()); iter.Valid(); iter.Next() {
-		if err := batch.Clear(iter.Key()); err != nil {
-			return err
-		}
-	}
-
-	// Write the snapshot into the range.
-	for _, kv := range snapData.KV {
-		if err := batch.Put(kv.Key, kv.Value); err != nil {
-			return err
-		}
-	}
-
-	// Restore the saved HardState.
-	if hardState == nil {
-		err := engine.MVCCDelete(batch, nil, hardStateKey, proto.ZeroTimestamp, nil)
-		if err != nil {
-			return err
-		}
+	if e, ok := r.rm.Engine().(engine.Ingester); ok && e.Dir() != "" {
+		err = r.ingestSnapshot(e, snapData.KV, hardStateKey)
 	} else {
-		err := engine.MVCCPut(batch, nil, hardStateKey, proto.ZeroTimestamp, *hardState, nil)
-		if err != nil {
-			return err
-		}
+		err = r.writeSnapshot(snapData.KV, hardStateKey)
+	}
+	if err != nil {
+		return err
 	}
 
 	// Read the updated range descriptor.
 	var desc proto.RangeDescriptor
-	if _, err := engine.MVCCGetProto(batch, engine.RangeDescriptorKey(r.Desc().StartKey),
+	if _, err := engine.MVCCGetProto(r.rm.Engine(), engine.RangeDescriptorKey(r.Desc().StartKey),
 		r.rm.Clock().Now(), false, nil, &desc); err != nil {
 		return err
 	}
 
-	if err := batch.Commit(); err != nil {
-		return err
-	}
-
 	// Save the descriptor and applied index to our member variables.
 	r.SetDesc(&desc)
 	atomic.StoreUint64(&r.appliedIndex, snap.Metadata.Index)
+	atomic.AddUint64(&r.writes, 1)
 
 	// TODO(bdarnell): extract the real last index.
 	// snap.Metadata.Index is the last applied index, but our snapshot may have given us
 	// some unapplied entries too. It's safe to set lastIndex too low (the entries will
 	// be re-sent), but it would be better to set this to the last entry in the log.
 	atomic.StoreUint64(&r.lastIndex, snap.Metadata.Index)
-	return err
+	return nil
+}
+
+// writeSnapshot replaces the range's data with the snapshot's key
+// values through a BatchWriter, so that neither the deletions of the
+// range's keys nor a large snapshot are held in a single batch. The
+// HardState at hardStateKey, which may record a previous vote cast by
+// this node, is left as is. Unlike ingestSnapshot, the snapshot isn't
+// applied atomically; it's only used by engines without a directory,
+// whose data doesn't survive a crash, and stale reads served by the
+// replica while it's applied may see part of it.
+func (r *Range) writeSnapshot(kvs []proto.RawKeyValue, hardStateKey proto.Key) error {
+	hardStateEncKey := engine.MVCCEncodeKey(hardStateKey)
+	bw := engine.NewBatchWriter(r.rm.Engine(), 0, nil)
+
+	// Delete everything in the range and recreate it from the snapshot.
+	iter := newRangeDataIterator(r, r.rm.Engine())
+	for ; iter.Valid(); iter.Next() {
+		if key := iter.Key(); !key.Equal(hardStateEncKey) {
+			if err := bw.Clear(key); err != nil {
+				iter.Close()
+				return err
+			}
+		}
+	}
+	err := iter.Error()
+	iter.Close()
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		if !kv.Key.Equal(hardStateEncKey) {
+			if err := bw.Put(kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// ingestSnapshot replaces the range's data with the snapshot's key
+// values, which are sorted as snapshots are generated, by ingesting
+// an sstable which holds them and deletions of the range's other keys.
+// Ingestion bypasses the memtable and write-ahead log, so applying
+// the snapshot doesn't compete with foreground writes for them, and
+// links the sstable in atomically, so no partially applied snapshot
+// is left behind by a crash. The HardState at hardStateKey, which may
+// record a previous vote cast by this node, is left as is.
+func (r *Range) ingestSnapshot(ingester engine.Ingester, kvs []proto.RawKeyValue, hardStateKey proto.Key) error {
+	hardStateEncKey := engine.MVCCEncodeKey(hardStateKey)
+	var clear []proto.EncodedKey
+	iter := newRangeDataIterator(r, r.rm.Engine())
+	for ; iter.Valid(); iter.Next() {
+		if key := iter.Key(); !key.Equal(hardStateEncKey) {
+			clear = append(clear, append(proto.EncodedKey(nil), key...))
+		}
+	}
+	err := iter.Error()
+	iter.Close()
+	if err != nil {
+		return err
+	}
+	snapKVs := make([]proto.RawKeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		if !kv.Key.Equal(hardStateEncKey) {
+			snapKVs = append(snapKVs, kv)
+		}
+	}
+	return engine.IngestKeyValues(ingester, snapKVs, clear)
 }
 
 // SetHardState implements the multiraft.WriteableGroupStorage interface.
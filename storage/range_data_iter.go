@@ -42,6 +42,24 @@ type rangeDataIterator struct {
 }
 
 func newRangeDataIterator(r *Range, e engine.Engine) *rangeDataIterator {
+	return newRangeDataIteratorFrom(r, e.NewIterator())
+}
+
+// newRangeScanIterator returns a rangeDataIterator for a scan of all
+// of the range's data, such as those of the range scanner's queues and
+// of snapshot generation, which reads ahead if the range's store is
+// configured to prefetch.
+func newRangeScanIterator(r *Range, e engine.Engine) *rangeDataIterator {
+	iter := e.NewIterator()
+	if size := r.rm.scanPrefetchSize(); size > 0 {
+		iter = engine.NewPrefetchIterator(iter, size)
+	}
+	return newRangeDataIteratorFrom(r, iter)
+}
+
+// newRangeDataIteratorFrom returns a rangeDataIterator over the range's
+// data read by iter, which it closes.
+func newRangeDataIteratorFrom(r *Range, iter engine.Iterator) *rangeDataIterator {
 	r.RLock()
 	startKey := r.Desc().StartKey
 	endKey := r.Desc().EndKey
@@ -68,7 +86,7 @@ func newRangeDataIterator(r *Range, e engine.Engine) *rangeDataIterator {
 				end:   engine.MVCCEncodeKey(endKey),
 			},
 		},
-		iter: e.NewIterator(),
+		iter: iter,
 	}
 	ri.iter.Seek(ri.ranges[ri.curIndex].start)
 	ri.advance()
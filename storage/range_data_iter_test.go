@@ -227,3 +227,44 @@ func disabledTestRangeDataIterator(t *testing.T) {
 		}
 	}
 }
+
+// TestRangeScanIterator verifies that scans of a range on a spinning
+// disk read ahead and return the same data as other iterations.
+func TestRangeScanIterator(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{
+		engine:      engine.NewInMem(proto.Attributes{Attrs: []string{"dc1", "hdd"}}, 1<<20),
+		dormantRaft: true, // elections would write hard state to engine
+	}
+	tc.Start(t)
+	defer tc.Stop()
+	if size := tc.store.scanPrefetchSize(); size != 0 {
+		t.Errorf("expected no prefetching by default; got %d bytes", size)
+	}
+	tc.store.ctx.ScanPrefetchSize = 1 << 10
+	if size := tc.store.scanPrefetchSize(); size != 1<<10 {
+		t.Errorf("expected prefetching of %d bytes on an hdd store; got %d", 1<<10, size)
+	}
+
+	var expKeys []proto.EncodedKey
+	iter := newRangeDataIterator(tc.rng, tc.rng.rm.Engine())
+	for ; iter.Valid(); iter.Next() {
+		expKeys = append(expKeys, iter.Key())
+	}
+	iter.Close()
+	if len(expKeys) == 0 {
+		t.Fatal("expected range data")
+	}
+	iter = newRangeScanIterator(tc.rng, tc.rng.rm.Engine())
+	defer iter.Close()
+	i := 0
+	for ; iter.Valid(); iter.Next() {
+		if i < len(expKeys) && !iter.Key().Equal(expKeys[i]) {
+			t.Errorf("%d: expected key %q; got %q", i, expKeys[i], iter.Key())
+		}
+		i++
+	}
+	if i != len(expKeys) {
+		t.Errorf("expected %d keys; got %d", len(expKeys), i)
+	}
+}
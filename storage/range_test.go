@@ -268,11 +268,13 @@ func TestRangeCanService(t *testing.T) {
 	gArgs, gReply := getArgs(proto.Key("a"), 1, tc.store.StoreID())
 	gArgs.Timestamp = tc.clock.Now()
 
-	// Try a consensus read and verify error.
+	// Try a bounded-staleness consensus read and verify error.
 	gArgs.ReadConsistency = proto.CONSENSUS
+	gArgs.MaxStaleness = time.Second.Nanoseconds()
 	if err := tc.rng.AddCmd(gArgs, gReply, true); err == nil {
-		t.Errorf("expected error on consensus read")
+		t.Errorf("expected error on bounded-staleness consensus read")
 	}
+	gArgs.MaxStaleness = 0
 
 	// Try an inconsistent read within a transaction.
 	gArgs.ReadConsistency = proto.INCONSISTENT
@@ -293,6 +295,63 @@ func TestRangeCanService(t *testing.T) {
 	}
 }
 
+// TestRangeReadPaths verifies that consistent reads are served by the
+// leader without going through Raft, that CONSENSUS reads are proposed
+// to Raft, and that other reads applied through Raft are counted.
+func TestRangeReadPaths(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	pArgs, pReply := putArgs([]byte("a"), []byte("1"), 1, tc.store.StoreID())
+	if err := tc.rng.AddCmd(pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expMetrics := func(local, consensus, unexpected int64) {
+		m := tc.store.Metrics()
+		if m.LocalReads != local || m.ConsensusReads != consensus || m.UnexpectedRaftReads != unexpected {
+			t.Errorf("expected %d local, %d consensus and %d unexpected raft reads; got %d, %d and %d",
+				local, consensus, unexpected, m.LocalReads, m.ConsensusReads, m.UnexpectedRaftReads)
+		}
+	}
+	m := tc.store.Metrics()
+	local, consensus := m.LocalReads, m.ConsensusReads
+	expMetrics(local, consensus, 0)
+
+	for i, rc := range []proto.ReadConsistencyType{proto.CONSISTENT, proto.CONSENSUS} {
+		gArgs, gReply := getArgs([]byte("a"), 1, tc.store.StoreID())
+		gArgs.Timestamp = tc.clock.Now()
+		gArgs.ReadConsistency = rc
+		if err := tc.rng.AddCmd(gArgs, gReply, true); err != nil {
+			t.Fatalf("%s: %s", rc, err)
+		}
+		if gReply.Value == nil || !bytes.Equal(gReply.Value.Bytes, []byte("1")) {
+			t.Errorf("%s: expected value 1; got %+v", rc, gReply.Value)
+		}
+		if i == 0 {
+			local++
+		} else {
+			consensus++
+		}
+		expMetrics(local, consensus, 0)
+	}
+
+	// A consistent read which finds its way into Raft is counted.
+	gArgs, gReply := getArgs([]byte("a"), 1, tc.store.StoreID())
+	gArgs.Timestamp = tc.clock.Now()
+	cmd, raftChan := tc.rng.proposeCmd(gArgs, gReply)
+	err := <-raftChan
+	if err == nil {
+		err = <-cmd.done
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	expMetrics(local, consensus, 1)
+}
+
 // TestRangeGossipFirstRange verifies that the first range gossips its
 // location and the cluster ID.
 func TestRangeGossipFirstRange(t *testing.T) {
@@ -376,7 +435,7 @@ func TestRangeGossipConfigWithMultipleKeyPrefixes(t *testing.T) {
 	}
 	reply := &proto.PutResponse{}
 
-	if err := tc.rng.executeCmd(0, req, reply); err != nil {
+	if err := tc.rng.executeCmd(0, false, req, reply); err != nil {
 		t.Fatal(err)
 	}
 
@@ -418,7 +477,7 @@ func TestRangeGossipConfigUpdates(t *testing.T) {
 	}
 	reply := &proto.PutResponse{}
 
-	if err := tc.rng.executeCmd(0, req, reply); err != nil {
+	if err := tc.rng.executeCmd(0, false, req, reply); err != nil {
 		t.Fatal(err)
 	}
 
@@ -437,6 +496,46 @@ func TestRangeGossipConfigUpdates(t *testing.T) {
 	}
 }
 
+// TestRangeGossipBalanceConfig verifies that a write to the balance
+// thresholds gossips them and that the store's allocator applies them.
+func TestRangeGossipBalanceConfig(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	if thresholds := tc.store.allocator.balanceThresholds(); thresholds != defaultBalanceThresholds {
+		t.Errorf("expected default thresholds %+v; got %+v", defaultBalanceThresholds, thresholds)
+	}
+
+	config := &proto.BalanceConfig{RangeCountThreshold: 0.2, BytesThreshold: 0.3}
+	data, err := gogoproto.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &proto.PutRequest{
+		RequestHeader: proto.RequestHeader{Key: engine.KeyConfigBalance, Timestamp: proto.MinTimestamp},
+		Value:         proto.Value{Bytes: data},
+	}
+	if err := tc.rng.executeCmd(0, false, req, &proto.PutResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := tc.gossip.GetInfo(gossip.KeyConfigBalance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(info, config) {
+		t.Errorf("expected gossiped balance config %s; got %v", config, info)
+	}
+	expThresholds := BalanceThresholds{RangeCount: 0.2, Bytes: 0.3}
+	util.SucceedsWithin(t, time.Second, func() error {
+		if thresholds := tc.store.allocator.balanceThresholds(); thresholds != expThresholds {
+			return util.Errorf("expected thresholds %+v; got %+v", expThresholds, thresholds)
+		}
+		return nil
+	})
+}
+
 // getArgs returns a GetRequest and GetResponse pair addressed to
 // the default replica for the specified key.
 func getArgs(key []byte, raftID int64, storeID proto.StoreID) (*proto.GetRequest, *proto.GetResponse) {
@@ -674,6 +773,238 @@ func TestRangeUpdateTSCache(t *testing.T) {
 	}
 }
 
+// TestRangeClosedTimestamp verifies that commands close a timestamp
+// trailing the leader's clock, that writes are moved above it, and
+// that bounded-staleness reads are served at a closed timestamp only
+// within their maximum staleness.
+func TestRangeClosedTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	tc.store.ctx.ClosedTimestampLag = time.Second
+	tc.manualClock.Set((10 * time.Second).Nanoseconds())
+	closed := proto.Timestamp{WallTime: (9 * time.Second).Nanoseconds()}
+
+	// A write below the closed timestamp is moved above it.
+	pArgs, pReply := putArgs([]byte("a"), []byte("1"), 1, tc.store.StoreID())
+	pArgs.Timestamp = makeTS((5 * time.Second).Nanoseconds(), 0)
+	if err := tc.rng.AddCmd(pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if !closed.Less(pReply.Timestamp) {
+		t.Errorf("expected write to be moved above %s; got %s", closed, pReply.Timestamp)
+	}
+	if ts := tc.rng.ClosedTimestamp(); !ts.Equal(closed) {
+		t.Errorf("expected closed timestamp %s; got %s", closed, ts)
+	}
+
+	// The closed timestamp doesn't regress if the clock does.
+	tc.manualClock.Set((5 * time.Second).Nanoseconds())
+	pArgs, pReply = putArgs([]byte("b"), []byte("1"), 1, tc.store.StoreID())
+	pArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(pArgs, pReply, true); err != nil {
+		t.Fatal(err)
+	}
+	if ts := tc.rng.ClosedTimestamp(); !ts.Equal(closed) {
+		t.Errorf("expected closed timestamp %s; got %s", closed, ts)
+	}
+
+	testCases := []struct {
+		ts, maxStaleness time.Duration
+		expTS            proto.Timestamp
+		expErr           bool
+	}{
+		{8 * time.Second, time.Second, makeTS((8 * time.Second).Nanoseconds(), 0), false},
+		{12 * time.Second, 5 * time.Second, closed, false},
+		{12 * time.Second, time.Second, proto.ZeroTimestamp, true},
+	}
+	for i, test := range testCases {
+		header := &proto.RequestHeader{
+			Timestamp:    makeTS(test.ts.Nanoseconds(), 0),
+			MaxStaleness: test.maxStaleness.Nanoseconds(),
+		}
+		ts, err := tc.rng.staleReadTimestamp(header)
+		if _, ok := err.(*proto.NotLeaderError); ok != test.expErr {
+			t.Errorf("%d: expected error %t; got %v", i, test.expErr, err)
+		}
+		if !ts.Equal(test.expTS) {
+			t.Errorf("%d: expected timestamp %s; got %s", i, test.expTS, ts)
+		}
+	}
+}
+
+// TestRangePublishClosedTimestamp verifies that the leader of a range
+// without writes periodically publishes its closed timestamp.
+func TestRangePublishClosedTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	tc.store.ctx.ClosedTimestampLag = 2 * time.Second
+
+	expClosed := func(wallTime time.Duration) {
+		closed := makeTS(wallTime.Nanoseconds(), 0)
+		util.SucceedsWithin(t, time.Second, func() error {
+			if ts := tc.rng.ClosedTimestamp(); !ts.Equal(closed) {
+				return util.Errorf("expected closed timestamp %s; got %s", closed, ts)
+			}
+			return nil
+		})
+	}
+	tc.manualClock.Set((10 * time.Second).Nanoseconds())
+	if err := util.IsTrueWithin(func() bool { return tc.rng.getLease() != nil }, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	tc.rng.maybePublishClosedTimestamp()
+	expClosed(8 * time.Second)
+
+	// The closed timestamp is recent enough to not be published again
+	// until it trails the clock by half the lag more than the target.
+	tc.manualClock.Set((10*time.Second + 500*time.Millisecond).Nanoseconds())
+	tc.rng.maybePublishClosedTimestamp()
+	tc.rng.proposeMu.Lock()
+	if ts := tc.rng.proposedClosedTS; ts.WallTime != (8 * time.Second).Nanoseconds() {
+		t.Errorf("expected no closed timestamp to be proposed; got %s", ts)
+	}
+	tc.rng.proposeMu.Unlock()
+	tc.manualClock.Set((11*time.Second + 500*time.Millisecond).Nanoseconds())
+	tc.rng.maybePublishClosedTimestamp()
+	expClosed(9*time.Second + 500*time.Millisecond)
+}
+
+// TestRangeCommittedWrites verifies that every version of each key
+// written up to a closed timestamp is returned, oldest first and
+// deletions included, and that writes beyond the closed timestamp
+// can't be requested.
+func TestRangeCommittedWrites(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	tc.store.ctx.ClosedTimestampLag = time.Second
+	tc.manualClock.Set((10 * time.Second).Nanoseconds())
+
+	for _, kv := range []struct{ key, value string }{{"a", "1"}, {"a", "2"}, {"b", "1"}} {
+		pArgs, pReply := putArgs([]byte(kv.key), []byte(kv.value), 1, tc.store.StoreID())
+		pArgs.Timestamp = tc.clock.Now()
+		if err := tc.rng.AddCmd(pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dArgs, dReply := deleteArgs(proto.Key("b"), 1, tc.store.StoreID())
+	dArgs.Timestamp = tc.clock.Now()
+	if err := tc.rng.AddCmd(dArgs, dReply, true); err != nil {
+		t.Fatal(err)
+	}
+
+	tc.manualClock.Set((20 * time.Second).Nanoseconds())
+	tc.rng.maybePublishClosedTimestamp()
+	closed := makeTS((19 * time.Second).Nanoseconds(), 0)
+	util.SucceedsWithin(t, time.Second, func() error {
+		if ts := tc.rng.ClosedTimestamp(); !ts.Equal(closed) {
+			return util.Errorf("expected closed timestamp %s; got %s", closed, ts)
+		}
+		return nil
+	})
+
+	versions, err := tc.rng.CommittedWrites(proto.Key("a"), proto.Key("c"), proto.ZeroTimestamp, closed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		key, value string
+		deleted    bool
+	}{{"a", "1", false}, {"a", "2", false}, {"b", "1", false}, {"b", "", true}}
+	if len(versions) != len(expected) {
+		t.Fatalf("expected %d versions; got %+v", len(expected), versions)
+	}
+	for i, exp := range expected {
+		v := versions[i]
+		if !v.Key.Equal(proto.Key(exp.key)) || v.Value.Deleted != exp.deleted ||
+			(!exp.deleted && string(v.Value.Value.Bytes) != exp.value) {
+			t.Errorf("%d: expected %+v; got %+v", i, exp, v)
+		}
+		if i > 0 && v.Key.Equal(versions[i-1].Key) && !versions[i-1].Timestamp.Less(v.Timestamp) {
+			t.Errorf("%d: expected versions of %s oldest first; got %+v", i, v.Key, versions)
+		}
+	}
+	if versions, err := tc.rng.CommittedWrites(proto.Key("a"), proto.Key("c"), closed, closed); err != nil || len(versions) != 0 {
+		t.Errorf("expected no versions since the closed timestamp; got %+v, %v", versions, err)
+	}
+	if _, err := tc.rng.CommittedWrites(proto.Key("a"), proto.Key("c"), proto.ZeroTimestamp, closed.Next()); err == nil {
+		t.Error("expected error requesting writes beyond the closed timestamp")
+	}
+}
+
+// TestRangeApplySnapshotIngested verifies that a snapshot applied on a
+// persistent store, where it's ingested, replaces the range's data.
+func TestRangeApplySnapshotIngested(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	dir := util.CreateTempDir(t, "_snapshot_test")
+	defer util.CleanupDir(dir)
+	rocksdb := engine.NewRocksDB(proto.Attributes{}, dir, 1<<20)
+	if err := rocksdb.Open(); err != nil {
+		t.Fatal(err)
+	}
+	tc := testContext{engine: rocksdb}
+	tc.Start(t)
+	defer tc.Stop()
+	tc.stopper.AddCloser(rocksdb)
+	verifyApplySnapshot(t, &tc)
+}
+
+// TestRangeApplySnapshotWritten verifies that a snapshot applied on an
+// in-memory store, where it's written in batches, replaces the range's
+// data.
+func TestRangeApplySnapshotWritten(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+	verifyApplySnapshot(t, &tc)
+}
+
+// verifyApplySnapshot verifies that a snapshot applied to the range of
+// tc replaces its data, including the keys written since the snapshot
+// was taken, and leaves the HardState as it is.
+func verifyApplySnapshot(t *testing.T, tc *testContext) {
+
+	put := func(key string) {
+		pArgs, pReply := putArgs([]byte(key), []byte(key), 1, tc.store.StoreID())
+		pArgs.Timestamp = tc.clock.Now()
+		if err := tc.rng.AddCmd(pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a")
+	snap, err := tc.rng.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	put("b")
+	hardState, _, err := tc.rng.InitialState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tc.rng.ApplySnapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]bool{"a": true, "b": false} {
+		v, err := engine.MVCCGet(tc.engine, proto.Key(key), tc.clock.Now(), true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (v != nil) != expected {
+			t.Errorf("%s: expected value %t; got %+v", key, expected, v)
+		}
+	}
+	if hs, _, err := tc.rng.InitialState(); err != nil || !reflect.DeepEqual(hs, hardState) {
+		t.Errorf("expected HardState %+v to be left as is; got %+v, %v", hardState, hs, err)
+	}
+}
+
 // TestRangeCommandQueue verifies that reads/writes must wait for
 // pending commands to complete through Raft before being executed on
 // range.
@@ -1783,7 +2114,7 @@ func TestConditionFailedError(t *testing.T) {
 	key := []byte("k")
 	value := []byte("quack")
 	pArgs, pReply := putArgs(key, value, 1, tc.store.StoreID())
-	if err := tc.rng.executeCmd(0, pArgs, pReply); err != nil {
+	if err := tc.rng.executeCmd(0, false, pArgs, pReply); err != nil {
 		t.Fatal(err)
 	}
 	args := &proto.ConditionalPutRequest{
@@ -1801,7 +2132,7 @@ func TestConditionFailedError(t *testing.T) {
 		},
 	}
 	reply := &proto.ConditionalPutResponse{}
-	err := tc.rng.executeCmd(0, args, reply)
+	err := tc.rng.executeCmd(0, false, args, reply)
 	if cErr, ok := err.(*proto.ConditionFailedError); err == nil || !ok {
 		t.Fatalf("expected ConditionFailedError, got %T with content %+v",
 			err, err)
@@ -0,0 +1,172 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// A tokenBucket limits the rate at which commands are admitted to a
+// sustained number per second, while allowing bursts of up to one
+// second's worth. A bucket with a zero rate admits every command
+// immediately.
+//
+// Commands are charged according to their priority class. System
+// commands are free. High priority commands spend a token but never
+// wait, so they are admitted ahead of any waiting commands of lower
+// classes. Low priority commands spend two tokens, so that they're
+// admitted at half the rate of normal commands.
+type tokenBucket struct {
+	sync.Mutex
+	rate   int64     // Commands per second; zero if unlimited
+	tokens float64   // Commands which may be admitted without waiting
+	last   time.Time // Time at which tokens was last replenished
+}
+
+// setRate changes the rate of the bucket. Tokens accumulated under
+// the former rate are retained, up to the new burst size.
+func (tb *tokenBucket) setRate(rate int64) {
+	tb.Lock()
+	defer tb.Unlock()
+	if rate == tb.rate {
+		return
+	}
+	if tb.rate == 0 || tb.tokens > float64(rate) {
+		tb.tokens = float64(rate)
+	}
+	tb.rate = rate
+}
+
+// reserve spends the tokens for a command of the specified priority
+// class arriving at now and returns how long the caller must wait
+// before executing it. Tokens are spent even if the caller must wait,
+// so that waiting commands are admitted in order at the bucket's rate.
+func (tb *tokenBucket) reserve(now time.Time, priority proto.PriorityClass) time.Duration {
+	tb.Lock()
+	defer tb.Unlock()
+	if tb.rate == 0 || priority == proto.SYSTEM_PRIORITY {
+		return 0
+	}
+	if elapsed := now.Sub(tb.last); elapsed > 0 {
+		tb.tokens += elapsed.Seconds() * float64(tb.rate)
+		if tb.tokens > float64(tb.rate) {
+			tb.tokens = float64(tb.rate)
+		}
+		tb.last = now
+	}
+	switch priority {
+	case proto.HIGH_PRIORITY:
+		tb.tokens--
+		return 0
+	case proto.LOW_PRIORITY:
+		tb.tokens -= 2
+	default:
+		tb.tokens--
+	}
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / float64(tb.rate) * float64(time.Second))
+}
+
+// rateLimits holds the token buckets of a zone, which admit the read
+// and write commands of clients at the rates of the zone's config.
+// Internal commands, such as intent resolution and lease requests, and
+// transaction commits are never throttled.
+type rateLimits struct {
+	reads, writes tokenBucket
+}
+
+// setRates sets the rates of the buckets to those of the zone config.
+func (rl *rateLimits) setRates(zone *proto.ZoneConfig) {
+	rl.reads.setRate(zone.ReadsPerSecond)
+	rl.writes.setRate(zone.WritesPerSecond)
+}
+
+// bucket returns the token bucket which admits args, or nil if args
+// is not rate limited.
+func (rl *rateLimits) bucket(args proto.Request) *tokenBucket {
+	if rl == nil {
+		return nil
+	}
+	switch args.(type) {
+	case *proto.ContainsRequest, *proto.GetRequest, *proto.ScanRequest:
+		return &rl.reads
+	case *proto.PutRequest, *proto.ConditionalPutRequest, *proto.IncrementRequest,
+		*proto.DeleteRequest, *proto.DeleteRangeRequest:
+		return &rl.writes
+	}
+	return nil
+}
+
+// zoneRateLimits holds the rate limits of a store's zones, keyed by the
+// prefixes of their zone configs. The ranges of a zone share its token
+// buckets, so that the store admits commands to the zone at its rates
+// however many ranges the zone is split into.
+type zoneRateLimits struct {
+	sync.Mutex
+	byPrefix map[string]*rateLimits
+}
+
+// canonicalPrefix returns the prefix of the zone config which applies
+// to the keys of the prefix config.
+func canonicalPrefix(pc *PrefixConfig) proto.Key {
+	if pc.Canonical != nil {
+		return pc.Canonical
+	}
+	return pc.Prefix
+}
+
+// update sets the rates of the zones of zoneMap. The tokens of zones
+// which remain are kept; the limits of removed zones are dropped.
+func (z *zoneRateLimits) update(zoneMap PrefixConfigMap) {
+	z.Lock()
+	defer z.Unlock()
+	byPrefix := map[string]*rateLimits{}
+	for _, pc := range zoneMap {
+		if pc.Canonical != nil {
+			continue
+		}
+		rl, ok := z.byPrefix[string(pc.Prefix)]
+		if !ok {
+			rl = &rateLimits{}
+		}
+		rl.setRates(pc.Config.(*proto.ZoneConfig))
+		byPrefix[string(pc.Prefix)] = rl
+	}
+	z.byPrefix = byPrefix
+}
+
+// get returns the rate limits of the zone of the prefix config, set to
+// the zone's rates.
+func (z *zoneRateLimits) get(pc *PrefixConfig) *rateLimits {
+	z.Lock()
+	defer z.Unlock()
+	if z.byPrefix == nil {
+		z.byPrefix = map[string]*rateLimits{}
+	}
+	prefix := string(canonicalPrefix(pc))
+	rl, ok := z.byPrefix[prefix]
+	if !ok {
+		rl = &rateLimits{}
+		z.byPrefix[prefix] = rl
+	}
+	rl.setRates(pc.Config.(*proto.ZoneConfig))
+	return rl
+}
@@ -0,0 +1,190 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestTokenBucket verifies that a token bucket admits a burst of one
+// second's worth of commands and then paces commands at its rate.
+func TestTokenBucket(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tb := &tokenBucket{}
+	start := time.Unix(100, 0)
+	if wait := tb.reserve(start, proto.NORMAL_PRIORITY); wait != 0 {
+		t.Errorf("expected unlimited bucket to admit command; got wait %s", wait)
+	}
+
+	tb.setRate(10)
+	for i := 0; i < 10; i++ {
+		if wait := tb.reserve(start, proto.NORMAL_PRIORITY); wait != 0 {
+			t.Fatalf("%d: expected command within burst to be admitted; got wait %s", i, wait)
+		}
+	}
+	testCases := []struct {
+		now  time.Time
+		wait time.Duration
+	}{
+		{start, 100 * time.Millisecond},
+		{start, 200 * time.Millisecond},
+		// Waiting commands spend tokens as they're replenished.
+		{start.Add(200 * time.Millisecond), 100 * time.Millisecond},
+		{start.Add(time.Second), 0},
+		// Tokens accumulate up to one second's worth.
+		{start.Add(time.Hour), 0},
+	}
+	for i, test := range testCases {
+		if wait := tb.reserve(test.now, proto.NORMAL_PRIORITY); wait != test.wait {
+			t.Errorf("%d: expected wait %s; got %s", i, test.wait, wait)
+		}
+	}
+	if tb.tokens != 9 {
+		t.Errorf("expected 9 tokens; got %f", tb.tokens)
+	}
+
+	// Lowering the rate caps the accumulated tokens.
+	tb.setRate(2)
+	if tb.tokens != 2 {
+		t.Errorf("expected 2 tokens; got %f", tb.tokens)
+	}
+}
+
+// TestTokenBucketPriority verifies that commands are charged according
+// to their priority class.
+func TestTokenBucketPriority(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tb := &tokenBucket{}
+	tb.setRate(2)
+	now := time.Unix(100, 0)
+	testCases := []struct {
+		priority proto.PriorityClass
+		wait     time.Duration
+		tokens   float64
+	}{
+		{proto.SYSTEM_PRIORITY, 0, 2},
+		{proto.LOW_PRIORITY, 0, 0},
+		{proto.HIGH_PRIORITY, 0, -1},
+		{proto.NORMAL_PRIORITY, time.Second, -2},
+		{proto.LOW_PRIORITY, 2 * time.Second, -4},
+		{proto.SYSTEM_PRIORITY, 0, -4},
+	}
+	for i, test := range testCases {
+		if wait := tb.reserve(now, test.priority); wait != test.wait || tb.tokens != test.tokens {
+			t.Errorf("%d: expected wait %s with %f tokens; got %s with %f", i, test.wait, test.tokens, wait, tb.tokens)
+		}
+	}
+}
+
+// TestZoneRateLimits verifies that the rate limits of a zone are
+// shared by all the keys of the zone, keyed by the prefix of its
+// config, and that updating the zone configs keeps the limits of the
+// remaining zones.
+func TestZoneRateLimits(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	zoneMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{WritesPerSecond: 10}},
+		{proto.Key("db1"), nil, &proto.ZoneConfig{WritesPerSecond: 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := &zoneRateLimits{}
+	z.update(zoneMap)
+	// The keys after db1 are in the default zone.
+	def := z.get(zoneMap.MatchByPrefix(engine.KeyMin))
+	if rl := z.get(zoneMap.MatchByPrefix(proto.Key("db2"))); rl != def {
+		t.Errorf("expected keys of the default zone to share its limits")
+	}
+	db1 := z.get(zoneMap.MatchByPrefix(proto.Key("db1/a")))
+	if db1 == def || db1.writes.rate != 20 {
+		t.Errorf("expected db1 to have its own limits of 20 writes per second; got %d", db1.writes.rate)
+	}
+
+	zoneMap, err = NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{WritesPerSecond: 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.update(zoneMap)
+	if rl := z.get(zoneMap.MatchByPrefix(engine.KeyMin)); rl != def || rl.writes.rate != 5 {
+		t.Errorf("expected the default zone to keep its limits at the new rate; got %d", rl.writes.rate)
+	}
+	if _, ok := z.byPrefix["db1"]; ok || len(z.byPrefix) != 1 {
+		t.Errorf("expected the limits of the removed zone to be dropped; got %v", z.byPrefix)
+	}
+}
+
+// TestRangeRateLimits verifies that the rate limits of a zone config
+// are applied to its ranges and that writes beyond the limit are
+// delayed while reads and internal commands are not.
+func TestRangeRateLimits(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	zoneMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{WritesPerSecond: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.gossip.AddInfo(gossip.KeyConfigZone, zoneMap, 0*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	util.SucceedsWithin(t, time.Second, func() error {
+		rl := tc.rng.getRateLimits()
+		if rl == nil {
+			return util.Errorf("expected rate limits to be set")
+		}
+		rl.writes.Lock()
+		defer rl.writes.Unlock()
+		if rate := rl.writes.rate; rate != 10 {
+			return util.Errorf("expected write rate 10; got %d", rate)
+		}
+		return nil
+	})
+
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		pArgs, pReply := putArgs([]byte(fmt.Sprintf("a%d", i)), []byte("value"), 1, tc.store.StoreID())
+		pArgs.Timestamp = tc.clock.Now()
+		if err := tc.rng.AddCmd(pArgs, pReply, true); err != nil {
+			t.Fatal(err)
+		}
+		gArgs, gReply := getArgs([]byte("a0"), 1, tc.store.StoreID())
+		gArgs.Timestamp = tc.clock.Now()
+		if err := tc.rng.AddCmd(gArgs, gReply, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m := tc.store.Metrics(); m.ThrottledCmds != 1 || m.ThrottledCmdsPerMinute != 1 {
+		t.Errorf("expected 1 throttled command; got %d (%d per minute)", m.ThrottledCmds, m.ThrottledCmdsPerMinute)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected throttled write to be delayed; took %s", elapsed)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// A readCacheEntry is the value read by a Get from a range replica.
+type readCacheEntry struct {
+	rng    *Range          // The replica which served the read
+	gen    uint64          // The write generation of the replica when read
+	readAt proto.Timestamp // The timestamp of the read
+	value  *proto.Value    // Nil if the key had no value
+}
+
+// A readCache caches the values of keys read by consistent,
+// non-transactional Gets served by range leaders of a store, sparing
+// read-hot keys the cost of an MVCC read. Only reads of a key's most
+// recent version are cached. An entry answers Gets at or above the
+// timestamp at which it was read, until a command is applied to the
+// range, which invalidates every entry of the range. The least
+// recently used entries are evicted. It's safe for concurrent use; a
+// nil readCache caches nothing.
+type readCache struct {
+	sync.Mutex
+	cache        *util.UnorderedCache
+	hits, misses rateCounter
+}
+
+// newReadCache returns a read cache of at most size entries, or nil
+// if size isn't positive.
+func newReadCache(size int) *readCache {
+	if size <= 0 {
+		return nil
+	}
+	return &readCache{
+		cache: util.NewUnorderedCache(util.CacheConfig{
+			Policy: util.CacheLRU,
+			ShouldEvict: func(n int, k, v interface{}) bool {
+				return n > size
+			},
+		}),
+	}
+}
+
+// get returns the value of key cached for a read by r at timestamp,
+// if any, counting the lookup as a hit or a miss.
+func (rc *readCache) get(r *Range, key proto.Key, timestamp proto.Timestamp) (*proto.Value, bool) {
+	if rc == nil {
+		return nil, false
+	}
+	rc.Lock()
+	v, ok := rc.cache.Get(string(key))
+	rc.Unlock()
+	if e, _ := v.(*readCacheEntry); ok && e.rng == r && e.gen == r.writeGen() && !timestamp.Less(e.readAt) {
+		rc.hits.inc(time.Now())
+		return cloneValue(e.value), true
+	}
+	rc.misses.inc(time.Now())
+	return nil, false
+}
+
+// add caches the value of key read by r at timestamp, when the
+// replica's write generation was gen.
+func (rc *readCache) add(r *Range, gen uint64, key proto.Key, timestamp proto.Timestamp, value *proto.Value) {
+	if rc == nil || gen != r.writeGen() {
+		return
+	}
+	rc.Lock()
+	defer rc.Unlock()
+	rc.cache.Add(string(key), &readCacheEntry{rng: r, gen: gen, readAt: timestamp, value: cloneValue(value)})
+}
+
+// metrics returns the numbers of hits and misses of the cache and the
+// fraction of lookups within rateWindow of now which hit.
+func (rc *readCache) metrics(now time.Time) (hits, misses int64, hitRate float64) {
+	if rc == nil {
+		return 0, 0, 0
+	}
+	if recentHits, recentLookups := rc.hits.Rate(now), rc.hits.Rate(now)+rc.misses.Rate(now); recentLookups > 0 {
+		hitRate = float64(recentHits) / float64(recentLookups)
+	}
+	return rc.hits.Total(), rc.misses.Total(), hitRate
+}
+
+// cloneValue returns a copy of v, which may be nil.
+func cloneValue(v *proto.Value) *proto.Value {
+	if v == nil {
+		return nil
+	}
+	return gogoproto.Clone(v).(*proto.Value)
+}
@@ -0,0 +1,78 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestReadCache verifies that cached values answer reads by the same
+// replica at or above their read timestamps, until the replica is
+// written, and that the least recently used keys are evicted.
+func TestReadCache(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	rc := newReadCache(2)
+	r, other := &Range{}, &Range{}
+	ts := proto.Timestamp{WallTime: 10}
+	value := &proto.Value{Bytes: []byte("value")}
+
+	rc.add(r, r.writeGen(), proto.Key("a"), ts, value)
+	value.Bytes[0] = 'V' // Cached values are copies
+	if v, ok := rc.get(r, proto.Key("a"), ts.Add(1, 0)); !ok || string(v.Bytes) != "value" {
+		t.Errorf("expected cached value; got %v, %t", v, ok)
+	}
+	if _, ok := rc.get(r, proto.Key("a"), proto.Timestamp{WallTime: 9}); ok {
+		t.Error("expected miss below the read timestamp")
+	}
+	if _, ok := rc.get(other, proto.Key("a"), ts); ok {
+		t.Error("expected miss for another replica")
+	}
+
+	// Values read before a write are neither cached nor answered.
+	gen := r.writeGen()
+	r.writes++
+	rc.add(r, gen, proto.Key("b"), ts, value)
+	if _, ok := rc.get(r, proto.Key("b"), ts); ok {
+		t.Error("expected value read before a write not to be cached")
+	}
+	if _, ok := rc.get(r, proto.Key("a"), ts); ok {
+		t.Error("expected write to invalidate cached value")
+	}
+
+	// Nil values are cached, and the least recently used key evicted.
+	for _, key := range []string{"a", "b", "c"} {
+		rc.add(r, r.writeGen(), proto.Key(key), ts, nil)
+	}
+	if _, ok := rc.get(r, proto.Key("a"), ts); ok {
+		t.Error("expected least recently used key to be evicted")
+	}
+	if v, ok := rc.get(r, proto.Key("c"), ts); !ok || v != nil {
+		t.Errorf("expected cached nil value; got %v, %t", v, ok)
+	}
+
+	if hits, misses, rate := rc.metrics(time.Now()); hits != 2 || misses != 5 || rate != 2.0/7 {
+		t.Errorf("expected 2 hits and 5 misses; got %d, %d, %f", hits, misses, rate)
+	}
+	var nilCache *readCache
+	nilCache.add(r, r.writeGen(), proto.Key("a"), ts, value)
+	if _, ok := nilCache.get(r, proto.Key("a"), ts); ok {
+		t.Error("expected nil cache to cache nothing")
+	}
+}
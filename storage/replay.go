@@ -0,0 +1,290 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/multiraft"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/coreos/etcd/raft/raftpb"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// replayCacheSize is the cache size of the in-memory engine holding
+// the state of a replayed range.
+const replayCacheSize = 1 << 20
+
+// A ReplayDiff is a change made to the raw, encoded key of a replayed
+// range's state by the application of a raft log entry. Old is nil if
+// the key was added and New is nil if it was deleted.
+type ReplayDiff struct {
+	Key      proto.EncodedKey
+	Old, New []byte
+}
+
+// A ReplayedEntry describes the application of a raft log entry by
+// ReplayRaftLog.
+type ReplayedEntry struct {
+	Index uint64
+	// Cmd is the entry's command. Entries which don't hold a command
+	// aren't replayed.
+	Cmd proto.InternalRaftCommand
+	// Err is the error with which the command was applied, if any.
+	Err   error
+	Diffs []ReplayDiff
+}
+
+// ReplayRaftLog applies the entries of the raft log of range raftID
+// held by the engine of a store to a fresh replica of the range in a
+// sandbox, an in-memory engine which holds nothing else, invoking f
+// with the changes made by each entry to the replica's state, in
+// order. The store's engine isn't modified, so it may be read-only.
+// Replaying is deterministic: the sandbox's clock reads the timestamp
+// of the command being applied, no other replicas or nodes are
+// contacted and splits and merges only modify the sandbox's state.
+// This allows divergence of replicas at apply time to be investigated
+// offline, by comparing the replays of their logs or replaying a log
+// after a change to the commands.
+//
+// The replica is given the range's current descriptor. Entries
+// truncated from the log can't be replayed, so commands which depend
+// on the state written by truncated entries, or by the split which
+// created the range, may fail in the sandbox where they succeeded
+// originally; their errors are reported in the ReplayedEntry.
+// Replaying stops at the first error returned by f.
+func ReplayRaftLog(source engine.Engine, raftID int64, f func(ReplayedEntry) error) error {
+	desc, err := lookupRangeDescriptor(source, raftID)
+	if err != nil {
+		return err
+	}
+	ents, err := loadRaftLog(source, raftID)
+	if err != nil {
+		return err
+	}
+	rm := newReplayRangeManager()
+	defer rm.engine.Close()
+	rng, err := NewRange(desc, rm)
+	if err != nil {
+		return err
+	}
+	for _, ent := range ents {
+		commandID, data, err := multiraft.DecodeEntry(ent)
+		if err != nil {
+			return err
+		}
+		if commandID == "" && len(data) == 0 {
+			continue
+		}
+		re := ReplayedEntry{Index: ent.Index}
+		if err := gogoproto.Unmarshal(data, &re.Cmd); err != nil {
+			return util.Errorf("unable to decode command at index %d: %s", ent.Index, err)
+		}
+		if args, ok := re.Cmd.Cmd.GetValue().(proto.Request); ok {
+			rm.manual.Set(args.Header().Timestamp.WallTime)
+		}
+		// The commands of a coalesced entry are applied together, when
+		// the clock reads the timestamp of the last of them.
+		for _, c := range re.Cmd.Coalesced {
+			if args, ok := c.Cmd.Cmd.GetValue().(proto.Request); ok {
+				rm.manual.Set(args.Header().Timestamp.WallTime)
+			}
+		}
+		snap := rm.engine.NewSnapshot()
+		_, re.Err = rng.processRaftCommand(cmdIDKey(commandID), ent.Index, re.Cmd, false)
+		re.Diffs, err = diffEngines(snap, rm.engine)
+		snap.Close()
+		if err != nil {
+			return err
+		}
+		if err := f(re); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupRangeDescriptor returns the descriptor of range raftID held
+// by the engine of a store.
+func lookupRangeDescriptor(e engine.Engine, raftID int64) (*proto.RangeDescriptor, error) {
+	var found *proto.RangeDescriptor
+	start := engine.RangeDescriptorKey(engine.KeyMin)
+	end := engine.RangeDescriptorKey(engine.KeyMax)
+	if err := engine.MVCCIterate(e, start, end, proto.MaxTimestamp, false, nil, func(kv proto.KeyValue) (bool, error) {
+		_, suffix, _ := engine.DecodeRangeKey(kv.Key)
+		if !suffix.Equal(engine.KeyLocalRangeDescriptorSuffix) {
+			return false, nil
+		}
+		var desc proto.RangeDescriptor
+		if err := gogoproto.Unmarshal(kv.Value.Bytes, &desc); err != nil {
+			return false, err
+		}
+		if desc.RaftID == raftID {
+			found = &desc
+			return true, nil
+		}
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, util.Errorf("store %s holds no replica of range %d", e, raftID)
+	}
+	return found, nil
+}
+
+// loadRaftLog returns the entries of the raft log of range raftID
+// held by the engine of a store, in order.
+func loadRaftLog(e engine.Engine, raftID int64) ([]raftpb.Entry, error) {
+	logKey := engine.RaftLogPrefix(raftID)
+	kvs, err := engine.MVCCScan(e, logKey, logKey.PrefixEnd(), 0, proto.ZeroTimestamp, true, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The log is stored backwards.
+	ents := make([]raftpb.Entry, len(kvs))
+	for i, kv := range kvs {
+		if err := gogoproto.Unmarshal(kv.Value.GetBytes(), &ents[len(kvs)-1-i]); err != nil {
+			return nil, err
+		}
+	}
+	return ents, nil
+}
+
+// diffEngines returns the changes made to the contents of before by
+// after, ordered by key.
+func diffEngines(before, after engine.Engine) ([]ReplayDiff, error) {
+	bIter, aIter := before.NewIterator(), after.NewIterator()
+	defer bIter.Close()
+	defer aIter.Close()
+	bIter.Seek(nil)
+	aIter.Seek(nil)
+	// Added and deleted keys are told apart from changed ones by their
+	// nil values.
+	value := func(iter engine.Iterator) []byte {
+		if v := iter.Value(); v != nil {
+			return v
+		}
+		return []byte{}
+	}
+	var diffs []ReplayDiff
+	for bIter.Valid() || aIter.Valid() {
+		c := 1
+		if !aIter.Valid() {
+			c = -1
+		} else if bIter.Valid() {
+			c = bytes.Compare(bIter.Key(), aIter.Key())
+		}
+		switch {
+		case c < 0:
+			diffs = append(diffs, ReplayDiff{Key: bIter.Key(), Old: value(bIter)})
+			bIter.Next()
+		case c > 0:
+			diffs = append(diffs, ReplayDiff{Key: aIter.Key(), New: value(aIter)})
+			aIter.Next()
+		default:
+			if oldValue, newValue := value(bIter), value(aIter); !bytes.Equal(oldValue, newValue) {
+				diffs = append(diffs, ReplayDiff{Key: aIter.Key(), Old: oldValue, New: newValue})
+			}
+			bIter.Next()
+			aIter.Next()
+		}
+	}
+	if err := bIter.Error(); err != nil {
+		return nil, err
+	}
+	return diffs, aIter.Error()
+}
+
+// A replayRangeManager is the RangeManager of a range replayed by
+// ReplayRaftLog. It holds the range's state in an in-memory engine and
+// isolates the range from the rest of the cluster: the replica isn't
+// part of a raft group and can't contact other nodes, and ranges
+// created or subsumed by its commands aren't tracked.
+type replayRangeManager struct {
+	engine    engine.Engine
+	manual    *hlc.ManualClock
+	clock     *hlc.Clock
+	leases    leaseMetrics
+	reads     readMetrics
+	throttled rateCounter
+}
+
+func newReplayRangeManager() *replayRangeManager {
+	manual := hlc.NewManualClock(0)
+	return &replayRangeManager{
+		engine: engine.NewInMem(proto.Attributes{}, replayCacheSize),
+		manual: manual,
+		clock:  hlc.NewClock(manual.UnixNano),
+	}
+}
+
+func (rm *replayRangeManager) ClusterID() string            { return "" }
+func (rm *replayRangeManager) StoreID() proto.StoreID       { return 0 }
+func (rm *replayRangeManager) RaftNodeID() multiraft.NodeID { return 0 }
+func (rm *replayRangeManager) Clock() *hlc.Clock            { return rm.clock }
+func (rm *replayRangeManager) Engine() engine.Engine        { return rm.engine }
+func (rm *replayRangeManager) DB() *client.KV               { return nil }
+func (rm *replayRangeManager) Allocator() *allocator        { return nil }
+func (rm *replayRangeManager) Gossip() *gossip.Gossip       { return nil }
+func (rm *replayRangeManager) SplitQueue() *splitQueue      { return nil }
+func (rm *replayRangeManager) ReadOnly() bool               { return false }
+func (rm *replayRangeManager) IOSuspect() bool              { return false }
+func (rm *replayRangeManager) Corrupt() bool                { return false }
+func (rm *replayRangeManager) Draining() bool               { return false }
+
+func (rm *replayRangeManager) AddRange(rng *Range) error               { return nil }
+func (rm *replayRangeManager) LookupRange(start, end proto.Key) *Range { return nil }
+func (rm *replayRangeManager) RemoveRange(rng *Range) error            { return nil }
+func (rm *replayRangeManager) SplitRange(origRng, newRng *Range) error { return nil }
+
+// MergeRange returns a placeholder for the subsumed range, whose
+// timestamp cache is merged into that of the subsuming range.
+func (rm *replayRangeManager) MergeRange(subsumingRng *Range, updatedEndKey proto.Key, subsumedRaftID int64) (*Range, error) {
+	return &Range{tsCache: NewTimestampCache(rm.clock)}, nil
+}
+
+func (rm *replayRangeManager) NewRangeDescriptor(start, end proto.Key, replicas []proto.Replica) (*proto.RangeDescriptor, error) {
+	return nil, util.Errorf("ranges can't be created while replaying a raft log")
+}
+
+func (rm *replayRangeManager) NewSnapshot() engine.Engine { return rm.engine.NewSnapshot() }
+
+func (rm *replayRangeManager) ProposeRaftCommand(cmdIDKey, proto.InternalRaftCommand) <-chan error {
+	errChan := make(chan error, 1)
+	errChan <- util.Errorf("commands can't be proposed while replaying a raft log")
+	return errChan
+}
+
+func (rm *replayRangeManager) closedTimestampLag() time.Duration { return 0 }
+func (rm *replayRangeManager) standby() bool                     { return false }
+func (rm *replayRangeManager) leaseMetrics() *leaseMetrics       { return &rm.leases }
+func (rm *replayRangeManager) readMetrics() *readMetrics         { return &rm.reads }
+func (rm *replayRangeManager) throttledCmds() *rateCounter       { return &rm.throttled }
+func (rm *replayRangeManager) readCache() *readCache             { return nil }
+func (rm *replayRangeManager) scanPrefetchSize() int             { return 0 }
+func (rm *replayRangeManager) txnAbandonTimeout() time.Duration  { return 0 }
+func (rm *replayRangeManager) startGroup(raftID int64) error     { return nil }
+
+func (rm *replayRangeManager) systemConfig(key string) (PrefixConfigMap, error) {
+	return nil, util.Errorf("system config %q isn't available while replaying a raft log", key)
+}
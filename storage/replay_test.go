@@ -0,0 +1,106 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestReplayRaftLog verifies that replaying a range's raft log applies
+// its commands in order, reporting their errors and changes, and that
+// replays are deterministic.
+func TestReplayRaftLog(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	for _, value := range []string{"1", "2"} {
+		pArgs, pReply := putArgs([]byte("a"), []byte(value), 1, store.StoreID())
+		if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cpArgs := &proto.ConditionalPutRequest{
+		RequestHeader: proto.RequestHeader{
+			Key:       []byte("a"),
+			Timestamp: proto.MinTimestamp,
+			RaftID:    1,
+			Replica:   proto.Replica{StoreID: store.StoreID()},
+		},
+		Value:    proto.Value{Bytes: []byte("3")},
+		ExpValue: &proto.Value{Bytes: []byte("missing")},
+	}
+	if err := store.ExecuteCmd(cpArgs, &proto.ConditionalPutResponse{}); err == nil {
+		t.Fatal("expected conditional put to fail")
+	}
+
+	replay := func() []ReplayedEntry {
+		var entries []ReplayedEntry
+		if err := ReplayRaftLog(store.Engine(), 1, func(re ReplayedEntry) error {
+			entries = append(entries, re)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+	entries := replay()
+
+	metaKey := engine.MVCCEncodeKey(proto.Key("a"))
+	var puts, cPutErrs int
+	var lastIndex uint64
+	for _, re := range entries {
+		if re.Index <= lastIndex {
+			t.Errorf("entry %d replayed after entry %d", re.Index, lastIndex)
+		}
+		lastIndex = re.Index
+		switch re.Cmd.Cmd.GetValue().(type) {
+		case *proto.PutRequest:
+			if re.Err != nil {
+				t.Errorf("%d: unexpected error: %s", re.Index, re.Err)
+			}
+			var changed bool
+			for _, d := range re.Diffs {
+				changed = changed || bytes.Equal(d.Key, metaKey)
+			}
+			if !changed {
+				t.Errorf("%d: expected put to change %q; got %+v", re.Index, metaKey, re.Diffs)
+			}
+			puts++
+		case *proto.ConditionalPutRequest:
+			if _, ok := re.Err.(*proto.ConditionFailedError); !ok {
+				t.Errorf("%d: expected condition failed error; got %v", re.Index, re.Err)
+			}
+			cPutErrs++
+		}
+	}
+	if puts != 2 || cPutErrs != 1 {
+		t.Errorf("expected 2 puts and 1 failed conditional put; got %d and %d", puts, cPutErrs)
+	}
+
+	if !reflect.DeepEqual(entries, replay()) {
+		t.Error("expected replays of the same log to be identical")
+	}
+	if err := ReplayRaftLog(store.Engine(), 2, func(ReplayedEntry) error { return nil }); err == nil {
+		t.Error("expected error replaying a range without a replica")
+	}
+}
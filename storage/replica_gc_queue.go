@@ -0,0 +1,125 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+const (
+	// replicaGCQueueMaxSize is the max size of the replica GC queue.
+	replicaGCQueueMaxSize = 100
+	// replicaGCQueueTimerDuration is the duration between checks of
+	// queued replicas.
+	replicaGCQueueTimerDuration = 1 * time.Second
+	// replicaGCInterval is how often each follower replica is checked
+	// for membership in its range.
+	replicaGCInterval = 24 * time.Hour // 1 day
+)
+
+// replicaGCQueue manages a queue of replicas to be checked for
+// membership in their ranges. Replicas removed from their range, e.g.
+// by rebalancing, stop receiving Raft traffic and may never learn of
+// their removal; their data would otherwise remain on the store
+// forever. A replica is checked against the authoritative range
+// descriptor, read from the range's leader, and destroyed if the
+// descriptor doesn't list its store or no longer exists.
+type replicaGCQueue struct {
+	*baseQueue
+	removed int64 // Replicas destroyed; updated atomically
+}
+
+// newReplicaGCQueue returns a new instance of replicaGCQueue.
+func newReplicaGCQueue() *replicaGCQueue {
+	rgcq := &replicaGCQueue{}
+	rgcq.baseQueue = newBaseQueue("replica gc", rgcq, replicaGCQueueMaxSize)
+	return rgcq
+}
+
+// shouldQueue determines whether a replica should be checked for
+// membership in its range. Replicas whose own descriptor doesn't list
+// their store are queued at priority 1. Follower replicas not checked
+// within the replica GC interval are queued at a lower priority, which
+// grows with the time since their last check. Leaders are members of
+// their ranges and are never queued, nor are replicas still awaiting
+// their initial snapshot.
+func (rgcq *replicaGCQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool, priority float64) {
+	if !rng.isInitialized() {
+		return
+	}
+	if _, replica := rng.Desc().FindReplica(rng.rm.StoreID()); replica == nil {
+		return true, 1
+	}
+	if rng.IsLeader() {
+		return
+	}
+	lastCheck, err := rng.GetLastReplicaGCTimestamp()
+	if err != nil {
+		log.Errorf("unable to fetch last replica GC timestamp: %s", err)
+		return
+	}
+	if score := float64(now.WallTime-lastCheck.WallTime) / float64(replicaGCInterval.Nanoseconds()); score > 1 {
+		priority = 1 - 1/score
+		shouldQ = true
+	}
+	return
+}
+
+// process reads the range's descriptor from its leader and destroys
+// the replica if it's no longer a member of the range. Otherwise, the
+// time of the check is recorded.
+func (rgcq *replicaGCQueue) process(now proto.Timestamp, rng *Range) error {
+	desc := rng.Desc()
+	call := client.GetCall(engine.RangeDescriptorKey(desc.StartKey))
+	call.Args.Header().User = UserRoot
+	call.Args.Header().PriorityClass = proto.LOW_PRIORITY
+	if err := rng.rm.DB().Run(call); err != nil {
+		return util.Errorf("unable to look up descriptor of range %s: %s", rng, err)
+	}
+	if reply := call.Reply.(*proto.GetResponse); reply.Value != nil {
+		current := &proto.RangeDescriptor{}
+		if err := gogoproto.Unmarshal(reply.Value.Bytes, current); err != nil {
+			return util.Errorf("unable to decode descriptor of range %s: %s", rng, err)
+		}
+		if _, replica := current.FindReplica(rng.rm.StoreID()); current.RaftID == desc.RaftID && replica != nil {
+			return rng.SetLastReplicaGCTimestamp(now)
+		}
+	}
+
+	log.Infof("destroying replica of range %s, which is no longer a member of the range", rng)
+	if err := rng.rm.RemoveRange(rng); err != nil {
+		return util.Errorf("unable to remove replica of range %s: %s", rng, err)
+	}
+	if err := rng.Destroy(); err != nil {
+		return util.Errorf("unable to destroy replica of range %s: %s", rng, err)
+	}
+	atomic.AddInt64(&rgcq.removed, 1)
+	return nil
+}
+
+// timer returns the interval between checks of successive queued
+// replicas.
+func (rgcq *replicaGCQueue) timer() time.Duration {
+	return replicaGCQueueTimerDuration
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestReplicaGCQueueShouldQueue verifies that replicas whose
+// descriptor doesn't list their store are queued, and that leaders
+// are not.
+func TestReplicaGCQueueShouldQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	rgcq := newReplicaGCQueue()
+	now := makeTS(replicaGCInterval.Nanoseconds()*2, 0)
+	if shouldQ, _ := rgcq.shouldQueue(now, tc.rng); shouldQ {
+		t.Error("expected leader not to be queued")
+	}
+
+	desc := *tc.rng.Desc()
+	defer tc.rng.SetDesc(&desc)
+	removed := desc
+	removed.Replicas = []proto.Replica{{NodeID: 2, StoreID: 2}}
+	tc.rng.SetDesc(&removed)
+	if shouldQ, priority := rgcq.shouldQueue(now, tc.rng); !shouldQ || priority != 1 {
+		t.Errorf("expected removed replica to be queued at priority 1; got %t, %f", shouldQ, priority)
+	}
+}
+
+// TestReplicaGCQueueProcess verifies that a replica is retained while
+// the range descriptor lists its store and destroyed once it doesn't.
+func TestReplicaGCQueueProcess(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	rgcq := newReplicaGCQueue()
+	now := tc.clock.Now()
+	if err := rgcq.process(now, tc.rng); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.store.GetRange(tc.rng.Desc().RaftID); err != nil {
+		t.Fatalf("expected replica to be retained: %s", err)
+	}
+	if ts, err := tc.rng.GetLastReplicaGCTimestamp(); err != nil || !ts.Equal(now) {
+		t.Errorf("expected last replica GC timestamp %s; got %s (%v)", now, ts, err)
+	}
+
+	// Rewrite the range descriptor without this store.
+	tc.manualClock.Increment(100)
+	desc := *tc.rng.Desc()
+	desc.Replicas = []proto.Replica{{NodeID: 2, StoreID: 2}}
+	if err := engine.MVCCPutProto(tc.engine, nil, engine.RangeDescriptorKey(desc.StartKey),
+		tc.clock.Now(), nil, &desc); err != nil {
+		t.Fatal(err)
+	}
+	if err := rgcq.process(tc.clock.Now(), tc.rng); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.store.GetRange(desc.RaftID); err == nil {
+		t.Error("expected replica to be removed from the store")
+	}
+	iter := newRangeDataIterator(tc.rng, tc.engine)
+	defer iter.Close()
+	if iter.Valid() {
+		t.Errorf("expected replica data to be destroyed; found key %q", iter.Key())
+	}
+	if rgcq.removed != 1 {
+		t.Errorf("expected 1 replica removed; got %d", rgcq.removed)
+	}
+}
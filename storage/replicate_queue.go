@@ -20,8 +20,8 @@ package storage
 import (
 	"time"
 
-	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
 )
@@ -38,17 +38,14 @@ const (
 // change to match the zone config.
 type replicateQueue struct {
 	*baseQueue
-	gossip    *gossip.Gossip
 	allocator *allocator
 	clock     *hlc.Clock
 	disabled  bool
 }
 
 // newReplicateQueue returns a new instance of replicateQueue.
-func newReplicateQueue(gossip *gossip.Gossip, allocator *allocator,
-	clock *hlc.Clock) *replicateQueue {
+func newReplicateQueue(allocator *allocator, clock *hlc.Clock) *replicateQueue {
 	rq := &replicateQueue{
-		gossip:    gossip,
 		allocator: allocator,
 		clock:     clock,
 	}
@@ -64,21 +61,26 @@ func (rq *replicateQueue) shouldQueue(now proto.Timestamp, rng *Range) (
 	}
 
 	// If the range spans multiple zones, ignore it until the split queue has processed it.
-	if len(computeSplitKeys(rq.gossip, rng)) > 0 {
+	if len(computeSplitKeys(rng)) > 0 {
 		return
 	}
 
 	// Load the zone config to find the desired replica attributes.
-	zone, err := lookupZoneConfig(rq.gossip, rng)
+	zone, err := lookupZoneConfig(rng)
 	if err != nil {
 		log.Error(err)
 		return
 	}
 
-	return rq.needsReplication(zone, rng)
+	return rq.needsReplication(zone, rng, now)
 }
 
-func (rq *replicateQueue) needsReplication(zone proto.ZoneConfig, rng *Range) (bool, float64) {
+func (rq *replicateQueue) needsReplication(zone proto.ZoneConfig, rng *Range, now proto.Timestamp) (bool, float64) {
+	if pinned := zone.ActivePin(time.Unix(0, now.WallTime)); pinned != nil {
+		missing, extra := pinChanges(pinned, rng.Desc().Replicas, rng.rm.StoreID())
+		changes := len(missing) + len(extra)
+		return changes > 0, float64(changes)
+	}
 	// TODO(bdarnell): handle non-empty ReplicaAttrs.
 	need := len(zone.ReplicaAttrs)
 	have := len(rng.Desc().Replicas)
@@ -90,15 +92,18 @@ func (rq *replicateQueue) needsReplication(zone proto.ZoneConfig, rng *Range) (b
 }
 
 func (rq *replicateQueue) process(now proto.Timestamp, rng *Range) error {
-	zone, err := lookupZoneConfig(rq.gossip, rng)
+	zone, err := lookupZoneConfig(rng)
 	if err != nil {
 		return err
 	}
 
-	if needs, _ := rq.needsReplication(zone, rng); !needs {
+	if needs, _ := rq.needsReplication(zone, rng, now); !needs {
 		// Something changed between shouldQueue and process.
 		return nil
 	}
+	if pinned := zone.ActivePin(time.Unix(0, now.WallTime)); pinned != nil {
+		return rq.processPin(pinned, rng)
+	}
 
 	// TODO(bdarnell): handle non-homogenous ReplicaAttrs.
 	newReplica, err := rq.allocator.allocate(zone.ReplicaAttrs[0], rng.Desc().Replicas)
@@ -119,6 +124,90 @@ func (rq *replicateQueue) process(now proto.Timestamp, rng *Range) error {
 	return err
 }
 
+// processPin moves the range's replicas towards the pinned stores:
+// first a replica is added to a pinned store which lacks one, then,
+// once every pinned store has a replica, one is removed from a store
+// which isn't pinned. The local replica is removed last, and not by
+// its own store: its leader lease, if it holds it, is first
+// transferred to a replica on a pinned store, which removes it.
+func (rq *replicateQueue) processPin(pinned []proto.StoreID, rng *Range) error {
+	var err error
+	missing, extra := pinChanges(pinned, rng.Desc().Replicas, rng.rm.StoreID())
+	if len(missing) > 0 {
+		var store *StoreDescriptor
+		if store, err = rq.allocator.findStore(missing[0]); err != nil {
+			return util.Errorf("unable to place replica of %s on pinned store: %s", rng, err)
+		}
+		log.Infof("adding replica of %s to pinned store %d", rng, store.StoreID)
+		err = rng.ChangeReplicas(proto.ADD_REPLICA,
+			proto.Replica{
+				NodeID:  store.Node.NodeID,
+				StoreID: store.StoreID,
+				Attrs:   store.Attrs,
+			})
+	} else if len(extra) > 0 && extra[0].StoreID == rng.rm.StoreID() {
+		return rq.transferPinnedLease(pinned, rng)
+	} else if len(extra) > 0 {
+		log.Infof("removing replica of %s from store %d, which isn't pinned", rng, extra[0].StoreID)
+		err = rng.ChangeReplicas(proto.REMOVE_REPLICA, extra[0])
+	} else {
+		return nil
+	}
+
+	// Enqueue this range again to see if there are more changes to be made.
+	go rq.MaybeAdd(rng, rq.clock.Now())
+
+	return err
+}
+
+// transferPinnedLease transfers the leader lease of the range, if the
+// local replica holds it, to a replica on a pinned store, so that the
+// local replica, whose store isn't pinned, may be removed by the new
+// lease holder.
+func (rq *replicateQueue) transferPinnedLease(pinned []proto.StoreID, rng *Range) error {
+	l := rng.getLease()
+	if l == nil || l.RaftNodeID != uint64(rng.rm.RaftNodeID()) || l.Expiration <= rq.clock.PhysicalNow() {
+		return nil
+	}
+	for _, storeID := range pinned {
+		if i, replica := rng.Desc().FindReplica(storeID); i >= 0 {
+			log.Infof("transferring leader lease of %s to pinned store %d to remove its replica from store %d",
+				rng, storeID, rng.rm.StoreID())
+			rng.transferLeaderLease(l, *replica)
+			return nil
+		}
+	}
+	return util.Errorf("no replica of %s on a pinned store to transfer its leader lease to", rng)
+}
+
+// pinChanges returns the pinned stores which lack replicas of a range
+// and the replicas which are on stores that aren't pinned. The replica
+// on the local store, localStoreID, if it isn't pinned, is returned
+// last, as its leader lease must first be transferred to another
+// replica.
+func pinChanges(pinned []proto.StoreID, replicas []proto.Replica, localStoreID proto.StoreID) (
+	missing []proto.StoreID, extra []proto.Replica) {
+	isPinned := map[proto.StoreID]bool{}
+	for _, storeID := range pinned {
+		isPinned[storeID] = true
+		if i, _ := proto.ReplicaSlice(replicas).FindReplica(storeID); i < 0 {
+			missing = append(missing, storeID)
+		}
+	}
+	var local []proto.Replica
+	for _, r := range replicas {
+		if isPinned[r.StoreID] {
+			continue
+		}
+		if r.StoreID == localStoreID {
+			local = append(local, r)
+		} else {
+			extra = append(extra, r)
+		}
+	}
+	return missing, append(extra, local...)
+}
+
 func (rq *replicateQueue) timer() time.Duration {
 	return replicateQueueTimerDuration
 }
@@ -0,0 +1,58 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestPinChanges verifies that the pinned stores lacking replicas and
+// the replicas on other stores are found, with the local replica last.
+func TestPinChanges(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	replicas := []proto.Replica{
+		{NodeID: 1, StoreID: 1},
+		{NodeID: 2, StoreID: 2},
+		{NodeID: 3, StoreID: 3},
+	}
+	testCases := []struct {
+		pinned     []proto.StoreID
+		local      proto.StoreID
+		expMissing []proto.StoreID
+		expExtra   []proto.StoreID
+	}{
+		{[]proto.StoreID{1, 2, 3}, 1, nil, nil},
+		{[]proto.StoreID{1, 2, 3, 4}, 1, []proto.StoreID{4}, nil},
+		{[]proto.StoreID{1, 4}, 1, []proto.StoreID{4}, []proto.StoreID{2, 3}},
+		{[]proto.StoreID{4, 5}, 2, []proto.StoreID{4, 5}, []proto.StoreID{1, 3, 2}},
+		{[]proto.StoreID{2, 3}, 1, nil, []proto.StoreID{1}},
+	}
+	for i, test := range testCases {
+		missing, extra := pinChanges(test.pinned, replicas, test.local)
+		var extraIDs []proto.StoreID
+		for _, r := range extra {
+			extraIDs = append(extraIDs, r.StoreID)
+		}
+		if !reflect.DeepEqual(missing, test.expMissing) || !reflect.DeepEqual(extraIDs, test.expExtra) {
+			t.Errorf("%d: expected missing %v and extra %v; got %v and %v",
+				i, test.expMissing, test.expExtra, missing, extraIDs)
+		}
+	}
+}
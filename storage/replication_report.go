@@ -0,0 +1,205 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/client"
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util"
+	gogoproto "github.com/gogo/protobuf/proto"
+)
+
+// The problems a range may have in a replication report.
+const (
+	// RangeUnavailable is reported for a range a majority of whose
+	// replicas are on stores missing from gossip; it can't commit
+	// commands until they return.
+	RangeUnavailable = "unavailable"
+	// RangeDeadReplicas is reported for a range some of whose replicas,
+	// but less than a majority, are on stores missing from gossip.
+	RangeDeadReplicas = "dead replicas"
+	// RangeUnderReplicated is reported for a range with fewer replicas
+	// than its zone requires.
+	RangeUnderReplicated = "under-replicated"
+	// RangeOverReplicated is reported for a range with more replicas
+	// than its zone requires.
+	RangeOverReplicated = "over-replicated"
+)
+
+// rangeDescriptorScanBatch is the number of addressing records read at
+// a time by a replication report.
+const rangeDescriptorScanBatch = 1000
+
+// A StoreReplication describes the replicas and leases of a store.
+type StoreReplication struct {
+	StoreID proto.StoreID `json:"store_id"`
+	NodeID  proto.NodeID  `json:"node_id"`
+	// Live is set if the store's descriptor is gossiped. The other
+	// stores are known only from the range descriptors listing replicas
+	// on them; their lease counts and bytes are zero.
+	Live bool `json:"live"`
+	// ReplicaCount is the number of range descriptors with a replica on
+	// the store.
+	ReplicaCount int `json:"replica_count"`
+	// LeaseCount is the number of leader leases held by the store as
+	// last gossiped.
+	LeaseCount int   `json:"lease_count"`
+	Bytes      int64 `json:"bytes"`
+	// Corruptions are the key spans found corrupt by the most recent
+	// scrub of the store, as last gossiped.
+	Corruptions []engine.Corruption `json:"corruptions,omitempty"`
+}
+
+// A ProblemRange is a range which isn't fully replicated on live
+// stores.
+type ProblemRange struct {
+	RaftID   int64           `json:"raft_id"`
+	StartKey proto.Key       `json:"start_key"`
+	EndKey   proto.Key       `json:"end_key"`
+	Replicas []proto.Replica `json:"replicas"`
+	// WantReplicas is the number of replicas required by the range's
+	// zone, or the number of its pinned stores while it's pinned.
+	WantReplicas int      `json:"want_replicas"`
+	Problems     []string `json:"problems"`
+}
+
+// A ReplicationReport describes the replicas and leases of each store
+// of the cluster and lists the ranges with problems.
+type ReplicationReport struct {
+	// Stores are sorted by store ID.
+	Stores []StoreReplication `json:"stores"`
+	// ProblemRanges are sorted by key.
+	ProblemRanges []ProblemRange `json:"problem_ranges"`
+}
+
+// ReplicationReport returns a report of the replication of the
+// cluster's ranges, read from their addressing records, on the stores
+// known to this one through gossip.
+func (s *Store) ReplicationReport() (ReplicationReport, error) {
+	stores, err := s.allocator.storeFinder(proto.Attributes{})
+	if err != nil {
+		return ReplicationReport{}, err
+	}
+	zoneMap, err := s.systemConfig(gossip.KeyConfigZone)
+	if err != nil || zoneMap == nil {
+		return ReplicationReport{}, util.Errorf("unable to lookup zone configs: %s", err)
+	}
+	descs, err := scanRangeDescriptors(s.ctx.DB)
+	if err != nil {
+		return ReplicationReport{}, err
+	}
+	return computeReplicationReport(stores, descs, zoneMap, time.Unix(0, s.ctx.Clock.PhysicalNow())), nil
+}
+
+// scanRangeDescriptors returns the descriptors of all ranges, in key
+// order, from their meta2 addressing records.
+func scanRangeDescriptors(db *client.KV) ([]proto.RangeDescriptor, error) {
+	var descs []proto.RangeDescriptor
+	startKey := engine.KeyMeta2Prefix
+	for {
+		call := client.ScanCall(startKey, engine.KeyMeta2Prefix.PrefixEnd(), rangeDescriptorScanBatch)
+		if err := db.Run(call); err != nil {
+			return nil, util.Errorf("unable to scan range descriptors: %s", err)
+		}
+		rows := call.Reply.(*proto.ScanResponse).Rows
+		for _, row := range rows {
+			var desc proto.RangeDescriptor
+			if err := gogoproto.Unmarshal(row.Value.Bytes, &desc); err != nil {
+				return nil, util.Errorf("unable to unmarshal range descriptor at %q: %s", row.Key, err)
+			}
+			descs = append(descs, desc)
+		}
+		if len(rows) < rangeDescriptorScanBatch {
+			return descs, nil
+		}
+		startKey = rows[len(rows)-1].Key.Next()
+	}
+}
+
+// computeReplicationReport returns a report of the replication of the
+// ranges with descriptors descs, in key order, on the live stores.
+func computeReplicationReport(stores []*StoreDescriptor, descs []proto.RangeDescriptor,
+	zoneMap PrefixConfigMap, now time.Time) ReplicationReport {
+	byID := map[proto.StoreID]*StoreReplication{}
+	for _, s := range stores {
+		byID[s.StoreID] = &StoreReplication{
+			StoreID:     s.StoreID,
+			NodeID:      s.Node.NodeID,
+			Live:        true,
+			LeaseCount:  s.LeaseCount,
+			Bytes:       storeBytes(s),
+			Corruptions: s.Corruptions,
+		}
+	}
+	report := ReplicationReport{Stores: []StoreReplication{}, ProblemRanges: []ProblemRange{}}
+	for i := range descs {
+		desc := &descs[i]
+		dead := 0
+		for _, r := range desc.Replicas {
+			sr, ok := byID[r.StoreID]
+			if !ok {
+				sr = &StoreReplication{StoreID: r.StoreID, NodeID: r.NodeID}
+				byID[r.StoreID] = sr
+			}
+			if !sr.Live {
+				dead++
+			}
+			sr.ReplicaCount++
+		}
+		zone := zoneMap.MatchByPrefix(zoneKey(desc)).Config.(*proto.ZoneConfig)
+		want := len(zone.ReplicaAttrs)
+		if pinned := zone.ActivePin(now); pinned != nil {
+			want = len(pinned)
+		}
+		var problems []string
+		if have := len(desc.Replicas); dead > have/2 {
+			problems = append(problems, RangeUnavailable)
+		} else if dead > 0 {
+			problems = append(problems, RangeDeadReplicas)
+		}
+		if have := len(desc.Replicas); have < want {
+			problems = append(problems, RangeUnderReplicated)
+		} else if have > want {
+			problems = append(problems, RangeOverReplicated)
+		}
+		if len(problems) > 0 {
+			report.ProblemRanges = append(report.ProblemRanges, ProblemRange{
+				RaftID:       desc.RaftID,
+				StartKey:     desc.StartKey,
+				EndKey:       desc.EndKey,
+				Replicas:     desc.Replicas,
+				WantReplicas: want,
+				Problems:     problems,
+			})
+		}
+	}
+	for _, sr := range byID {
+		report.Stores = append(report.Stores, *sr)
+	}
+	sort.Sort(storeReplicationsByID(report.Stores))
+	return report
+}
+
+type storeReplicationsByID []StoreReplication
+
+func (s storeReplicationsByID) Len() int           { return len(s) }
+func (s storeReplicationsByID) Less(i, j int) bool { return s[i].StoreID < s[j].StoreID }
+func (s storeReplicationsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,87 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestComputeReplicationReport verifies the replica counts of live and
+// dead stores and the problems found with under-replicated ranges and
+// ranges with replicas on dead stores.
+func TestComputeReplicationReport(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	stores := []*StoreDescriptor{
+		{StoreID: 2, Node: gossip.NodeDescriptor{NodeID: 2}, LeaseCount: 1,
+			Capacity: engine.StoreCapacity{Capacity: 1000, Available: 800}},
+		{StoreID: 1, Node: gossip.NodeDescriptor{NodeID: 1}, LeaseCount: 3,
+			Capacity: engine.StoreCapacity{Capacity: 1000, Available: 900}},
+	}
+	replicas := func(ids ...int) []proto.Replica {
+		var r []proto.Replica
+		for _, id := range ids {
+			r = append(r, proto.Replica{NodeID: proto.NodeID(id), StoreID: proto.StoreID(id)})
+		}
+		return r
+	}
+	descs := []proto.RangeDescriptor{
+		{RaftID: 1, StartKey: engine.KeyMin, EndKey: proto.Key("a"), Replicas: replicas(1, 2, 3)},
+		{RaftID: 2, StartKey: proto.Key("a"), EndKey: proto.Key("b"), Replicas: replicas(1, 3)},
+		{RaftID: 3, StartKey: proto.Key("b"), EndKey: proto.Key("db1"), Replicas: replicas(3)},
+		{RaftID: 4, StartKey: proto.Key("db1"), EndKey: engine.KeyMax, Replicas: replicas(1)},
+	}
+	attrs := []proto.Attributes{{}, {}, {}}
+	zoneMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{ReplicaAttrs: attrs}},
+		{proto.Key("db1"), nil, &proto.ZoneConfig{ReplicaAttrs: attrs[:1]}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := computeReplicationReport(stores, descs, zoneMap, time.Now())
+	expStores := []StoreReplication{
+		{StoreID: 1, NodeID: 1, Live: true, ReplicaCount: 3, LeaseCount: 3, Bytes: 100},
+		{StoreID: 2, NodeID: 2, Live: true, ReplicaCount: 1, LeaseCount: 1, Bytes: 200},
+		{StoreID: 3, NodeID: 3, ReplicaCount: 3},
+	}
+	if !reflect.DeepEqual(report.Stores, expStores) {
+		t.Errorf("expected stores %+v; got %+v", expStores, report.Stores)
+	}
+	expProblems := map[int64][]string{
+		1: {RangeDeadReplicas},
+		2: {RangeDeadReplicas, RangeUnderReplicated},
+		3: {RangeUnavailable, RangeUnderReplicated},
+	}
+	if len(report.ProblemRanges) != len(expProblems) {
+		t.Fatalf("expected %d problem ranges; got %+v", len(expProblems), report.ProblemRanges)
+	}
+	for _, pr := range report.ProblemRanges {
+		if !reflect.DeepEqual(pr.Problems, expProblems[pr.RaftID]) {
+			t.Errorf("range %d: expected problems %v; got %v", pr.RaftID, expProblems[pr.RaftID], pr.Problems)
+		}
+		if pr.WantReplicas != 3 {
+			t.Errorf("range %d: expected 3 replicas wanted; got %d", pr.RaftID, pr.WantReplicas)
+		}
+	}
+}
@@ -209,26 +209,38 @@ func (rc *ResponseCache) CopyFrom(e engine.Engine, originRaftID int64) error {
 // command will be signaled to wakeup and read the command response
 // from the cache.
 func (rc *ResponseCache) PutResponse(cmdID proto.ClientCmdID, reply proto.Response) error {
+	err := rc.writeResponse(rc.engine, cmdID, reply)
+	// Remove inflight after writing response to cache!
+	rc.removeInflight(cmdID)
+	return err
+}
+
+// writeResponse writes a response for the specified cmdID to e, which
+// is typically a batch holding the command's other writes. The
+// inflight entry is left in place; the caller must remove it via
+// removeInflight once the batch is committed.
+func (rc *ResponseCache) writeResponse(e engine.Engine, cmdID proto.ClientCmdID, reply proto.Response) error {
 	// Do nothing if command ID is empty.
-	if cmdID.IsEmpty() {
+	if cmdID.IsEmpty() || !rc.shouldCacheResponse(reply) {
 		return nil
 	}
-	// Write the response value to the engine.
-	var err error
-	if rc.shouldCacheResponse(reply) {
-		key := engine.ResponseCacheKey(rc.raftID, &cmdID)
-		rwResp := &proto.ReadWriteCmdResponse{}
-		rwResp.SetValue(reply)
-		err = engine.MVCCPutProto(rc.engine, nil, key, proto.ZeroTimestamp, nil, rwResp)
-	}
+	key := engine.ResponseCacheKey(rc.raftID, &cmdID)
+	rwResp := &proto.ReadWriteCmdResponse{}
+	rwResp.SetValue(reply)
+	return engine.MVCCPutProto(e, nil, key, proto.ZeroTimestamp, nil, rwResp)
+}
 
-	// Take lock after writing response to cache!
+// removeInflight removes the inflight entry for cmdID, signaling any
+// requests waiting on the outcome of the command.
+func (rc *ResponseCache) removeInflight(cmdID proto.ClientCmdID) {
+	// Do nothing if command ID is empty.
+	if cmdID.IsEmpty() {
+		return
+	}
 	rc.Lock()
 	defer rc.Unlock()
 	// Even on error, we remove the entry from the inflight map.
 	rc.removeInflightLocked(cmdID)
-
-	return err
 }
 
 // shouldCacheResponse returns whether the response should be cached.
@@ -247,7 +259,7 @@ func (rc *ResponseCache) shouldCacheResponse(reply proto.Response) bool {
 // map. Any subsequent invocations of GetResponse for the same client
 // command will block on the inflight cond var until either the
 // response cache is cleared or this command is removed via
-// PutResponse().
+// PutResponse() or removeInflight().
 func (rc *ResponseCache) addInflightLocked(cmdID proto.ClientCmdID) {
 	if _, ok := rc.inflight[makeCmdIDKey(cmdID)]; ok {
 		panic(fmt.Sprintf("command %+v is already inflight; GetResponse() should have been "+
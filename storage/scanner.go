@@ -29,6 +29,15 @@ import (
 	"github.com/cockroachdb/cockroach/util/log"
 )
 
+const (
+	// minRangeScanBudget is the smallest time budget given to each
+	// range visit, however many ranges are to be scanned.
+	minRangeScanBudget = 10 * time.Millisecond
+	// maxRangeOverBudgetVisits is the number of consecutive visits on
+	// which a range may exceed its time budget before it's skipped.
+	maxRangeOverBudgetVisits = 3
+)
+
 // A rangeQueue is a prioritized queue of ranges for which work is
 // scheduled. For example, there's a GC queue for ranges which are due
 // for garbage collection, a rebalance queue to move ranges from full
@@ -44,6 +53,8 @@ type rangeQueue interface {
 	MaybeAdd(*Range, proto.Timestamp)
 	// MaybeRemove removes the range from the queue if it is present.
 	MaybeRemove(*Range)
+	// Length returns the number of ranges in the queue.
+	Length() int
 }
 
 // A rangeIterator provides access to a sequence of ranges to consider
@@ -70,14 +81,27 @@ type storeStats struct {
 // A rangeScanner iterates over ranges at a measured pace in order to
 // complete approximately one full scan per interval. Each range is
 // tested for inclusion in a sequence of prioritized range queues.
+//
+// Each range visit is given a time budget of the interval divided by
+// the number of ranges to scan plus the number of ranges waiting in
+// the queues, which are processed within the same interval; a deep
+// backlog leaves less time for each visit. A range which exceeds its budget on
+// maxRangeOverBudgetVisits consecutive visits is skipped on its next
+// visit, so that a few slow ranges can't make the scanner fall behind
+// on all others.
 type rangeScanner struct {
-	interval time.Duration  // Duration interval for scan loop
-	iter     rangeIterator  // Iterator to implement scan of ranges
-	queues   []rangeQueue   // Range queues managed by this scanner
-	removed  chan *Range    // Ranges to remove from queues
-	count    int64          // Count of times through the scanning loop
-	stats    unsafe.Pointer // Latest store stats object; updated atomically
-	scanFn   func()         // Function called at each complete scan iteration
+	interval   int64          // Duration interval for scan loop, in nanoseconds; accessed atomically
+	iter       rangeIterator  // Iterator to implement scan of ranges
+	queues     []rangeQueue   // Range queues managed by this scanner
+	removed    chan *Range    // Ranges to remove from queues
+	count      int64          // Count of times through the scanning loop
+	overBudget int64          // Count of range visits which exceeded their budget
+	skipped    int64          // Count of range visits skipped
+	stats      unsafe.Pointer // Latest store stats object; updated atomically
+	scanFn     func()         // Function called at each complete scan iteration
+	// slow maps ranges to the number of consecutive visits on which
+	// they exceeded their budget. It's only accessed by the scan loop.
+	slow map[*Range]int
 }
 
 // newRangeScanner creates a new range scanner with the provided loop interval,
@@ -85,11 +109,12 @@ type rangeScanner struct {
 // loop that function will be called.
 func newRangeScanner(interval time.Duration, iter rangeIterator, scanFn func()) *rangeScanner {
 	return &rangeScanner{
-		interval: interval,
+		interval: int64(interval),
 		iter:     iter,
 		removed:  make(chan *Range, 10),
 		stats:    unsafe.Pointer(&storeStats{RangeCount: iter.EstimatedCount()}),
 		scanFn:   scanFn,
+		slow:     map[*Range]int{},
 	}
 }
 
@@ -99,6 +124,18 @@ func (rs *rangeScanner) AddQueues(queues ...rangeQueue) {
 	rs.queues = append(rs.queues, queues...)
 }
 
+// SetInterval changes the interval in which the scanner paces a full
+// scan. It takes effect from the next range visit.
+func (rs *rangeScanner) SetInterval(interval time.Duration) {
+	atomic.StoreInt64(&rs.interval, int64(interval))
+}
+
+// Interval returns the interval in which the scanner paces a full
+// scan.
+func (rs *rangeScanner) Interval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&rs.interval))
+}
+
 // Start spins up the scanning loop. Call Stop() to exit the loop.
 func (rs *rangeScanner) Start(clock *hlc.Clock, stopper *util.Stopper) {
 	for _, queue := range rs.queues {
@@ -121,6 +158,72 @@ func (rs *rangeScanner) Count() int64 {
 	return atomic.LoadInt64(&rs.count)
 }
 
+// OverBudget returns the number of range visits which took longer
+// than their time budget.
+func (rs *rangeScanner) OverBudget() int64 {
+	return atomic.LoadInt64(&rs.overBudget)
+}
+
+// Skipped returns the number of range visits skipped because the
+// range consistently exceeded its time budget.
+func (rs *rangeScanner) Skipped() int64 {
+	return atomic.LoadInt64(&rs.skipped)
+}
+
+// queueDepth returns the number of ranges waiting in the queues.
+func (rs *rangeScanner) queueDepth() int {
+	var depth int
+	for _, q := range rs.queues {
+		depth += q.Length()
+	}
+	return depth
+}
+
+// rangeBudget returns the time budget for the next range visit of a
+// scan of count ranges, given the current depth of the queues.
+func (rs *rangeScanner) rangeBudget(count int) time.Duration {
+	budget := rs.Interval()
+	if n := count + rs.queueDepth(); n > 0 {
+		budget /= time.Duration(n)
+	}
+	if budget < minRangeScanBudget {
+		budget = minRangeScanBudget
+	}
+	return budget
+}
+
+// visitRange accumulates the range's stats and tests the range for
+// inclusion in each of the queues, unless the range has exceeded its
+// budget on too many consecutive visits, in which case the queues are
+// skipped this once.
+func (rs *rangeScanner) visitRange(rng *Range, clock *hlc.Clock, budget time.Duration, stats *storeStats) {
+	stats.RangeCount++
+	engine.Accumulate(&stats.MVCC, rng.stats.GetMVCC())
+	if rs.slow[rng] >= maxRangeOverBudgetVisits {
+		// Visit the range again next time, but skip it straight away
+		// if it's still over budget.
+		rs.slow[rng] = maxRangeOverBudgetVisits - 1
+		atomic.AddInt64(&rs.skipped, 1)
+		return
+	}
+	start := time.Now()
+	// Try adding range to all queues.
+	for _, q := range rs.queues {
+		q.MaybeAdd(rng, clock.Now())
+	}
+	elapsed := time.Now().Sub(start)
+	if elapsed <= budget {
+		delete(rs.slow, rng)
+		return
+	}
+	atomic.AddInt64(&rs.overBudget, 1)
+	rs.slow[rng]++
+	if n := rs.slow[rng]; n >= maxRangeOverBudgetVisits {
+		log.Warningf("%s took %s to scan, exceeding its budget of %s on %d consecutive visits; skipping its next visit",
+			rng, elapsed, budget, n)
+	}
+}
+
 // RemoveRange removes a range from any range queues the scanner may
 // have placed it in. This method should be called by the Store
 // when a range is removed (e.g. rebalanced or merged).
@@ -135,10 +238,11 @@ func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 	stopper.RunWorker(func() {
 		start := time.Now()
 		stats := &storeStats{}
+		count := rs.iter.EstimatedCount()
 
 		for {
 			elapsed := time.Now().Sub(start)
-			remainingNanos := rs.interval.Nanoseconds() - elapsed.Nanoseconds()
+			remainingNanos := rs.Interval().Nanoseconds() - elapsed.Nanoseconds()
 			if remainingNanos < 0 {
 				remainingNanos = 0
 			}
@@ -155,16 +259,12 @@ func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 				}
 				rng := rs.iter.Next()
 				if rng != nil {
-					// Try adding range to all queues.
-					for _, q := range rs.queues {
-						q.MaybeAdd(rng, clock.Now())
-					}
-					stats.RangeCount++
-					engine.Accumulate(&stats.MVCC, rng.stats.GetMVCC())
+					rs.visitRange(rng, clock, rs.rangeBudget(count), stats)
 				} else {
 					// Otherwise, we're done with the iteration. Reset iteration and start time.
 					rs.iter.Reset()
 					start = time.Now()
+					count = rs.iter.EstimatedCount()
 					// Increment iteration counter.
 					atomic.AddInt64(&rs.count, 1)
 					// Store the most recent scan results in the scanner's stats.
@@ -182,6 +282,7 @@ func (rs *rangeScanner) scanLoop(clock *hlc.Clock, stopper *util.Stopper) {
 				for _, q := range rs.queues {
 					q.MaybeRemove(rng)
 				}
+				delete(rs.slow, rng)
 				log.V(6).Infof("removed range %s", rng)
 
 			case <-stopper.ShouldStop():
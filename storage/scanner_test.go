@@ -158,7 +158,7 @@ func (tq *testQueue) MaybeRemove(rng *Range) {
 	}
 }
 
-func (tq *testQueue) count() int {
+func (tq *testQueue) Length() int {
 	tq.Lock()
 	defer tq.Unlock()
 	return len(tq.ranges)
@@ -198,7 +198,7 @@ func TestScannerAddToQueues(t *testing.T) {
 	// Start queue and verify that all ranges are added to both queues.
 	s.Start(clock, stopper)
 	if err := util.IsTrueWithin(func() bool {
-		return q1.count() == count && q2.count() == count
+		return q1.Length() == count && q2.Length() == count
 	}, 50*time.Millisecond); err != nil {
 		t.Error(err)
 	}
@@ -207,7 +207,7 @@ func TestScannerAddToQueues(t *testing.T) {
 	rng := iter.remove(0)
 	s.RemoveRange(rng)
 	if err := util.IsTrueWithin(func() bool {
-		return q1.count() == count-1 && q2.count() == count-1
+		return q1.Length() == count-1 && q2.Length() == count-1
 	}, 10*time.Millisecond); err != nil {
 		t.Error(err)
 	}
@@ -302,3 +302,91 @@ func TestScannerStats(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// slowQueue is a range queue which takes delay to consider the range
+// slow for inclusion and counts the number of times it was considered.
+type slowQueue struct {
+	testQueue
+	slow   *Range
+	delay  time.Duration
+	visits int
+}
+
+func (sq *slowQueue) MaybeAdd(rng *Range, now proto.Timestamp) {
+	if rng == sq.slow {
+		time.Sleep(sq.delay)
+		sq.Lock()
+		sq.visits++
+		sq.Unlock()
+	}
+	sq.testQueue.MaybeAdd(rng, now)
+}
+
+func (sq *slowQueue) slowVisits() int {
+	sq.Lock()
+	defer sq.Unlock()
+	return sq.visits
+}
+
+// TestScannerRangeBudget verifies that a range which consistently
+// exceeds its time budget is skipped, while its stats are still
+// accumulated.
+func TestScannerRangeBudget(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	const count = 3
+	iter := newTestIterator(count)
+	iter.ranges[0].SetDesc(&proto.RangeDescriptor{RaftID: 1})
+	q := &slowQueue{slow: &iter.ranges[0], delay: 2 * minRangeScanBudget}
+	q.setDisabled(true)
+	s := newRangeScanner(1*time.Millisecond, iter, nil)
+	s.AddQueues(q)
+	mc := hlc.NewManualClock(0)
+	clock := hlc.NewClock(mc.UnixNano)
+	stopper := util.NewStopper()
+	s.Start(clock, stopper)
+
+	if err := util.IsTrueWithin(func() bool {
+		return s.Skipped() >= 2
+	}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	stopper.Stop()
+	// The range is visited until it has exceeded its budget on
+	// maxRangeOverBudgetVisits consecutive visits, then on every other
+	// visit.
+	if ob := s.OverBudget(); ob < maxRangeOverBudgetVisits+1 {
+		t.Errorf("expected at least %d visits over budget; got %d", maxRangeOverBudgetVisits+1, ob)
+	}
+	if v := q.slowVisits(); int64(v) != s.OverBudget() {
+		t.Errorf("expected %d visits of the slow range; got %d", s.OverBudget(), v)
+	}
+	if rc := s.Stats().RangeCount; rc != count {
+		t.Errorf("range count expected %d; got %d", count, rc)
+	}
+	if c := q.Length(); c != count {
+		t.Errorf("expected %d ranges in queue; got %d", count, c)
+	}
+}
+
+// TestScannerRangeBudgetQueueDepth verifies that the time budget of a
+// range visit shrinks with the depth of the queues.
+func TestScannerRangeBudgetQueueDepth(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	iter := newTestIterator(4)
+	q := &testQueue{}
+	s := newRangeScanner(1*time.Second, iter, nil)
+	s.AddQueues(q)
+	if b := s.rangeBudget(4); b != 250*time.Millisecond {
+		t.Errorf("expected budget of 250ms with empty queues; got %s", b)
+	}
+	for i := range iter.ranges {
+		q.MaybeAdd(&iter.ranges[i], proto.ZeroTimestamp)
+	}
+	if b := s.rangeBudget(4); b != 125*time.Millisecond {
+		t.Errorf("expected budget of 125ms with 4 queued ranges; got %s", b)
+	}
+	s.SetInterval(1 * time.Millisecond)
+	if b := s.rangeBudget(4); b != minRangeScanBudget {
+		t.Errorf("expected minimum budget of %s; got %s", minRangeScanBudget, b)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// DefaultScrubInterval is the default interval at which the checksums
+// of a store's engine are verified.
+const DefaultScrubInterval = 24 * time.Hour
+
+// scrubResults holds the results of a store's scrubs.
+type scrubResults struct {
+	sync.Mutex
+	count       int64               // Completed scrubs
+	corruptions []engine.Corruption // Found by the most recent scrub
+}
+
+// Corrupt returns whether the most recent scrub of the store found
+// corrupt data.
+func (s *Store) Corrupt() bool {
+	s.scrubs.Lock()
+	defer s.scrubs.Unlock()
+	return len(s.scrubs.corruptions) > 0
+}
+
+// Corruptions returns the corruption found by the most recent scrub of
+// the store.
+func (s *Store) Corruptions() []engine.Corruption {
+	s.scrubs.Lock()
+	defer s.scrubs.Unlock()
+	return append([]engine.Corruption(nil), s.scrubs.corruptions...)
+}
+
+// monitorScrub periodically scrubs the store, if its engine is a
+// Scrubber.
+func (s *Store) monitorScrub() {
+	interval := s.ctx.ScrubInterval
+	if _, ok := s.engine.(engine.Scrubber); !ok || interval <= 0 {
+		return
+	}
+	s.stopper.RunWorker(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.Scrub(); err != nil {
+					log.Warningf("store %s: scrub failed: %s", s, err)
+				}
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// Scrub verifies the checksums of the data the store's engine holds
+// on disk and returns the corruption found. Each corrupt key range is
+// logged. When the corrupt key spans change, the store's descriptor is
+// gossiped with them so that, like a suspect store, it receives no new
+// replicas and the replicas on it may be replaced; while it's corrupt, it transfers away the leader leases it holds. A scrub
+// interrupted by the store's stopper isn't recorded.
+func (s *Store) Scrub() ([]engine.Corruption, error) {
+	sc, ok := s.engine.(engine.Scrubber)
+	if !ok {
+		return nil, nil
+	}
+	start := time.Now()
+	corruptions, err := sc.Scrub(s.stopper.ShouldStop())
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-s.stopper.ShouldStop():
+		return corruptions, nil
+	default:
+	}
+	for _, c := range corruptions {
+		log.Errorf("store %s: corrupt data in key range [%q, %q] of sstables %s: %s",
+			s, c.Start, c.End, c.Files, c.Err)
+	}
+	s.scrubs.Lock()
+	s.scrubs.count++
+	prev := s.scrubs.corruptions
+	s.scrubs.corruptions = corruptions
+	s.scrubs.Unlock()
+	if len(prev) != len(corruptions) || (len(prev) > 0 && !reflect.DeepEqual(prev, corruptions)) {
+		if len(corruptions) > 0 {
+			log.Warningf("store %s is corrupt; replicas should be moved off it and its device replaced", s)
+		} else {
+			log.Infof("store %s is no longer corrupt", s)
+		}
+		s.mu.RLock()
+		n := s.nodeDesc
+		s.mu.RUnlock()
+		if n != nil && s.ctx.Gossip != nil {
+			s.GossipCapacity(n)
+		}
+	}
+	if len(corruptions) > 0 {
+		s.TransferLeaderLeases()
+	}
+	log.Infof("store %s: scrubbed in %s", s, time.Since(start))
+	return corruptions, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// corruptEngine is an engine whose scrubs find the given corruption.
+type corruptEngine struct {
+	engine.Engine
+	corruptions []engine.Corruption
+}
+
+func (e *corruptEngine) Scrub(stop <-chan struct{}) ([]engine.Corruption, error) {
+	return e.corruptions, nil
+}
+
+// TestStoreScrub verifies that a store whose scrub finds corrupt data
+// is corrupt and described as suspect, with the corrupt key spans,
+// until a scrub finds it clean.
+func TestStoreScrub(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	if corruptions, err := store.Scrub(); err != nil || len(corruptions) != 0 {
+		t.Fatalf("expected no corruption; got %+v, %v", corruptions, err)
+	}
+	if m := store.Metrics(); m.Scrubs != 1 || m.Corruptions != 0 {
+		t.Errorf("expected 1 clean scrub; got %d scrubs finding %d corruptions", m.Scrubs, m.Corruptions)
+	}
+
+	corrupt := &corruptEngine{Engine: store.engine, corruptions: []engine.Corruption{
+		{Start: proto.EncodedKey("a"), End: proto.EncodedKey("b"), Files: []string{"/000001.sst"}, Err: "block checksum mismatch"},
+	}}
+	store.engine = corrupt
+	if _, err := store.Scrub(); err != nil {
+		t.Fatal(err)
+	}
+	nodeDesc := &gossip.NodeDescriptor{NodeID: 1}
+	if desc, err := store.Descriptor(nodeDesc); err != nil {
+		t.Fatal(err)
+	} else if !store.Corrupt() || !desc.Suspect || !reflect.DeepEqual(desc.Corruptions, corrupt.corruptions) {
+		t.Errorf("expected corrupt store to be suspect with its corrupt spans; got %+v", desc)
+	}
+	if m := store.Metrics(); m.Scrubs != 2 || m.Corruptions != 1 {
+		t.Errorf("expected 2 scrubs, the last finding 1 corruption; got %d and %d", m.Scrubs, m.Corruptions)
+	}
+
+	// A scrub finding other corrupt spans gossips them, though the store
+	// was corrupt already.
+	store.GossipCapacity(nodeDesc)
+	corrupt.corruptions = append(corrupt.corruptions, engine.Corruption{
+		Start: proto.EncodedKey("c"), End: proto.EncodedKey("d"), Files: []string{"/000002.sst"}, Err: "block checksum mismatch"})
+	if _, err := store.Scrub(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := store.Gossip().GetInfo(gossip.MakeMaxAvailCapacityKey(nodeDesc.NodeID, store.StoreID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc := info.(StoreDescriptor); !reflect.DeepEqual(desc.Corruptions, corrupt.corruptions) {
+		t.Errorf("expected gossiped corrupt spans %+v; got %+v", corrupt.corruptions, desc.Corruptions)
+	}
+
+	corrupt.corruptions = nil
+	if _, err := store.Scrub(); err != nil {
+		t.Fatal(err)
+	}
+	if desc, err := store.Descriptor(nodeDesc); err != nil {
+		t.Fatal(err)
+	} else if store.Corrupt() || desc.Suspect || len(desc.Corruptions) != 0 {
+		t.Errorf("expected clean store not to be suspect; got %+v", desc)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 )
@@ -39,17 +40,15 @@ const (
 // or along intersecting accounting or zone config boundaries.
 type splitQueue struct {
 	*baseQueue
-	db     *client.KV
-	gossip *gossip.Gossip
+	db *client.KV
 	// Some tests in this package disable the split queue.
 	disabled bool
 }
 
 // newSplitQueue returns a new instance of splitQueue.
-func newSplitQueue(db *client.KV, gossip *gossip.Gossip) *splitQueue {
+func newSplitQueue(db *client.KV) *splitQueue {
 	sq := &splitQueue{
-		db:     db,
-		gossip: gossip,
+		db: db,
 	}
 	sq.baseQueue = newBaseQueue("split", sq, splitQueueMaxSize)
 	return sq
@@ -66,14 +65,14 @@ func (sq *splitQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ bool
 	}
 
 	// Set priority to 1 in the event the range is split by acct or zone configs.
-	if len(computeSplitKeys(sq.gossip, rng)) > 0 {
+	if len(computeSplitKeys(rng)) > 0 {
 		priority = 1
 		shouldQ = true
 	}
 
 	// Add priority based on the size of range compared to the max
 	// size for the zone it's in.
-	zone, err := lookupZoneConfig(sq.gossip, rng)
+	zone, err := lookupZoneConfig(rng)
 	if err != nil {
 		log.Error(err)
 		return
@@ -92,7 +91,7 @@ func (sq *splitQueue) process(now proto.Timestamp, rng *Range) error {
 		return nil
 	}
 	// First handle case of splitting due to accounting and zone config maps.
-	splitKeys := computeSplitKeys(sq.gossip, rng)
+	splitKeys := computeSplitKeys(rng)
 	if len(splitKeys) > 0 {
 		log.Infof("splitting range %q-%q at keys %v", rng.Desc().StartKey, rng.Desc().EndKey, splitKeys)
 		for _, splitKey := range splitKeys {
@@ -109,7 +108,7 @@ func (sq *splitQueue) process(now proto.Timestamp, rng *Range) error {
 		return nil
 	}
 	// Next handle case of splitting due to size.
-	zone, err := lookupZoneConfig(sq.gossip, rng)
+	zone, err := lookupZoneConfig(rng)
 	if err != nil {
 		return err
 	}
@@ -129,25 +128,26 @@ func (sq *splitQueue) timer() time.Duration {
 // computeSplitKeys returns an array of keys at which the supplied
 // range should be split, as computed by intersecting the range with
 // accounting and zone config map boundaries.
-func computeSplitKeys(g *gossip.Gossip, rng *Range) []proto.Key {
+func computeSplitKeys(rng *Range) []proto.Key {
 	// Now split the range into pieces by intersecting it with the
 	// boundaries of the config map.
 	splitKeys := proto.KeySlice{}
 	for _, configKey := range []string{gossip.KeyConfigAccounting, gossip.KeyConfigZone} {
-		info, err := g.GetInfo(configKey)
+		configMap, err := rng.rm.systemConfig(configKey)
 		if err != nil {
-			log.Errorf("unable to fetch %s config from gossip: %s", configKey, err)
+			log.Error(err)
 			continue
 		}
-		configMap := info.(PrefixConfigMap)
 		splits, err := configMap.SplitRangeByPrefixes(rng.Desc().StartKey, rng.Desc().EndKey)
 		if err != nil {
 			log.Errorf("unable to split range %q-%q by prefix map %s", rng.Desc().StartKey, rng.Desc().EndKey, configMap)
 			continue
 		}
-		// Gather new splits.
+		// Gather new splits. Boundaries within the keys which can't be
+		// split, such as the system zone's start amid the first range's
+		// meta1 records, are skipped.
 		for _, split := range splits {
-			if split.end.Less(rng.Desc().EndKey) {
+			if split.end.Less(rng.Desc().EndKey) && engine.IsValidSplitKey(split.end) {
 				splitKeys = append(splitKeys, split.end)
 			}
 		}
@@ -165,12 +165,24 @@ func computeSplitKeys(g *gossip.Gossip, rng *Range) []proto.Key {
 	return unique
 }
 
+// zoneKey returns the key by which the zone config of the range is
+// looked up. This is the range's start key, except for the first
+// range: it holds the meta1 addressing records and, as it can't be
+// split ahead of meta2, always extends into the system keys, whose
+// zone config it takes.
+func zoneKey(desc *proto.RangeDescriptor) proto.Key {
+	if desc.StartKey.Equal(engine.KeyMin) {
+		return engine.KeySystemPrefix
+	}
+	return desc.StartKey
+}
+
 // lookupZoneConfig returns the zone config matching the range.
-func lookupZoneConfig(g *gossip.Gossip, rng *Range) (proto.ZoneConfig, error) {
-	zoneMap, err := g.GetInfo(gossip.KeyConfigZone)
+func lookupZoneConfig(rng *Range) (proto.ZoneConfig, error) {
+	zoneMap, err := rng.rm.systemConfig(gossip.KeyConfigZone)
 	if err != nil || zoneMap == nil {
 		return proto.ZoneConfig{}, util.Errorf("unable to lookup zone config for range %s: %s", rng, err)
 	}
-	prefixConfig := zoneMap.(PrefixConfigMap).MatchByPrefix(rng.Desc().StartKey)
+	prefixConfig := zoneMap.MatchByPrefix(zoneKey(rng.Desc()))
 	return *prefixConfig.Config.(*proto.ZoneConfig), nil
 }
@@ -19,6 +19,7 @@ package storage
 
 import (
 	"math"
+	"reflect"
 	"testing"
 	"time"
 
@@ -59,6 +60,8 @@ func TestSplitQueueShouldQueue(t *testing.T) {
 	if err := tc.gossip.AddInfo(gossip.KeyConfigZone, zoneMap, 0*time.Second); err != nil {
 		t.Fatal(err)
 	}
+	waitForConfig(t, tc.store, gossip.KeyConfigAccounting, acctMap)
+	waitForConfig(t, tc.store, gossip.KeyConfigZone, zoneMap)
 
 	testCases := []struct {
 		start, end proto.Key
@@ -84,7 +87,7 @@ func TestSplitQueueShouldQueue(t *testing.T) {
 		{proto.KeyMin, proto.KeyMax, 64<<20 + 1, true, 2},
 	}
 
-	splitQ := newSplitQueue(nil, tc.gossip)
+	splitQ := newSplitQueue(nil)
 
 	for i, test := range testCases {
 		tc.rng.stats.SetMVCCStats(tc.rng.rm.Engine(), proto.MVCCStats{KeyBytes: test.bytes})
@@ -102,6 +105,55 @@ func TestSplitQueueShouldQueue(t *testing.T) {
 	}
 }
 
+// TestSplitQueueSystemZone verifies that the first range, which can't
+// be split from the system keys, takes their zone config and isn't
+// split at the start of the system zone.
+func TestSplitQueueSystemZone(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	zoneMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{RangeMaxBytes: 64 << 20}},
+		{engine.KeySystemPrefix, nil, &proto.ZoneConfig{RangeMaxBytes: 32 << 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.gossip.AddInfo(gossip.KeyConfigZone, zoneMap, 0*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	waitForConfig(t, tc.store, gossip.KeyConfigZone, zoneMap)
+
+	testCases := []struct {
+		start, end proto.Key
+		splitKeys  []proto.Key
+		maxBytes   int64
+	}{
+		{engine.KeyMin, engine.KeyMax, []proto.Key{engine.KeySystemMax}, 32 << 20},
+		{engine.KeyMin, engine.KeySystemMax, nil, 32 << 20},
+		{engine.KeyMeta2Prefix, engine.KeySystemMax, nil, 32 << 20},
+		{engine.KeySystemMax, engine.KeyMax, nil, 64 << 20},
+	}
+	for i, test := range testCases {
+		copy := *tc.rng.Desc()
+		copy.StartKey = test.start
+		copy.EndKey = test.end
+		tc.rng.SetDesc(&copy)
+		if splitKeys := computeSplitKeys(tc.rng); !reflect.DeepEqual(splitKeys, test.splitKeys) {
+			t.Errorf("%d: expected split keys %v; got %v", i, test.splitKeys, splitKeys)
+		}
+		zone, err := lookupZoneConfig(tc.rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if zone.RangeMaxBytes != test.maxBytes {
+			t.Errorf("%d: expected zone with max bytes %d; got %d", i, test.maxBytes, zone.RangeMaxBytes)
+		}
+	}
+}
+
 ////
 // NOTE: tests which actually verify processing of the split queue are
 // in client_split_test.go, which is in a different test package in
@@ -42,11 +42,23 @@ import (
 const (
 	// UserRoot is the username for the root user.
 	UserRoot = "root"
+	// UserReplication is the username with which a primary cluster
+	// ships its writes to a standby, which admits them while it serves
+	// other users read-only. It's granted the permissions of the root
+	// user, except for admin commands.
+	UserReplication = "replication"
 	// GCResponseCacheExpiration is the expiration duration for response
 	// cache entries.
 	GCResponseCacheExpiration = 1 * time.Hour
 	// raftIDAllocCount is the number of Raft IDs to allocate per allocation.
 	raftIDAllocCount = 10
+	// maxCoalescedRaftEvents is the maximum number of queued raft
+	// events processed together, with a single sync of the engine for
+	// the commands committed among them.
+	maxCoalescedRaftEvents = 64
+	// raftApplyConcurrency is the maximum number of ranges whose
+	// committed raft commands are applied concurrently.
+	raftApplyConcurrency = 8
 	// defaultScanInterval is the default value for the scan interval.
 	// command line flag.
 	defaultRaftTickInterval         = 10 * time.Millisecond
@@ -54,8 +66,26 @@ const (
 	defaultRaftElectionTimeoutTicks = 15
 	// ttlCapacityGossip is time-to-live for capacity-related info.
 	ttlCapacityGossip = 2 * time.Minute
+	// startupLogInterval is the interval at which the progress of
+	// loading a store's replicas is logged.
+	startupLogInterval = 10 * time.Second
 )
 
+// DefaultSnapshotApplyRate is the default number of bytes of incoming
+// snapshot data per second a store admits for application.
+const DefaultSnapshotApplyRate = 32 << 20
+
+// snapshotsShare is the fraction of the provisioned throughput of a
+// store's device admitted for the application of snapshots.
+const snapshotsShare = 0.25
+
+// DefaultScanPrefetchSize is the default number of bytes read ahead by
+// scans of all of a range's data on stores on spinning disks.
+const DefaultScanPrefetchSize = 1 << 20
+
+// hddAttr is the attribute of stores on spinning disks.
+const hddAttr = "hdd"
+
 var (
 	// defaultRangeRetryOptions are default retry options for retrying commands
 	// sent to the store's ranges, for WriteTooOld and WriteIntent errors.
@@ -173,11 +203,18 @@ func (rs RangeSlice) Less(i, j int) bool {
 }
 
 // A NotBootstrappedError indicates that an engine has not yet been
-// bootstrapped due to a store identifier not being present.
-type NotBootstrappedError struct{}
+// bootstrapped due to a store identifier not being present, or
+// because the bootstrap of a new cluster on it was interrupted before
+// completing.
+type NotBootstrappedError struct {
+	Interrupted bool
+}
 
 // Error formats error.
 func (e *NotBootstrappedError) Error() string {
+	if e.Interrupted {
+		return "store bootstrap was interrupted"
+	}
 	return "store has not been bootstrapped"
 }
 
@@ -188,6 +225,21 @@ type StoreDescriptor struct {
 	Attrs    proto.Attributes // store specific attributes (e.g. ssd, hdd, mem)
 	Node     gossip.NodeDescriptor
 	Capacity engine.StoreCapacity
+	// RangeCount is the number of ranges with replicas on the store.
+	RangeCount int
+	// LeaseCount is the number of ranges whose unexpired leader lease
+	// is held by the store's replica.
+	LeaseCount int
+	// Suspect is set if the latency of the store's device degraded or
+	// a scrub found corrupt data on it; replicas aren't allocated to
+	// suspect stores, nor leases transferred to them.
+	Suspect bool
+	// Corruptions are the key spans, and the sstables holding them,
+	// found corrupt by the most recent scrub of the store.
+	Corruptions []engine.Corruption
+	// NearlyFull is set if the store is running out of space; like
+	// suspect stores, nearly full stores receive no replicas or leases.
+	NearlyFull bool
 }
 
 // CombinedAttrs returns the full list of attributes for the store,
@@ -256,15 +308,33 @@ type Store struct {
 	splitQueue     *splitQueue     // Range splitting queue
 	verifyQueue    *verifyQueue    // Checksum verification queue
 	replicateQueue *replicateQueue // Replication queue
+	replicaGCQueue *replicaGCQueue // Replica GC queue
 	scanner        *rangeScanner   // Range scanner
 	multiraft      *multiraft.MultiRaft
 	started        int32
+	opened         int32 // Non-zero once the engine has been opened; updated atomically
+	replicasLoaded int64 // Range descriptors read by Start; updated atomically
+	raftGroups     int64 // Raft groups created since Start; updated atomically
+	readOnly       int32 // Non-zero if the store rejects writes; updated atomically
+	draining       int32 // Non-zero if the store is giving up its leader leases; updated atomically
+	leases         leaseMetrics
+	reads          readMetrics
+	throttled      rateCounter    // Client commands delayed by rate limits
+	zoneLimits     zoneRateLimits // Rate limits of the zones, shared by their ranges
+	contention     *contentionLog // Sample of recent transaction pushes
+	ioHealth       *ioHealth      // Latency of the store's device
+	scrubs         scrubResults   // Corruption found by checksum verification
+	space          diskSpace      // Free space of the store's device
+	hotKeys        *readCache     // Cached values of read-hot keys; nil if disabled
+	configs        *configCache   // Cached system config maps
 	stopper        *util.Stopper
 	status         *proto.StoreStatus
+	raftApplySem   chan struct{} // Limits concurrent application of raft commands
 
-	mu          sync.RWMutex     // Protects variables below...
-	ranges      map[int64]*Range // Map of ranges by Raft ID
-	rangesByKey RangeSlice       // Sorted slice of ranges by StartKey
+	mu          sync.RWMutex           // Protects variables below...
+	ranges      map[int64]*Range       // Map of ranges by Raft ID
+	rangesByKey RangeSlice             // Sorted slice of ranges by StartKey
+	nodeDesc    *gossip.NodeDescriptor // Descriptor of the node, once gossiped
 }
 
 var _ multiraft.Storage = &Store{}
@@ -299,6 +369,84 @@ type StoreContext struct {
 
 	// ScanInterval is the default value for the scan interval
 	ScanInterval time.Duration
+
+	// ClosedTimestampLag is how far behind its clock a range leader
+	// closes timestamps, below which followers may serve
+	// bounded-staleness reads. Leaders publish their closed timestamps
+	// with each command and, for ranges without writes, periodically.
+	// Zero disables closing timestamps.
+	ClosedTimestampLag time.Duration
+
+	// Standby is set on the stores of a standby cluster. Their ranges
+	// apply the non-transactional writes of the replication user at the
+	// timestamps at which the primary committed them, rather than
+	// moving them above the timestamp cache and closed timestamp. A
+	// replicated write which is older than the existing value of its
+	// key has already been superseded and is dropped.
+	Standby bool
+
+	// SnapshotApplyRate is the IO budget, in bytes per second, for
+	// applying snapshots received from other stores. Snapshots beyond
+	// the budget are delayed so that rebalancing doesn't compete with
+	// foreground writes for the engine. Zero leaves snapshots
+	// unthrottled. The budget of a store whose engine declares its
+	// provisioned throughput is instead a share of that throughput.
+	SnapshotApplyRate int64
+
+	// TxnAbandonTimeout is how long after its last heartbeat a pending
+	// transaction is considered abandoned by its coordinator, at which
+	// point the GC queue aborts it and resolves its intents. Timeouts
+	// are at least twice DefaultHeartbeatInterval, as transactions
+	// heartbeat within that are live. Zero disables aborting abandoned
+	// transactions.
+	TxnAbandonTimeout time.Duration
+
+	// Traces, if not nil, samples the commands executed by the stores
+	// of the node for tracing and retains their traces.
+	Traces *TraceLog
+
+	// IOProbeInterval is the interval at which the latency of the
+	// store's device is probed. A store whose probes exceed
+	// MaxSyncLatency or MaxReadLatency is suspect: it's gossiped as
+	// such, so that no replicas are allocated to it, and it transfers
+	// away the leader leases it holds. Zero disables probing.
+	IOProbeInterval time.Duration
+	MaxSyncLatency  time.Duration
+	MaxReadLatency  time.Duration
+
+	// ScrubInterval is the interval at which the checksums of the data
+	// the store's engine holds on disk are verified, if it's a
+	// Scrubber. A store found to hold corrupt data is gossiped as
+	// suspect. Zero disables scrubbing.
+	ScrubInterval time.Duration
+
+	// MinAvailable is the free space, in bytes, below which a
+	// persistent store is nearly full: it's gossiped as such, so that
+	// no replicas are allocated to it, and it refuses writes other than
+	// those which free space, such as deletions and raft log
+	// truncation. Zero disables the check.
+	MinAvailable int64
+
+	// BallastSize is the size of the ballast file kept in the data
+	// directory of a persistent store: space held in reserve, which an
+	// operator may free by deleting the file should the device fill up
+	// regardless. The file is created, or recreated once deleted, only
+	// if MinAvailable bytes remain free. Zero disables the ballast.
+	BallastSize int64
+
+	// ReadCacheSize is the number of keys whose values, as read by
+	// consistent, non-transactional Gets served by the store's range
+	// leaders, are cached to answer later Gets of read-hot keys without
+	// an MVCC read. Cached values are invalidated by any command applied
+	// to their range. Zero disables the cache.
+	ReadCacheSize int
+
+	// ScanPrefetchSize is the number of bytes read ahead, in the
+	// background, by scans of all of a range's data, such as those of
+	// the range scanner's queues and of snapshot generation, on stores
+	// with the hdd attribute. Reading ahead hides part of the seek
+	// latency of spinning disks. Zero disables prefetching.
+	ScanPrefetchSize int
 }
 
 // Valid returns true if the StoreContext is populated correctly.
@@ -336,22 +484,28 @@ func NewStore(ctx StoreContext, eng engine.Engine) *Store {
 
 	sf := newStoreFinder(ctx.Gossip)
 	s := &Store{
-		ctx:         ctx,
-		StoreFinder: sf,
-		engine:      eng,
-		allocator:   newAllocator(sf.findStores),
-		ranges:      map[int64]*Range{},
-		status:      &proto.StoreStatus{},
+		ctx:          ctx,
+		StoreFinder:  sf,
+		engine:       eng,
+		allocator:    newAllocator(sf.findStores),
+		ranges:       map[int64]*Range{},
+		status:       &proto.StoreStatus{},
+		raftApplySem: make(chan struct{}, raftApplyConcurrency),
+		contention:   newContentionLog(contentionLogSize, contentionSampleRate),
+		ioHealth:     newIOHealth(ctx.MaxSyncLatency, ctx.MaxReadLatency),
+		hotKeys:      newReadCache(ctx.ReadCacheSize),
+		configs:      newConfigCache(),
 	}
 
 	// Add range scanner and configure with queues.
 	s.scanner = newRangeScanner(ctx.ScanInterval, newStoreRangeIterator(s), s.
 		updateStoreStatus)
 	s.gcQueue = newGCQueue()
-	s.splitQueue = newSplitQueue(s.ctx.DB, s.ctx.Gossip)
+	s.splitQueue = newSplitQueue(s.ctx.DB)
 	s.verifyQueue = newVerifyQueue(s.scanner.Stats)
-	s.replicateQueue = newReplicateQueue(s.ctx.Gossip, s.allocator, s.ctx.Clock)
-	s.scanner.AddQueues(s.gcQueue, s.splitQueue, s.verifyQueue, s.replicateQueue)
+	s.replicateQueue = newReplicateQueue(s.allocator, s.ctx.Clock)
+	s.replicaGCQueue = newReplicaGCQueue()
+	s.scanner.AddQueues(s.gcQueue, s.splitQueue, s.verifyQueue, s.replicateQueue, s.replicaGCQueue)
 
 	return s
 
@@ -367,6 +521,45 @@ func (s *Store) IsStarted() bool {
 	return atomic.LoadInt32(&s.started) == 1
 }
 
+// StartupProgress reports how far a store has gotten in recovering
+// its state, so that a store which is slow to start after a crash can
+// be told apart from one which is hung.
+type StartupProgress struct {
+	// Opened is true once the store's engine has been opened.
+	Opened bool
+	// ReplicasLoaded is the number of replicas whose range descriptors
+	// have been read from the engine.
+	ReplicasLoaded int64
+	// RaftGroupsRecovered is the number of raft groups whose state has
+	// been loaded. Groups are created on demand, so this keeps growing
+	// after the store has started.
+	RaftGroupsRecovered int64
+	// Started is true once the store is serving traffic.
+	Started bool
+}
+
+// StartupProgress returns the store's startup progress.
+func (s *Store) StartupProgress() StartupProgress {
+	return StartupProgress{
+		Opened:              atomic.LoadInt32(&s.opened) == 1,
+		ReplicasLoaded:      atomic.LoadInt64(&s.replicasLoaded),
+		RaftGroupsRecovered: atomic.LoadInt64(&s.raftGroups),
+		Started:             s.IsStarted(),
+	}
+}
+
+// SetScanInterval changes the interval in which the store's range
+// scanner completes a full scan, without restarting the store.
+func (s *Store) SetScanInterval(interval time.Duration) {
+	s.scanner.SetInterval(interval)
+}
+
+// BalanceReport returns a report of the balance of the stores known to
+// this one through gossip against its allocator's thresholds.
+func (s *Store) BalanceReport() (BalanceReport, error) {
+	return s.allocator.balance(proto.Attributes{})
+}
+
 // Start the engine, set the GC and read the StoreIdent.
 func (s *Store) Start(stopper *util.Stopper) error {
 	s.stopper = stopper
@@ -387,7 +580,17 @@ func (s *Store) Start(stopper *util.Stopper) error {
 		} else if !ok {
 			return &NotBootstrappedError{}
 		}
+		// A store whose bootstrap was interrupted may be missing its
+		// initial range and must be bootstrapped again.
+		if pending, err := s.bootstrapPending(); err != nil {
+			return err
+		} else if pending {
+			s.Ident = proto.StoreIdent{}
+			return &NotBootstrappedError{Interrupted: true}
+		}
 	}
+	atomic.StoreInt32(&s.opened, 1)
+	log.Infof("store %s: opened engine; loading replicas", s)
 
 	// Create ID allocators.
 	idAlloc, err := NewIDAllocator(engine.KeyRaftIDGenerator, s.ctx.DB, 2 /* min ID */, raftIDAllocCount, s.stopper)
@@ -416,6 +619,7 @@ func (s *Store) Start(stopper *util.Stopper) error {
 		ElectionTimeoutTicks:   s.ctx.RaftElectionTimeoutTicks,
 		HeartbeatIntervalTicks: s.ctx.RaftHeartbeatIntervalTicks,
 		EntryFormatter:         raftEntryFormatter,
+		SnapshotRate:           s.snapshotApplyRate(),
 	}); err != nil {
 		return err
 	}
@@ -424,6 +628,8 @@ func (s *Store) Start(stopper *util.Stopper) error {
 	// (consistent=false). Uncommitted intents which have been abandoned
 	// due to a split crashing halfway will simply be resolved on the
 	// next split attempt. They can otherwise be ignored.
+	loadStart := time.Now()
+	lastLog := loadStart
 	if err := engine.MVCCIterate(s.engine, start, end, now, false, nil, func(kv proto.KeyValue) (bool, error) {
 		// Only consider range metadata entries; ignore others.
 		_, suffix, _ := engine.DecodeRangeKey(kv.Key)
@@ -444,6 +650,11 @@ func (s *Store) Start(stopper *util.Stopper) error {
 		if err != nil {
 			return false, err
 		}
+		loaded := atomic.AddInt64(&s.replicasLoaded, 1)
+		if time.Since(lastLog) >= startupLogInterval {
+			log.Infof("store %s: loaded %d replicas", s, loaded)
+			lastLog = time.Now()
+		}
 		// Note that we do not create raft groups at this time; they will be created
 		// on-demand the first time they are needed. This helps reduce the amount of
 		// election-related traffic in a cold start.
@@ -458,20 +669,28 @@ func (s *Store) Start(stopper *util.Stopper) error {
 	}
 	// Sort the rangesByKey slice after they've all been added.
 	sort.Sort(s.rangesByKey)
+	log.Infof("store %s: loaded %d replicas in %s", s, atomic.LoadInt64(&s.replicasLoaded),
+		time.Since(loadStart))
 
 	// Start Raft processing goroutines.
 	s.multiraft.Start(s.stopper)
 	s.processRaft()
+	s.publishClosedTimestamps()
+	s.monitorIOHealth()
+	s.monitorScrub()
+	s.monitorDiskSpace()
 
 	// Start the scanner.
 	s.scanner.Start(s.ctx.Clock, s.stopper)
 
-	// Register callbacks for any changes to accounting and zone
-	// configurations; we split ranges along prefix boundaries.
-	// Gossip is only ever nil for unittests.
+	// Register callbacks for any changes to the system configurations;
+	// they're cached by the store, and we split ranges along accounting
+	// and zone prefix boundaries. Gossip is only ever nil for unittests.
 	if s.ctx.Gossip != nil {
-		s.ctx.Gossip.RegisterCallback(gossip.KeyConfigAccounting, s.configGossipUpdate)
-		s.ctx.Gossip.RegisterCallback(gossip.KeyConfigZone, s.configGossipUpdate)
+		for _, key := range configGossipKeys {
+			s.ctx.Gossip.RegisterCallback(key, s.configGossipUpdate)
+		}
+		s.ctx.Gossip.RegisterCallback(gossip.KeyConfigBalance, s.balanceGossipUpdate)
 		// Callback triggers on capacity gossip from all stores.
 		capacityRegex := gossip.MakePrefixPattern(gossip.KeyMaxAvailCapacityPrefix)
 		s.ctx.Gossip.RegisterCallback(capacityRegex, s.capacityGossipUpdate)
@@ -488,8 +707,9 @@ func (s *Store) Start(stopper *util.Stopper) error {
 	return nil
 }
 
-// configGossipUpdate is a callback for gossip updates to
-// configuration maps which affect range split boundaries.
+// configGossipUpdate is a callback for gossip updates to the system
+// configuration maps. The store's cached copy of the map is replaced;
+// accounting and zone configs also affect range split boundaries.
 func (s *Store) configGossipUpdate(key string, contentsChanged bool) {
 	if !contentsChanged {
 		return // Skip update if it's just a newer timestamp or fewer hops to info
@@ -504,14 +724,55 @@ func (s *Store) configGossipUpdate(key string, contentsChanged bool) {
 		log.Errorf("gossiped info is not a prefix configuration map: %+v", info)
 		return
 	}
+	version := s.configs.update(key, configMap, s.ctx.Clock.PhysicalNow())
+	log.V(1).Infof("store %s: cached %s config version %d", s, key, version)
+	if key == gossip.KeyConfigPermission {
+		return
+	}
 	s.maybeSplitRangesByConfigs(configMap)
 
-	// If the zone configs changed, run through ranges and set max bytes.
+	// If the zone configs changed, run through ranges and set max
+	// bytes and rate limits.
 	if key == gossip.KeyConfigZone {
-		s.setRangesMaxBytes(configMap)
+		s.setRangesZoneLimits(configMap)
 	}
 }
 
+// balanceGossipUpdate is a callback for gossip updates to the cluster's
+// balance thresholds, which it applies to the store's allocator.
+func (s *Store) balanceGossipUpdate(key string, contentsChanged bool) {
+	if !contentsChanged {
+		return
+	}
+	info, err := s.ctx.Gossip.GetInfo(key)
+	if err != nil {
+		log.Errorf("unable to fetch balance config from gossip: %s", err)
+		return
+	}
+	config, ok := info.(*proto.BalanceConfig)
+	if !ok {
+		log.Errorf("gossiped info is not a balance config: %+v", info)
+		return
+	}
+	s.allocator.setBalanceThresholds(BalanceThresholds{
+		RangeCount: config.RangeCountThreshold,
+		Bytes:      config.BytesThreshold,
+	})
+	log.V(1).Infof("store %s: balance thresholds set to %s", s, config)
+}
+
+// snapshotApplyRate returns the IO budget for applying snapshots: a
+// share of the provisioned throughput of the store's device, if it's
+// declared, and StoreContext.SnapshotApplyRate otherwise.
+func (s *Store) snapshotApplyRate() int64 {
+	if p, ok := s.engine.(engine.Provisioned); ok {
+		if throughput := p.Provisioning().Throughput(); throughput > 0 {
+			return int64(float64(throughput) * snapshotsShare)
+		}
+	}
+	return s.ctx.SnapshotApplyRate
+}
+
 // GossipCapacity broadcasts the node's capacity on the gossip network.
 func (s *Store) GossipCapacity(n *gossip.NodeDescriptor) {
 	storeDesc, err := s.Descriptor(n)
@@ -519,6 +780,9 @@ func (s *Store) GossipCapacity(n *gossip.NodeDescriptor) {
 		log.Warningf("problem getting store descriptor for store %+v: %v", s.Ident, err)
 		return
 	}
+	s.mu.Lock()
+	s.nodeDesc = n
+	s.mu.Unlock()
 	// Unique gossip key per store.
 	keyMaxCapacity := gossip.MakeMaxAvailCapacityKey(storeDesc.Node.NodeID, storeDesc.StoreID)
 	// Gossip store descriptor.
@@ -556,33 +820,58 @@ func (s *Store) ForceReplicationScan() {
 	}
 }
 
-// setRangesMaxBytes sets the max bytes for every range according
-// to the zone configs.
+// setRangesZoneLimits sets the max bytes, rate limits and commit
+// coalescing window of every range according to the zone configs.
 //
 // TODO(spencer): scanning all ranges with the lock held could cause
 // perf issues if the number of ranges grows large enough.
-func (s *Store) setRangesMaxBytes(zoneMap PrefixConfigMap) {
+func (s *Store) setRangesZoneLimits(zoneMap PrefixConfigMap) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.zoneLimits.update(zoneMap)
 	zone := zoneMap[0].Config.(*proto.ZoneConfig)
 	idx := 0
 	// Note that we must iterate through the ranges in lexicographic
 	// order to match the ordering of the zoneMap.
 	for _, rng := range s.rangesByKey {
-		if idx < len(zoneMap)-1 && !rng.Desc().StartKey.Less(zoneMap[idx+1].Prefix) {
+		for idx < len(zoneMap)-1 && !zoneKey(rng.Desc()).Less(zoneMap[idx+1].Prefix) {
 			idx++
 			zone = zoneMap[idx].Config.(*proto.ZoneConfig)
 		}
 		rng.SetMaxBytes(zone.RangeMaxBytes)
+		rng.setRateLimits(s.zoneLimits.get(zoneMap[idx]))
+		rng.SetCommitCoalescingWindow(time.Duration(zone.CommitCoalescingWindowMicros) * time.Microsecond)
 	}
 }
 
 // Bootstrap writes a new store ident to the underlying engine. To
 // ensure that no crufty data already exists in the engine, it scans
 // the engine contents before writing the new store ident. The engine
-// should be completely empty. It returns an error if called on a
-// non-empty engine.
+// should be completely empty, unless a bootstrap begun with
+// BeginBootstrap was interrupted, in which case its contents are
+// cleared. It returns an error if called on any other non-empty
+// engine.
 func (s *Store) Bootstrap(ident proto.StoreIdent, stopper *util.Stopper) error {
+	return s.bootstrap(ident, stopper, false)
+}
+
+// BeginBootstrap is like Bootstrap, but atomically marks the bootstrap
+// as in progress along with writing the store ident. Until the mark is
+// removed by FinishBootstrap, the store refuses to start with a
+// NotBootstrappedError and may be bootstrapped again. It's used to
+// bootstrap a new cluster, which takes several steps after the ident
+// is written.
+func (s *Store) BeginBootstrap(ident proto.StoreIdent, stopper *util.Stopper) error {
+	return s.bootstrap(ident, stopper, true)
+}
+
+// FinishBootstrap removes the mark written by BeginBootstrap, after
+// which the store may be restarted.
+func (s *Store) FinishBootstrap() error {
+	return engine.MVCCDelete(s.engine, nil, engine.StoreBootstrapKey(), proto.ZeroTimestamp, nil)
+}
+
+func (s *Store) bootstrap(ident proto.StoreIdent, stopper *util.Stopper, mark bool) error {
 	if s.Ident.NodeID != 0 {
 		return util.Errorf("engine already bootstrapped")
 	}
@@ -590,23 +879,48 @@ func (s *Store) Bootstrap(ident proto.StoreIdent, stopper *util.Stopper) error {
 		return err
 	}
 	stopper.AddCloser(s.engine)
-	s.Ident = ident
 	kvs, err := engine.Scan(s.engine, proto.EncodedKey(engine.KeyMin), proto.EncodedKey(engine.KeyMax), 1)
 	if err != nil {
 		return util.Errorf("store %s: unable to access: %s", s.engine, err)
 	} else if len(kvs) > 0 {
-		// See if this is an already-bootstrapped store.
-		ok, err := engine.MVCCGetProto(s.engine, engine.StoreIdentKey(), proto.ZeroTimestamp, true, nil, &s.Ident)
-		if err != nil {
-			return util.Errorf("store %s is non-empty but cluster ID could not be determined: %s", s.engine, err)
+		if pending, err := s.bootstrapPending(); err != nil {
+			return err
+		} else if pending {
+			log.Warningf("store %s: clearing the contents of an interrupted bootstrap", s.engine)
+			if _, err := engine.ClearRange(s.engine, proto.EncodedKey(engine.KeyMin),
+				proto.EncodedKey(engine.KeyMax)); err != nil {
+				return util.Errorf("store %s: unable to clear interrupted bootstrap: %s", s.engine, err)
+			}
+		} else {
+			// See if this is an already-bootstrapped store.
+			ok, err := engine.MVCCGetProto(s.engine, engine.StoreIdentKey(), proto.ZeroTimestamp, true, nil, &s.Ident)
+			if err != nil {
+				return util.Errorf("store %s is non-empty but cluster ID could not be determined: %s", s.engine, err)
+			}
+			if ok {
+				return util.Errorf("store %s already belongs to cockroach cluster %s", s.engine, s.Ident.ClusterID)
+			}
+			return util.Errorf("store %s is not-empty and has invalid contents (first key: %q)", s.engine, kvs[0].Key)
 		}
-		if ok {
-			return util.Errorf("store %s already belongs to cockroach cluster %s", s.engine, s.Ident.ClusterID)
+	}
+	s.Ident = ident
+	batch := s.engine.NewBatch()
+	if mark {
+		if err := engine.MVCCPutProto(batch, nil, engine.StoreBootstrapKey(), proto.ZeroTimestamp, nil, &s.Ident); err != nil {
+			return err
 		}
-		return util.Errorf("store %s is not-empty and has invalid contents (first key: %q)", s.engine, kvs[0].Key)
 	}
-	err = engine.MVCCPutProto(s.engine, nil, engine.StoreIdentKey(), proto.ZeroTimestamp, nil, &s.Ident)
-	return err
+	if err := engine.MVCCPutProto(batch, nil, engine.StoreIdentKey(), proto.ZeroTimestamp, nil, &s.Ident); err != nil {
+		return err
+	}
+	return batch.Commit()
+}
+
+// bootstrapPending returns whether the engine holds the mark of an
+// interrupted bootstrap.
+func (s *Store) bootstrapPending() (bool, error) {
+	var ident proto.StoreIdent
+	return engine.MVCCGetProto(s.engine, engine.StoreBootstrapKey(), proto.ZeroTimestamp, true, nil, &ident)
 }
 
 // GetRange fetches a range by Raft ID. Returns an error if no range is found.
@@ -761,6 +1075,133 @@ func (s *Store) Gossip() *gossip.Gossip { return s.ctx.Gossip }
 // SplitQueue accessor.
 func (s *Store) SplitQueue() *splitQueue { return s.splitQueue }
 
+func (s *Store) leaseMetrics() *leaseMetrics { return &s.leases }
+
+func (s *Store) readMetrics() *readMetrics { return &s.reads }
+
+func (s *Store) throttledCmds() *rateCounter { return &s.throttled }
+
+func (s *Store) readCache() *readCache { return s.hotKeys }
+
+// scanPrefetchSize returns the number of bytes read ahead by scans of
+// all of a range's data: StoreContext.ScanPrefetchSize if the store is
+// on a spinning disk, as given by the hdd attribute, and zero
+// otherwise.
+func (s *Store) scanPrefetchSize() int {
+	for _, attr := range s.Attrs().Attrs {
+		if attr == hddAttr {
+			return s.ctx.ScanPrefetchSize
+		}
+	}
+	return 0
+}
+
+// closedTimestampLag returns the lag of the timestamps closed by range
+// leaders on this store.
+func (s *Store) closedTimestampLag() time.Duration { return s.ctx.ClosedTimestampLag }
+
+// standby returns whether the store belongs to a standby cluster.
+func (s *Store) standby() bool { return s.ctx.Standby }
+
+// txnAbandonTimeout returns how long after its last heartbeat a
+// pending transaction is considered abandoned, or zero if abandoned
+// transactions aren't aborted.
+func (s *Store) txnAbandonTimeout() time.Duration {
+	timeout := s.ctx.TxnAbandonTimeout
+	if timeout > 0 && timeout < 2*DefaultHeartbeatInterval {
+		timeout = 2 * DefaultHeartbeatInterval
+	}
+	return timeout
+}
+
+// IOSuspect returns whether the latency of the store's device
+// degraded beyond the thresholds of the store's context.
+func (s *Store) IOSuspect() bool { return s.ioHealth.isSuspect() }
+
+// IOLatency returns the latencies measured by the most recent probe
+// of the store's device.
+func (s *Store) IOLatency() IOLatency { return s.ioHealth.latency() }
+
+// ReadOnly returns whether the store is in read-only mode.
+func (s *Store) ReadOnly() bool { return atomic.LoadInt32(&s.readOnly) != 0 }
+
+// SetReadOnly puts the store into or takes it out of read-only mode.
+// A read-only store rejects all commands other than reads and those
+// which push transactions or resolve their intents, transfers away the
+// leader leases it holds and doesn't request new ones, but continues
+// to apply the commands committed by the raft groups of its ranges and
+// to send snapshots to their other replicas. This is useful while
+// investigating a node or the health of its storage.
+func (s *Store) SetReadOnly(readOnly bool) {
+	var v int32
+	if readOnly {
+		v = 1
+	}
+	if atomic.SwapInt32(&s.readOnly, v) != v {
+		log.Infof("store %s read-only mode set to %t", s, readOnly)
+		if readOnly {
+			s.TransferLeaderLeases()
+		}
+	}
+}
+
+// allowedReadOnly returns whether a write may be executed by a
+// read-only store: pushing, heartbeating and resolving the intents of
+// transactions, without which the intents on its ranges could never
+// be cleaned up.
+func allowedReadOnly(args proto.Request) bool {
+	switch args.(type) {
+	case *proto.InternalPushTxnRequest, *proto.InternalHeartbeatTxnRequest,
+		*proto.InternalResolveIntentRequest:
+		return true
+	}
+	return false
+}
+
+// readOnlyError returns the error with which a read-only store rejects
+// a write to the range: a NotLeaderError naming another replica, the
+// lease holder if known, so that the client retries the write there,
+// or, if the store holds the range's only replica, an error stating
+// that the store is read-only.
+func (s *Store) readOnlyError(raftID int64, args proto.Request) error {
+	if rng, err := s.GetRange(raftID); err == nil {
+		other := -1
+		l := rng.getLease()
+		for i, replica := range rng.Desc().Replicas {
+			if replica.StoreID == s.StoreID() {
+				continue
+			}
+			if l != nil && l.RaftNodeID == uint64(MakeRaftNodeID(replica.NodeID, replica.StoreID)) {
+				return &proto.NotLeaderError{Leader: replica}
+			}
+			if other < 0 {
+				other = i
+			}
+		}
+		if other >= 0 {
+			return &proto.NotLeaderError{Leader: rng.Desc().Replicas[other]}
+		}
+	}
+	return util.Errorf("store %s is in read-only mode; cannot execute %s", s, args.Method())
+}
+
+// Draining returns whether the store is draining.
+func (s *Store) Draining() bool { return atomic.LoadInt32(&s.draining) != 0 }
+
+// SetDraining puts the store into or takes it out of draining mode. A
+// draining store doesn't request leader leases for its ranges, so that
+// the leases transferred away by TransferLeaderLeases aren't
+// reacquired before the store is shut down.
+func (s *Store) SetDraining(draining bool) {
+	var v int32
+	if draining {
+		v = 1
+	}
+	if atomic.SwapInt32(&s.draining, v) != v {
+		log.Infof("store %s draining mode set to %t", s, draining)
+	}
+}
+
 // NewRangeDescriptor creates a new descriptor based on start and end
 // keys and the supplied proto.Replicas slice. It allocates new Raft
 // and range IDs to fill out the supplied replicas.
@@ -793,6 +1234,14 @@ func (s *Store) SplitRange(origRng, newRng *Range) error {
 	if err != nil {
 		return err
 	}
+	// The new range may lie in a different zone than the original.
+	if zoneMap, err := s.systemConfig(gossip.KeyConfigZone); err == nil && zoneMap != nil {
+		pc := zoneMap.MatchByPrefix(zoneKey(newRng.Desc()))
+		zone := pc.Config.(*proto.ZoneConfig)
+		newRng.SetMaxBytes(zone.RangeMaxBytes)
+		newRng.setRateLimits(s.zoneLimits.get(pc))
+		newRng.SetCommitCoalescingWindow(time.Duration(zone.CommitCoalescingWindowMicros) * time.Microsecond)
+	}
 	if err := s.startGroup(newRng.Desc().RaftID); err != nil {
 		return err
 	}
@@ -909,6 +1358,196 @@ func (s *Store) Capacity() (engine.StoreCapacity, error) {
 	return s.engine.Capacity()
 }
 
+// StoreMetrics holds counters and gauges describing a store's ranges
+// and the progress of its range scanner.
+type StoreMetrics struct {
+	RangeCount     int   // Number of ranges in the store
+	ReadOnly       bool  // Whether the store is in read-only mode
+	IOSuspect      bool  // Whether the latency of the store's device degraded
+	NearlyFull     bool  // Whether the store is running out of space
+	ScanCount      int64 // Number of complete scans of the store's ranges
+	ScanOverBudget int64 // Number of range visits which exceeded their time budget
+	ScanSkipped    int64 // Number of range visits skipped for exceeding their budget
+	LeasesHeld     int   // Number of unexpired leader leases held by the store's replicas
+	// LeaseAcquisitions, LeaseTransfers and LeaseErrors are the total
+	// numbers of leases acquired by the store's replicas, of those
+	// acquired from other replicas, and of failed lease requests and
+	// commands rejected for want of a lease. The PerMinute fields count
+	// those of the last minute.
+	LeaseAcquisitions          int64
+	LeaseAcquisitionsPerMinute int64
+	LeaseTransfers             int64
+	LeaseTransfersPerMinute    int64
+	LeaseErrors                int64
+	LeaseErrorsPerMinute       int64
+	// MVCC is the aggregation of MVCC stats across all ranges as of the
+	// most recent complete scan.
+	MVCC proto.MVCCStats
+	// ConfigVersions are the versions of the store's cached system
+	// config maps, keyed by gossip key.
+	ConfigVersions map[string]ConfigVersion
+	// AbandonedTxns is the number of transactions found abandoned by
+	// their coordinators and aborted by the GC queue, and
+	// AbandonedIntents the number of their intents sent for resolution.
+	AbandonedTxns    int64
+	AbandonedIntents int64
+	// ThrottledCmds is the number of client commands delayed by the
+	// rate limits of their zones, in total and over the last minute.
+	ThrottledCmds          int64
+	ThrottledCmdsPerMinute int64
+	// ReplicasGCed is the number of replicas destroyed by the replica GC
+	// queue after their removal from their ranges.
+	ReplicasGCed int64
+	// LocalReads is the number of reads served from the state of the
+	// store's replicas without going through Raft, ConsensusReads the
+	// number proposed to Raft as they required CONSENSUS, and
+	// UnexpectedRaftReads the number of other reads applied through
+	// Raft, which should always be zero.
+	LocalReads          int64
+	ConsensusReads      int64
+	UnexpectedRaftReads int64
+	// ReadCacheHits and ReadCacheMisses are the numbers of Gets answered
+	// from the store's read cache and of those looked up in it and read
+	// from the engine; ReadCacheHitRate is the fraction of the lookups
+	// of the last minute which hit. All are zero without a read cache.
+	ReadCacheHits    int64
+	ReadCacheMisses  int64
+	ReadCacheHitRate float64
+	// Engine counts the reads served by the store's engine, if it's a
+	// ReadCounter; it's zero otherwise.
+	Engine engine.ReadStats
+	// IOLatency holds the latencies measured by the most recent probe
+	// of the store's device.
+	IOLatency IOLatency
+	// Scrubs is the number of completed scrubs of the store, and
+	// Corruptions the number of corrupt key ranges found by the most
+	// recent one.
+	Scrubs      int64
+	Corruptions int
+}
+
+// Metrics returns the store's current metrics.
+func (s *Store) Metrics() StoreMetrics {
+	now := time.Now()
+	wallTime := s.ctx.Clock.PhysicalNow()
+	raftNodeID := uint64(s.RaftNodeID())
+	s.mu.RLock()
+	rangeCount := len(s.ranges)
+	var leasesHeld int
+	for _, r := range s.ranges {
+		if l := r.getLease(); l != nil && l.RaftNodeID == raftNodeID && l.Expiration > wallTime {
+			leasesHeld++
+		}
+	}
+	s.mu.RUnlock()
+	var readStats engine.ReadStats
+	if r, ok := s.engine.(engine.ReadCounter); ok {
+		readStats = r.ReadStats()
+	}
+	cacheHits, cacheMisses, cacheHitRate := s.hotKeys.metrics(now)
+	s.scrubs.Lock()
+	scrubs, corruptions := s.scrubs.count, len(s.scrubs.corruptions)
+	s.scrubs.Unlock()
+	return StoreMetrics{
+		RangeCount:                 rangeCount,
+		ReadOnly:                   s.ReadOnly(),
+		IOSuspect:                  s.IOSuspect(),
+		NearlyFull:                 s.NearlyFull(),
+		ScanCount:                  s.scanner.Count(),
+		ScanOverBudget:             s.scanner.OverBudget(),
+		ScanSkipped:                s.scanner.Skipped(),
+		LeasesHeld:                 leasesHeld,
+		LeaseAcquisitions:          s.leases.acquisitions.Total(),
+		LeaseAcquisitionsPerMinute: s.leases.acquisitions.Rate(now),
+		LeaseTransfers:             s.leases.transfers.Total(),
+		LeaseTransfersPerMinute:    s.leases.transfers.Rate(now),
+		LeaseErrors:                s.leases.errors.Total(),
+		LeaseErrorsPerMinute:       s.leases.errors.Rate(now),
+		MVCC:                       s.scanner.Stats().MVCC,
+		ConfigVersions:             s.configs.versions(),
+		AbandonedTxns:              atomic.LoadInt64(&s.gcQueue.abandonedTxns),
+		AbandonedIntents:           atomic.LoadInt64(&s.gcQueue.abandonedIntents),
+		ThrottledCmds:              s.throttled.Total(),
+		ThrottledCmdsPerMinute:     s.throttled.Rate(now),
+		ReplicasGCed:               atomic.LoadInt64(&s.replicaGCQueue.removed),
+		LocalReads:                 s.reads.local.Total(),
+		ConsensusReads:             s.reads.consensus.Total(),
+		UnexpectedRaftReads:        s.reads.unexpected.Total(),
+		ReadCacheHits:              cacheHits,
+		ReadCacheMisses:            cacheMisses,
+		ReadCacheHitRate:           cacheHitRate,
+		Engine:                     readStats,
+		IOLatency:                  s.IOLatency(),
+		Scrubs:                     scrubs,
+		Corruptions:                corruptions,
+	}
+}
+
+// ContentionEvents returns a sample of the most recent pushes of
+// transactions whose intents conflicted with commands sent to the
+// store, oldest first.
+func (s *Store) ContentionEvents() []ContentionEvent {
+	return s.contention.Events()
+}
+
+// A RangeStatus describes a range replica of a store, the leader lease
+// of the range as known to the replica and the range's recent splits
+// and merges.
+type RangeStatus struct {
+	StoreID  proto.StoreID `json:"store_id"`
+	RaftID   int64         `json:"raft_id"`
+	StartKey proto.Key     `json:"start_key"`
+	EndKey   proto.Key     `json:"end_key"`
+	// LeaseNodeID and LeaseStoreID identify the replica holding the
+	// lease; they are zero if no lease has been granted.
+	LeaseNodeID  proto.NodeID  `json:"lease_node_id"`
+	LeaseStoreID proto.StoreID `json:"lease_store_id"`
+	LeaseTerm    uint64        `json:"lease_term"`
+	// LeaseExpiration is the wall time, in unix nanos, at which the lease
+	// expires.
+	LeaseExpiration int64 `json:"lease_expiration"`
+	LeaseExpired    bool  `json:"lease_expired"`
+	// ClosedTimestamp is the timestamp up to which the replica may
+	// serve bounded-staleness reads.
+	ClosedTimestamp proto.Timestamp `json:"closed_timestamp"`
+	// History holds the most recent splits and merges in which the
+	// range took part, oldest first.
+	History []proto.RangeHistoryEvent `json:"history"`
+}
+
+// RangeStatuses returns the status of each of the store's ranges, in
+// key order.
+func (s *Store) RangeStatuses() []RangeStatus {
+	wallTime := s.ctx.Clock.PhysicalNow()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	statuses := make([]RangeStatus, 0, len(s.rangesByKey))
+	for _, r := range s.rangesByKey {
+		desc := r.Desc()
+		status := RangeStatus{
+			StoreID:  s.StoreID(),
+			RaftID:   desc.RaftID,
+			StartKey: desc.StartKey,
+			EndKey:   desc.EndKey,
+
+			ClosedTimestamp: r.ClosedTimestamp(),
+		}
+		if l := r.getLease(); l != nil {
+			status.LeaseNodeID, status.LeaseStoreID = DecodeRaftNodeID(multiraft.NodeID(l.RaftNodeID))
+			status.LeaseTerm = l.Term
+			status.LeaseExpiration = l.Expiration
+			status.LeaseExpired = l.Expiration <= wallTime
+		}
+		if hist, err := r.GetHistory(); err != nil {
+			log.Warningf("unable to fetch history of range %d: %s", desc.RaftID, err)
+		} else {
+			status.History = hist.Events
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
 // Descriptor returns a StoreDescriptor including current store
 // capacity information.
 func (s *Store) Descriptor(nodeDesc *gossip.NodeDescriptor) (*StoreDescriptor, error) {
@@ -916,12 +1555,29 @@ func (s *Store) Descriptor(nodeDesc *gossip.NodeDescriptor) (*StoreDescriptor, e
 	if err != nil {
 		return nil, err
 	}
+	wallTime := s.ctx.Clock.PhysicalNow()
+	s.mu.RLock()
+	rangeCount := len(s.ranges)
+	leaseCount := 0
+	for _, r := range s.ranges {
+		if l := r.getLease(); l != nil && l.Expiration > wallTime {
+			if _, storeID := DecodeRaftNodeID(multiraft.NodeID(l.RaftNodeID)); storeID == s.Ident.StoreID {
+				leaseCount++
+			}
+		}
+	}
+	s.mu.RUnlock()
 	// Initialize the store descriptor.
 	return &StoreDescriptor{
-		StoreID:  s.Ident.StoreID,
-		Attrs:    s.Attrs(),
-		Node:     *nodeDesc,
-		Capacity: capacity,
+		StoreID:     s.Ident.StoreID,
+		Attrs:       s.Attrs(),
+		Node:        *nodeDesc,
+		Capacity:    capacity,
+		RangeCount:  rangeCount,
+		LeaseCount:  leaseCount,
+		Suspect:     s.IOSuspect() || s.Corrupt(),
+		Corruptions: s.Corruptions(),
+		NearlyFull:  s.NearlyFull(),
 	}, nil
 }
 
@@ -929,12 +1585,25 @@ func (s *Store) Descriptor(nodeDesc *gossip.NodeDescriptor) (*StoreDescriptor, e
 // method, args & reply into a Raft Cmd struct and executes the
 // command using the fetched range.
 func (s *Store) ExecuteCmd(args proto.Request, reply proto.Response) error {
+	trace := s.ctx.Traces.start(s.StoreID(), args)
+	defer func() { s.ctx.Traces.finish(trace, reply.Header().GoError()) }()
+
 	// If the request has a zero timestamp, initialize to this node's clock.
 	header := args.Header()
 	if err := verifyKeys(header.Key, header.EndKey); err != nil {
 		reply.Header().SetGoError(err)
 		return err
 	}
+	if s.ReadOnly() && !proto.IsReadOnly(args) && !allowedReadOnly(args) {
+		err := s.readOnlyError(header.RaftID, args)
+		reply.Header().SetGoError(err)
+		return err
+	}
+	if s.NearlyFull() && !proto.IsReadOnly(args) && !allowedNearlyFull(args) {
+		err := util.Errorf("store %s is nearly full; cannot execute %s", s, args.Method())
+		reply.Header().SetGoError(err)
+		return err
+	}
 	if header.Timestamp.Equal(proto.ZeroTimestamp) {
 		// Update the incoming timestamp if unset.
 		header.Timestamp = s.ctx.Clock.Now()
@@ -964,7 +1633,15 @@ func (s *Store) ExecuteCmd(args proto.Request, reply proto.Response) error {
 			return util.RetryBreak, err
 		}
 
+		trace.eventf("executing at %s", header.Timestamp)
 		if err = rng.AddCmd(args, reply, true); err == nil {
+			trace.eventf("executed")
+			return util.RetryBreak, nil
+		}
+		trace.eventf("failed: %s", err)
+		if _, ok := err.(*proto.WriteIntentError); ok && failsOnIntents(args) {
+			// The client asked to learn of intents rather than wait for
+			// their transactions to be pushed.
 			return util.RetryBreak, nil
 		}
 
@@ -976,21 +1653,31 @@ func (s *Store) ExecuteCmd(args proto.Request, reply proto.Response) error {
 
 		switch t := err.(type) {
 		case *proto.WriteTooOldError:
+			if isReplicatedWrite(rng.rm, args) {
+				// A newer version was shipped before this one, which
+				// has thus been superseded.
+				reply.Header().SetGoError(nil)
+				trace.eventf("superseded by existing write")
+				return util.RetryBreak, nil
+			}
 			// Update request timestamp and retry immediately.
 			header.Timestamp = t.ExistingTimestamp
 			header.Timestamp.Logical++
+			trace.eventf("retrying above existing write")
 			return util.RetryReset, nil
 		case *proto.WriteIntentError:
 			// If write intent error is resolved, exit retry/backoff loop to
 			// immediately retry.
 			if t.Resolved {
+				trace.eventf("resolved conflicting intent of txn %q; retrying", t.Txn.Name)
 				return util.RetryReset, nil
 			}
 			// Otherwise, update timestamp on read/write and backoff / retry.
-			if proto.IsWrite(args) && header.Timestamp.Less(t.Txn.Timestamp) {
+			if proto.IsWrite(args) && !isReplicatedWrite(rng.rm, args) && header.Timestamp.Less(t.Txn.Timestamp) {
 				header.Timestamp = t.Txn.Timestamp
 				header.Timestamp.Logical++
 			}
+			trace.eventf("unable to push txn %q of conflicting intent; backing off", t.Txn.Name)
 			return util.RetryContinue, nil
 		}
 		return util.RetryBreak, nil
@@ -1006,6 +1693,14 @@ func (s *Store) ExecuteCmd(args proto.Request, reply proto.Response) error {
 	return reply.Header().GoError()
 }
 
+// failsOnIntents returns true if args is a scan with the
+// FAIL_ON_INTENTS policy, whose write intent errors are returned to
+// the client without pushing the conflicting transaction.
+func failsOnIntents(args proto.Request) bool {
+	sArgs, ok := args.(*proto.ScanRequest)
+	return ok && sArgs.IntentPolicy == proto.FAIL_ON_INTENTS
+}
+
 // maybeResolveWriteIntentError checks the reply's error. If the error
 // is a writeIntentError, it tries to push the conflicting
 // transaction: either move its timestamp forward on a read/write
@@ -1037,7 +1732,16 @@ func (s *Store) maybeResolveWriteIntentError(rng *Range, args proto.Request, rep
 	}
 	pushReply := &proto.InternalPushTxnResponse{}
 	s.ctx.DB.Run(client.Call{Args: pushArgs, Reply: pushReply})
-	if pushErr := pushReply.GoError(); pushErr != nil {
+	pushErr := pushReply.GoError()
+	outcome := ContentionPushed
+	if pushErr != nil {
+		outcome = ContentionFailed
+	} else if pushArgs.Abort {
+		outcome = ContentionAborted
+	}
+	s.contention.record(newContentionEvent(s.StoreID(), rng.Desc().RaftID, wiErr.Key, args.Header(),
+		&wiErr.Txn, pushArgs.Abort, outcome))
+	if pushErr != nil {
 		log.V(1).Infof("push %q failed: %s", pushArgs.Header().Key, pushErr)
 
 		// For write/write conflicts within a transaction, propagate the
@@ -1090,7 +1794,7 @@ func (s *Store) startGroup(raftID int64) error {
 // mean that it has been applied to the range yet).
 func (s *Store) ProposeRaftCommand(idKey cmdIDKey, cmd proto.InternalRaftCommand) <-chan error {
 	value := cmd.Cmd.GetValue()
-	if value == nil {
+	if value == nil && cmd.ClosedTimestamp.Equal(proto.ZeroTimestamp) && len(cmd.Coalesced) == 0 {
 		panic("proposed a nil command")
 	}
 	// Lazily create group. TODO(bdarnell): make this non-lazy
@@ -1143,78 +1847,85 @@ func (s *Store) processRaft() {
 		for {
 			select {
 			case e := <-s.multiraft.Events:
-				var cmd proto.InternalRaftCommand
-				var groupID int64
-				var commandID string
-				var index uint64
-				var callback func(error)
-
-				switch e := e.(type) {
-				case *multiraft.EventCommandCommitted:
-					groupID = int64(e.GroupID)
-					commandID = e.CommandID
-					index = e.Index
-					err := gogoproto.Unmarshal(e.Command, &cmd)
-					if err != nil {
-						log.Fatal(err)
+				// Coalesce the events which are already queued, so that the
+				// writes of the commands committed among them are synced
+				// together.
+				events := []interface{}{e}
+			coalesce:
+				for len(events) < maxCoalescedRaftEvents {
+					select {
+					case e := <-s.multiraft.Events:
+						events = append(events, e)
+					default:
+						break coalesce
 					}
+				}
+				s.applyRaftEvents(events)
 
-				case *multiraft.EventMembershipChangeCommitted:
-					groupID = int64(e.GroupID)
-					commandID = e.CommandID
-					index = e.Index
-					callback = e.Callback
-					err := gogoproto.Unmarshal(e.Payload, &cmd)
-					if err != nil {
-						log.Fatal(err)
-					}
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
 
-				case *multiraft.EventLeaderElection:
-					if e.NodeID == 0 {
-						// Election in progress.
-						continue
-					}
-					// Only the store housing the election winner gets to
-					// proceed.
-					groupID = int64(e.GroupID)
-					r, err := s.GetRange(groupID)
-					if err != nil {
-						log.Warning(err)
-						continue
-					}
-					// TODO(tschottdorf): remove this once we have the whole
-					// range lazily start up and the response cache moved to
-					// the correct location to deduplicate multiraft
-					// reproposals (at the time of writing commands can be
-					// executed multiple times if issued during an election).
-					r.election <- struct{}{}
-
-					// Done with this event, go back to listening on the channel.
-					continue
-
-				default:
-					continue
+// publishClosedTimestamps periodically has the leaders of the store's
+// ranges publish their closed timestamps, so that followers may serve
+// bounded-staleness reads of ranges which aren't being written to.
+func (s *Store) publishClosedTimestamps() {
+	lag := s.ctx.ClosedTimestampLag
+	if lag <= 0 {
+		return
+	}
+	s.stopper.RunWorker(func() {
+		ticker := time.NewTicker(lag / closedTimestampPublications)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.mu.RLock()
+				ranges := append([]*Range(nil), s.rangesByKey...)
+				s.mu.RUnlock()
+				for _, r := range ranges {
+					r.maybePublishClosedTimestamp()
 				}
+			case <-s.stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
 
-				if groupID != cmd.RaftID {
-					log.Fatalf("e.GroupID (%d) should == cmd.RaftID (%d)", groupID, cmd.RaftID)
+// monitorIOHealth periodically probes the latency of the store's
+// device. When the store becomes suspect or healthy again, its
+// descriptor is gossiped right away; while it's suspect, it transfers
+// away the leader leases it holds.
+func (s *Store) monitorIOHealth() {
+	interval := s.ctx.IOProbeInterval
+	if interval <= 0 {
+		return
+	}
+	var dir string
+	if d, ok := s.engine.(engine.DirEngine); ok {
+		dir = d.Dir()
+	}
+	identKey := engine.MVCCEncodeKey(engine.StoreIdentKey())
+	s.stopper.RunWorker(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l, err := probeIO(dir, s.engine, identKey)
+				if err != nil {
+					log.Warningf("store %s: io probe failed: %s", s, err)
 				}
-
-				s.mu.Lock()
-				r, ok := s.ranges[groupID]
-				s.mu.Unlock()
-				var err error
-				if !ok {
-					err = util.Errorf("got committed raft command for %d but have no range with that ID: %+v",
-						groupID, cmd)
-					log.Error(err)
-				} else {
-					err = r.processRaftCommand(cmdIDKey(commandID), index, cmd)
+				if s.ioHealth.record(l, err) {
+					s.ioHealthChanged()
 				}
-				if callback != nil {
-					callback(err)
+				if s.IOSuspect() {
+					s.TransferLeaderLeases()
 				}
-
 			case <-s.stopper.ShouldStop():
 				return
 			}
@@ -1222,6 +1933,305 @@ func (s *Store) processRaft() {
 	})
 }
 
+// ioHealthChanged logs a change of the store's health and gossips its
+// descriptor, if the node's descriptor is known yet.
+func (s *Store) ioHealthChanged() {
+	if s.IOSuspect() {
+		log.Warningf("store %s is suspect: io latency %+v exceeds sync threshold %s or read threshold %s",
+			s, s.IOLatency(), s.ctx.MaxSyncLatency, s.ctx.MaxReadLatency)
+	} else {
+		log.Infof("store %s is healthy again: io latency %+v", s, s.IOLatency())
+	}
+	s.mu.RLock()
+	n := s.nodeDesc
+	s.mu.RUnlock()
+	if n != nil && s.ctx.Gossip != nil {
+		s.GossipCapacity(n)
+	}
+}
+
+// TransferLeaderLeases transfers each unexpired leader lease held by
+// the store to another replica of its range on a store known through
+// gossip not to be suspect or nearly full. Leases of ranges without such a replica
+// are kept. Transfers complete once committed by the ranges' raft
+// groups; the number of leases for which a transfer was proposed is
+// returned.
+func (s *Store) TransferLeaderLeases() int {
+	wallTime := s.ctx.Clock.PhysicalNow()
+	raftNodeID := uint64(s.RaftNodeID())
+	s.mu.RLock()
+	ranges := append([]*Range(nil), s.rangesByKey...)
+	s.mu.RUnlock()
+	var transferred int
+	for _, r := range ranges {
+		l := r.getLease()
+		if l == nil || l.RaftNodeID != raftNodeID || l.Expiration <= wallTime {
+			continue
+		}
+		for _, replica := range r.Desc().Replicas {
+			if replica.StoreID == s.StoreID() {
+				continue
+			}
+			if desc, err := s.allocator.findStore(replica.StoreID); err != nil || desc.Suspect || desc.NearlyFull {
+				continue
+			}
+			log.Infof("store %s: transferring leader lease of range %d to store %d",
+				s, r.Desc().RaftID, replica.StoreID)
+			r.transferLeaderLease(l, replica)
+			transferred++
+			break
+		}
+	}
+	return transferred
+}
+
+// LeaseholderRanges returns the ranges, in key order, whose unexpired
+// leader lease is held by the store.
+func (s *Store) LeaseholderRanges() []*Range {
+	wallTime := s.ctx.Clock.PhysicalNow()
+	raftNodeID := uint64(s.RaftNodeID())
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ranges []*Range
+	for _, r := range s.rangesByKey {
+		if l := r.getLease(); l != nil && l.RaftNodeID == raftNodeID && l.Expiration > wallTime {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// A raftEvent is a multiraft event for a single range: either a
+// committed command or notice of the range's election as leader.
+type raftEvent struct {
+	groupID   int64
+	commandID string
+	index     uint64
+	cmd       proto.InternalRaftCommand
+	callback  func(error)
+	election  bool
+}
+
+// newRaftEvent decodes a multiraft event, returning nil for events
+// which require no processing.
+func newRaftEvent(e interface{}) *raftEvent {
+	re := &raftEvent{}
+	switch e := e.(type) {
+	case *multiraft.EventCommandCommitted:
+		re.groupID = int64(e.GroupID)
+		re.commandID = e.CommandID
+		re.index = e.Index
+		err := gogoproto.Unmarshal(e.Command, &re.cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case *multiraft.EventMembershipChangeCommitted:
+		re.groupID = int64(e.GroupID)
+		re.commandID = e.CommandID
+		re.index = e.Index
+		re.callback = e.Callback
+		err := gogoproto.Unmarshal(e.Payload, &re.cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case *multiraft.EventLeaderElection:
+		if e.NodeID == 0 {
+			// Election in progress.
+			return nil
+		}
+		re.groupID = int64(e.GroupID)
+		re.election = true
+		return re
+
+	default:
+		return nil
+	}
+
+	if re.groupID != re.cmd.RaftID {
+		log.Fatalf("e.GroupID (%d) should == cmd.RaftID (%d)", re.groupID, re.cmd.RaftID)
+	}
+	return re
+}
+
+// isBarrier returns whether the event must be applied on its own,
+// after all preceding and before all following events. This is the
+// case for membership changes and for commands with commit triggers,
+// which may split or merge ranges and so affect other ranges' events.
+func (re *raftEvent) isBarrier() bool {
+	if re.election {
+		return false
+	}
+	if re.callback != nil {
+		return true
+	}
+	et, ok := re.cmd.Cmd.GetValue().(*proto.EndTransactionRequest)
+	return ok && et.InternalCommitTrigger != nil
+}
+
+// applyRaftEvents applies consecutive multiraft events. The events of
+// each range are applied in order, but those of different ranges are
+// applied concurrently, with at most raftApplyConcurrency ranges at a
+// time, between barrier events. Each command is acknowledged once the
+// engine has been synced after all commands were applied.
+func (s *Store) applyRaftEvents(events []interface{}) {
+	// Split the events into segments, each of which is a barrier or the
+	// events between two barriers, and the segments by range.
+	var segments [][][]*raftEvent
+	var pending []*raftEvent
+	for _, e := range events {
+		re := newRaftEvent(e)
+		if re == nil {
+			continue
+		}
+		if !re.isBarrier() {
+			pending = append(pending, re)
+			continue
+		}
+		if len(pending) > 0 {
+			segments = append(segments, groupRaftEventsByRange(pending))
+			pending = nil
+		}
+		segments = append(segments, [][]*raftEvent{{re}})
+	}
+	if len(pending) > 0 {
+		segments = append(segments, groupRaftEventsByRange(pending))
+	}
+	if len(segments) == 0 {
+		return
+	}
+
+	// If all events are for a single range, the last command is applied
+	// with a sync of the engine, sparing a separate sync.
+	if len(segments) == 1 && len(segments[0]) == 1 {
+		for _, ack := range s.applyRangeRaftEvents(segments[0][0], true) {
+			ack()
+		}
+		return
+	}
+
+	var acks []func()
+	for _, segment := range segments {
+		rangeAcks := make([][]func(), len(segment))
+		var wg sync.WaitGroup
+		for i, rangeEvents := range segment {
+			wg.Add(1)
+			s.raftApplySem <- struct{}{}
+			go func(i int, rangeEvents []*raftEvent) {
+				defer func() {
+					<-s.raftApplySem
+					wg.Done()
+				}()
+				rangeAcks[i] = s.applyRangeRaftEvents(rangeEvents, false)
+			}(i, rangeEvents)
+		}
+		wg.Wait()
+		for _, a := range rangeAcks {
+			acks = append(acks, a...)
+		}
+	}
+	if len(acks) > 0 {
+		if err := s.engine.NewBatch().CommitSync(); err != nil {
+			log.Errorf("failed to sync engine: %s", err)
+		}
+	}
+	// Commands are only acknowledged once they are durable.
+	for _, ack := range acks {
+		ack()
+	}
+}
+
+// groupRaftEventsByRange groups events by range, preserving the order
+// of each range's events.
+func groupRaftEventsByRange(events []*raftEvent) [][]*raftEvent {
+	var groups [][]*raftEvent
+	indexes := map[int64]int{}
+	for _, re := range events {
+		i, ok := indexes[re.groupID]
+		if !ok {
+			i = len(groups)
+			indexes[re.groupID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], re)
+	}
+	return groups
+}
+
+// applyRangeRaftEvents applies the events of a single range in order,
+// returning a function acknowledging each command. If syncLast is
+// true, the engine is synced with the last command; the functions
+// must otherwise only be invoked once the engine has been synced.
+func (s *Store) applyRangeRaftEvents(events []*raftEvent, syncLast bool) []func() {
+	last := -1
+	for i, re := range events {
+		if !re.election {
+			last = i
+		}
+	}
+	var acks []func()
+	for i, re := range events {
+		if re.election {
+			s.processRaftElection(re.groupID)
+			continue
+		}
+		acks = append(acks, s.processRaftCommand(re, syncLast && i == last))
+	}
+	return acks
+}
+
+// processRaftElection notifies the range for groupID that this store
+// won its election.
+func (s *Store) processRaftElection(groupID int64) {
+	// Only the store housing the election winner gets to
+	// proceed.
+	r, err := s.GetRange(groupID)
+	if err != nil {
+		log.Warning(err)
+		return
+	}
+	// TODO(tschottdorf): remove this once we have the whole
+	// range lazily start up and the response cache moved to
+	// the correct location to deduplicate multiraft
+	// reproposals (at the time of writing commands can be
+	// executed multiple times if issued during an election).
+	r.election <- struct{}{}
+}
+
+// processRaftCommand applies a committed command to its range, syncing
+// the engine if sync is true, and returns a function acknowledging the
+// command. The function must only be invoked once the command's writes
+// are durable.
+func (s *Store) processRaftCommand(re *raftEvent, sync bool) func() {
+	s.mu.Lock()
+	r, ok := s.ranges[re.groupID]
+	s.mu.Unlock()
+	var err error
+	var done func()
+	if !ok {
+		err = util.Errorf("got committed raft command for %d but have no range with that ID: %+v",
+			re.groupID, re.cmd)
+		log.Error(err)
+		// Make the commands applied before this one durable.
+		if sync {
+			if sErr := s.engine.NewBatch().CommitSync(); sErr != nil {
+				log.Errorf("failed to sync engine: %s", sErr)
+			}
+		}
+	} else {
+		done, err = r.processRaftCommand(cmdIDKey(re.commandID), re.index, re.cmd, sync)
+	}
+	return func() {
+		if done != nil {
+			done()
+		}
+		if re.callback != nil {
+			re.callback(err)
+		}
+	}
+}
+
 // GroupStorage implements the multiraft.Storage interface.
 func (s *Store) GroupStorage(groupID uint64) multiraft.WriteableGroupStorage {
 	s.mu.Lock()
@@ -1246,6 +2256,8 @@ func (s *Store) GroupStorage(groupID uint64) multiraft.WriteableGroupStorage {
 }
 
 // AppliedIndex implements the multiraft.StateMachine interface.
+// MultiRaft calls it once for each group it creates, which is counted
+// as a recovered raft group.
 func (s *Store) AppliedIndex(groupID uint64) (uint64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -1253,6 +2265,7 @@ func (s *Store) AppliedIndex(groupID uint64) (uint64, error) {
 	if !ok {
 		return 0, util.Errorf("range %d not found", groupID)
 	}
+	atomic.AddInt64(&s.raftGroups, 1)
 	return atomic.LoadUint64(&r.appliedIndex), nil
 }
 
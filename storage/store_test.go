@@ -23,7 +23,9 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -190,6 +192,70 @@ func TestBootstrapOfNonEmptyStore(t *testing.T) {
 	}
 }
 
+// TestStoreInterruptedBootstrap verifies that a store whose bootstrap
+// was interrupted refuses to start, and that it may be bootstrapped
+// again.
+func TestStoreInterruptedBootstrap(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	ctx := TestStoreContext
+	manual := hlc.NewManualClock(0)
+	ctx.Clock = hlc.NewClock(manual.UnixNano)
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	ctx.Transport = multiraft.NewLocalRPCTransport()
+	stopper := util.NewStopper()
+	stopper.AddCloser(ctx.Transport)
+	defer stopper.Stop()
+
+	// Begin a bootstrap, but don't finish it.
+	store := NewStore(ctx, eng)
+	if err := store.BeginBootstrap(testIdent, stopper); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.BootstrapRange(); err != nil {
+		t.Fatal(err)
+	}
+	store = NewStore(ctx, eng)
+	if err := store.Start(stopper); err == nil {
+		t.Fatal("expected failure starting store with interrupted bootstrap")
+	} else if nbErr, ok := err.(*NotBootstrappedError); !ok || !nbErr.Interrupted {
+		t.Fatalf("expected interrupted bootstrap error; got %v", err)
+	}
+
+	// Bootstrapping again clears the contents of the interrupted
+	// bootstrap.
+	ident := testIdent
+	ident.ClusterID = "cluster-2"
+	if err := store.BeginBootstrap(ident, stopper); err != nil {
+		t.Fatal(err)
+	}
+	var desc proto.RangeDescriptor
+	descKey := engine.RangeDescriptorKey(engine.KeyMin)
+	if ok, err := engine.MVCCGetProto(eng, descKey, ctx.Clock.Now(), true, nil, &desc); err != nil || ok {
+		t.Errorf("expected range of interrupted bootstrap to be cleared; got %t, %v", ok, err)
+	}
+	if err := store.BootstrapRange(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.FinishBootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	store = NewStore(ctx, eng)
+	if err := store.Start(stopper); err != nil {
+		t.Fatalf("failure starting bootstrapped store: %s", err)
+	}
+	if store.Ident.ClusterID != ident.ClusterID {
+		t.Errorf("expected cluster ID %q; got %q", ident.ClusterID, store.Ident.ClusterID)
+	}
+	if _, err := store.GetRange(1); err != nil {
+		t.Errorf("failure fetching 1st range: %s", err)
+	}
+
+	// A completely bootstrapped store can't be bootstrapped again.
+	if err := NewStore(ctx, eng).Bootstrap(testIdent, stopper); err == nil {
+		t.Error("expected bootstrap error on bootstrapped store")
+	}
+}
+
 func TestRangeSliceSort(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	var rs RangeSlice
@@ -381,6 +447,56 @@ func TestStoreExecuteCmd(t *testing.T) {
 	}
 }
 
+// TestStoreReplicatedWrites verifies that a standby store applies the
+// writes of the replication user at their own timestamps, below the
+// timestamp cache, and drops those superseded by newer versions, while
+// other writes are moved above the timestamp cache.
+func TestStoreReplicatedWrites(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, manual, stopper := createTestStore(t)
+	defer stopper.Stop()
+	store.ctx.Standby = true
+	manual.Set((10 * time.Second).Nanoseconds())
+
+	gArgs, gReply := getArgs([]byte("a"), 1, store.StoreID())
+	gArgs.Timestamp = makeTS((9 * time.Second).Nanoseconds(), 0)
+	if err := store.ExecuteCmd(gArgs, gReply); err != nil {
+		t.Fatal(err)
+	}
+	put := func(value string, ts proto.Timestamp, user string) proto.Timestamp {
+		pArgs, pReply := putArgs([]byte("a"), []byte(value), 1, store.StoreID())
+		pArgs.Timestamp = ts
+		pArgs.User = user
+		if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+			t.Fatal(err)
+		}
+		return pReply.Timestamp
+	}
+	get := func(ts proto.Timestamp) *proto.Value {
+		v, err := engine.MVCCGet(store.Engine(), proto.Key("a"), ts, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	ts5 := makeTS((5 * time.Second).Nanoseconds(), 0)
+	if ts := put("5", ts5, UserReplication); !ts.Equal(ts5) {
+		t.Errorf("expected replicated write at %s; got %s", ts5, ts)
+	}
+	if v := get(ts5); v == nil || string(v.Bytes) != "5" {
+		t.Errorf("expected replicated value at %s; got %+v", ts5, v)
+	}
+	// An older version shipped after a newer one is dropped.
+	put("3", makeTS((3*time.Second).Nanoseconds(), 0), UserReplication)
+	if v := get(makeTS((9 * time.Second).Nanoseconds(), 0)); v == nil || string(v.Bytes) != "5" {
+		t.Errorf("expected superseded write to be dropped; got %+v", v)
+	}
+	if ts := put("6", makeTS((6*time.Second).Nanoseconds(), 0), UserRoot); !gArgs.Timestamp.Less(ts) {
+		t.Errorf("expected write of root to be moved above %s; got %s", gArgs.Timestamp, ts)
+	}
+}
+
 // TestStoreVerifyKeys checks that key length is enforced and
 // that end keys must sort >= start.
 func TestStoreVerifyKeys(t *testing.T) {
@@ -725,6 +841,251 @@ func TestStoreResolveWriteIntent(t *testing.T) {
 	}
 }
 
+// TestStoreContentionEvents verifies that pushes of conflicting
+// transactions are recorded with their priorities and outcomes.
+func TestStoreContentionEvents(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	store.contention = newContentionLog(10, 1)
+
+	for i, resolvable := range []bool{true, false} {
+		key := proto.Key(fmt.Sprintf("key-%d", i))
+		pusher := newTransaction("pusher", key, 1, proto.SERIALIZABLE, store.ctx.Clock)
+		pushee := newTransaction("pushee", key, 1, proto.SERIALIZABLE, store.ctx.Clock)
+		pushee.Priority = 2
+		pusher.Priority = 1
+		if resolvable {
+			pusher.Priority = 3
+		}
+		pArgs, pReply := putArgs(key, []byte("value"), 1, store.StoreID())
+		pArgs.Timestamp = store.ctx.Clock.Now()
+		pArgs.Txn = pushee
+		if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+			t.Fatal(err)
+		}
+		pArgs.Timestamp = store.ctx.Clock.Now()
+		pArgs.Txn = pusher
+		store.ExecuteCmd(pArgs, pReply)
+	}
+
+	events := store.ContentionEvents()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 contention events; got %+v", events)
+	}
+	for i, outcome := range []string{ContentionAborted, ContentionFailed} {
+		e := events[i]
+		if key := proto.Key(fmt.Sprintf("key-%d", i)); !e.Key.Equal(key) {
+			t.Errorf("%d: expected key %q; got %q", i, key, e.Key)
+		}
+		if e.PusherName != "pusher" || e.PusheeName != "pushee" || e.PusheePriority != 2 || !e.Abort {
+			t.Errorf("%d: unexpected event %+v", i, e)
+		}
+		if e.Outcome != outcome {
+			t.Errorf("%d: expected outcome %s; got %s", i, outcome, e.Outcome)
+		}
+	}
+}
+
+// TestStoreTraces verifies that sampled commands are traced with the
+// steps of their execution, including the push of a conflicting
+// transaction.
+func TestStoreTraces(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	store.ctx.Traces = NewTraceLog(10, 1)
+
+	key := proto.Key("a")
+	pushee := newTransaction("pushee", key, 1, proto.SERIALIZABLE, store.ctx.Clock)
+	pusher := newTransaction("pusher", key, 1, proto.SERIALIZABLE, store.ctx.Clock)
+	pushee.Priority = 1
+	pusher.Priority = 2
+	for _, txn := range []*proto.Transaction{pushee, pusher} {
+		pArgs, pReply := putArgs(key, []byte("value"), 1, store.StoreID())
+		pArgs.Timestamp = store.ctx.Clock.Now()
+		pArgs.Txn = txn
+		if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	traces := store.ctx.Traces.Traces()
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 traces; got %+v", traces)
+	}
+	trace := traces[1]
+	if trace.Method != "Put" || trace.TxnName != "pusher" || !trace.Key.Equal(key) || trace.Error != "" {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+	var resolved bool
+	for _, e := range trace.Events {
+		if strings.HasPrefix(e.Message, "resolved conflicting intent") {
+			resolved = true
+		}
+	}
+	if !resolved || trace.Events[len(trace.Events)-1].Message != "executed" {
+		t.Errorf("expected trace of push and execution; got %+v", trace.Events)
+	}
+
+	store.ctx.Traces.SetSampleRate(0)
+	gArgs, gReply := getArgs(key, 1, store.StoreID())
+	store.ExecuteCmd(gArgs, gReply)
+	if traces := store.ctx.Traces.Traces(); len(traces) != 2 {
+		t.Errorf("expected unsampled command not to be traced; got %d traces", len(traces))
+	}
+}
+
+// TestStoreReadOnly verifies that a read-only store rejects writes,
+// redirecting them to another replica of the range where there is
+// one, but pushes transactions and resolves their intents.
+func TestStoreReadOnly(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	key := proto.Key("a")
+	pushee := newTransaction("pushee", key, 1, proto.SERIALIZABLE, store.ctx.Clock)
+	pushee.Priority = 1
+	pArgs, pReply := putArgs(key, []byte("value"), 1, store.StoreID())
+	pArgs.Txn = pushee
+	pArgs.Timestamp = pushee.Timestamp
+	if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+
+	store.SetReadOnly(true)
+	pArgs, pReply = putArgs(proto.Key("b"), []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(pArgs, pReply); err == nil {
+		t.Error("expected put to read-only store to fail")
+	} else if _, ok := err.(*proto.NotLeaderError); ok {
+		t.Errorf("expected put to the only replica to fail without redirection; got %s", err)
+	}
+
+	hArgs, hReply := heartbeatArgs(pushee, 1, store.StoreID())
+	hArgs.Timestamp = store.ctx.Clock.Now()
+	if err := store.ExecuteCmd(hArgs, hReply); err != nil {
+		t.Errorf("expected heartbeat by read-only store to succeed; got %s", err)
+	}
+	pusher := newTransaction("pusher", key, 1, proto.SERIALIZABLE, store.ctx.Clock)
+	pusher.Priority = 2
+	pushArgs, pushReply := pushTxnArgs(pusher, pushee, true, 1, store.StoreID())
+	if err := store.ExecuteCmd(pushArgs, pushReply); err != nil {
+		t.Fatalf("expected push by read-only store to succeed; got %s", err)
+	}
+	rArgs := &proto.InternalResolveIntentRequest{
+		RequestHeader: proto.RequestHeader{
+			Timestamp: pushReply.PusheeTxn.Timestamp,
+			Key:       key,
+			RaftID:    1,
+			Replica:   proto.Replica{StoreID: store.StoreID()},
+			Txn:       pushReply.PusheeTxn,
+		},
+	}
+	if err := store.ExecuteCmd(rArgs, &proto.InternalResolveIntentResponse{}); err != nil {
+		t.Errorf("expected intent resolution by read-only store to succeed; got %s", err)
+	}
+
+	// With another replica of the range, writes are redirected to it.
+	rng, err := store.GetRange(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := gogoproto.Clone(rng.Desc()).(*proto.RangeDescriptor)
+	desc.Replicas = append(desc.Replicas, proto.Replica{NodeID: 2, StoreID: 2})
+	rng.SetDesc(desc)
+	pArgs, pReply = putArgs(proto.Key("b"), []byte("value"), 1, store.StoreID())
+	if err := store.ExecuteCmd(pArgs, pReply); err == nil {
+		t.Error("expected put to read-only store to fail")
+	} else if nlErr, ok := err.(*proto.NotLeaderError); !ok || nlErr.Leader.StoreID != 2 {
+		t.Errorf("expected put to be redirected to store 2; got %v", err)
+	}
+}
+
+// TestStoreReadCache verifies that repeated Gets are answered from the
+// read cache until the key's range is written, and that Gets below the
+// timestamp of a cached read or within transactions aren't.
+func TestStoreReadCache(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+	store.hotKeys = newReadCache(10)
+
+	key := proto.Key("a")
+	put := func(value string) {
+		pArgs, pReply := putArgs(key, []byte(value), 1, store.StoreID())
+		pArgs.Timestamp = store.ctx.Clock.Now()
+		if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(timestamp proto.Timestamp, txn *proto.Transaction) string {
+		gArgs, gReply := getArgs(key, 1, store.StoreID())
+		gArgs.Timestamp = timestamp
+		gArgs.Txn = txn
+		if err := store.ExecuteCmd(gArgs, gReply); err != nil {
+			t.Fatal(err)
+		}
+		if gReply.Value == nil {
+			return ""
+		}
+		return string(gReply.Value.Bytes)
+	}
+	expectMetrics := func(hits, misses int64) {
+		if m := store.Metrics(); m.ReadCacheHits != hits || m.ReadCacheMisses != misses {
+			t.Errorf("expected %d hits and %d misses; got %d and %d", hits, misses,
+				m.ReadCacheHits, m.ReadCacheMisses)
+		}
+	}
+
+	put("1")
+	readAt := store.ctx.Clock.Now()
+	if v := get(readAt, nil); v != "1" {
+		t.Errorf("expected value 1; got %q", v)
+	}
+	if v := get(store.ctx.Clock.Now(), nil); v != "1" {
+		t.Errorf("expected cached value 1; got %q", v)
+	}
+	expectMetrics(1, 1)
+	if rate := store.Metrics().ReadCacheHitRate; rate != 0.5 {
+		t.Errorf("expected hit rate 0.5; got %f", rate)
+	}
+
+	// Reads below the cached read and transactional reads are served by
+	// the engine.
+	get(proto.Timestamp{WallTime: readAt.WallTime, Logical: readAt.Logical - 1}, nil)
+	expectMetrics(1, 2)
+	get(store.ctx.Clock.Now(), newTransaction("test", key, 1, proto.SERIALIZABLE, store.ctx.Clock))
+	expectMetrics(1, 2)
+
+	// A write to the range invalidates the cached value.
+	put("2")
+	if v := get(store.ctx.Clock.Now(), nil); v != "2" {
+		t.Errorf("expected value 2 after write; got %q", v)
+	}
+	expectMetrics(1, 3)
+
+	// A read below the key's newest version isn't cached, so reads at
+	// or above that version see it.
+	t1, t2 := store.ctx.Clock.Now(), store.ctx.Clock.Now()
+	pArgs, pReply := putArgs(key, []byte("3"), 1, store.StoreID())
+	pArgs.Timestamp = t2
+	if err := store.ExecuteCmd(pArgs, pReply); err != nil {
+		t.Fatal(err)
+	}
+	if v := get(t1, nil); v != "2" {
+		t.Errorf("expected value 2 below the newest version; got %q", v)
+	}
+	if v := get(t2, nil); v != "3" {
+		t.Errorf("expected value 3 at the newest version; got %q", v)
+	}
+	expectMetrics(1, 5)
+	if v := get(t2, nil); v != "3" {
+		t.Errorf("expected cached value 3; got %q", v)
+	}
+	expectMetrics(2, 5)
+}
+
 // TestStoreResolveWriteIntentRollback verifies that resolving a write
 // intent by aborting it yields the previous value.
 func TestStoreResolveWriteIntentRollback(t *testing.T) {
@@ -1052,6 +1413,57 @@ func TestStoreReadInconsistent(t *testing.T) {
 	}
 }
 
+// TestStoreScanIntentPolicies verifies that scans which fail on
+// intents return write intent errors without pushing the conflicting
+// transaction, and that scans which skip intents report the keys
+// holding them.
+func TestStoreScanIntentPolicies(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	keyA := proto.Key("a")
+	keyB := proto.Key("b")
+	args, reply := putArgs(keyA, []byte("value1"), 1, store.StoreID())
+	if err := store.ExecuteCmd(args, reply); err != nil {
+		t.Fatal(err)
+	}
+	args.Key = keyB
+	if err := store.ExecuteCmd(args, reply); err != nil {
+		t.Fatal(err)
+	}
+	txn := newTransaction("test", keyA, 1, proto.SERIALIZABLE, store.ctx.Clock)
+	args.Key = keyA
+	args.Value.Bytes = []byte("value2")
+	args.Timestamp = txn.Timestamp
+	args.Txn = txn
+	if err := store.ExecuteCmd(args, reply); err != nil {
+		t.Fatal(err)
+	}
+
+	sArgs, sReply := scanArgs(keyA, proto.KeyMax, 1, store.StoreID())
+	sArgs.IntentPolicy = proto.FAIL_ON_INTENTS
+	err := store.ExecuteCmd(sArgs, sReply)
+	if wiErr, ok := err.(*proto.WriteIntentError); !ok || wiErr.Resolved || !wiErr.Key.Equal(keyA) {
+		t.Fatalf("expected unresolved write intent error on %q; got %v", keyA, err)
+	}
+	if events := store.ContentionEvents(); len(events) != 0 {
+		t.Errorf("expected no pushes; got %+v", events)
+	}
+
+	sArgs, sReply = scanArgs(keyA, proto.KeyMax, 1, store.StoreID())
+	sArgs.IntentPolicy = proto.SKIP_INTENTS
+	if err := store.ExecuteCmd(sArgs, sReply); err != nil {
+		t.Fatal(err)
+	}
+	if len(sReply.Rows) != 1 || !sReply.Rows[0].Key.Equal(keyB) {
+		t.Errorf("expected a row for %q; got %+v", keyB, sReply.Rows)
+	}
+	if len(sReply.SkippedIntents) != 1 || !sReply.SkippedIntents[0].Equal(keyA) {
+		t.Errorf("expected intent on %q to be skipped; got %q", keyA, sReply.SkippedIntents)
+	}
+}
+
 func TestRaftNodeID(t *testing.T) {
 	defer leaktest.AfterTest(t)
 	cases := []struct {
@@ -1096,3 +1508,95 @@ func TestRaftNodeID(t *testing.T) {
 		}()
 	}
 }
+
+// TestGroupRaftEventsByRange verifies that raft events are grouped by
+// range in order of each range's first event, preserving the order of
+// each range's events, and that only commands which may change other
+// ranges are applied as barriers.
+func TestGroupRaftEventsByRange(t *testing.T) {
+	events := []*raftEvent{
+		{groupID: 1, index: 10},
+		{groupID: 2, index: 11},
+		{groupID: 1, index: 12},
+		{groupID: 3, election: true},
+		{groupID: 2, index: 13},
+	}
+	groups := groupRaftEventsByRange(events)
+	expected := [][]uint64{{10, 12}, {11, 13}, {0}}
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %d groups; got %d", len(expected), len(groups))
+	}
+	for i, group := range groups {
+		var indexes []uint64
+		for _, re := range group {
+			indexes = append(indexes, re.index)
+		}
+		if !reflect.DeepEqual(indexes, expected[i]) {
+			t.Errorf("%d: expected indexes %v; got %v", i, expected[i], indexes)
+		}
+	}
+
+	put := &raftEvent{groupID: 1}
+	put.cmd.Cmd.SetValue(&proto.PutRequest{})
+	commit := &raftEvent{groupID: 1}
+	commit.cmd.Cmd.SetValue(&proto.EndTransactionRequest{})
+	split := &raftEvent{groupID: 1}
+	split.cmd.Cmd.SetValue(&proto.EndTransactionRequest{
+		InternalCommitTrigger: &proto.InternalCommitTrigger{SplitTrigger: &proto.SplitTrigger{}},
+	})
+	membership := &raftEvent{groupID: 1, callback: func(error) {}}
+	election := &raftEvent{groupID: 1, election: true}
+	for i, c := range []struct {
+		re      *raftEvent
+		barrier bool
+	}{
+		{put, false},
+		{commit, false},
+		{split, true},
+		{membership, true},
+		{election, false},
+	} {
+		if b := c.re.isBarrier(); b != c.barrier {
+			t.Errorf("%d: expected barrier %t; got %t", i, c.barrier, b)
+		}
+	}
+}
+
+// provisionedEngine declares the provisioned throughput of the engine
+// it wraps.
+type provisionedEngine struct {
+	engine.Engine
+	provisioning engine.Provisioning
+}
+
+func (e *provisionedEngine) Provisioning() engine.Provisioning {
+	return e.provisioning
+}
+
+// TestStoreSnapshotApplyRate verifies that a store whose engine
+// declares its provisioned throughput applies snapshots at a share of
+// it, and at the rate of its context otherwise.
+func TestStoreSnapshotApplyRate(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	ctx := TestStoreContext
+	ctx.Clock = hlc.NewClock(hlc.NewManualClock(0).UnixNano)
+	ctx.Transport = multiraft.NewLocalRPCTransport()
+	defer ctx.Transport.Close()
+	ctx.SnapshotApplyRate = 1 << 20
+	eng := engine.NewInMem(proto.Attributes{}, 1<<20)
+	defer eng.Close()
+	testCases := []struct {
+		engine   engine.Engine
+		expected int64
+	}{
+		{eng, 1 << 20},
+		{&provisionedEngine{Engine: eng}, 1 << 20},
+		{&provisionedEngine{Engine: eng, provisioning: engine.Provisioning{Bandwidth: 100 << 20}}, 25 << 20},
+		{&provisionedEngine{Engine: eng, provisioning: engine.Provisioning{IOPS: 100}}, 25 * engine.ProvisionedIOSize},
+	}
+	for i, test := range testCases {
+		if rate := NewStore(ctx, test.engine).snapshotApplyRate(); rate != test.expected {
+			t.Errorf("%d: expected snapshot apply rate %d; got %d", i, test.expected, rate)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+const (
+	// TraceLogSize is the number of traces retained by each node.
+	TraceLogSize = 1000
+	// DefaultTraceSampleRate is the default fraction of commands which
+	// are traced.
+	DefaultTraceSampleRate = 0.001
+)
+
+// A Trace records the steps of the execution of a sampled command by
+// a store, from its receipt until its reply, so that the causes of
+// rare slow commands can be found without tracing every command.
+type Trace struct {
+	// Start is the wall time, in unix nanos, at which the command was
+	// received.
+	Start    int64         `json:"start"`
+	Method   string        `json:"method"`
+	StoreID  proto.StoreID `json:"store_id"`
+	RaftID   int64         `json:"raft_id"`
+	Key      proto.Key     `json:"key"`
+	EndKey   proto.Key     `json:"end_key,omitempty"`
+	TxnName  string        `json:"txn_name,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Events   []TraceEvent  `json:"events"`
+	Error    string        `json:"error,omitempty"`
+
+	start time.Time
+}
+
+// A TraceEvent is a step in the execution of a traced command, at
+// Offset from the receipt of the command.
+type TraceEvent struct {
+	Offset  time.Duration `json:"offset"`
+	Message string        `json:"message"`
+}
+
+// eventf records a step of the traced command. It's a no-op on a nil
+// trace, that of a command which isn't sampled.
+func (t *Trace) eventf(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.Events = append(t.Events, TraceEvent{
+		Offset:  time.Since(t.start),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// A TraceLog samples the commands executed by the stores of a node for
+// tracing and retains the most recent traces in a ring buffer. A nil
+// TraceLog traces nothing. It's safe for concurrent use.
+type TraceLog struct {
+	sync.Mutex
+	sampleRate float64
+	traces     []Trace
+	next       int // Index at which to record the next trace
+	full       bool
+}
+
+// NewTraceLog returns a TraceLog retaining up to size traces, which
+// traces sampleRate of commands.
+func NewTraceLog(size int, sampleRate float64) *TraceLog {
+	return &TraceLog{
+		sampleRate: sampleRate,
+		traces:     make([]Trace, size),
+	}
+}
+
+// SetSampleRate sets the fraction of commands which are traced.
+func (tl *TraceLog) SetSampleRate(sampleRate float64) {
+	tl.Lock()
+	defer tl.Unlock()
+	tl.sampleRate = sampleRate
+}
+
+// start returns a trace of the command executed by the store if it's
+// sampled, and nil otherwise.
+func (tl *TraceLog) start(storeID proto.StoreID, args proto.Request) *Trace {
+	if tl == nil {
+		return nil
+	}
+	tl.Lock()
+	sampleRate := tl.sampleRate
+	tl.Unlock()
+	if sampleRate <= 0 || (sampleRate < 1 && rand.Float64() >= sampleRate) {
+		return nil
+	}
+	header := args.Header()
+	now := time.Now()
+	t := &Trace{
+		Start:   now.UnixNano(),
+		Method:  args.Method().String(),
+		StoreID: storeID,
+		RaftID:  header.RaftID,
+		Key:     header.Key,
+		EndKey:  header.EndKey,
+		start:   now,
+	}
+	if header.Txn != nil {
+		t.TxnName = header.Txn.Name
+	}
+	return t
+}
+
+// finish records the duration and error of a traced command and adds
+// its trace to the log, replacing the oldest trace if the log is full.
+func (tl *TraceLog) finish(t *Trace, err error) {
+	if t == nil {
+		return
+	}
+	t.Duration = time.Since(t.start)
+	if err != nil {
+		t.Error = err.Error()
+	}
+	tl.Lock()
+	defer tl.Unlock()
+	tl.traces[tl.next] = *t
+	tl.next++
+	if tl.next == len(tl.traces) {
+		tl.next = 0
+		tl.full = true
+	}
+}
+
+// Traces returns the traces in the log, oldest first.
+func (tl *TraceLog) Traces() []Trace {
+	if tl == nil {
+		return nil
+	}
+	tl.Lock()
+	defer tl.Unlock()
+	if !tl.full {
+		return append([]Trace(nil), tl.traces[:tl.next]...)
+	}
+	return append(append([]Trace(nil), tl.traces[tl.next:]...), tl.traces[:tl.next]...)
+}
@@ -0,0 +1,66 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// TestTraceLog verifies that sampled commands are traced, that the
+// most recent traces are retained, oldest first, and that nil traces
+// and trace logs are no-ops.
+func TestTraceLog(t *testing.T) {
+	tl := NewTraceLog(3, 1)
+	keys := func() []string {
+		var ks []string
+		for _, trace := range tl.Traces() {
+			ks = append(ks, string(trace.Key))
+		}
+		return ks
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		args := &proto.GetRequest{RequestHeader: proto.RequestHeader{Key: proto.Key(key), RaftID: 1}}
+		trace := tl.start(1, args)
+		if trace == nil {
+			t.Fatalf("expected command at %q to be sampled", key)
+		}
+		trace.eventf("step %d", 1)
+		tl.finish(trace, nil)
+	}
+	if ks := keys(); !reflect.DeepEqual(ks, []string{"b", "c", "d"}) {
+		t.Errorf("expected traces [b c d]; got %v", ks)
+	}
+	trace := tl.Traces()[2]
+	if trace.Method != "Get" || trace.StoreID != 1 || trace.RaftID != 1 || len(trace.Events) != 1 ||
+		trace.Events[0].Message != "step 1" {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+
+	tl.SetSampleRate(0)
+	if trace := tl.start(1, &proto.GetRequest{}); trace != nil {
+		t.Errorf("expected unsampled command not to be traced; got %+v", trace)
+	}
+	var nilTrace *Trace
+	nilTrace.eventf("ignored")
+	tl.finish(nilTrace, nil)
+	var nilLog *TraceLog
+	if trace := nilLog.start(1, &proto.GetRequest{}); trace != nil || nilLog.Traces() != nil {
+		t.Errorf("expected nil trace log not to trace")
+	}
+}
@@ -75,9 +75,9 @@ func (vq *verifyQueue) shouldQueue(now proto.Timestamp, rng *Range) (shouldQ boo
 // checksum is checked on load.
 func (vq *verifyQueue) process(now proto.Timestamp, rng *Range) error {
 	snap := rng.rm.Engine().NewSnapshot()
-	iter := newRangeDataIterator(rng, snap)
-	defer iter.Close()
 	defer snap.Close()
+	iter := newRangeScanIterator(rng, snap)
+	defer iter.Close()
 
 	// Iterate through all keys & values.
 	for ; iter.Valid(); iter.Next() {
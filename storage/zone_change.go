@@ -0,0 +1,163 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/cockroachdb/cockroach/proto"
+)
+
+// A RangeChange describes how the replicas of a range would change if
+// a zone config were set.
+type RangeChange struct {
+	RaftID   int64     `json:"raft_id"`
+	StartKey proto.Key `json:"start_key"`
+	EndKey   proto.Key `json:"end_key"`
+	// AddReplicas is the number of replicas which would be created on
+	// stores matching the new zone's attributes, either to raise the
+	// replica count or to replace replicas on stores which no longer
+	// match. RemoveReplicas is the number which would be removed.
+	AddReplicas    int `json:"add_replicas"`
+	RemoveReplicas int `json:"remove_replicas"`
+	// Splits is the number of splits needed for the range to fit within
+	// the new zone's maximum size.
+	Splits int `json:"splits"`
+	// Bytes is the estimated size of each replica of the range.
+	Bytes int64 `json:"bytes"`
+}
+
+// A ZoneChangePlan describes what setting a zone config would change,
+// without setting it. Sizes are estimated from the used bytes of the
+// stores holding each range's replicas, and the duration from the
+// store's snapshot apply rate, assuming the new replicas are copied
+// one at a time; both are rough. Pinned stores aren't considered.
+type ZoneChangePlan struct {
+	Prefix proto.Key `json:"prefix"`
+	// Ranges lists the ranges whose replicas would be added, removed or
+	// split, in key order.
+	Ranges []RangeChange `json:"ranges"`
+	// RangesMoved is the number of ranges some of whose replicas would
+	// be added or removed.
+	RangesMoved int `json:"ranges_moved"`
+	// BytesRewritten is the estimated number of bytes copied to new
+	// replicas.
+	BytesRewritten    int64         `json:"bytes_rewritten"`
+	EstimatedDuration time.Duration `json:"estimated_duration"`
+}
+
+// ZoneChangePlan returns a plan of the changes to the replicas of the
+// cluster's ranges which replacing the zone config map oldMap with
+// newMap, differing in the zone config for the key prefix, would
+// cause. It's computed from the ranges' addressing records and the
+// stores known to this one through gossip. The maps shouldn't have
+// their inheritance resolved, so that the zones inheriting from the
+// changed one are planned too.
+func (s *Store) ZoneChangePlan(prefix proto.Key, oldMap, newMap PrefixConfigMap) (ZoneChangePlan, error) {
+	stores, err := s.allocator.storeFinder(proto.Attributes{})
+	if err != nil {
+		return ZoneChangePlan{}, err
+	}
+	descs, err := scanRangeDescriptors(s.ctx.DB)
+	if err != nil {
+		return ZoneChangePlan{}, err
+	}
+	rate := s.snapshotApplyRate()
+	if rate <= 0 {
+		rate = DefaultSnapshotApplyRate
+	}
+	return computeZoneChangePlan(prefix, stores, descs, oldMap, newMap, rate), nil
+}
+
+// computeZoneChangePlan returns a plan of the changes to the ranges
+// with descriptors descs, in key order, caused by replacing the zone
+// config map oldMap with newMap. The replicas of a range are matched
+// to the replica attributes of its new zone, each to a different
+// replica on a store whose attributes include them; required
+// attributes without a matching replica need a new replica and
+// replicas left unmatched are removed.
+func computeZoneChangePlan(prefix proto.Key, stores []*StoreDescriptor, descs []proto.RangeDescriptor,
+	oldMap, newMap PrefixConfigMap, rate int64) ZoneChangePlan {
+	byID := map[proto.StoreID]*StoreDescriptor{}
+	replicaCounts := map[proto.StoreID]int64{}
+	for _, s := range stores {
+		byID[s.StoreID] = s
+	}
+	for i := range descs {
+		for _, r := range descs[i].Replicas {
+			replicaCounts[r.StoreID]++
+		}
+	}
+
+	plan := ZoneChangePlan{Prefix: prefix, Ranges: []RangeChange{}}
+	for i := range descs {
+		desc := &descs[i]
+		key := zoneKey(desc)
+		oldZone, _ := oldMap.ResolveZoneConfig(key)
+		newZone, _ := newMap.ResolveZoneConfig(key)
+		if reflect.DeepEqual(oldZone, newZone) {
+			continue
+		}
+
+		matched := 0
+		used := make([]bool, len(desc.Replicas))
+		for _, attrs := range newZone.ReplicaAttrs {
+			for j, r := range desc.Replicas {
+				if s, ok := byID[r.StoreID]; ok && !used[j] && attrs.IsSubset(*s.CombinedAttrs()) {
+					used[j] = true
+					matched++
+					break
+				}
+			}
+		}
+
+		// A replica's size is estimated as the mean size of the replicas
+		// of each live store holding one.
+		var bytes, live int64
+		for _, r := range desc.Replicas {
+			if s, ok := byID[r.StoreID]; ok && replicaCounts[r.StoreID] > 0 {
+				bytes += storeBytes(s) / replicaCounts[r.StoreID]
+				live++
+			}
+		}
+		if live > 0 {
+			bytes /= live
+		}
+
+		change := RangeChange{
+			RaftID:         desc.RaftID,
+			StartKey:       desc.StartKey,
+			EndKey:         desc.EndKey,
+			AddReplicas:    len(newZone.ReplicaAttrs) - matched,
+			RemoveReplicas: len(desc.Replicas) - matched,
+			Bytes:          bytes,
+		}
+		if max := newZone.RangeMaxBytes; max > 0 && bytes > max {
+			change.Splits = int((bytes - 1) / max)
+		}
+		if change.AddReplicas == 0 && change.RemoveReplicas == 0 && change.Splits == 0 {
+			continue
+		}
+		if change.AddReplicas > 0 || change.RemoveReplicas > 0 {
+			plan.RangesMoved++
+		}
+		plan.BytesRewritten += int64(change.AddReplicas) * bytes
+		plan.Ranges = append(plan.Ranges, change)
+	}
+	plan.EstimatedDuration = time.Duration(float64(plan.BytesRewritten) / float64(rate) * float64(time.Second))
+	return plan
+}
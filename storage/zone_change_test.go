@@ -0,0 +1,93 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/proto"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestComputeZoneChangePlan verifies that only the ranges of a changed
+// zone are planned, that replicas on stores which no longer match the
+// zone's attributes are replaced and that the bytes copied and the
+// splits are estimated from the stores' used bytes.
+func TestComputeZoneChangePlan(t *testing.T) {
+	defer leaktest.AfterTest(t)
+	store := func(id int, attr string, used int64) *StoreDescriptor {
+		return &StoreDescriptor{
+			StoreID:  proto.StoreID(id),
+			Node:     gossip.NodeDescriptor{NodeID: proto.NodeID(id)},
+			Attrs:    proto.Attributes{Attrs: []string{attr}},
+			Capacity: engine.StoreCapacity{Capacity: 1000, Available: 1000 - used},
+		}
+	}
+	stores := []*StoreDescriptor{store(1, "ssd", 300), store(2, "ssd", 200), store(3, "hdd", 100)}
+	replicas := func(ids ...int) []proto.Replica {
+		var r []proto.Replica
+		for _, id := range ids {
+			r = append(r, proto.Replica{NodeID: proto.NodeID(id), StoreID: proto.StoreID(id)})
+		}
+		return r
+	}
+	descs := []proto.RangeDescriptor{
+		{RaftID: 1, StartKey: engine.KeyMin, EndKey: proto.Key("db1"), Replicas: replicas(1, 2, 3)},
+		{RaftID: 2, StartKey: proto.Key("db1"), EndKey: proto.Key("db2"), Replicas: replicas(1, 3)},
+		{RaftID: 3, StartKey: proto.Key("db2"), EndKey: engine.KeyMax, Replicas: replicas(1, 2)},
+	}
+	oldMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, &proto.ZoneConfig{ReplicaAttrs: []proto.Attributes{{}, {}, {}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssd := proto.Attributes{Attrs: []string{"ssd"}}
+	zone := &proto.ZoneConfig{ReplicaAttrs: []proto.Attributes{ssd, ssd, ssd}, RangeMaxBytes: 50}
+	newMap, err := NewPrefixConfigMap([]*PrefixConfig{
+		{engine.KeyMin, nil, oldMap[0].Config},
+		{proto.Key("db1"), nil, zone},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stores 1, 2 and 3 hold 100, 100 and 50 bytes per replica, so each
+	// replica of range 2 is estimated at 75 bytes.
+	plan := computeZoneChangePlan(proto.Key("db1"), stores, descs, oldMap, newMap, 100)
+	expRanges := []RangeChange{
+		{RaftID: 2, StartKey: proto.Key("db1"), EndKey: proto.Key("db2"),
+			AddReplicas: 2, RemoveReplicas: 1, Splits: 1, Bytes: 75},
+	}
+	if !reflect.DeepEqual(plan.Ranges, expRanges) {
+		t.Errorf("expected ranges %+v; got %+v", expRanges, plan.Ranges)
+	}
+	if plan.RangesMoved != 1 || plan.BytesRewritten != 150 {
+		t.Errorf("expected 1 range moved and 150 bytes rewritten; got %d and %d", plan.RangesMoved, plan.BytesRewritten)
+	}
+	if plan.EstimatedDuration != 1500*time.Millisecond {
+		t.Errorf("expected duration of 1.5s; got %s", plan.EstimatedDuration)
+	}
+
+	// Setting the zone config already in place changes nothing.
+	if plan := computeZoneChangePlan(proto.Key("db1"), stores, descs, newMap, newMap, 100); len(plan.Ranges) != 0 {
+		t.Errorf("expected no changes; got %+v", plan.Ranges)
+	}
+}
@@ -71,8 +71,8 @@ func (tm *testModel) Start() {
 // underlying engine. Data is returned as a map of strings to proto.Values.
 func (tm *testModel) getActualData() map[string]*proto.Value {
 	// Scan over all TS Keys stored in the engine
-	startKey := keyDataPrefix
-	endKey := keyDataPrefix.PrefixEnd()
+	startKey := KeyDataPrefix
+	endKey := KeyDataPrefix.PrefixEnd()
 	keyValues, err := engine.MVCCScan(tm.Eng, startKey, endKey, 0, tm.Clock.Now(), true, nil)
 	if err != nil {
 		tm.t.Fatalf("error scanning TS data from engine: %s", err.Error())
@@ -57,8 +57,8 @@ import (
 //
 // 		slot := (timestamp / keyDuration) // integer division
 var (
-	// keyDataPrefix is the key prefix for time series data keys.
-	keyDataPrefix = proto.MakeKey(engine.KeySystemPrefix, proto.Key("tsd"))
+	// KeyDataPrefix is the key prefix for time series data keys.
+	KeyDataPrefix = proto.MakeKey(engine.KeySystemPrefix, proto.Key("tsd"))
 )
 
 // MakeDataKey creates a time series data key for the given series name, source,
@@ -69,7 +69,7 @@ func MakeDataKey(name string, source string, r Resolution, timestamp int64) prot
 	// Normalize timestamp into a timeslot before recording.
 	timeslot := timestamp / r.KeyDuration()
 
-	k := append(proto.Key(nil), keyDataPrefix...)
+	k := append(proto.Key(nil), KeyDataPrefix...)
 	k = encoding.EncodeBytes(k, []byte(name))
 	k = encoding.EncodeVarint(k, int64(r))
 	k = encoding.EncodeVarint(k, timeslot)
@@ -88,10 +88,10 @@ func DecodeDataKey(key proto.Key) (string, string, Resolution, int64) {
 	)
 
 	// Detect and remove prefix.
-	if !bytes.HasPrefix(remainder, keyDataPrefix) {
+	if !bytes.HasPrefix(remainder, KeyDataPrefix) {
 		panic(fmt.Sprintf("malformed time series data key %v: improper prefix", key))
 	}
-	remainder = remainder[len(keyDataPrefix):]
+	remainder = remainder[len(KeyDataPrefix):]
 
 	// Decode series name.
 	remainder, name = encoding.DecodeBytes(remainder)
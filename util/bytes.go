@@ -0,0 +1,66 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package util
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// byteSizeSuffixes maps the suffixes accepted by ParseBytes to their
+// multipliers.
+var byteSizeSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// ParseBytes parses an integer number of bytes with an optional binary
+// (KiB, MiB, GiB, TiB) or decimal (KB, MB, GB, TB) suffix, e.g. 512MiB
+// or 2GB, or a percentage of the system's memory, e.g. 10%.
+func ParseBytes(s string) (int64, error) {
+	if strings.HasSuffix(s, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return 0, Errorf("invalid percentage %q; must be in (0, 100]", s)
+		}
+		total, err := SystemMemory()
+		if err != nil {
+			return 0, Errorf("unable to size %s of system memory: %s", s, err)
+		}
+		return int64(float64(total) * percent / 100), nil
+	}
+	multiplier := int64(1)
+	num := s
+	for _, bs := range byteSizeSuffixes {
+		if strings.HasSuffix(s, bs.suffix) {
+			num, multiplier = strings.TrimSuffix(s, bs.suffix), bs.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, Errorf("invalid size %q", s)
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, Errorf("size %q is larger than the maximum of %d bytes", s, int64(math.MaxInt64))
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+// TestParseBytes verifies parsing of byte sizes with and without
+// suffixes and as percentages of system memory.
+func TestParseBytes(t *testing.T) {
+	testCases := []struct {
+		s      string
+		exp    int64
+		expErr bool
+	}{
+		{"0", 0, false},
+		{"1000", 1000, false},
+		{"1000B", 1000, false},
+		{"512KiB", 512 << 10, false},
+		{"512MiB", 512 << 20, false},
+		{"2GiB", 2 << 30, false},
+		{"1TiB", 1 << 40, false},
+		{"2KB", 2e3, false},
+		{"2MB", 2e6, false},
+		{"2GB", 2e9, false},
+		{"2TB", 2e12, false},
+		{"", 0, true},
+		{"lots", 0, true},
+		{"-1", 0, true},
+		{"1.5GiB", 0, true},
+		{"GiB", 0, true},
+		{"0%", 0, true},
+		{"101%", 0, true},
+		{"x%", 0, true},
+		{"8388607TiB", 8388607 << 40, false},
+		{"8388608TiB", 0, true},
+		{"9999999TiB", 0, true},
+		{"9223372036854775807", math.MaxInt64, false},
+		{"9223372036854775808", 0, true},
+	}
+	for i, test := range testCases {
+		n, err := ParseBytes(test.s)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%d: expected error parsing %q", i, test.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %s", i, err)
+		} else if n != test.exp {
+			t.Errorf("%d: expected %q to be %d bytes; got %d", i, test.s, test.exp, n)
+		}
+	}
+
+	total, err := SystemMemory()
+	if err != nil {
+		t.Skipf("unable to determine system memory: %s", err)
+	}
+	if n, err := ParseBytes("50%"); err != nil {
+		t.Error(err)
+	} else if n != total/2 {
+		t.Errorf("expected 50%% to be %d bytes; got %d", total/2, n)
+	}
+}
@@ -18,8 +18,19 @@
 package util
 
 import (
+	"encoding/hex"
 	"net"
 	"os"
+	"strings"
+)
+
+const (
+	// UnixAddrPrefix prefixes addresses which name a unix domain
+	// socket by its path, as in "unix:///tmp/cockroach.sock".
+	UnixAddrPrefix = "unix://"
+	// unixHTTPHostSuffix suffixes the hosts which HTTPHost encodes
+	// unix socket paths in.
+	unixHTTPHostSuffix = ".unix-socket"
 )
 
 // EnsureHost takes a host:port pair, where the host portion is optional.
@@ -37,3 +48,41 @@ func EnsureHost(addr string) string {
 	}
 	return net.JoinHostPort(host, port)
 }
+
+// ParseAddr returns the network address named by addr, which is either
+// a host:port pair or the path of a unix socket prefixed by
+// UnixAddrPrefix.
+func ParseAddr(addr string) RawAddr {
+	if strings.HasPrefix(addr, UnixAddrPrefix) {
+		return MakeRawAddr("unix", strings.TrimPrefix(addr, UnixAddrPrefix))
+	}
+	return MakeRawAddr("tcp", addr)
+}
+
+// HTTPHost returns the host by which the node at addr is named in the
+// URLs of HTTP requests. Unix socket paths can't appear in URLs, so
+// they're hex-encoded in a host name which the clients returned by
+// client.NewHTTPClient dial; host:port pairs are returned unchanged.
+func HTTPHost(addr string) string {
+	if !strings.HasPrefix(addr, UnixAddrPrefix) {
+		return addr
+	}
+	return hex.EncodeToString([]byte(strings.TrimPrefix(addr, UnixAddrPrefix))) + unixHTTPHostSuffix
+}
+
+// UnixSocketFromHTTPHost returns the path of the unix socket encoded
+// by HTTPHost in host, which may carry a port, or false if host
+// doesn't name a unix socket.
+func UnixSocketFromHTTPHost(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !strings.HasSuffix(host, unixHTTPHostSuffix) {
+		return "", false
+	}
+	path, err := hex.DecodeString(strings.TrimSuffix(host, unixHTTPHostSuffix))
+	if err != nil {
+		return "", false
+	}
+	return string(path), true
+}
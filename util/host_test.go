@@ -0,0 +1,51 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package util
+
+import "testing"
+
+func TestParseAddr(t *testing.T) {
+	testCases := []struct {
+		addr, network, str string
+	}{
+		{"localhost:26257", "tcp", "localhost:26257"},
+		{":0", "tcp", ":0"},
+		{"unix:///tmp/cockroach.sock", "unix", "/tmp/cockroach.sock"},
+	}
+	for i, test := range testCases {
+		if addr := ParseAddr(test.addr); addr.Network() != test.network || addr.String() != test.str {
+			t.Errorf("%d: expected %s address %s; got %s address %s", i, test.network, test.str,
+				addr.Network(), addr)
+		}
+	}
+}
+
+func TestHTTPHost(t *testing.T) {
+	if host := HTTPHost("localhost:26257"); host != "localhost:26257" {
+		t.Errorf("expected host:port to be unchanged; got %s", host)
+	}
+	host := HTTPHost("unix:///tmp/cockroach.sock")
+	for _, h := range []string{host, host + ":443"} {
+		if path, ok := UnixSocketFromHTTPHost(h); !ok || path != "/tmp/cockroach.sock" {
+			t.Errorf("expected %s to name /tmp/cockroach.sock; got %q, %t", h, path, ok)
+		}
+	}
+	for _, h := range []string{"localhost:443", "nothex.unix-socket:443"} {
+		if path, ok := UnixSocketFromHTTPHost(h); ok {
+			t.Errorf("expected %s not to name a unix socket; got %s", h, path)
+		}
+	}
+}
@@ -77,7 +77,12 @@ var jsonConfig = []byte(`{
     }
   ],
   "range_min_bytes": 1048576,
-  "range_max_bytes": 67108864
+  "range_max_bytes": 67108864,
+  "reads_per_second": 0,
+  "writes_per_second": 0,
+  "pin_expiration": 0,
+  "inherit": false,
+  "commit_coalescing_window_micros": 0
 }`)
 
 var protobufConfig []byte
@@ -17,7 +17,13 @@
 
 package log
 
-import "github.com/golang/glog"
+import (
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/golang/glog"
+)
 
 func init() {
 	glog.CopyStandardLogTo("INFO")
@@ -33,6 +39,28 @@ func FatalOnPanic() {
 	}
 }
 
+// Verbosity returns the level of V-style logging, as set by the -v
+// flag.
+func Verbosity() int {
+	level, _ := strconv.Atoi(flag.Lookup("v").Value.String())
+	return level
+}
+
+// SetVerbosity sets the level of V-style logging, as if it had been
+// given with the -v flag.
+func SetVerbosity(level int) error {
+	return flag.Lookup("v").Value.Set(strconv.Itoa(level))
+}
+
+// Dir returns the directory to which log files are written, as set
+// by the -log_dir flag, or the temporary directory used if it's unset.
+func Dir() string {
+	if dir := flag.Lookup("log_dir").Value.String(); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
 // Info logs to the INFO log.
 // Arguments are handled in the manner of fmt.Print; a newline is appended if missing.
 var Info = glog.Info